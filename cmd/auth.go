@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sim-deos/plain/internal/app"
+	"github.com/sim-deos/plain/internal/forge"
+	"github.com/sim-deos/plain/internal/secret"
+
+	"github.com/spf13/cobra"
+)
+
+// deviceScopes are the OAuth scopes plain needs to open and inspect pull requests on each forge.
+var deviceScopes = map[forge.Kind][]string{
+	forge.GitHub: {"repo"},
+	forge.GitLab: {"api"},
+}
+
+func NewAuthCmd(a *app.App) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "auth",
+		Short: "Manages logins to GitHub and GitLab",
+		Long: `Logs in to the forges plain talks to when opening pull requests and checking CI.
+		Tokens are kept in the OS keychain where one is available, and in an encrypted file otherwise.
+		Without a login, plain uses a GitHub token in $GH_TOKEN or $GITHUB_TOKEN, a GitLab token in
+		$GITLAB_TOKEN, the token the GitHub CLI (gh) is logged in with, or the password git's credential
+		helper has for the host. Self-hosted GitLab instances without gitlab in their name are found by
+		logging in to them with --kind gitlab.`,
+	}
+
+	c.AddCommand(newAuthLoginCmd(a), newAuthStatusCmd(a), newAuthLogoutCmd(a))
+	return c
+}
+
+func newAuthLoginCmd(a *app.App) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "login",
+		Short: "Logs in to a forge host",
+		Long: `Logs in to a forge host through your browser using a one-time code.
+		To use a personal access token instead, pipe it in with --with-token.
+		Browser login needs an OAuth app client ID, passed with --client-id or set with
+		git config plain.<host>.clientId.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error { return runAuthLogin(a, cmd, args) },
+	}
+	c.Flags().String("host", "github.com", "The forge host to log in to")
+	c.Flags().String("kind", "", "The forge software the host runs (github or gitlab), detected from the host by default")
+	c.Flags().Bool("with-token", false, "Read a personal access token from standard input")
+	c.Flags().String("client-id", "", "The OAuth app client ID to use for browser login")
+	return c
+}
+
+func newAuthStatusCmd(a *app.App) *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Shows which forge hosts you are logged in to",
+		Args:  cobra.NoArgs,
+		RunE:  func(cmd *cobra.Command, args []string) error { return runAuthStatus(a, cmd, args) },
+	}
+}
+
+func newAuthLogoutCmd(a *app.App) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "logout",
+		Short: "Logs out of a forge host and forgets its token",
+		Args:  cobra.NoArgs,
+		RunE:  func(cmd *cobra.Command, args []string) error { return runAuthLogout(a, cmd, args) },
+	}
+	c.Flags().String("host", "github.com", "The forge host to log out of")
+	return c
+}
+
+func runAuthLogin(a *app.App, cmd *cobra.Command, args []string) error {
+	host, _ := cmd.Flags().GetString("host")
+	withToken, _ := cmd.Flags().GetBool("with-token")
+
+	kind, err := forgeKind(cmd, host)
+	if err != nil {
+		return err
+	}
+
+	tokens, err := forge.NewTokens()
+	if err != nil {
+		return err
+	}
+
+	login := forge.Login{Host: host, Kind: kind, LoggedIn: time.Now()}
+	var token string
+	if withToken {
+		login.Method = "token"
+		token, err = bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && token == "" {
+			return fmt.Errorf("failed to read token: %w", err)
+		}
+		token = strings.TrimSpace(token)
+	} else {
+		login.Method = "device"
+		token, err = deviceLogin(a, cmd, host, kind)
+		if err != nil {
+			return err
+		}
+	}
+
+	if token == "" {
+		return errors.New("no token provided")
+	}
+
+	if err := tokens.Save(login, token); err != nil {
+		return fmt.Errorf("failed to save token: %w", err)
+	}
+	fmt.Fprintf(a.Err, "plain: logged in to %s, token saved to %s\n", host, tokens.Store.Name())
+	return nil
+}
+
+func deviceLogin(a *app.App, cmd *cobra.Command, host string, kind forge.Kind) (string, error) {
+	clientID, _ := cmd.Flags().GetString("client-id")
+	if clientID == "" {
+		configured, err := a.Git.GetConfigValues("plain." + host + ".clientId")
+		if err != nil {
+			return "", err
+		}
+		if len(configured) == 0 {
+			return "", fmt.Errorf("no OAuth client ID for %s: pass --client-id, or use --with-token", host)
+		}
+		clientID = configured[len(configured)-1]
+	}
+
+	httpClient, err := newHTTPClient(a, host)
+	if err != nil {
+		return "", err
+	}
+	flow := &forge.DeviceFlow{Host: host, Kind: kind, ClientID: clientID, Scopes: deviceScopes[kind], HTTP: httpClient}
+	ctx := context.Background()
+
+	code, err := flow.Start(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Fprintf(a.Err, "plain: open %s and enter the code %s\n", code.VerificationURI, code.UserCode)
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(code.ExpiresIn)*time.Second)
+	defer cancel()
+
+	return flow.Wait(ctx, code)
+}
+
+func runAuthStatus(a *app.App, cmd *cobra.Command, args []string) error {
+	tokens, err := forge.NewTokens()
+	if err != nil {
+		return err
+	}
+	tokens.Env = a.Settings.TokenEnv
+
+	logins, err := tokens.Logins()
+	if err != nil {
+		return err
+	}
+	if len(logins) == 0 {
+		if _, source, err := tokens.Find("github.com", forge.GitHub); err == nil {
+			fmt.Fprintf(a.Err, "plain: not logged in to any forge, using the github.com token from %s\n", source)
+			return nil
+		}
+		fmt.Fprintln(a.Err, "plain: not logged in to any forge, run plain auth login")
+		return nil
+	}
+
+	for _, login := range logins {
+		state := "logged in"
+		if _, err := tokens.Token(login.Host); errors.Is(err, secret.ErrNotFound) {
+			state = "token missing, run plain auth login again"
+		} else if err != nil {
+			state = "token unreadable: " + err.Error()
+		}
+		fmt.Fprintf(a.Out, "%s (%s): %s via %s since %s\n", login.Host, login.Kind, state, login.Method, login.LoggedIn.Format("2006-01-02"))
+	}
+	fmt.Fprintf(a.Out, "\ntokens are stored in %s\n", tokens.Store.Name())
+	return nil
+}
+
+func runAuthLogout(a *app.App, cmd *cobra.Command, args []string) error {
+	host, _ := cmd.Flags().GetString("host")
+
+	tokens, err := forge.NewTokens()
+	if err != nil {
+		return err
+	}
+	if err := tokens.Delete(host); err != nil {
+		return fmt.Errorf("failed to remove token: %w", err)
+	}
+	fmt.Fprintf(a.Err, "plain: logged out of %s\n", host)
+	return nil
+}
+
+// forgeKind returns the forge kind from the --kind flag, or detects it from host.
+func forgeKind(cmd *cobra.Command, host string) (forge.Kind, error) {
+	name, _ := cmd.Flags().GetString("kind")
+	if name != "" {
+		return forge.ParseKind(name)
+	}
+	return forge.DetectKind(host)
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/sim-deos/plain/internal/app"
+	"github.com/sim-deos/plain/internal/git"
+
+	"github.com/spf13/cobra"
+)
+
+// addBackendFlag adds --git-backend to root, which chooses how every command works with git: shell runs
+// the git command, and native reads and writes the repository itself, handing what it can't do yet to
+// the git command. Without the flag, git config plain.gitBackend chooses, and shell is the default.
+// Without the git command installed, plain is always native.
+func addBackendFlag(root *cobra.Command, a *app.App, config func() (*git.Config, error)) {
+	root.PersistentFlags().String("git-backend", "", "How plain works with git: shell or native (default plain.gitBackend, else shell)")
+
+	preRun := root.PersistentPreRun
+	root.PersistentPreRun = nil
+	root.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		c, err := config()
+		if err != nil {
+			return err
+		}
+		if err := configureShell(a, cmd, c); err != nil {
+			return err
+		}
+		if err := selectBackend(a, cmd, c); err != nil {
+			return err
+		}
+		if preRun != nil {
+			preRun(cmd, args)
+		}
+		return nil
+	}
+}
+
+// configureShell ties the git commands a [git.ShellClient] runs to the command's context, and kills any
+// that run longer than git config plain.gitTimeout, using values like 30s or 2m, or 0 for no limit.
+// What git shows, like a merge's summary or a push's progress, is a message for the user, so it all
+// goes to a.Err.
+func configureShell(a *app.App, cmd *cobra.Command, config *git.Config) error {
+	shell, ok := a.Git.(*git.ShellClient)
+	if !ok {
+		return nil
+	}
+	shell.Context = cmd.Context()
+	shell.Stdout, shell.Stderr = a.Err, a.Err
+	value, ok := config.Get("plain.gitTimeout")
+	if !ok || value == "" {
+		return nil
+	}
+	var err error
+	if shell.Timeout, err = parseAge(value); err != nil {
+		return fmt.Errorf("invalid plain.gitTimeout %q: %w", value, err)
+	}
+	return nil
+}
+
+// selectBackend replaces a.Git with the backend --git-backend or plain.gitBackend names.
+func selectBackend(a *app.App, cmd *cobra.Command, config *git.Config) error {
+	backend, _ := cmd.Flags().GetString("git-backend")
+	explicit := backend != ""
+	if !explicit {
+		backend, _ = config.Get("plain.gitBackend")
+	}
+
+	switch backend {
+	case "", "shell":
+		if a.GitMissing && explicit {
+			return fmt.Errorf("cannot use the shell git backend: %w", git.ErrNoGit)
+		}
+	case "native":
+		if !a.GitMissing {
+			a.Git = &git.NativeClient{Fallback: a.Git}
+		}
+	default:
+		return fmt.Errorf("unknown git backend %q, want shell or native", backend)
+	}
+
+	// Like git, the native backend fetches and pushes over https through the configured proxy and CA
+	// bundle, and within plain.httpTimeout.
+	if native, ok := a.Git.(*git.NativeClient); ok {
+		client, err := newHTTPClient(a, "")
+		if err != nil {
+			return err
+		}
+		native.HTTP = client
+	}
+	return nil
+}
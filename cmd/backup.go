@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/sim-deos/plain/internal/app"
+	"github.com/sim-deos/plain/internal/diff"
+	"github.com/sim-deos/plain/internal/git"
+
+	"github.com/spf13/cobra"
+)
+
+func NewBackupCmd(a *app.App) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "backup <file>",
+		Short: "Saves branches and their history to a single file",
+		Long: `Writes every branch and tag, with all of their history, to a bundle file: a backup that plain
+		restore reads back without a network. git reads it too, so git clone <file> <dir> makes a new
+		repository from it.
+
+		To hand a branch to someone, like by email or on a USB stick, name it with --branch and a branch
+		they already have with --base: plain backup --branch login-page --base main login.bundle. Only the
+		commits the branch adds are written, and restoring the file needs the base's history.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error { return runBackup(a, cmd, args[0]) },
+	}
+	c.Flags().StringArray("branch", nil, "A branch to back up, instead of every branch and tag")
+	c.Flags().StringArray("base", nil, "A branch or commit the receiver already has, whose history is left out")
+	return c
+}
+
+func runBackup(a *app.App, cmd *cobra.Command, path string) error {
+	branches, _ := cmd.Flags().GetStringArray("branch")
+	bases, _ := cmd.Flags().GetStringArray("base")
+
+	repo, err := git.OpenRepository()
+	if err != nil {
+		return err
+	}
+	var refs []string
+	for _, branch := range branches {
+		if _, err := repo.ResolveRevision("refs/heads/" + branch); err != nil {
+			return fmt.Errorf("no branch named %s", branch)
+		}
+		refs = append(refs, "refs/heads/"+branch)
+	}
+	if len(branches) == 0 {
+		all, err := repo.Refs()
+		if err != nil {
+			return err
+		}
+		for name, hash := range all {
+			if (strings.HasPrefix(name, "refs/heads/") || strings.HasPrefix(name, "refs/tags/")) && !strings.HasPrefix(hash, "ref: ") {
+				refs = append(refs, name)
+			}
+		}
+		if len(refs) == 0 {
+			return errors.New("nothing to back up, the repository has no branches")
+		}
+		slices.Sort(refs)
+		// HEAD tells git clone which branch to check out.
+		if current, err := repo.CurrentBranch(); err == nil && slices.Contains(refs, "refs/heads/"+current) {
+			refs = append([]string{"HEAD"}, refs...)
+		}
+	}
+
+	// The bundle is written next to path and renamed into place, so a failure leaves no partial file.
+	f, err := os.CreateTemp(filepath.Dir(path), ".plain-backup-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	bundle, err := repo.CreateBundle(f, refs, bases)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if errors.Is(err, git.ErrEmptyBundle) {
+		return fmt.Errorf("nothing to back up, %s already has everything", strings.Join(bases, " and "))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return err
+	}
+
+	var heads, tags int
+	for _, ref := range bundle.Refs {
+		switch {
+		case strings.HasPrefix(ref.Name, "refs/heads/"):
+			heads++
+		case strings.HasPrefix(ref.Name, "refs/tags/"):
+			tags++
+		}
+	}
+	var size int64
+	if info, err := os.Stat(path); err == nil {
+		size = info.Size()
+	}
+	fmt.Fprintf(a.Err, "plain: backed up %d branch(es) and %d tag(s) to %s (%s)\n", heads, tags, path, diff.Size(size))
+	if len(bundle.Prerequisites) > 0 {
+		fmt.Fprintln(a.Err, "plain: restoring it needs the commits it builds on:")
+		for _, prerequisite := range bundle.Prerequisites {
+			fmt.Fprintf(a.Err, "  %s %s\n", shortHash(prerequisite.Hash), prerequisite.Name)
+		}
+	}
+	return nil
+}
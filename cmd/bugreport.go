@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/sim-deos/plain/internal/app"
+	"github.com/sim-deos/plain/internal/bugreport"
+	"github.com/sim-deos/plain/internal/git"
+
+	"github.com/spf13/cobra"
+)
+
+func NewBugreportCmd(a *app.App) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "bugreport",
+		Short: "Writes a file describing plain's environment to attach to an issue",
+		Long: `Gathers what helps make sense of a problem into one file: the versions of plain, Go, and git,
+		the operating system, counts of the repository's commits, refs, remotes, and objects, what moved
+		HEAD lately, and the last plain command that failed, with the errors it wrapped or, if it
+		crashed, where. Branch names, file names, remote URLs, and commit messages are left out, but
+		the last error's message is kept as it was printed, so look the file over before sharing it.
+
+		The report is written to plain-bugreport.txt in the current directory unless --output names
+		another file, or - for standard output. It can be made outside of a repository too.
+
+		For a problem that comes and goes, set git config plain.logFile to true: every command, the git
+		commands it runs, and how it failed are then logged to plain.log beside the record of the last
+		failure, in plain under your cache directory. The log names the commands and their arguments, so
+		look it over too before attaching it.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error { return runBugreport(a, cmd) },
+	}
+	c.Flags().StringP("output", "o", "plain-bugreport.txt", "The file to write the report to, or - for standard output")
+	return c
+}
+
+func runBugreport(a *app.App, cmd *cobra.Command) error {
+	path, _ := cmd.Flags().GetString("output")
+
+	repo, err := git.OpenRepository()
+	if errors.Is(err, git.ErrNotRepo) {
+		repo = nil
+	} else if err != nil {
+		return err
+	}
+	log, err := bugreport.NewErrorLog()
+	if err != nil {
+		return err
+	}
+	report, err := bugreport.Gather(repo, bugreport.DefaultOperations, log)
+	if err != nil {
+		return fmt.Errorf("failed to describe the repository: %w", err)
+	}
+	if a.GitMissing {
+		report.Git = "not installed, using the built-in git"
+	}
+
+	if path == "-" {
+		return report.Write(a.Out)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := report.Write(f); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.Err, "plain: wrote %s; look it over, then attach it to the issue\n", path)
+	return nil
+}
@@ -1,22 +1,307 @@
-/*
-Copyright © 2025 NAME HERE <EMAIL ADDRESS>
-*/
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/sim-deos/plain/internal/app"
+	"github.com/sim-deos/plain/internal/forge"
+	"github.com/sim-deos/plain/internal/git"
+	"github.com/sim-deos/plain/internal/timetrack"
 
 	"github.com/spf13/cobra"
 )
 
+// checkpointNotesRef holds the notes recording checkpoint metadata, apart from the user's own notes.
+const checkpointNotesRef = "refs/notes/plain"
+
 func NewCheckpointCmd(a *app.App) *cobra.Command {
 	checkpointCmd := &cobra.Command{
 		Use:   "checkpoint",
 		Short: "Set up a checkpoint in your code history",
-		Long:  `Todo`,
-		Run:   func(cmd *cobra.Command, args []string) { fmt.Println("checkpoint called") },
+		Long: `Commits every change in the work tree, including untracked files that aren't ignored, to the
+		current branch.
+
+		Checkpoints can be linked to work sessions for time tracking tools. Setting git config
+		plain.timeTracking to trailers adds Plain-Session and Plain-Issue trailers to each checkpoint,
+		events appends a JSON line per checkpoint to .git/plain/events.jsonl, and both does both.
+		notes keeps the trailers out of the message, in a git note on refs/notes/plain instead.
+		A session ends after plain.sessionIdle (30m by default) without a checkpoint. Time tracking
+		is off by default.
+
+		When origin is a Gerrit server, one of googlesource.com, gerrithub.io, or a host named with git
+		config plain.gerritHost, or when plain.changeId is true, each checkpoint gets a Change-Id
+		trailer so Gerrit can track it as a change. Setting gerrit.createChangeId to false turns this
+		off, as it does for Gerrit's own commit-msg hook.
+
+		With git config commit.gpgsign set, checkpoints are signed as git commit signs them, with
+		gpg.program and user.signingkey, or with an SSH key when gpg.format is ssh.
+
+		While pairing, set up with plain pair add, each checkpoint credits the partners with a
+		Co-authored-by trailer.
+
+		To be reminded when changes have gone too long without a checkpoint, set
+		plain.checkpointReminder; see plain prompt.
+
+		Branches protected in the team's .plain/team.toml, or with git config plain.protectedBranch,
+		can't be checkpointed on; see plain help settings.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error { return runCheckpoint(a, cmd, args) },
+	}
+	checkpointCmd.Flags().StringP("message", "m", "Checkpoint", "The checkpoint's commit message")
+	return needsGit(locksRepo(a, checkpointCmd), "commit the checkpoint")
+}
+
+func runCheckpoint(a *app.App, cmd *cobra.Command, args []string) error {
+	message, _ := cmd.Flags().GetString("message")
+
+	branch, err := a.Git.GetCurrentBranch()
+	if err != nil {
+		return err
+	}
+	if branch == "" {
+		return fmt.Errorf("cannot checkpoint: %w", errDetachedHead)
+	}
+
+	repo, err := git.OpenRepository()
+	if err != nil {
+		return err
+	}
+	policy, err := teamPolicy(a, repo)
+	if err != nil {
+		return err
+	}
+	if policy.IsProtected(branch) {
+		return fmt.Errorf("%s is protected, so changes reach it through pull requests; start a feature for them with plain start", branch)
+	}
+
+	mode, tracker, err := timeTracking(a, repo)
+	if err != nil {
+		return err
+	}
+
+	parent, err := repo.ResolveRevision("HEAD")
+	old, parentTree := parent, ""
+	if errors.Is(err, git.ErrRefNotFound) {
+		old = git.ZeroHash
+	} else if err != nil {
+		return err
+	} else {
+		commit, err := repo.ReadCommit(parent)
+		if err != nil {
+			return err
+		}
+		parentTree = commit.Tree
+	}
+
+	tree, err := stageAll(a, repo)
+	if err != nil {
+		return err
+	}
+	if tree == "" || tree == parentTree {
+		fmt.Fprintln(a.Err, "plain: nothing to checkpoint")
+		return nil
+	}
+
+	who, err := identity(a)
+	if err != nil {
+		return err
+	}
+
+	var session timetrack.Session
+	issue := forge.FindIssue(branch, nil)
+	if mode != timetrack.Off {
+		session, err = tracker.Session(who.Time)
+		if err != nil {
+			return err
+		}
+	}
+	var trailers []string
+	if mode&timetrack.RecordTrailers != 0 {
+		trailers = timetrack.Trailers(session, issue)
+	}
+	coauthors, err := pairTrailers(repo)
+	if err != nil {
+		return err
+	}
+	if trailers = append(trailers, coauthors...); len(trailers) > 0 {
+		message = strings.TrimRight(message, "\n") + "\n\n" + strings.Join(trailers, "\n")
+	}
+
+	var parents []string
+	if parent != "" {
+		parents = []string{parent}
+	}
+	changeIDs, err := changeIDsEnabled(a)
+	if err != nil {
+		return err
+	}
+	if changeIDs {
+		message = forge.WithChangeID(message, forge.NewChangeID(tree, parents, who, message))
+	}
+	hash, err := repo.CreateCommit(tree, parents, who, who, message)
+	if err != nil {
+		return err
+	}
+
+	subject, _, _ := strings.Cut(message, "\n")
+	if err := repo.UpdateRef("refs/heads/"+branch, old, hash, who, "checkpoint: "+subject); err != nil {
+		return err
+	}
+
+	if mode&timetrack.RecordNotes != 0 {
+		note := strings.Join(append([]string{"Plain-Checkpoint: true"}, timetrack.Trailers(session, issue)...), "\n")
+		if err := repo.SetNote(checkpointNotesRef, hash, note, who); err != nil {
+			return fmt.Errorf("failed to record checkpoint note: %w", err)
+		}
+	}
+	if mode&timetrack.RecordEvents != 0 {
+		err := tracker.Record(timetrack.Event{
+			Time:           who.Time,
+			Kind:           "checkpoint",
+			Session:        session.ID,
+			SessionStarted: session.Started,
+			Issue:          issue,
+			Branch:         branch,
+			Commit:         hash,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to record checkpoint event: %w", err)
+		}
+	}
+
+	fmt.Fprintf(a.Err, "plain: checkpoint %s\n", hash[:7])
+	return nil
+}
+
+// stageAll stages every change in the work tree and returns the resulting tree, or "" if the work tree
+// and index were clean.
+func stageAll(a *app.App, repo *git.Repository) (string, error) {
+	ignore, err := loadIgnore(a, repo)
+	if err != nil {
+		return "", err
+	}
+
+	changes, err := repo.Status(ignore)
+	if err != nil {
+		return "", err
+	}
+	if len(changes) == 0 {
+		return "", nil
+	}
+
+	idx, err := repo.Index()
+	if err != nil {
+		return "", err
+	}
+	for _, change := range changes {
+		if change.Staged == git.StatusConflicted {
+			return "", fmt.Errorf("%s has merge conflicts, resolve them before checkpointing", change.Path)
+		}
+		// Nested repositories are listed with a trailing slash, and aren't checkpointed.
+		if change.Unstaged == 0 || strings.HasSuffix(change.Path, "/") {
+			continue
+		}
+		if err := repo.StagePath(idx, change.Path); err != nil {
+			return "", err
+		}
+	}
+	if err := repo.WriteIndex(idx); err != nil {
+		return "", err
+	}
+	return idx.WriteTree(repo.Encoder())
+}
+
+// changeIDsEnabled reports whether commits plain creates should carry Gerrit Change-Id trailers: when
+// git config plain.changeId says so, or otherwise when origin is a Gerrit host, including those named
+// with plain.gerritHost. gerrit.createChangeId set
+// to false turns them off either way.
+func changeIDsEnabled(a *app.App) (bool, error) {
+	if create, err := lastConfigValue(a, "gerrit.createChangeId"); err != nil || isFalse(create) {
+		return false, err
+	}
+	enabled, err := lastConfigValue(a, "plain.changeId")
+	if err != nil {
+		return false, err
+	}
+	if enabled != "" {
+		return !isFalse(enabled), nil
+	}
+
+	url, err := remoteURL(defaultRemote)
+	if err != nil || url == "" {
+		return false, err
+	}
+	host, err := forge.RemoteHost(url)
+	if err != nil {
+		return false, nil
+	}
+	configured, err := a.Git.GetConfigValues("plain.gerritHost")
+	if err != nil {
+		return false, err
+	}
+	return forge.IsGerritHost(host, configured), nil
+}
+
+// isFalse reports whether a git config value is one of git's spellings of false.
+func isFalse(value string) bool {
+	switch strings.ToLower(value) {
+	case "false", "no", "off", "0":
+		return true
+	}
+	return false
+}
+
+// loadIgnore reads the repository's ignore rules, including the user's core.excludesFile.
+func loadIgnore(a *app.App, repo *git.Repository) (*git.Ignore, error) {
+	excludesFile, err := lastConfigValue(a, "core.excludesFile")
+	if err != nil {
+		return nil, err
+	}
+	return git.LoadIgnore(repo.WorkTree, repo.CommonDir, excludesFile)
+}
+
+// identity returns the user's signature from user.name and user.email, timestamped now.
+func identity(a *app.App) (git.Signature, error) {
+	who := git.Signature{Time: time.Now()}
+	for key, field := range map[string]*string{"user.name": &who.Name, "user.email": &who.Email} {
+		values, err := a.Git.GetConfigValues(key)
+		if err != nil {
+			return git.Signature{}, err
+		}
+		if len(values) == 0 {
+			return git.Signature{}, fmt.Errorf("%s is not set, configure it with git config --global %s", key, key)
+		}
+		*field = values[len(values)-1]
+	}
+	return who, nil
+}
+
+// timeTracking returns where checkpoints are tracked according to plain.timeTracking, and a tracker
+// honouring plain.sessionIdle.
+func timeTracking(a *app.App, repo *git.Repository) (timetrack.Mode, *timetrack.Tracker, error) {
+	values, err := a.Git.GetConfigValues("plain.timeTracking")
+	if err != nil || len(values) == 0 {
+		return timetrack.Off, nil, err
+	}
+	mode, err := timetrack.ParseMode(values[len(values)-1])
+	if err != nil || mode == timetrack.Off {
+		return timetrack.Off, nil, err
+	}
+
+	tracker := timetrack.NewTracker(repo.GitDir)
+
+	idle, err := a.Git.GetConfigValues("plain.sessionIdle")
+	if err != nil {
+		return timetrack.Off, nil, err
+	}
+	if len(idle) > 0 {
+		tracker.Idle, err = time.ParseDuration(idle[len(idle)-1])
+		if err != nil {
+			return timetrack.Off, nil, fmt.Errorf("bad plain.sessionIdle: %w", err)
+		}
 	}
-	return checkpointCmd
+	return mode, tracker, nil
 }
@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/sim-deos/plain/internal/app"
+	"github.com/sim-deos/plain/internal/forge"
+	"github.com/sim-deos/plain/internal/git"
+
+	"github.com/spf13/cobra"
+)
+
+// checksPollInterval is how often --watch asks the forge for updated check states.
+const checksPollInterval = 10 * time.Second
+
+func NewChecksCmd(a *app.App) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "checks",
+		Short: "Shows CI check status for this feature's head commit",
+		Long: `Lists the CI checks the forge reports for the commit at the tip of your current feature.
+		With --watch, plain keeps polling and prints each check as its state changes until none are
+		pending. With --rerun-failed, failed checks are re-triggered first. The command fails if any
+		check has failed.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error { return runChecks(a, cmd, args) },
+	}
+	c.Flags().BoolP("watch", "w", false, "Keep polling until every check has finished")
+	c.Flags().Bool("rerun-failed", false, "Re-trigger failed checks")
+	return c
+}
+
+func runChecks(a *app.App, cmd *cobra.Command, args []string) error {
+	watch, _ := cmd.Flags().GetBool("watch")
+	rerun, _ := cmd.Flags().GetBool("rerun-failed")
+
+	repo, err := git.OpenRepository()
+	if err != nil {
+		return err
+	}
+	head, err := repo.ResolveRevision("HEAD")
+	if err != nil {
+		return err
+	}
+
+	client, _, err := forgeClient(a, defaultRemote)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	checks, err := client.Checks(ctx, head)
+	if err != nil {
+		return err
+	}
+	if len(checks) == 0 {
+		fmt.Fprintf(a.Err, "plain: no checks reported for %s\n", head[:7])
+		return nil
+	}
+
+	if rerun {
+		n := 0
+		for _, check := range checks {
+			if check.Status != forge.CheckFailed {
+				continue
+			}
+			if !check.Rerunnable {
+				fmt.Fprintf(a.Err, "plain: %s cannot be re-run from here, see %s\n", check.Name, check.URL)
+				continue
+			}
+			if err := client.RerunCheck(ctx, check); err != nil {
+				return err
+			}
+			n++
+		}
+		fmt.Fprintf(a.Err, "plain: re-triggered %d failed check(s)\n", n)
+		if n > 0 {
+			// Re-run checks report as pending only once the forge picks them up.
+			if checks, err = client.Checks(ctx, head); err != nil {
+				return err
+			}
+		}
+	}
+
+	fmt.Fprintf(a.Err, "plain: checks for %s\n", head[:7])
+	for _, check := range checks {
+		printCheck(a.Out, check)
+	}
+
+	if watch {
+		seen := make(map[string]forge.CheckStatus)
+		for _, check := range checks {
+			seen[check.Name] = check.Status
+		}
+		for pendingChecks(checks) > 0 {
+			time.Sleep(checksPollInterval)
+			if checks, err = client.Checks(ctx, head); err != nil {
+				return err
+			}
+			for _, check := range checks {
+				if status, ok := seen[check.Name]; !ok || status != check.Status {
+					printCheck(a.Out, check)
+					seen[check.Name] = check.Status
+				}
+			}
+		}
+	}
+
+	failed := 0
+	for _, check := range checks {
+		if check.Status == forge.CheckFailed {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d check(s) failed", failed)
+	}
+	if pending := pendingChecks(checks); pending > 0 {
+		fmt.Fprintf(a.Err, "plain: %d check(s) still pending\n", pending)
+		return nil
+	}
+	fmt.Fprintf(a.Err, "plain: all checks passed\n")
+	return nil
+}
+
+func printCheck(w io.Writer, check forge.Check) {
+	fmt.Fprintf(w, "  %-8s %s", check.Status, check.Name)
+	if check.URL != "" && check.Status == forge.CheckFailed {
+		fmt.Fprintf(w, "  %s", check.URL)
+	}
+	fmt.Fprintln(w)
+}
+
+func pendingChecks(checks []forge.Check) int {
+	n := 0
+	for _, check := range checks {
+		if check.Status == forge.CheckPending {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sim-deos/plain/internal/app"
+	"github.com/sim-deos/plain/internal/display"
+	"github.com/sim-deos/plain/internal/forge"
+	"github.com/sim-deos/plain/internal/git"
+
+	"github.com/spf13/cobra"
+)
+
+// commentContextLines is how many lines around a comment's line are shown from the local file.
+const commentContextLines = 2
+
+func NewCommentsCmd(a *app.App) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "comments",
+		Short: "Shows unresolved review comments on this feature's pull request",
+		Long: `Lists the unresolved review threads on this feature's pull request, along with the lines of
+		your local files they refer to. Each thread is numbered so you can act on it:
+		--open N opens the file at the thread's line in your editor, and --reply N posts a reply,
+		taken from --message or written in your editor.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error { return runComments(a, cmd, args) },
+	}
+	c.Flags().Int("open", 0, "Open the file for thread N in your editor")
+	c.Flags().Int("reply", 0, "Reply to thread N")
+	c.Flags().StringP("message", "m", "", "The reply to post with --reply")
+	c.Flags().Bool("all", false, "Include resolved threads")
+	return c
+}
+
+func runComments(a *app.App, cmd *cobra.Command, args []string) error {
+	open, _ := cmd.Flags().GetInt("open")
+	reply, _ := cmd.Flags().GetInt("reply")
+	all, _ := cmd.Flags().GetBool("all")
+
+	branch, err := a.Git.GetCurrentBranch()
+	if err != nil {
+		return err
+	}
+
+	client, _, err := forgeClient(a, defaultRemote)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	pr, err := client.FindPullRequest(ctx, branch)
+	if err != nil {
+		return err
+	}
+
+	threads, err := client.ReviewThreads(ctx, pr)
+	if err != nil {
+		return err
+	}
+
+	var shown []forge.ReviewThread
+	for _, thread := range threads {
+		if all || !thread.Resolved {
+			shown = append(shown, thread)
+		}
+	}
+
+	repo, err := git.OpenRepository()
+	if err != nil {
+		return err
+	}
+
+	pick := func(n int) (forge.ReviewThread, error) {
+		if n < 1 || n > len(shown) {
+			return forge.ReviewThread{}, fmt.Errorf("there is no thread %d, run plain comments to list them", n)
+		}
+		return shown[n-1], nil
+	}
+
+	switch {
+	case open != 0:
+		thread, err := pick(open)
+		if err != nil {
+			return err
+		}
+		return openEditorAt(filepath.Join(repo.WorkTree, filepath.FromSlash(thread.Path)), max(thread.Line, 1))
+	case reply != 0:
+		thread, err := pick(reply)
+		if err != nil {
+			return err
+		}
+		return replyToThread(a, ctx, client, cmd, thread)
+	}
+
+	if len(shown) == 0 {
+		fmt.Fprintf(a.Err, "plain: no unresolved review comments on #%d\n", pr.Number)
+		return nil
+	}
+
+	dates, err := dateFormat(a)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.Err, "plain: %d unresolved thread(s) on #%d %s\n", len(shown), pr.Number, pr.URL)
+	now := time.Now()
+	for i, thread := range shown {
+		printThread(a.Out, repo.WorkTree, i+1, thread, dates, now)
+	}
+	return nil
+}
+
+func printThread(w io.Writer, root string, n int, thread forge.ReviewThread, dates display.DateFormat, now time.Time) {
+	location := thread.Path
+	if thread.Line > 0 {
+		location = fmt.Sprintf("%s:%d", thread.Path, thread.Line)
+	}
+	if thread.Outdated {
+		location += " (outdated)"
+	}
+	if thread.Resolved {
+		location += " (resolved)"
+	}
+	fmt.Fprintf(w, "\n[%d] %s\n", n, location)
+
+	if thread.Line > 0 && !thread.Outdated {
+		for _, line := range fileContext(filepath.Join(root, filepath.FromSlash(thread.Path)), thread.Line) {
+			fmt.Fprintf(w, "    %s\n", line)
+		}
+	}
+
+	for _, comment := range thread.Comments {
+		fmt.Fprintf(w, "  %s (%s):\n", comment.Author, dates.Format(comment.CreatedAt, now))
+		for _, line := range strings.Split(strings.TrimSpace(comment.Body), "\n") {
+			fmt.Fprintf(w, "    %s\n", line)
+		}
+	}
+}
+
+// fileContext returns the lines around line in the file at path, numbered, with line itself marked.
+func fileContext(path string, line int) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan() && n <= line+commentContextLines; n++ {
+		if n < line-commentContextLines {
+			continue
+		}
+		marker := " "
+		if n == line {
+			marker = ">"
+		}
+		lines = append(lines, fmt.Sprintf("%s%4d | %s", marker, n, scanner.Text()))
+	}
+	return lines
+}
+
+func replyToThread(a *app.App, ctx context.Context, client forge.Client, cmd *cobra.Command, thread forge.ReviewThread) error {
+	message, _ := cmd.Flags().GetString("message")
+	if message == "" {
+		var err error
+		message, err = editText("", "plain-reply-*.md")
+		if err != nil {
+			return fmt.Errorf("failed to write reply: %w", err)
+		}
+	}
+
+	message = strings.TrimSpace(message)
+	if message == "" {
+		return fmt.Errorf("reply is empty, nothing was posted")
+	}
+
+	if err := client.ReplyToThread(ctx, thread, message); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.Err, "plain: replied on %s\n", thread.Path)
+	return nil
+}
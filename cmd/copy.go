@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/sim-deos/plain/internal/app"
+	"github.com/sim-deos/plain/internal/git"
+
+	"github.com/spf13/cobra"
+)
+
+func NewCopyCmd(a *app.App) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "copy <commit>",
+		Short: "Copies a commit onto the current feature",
+		Long: `Applies the changes a commit made, on any branch, to the current feature as a new commit with
+		the same author and message, like git cherry-pick.
+		If the changes conflict with the feature, the conflicts are left in the files for you to resolve;
+		then run plain copy --continue, or plain copy --abort to give up. To copy a merge commit, choose
+		which of its parents to compare it against with --mainline.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			resume, _ := cmd.Flags().GetBool("continue")
+			abort, _ := cmd.Flags().GetBool("abort")
+			if resume || abort {
+				return cobra.NoArgs(cmd, args)
+			}
+			return cobra.ExactArgs(1)(cmd, args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error { return runCopy(a, cmd, args) },
+	}
+	c.Flags().IntP("mainline", "m", 0, "The parent, counting from 1, to compare a merge commit against")
+	c.Flags().Bool("continue", false, "Commit the copy once its conflicts are resolved")
+	c.Flags().Bool("abort", false, "Give up on a copy that stopped on conflicts")
+	c.MarkFlagsMutuallyExclusive("continue", "abort")
+	return locksRepo(a, c)
+}
+
+func runCopy(a *app.App, cmd *cobra.Command, args []string) error {
+	repo, err := git.OpenRepository()
+	if err != nil {
+		return err
+	}
+
+	if abort, _ := cmd.Flags().GetBool("abort"); abort {
+		if err := repo.AbortPick(); err != nil {
+			return err
+		}
+		fmt.Fprintln(a.Err, "plain: gave up on the copy")
+		return nil
+	}
+
+	who, err := identity(a)
+	if err != nil {
+		return err
+	}
+
+	var hash string
+	if resume, _ := cmd.Flags().GetBool("continue"); resume {
+		hash, err = repo.ContinuePick(who)
+	} else {
+		mainline, _ := cmd.Flags().GetInt("mainline")
+		hash, err = repo.CherryPick(args[0], who, git.PickOptions{Mainline: mainline})
+	}
+
+	switch {
+	case errors.Is(err, git.ErrPickConflict):
+		return fmt.Errorf("%w\nresolve them, then run plain copy --continue, or plain copy --abort to give up", err)
+	case errors.Is(err, git.ErrEmptyPick):
+		fmt.Fprintln(a.Err, "plain: nothing to copy, the feature already has those changes")
+		return nil
+	case err != nil:
+		return fmt.Errorf("failed to copy: %w", err)
+	}
+	fmt.Fprintf(a.Err, "plain: copied as %s\n", hash[:7])
+	return nil
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sim-deos/plain/internal/app"
+	"github.com/sim-deos/plain/internal/forge"
+	"github.com/sim-deos/plain/internal/git"
+
+	"github.com/spf13/cobra"
+)
+
+func NewDescribeCmd(a *app.App) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "describe",
+		Short: "Writes a pull request description for this feature",
+		Long: `Generates a pull request title and body from this feature's commits.
+		If the repository has a pull request template, its sections are filled in where possible.
+		A linked issue is found from commit trailers (Fixes #12, or Refs #12 to leave it open) or the
+		branch name (12-fix-login), or can be given with --issue. Use --edit to adjust the result in
+		your editor.
+		Commits merged into the feature from other branches are described too, unless git config
+		plain.firstParent is true.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error { return runDescribe(a, cmd, args) },
+	}
+	addPullRequestFlags(c)
+	c.Flags().BoolP("edit", "e", false, "Edit the description before printing it")
+	return c
+}
+
+func runDescribe(a *app.App, cmd *cobra.Command, args []string) error {
+	edit, _ := cmd.Flags().GetBool("edit")
+
+	desc, err := describeFeature(a, cmd)
+	if err != nil {
+		return err
+	}
+
+	text := desc.Title + "\n\n" + desc.Body
+	if edit {
+		text, err = editText(text, "plain-pr-*.md")
+		if err != nil {
+			return fmt.Errorf("failed to edit description: %w", err)
+		}
+	}
+	fmt.Fprint(a.Out, strings.TrimRight(text, "\n")+"\n")
+	return nil
+}
+
+// describeFeature generates a pull request description for the current branch from the --from and --issue flags.
+func describeFeature(a *app.App, cmd *cobra.Command) (forge.Description, error) {
+	if err := useRecordedBase(cmd, "from"); err != nil {
+		return forge.Description{}, err
+	}
+	base, _ := cmd.Flags().GetString("from")
+	issue, _ := cmd.Flags().GetString("issue")
+
+	branch, err := a.Git.GetCurrentBranch()
+	if err != nil {
+		return forge.Description{}, err
+	}
+
+	repo, err := git.OpenRepository()
+	if err != nil {
+		return forge.Description{}, err
+	}
+
+	parents, err := lineage(a, cmd)
+	if err != nil {
+		return forge.Description{}, err
+	}
+	commits, err := repo.Log(base, "HEAD", parents)
+	if err != nil {
+		return forge.Description{}, fmt.Errorf("failed to read feature commits: %w", err)
+	}
+	if len(commits) == 0 {
+		return forge.Description{}, fmt.Errorf("%s has no commits that are not on %s", branch, base)
+	}
+
+	template, err := forge.FindTemplate(repo.WorkTree)
+	if err != nil {
+		return forge.Description{}, fmt.Errorf("failed to read pull request template: %w", err)
+	}
+
+	return forge.Describe(forge.DescribeOptions{Branch: branch, Commits: commits, Issue: issue, Template: template}), nil
+}
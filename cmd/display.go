@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"cmp"
+
+	"github.com/sim-deos/plain/internal/app"
+	"github.com/sim-deos/plain/internal/display"
+	"github.com/sim-deos/plain/internal/git"
+
+	"github.com/spf13/cobra"
+)
+
+// charset returns the glyphs to draw graphs with, from git config plain.charset or the display.charset
+// setting, detecting what the terminal supports unless it is set to unicode or ascii.
+func charset(a *app.App) (display.Charset, error) {
+	value, err := lastConfigValue(a, "plain.charset")
+	if err != nil {
+		return display.Charset{}, err
+	}
+	return display.ParseCharset(cmp.Or(value, a.Settings.Charset))
+}
+
+// dateFormat returns how to show times, from git config plain.dateFormat or the display.date-format
+// setting.
+func dateFormat(a *app.App) (display.DateFormat, error) {
+	value, err := lastConfigValue(a, "plain.dateFormat")
+	if err != nil {
+		return display.DateFormat{}, err
+	}
+	return display.ParseDateFormat(cmp.Or(value, a.Settings.DateFormat))
+}
+
+// lineage returns which commits under merges to list: only the feature's own line, following first
+// parents, with --first-parent where the command has it or with git config plain.firstParent, and the
+// commits merged in from other branches as well otherwise.
+func lineage(a *app.App, cmd *cobra.Command) (git.Lineage, error) {
+	if flag := cmd.Flags().Lookup("first-parent"); flag != nil && flag.Changed {
+		if on, _ := cmd.Flags().GetBool("first-parent"); on {
+			return git.FirstParent, nil
+		}
+		return git.AllParents, nil
+	}
+	value, err := lastConfigValue(a, "plain.firstParent")
+	if err != nil || value == "" || isFalse(value) {
+		return git.AllParents, err
+	}
+	return git.FirstParent, nil
+}
+
+// lastConfigValue returns the value of key that git config would use, or "" if it is not set. A key of
+// plain's own, like plain.gitTimeout, is overridden by its environment variable, like PLAIN_GIT_TIMEOUT,
+// as [NewRootCmd] arranges.
+func lastConfigValue(a *app.App, key string) (string, error) {
+	values, err := a.Git.GetConfigValues(key)
+	if err != nil || len(values) == 0 {
+		return "", err
+	}
+	return values[len(values)-1], nil
+}
@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sim-deos/plain/internal/app"
+	"github.com/sim-deos/plain/internal/diff"
+	"github.com/sim-deos/plain/internal/doctor"
+	"github.com/sim-deos/plain/internal/git"
+
+	"github.com/spf13/cobra"
+)
+
+func NewDoctorCmd(a *app.App) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "doctor",
+		Short: "Checks the repository and the feature's commits for problems",
+		Long: `Checks the repository for damage the way git fsck does: every loose object is hashed to make
+		sure it is intact, commits, trees, and tags are checked for the layout git writes and for missing
+		objects, and every ref must point to an object that exists. Objects in pack files aren't checked.
+
+		Then looks through the commits of the current feature for ones that are probably mistakes, and
+		explains each problem and how to fix it: commits dated in the future, commits made with an email
+		other than git config user.email, commits that change more than plain.doctorMaxFiles files (100
+		by default) or add more than plain.doctorMaxSize of data (5m), merges of other branches into the
+		feature, and octopus merges of several branches at once.
+
+		With --fix, an undamaged repository is also cleaned up: loose objects that nothing refers to
+		any more and that are older than plain.pruneExpire (2w by default) are deleted, and the
+		remaining loose objects are moved into a pack, which keeps reading history fast.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error { return runDoctor(a, cmd) },
+	}
+	c.Flags().StringP("from", "f", "main", "Base branch the feature started from")
+	c.Flags().Bool("fix", false, "Delete unreachable objects and pack the loose ones")
+	return c
+}
+
+func runDoctor(a *app.App, cmd *cobra.Command) error {
+	if err := useRecordedBase(cmd, "from"); err != nil {
+		return err
+	}
+	base, _ := cmd.Flags().GetString("from")
+	fix, _ := cmd.Flags().GetBool("fix")
+
+	repo, err := git.OpenRepository()
+	if err != nil {
+		return err
+	}
+	if fix {
+		unlock, err := lockRepo(a, cmd)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+	config, err := repo.Config()
+	if err != nil {
+		return err
+	}
+	maxFiles, err := config.Int("plain.doctorMaxFiles", doctor.DefaultMaxFiles)
+	if err != nil {
+		return err
+	}
+	maxSize, err := config.Int("plain.doctorMaxSize", doctor.DefaultMaxBytes)
+	if err != nil {
+		return err
+	}
+	email, err := lastConfigValue(a, "user.email")
+	if err != nil {
+		return err
+	}
+
+	damaged, err := reportDamage(a, repo)
+	if err != nil {
+		return err
+	}
+	if fix {
+		if damaged {
+			fmt.Fprintln(a.Err, "plain: not cleaning up until the damage is repaired")
+		} else if err := cleanUp(a, repo); err != nil {
+			return err
+		}
+	}
+
+	commits, err := repo.CommitsBetween(base, "HEAD")
+	if err != nil {
+		return fmt.Errorf("failed to read feature commits: %w", err)
+	}
+	findings, err := doctor.Check(repo, commits, doctor.Options{Email: email, Base: base, MaxFiles: int(maxFiles), MaxBytes: maxSize})
+	if err != nil {
+		return fmt.Errorf("failed to check feature commits: %w", err)
+	}
+
+	if len(findings) == 0 {
+		fmt.Fprintf(a.Err, "plain: found no problems in %d commit(s) since %s\n", len(commits), base)
+		return nil
+	}
+	fmt.Fprintf(a.Err, "plain: found %d problem(s) in %d commit(s) since %s\n", len(findings), len(commits), base)
+	for _, finding := range findings {
+		summary, _, _ := strings.Cut(finding.Commit.Message, "\n")
+		fmt.Fprintf(a.Out, "\n  %s %s\n", shortHash(finding.Commit.Hash), summary)
+		fmt.Fprintf(a.Out, "    %s: %s\n", finding.Kind, finding.Problem)
+		fmt.Fprintf(a.Out, "    fix: %s\n", finding.Fix)
+	}
+	return nil
+}
+
+// reportDamage prints what [git.Repository.Verify] finds wrong with the repository's objects and refs,
+// and reports whether it found anything.
+func reportDamage(a *app.App, repo *git.Repository) (bool, error) {
+	report, err := repo.Verify()
+	if err != nil {
+		return false, fmt.Errorf("failed to check the repository: %w", err)
+	}
+	skipped := ""
+	if report.Packs > 0 {
+		skipped = fmt.Sprintf(" (%d pack file(s) not checked)", report.Packs)
+	}
+	if report.OK() {
+		fmt.Fprintf(a.Err, "plain: checked %d object(s) and %d ref(s)%s, found no damage\n", report.Objects, report.Refs, skipped)
+		return false, nil
+	}
+	fmt.Fprintf(a.Err, "plain: found %d problem(s) in %d object(s) and %d ref(s)%s\n", len(report.Problems), report.Objects, report.Refs, skipped)
+	for _, problem := range report.Problems {
+		fmt.Fprintf(a.Out, "  %s %s: %s\n", problem.Kind, problem.Object, problem.Detail)
+	}
+	fmt.Fprintln(a.Err, "plain: restore damaged objects from another clone, or run git fsck for details")
+	return true, nil
+}
+
+// cleanUp deletes unreachable loose objects older than git config plain.pruneExpire, then packs the
+// loose objects that are left.
+func cleanUp(a *app.App, repo *git.Repository) error {
+	expiry := git.DefaultPruneExpiry
+	value, err := lastConfigValue(a, "plain.pruneExpire")
+	if err != nil {
+		return err
+	}
+	if value != "" {
+		if expiry, err = parseAge(value); err != nil {
+			return fmt.Errorf("invalid plain.pruneExpire %q: %w", value, err)
+		}
+	}
+
+	pruned, err := repo.Prune(time.Now().Add(-expiry))
+	if err != nil {
+		return fmt.Errorf("failed to prune unreachable objects: %w", err)
+	}
+	fmt.Fprintf(a.Err, "plain: deleted %d unreachable object(s), freeing %s\n", pruned.Objects, diff.Size(pruned.Bytes))
+
+	packed, err := repo.Repack()
+	if err != nil {
+		return fmt.Errorf("failed to pack loose objects: %w", err)
+	}
+	if packed.Objects > 0 {
+		fmt.Fprintf(a.Err, "plain: packed %d loose object(s) into %s\n", packed.Objects, filepath.Base(packed.Pack))
+	} else {
+		fmt.Fprintln(a.Err, "plain: no loose objects to pack")
+	}
+	return nil
+}
@@ -1,12 +1,14 @@
-/*
-Copyright © 2025 NAME HERE <EMAIL ADDRESS>
-*/
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/sim-deos/plain/internal/app"
+	"github.com/sim-deos/plain/internal/forge"
+	"github.com/sim-deos/plain/internal/git"
 
 	"github.com/spf13/cobra"
 )
@@ -14,21 +16,225 @@ import (
 func NewDoneCmd(a *app.App) *cobra.Command {
 	doneCmd := &cobra.Command{
 		Use:   "done",
-		Short: "A brief description of your command",
-		Long:  `Not yet implemented`,
-		Run: func(cmd *cobra.Command, args []string) {
-			dirty, err := a.Git.IsBranchDirty()
-			if err != nil {
-				fmt.Println(err.Error())
-				return
-			}
+		Short: "Finishes the current feature",
+		Long: `Finishes the current feature by merging it into the branch given with --into, or else the
+		one it started from, as plain start recorded it, or main.
+		When the feature simply builds on that branch, the branch is moved up to it and you stay on the
+		feature. Otherwise plain switches to the branch and merges the feature with git merge.
+		With --pr, pushes the feature and opens a pull request for review instead. Add --auto-merge to
+		have the forge merge it as soon as its required checks and reviews pass.
+
+		When you are logged in to the forge, plain follows the branch's protection rules: a branch that
+		only takes pull requests gets one instead of a local merge, a branch that requires linear history
+		has the feature rebased onto it rather than merged, and auto-merge squashes rather than creating
+		a merge commit unless --merge-method says otherwise.
+
+		A team's .plain/team.toml can add to those rules; see plain help settings for it, your own
+		defaults, and what overrides them.
+
+		A feature can't be finished while a merge, rebase, or other git operation is in progress; plain
+		says how to finish or undo it first. It also warns when the last git fetch brought in commits
+		for the branch that it doesn't have yet.`,
+		RunE: func(cmd *cobra.Command, args []string) error { return runDone(a, cmd, args) },
+	}
+	doneCmd.Flags().String("into", "main", "The branch to merge the feature into")
+	doneCmd.Flags().Bool("pr", false, "Open a pull request instead of merging locally")
+	doneCmd.Flags().Bool("auto-merge", false, "Merge the pull request automatically once checks pass")
+	doneCmd.Flags().String("merge-method", "merge", "How auto-merge brings the feature in: merge, squash, or rebase")
+	addPullRequestFlags(doneCmd)
+	return needsGit(locksRepo(a, doneCmd), "merge and push the feature")
+}
+
+func runDone(a *app.App, cmd *cobra.Command, args []string) error {
+	openPR, _ := cmd.Flags().GetBool("pr")
+	autoMerge, _ := cmd.Flags().GetBool("auto-merge")
+	methodName, _ := cmd.Flags().GetString("merge-method")
+
+	if autoMerge && !openPR {
+		return fmt.Errorf("--auto-merge only applies with --pr")
+	}
+
+	repo, err := git.OpenRepository()
+	if err != nil {
+		return err
+	}
+	if err := checkIdle(repo, "finish the feature"); err != nil {
+		return err
+	}
+
+	for _, flag := range []string{"into", "from"} {
+		if err := useRecordedBase(cmd, flag); err != nil {
+			return err
+		}
+	}
+	into, _ := cmd.Flags().GetString("into")
+	target := into
+	if openPR {
+		target, _ = cmd.Flags().GetString("from")
+	}
+	policy, err := teamPolicy(a, repo)
+	if err != nil {
+		return err
+	}
+	protection := policy.Rules(target, branchProtection(a, target))
+
+	if !openPR && protection.PullRequestsOnly {
+		fmt.Fprintf(a.Err, "plain: %s only accepts pull requests, opening one instead\n", into)
+		if !cmd.Flags().Changed("from") {
+			cmd.Flags().Set("from", into)
+		}
+		openPR = true
+	}
+	if !openPR {
+		if len(protection.RequiredChecks) > 0 {
+			fmt.Fprintf(a.Err, "plain: warning: %s requires %s to pass, so the forge may reject pushing the merge\n", into, strings.Join(protection.RequiredChecks, ", "))
+		}
+		return mergeFeature(a, into, protection.LinearHistory)
+	}
+
+	method, err := forge.ParseMergeMethod(methodName)
+	if err != nil {
+		return err
+	}
+	if !cmd.Flags().Changed("merge-method") && policy.MergeStrategy != "" {
+		method = policy.MergeStrategy
+	}
+	if autoMerge && !policy.Allows(method) {
+		return fmt.Errorf("the team merges with %s, so --merge-method %s isn't allowed", policy.MergeStrategy, method)
+	}
+	if autoMerge && protection.LinearHistory && method == forge.MergeCommit {
+		if cmd.Flags().Changed("merge-method") {
+			fmt.Fprintf(a.Err, "plain: warning: %s requires linear history, so the forge will refuse to merge with a merge commit\n", target)
+		} else {
+			method = forge.Squash
+		}
+	}
+
+	client, pr, err := openPullRequest(a, cmd, false)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(a.Err, "plain: opened pull request #%d: %s\n", pr.Number, pr.URL)
+	var needs []string
+	if protection.RequiredReviews > 0 {
+		needs = append(needs, fmt.Sprintf("%d approving review(s)", protection.RequiredReviews))
+	}
+	if len(protection.RequiredChecks) > 0 {
+		needs = append(needs, strings.Join(protection.RequiredChecks, ", ")+" to pass")
+	}
+	if len(needs) > 0 {
+		fmt.Fprintf(a.Err, "plain: #%d needs %s before it can be merged\n", pr.Number, strings.Join(needs, " and "))
+	}
+
+	if autoMerge {
+		if err := client.EnableAutoMerge(context.Background(), pr, method); err != nil {
+			return err
+		}
+		fmt.Fprintf(a.Err, "plain: #%d will be merged (%s) once its checks pass\n", pr.Number, method)
+	}
+	return nil
+}
+
+// branchProtection returns the rules the forge enforces on branch, warning about local git config that
+// works against them. It gives up quietly when the forge can't be reached or the user isn't logged in,
+// since plain then works as it would without a forge.
+func branchProtection(a *app.App, branch string) forge.BranchProtection {
+	client, repo, err := forgeClient(a, defaultRemote)
+	if err != nil {
+		return forge.BranchProtection{}
+	}
+	protection, err := client.BranchProtection(context.Background(), branch)
+	if err != nil {
+		fmt.Fprintf(a.Err, "plain: warning: cannot read the protection rules of %s: %v\n", branch, err)
+		return forge.BranchProtection{}
+	}
+
+	if protection.LinearHistory {
+		if ff, _ := lastConfigValue(a, "merge.ff"); ff == "false" {
+			fmt.Fprintf(a.Err, "plain: warning: git config merge.ff is false, but %s on %s requires linear history\n", branch, repo.Host)
+		}
+	}
+	return protection
+}
+
+// mergeFeature merges the current feature into the branch into, moving into's ref when that is all it
+// takes. When the histories diverged it falls back to git merge, or, if into requires linear history,
+// rebases the feature onto into first so into can still be moved up to it.
+func mergeFeature(a *app.App, into string, linear bool) error {
+	status, err := a.Git.Status()
+	if err != nil {
+		return err
+	}
+	if status.Dirty() {
+		return fmt.Errorf("cannot merge the feature: its %w", git.ErrDirtyWorkTree)
+	}
+
+	feature := status.Branch.Head
+	if feature == "" || feature == into {
+		return fmt.Errorf("switch to the feature to merge into %s first", into)
+	}
+
+	repo, err := git.OpenRepository()
+	if err != nil {
+		return err
+	}
+	who, err := identity(a)
+	if err != nil {
+		return err
+	}
+	warnBehindFetch(a, repo, into)
 
-			if dirty {
-				fmt.Println("branch is dirty")
-			} else {
-				fmt.Println("branch is clean")
+	result, err := repo.FastForward(into, feature, who)
+	if err != nil {
+		return fmt.Errorf("failed to merge into %s: %w", into, err)
+	}
+	switch {
+	case result == git.MergeUpToDate:
+		fmt.Fprintf(a.Err, "plain: %s already contains %s\n", into, feature)
+	case result == git.MergeFastForwarded:
+		fmt.Fprintf(a.Err, "plain: fast-forwarded %s to %s\n", into, feature)
+	case linear:
+		if _, err := repo.Rebase(into, "", who); err != nil {
+			if errors.Is(err, git.ErrPickConflict) {
+				return fmt.Errorf("%s requires linear history, and rebasing %s onto it stopped: %w\n"+
+					"Resolve the conflicts, run plain onto --continue, then plain done again", into, feature, err)
+			}
+			return err
+		}
+		if _, err := repo.FastForward(into, feature, who); err != nil {
+			return fmt.Errorf("failed to merge into %s: %w", into, err)
+		}
+		fmt.Fprintf(a.Err, "plain: rebased %s onto %s and fast-forwarded %s, which requires linear history\n", feature, into, into)
+	default:
+		if err := a.Git.SwitchBranch(into); err != nil {
+			return fmt.Errorf("failed to switch to %s: %w", into, err)
+		}
+		if err := a.Git.Merge(feature); err != nil {
+			if msg, ok, _ := repo.MergeMessage(); ok && len(msg.Conflicts) > 0 {
+				return fmt.Errorf("merging %s into %s stopped on conflicts in %s\n"+
+					"resolve them and commit with git commit, or run git merge --abort to give up", feature, into, strings.Join(msg.Conflicts, ", "))
 			}
-		},
+			return fmt.Errorf("failed to merge %s into %s: %w", feature, into, err)
+		}
+		fmt.Fprintf(a.Err, "plain: merged %s into %s\n", feature, into)
+	}
+	return nil
+}
+
+// warnBehindFetch warns when the last fetch brought in commits for the branch into that it doesn't have
+// yet, since the feature would then be merged into an out of date copy of it.
+func warnBehindFetch(a *app.App, repo *git.Repository, into string) {
+	fetched, err := repo.FetchHead()
+	if err != nil {
+		return
+	}
+	for _, ref := range fetched {
+		if ref.Kind != "branch" || ref.Name != into {
+			continue
+		}
+		if base, err := repo.MergeBase(into, ref.Hash); err == nil && base != ref.Hash {
+			fmt.Fprintf(a.Err, "plain: warning: %s is behind the %s last fetched from %s, update it to include others' work\n", into, shortHash(ref.Hash), ref.Remote)
+		}
+		return
 	}
-	return doneCmd
 }
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// editText opens text in the user's editor ($VISUAL, then $EDITOR, then vi) and returns the edited result.
+func editText(text, pattern string) (string, error) {
+	f, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.WriteString(text); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	if err := runEditor(f.Name()); err != nil {
+		return "", err
+	}
+
+	edited, err := os.ReadFile(f.Name())
+	return string(edited), err
+}
+
+// openEditorAt opens path in the user's editor with the cursor on line.
+func openEditorAt(path string, line int) error {
+	editor := editorCommand()
+	switch filepath.Base(editor[0]) {
+	case "code", "code-insiders", "subl", "zed":
+		return runEditor("--goto", fmt.Sprintf("%s:%d", path, line))
+	default:
+		// vi, vim, nvim, nano, emacs, and most terminal editors accept +line
+		return runEditor(fmt.Sprintf("+%d", line), path)
+	}
+}
+
+func runEditor(args ...string) error {
+	editor := editorCommand()
+	editCmd := exec.Command(editor[0], append(editor[1:], args...)...)
+	editCmd.Stdin = os.Stdin
+	editCmd.Stdout = os.Stdout
+	editCmd.Stderr = os.Stderr
+	return editCmd.Run()
+}
+
+// editorCommand returns the user's editor ($VISUAL, then $EDITOR, then vi) split into its arguments,
+// since editors are often configured with flags, e.g. "code --wait".
+func editorCommand() []string {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	return strings.Fields(editor)
+}
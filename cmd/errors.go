@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/sim-deos/plain/internal/forge"
+	"github.com/sim-deos/plain/internal/git"
+	"github.com/sim-deos/plain/internal/oplock"
+	"github.com/sim-deos/plain/internal/secret"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	errBranchExists = errors.New("branch already exists")
+	errDetachedHead = errors.New("HEAD is detached")
+)
+
+// errorHints says what to do about failures people can fix themselves, whichever command they come from.
+// A failure is matched by the error it wraps, and the first match is the hint shown.
+var errorHints = []struct {
+	err  error
+	hint string
+}{
+	{git.ErrDirtyWorkTree, "checkpoint the changes with plain checkpoint first"},
+	{git.ErrUnmerged, "resolve the conflicts and checkpoint the result first"},
+	{git.ErrNoUpstream, "publish the feature with plain publish, which pushes it and tracks it"},
+	{git.ErrNotRepo, "run plain init to create a repository here, or change to one"},
+	{git.ErrNoGit, "install git from https://git-scm.com, or use a command that works without it"},
+	{git.ErrIndexLocked, "if no other git command is running, remove .git/index.lock"},
+	{git.ErrRefLocked, "if no other git command is running, remove the ref's .lock file"},
+	{git.ErrUnknownHost, "connect once with ssh to check the host's key and add it to known_hosts"},
+	{oplock.ErrLocked, "wait for it to finish, or if it was interrupted on another machine, remove .git/" + oplock.File},
+	{errDetachedHead, "switch to a feature with git switch first"},
+	{secret.ErrNotFound, "log in to the forge with plain auth login"},
+	{forge.ErrNoPullRequest, "open one with plain publish"},
+}
+
+// hintedError is a failure that says what to do about it where it happens, which takes the place of any
+// hint from errorHints.
+type hintedError struct {
+	err  error
+	hint string
+}
+
+func (e *hintedError) Error() string { return e.err.Error() }
+func (e *hintedError) Unwrap() error { return e.err }
+
+// withHint returns err with hint saying what to do about it.
+func withHint(err error, hint string) error {
+	return &hintedError{err: err, hint: hint}
+}
+
+// errorHint returns what to do about err, or "" when plain doesn't know.
+func errorHint(err error) string {
+	var hinted *hintedError
+	if errors.As(err, &hinted) {
+		return hinted.hint
+	}
+	var timeout *git.TimeoutError
+	if errors.As(err, &timeout) {
+		return "allow git longer with git config plain.gitTimeout, e.g. 30m, or 0 for no limit"
+	}
+	for _, known := range errorHints {
+		if errors.Is(err, known.err) {
+			return known.hint
+		}
+	}
+	return ""
+}
+
+// printError prints the error a command failed with, followed by what to do about it when plain knows.
+func printError(w io.Writer, err error) {
+	fmt.Fprintf(w, "plain: error: %s\n", err)
+	if hint := errorHint(err); hint != "" {
+		fmt.Fprintf(w, "plain: hint: %s\n", hint)
+	}
+}
+
+// silenceErrors leaves printing the errors commands under root fail with to [Execute]. Only mistakes in
+// how a command was called, like a missing argument, are caught before its pre-run, so only those are
+// followed by its usage.
+func silenceErrors(root *cobra.Command) {
+	root.SilenceErrors = true
+	preRun := root.PersistentPreRunE
+	root.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		if preRun != nil {
+			return preRun(cmd, args)
+		}
+		return nil
+	}
+}
+
+// Execute runs root with the command line's arguments, printing any error it fails with as printError
+// does, and returns the command that ran.
+func Execute(root *cobra.Command) (*cobra.Command, error) {
+	c, err := root.ExecuteC()
+	if err != nil {
+		printError(root.ErrOrStderr(), err)
+	}
+	return c, err
+}
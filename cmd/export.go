@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/sim-deos/plain/internal/app"
+	"github.com/sim-deos/plain/internal/export"
+	"github.com/sim-deos/plain/internal/git"
+
+	"github.com/spf13/cobra"
+)
+
+func NewExportCmd(a *app.App) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "export (--sqlite <file> | --sql <file>)",
+		Short: "Exports the repository's history for querying",
+		Long: `Writes every commit reachable from a ref, with its parents, the refs themselves, and the lines
+		each commit added and deleted per file, to a SQLite database: plain export --sqlite history.db.
+		The database is replaced if it exists. Merges are compared against their first parent.
+		Building the database needs the sqlite3 command; without it, --sql writes the SQL script that
+		creates the database instead, to a file or to standard output with -.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error { return runExport(a, cmd) },
+	}
+	c.Flags().String("sqlite", "", "the SQLite database to write")
+	c.Flags().String("sql", "", "the file to write a SQL script to, or - for standard output")
+	c.MarkFlagsOneRequired("sqlite", "sql")
+	c.MarkFlagsMutuallyExclusive("sqlite", "sql")
+	return c
+}
+
+func runExport(a *app.App, cmd *cobra.Command) error {
+	repo, err := git.OpenRepository()
+	if err != nil {
+		return err
+	}
+
+	history, err := export.Collect(repo)
+	if err != nil {
+		return fmt.Errorf("failed to read history: %w", err)
+	}
+
+	if path, _ := cmd.Flags().GetString("sqlite"); path != "" {
+		if err := export.WriteSQLite(path, history); err != nil {
+			if errors.Is(err, export.ErrNoSQLite) {
+				return fmt.Errorf("%w (plain export --sql history.sql writes the script sqlite3 would run)", err)
+			}
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		fmt.Fprintf(a.Err, "plain: exported %d commit(s) to %s\n", len(history.Commits), path)
+		return nil
+	}
+
+	path, _ := cmd.Flags().GetString("sql")
+	if path == "-" {
+		return export.WriteSQL(a.Out, history)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := export.WriteSQL(f, history); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.Err, "plain: exported %d commit(s) to %s\n", len(history.Commits), path)
+	return nil
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sim-deos/plain/internal/app"
+	"github.com/sim-deos/plain/internal/git"
+
+	"github.com/spf13/cobra"
+)
+
+func NewFingerprintCmd(a *app.App) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "fingerprint",
+		Short: "Prints a token that changes whenever the repository does",
+		Long: `Prints a single token built from the commit HEAD points to, when the index was last written,
+		and a checksum of every ref, where HEAD points, and any merge or rebase in progress. It changes
+		whenever something is committed, staged, fetched, or checked out, so editor plugins and scripts
+		can poll it and only refresh when it does. Only refs and file times are read, never history, so
+		it stays fast in any repository. Edits to files that aren't staged don't change it.
+
+		plain's own caches, like the one behind plain prompt, are invalidated by the same token.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error { return runFingerprint(a, cmd) },
+	}
+	c.Flags().Bool("json", false, "Print the parts of the fingerprint as JSON")
+	return c
+}
+
+type fingerprintJSON struct {
+	Fingerprint string    `json:"fingerprint"`
+	Head        string    `json:"head"`
+	Index       time.Time `json:"index"`
+	Refs        string    `json:"refs"`
+}
+
+func runFingerprint(a *app.App, cmd *cobra.Command) error {
+	asJSON, _ := cmd.Flags().GetBool("json")
+
+	repo, err := git.OpenRepository()
+	if err != nil {
+		return err
+	}
+	fingerprint, err := repo.Fingerprint()
+	if err != nil {
+		return fmt.Errorf("failed to fingerprint the repository: %w", err)
+	}
+	if asJSON {
+		return printJSON(a.Out, fingerprintJSON{Fingerprint: fingerprint.String(), Head: fingerprint.Head, Index: fingerprint.Index, Refs: fingerprint.Refs})
+	}
+	fmt.Fprintln(a.Out, fingerprint)
+	return nil
+}
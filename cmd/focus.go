@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/sim-deos/plain/internal/focus"
+	"github.com/sim-deos/plain/internal/git"
+
+	"github.com/spf13/cobra"
+)
+
+// completeFeature completes a command's only argument with the names of the features in plain.focus.
+func completeFeature(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return completeFeatureFlag(cmd, args, toComplete)
+}
+
+// completeFeatureFlag completes a flag's value with the names of the features in plain.focus, or with
+// every feature when none in focus starts with what was typed so far.
+func completeFeatureFlag(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	repo, err := git.OpenRepository()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	branches, err := repo.Branches()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	current, _ := repo.CurrentBranch()
+	// A focus that can't be read shouldn't stop completion, it just doesn't narrow it.
+	f, _ := focus.Load(repo, current)
+
+	var inFocus, all []string
+	for _, branch := range branches {
+		if !strings.HasPrefix(branch.Name, toComplete) {
+			continue
+		}
+		all = append(all, branch.Name)
+		if f.Includes(branch.Name, branch.Head) {
+			inFocus = append(inFocus, branch.Name)
+		}
+	}
+	if len(inFocus) == 0 {
+		return all, cobra.ShellCompDirectiveNoFileComp
+	}
+	return inFocus, cobra.ShellCompDirectiveNoFileComp
+}
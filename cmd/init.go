@@ -13,7 +13,7 @@ func NewInitCmd(a *app.App) *cobra.Command {
 		Long:  `Not yet implemented`,
 		RunE:  func(cmd *cobra.Command, args []string) error { return runInit(a, cmd, args) },
 	}
-	return initCmd
+	return needsGit(initCmd, "create the repository")
 }
 
 func runInit(a *app.App, cmd *cobra.Command, args []string) error {
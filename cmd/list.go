@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sim-deos/plain/internal/app"
+	"github.com/sim-deos/plain/internal/display"
+	"github.com/sim-deos/plain/internal/focus"
+	"github.com/sim-deos/plain/internal/git"
+	"github.com/sim-deos/plain/internal/meta"
+
+	"github.com/spf13/cobra"
+)
+
+func NewListCmd(a *app.App) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "list",
+		Short: "Lists your features",
+		Long: `Lists every feature along with how long ago it was last worked on.
+		Features with no commits for longer than the stale threshold are marked as stale. The threshold
+		defaults to 30 days and can be set with --stale-after, the plain.staleAfter git config key, or
+		the list.stale-after setting (see plain help settings), using values like 12h, 10d, or 2w.
+
+		--porcelain prints one line per feature for scripts: "<current> <last-activity> <state> <name>",
+		where current is * for the checked out feature and . otherwise, last-activity is a Unix
+		timestamp, and state is stale or active. --json prints the same information as JSON.
+
+		--team lists the features everyone has shared with plain meta push instead, with their owner,
+		status, and description, fetched from the remote the metadata is shared on.
+
+		In a repository shared by many people, git config plain.focus narrows the list, plain preview
+		--changed-since, and completion of feature names down to your own features. Set it to mine
+		for the features whose last commit you authored as user.email, to someone's email address for
+		theirs, or to a prefix like ann/ for the features named with it; set it more than once to
+		combine them. The checked out feature is always listed. --all lists everything regardless.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error { return runList(a, cmd, args) },
+	}
+	c.Flags().Bool("stale", false, "Only list stale features")
+	c.Flags().String("stale-after", "30d", "How long a feature can go without commits before it is stale")
+	c.Flags().Bool("team", false, "List the features shared by the whole team")
+	c.Flags().Bool("all", false, "List the features outside plain.focus too")
+	addOutputFlags(c)
+	c.MarkFlagsMutuallyExclusive("team", "porcelain")
+	c.MarkFlagsMutuallyExclusive("team", "stale")
+	return c
+}
+
+type featureJSON struct {
+	Name         string    `json:"name"`
+	Current      bool      `json:"current"`
+	LastActivity time.Time `json:"lastActivity"`
+	Stale        bool      `json:"stale"`
+}
+
+func runList(a *app.App, cmd *cobra.Command, args []string) error {
+	if team, _ := cmd.Flags().GetBool("team"); team {
+		asJSON, _ := cmd.Flags().GetBool("json")
+		return listTeam(a, asJSON)
+	}
+
+	onlyStale, _ := cmd.Flags().GetBool("stale")
+	asJSON, _ := cmd.Flags().GetBool("json")
+	porcelain, _ := cmd.Flags().GetBool("porcelain")
+	all, _ := cmd.Flags().GetBool("all")
+
+	threshold, err := staleThreshold(a, cmd)
+	if err != nil {
+		return err
+	}
+
+	repo, err := git.OpenRepository()
+	if err != nil {
+		return err
+	}
+
+	branches, err := repo.Branches()
+	if err != nil {
+		return fmt.Errorf("failed to list features: %w", err)
+	}
+
+	current, _ := a.Git.GetCurrentBranch()
+	var inFocus *focus.Focus
+	if !all {
+		if inFocus, err = focus.Load(repo, current); err != nil {
+			return err
+		}
+	}
+	now := time.Now()
+	features := []featureJSON{}
+	hidden := 0
+	for _, branch := range branches {
+		if !inFocus.Includes(branch.Name, branch.Head) {
+			hidden++
+			continue
+		}
+		stale := branch.IsStale(threshold, now)
+		if onlyStale && !stale {
+			continue
+		}
+
+		switch {
+		case asJSON:
+			features = append(features, featureJSON{branch.Name, branch.Name == current, branch.LastActivity(), stale})
+			continue
+		case porcelain:
+			marker, state := ".", "active"
+			if branch.Name == current {
+				marker = "*"
+			}
+			if stale {
+				state = "stale"
+			}
+			fmt.Fprintf(a.Out, "%s %d %s %s\n", marker, branch.LastActivity().Unix(), state, branch.Name)
+			continue
+		}
+
+		marker := " "
+		if branch.Name == current {
+			marker = "*"
+		}
+
+		badge := ""
+		if stale {
+			badge = "  [stale]"
+		}
+		fmt.Fprintf(a.Out, "%s %-30s %s%s\n", marker, branch.Name, display.Age(now.Sub(branch.LastActivity())), badge)
+	}
+
+	if asJSON {
+		return printJSON(a.Out, features)
+	}
+	if hidden > 0 && !porcelain {
+		fmt.Fprintf(a.Err, "  (%d features outside plain.focus hidden, use --all to list them)\n", hidden)
+	}
+	return nil
+}
+
+// listTeam lists the features shared through the metadata ref, along with any recorded locally that
+// aren't shared yet. If the remote can't be reached, the copy fetched last time is used.
+func listTeam(a *app.App, asJSON bool) error {
+	repo, err := git.OpenRepository()
+	if err != nil {
+		return err
+	}
+	remote, err := metaRemote(a, nil)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fetchMeta(a, repo, remote); err != nil {
+		fmt.Fprintf(a.Err, "plain: %v, showing what was last fetched\n", err)
+	}
+	shared, err := meta.ReadCommit(repo, metaTrackingRef(remote))
+	if err != nil {
+		return fmt.Errorf("failed to read the shared features: %w", err)
+	}
+	local, err := meta.NewStore(repo.CommonDir).All()
+	if err != nil {
+		return fmt.Errorf("failed to read feature metadata: %w", err)
+	}
+	features := meta.Combine(shared, local)
+
+	if asJSON {
+		if features == nil {
+			features = []meta.Feature{}
+		}
+		return printJSON(a.Out, features)
+	}
+	if len(features) == 0 {
+		fmt.Fprintf(a.Err, "plain: nobody has shared any features on %s yet, share yours with plain meta push\n", remote)
+		return nil
+	}
+
+	now := time.Now()
+	for _, feature := range features {
+		owner, status := feature.Owner, feature.Status
+		if owner == "" {
+			owner = "-"
+		}
+		if status == "" {
+			status = "-"
+		}
+		summary := feature.Description
+		if feature.Issue != "" {
+			summary = strings.TrimSpace(feature.Issue + " " + summary)
+		}
+		fmt.Fprintf(a.Out, "  %-30s %-20s %-12s %-8s %s\n", feature.Name, owner, status, display.Age(now.Sub(feature.Updated)), summary)
+	}
+	return nil
+}
+
+// staleThreshold reads the stale threshold from the flag, then git config, then what the flag defaults
+// to: the list.stale-after setting, as applySettings set it, or 30 days.
+func staleThreshold(a *app.App, cmd *cobra.Command) (time.Duration, error) {
+	value, _ := cmd.Flags().GetString("stale-after")
+	if !cmd.Flags().Changed("stale-after") {
+		configured, err := lastConfigValue(a, "plain.staleAfter")
+		if err != nil {
+			return 0, err
+		}
+		value = cmp.Or(configured, value)
+	}
+
+	threshold, err := parseAge(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid stale threshold %q: %w", value, err)
+	}
+	return threshold, nil
+}
+
+// parseAge parses a duration, additionally accepting day (d) and week (w) units.
+func parseAge(value string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(value, suffix); ok {
+			count, err := strconv.Atoi(n)
+			if err != nil {
+				return 0, err
+			}
+			return time.Duration(count) * unit, nil
+		}
+	}
+	return time.ParseDuration(value)
+}
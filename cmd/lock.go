@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/sim-deos/plain/internal/app"
+	"github.com/sim-deos/plain/internal/git"
+	"github.com/sim-deos/plain/internal/oplock"
+
+	"github.com/spf13/cobra"
+)
+
+// locksRepo has c hold the repository's operation lock while it runs, so that another plain command
+// can't switch branches or move refs while c does.
+func locksRepo(a *app.App, c *cobra.Command) *cobra.Command {
+	run := c.RunE
+	c.RunE = func(cmd *cobra.Command, args []string) error {
+		unlock, err := lockRepo(a, cmd)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+		return run(cmd, args)
+	}
+	return c
+}
+
+// lockRepo takes the operation lock of the repository plain runs in for cmd and returns what releases
+// it. Outside a repository there is nothing to lock, which cmd reports itself.
+func lockRepo(a *app.App, cmd *cobra.Command) (unlock func(), err error) {
+	repo, err := git.OpenRepository()
+	if err != nil {
+		return func() {}, nil
+	}
+	lock, err := oplock.Acquire(repo.CommonDir, cmd.CommandPath())
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		if err := lock.Release(); err != nil {
+			fmt.Fprintf(a.Err, "plain: warning: failed to release the repository's lock: %v\n", err)
+		}
+	}, nil
+}
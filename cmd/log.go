@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"log/slog"
+	"os"
+
+	"github.com/sim-deos/plain/internal/app"
+	"github.com/sim-deos/plain/internal/git"
+	"github.com/sim-deos/plain/internal/logging"
+
+	"github.com/spf13/cobra"
+)
+
+// addLogging adds --verbose and --debug to root, which log what every command does to standard error
+// as it runs: each git command with its arguments and how long it took, and with --debug, each object
+// file read as well. With git config plain.logFile set to true, commands and the git commands they run
+// are also logged to plain.log in plain's cache directory, whatever the flags, for diagnosing a failure
+// after it happened.
+func addLogging(root *cobra.Command, a *app.App, config func() (*git.Config, error)) {
+	root.PersistentFlags().Bool("verbose", false, "Log every git command plain runs, to standard error")
+	root.PersistentFlags().Bool("debug", false, "Log every object file plain reads too, to standard error")
+
+	preRun := root.PersistentPreRunE
+	root.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		c, err := config()
+		if err != nil {
+			return err
+		}
+		if err := startLogging(a, cmd, c); err != nil {
+			return err
+		}
+		if preRun != nil {
+			return preRun(cmd, args)
+		}
+		return nil
+	}
+}
+
+// startLogging points a.Log, and the git layer's logging, at where cmd's flags and git config
+// plain.logFile say logs go. Without any of them, nothing is logged.
+func startLogging(a *app.App, cmd *cobra.Command, config *git.Config) error {
+	var handlers []slog.Handler
+	verbose, _ := cmd.Flags().GetBool("verbose")
+	debug, _ := cmd.Flags().GetBool("debug")
+	if verbose || debug {
+		level := slog.LevelInfo
+		if debug {
+			level = slog.LevelDebug
+		}
+		handlers = append(handlers, slog.NewTextHandler(a.Err, &slog.HandlerOptions{Level: level, ReplaceAttr: withoutTime}))
+	}
+
+	if value, _ := config.Get("plain.logFile"); value != "" && !isFalse(value) {
+		file, err := logging.NewFile()
+		if err != nil {
+			return err
+		}
+		// Several commands may log at once, so each says which process it is.
+		handler := slog.NewTextHandler(file, &slog.HandlerOptions{Level: slog.LevelInfo})
+		handlers = append(handlers, handler.WithAttrs([]slog.Attr{slog.Int("pid", os.Getpid())}))
+	}
+
+	if len(handlers) == 0 {
+		return nil
+	}
+	a.Log = slog.New(logging.Tee(handlers...))
+	git.SetLogger(a.Log)
+	a.Log.Info("running plain", "command", cmd.CommandPath())
+	return nil
+}
+
+// withoutTime leaves the time out of records shown as they happen, where it only adds noise.
+func withoutTime(groups []string, attr slog.Attr) slog.Attr {
+	if len(groups) == 0 && attr.Key == slog.TimeKey {
+		return slog.Attr{}
+	}
+	return attr
+}
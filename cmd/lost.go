@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sim-deos/plain/internal/app"
+	"github.com/sim-deos/plain/internal/git"
+
+	"github.com/spf13/cobra"
+)
+
+func NewLostCmd(a *app.App) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "lost [<commit> <feature-name>]",
+		Short: "Finds work that is no longer on any branch",
+		Long: `Lists commits that are no longer reachable from any branch or tag, such as work on a deleted
+		branch or commits discarded by a reset, along with when they were made.
+		To bring one back, pass its hash and a name for the new feature: plain lost <commit> <feature-name>.
+		Dates follow git config plain.dateFormat: iso (the default), iso-strict, rfc, short, us, eu, uk,
+		or relative.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 && len(args) != 2 {
+				return errors.New("expected no arguments, or a commit and a feature name")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error { return runLost(a, cmd, args) },
+	}
+	return c
+}
+
+func runLost(a *app.App, cmd *cobra.Command, args []string) error {
+	repo, err := git.OpenRepository()
+	if err != nil {
+		return err
+	}
+
+	lost, err := repo.LostCommits()
+	if err != nil {
+		return fmt.Errorf("failed to search for lost commits: %w", err)
+	}
+
+	if len(args) == 2 {
+		unlock, err := lockRepo(a, cmd)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+		return restoreLost(a, lost, args[0], args[1])
+	}
+
+	if len(lost) == 0 {
+		fmt.Fprintln(a.Err, "plain: no lost commits found")
+		return nil
+	}
+
+	dates, err := dateFormat(a)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.Err, "plain: found %d lost commit(s)\n", len(lost))
+	now := time.Now()
+	for _, commit := range lost {
+		summary, _, _ := strings.Cut(commit.Message, "\n")
+		fmt.Fprintf(a.Out, "  %s %s  %s\n", commit.DisName(), dates.Format(commit.Committer.Time, now), summary)
+	}
+	fmt.Fprintln(a.Err, "\nrestore one with: plain lost <commit> <feature-name>")
+	return nil
+}
+
+func restoreLost(a *app.App, lost []git.Commit, hash, feature string) error {
+	if len(hash) < git.MinAbbrev {
+		return fmt.Errorf("%q is too short to pick a lost commit, give at least %d digits of its hash", hash, git.MinAbbrev)
+	}
+	var matches []git.Commit
+	for _, commit := range lost {
+		if strings.HasPrefix(commit.Hash, hash) {
+			matches = append(matches, commit)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return fmt.Errorf("%s is not one of the lost commits", hash)
+	case 1:
+	default:
+		var b strings.Builder
+		for _, commit := range matches {
+			summary, _, _ := strings.Cut(commit.Message, "\n")
+			fmt.Fprintf(&b, "\n  %s %s", commit.Hash[:min(len(commit.Hash), max(8, len(hash)+4))], summary)
+		}
+		return fmt.Errorf("%w: %s matches %d lost commits, give more of the hash:%s", git.ErrAmbiguousRevision, hash, len(matches), b.String())
+	}
+
+	commit := matches[0]
+	if err := a.Git.CreateBranch(feature, commit.Hash); err != nil {
+		return fmt.Errorf("failed to create branch: %w", err)
+	}
+	fmt.Fprintf(a.Err, "plain: restored %s as a new feature called %s\n", commit.DisName(), feature)
+	return nil
+}
@@ -0,0 +1,363 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/sim-deos/plain/internal/app"
+	"github.com/sim-deos/plain/internal/git"
+	"github.com/sim-deos/plain/internal/meta"
+
+	"github.com/spf13/cobra"
+)
+
+func NewMetaCmd(a *app.App) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "meta",
+		Short: "Backs up and shares feature metadata",
+		Long: `Moves the metadata plain keeps about each feature, such as its base branch and linked issue,
+		in and out of the repository. It can be exported to JSON and imported again, or shared with the
+		team through the ` + meta.Ref + ` ref: push publishes it to a remote and pull merges what is there.
+		Where both sides have a feature, the copy updated most recently wins. The remote defaults to
+		git config plain.metaRemote, or ` + defaultRemote + `. plain list --team shows what is shared.`,
+	}
+
+	c.AddCommand(newMetaSetCmd(a), newMetaExportCmd(a), newMetaImportCmd(a), newMetaPushCmd(a), newMetaPullCmd(a))
+	return c
+}
+
+func newMetaSetCmd(a *app.App) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "set [<feature>]",
+		Short: "Describes a feature for the team",
+		Long: `Records a feature's description, status, or linked issue, for the current feature unless one
+		is named. Only the values given are changed.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error { return runMetaSet(a, cmd, args) },
+	}
+	c.Flags().String("description", "", "what the feature is for")
+	c.Flags().String("status", "", "where the feature stands, e.g. in-progress, in-review, or blocked")
+	c.Flags().String("issue", "", "the issue the feature is linked to, e.g. PROJ-42")
+	c.Flags().String("owner", "", "who is working on the feature, yourself by default")
+	c.ValidArgsFunction = completeFeature
+	return locksRepo(a, c)
+}
+
+func newMetaExportCmd(a *app.App) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "export [<file>]",
+		Short: "Writes feature metadata as JSON",
+		Long: `Writes the metadata of every feature as JSON to the file, or to standard output without one.
+		With --ref, it is committed to ` + meta.Ref + ` instead.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error { return runMetaExport(a, cmd, args) },
+	}
+	c.Flags().Bool("ref", false, "commit the metadata to "+meta.Ref)
+	return c
+}
+
+func newMetaImportCmd(a *app.App) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "import [<file>]",
+		Short: "Reads feature metadata from JSON",
+		Long: `Reads feature metadata written by plain meta export from the file, or from standard input
+		without one or with -. With --ref, it is read from ` + meta.Ref + ` instead.
+		Features recorded here that were updated more recently than the imported copy are kept.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error { return runMetaImport(a, cmd, args) },
+	}
+	c.Flags().Bool("ref", false, "read the metadata from "+meta.Ref)
+	c.Flags().Bool("replace", false, "forget features that aren't in the imported metadata")
+	return locksRepo(a, c)
+}
+
+func newMetaPushCmd(a *app.App) *cobra.Command {
+	return needsGit(locksRepo(a, &cobra.Command{
+		Use:   "push [<remote>]",
+		Short: "Shares feature metadata through the remote",
+		Long: `Merges the remote's ` + meta.Ref + ` into the local metadata, commits the result to the ref,
+		and pushes it to the remote.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error { return runMetaPush(a, args) },
+	}), "push the metadata")
+}
+
+func newMetaPullCmd(a *app.App) *cobra.Command {
+	return locksRepo(a, &cobra.Command{
+		Use:   "pull [<remote>]",
+		Short: "Merges the feature metadata shared on the remote",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  func(cmd *cobra.Command, args []string) error { return runMetaPull(a, args) },
+	})
+}
+
+func runMetaSet(a *app.App, cmd *cobra.Command, args []string) error {
+	repo, err := git.OpenRepository()
+	if err != nil {
+		return err
+	}
+	name := ""
+	if len(args) > 0 {
+		name = args[0]
+	} else if name, err = a.Git.GetCurrentBranch(); err != nil || name == "" {
+		return errors.New("not on a feature, name the feature to describe")
+	}
+
+	store := meta.NewStore(repo.CommonDir)
+	feature, ok, err := store.Load(name)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if !ok {
+		feature = meta.Feature{Name: name, Created: now}
+	}
+	for flag, field := range map[string]*string{
+		"description": &feature.Description,
+		"status":      &feature.Status,
+		"issue":       &feature.Issue,
+		"owner":       &feature.Owner,
+	} {
+		if cmd.Flags().Changed(flag) {
+			*field, _ = cmd.Flags().GetString(flag)
+		}
+	}
+	if feature.Owner == "" {
+		who, err := identity(a)
+		if err != nil {
+			return err
+		}
+		feature.Owner = who.Name
+	}
+	feature.Updated = now
+
+	if err := store.Save(feature); err != nil {
+		return fmt.Errorf("failed to record feature metadata: %w", err)
+	}
+	fmt.Fprintf(a.Err, "plain: updated %s, share it with plain meta push\n", name)
+	return nil
+}
+
+func runMetaExport(a *app.App, cmd *cobra.Command, args []string) error {
+	repo, err := git.OpenRepository()
+	if err != nil {
+		return err
+	}
+	features, err := meta.NewStore(repo.CommonDir).All()
+	if err != nil {
+		return fmt.Errorf("failed to read feature metadata: %w", err)
+	}
+
+	if toRef, _ := cmd.Flags().GetBool("ref"); toRef {
+		unlock, err := lockRepo(a, cmd)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+		who, err := identity(a)
+		if err != nil {
+			return err
+		}
+		changed, err := meta.WriteRef(repo, features, who, "")
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", meta.Ref, err)
+		}
+		if !changed {
+			fmt.Fprintf(a.Err, "plain: %s is already up to date\n", meta.Ref)
+			return nil
+		}
+		fmt.Fprintf(a.Err, "plain: committed %d feature(s) to %s\n", len(features), meta.Ref)
+		return nil
+	}
+
+	if len(args) == 0 || args[0] == "-" {
+		return meta.Export(a.Out, features)
+	}
+	f, err := os.Create(args[0])
+	if err != nil {
+		return err
+	}
+	if err := meta.Export(f, features); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.Err, "plain: exported %d feature(s) to %s\n", len(features), args[0])
+	return nil
+}
+
+func runMetaImport(a *app.App, cmd *cobra.Command, args []string) error {
+	repo, err := git.OpenRepository()
+	if err != nil {
+		return err
+	}
+	replace, _ := cmd.Flags().GetBool("replace")
+
+	var features []meta.Feature
+	if fromRef, _ := cmd.Flags().GetBool("ref"); fromRef {
+		if len(args) > 0 {
+			return errors.New("expected no file with --ref")
+		}
+		if features, err = meta.ReadRef(repo); err != nil {
+			return fmt.Errorf("failed to read %s: %w", meta.Ref, err)
+		}
+	} else {
+		var r io.Reader = os.Stdin
+		if len(args) > 0 && args[0] != "-" {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			r = f
+		}
+		if features, err = meta.Import(r); err != nil {
+			return err
+		}
+	}
+
+	changed, err := meta.NewStore(repo.CommonDir).Merge(features, replace)
+	if err != nil {
+		return fmt.Errorf("failed to record feature metadata: %w", err)
+	}
+	fmt.Fprintf(a.Err, "plain: imported %d feature(s), %d changed\n", len(features), changed)
+	return nil
+}
+
+func runMetaPush(a *app.App, args []string) error {
+	repo, err := git.OpenRepository()
+	if err != nil {
+		return err
+	}
+	remote, err := metaRemote(a, args)
+	if err != nil {
+		return err
+	}
+	who, err := identity(a)
+	if err != nil {
+		return err
+	}
+
+	// Merging the remote's copy first makes the push a fast-forward, so nobody's metadata is lost.
+	store := meta.NewStore(repo.CommonDir)
+	remoteHash, err := pullMeta(a, repo, store, remote)
+	if err != nil {
+		return err
+	}
+	features, err := store.All()
+	if err != nil {
+		return fmt.Errorf("failed to read feature metadata: %w", err)
+	}
+	if _, err := meta.WriteRef(repo, features, who, remoteHash); err != nil {
+		return fmt.Errorf("failed to write %s: %w", meta.Ref, err)
+	}
+	if err := a.Git.PushRef(remote, meta.Ref); err != nil {
+		return fmt.Errorf("failed to push %s: %w", meta.Ref, err)
+	}
+	fmt.Fprintf(a.Err, "plain: shared %d feature(s) on %s\n", len(features), remote)
+	return nil
+}
+
+func runMetaPull(a *app.App, args []string) error {
+	repo, err := git.OpenRepository()
+	if err != nil {
+		return err
+	}
+	remote, err := metaRemote(a, args)
+	if err != nil {
+		return err
+	}
+
+	remoteHash, err := pullMeta(a, repo, meta.NewStore(repo.CommonDir), remote)
+	if err != nil {
+		return err
+	}
+	if remoteHash == "" {
+		fmt.Fprintf(a.Err, "plain: %s has no shared feature metadata yet\n", remote)
+		return nil
+	}
+	fmt.Fprintf(a.Err, "plain: merged the feature metadata shared on %s\n", remote)
+	return nil
+}
+
+// metaRemote returns the remote named in args, or else the one feature metadata is shared on.
+func metaRemote(a *app.App, args []string) (string, error) {
+	if len(args) > 0 {
+		return args[0], nil
+	}
+	remote, err := lastConfigValue(a, "plain.metaRemote")
+	if err != nil || remote == "" {
+		return defaultRemote, err
+	}
+	return remote, nil
+}
+
+// metaTrackingRef is where the copy of the metadata ref fetched from remote is kept.
+func metaTrackingRef(remote string) string {
+	return "refs/plain/remotes/" + remote + "/meta"
+}
+
+// fetchMeta fetches the remote's copy of the metadata ref, returning the hash it fetched, or "" if the
+// remote has none.
+func fetchMeta(a *app.App, repo *git.Repository, remote string) (string, error) {
+	err := a.Git.FetchRef(remote, meta.Ref, metaTrackingRef(remote))
+	if errors.Is(err, git.ErrRemoteRefNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch %s from %s: %w", meta.Ref, remote, err)
+	}
+	return repo.ResolveRevision(metaTrackingRef(remote))
+}
+
+// pullMeta fetches the remote's copy of the metadata ref and merges it into the store, returning the
+// hash it fetched, or "" if the remote has none.
+func pullMeta(a *app.App, repo *git.Repository, store *meta.Store, remote string) (string, error) {
+	hash, err := fetchMeta(a, repo, remote)
+	if err != nil || hash == "" {
+		return "", err
+	}
+	features, err := meta.ReadCommit(repo, hash)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s from %s: %w", meta.Ref, remote, err)
+	}
+	if _, err := store.Merge(features, false); err != nil {
+		return "", fmt.Errorf("failed to record feature metadata: %w", err)
+	}
+	return hash, nil
+}
+
+// useRecordedBase sets the flag naming the branch the current feature started from, like --from or
+// --into, to the base plain start recorded for the feature, unless the flag was given. The flag is still
+// reported as not changed, as [applySettings] leaves it. Without a recorded base, the flag keeps its
+// default.
+func useRecordedBase(cmd *cobra.Command, name string) error {
+	flag := cmd.Flags().Lookup(name)
+	if flag == nil || flag.Changed {
+		return nil
+	}
+	feature, ok, err := currentFeature()
+	if err != nil || !ok || feature.Base == "" {
+		return err
+	}
+	return flag.Value.Set(feature.Base)
+}
+
+// currentFeature returns the metadata recorded for the branch checked out. The boolean is false if none
+// was, or no branch is checked out.
+func currentFeature() (meta.Feature, bool, error) {
+	repo, err := git.OpenRepository()
+	if err != nil {
+		return meta.Feature{}, false, err
+	}
+	branch, err := repo.CurrentBranch()
+	if err != nil || branch == "" {
+		return meta.Feature{}, false, err
+	}
+	return meta.NewStore(repo.CommonDir).Load(branch)
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sim-deos/plain/internal/git"
+
+	"github.com/spf13/cobra"
+)
+
+// needsGitAnnotation marks a command that can't work without the git command, with what it needs git for.
+const needsGitAnnotation = "plain.needsGit"
+
+// needsGit marks c as unavailable without the git command, which it needs to do what.
+func needsGit(c *cobra.Command, what string) *cobra.Command {
+	if c.Annotations == nil {
+		c.Annotations = map[string]string{}
+	}
+	c.Annotations[needsGitAnnotation] = what
+	return c
+}
+
+// disableGitCommands turns the commands under root that need the git command into ones that explain
+// they can't run, and lists them in root's help. The rest work without git, since plain reads and
+// writes the repository itself.
+func disableGitCommands(root *cobra.Command) {
+	var unavailable []string
+	var walk func(c *cobra.Command)
+	walk = func(c *cobra.Command) {
+		for _, sub := range c.Commands() {
+			walk(sub)
+		}
+		what, ok := c.Annotations[needsGitAnnotation]
+		if !ok {
+			return
+		}
+		name := strings.TrimPrefix(c.CommandPath(), root.Name()+" ")
+		unavailable = append(unavailable, name)
+		c.Short += " (needs git)"
+		c.Args = cobra.ArbitraryArgs
+		c.RunE = func(cmd *cobra.Command, args []string) error {
+			return fmt.Errorf("plain %s needs git to %s: %w", name, what, git.ErrNoGit)
+		}
+	}
+	walk(root)
+
+	root.Long += fmt.Sprintf("\n\ngit isn't installed, so plain reads the repository itself. These commands need git and\n"+
+		"are unavailable until it is installed: %s.", strings.Join(unavailable, ", "))
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sim-deos/plain/internal/app"
+	"github.com/sim-deos/plain/internal/git"
+	"github.com/sim-deos/plain/internal/meta"
+
+	"github.com/spf13/cobra"
+)
+
+func NewOntoCmd(a *app.App) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "onto <branch>",
+		Short: "Moves the current feature on top of another branch",
+		Long: `Replays the current feature's commits on top of a branch, one at a time, like git rebase, so
+		the feature starts from the branch's latest commit. Commits the branch already has are dropped,
+		and merge commits are left out.
+		To move a feature that started from one branch onto another, pass the branch it started from
+		with --from, unless plain start recorded it. Once moved, the branch is recorded as the feature's
+		base instead, for plain preview and done. If a commit conflicts, the conflicts are left in the files for you to resolve; then
+		run plain onto --continue, or plain onto --abort to put the feature back as it was.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			resume, _ := cmd.Flags().GetBool("continue")
+			abort, _ := cmd.Flags().GetBool("abort")
+			if resume || abort {
+				return cobra.NoArgs(cmd, args)
+			}
+			return cobra.ExactArgs(1)(cmd, args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error { return runOnto(a, cmd, args) },
+	}
+	c.Flags().StringP("from", "f", "", "The branch the feature started from, if not the one it moves onto")
+	c.Flags().Bool("continue", false, "Carry on once the conflicts are resolved")
+	c.Flags().Bool("abort", false, "Give up and put the feature back as it was")
+	c.MarkFlagsMutuallyExclusive("continue", "abort")
+	c.ValidArgsFunction = completeFeature
+	c.RegisterFlagCompletionFunc("from", completeFeatureFlag)
+	return locksRepo(a, c)
+}
+
+func runOnto(a *app.App, cmd *cobra.Command, args []string) error {
+	repo, err := git.OpenRepository()
+	if err != nil {
+		return err
+	}
+	who, err := identity(a)
+	if err != nil {
+		return err
+	}
+
+	if abort, _ := cmd.Flags().GetBool("abort"); abort {
+		if err := repo.AbortRebase(who); err != nil {
+			return err
+		}
+		fmt.Fprintln(a.Err, "plain: put the feature back as it was")
+		return nil
+	}
+
+	head, err := repo.ResolveRevision("HEAD")
+	if err != nil {
+		return err
+	}
+
+	// The branch moved onto becomes the feature's base once the move is done, which for one that
+	// stopped on conflicts is when it continues.
+	var result, onto string
+	if resume, _ := cmd.Flags().GetBool("continue"); resume {
+		if onto, err = repo.RebaseOnto(); err != nil {
+			return err
+		}
+		result, err = repo.ContinueRebase(who)
+	} else {
+		if err := useRecordedBase(cmd, "from"); err != nil {
+			return err
+		}
+		upstream, _ := cmd.Flags().GetString("from")
+		if upstream == "" {
+			upstream = args[0]
+		}
+		onto = args[0]
+		result, err = repo.Rebase(upstream, onto, who)
+	}
+
+	switch {
+	case errors.Is(err, git.ErrPickConflict):
+		return fmt.Errorf("%w\nresolve them, then run plain onto --continue, or plain onto --abort to give up", err)
+	case err != nil:
+		return fmt.Errorf("failed to move the feature: %w", err)
+	}
+	if onto != "" {
+		if err := recordBase(repo, onto); err != nil {
+			fmt.Fprintf(a.Err, "plain: warning: failed to record the feature's new base: %v\n", err)
+		}
+	}
+	if result == head {
+		fmt.Fprintln(a.Err, "plain: the feature is already up to date")
+		return nil
+	}
+	fmt.Fprintf(a.Err, "plain: the feature is now at %s\n", result[:7])
+	return nil
+}
+
+// recordBase records base as the branch the current feature starts from, if plain keeps metadata for it.
+func recordBase(repo *git.Repository, base string) error {
+	feature, ok, err := currentFeature()
+	if err != nil || !ok {
+		return err
+	}
+	if feature.BaseCommit, err = repo.ResolveRevision(base); err != nil {
+		return err
+	}
+	feature.Base, feature.Updated = base, time.Now()
+	return meta.NewStore(repo.CommonDir).Save(feature)
+}
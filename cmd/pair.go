@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/sim-deos/plain/internal/app"
+	"github.com/sim-deos/plain/internal/git"
+	"github.com/sim-deos/plain/internal/pair"
+
+	"github.com/spf13/cobra"
+)
+
+// pairShortcutPrefix starts the git config keys naming partners by shortcut, as in plain.pair.ann.
+const pairShortcutPrefix = "plain.pair."
+
+func NewPairCmd(a *app.App) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "pair",
+		Short: "Credits the people you pair with on your checkpoints",
+		Long: `Lists who you are pairing with. While pairing, every checkpoint gets a Co-authored-by trailer
+		for each partner, which GitHub and GitLab show as co-authors of the commit. Add partners with
+		plain pair add, and end the session with plain pair stop.
+
+		Partners are kept for the worktree, in .git/plain, until the session is stopped. Save the
+		people you pair with often as shortcuts in your git config, such as
+		git config --global plain.pair.ann "Ann Lee <ann@example.com>", then plain pair add ann.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error { return runPair(a) },
+	}
+	c.AddCommand(newPairAddCmd(a), newPairStopCmd(a))
+	return c
+}
+
+func newPairAddCmd(a *app.App) *cobra.Command {
+	return &cobra.Command{
+		Use:   "add <partner>...",
+		Short: "Starts crediting partners on checkpoints",
+		Long: `Adds partners to the pairing session, each given as a shortcut from git config plain.pair.<name>
+		or as "Name <email>". Everyone added stays until plain pair stop.`,
+		Args:              cobra.MinimumNArgs(1),
+		RunE:              func(cmd *cobra.Command, args []string) error { return runPairAdd(a, args) },
+		ValidArgsFunction: completePairShortcut,
+	}
+}
+
+func newPairStopCmd(a *app.App) *cobra.Command {
+	return &cobra.Command{
+		Use:   "stop",
+		Short: "Stops crediting partners on checkpoints",
+		Args:  cobra.NoArgs,
+		RunE:  func(cmd *cobra.Command, args []string) error { return runPairStop(a) },
+	}
+}
+
+func runPair(a *app.App) error {
+	repo, err := git.OpenRepository()
+	if err != nil {
+		return err
+	}
+	partners, err := pair.NewSession(repo.GitDir).Partners()
+	if err != nil {
+		return err
+	}
+	if len(partners) == 0 {
+		fmt.Fprintln(a.Err, "plain: not pairing, start with plain pair add <partner>")
+		return nil
+	}
+	fmt.Fprintln(a.Err, "plain: pairing with")
+	for _, p := range partners {
+		fmt.Fprintf(a.Out, "  %s\n", p)
+	}
+	return nil
+}
+
+func runPairAdd(a *app.App, args []string) error {
+	repo, err := git.OpenRepository()
+	if err != nil {
+		return err
+	}
+	var partners []pair.Partner
+	for _, arg := range args {
+		p, err := lookupPartner(a, arg)
+		if err != nil {
+			return err
+		}
+		partners = append(partners, p)
+	}
+
+	all, err := pair.NewSession(repo.GitDir).Add(partners...)
+	if err != nil {
+		return fmt.Errorf("failed to record partners: %w", err)
+	}
+	names := make([]string, len(all))
+	for i, p := range all {
+		names[i] = p.Name
+	}
+	fmt.Fprintf(a.Err, "plain: pairing with %s; checkpoints credit them until plain pair stop\n", strings.Join(names, ", "))
+	return nil
+}
+
+func runPairStop(a *app.App) error {
+	repo, err := git.OpenRepository()
+	if err != nil {
+		return err
+	}
+	partners, err := pair.NewSession(repo.GitDir).Stop()
+	if err != nil {
+		return err
+	}
+	if len(partners) == 0 {
+		fmt.Fprintln(a.Err, "plain: not pairing")
+		return nil
+	}
+	fmt.Fprintf(a.Err, "plain: stopped pairing with %d partner(s)\n", len(partners))
+	return nil
+}
+
+// lookupPartner resolves arg, a shortcut set in git config plain.pair.<name> or "Name <email>".
+func lookupPartner(a *app.App, arg string) (pair.Partner, error) {
+	if strings.Contains(arg, "<") {
+		return pair.ParsePartner(arg)
+	}
+	value, err := lastConfigValue(a, pairShortcutPrefix+arg)
+	if err != nil {
+		return pair.Partner{}, err
+	}
+	if value == "" {
+		return pair.Partner{}, fmt.Errorf("%q isn't a shortcut; pass \"Name <email>\", or save it with git config --global %s%s \"Name <email>\"", arg, pairShortcutPrefix, arg)
+	}
+	p, err := pair.ParsePartner(value)
+	if err != nil {
+		return pair.Partner{}, fmt.Errorf("bad %s%s: %w", pairShortcutPrefix, arg, err)
+	}
+	return p, nil
+}
+
+// pairTrailers returns the Co-authored-by trailers for the partners the worktree is pairing with.
+func pairTrailers(repo *git.Repository) ([]string, error) {
+	partners, err := pair.NewSession(repo.GitDir).Partners()
+	if err != nil {
+		return nil, err
+	}
+	return pair.Trailers(partners), nil
+}
+
+// completePairShortcut completes partners with the shortcuts in git config.
+func completePairShortcut(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	config, err := git.GlobalConfig()
+	if repo, openErr := git.OpenRepository(); openErr == nil {
+		config, err = repo.Config()
+	}
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var shortcuts []string
+	for _, entry := range config.Entries() {
+		name, ok := strings.CutPrefix(entry.Key, pairShortcutPrefix)
+		if ok && strings.HasPrefix(name, toComplete) && !strings.Contains(name, ".") && !slices.Contains(shortcuts, name) {
+			shortcuts = append(shortcuts, name)
+		}
+	}
+	return shortcuts, cobra.ShellCompDirectiveNoFileComp
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sim-deos/plain/internal/app"
+	"github.com/sim-deos/plain/internal/git"
+
+	"github.com/spf13/cobra"
+)
+
+func NewPathCmd(a *app.App) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "path <from> <to>",
+		Short: "Shows how one commit led to another",
+		Long: `Finds the chains of commits leading from <from> to <to>, like git log --ancestry-path.
+		Useful for figuring out how a change reached a release branch.
+		Both arguments may be branches, tags, or full commit hashes.
+		With --first-parent, or git config plain.firstParent set to true, only the line each merge was
+		made on is followed, leaving out the paths through the branches merged into it.
+		Paths are drawn with Unicode when the terminal supports it, and ASCII otherwise; set git
+		config plain.charset to unicode or ascii to choose.
+		With --signatures, each signed commit's signature is checked as git verify-commit checks it,
+		and marked verified when it is good and from a trusted key. SSH signatures are checked against
+		git config gpg.ssh.allowedSignersFile.
+		In a shallow clone, the commits between the two may not have been fetched. When the history runs
+		out before <from> is found, plain offers to fetch more of it from origin; --deepen <n> fetches
+		n more commits without asking.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error { return runPath(a, cmd, args) },
+	}
+	c.Flags().IntP("limit", "n", 5, fmt.Sprintf("Maximum number of chains to show (0 shows all, up to %d)", git.MaxAncestryChains))
+	c.Flags().Bool("first-parent", false, "Follow only the first parent of merges")
+	c.Flags().Bool("signatures", false, "Check and show the commits' signatures")
+	c.Flags().Int("deepen", 0, "Fetch this many more commits when a shallow clone's history runs out")
+	return c
+}
+
+func runPath(a *app.App, cmd *cobra.Command, args []string) error {
+	limit, _ := cmd.Flags().GetInt("limit")
+	signatures, _ := cmd.Flags().GetBool("signatures")
+
+	glyphs, err := charset(a)
+	if err != nil {
+		return err
+	}
+	parents, err := lineage(a, cmd)
+	if err != nil {
+		return err
+	}
+
+	var history git.BranchHistory
+	var chains [][]string
+	for deepened := false; ; deepened = true {
+		fromHistory, err := git.GetHistoryForRevision(args[0])
+		if err != nil {
+			return fmt.Errorf("cannot read %s: %w", args[0], err)
+		}
+
+		history, err = git.GetLineageForRevision(args[1], parents)
+		if err != nil {
+			return fmt.Errorf("cannot read %s: %w", args[1], err)
+		}
+
+		from, to := fromHistory.Head.Hash, history.Head.Hash
+		chains = git.AncestryChains(history.Graph, from, to, limit, parents)
+		// --deepen fetches more history once; asking goes on until the answer is no.
+		if len(chains) > 0 || !history.Truncated() || deepened && cmd.Flags().Changed("deepen") {
+			break
+		}
+		if more, err := offerDeepen(a, cmd, args[1]); err != nil {
+			return err
+		} else if !more {
+			break
+		}
+	}
+	if len(chains) == 0 {
+		relation := "an ancestor"
+		if parents == git.FirstParent {
+			relation = "on the first-parent line"
+		}
+		if history.Truncated() {
+			fmt.Fprintf(a.Err, "plain: %s is not %s of %s in the history fetched so far\n", args[0], relation, args[1])
+			return nil
+		}
+		fmt.Fprintf(a.Err, "plain: %s is not %s of %s\n", args[0], relation, args[1])
+		return nil
+	}
+
+	var repo *git.Repository
+	if signatures {
+		if repo, err = git.OpenRepository(); err != nil {
+			return err
+		}
+	}
+
+	if len(chains) == git.MaxAncestryChains && (limit <= 0 || limit > git.MaxAncestryChains) {
+		fmt.Fprintf(a.Err, "plain: showing the first %d paths from %s to %s, there may be more\n", len(chains), args[0], args[1])
+	} else {
+		fmt.Fprintf(a.Err, "plain: found %d path(s) from %s to %s\n", len(chains), args[0], args[1])
+	}
+	for i, chain := range chains {
+		fmt.Fprintf(a.Out, "\npath %d (%d commits)\n", i+1, len(chain))
+		for j, hash := range chain {
+			if j > 0 {
+				fmt.Fprintf(a.Out, "  %s\n", glyphs.Line)
+			}
+			commit := history.Graph[hash]
+			summary, _, _ := strings.Cut(commit.Message, "\n")
+			if repo != nil {
+				badge, err := signatureBadge(repo, hash)
+				if err != nil {
+					return err
+				}
+				summary += badge
+			}
+			fmt.Fprintf(a.Out, "  %s %s %s\n", glyphs.Commit, commit.DisName(), summary)
+		}
+	}
+	return nil
+}
+
+// signatureBadge describes the signature of the commit hash names, to follow it in history output, or
+// returns "" if it is unsigned.
+func signatureBadge(repo *git.Repository, hash string) (string, error) {
+	v, err := repo.VerifyCommit(hash)
+	switch {
+	case err != nil:
+		return "", fmt.Errorf("cannot check the signature of %s: %w", hash, err)
+	case v.Status == git.Unsigned:
+		return "", nil
+	case v.Verified():
+		return fmt.Sprintf(" [verified: %s]", v.Signer), nil
+	}
+	return fmt.Sprintf(" [signature %s]", v.Status), nil
+}
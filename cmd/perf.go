@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sim-deos/plain/internal/diff"
+	"github.com/sim-deos/plain/internal/git"
+
+	"github.com/spf13/cobra"
+)
+
+// addPerfFlag adds --perf to root, which measures what the git layer does during any command and prints
+// it to standard error once the command is done. The measurements come from the same [git.Observer]
+// programs embedding the git layer install.
+func addPerfFlag(root *cobra.Command) {
+	root.PersistentFlags().Bool("perf", false, "Print how much reading the repository took, to standard error")
+
+	var metrics *git.Metrics
+	var started time.Time
+	root.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		if perf, _ := cmd.Flags().GetBool("perf"); perf {
+			metrics = git.NewMetrics()
+			git.SetObserver(metrics)
+			started = time.Now()
+		}
+	}
+	root.PersistentPostRun = func(cmd *cobra.Command, args []string) {
+		if metrics != nil {
+			git.SetObserver(nil)
+			printPerf(cmd.ErrOrStderr(), metrics.Stats(), time.Since(started))
+		}
+	}
+}
+
+func printPerf(w io.Writer, stats git.Stats, total time.Duration) {
+	fmt.Fprintf(w, "plain: perf: %s in total\n", total.Round(time.Microsecond))
+
+	read := 0
+	var kinds []string
+	for _, kind := range []git.GitObjectKind{git.CommitObject, git.TreeObject, git.BlobObject, git.TagObject} {
+		if n := stats.Objects[kind]; n > 0 {
+			read += n
+			kinds = append(kinds, fmt.Sprintf("%d %s", n, kind))
+		}
+	}
+	if read > 0 {
+		fmt.Fprintf(w, "  objects read  %d (%s), %d from packs, %s\n", read, strings.Join(kinds, ", "), stats.Packed, diff.Size(stats.Bytes))
+	}
+	if stats.Inflated > 0 {
+		fmt.Fprintf(w, "  inflated      %s\n", diff.Size(stats.Inflated))
+	}
+
+	var caches []string
+	for cache := range stats.Hits {
+		caches = append(caches, cache)
+	}
+	for cache := range stats.Misses {
+		if _, ok := stats.Hits[cache]; !ok {
+			caches = append(caches, cache)
+		}
+	}
+	slices.Sort(caches)
+	for _, cache := range caches {
+		fmt.Fprintf(w, "  %-13s %d hit(s), %d miss(es)\n", cache+" cache", stats.Hits[cache], stats.Misses[cache])
+	}
+
+	// Slowest first; phases nest, like history inside status, so their times overlap.
+	var phases []string
+	for phase := range stats.Phases {
+		phases = append(phases, phase)
+	}
+	slices.SortFunc(phases, func(a, b string) int {
+		return cmp.Compare(stats.Phases[b], stats.Phases[a])
+	})
+	for _, phase := range phases {
+		fmt.Fprintf(w, "  %-13s %s (%d run(s))\n", phase, stats.Phases[phase].Round(time.Microsecond), stats.Runs[phase])
+	}
+}
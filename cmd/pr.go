@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sim-deos/plain/internal/app"
+	"github.com/sim-deos/plain/internal/forge"
+	"github.com/sim-deos/plain/internal/git"
+	"github.com/sim-deos/plain/internal/httpclient"
+	"github.com/sim-deos/plain/internal/secret"
+
+	"github.com/spf13/cobra"
+)
+
+const defaultRemote = "origin"
+
+// addPullRequestFlags adds the flags used by describeFeature and openPullRequest.
+func addPullRequestFlags(c *cobra.Command) {
+	c.Flags().StringP("from", "f", "main", "Base branch the feature started from, and that the pull request merges into")
+	c.Flags().String("issue", "", "The issue this feature closes, e.g. 42 or PROJ-42")
+}
+
+// forgeClient returns a client for the forge hosting the given remote, using the token [forge.Tokens.Find]
+// finds: one in the environment, the one saved by plain auth login, the GitHub CLI's, or git's.
+func forgeClient(a *app.App, remote string) (forge.Client, forge.Repo, error) {
+	url, err := remoteURL(remote)
+	if err != nil {
+		return nil, forge.Repo{}, err
+	}
+	if url == "" {
+		return nil, forge.Repo{}, fmt.Errorf("this repository has no remote named %s", remote)
+	}
+
+	repo, err := forge.ParseRemoteURL(url)
+	if err != nil {
+		return nil, forge.Repo{}, err
+	}
+
+	tokens, err := forge.NewTokens()
+	if err != nil {
+		return nil, forge.Repo{}, err
+	}
+	tokens.Env = a.Settings.TokenEnv
+	kind, err := hostKind(tokens, repo.Host)
+	if err != nil {
+		return nil, forge.Repo{}, err
+	}
+	token, _, err := tokens.Find(repo.Host, kind)
+	if errors.Is(err, secret.ErrNotFound) {
+		return nil, forge.Repo{}, fmt.Errorf("not logged in to %s, run plain auth login --host %s", repo.Host, repo.Host)
+	}
+	if err != nil {
+		return nil, forge.Repo{}, err
+	}
+
+	httpClient, err := newHTTPClient(a, repo.Host)
+	if err != nil {
+		return nil, forge.Repo{}, err
+	}
+	client, err := forge.NewClient(repo, kind, token, httpClient)
+	return client, repo, err
+}
+
+// hostKind returns the forge software host runs: what it was logged in to as with plain auth login
+// --kind, which is how self-hosted forges with other names are told apart, or else what its name says.
+func hostKind(tokens *forge.Tokens, host string) (forge.Kind, error) {
+	logins, err := tokens.Logins()
+	if err != nil {
+		return 0, err
+	}
+	for _, login := range logins {
+		if login.Host == host {
+			return login.Kind, nil
+		}
+	}
+	return forge.DetectKind(host)
+}
+
+// remoteURL returns the first URL of remote, as git config url.<base>.insteadOf rewrites it, or "" if
+// there is no such remote.
+func remoteURL(remote string) (string, error) {
+	repo, err := git.OpenRepository()
+	if err != nil {
+		return "", err
+	}
+	config, err := repo.Config()
+	if err != nil {
+		return "", err
+	}
+	for _, r := range config.Remotes() {
+		if r.Name == remote && len(r.URLs) > 0 {
+			return r.URLs[0], nil
+		}
+	}
+	return "", nil
+}
+
+// newHTTPClient returns a retrying HTTP client for talking to host, whose requests time out after git
+// config plain.<host>.timeout, or plain.httpTimeout for every host, using values like 30s or 2m. Like git,
+// it goes through the proxy in http.proxy and trusts the certificates in http.sslCAInfo, or
+// $GIT_SSL_CAINFO, falling back to the HTTPS_PROXY and NO_PROXY environment variables. With an empty
+// host, as for the native git backend, which talks to whichever remotes a command needs, only
+// plain.httpTimeout applies.
+func newHTTPClient(a *app.App, host string) (*http.Client, error) {
+	network, err := networkConfig(a)
+	if err != nil {
+		return nil, err
+	}
+	t, err := httpclient.NewTransportFor(network)
+	if err != nil {
+		return nil, err
+	}
+	keys := map[string]*time.Duration{"plain.httpTimeout": &t.Timeout}
+	if host != "" {
+		keys["plain."+host+".timeout"] = nil
+	}
+	for key, timeout := range keys {
+		value, err := lastConfigValue(a, key)
+		if err != nil {
+			return nil, err
+		}
+		if value == "" {
+			continue
+		}
+		parsed, err := parseAge(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q: %w", key, value, err)
+		}
+		if timeout == nil {
+			t.Timeouts[host] = parsed
+		} else {
+			*timeout = parsed
+		}
+	}
+	return t.Client(), nil
+}
+
+// networkConfig reads the proxy and CA bundle git is configured to use.
+func networkConfig(a *app.App) (httpclient.Network, error) {
+	var network httpclient.Network
+	var err error
+	if network.Proxy, err = lastConfigValue(a, "http.proxy"); err != nil {
+		return network, err
+	}
+	network.CAInfo = os.Getenv("GIT_SSL_CAINFO")
+	if network.CAInfo == "" {
+		if network.CAInfo, err = lastConfigValue(a, "http.sslCAInfo"); err != nil {
+			return network, err
+		}
+	}
+	if rest, ok := strings.CutPrefix(network.CAInfo, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return network, err
+		}
+		network.CAInfo = filepath.Join(home, rest)
+	}
+	return network, nil
+}
+
+// openPullRequest pushes the current feature and opens a pull request for it.
+func openPullRequest(a *app.App, cmd *cobra.Command, draft bool) (forge.Client, forge.PullRequest, error) {
+	if err := useRecordedBase(cmd, "from"); err != nil {
+		return nil, forge.PullRequest{}, err
+	}
+	base, _ := cmd.Flags().GetString("from")
+
+	branch, err := a.Git.GetCurrentBranch()
+	if err != nil {
+		return nil, forge.PullRequest{}, err
+	}
+
+	desc, err := describeFeature(a, cmd)
+	if err != nil {
+		return nil, forge.PullRequest{}, err
+	}
+
+	client, _, err := forgeClient(a, defaultRemote)
+	if err != nil {
+		return nil, forge.PullRequest{}, err
+	}
+
+	if err := checkPushSize(a, cmd, defaultRemote, branch); err != nil {
+		return nil, forge.PullRequest{}, err
+	}
+	if err := a.Git.Push(defaultRemote, branch); err != nil {
+		return nil, forge.PullRequest{}, fmt.Errorf("failed to push %s: %w", branch, err)
+	}
+
+	pr, err := client.CreatePullRequest(context.Background(), forge.PullRequestOptions{
+		Head:  branch,
+		Base:  base,
+		Title: desc.Title,
+		Body:  desc.Body,
+		Draft: draft,
+	})
+	if err != nil {
+		return nil, forge.PullRequest{}, err
+	}
+	return client, pr, nil
+}
@@ -1,9 +1,17 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/sim-deos/plain/internal/app"
+	"github.com/sim-deos/plain/internal/diff"
+	"github.com/sim-deos/plain/internal/focus"
+	"github.com/sim-deos/plain/internal/git"
 
 	"github.com/spf13/cobra"
 )
@@ -11,9 +19,372 @@ import (
 func NewPreviewCmd(a *app.App) *cobra.Command {
 	previewCmd := &cobra.Command{
 		Use:   "preview",
-		Short: "A brief description of your command",
-		Long:  `Long not yet implemented`,
-		Run:   func(cmd *cobra.Command, args []string) { fmt.Println("preview called") },
+		Short: "Preview the changes made on this feature",
+		Long: `Lists the files changed on this feature since it started from its base branch: the one plain
+		start recorded for it, or main, unless --from names another.
+		Generated files (marked linguist-generated in .gitattributes, or matching a plain.generated
+		pattern in git config) are collapsed by default; use --show-generated to list them.
+		With --patch, each file's changes are shown as a unified diff, computed with the algorithm
+		given by --diff-algorithm or git config diff.algorithm: myers (the default), patience, or
+		histogram. --word-diff shows changed lines once, marking removed words [-like this-] and added
+		words {+like this+}. Binary files are summarised by how much their size changed, and so are
+		files stored with Git LFS, by the size of their content rather than the pointer git holds.
+		With --changed-since, shows what changed in the repository's history instead, such as after a
+		fetch: the commits that appeared and the branches and tags that were created, moved, or deleted
+		since a time (an age like 2h or 3d, or a date like 2025-01-31) or since a commit was made. Past
+		positions of refs are read from their reflogs. Commits merged into a branch from elsewhere are
+		listed too, unless --first-parent or git config plain.firstParent limits the list to the commits
+		made on each branch's own line. When git config plain.focus is set, as plain list describes,
+		only the branches in focus and the commits that reached them are shown, unless --all is given.`,
+		RunE: func(cmd *cobra.Command, args []string) error { return runPreview(a, cmd, args) },
 	}
+	previewCmd.Flags().StringP("from", "f", "main", "Base branch the feature started from")
+	previewCmd.Flags().BoolP("show-generated", "g", false, "List generated files instead of collapsing them")
+	previewCmd.Flags().BoolP("patch", "p", false, "Show the changes in each file")
+	previewCmd.Flags().Bool("word-diff", false, "Show --patch changes word by word within lines")
+	previewCmd.Flags().String("diff-algorithm", "", "Diff algorithm for --patch: myers, patience, or histogram")
+	previewCmd.Flags().String("changed-since", "", "Show new commits and moved refs since a time or commit")
+	previewCmd.Flags().Bool("first-parent", false, "With --changed-since, leave out commits brought in by merges")
+	previewCmd.Flags().Bool("all", false, "With --changed-since, show the branches outside plain.focus too")
+	previewCmd.RegisterFlagCompletionFunc("from", completeFeatureFlag)
+	previewCmd.MarkFlagsMutuallyExclusive("changed-since", "patch")
+	previewCmd.MarkFlagsMutuallyExclusive("changed-since", "word-diff")
 	return previewCmd
 }
+
+func runPreview(a *app.App, cmd *cobra.Command, args []string) error {
+	if err := useRecordedBase(cmd, "from"); err != nil {
+		return err
+	}
+	base, _ := cmd.Flags().GetString("from")
+	showGenerated, _ := cmd.Flags().GetBool("show-generated")
+	patch, _ := cmd.Flags().GetBool("patch")
+	words, _ := cmd.Flags().GetBool("word-diff")
+
+	if since, _ := cmd.Flags().GetString("changed-since"); since != "" {
+		return previewGraph(a, cmd, since)
+	}
+
+	attrs, err := loadGeneratedAttributes(a)
+	if err != nil {
+		return fmt.Errorf("failed to read attributes: %w", err)
+	}
+
+	if patch || words {
+		alg, err := diffAlgorithm(a, cmd)
+		if err != nil {
+			return err
+		}
+		return previewPatch(a, base, alg, words, attrs, showGenerated)
+	}
+
+	files, err := a.Git.ChangedFiles(base)
+	if err != nil {
+		return fmt.Errorf("failed to list changes: %w", err)
+	}
+
+	fmt.Fprintf(a.Err, "plain: %d files changed since %s\n", len(files), base)
+	var generated int
+	for _, file := range files {
+		if !showGenerated && attrs.IsGenerated(file) {
+			generated++
+			continue
+		}
+		fmt.Fprintf(a.Out, "  %s\n", file)
+	}
+
+	if generated > 0 {
+		fmt.Fprintf(a.Err, "  (%d generated files hidden, use --show-generated to list them)\n", generated)
+	}
+	return nil
+}
+
+// diffAlgorithm returns the algorithm chosen with --diff-algorithm, falling back to git config diff.algorithm.
+func diffAlgorithm(a *app.App, cmd *cobra.Command) (diff.Algorithm, error) {
+	name, _ := cmd.Flags().GetString("diff-algorithm")
+	if name == "" {
+		configured, err := a.Git.GetConfigValues("diff.algorithm")
+		if err != nil {
+			return nil, err
+		}
+		if len(configured) > 0 {
+			name = configured[len(configured)-1]
+		}
+	}
+	return diff.ParseAlgorithm(name)
+}
+
+// previewPatch prints a unified diff of every file changed since the feature forked from base, word by
+// word within lines if words is set.
+func previewPatch(a *app.App, base string, alg diff.Algorithm, words bool, attrs *git.Attributes, showGenerated bool) error {
+	forked, err := a.Git.MergeBase(base, "HEAD")
+	if err != nil {
+		return fmt.Errorf("failed to find where the feature started from %s: %w", base, err)
+	}
+
+	repo, err := git.OpenRepository()
+	if err != nil {
+		return err
+	}
+	head, err := repo.ResolveRevision("HEAD")
+	if err != nil {
+		return err
+	}
+
+	var trees [2]string
+	for i, hash := range []string{forked, head} {
+		commit, err := repo.ReadCommit(hash)
+		if err != nil {
+			return err
+		}
+		trees[i] = commit.Tree
+	}
+	changes, err := repo.DiffTrees(trees[0], trees[1])
+	if err != nil {
+		return fmt.Errorf("failed to list changes: %w", err)
+	}
+
+	var generated int
+	for _, change := range changes {
+		if !showGenerated && attrs.IsGenerated(change.Path) {
+			generated++
+			continue
+		}
+		if err := printFileDiff(a.Out, repo, alg, words, attrs, change); err != nil {
+			return err
+		}
+	}
+
+	if generated > 0 {
+		fmt.Fprintf(a.Err, "(%d generated files hidden, use --show-generated to show them)\n", generated)
+	}
+	return nil
+}
+
+// printFileDiff prints the changes to one file in unified diff format. Binary files, detected through
+// gitattributes or by sniffing their content, are summarised by how much their size changed instead, as
+// are files stored with Git LFS, by the size of the content their pointers stand for.
+func printFileDiff(w io.Writer, repo *git.Repository, alg diff.Algorithm, words bool, attrs *git.Attributes, change git.Change) error {
+	patch := diff.FilePatch{
+		OldPath: change.Path,
+		NewPath: change.Path,
+		OldMode: uint32(change.From.Mode),
+		NewMode: uint32(change.To.Mode),
+	}
+	switch change.Status {
+	case git.StatusAdded:
+		patch.OldPath = ""
+	case git.StatusDeleted:
+		patch.NewPath = ""
+	}
+
+	// Submodules are recorded as commits, which have no content to compare.
+	if change.From.Mode == git.ModeSubmodule || change.To.Mode == git.ModeSubmodule {
+		fmt.Fprint(w, patch.Header())
+		fmt.Fprintf(w, "Submodule %s %s..%s\n", change.Path, shortHash(change.From.Hash), shortHash(change.To.Hash))
+		return nil
+	}
+	patch.OldHash, patch.NewHash = shortHash(change.From.Hash), shortHash(change.To.Hash)
+
+	var contents [2][]byte
+	for i, entry := range []git.TreeEntry{change.From, change.To} {
+		if entry.Hash == "" {
+			continue
+		}
+		data, err := repo.ReadBlob(entry.Hash)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", change.Path, err)
+		}
+		contents[i] = data
+	}
+
+	if summary, ok, err := lfsSummary(repo, change.Path, contents); err != nil || ok {
+		if ok {
+			fmt.Fprint(w, patch.Header())
+			fmt.Fprintln(w, summary)
+		}
+		return err
+	}
+
+	binary, known := attrs.IsBinary(change.Path)
+	if !known {
+		binary = diff.IsBinary(contents[0]) || diff.IsBinary(contents[1])
+	}
+	if binary {
+		oldSize, newSize := int64(len(contents[0])), int64(len(contents[1]))
+		fmt.Fprint(w, patch.Header())
+		note := ""
+		if attrs.IsLFS(change.Path) {
+			note = ", committed to git although .gitattributes stores it with LFS"
+		}
+		fmt.Fprintf(w, "Binary file %s changed, %s (%s -> %s%s)\n", change.Path, diff.SizeDelta(oldSize, newSize), diff.Size(oldSize), diff.Size(newSize), note)
+		return nil
+	}
+
+	patch.Hunks = alg.Diff(contents[0], contents[1], diff.DefaultContext)
+	if words {
+		fmt.Fprint(w, patch.WordString())
+	} else {
+		fmt.Fprint(w, patch.String())
+	}
+	return nil
+}
+
+// lfsSummary describes a change to a file stored with Git LFS, whose blobs are pointers to the content
+// rather than the content itself, by the sizes of the files they point to. It reports false if either
+// side of the change isn't a pointer, so that files committed without LFS are diffed as usual.
+func lfsSummary(repo *git.Repository, path string, contents [2][]byte) (string, bool, error) {
+	var pointers [2]git.LFSPointer
+	for i, data := range contents {
+		if data == nil {
+			continue
+		}
+		p, ok := git.ParseLFSPointer(data)
+		if !ok {
+			return "", false, nil
+		}
+		pointers[i] = p
+	}
+	current := pointers[1]
+	if current.OID == "" {
+		current = pointers[0]
+	}
+	if current.OID == "" {
+		return "", false, nil
+	}
+	downloaded, err := repo.HasLFSObject(current)
+	if err != nil {
+		return "", false, err
+	}
+	where := "not downloaded"
+	if downloaded {
+		where = "downloaded"
+	}
+	oldSize, newSize := pointers[0].Size, pointers[1].Size
+	return fmt.Sprintf("LFS object %s changed, %s (%s -> %s, %s)", path, diff.SizeDelta(oldSize, newSize), diff.Size(oldSize), diff.Size(newSize), where), true, nil
+}
+
+func shortHash(hash string) string {
+	if hash == "" {
+		return "0000000"
+	}
+	return hash[:7]
+}
+
+// loadGeneratedAttributes reads the repo's gitattributes, layering any plain.generated patterns from git config underneath.
+func loadGeneratedAttributes(a *app.App) (*git.Attributes, error) {
+	repo, err := git.OpenRepository()
+	if err != nil {
+		return nil, err
+	}
+
+	attrs, err := git.LoadAttributes(repo.WorkTree, repo.CommonDir)
+	if err != nil {
+		return nil, err
+	}
+
+	patterns, err := a.Git.GetConfigValues("plain.generated")
+	if err != nil {
+		return nil, err
+	}
+	for _, pattern := range patterns {
+		attrs.AddRule(filepath.ToSlash(pattern), "linguist-generated")
+	}
+	return attrs, nil
+}
+
+// previewGraph prints the commits and ref moves since since, an age, a date, or a commit.
+func previewGraph(a *app.App, cmd *cobra.Command, since string) error {
+	repo, err := git.OpenRepository()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	at, err := sinceTime(repo, since, now)
+	if err != nil {
+		return err
+	}
+
+	before, err := repo.RefsAt(at)
+	if err != nil {
+		return fmt.Errorf("failed to read reflogs: %w", err)
+	}
+	after, err := repo.Refs()
+	if err != nil {
+		return err
+	}
+	if all, _ := cmd.Flags().GetBool("all"); !all {
+		current, _ := repo.CurrentBranch()
+		inFocus, err := focus.Load(repo, current)
+		if err != nil {
+			return err
+		}
+		if before, after, err = inFocus.FilterRefs(repo, before, after); err != nil {
+			return err
+		}
+	}
+	parents, err := lineage(a, cmd)
+	if err != nil {
+		return err
+	}
+	changes, err := repo.DiffGraphs(before, after, parents)
+	if err != nil {
+		return fmt.Errorf("failed to compare history: %w", err)
+	}
+
+	dates, err := dateFormat(a)
+	if err != nil {
+		return err
+	}
+	if len(changes.Commits) == 0 && len(changes.Refs) == 0 {
+		fmt.Fprintf(a.Err, "plain: nothing changed since %s\n", dates.Format(at, now))
+		return nil
+	}
+
+	fmt.Fprintf(a.Err, "plain: %d new commit(s) since %s\n", len(changes.Commits), dates.Format(at, now))
+	for _, commit := range changes.Commits {
+		summary, _, _ := strings.Cut(commit.Message, "\n")
+		fmt.Fprintf(a.Out, "  %s %s  %s\n", commit.DisName(), commit.Author.Name, summary)
+	}
+	if len(changes.Refs) > 0 {
+		fmt.Fprintf(a.Err, "plain: %d ref(s) changed\n", len(changes.Refs))
+	}
+	for _, ref := range changes.Refs {
+		switch {
+		case ref.Old == "":
+			fmt.Fprintf(a.Out, "  %s created at %s\n", ref.Name, shortHash(ref.New))
+		case ref.New == "":
+			fmt.Fprintf(a.Out, "  %s deleted, was %s\n", ref.Name, shortHash(ref.Old))
+		case ref.Forced:
+			fmt.Fprintf(a.Out, "  %s %s -> %s (forced)\n", ref.Name, shortHash(ref.Old), shortHash(ref.New))
+		default:
+			fmt.Fprintf(a.Out, "  %s %s -> %s\n", ref.Name, shortHash(ref.Old), shortHash(ref.New))
+		}
+	}
+	return nil
+}
+
+// sinceTime parses the --changed-since value: an age before now, a date, or a revision, meaning the
+// time its commit was made.
+func sinceTime(repo *git.Repository, since string, now time.Time) (time.Time, error) {
+	if age, err := parseAge(since); err == nil {
+		return now.Add(-age), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, since, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	hash, err := repo.ResolveRevision(since)
+	if errors.Is(err, git.ErrAmbiguousRevision) {
+		return time.Time{}, err
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not an age, a date, or a commit", since)
+	}
+	commit, err := repo.ReadCommit(hash)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return commit.Committer.Time, nil
+}
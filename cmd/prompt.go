@@ -0,0 +1,251 @@
+package cmd
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/sim-deos/plain/internal/app"
+	"github.com/sim-deos/plain/internal/display"
+	"github.com/sim-deos/plain/internal/git"
+	"github.com/sim-deos/plain/internal/prompt"
+
+	"github.com/spf13/cobra"
+)
+
+func NewPromptCmd(a *app.App) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "prompt",
+		Short: "Prints a one line summary for your shell prompt",
+		Long: `Prints the current feature, how far it is ahead of and behind its upstream, and a star if
+		there are uncommitted changes, for example "login ↑2 ↓1 *". Nothing is printed outside a repository.
+
+		The summary is cached in .git/plain and reused for a few seconds while HEAD, the refs, and the
+		index stay the same, as plain fingerprint reports. When it has to be recomputed and that takes
+		longer than --budget, the last cached summary is printed instead, so a large repository never
+		holds up the prompt. Add it to your prompt with something like PS1='$(plain prompt) \$ '.
+
+		Whenever the cache is out of date, a detached background refresh also recomputes it, so the
+		next prompt is fast even when this one ran out of time. At most every plain.prefetchInterval
+		(5m by default, 0 to disable) the refresh first fetches the feature's upstream remote, keeping
+		the ahead and behind counts current without ever waiting on the network.
+
+		Setting plain.checkpointReminder to a duration such as 45m or 2h turns the star into "*!"
+		once the work tree has had changes for that long without a checkpoint. With
+		plain.checkpointNotify set to true, the background refresh also shows a desktop notification,
+		at most once per reminder period. Both are off by default.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error { return runPrompt(a, cmd, args) },
+	}
+	c.Flags().Duration("budget", prompt.DefaultBudget, "How long to spend recomputing before printing the cached summary")
+	c.Flags().Bool("refresh", false, "Fetch and recompute the cached summary without printing it")
+	c.Flags().MarkHidden("refresh")
+	return c
+}
+
+func runPrompt(a *app.App, cmd *cobra.Command, args []string) error {
+	budget, _ := cmd.Flags().GetDuration("budget")
+	refresh, _ := cmd.Flags().GetBool("refresh")
+
+	repo, err := git.OpenRepository()
+	if err != nil {
+		// Prompts run everywhere, so being outside a repository isn't worth complaining about.
+		return nil
+	}
+	branch, err := repo.CurrentBranch()
+	if err != nil {
+		return nil
+	}
+	// The config is read once, without git, since it runs on every prompt.
+	config, err := repo.Config()
+	if err != nil {
+		return nil
+	}
+	if refresh {
+		return refreshPrompt(a, repo, config, branch)
+	}
+
+	// The charset is detected rather than read from git config, which would cost a git process per prompt.
+	charset := display.DetectCharset()
+	// A bad plain.checkpointReminder is reported by the refresh rather than breaking every prompt.
+	reminder, _, _ := checkpointReminder(config)
+	show := func(state prompt.State) {
+		state.Overdue = state.CheckpointOverdue(reminder, time.Now())
+		fmt.Fprintln(a.Out, state.Format(charset))
+	}
+	cache := prompt.NewCache(repo.GitDir)
+	cached, ok := cache.Load()
+	ok = ok && cached.Branch == branch
+	if ok && cached.Fresh(promptKey(repo), prompt.DefaultTTL, time.Now()) {
+		show(cached)
+		return nil
+	}
+
+	if now := time.Now(); cache.ClaimRefresh(now) {
+		// A failed start only means the next prompt tries again.
+		if self, err := os.Executable(); err == nil {
+			background := exec.Command(self, "prompt", "--refresh")
+			background.Dir = repo.WorkTree
+			// Nobody is there to answer, so the fetch fails rather than asking for credentials.
+			background.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GCM_INTERACTIVE=never")
+			prompt.StartDetached(background)
+		}
+	}
+
+	type result struct {
+		state prompt.State
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		state, err := computePrompt(repo, config, branch, cached)
+		if err == nil {
+			err = cache.Save(state)
+		}
+		done <- result{state, err}
+	}()
+
+	fallback := prompt.State{Branch: branch}
+	if ok {
+		fallback = cached
+	}
+	select {
+	case r := <-done:
+		if r.err == nil {
+			fallback = r.state
+		}
+	case <-time.After(budget):
+	}
+	show(fallback)
+	return nil
+}
+
+// refreshPrompt runs in the background to fetch the upstream remote, when plain.prefetchInterval allows,
+// recompute the cached prompt state, and remind of an overdue checkpoint when plain.checkpointNotify asks.
+func refreshPrompt(a *app.App, repo *git.Repository, config *git.Config, branch string) error {
+	interval := prompt.DefaultFetchInterval
+	if value, _ := config.Get("plain.prefetchInterval"); value != "" {
+		var err error
+		if interval, err = parseAge(value); err != nil {
+			return fmt.Errorf("invalid plain.prefetchInterval %q: %w", value, err)
+		}
+	}
+
+	cache := prompt.NewCache(repo.GitDir)
+	if branch != "" {
+		remote, _, err := upstream(config, branch)
+		if err != nil {
+			return err
+		}
+		// Fetching a local upstream (remote ".") would be pointless.
+		if remote != "" && remote != "." && cache.ClaimFetch(interval, time.Now()) {
+			// A failed fetch, say while offline, still leaves the local state worth caching.
+			a.Git.Fetch(remote)
+		}
+	}
+
+	previous, _ := cache.Load()
+	state, err := computePrompt(repo, config, branch, previous)
+	if err != nil {
+		return err
+	}
+	if err := cache.Save(state); err != nil {
+		return err
+	}
+
+	reminder, notify, err := checkpointReminder(config)
+	if err != nil {
+		return err
+	}
+	if now := time.Now(); notify && state.CheckpointOverdue(reminder, now) && cache.ClaimReminder(reminder, now) {
+		// Without a way to show notifications, the prompt's "*!" still reminds.
+		prompt.Notify("plain", fmt.Sprintf("%s has had changes without a checkpoint for %s", cmp.Or(branch, "HEAD"),
+			now.Sub(state.DirtySince).Round(time.Minute)))
+	}
+	return nil
+}
+
+// checkpointReminder returns how long the work tree may have changes without a checkpoint before the
+// prompt reminds, from plain.checkpointReminder, 0 when it shouldn't, and whether plain.checkpointNotify
+// asks for a desktop notification too.
+func checkpointReminder(config *git.Config) (time.Duration, bool, error) {
+	value, _ := config.Get("plain.checkpointReminder")
+	if value == "" {
+		return 0, false, nil
+	}
+	after, err := parseAge(value)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid plain.checkpointReminder %q: %w", value, err)
+	}
+	notify, err := config.Bool("plain.checkpointNotify")
+	if err != nil {
+		return 0, false, err
+	}
+	return after, notify, nil
+}
+
+// computePrompt works out the prompt's state from scratch, except for how long the changes have gone
+// without a checkpoint, which carries on from previous, the state cached before.
+func computePrompt(repo *git.Repository, config *git.Config, branch string, previous prompt.State) (prompt.State, error) {
+	state := prompt.State{Branch: branch, Computed: time.Now()}
+	if branch != "" {
+		_, ref, err := upstream(config, branch)
+		if err != nil {
+			return state, err
+		}
+		if _, err := repo.ResolveRevision(ref); ref != "" && err == nil {
+			state.Upstream = ref
+			state.Ahead, state.Behind, err = repo.AheadBehind("HEAD", ref)
+			if err != nil {
+				return state, err
+			}
+		}
+	}
+
+	excludesFile, _ := config.Get("core.excludesFile")
+	ignore, err := git.LoadIgnore(repo.WorkTree, repo.CommonDir, excludesFile)
+	if err != nil {
+		return state, err
+	}
+	changes, err := repo.Status(ignore)
+	if err != nil {
+		return state, err
+	}
+	state.Dirty = len(changes) > 0
+	state.Key = promptKey(repo)
+
+	// The last checkpoint is HEAD's commit; an unborn branch has none yet.
+	var lastCheckpoint time.Time
+	if head, err := repo.ResolveRevision("HEAD"); err == nil {
+		if commit, err := repo.ReadCommit(head); err == nil {
+			lastCheckpoint = commit.Committer.Time
+		}
+	}
+	state.TrackDirty(previous, lastCheckpoint)
+	return state, nil
+}
+
+// upstream returns the remote branch tracks and the ref its upstream is kept in locally, resolved from
+// branch.<name>.remote, branch.<name>.merge, and the remote's fetch refspecs. Both are "" if it has no
+// upstream.
+func upstream(config *git.Config, branch string) (remote, ref string, err error) {
+	up, err := config.Upstream(branch)
+	if errors.Is(err, git.ErrNoUpstream) {
+		return "", "", nil
+	}
+	return up.Remote, up.Ref, err
+}
+
+// promptKey identifies the state of the repository the prompt summarises without reading any objects.
+// The upstream is one of the refs the fingerprint covers.
+func promptKey(repo *git.Repository) string {
+	fingerprint, err := repo.Fingerprint()
+	if err != nil {
+		// An unreadable fingerprint matches no cached state, so the prompt is recomputed.
+		return ""
+	}
+	return fingerprint.String()
+}
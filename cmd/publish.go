@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/sim-deos/plain/internal/app"
+
+	"github.com/spf13/cobra"
+)
+
+func NewPublishCmd(a *app.App) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "publish",
+		Short: "Shares this feature on the remote",
+		Long: `Pushes this feature to the remote so others can see it.
+		With --pr, also opens a pull request described from the feature's commits.
+		Add --draft to open it as a draft that can't be merged until it is marked ready.
+
+		Before pushing, plain estimates how much the remote doesn't have yet and warns when it is more
+		than git config plain.pushWarnCommits commits (250 by default) or plain.pushWarnSize bytes (50m),
+		which usually means the feature was started from the wrong branch. At a terminal it asks before
+		pushing and can list the commits first. Set either to 0 to turn its check off.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error { return runPublish(a, cmd, args) },
+	}
+	c.Flags().Bool("pr", false, "Open a pull request for the feature")
+	c.Flags().Bool("draft", false, "Open the pull request as a draft")
+	addPullRequestFlags(c)
+	return c
+}
+
+func runPublish(a *app.App, cmd *cobra.Command, args []string) error {
+	openPR, _ := cmd.Flags().GetBool("pr")
+	draft, _ := cmd.Flags().GetBool("draft")
+
+	if draft && !openPR {
+		return fmt.Errorf("--draft only applies with --pr")
+	}
+
+	if !openPR {
+		branch, err := a.Git.GetCurrentBranch()
+		if err != nil {
+			return err
+		}
+		if err := checkPushSize(a, cmd, defaultRemote, branch); err != nil {
+			return err
+		}
+		if err := a.Git.Push(defaultRemote, branch); err != nil {
+			return fmt.Errorf("failed to push %s: %w", branch, err)
+		}
+		fmt.Fprintf(a.Err, "plain: published %s to %s\n", branch, defaultRemote)
+		return nil
+	}
+
+	_, pr, err := openPullRequest(a, cmd, draft)
+	if err != nil {
+		return err
+	}
+
+	kind := "pull request"
+	if pr.Draft {
+		kind = "draft pull request"
+	}
+	fmt.Fprintf(a.Err, "plain: opened %s #%d: %s\n", kind, pr.Number, pr.URL)
+	return nil
+}
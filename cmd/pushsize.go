@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sim-deos/plain/internal/app"
+	"github.com/sim-deos/plain/internal/diff"
+	"github.com/sim-deos/plain/internal/git"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	defaultPushWarnCommits = 250
+	defaultPushWarnSize    = 50 << 20
+)
+
+var errPushCancelled = errors.New("push cancelled")
+
+// checkPushSize warns before branch is pushed to remote when it would send more commits than git config
+// plain.pushWarnCommits or more data, counting the Git LFS files it adds, than plain.pushWarnSize, using
+// values like 20m, which usually means the feature was started from the wrong branch or has another
+// branch's history merged into it. At a terminal it asks whether to push anyway, offering to list the
+// commits first, and otherwise only warns. Setting either to 0 turns its check off.
+func checkPushSize(a *app.App, cmd *cobra.Command, remote, branch string) error {
+	repo, err := git.OpenRepository()
+	if err != nil {
+		return err
+	}
+	config, err := repo.Config()
+	if err != nil {
+		return err
+	}
+	maxCommits, err := config.Int("plain.pushWarnCommits", defaultPushWarnCommits)
+	if err != nil {
+		return err
+	}
+	maxSize, err := config.Int("plain.pushWarnSize", defaultPushWarnSize)
+	if err != nil {
+		return err
+	}
+	if maxCommits <= 0 && maxSize <= 0 {
+		return nil
+	}
+
+	// The estimate only helps catch mistakes, so a history it can't read is no reason not to push.
+	estimate, err := repo.EstimatePush(branch, remote)
+	if err != nil {
+		return nil
+	}
+	commits := len(estimate.Commits)
+	if (maxCommits <= 0 || int64(commits) <= maxCommits) && (maxSize <= 0 || estimate.Bytes+estimate.LFSBytes <= maxSize) {
+		return nil
+	}
+
+	line := func(commit git.Commit) string {
+		summary, _, _ := strings.Cut(commit.Message, "\n")
+		return shortHash(commit.Hash) + " " + summary
+	}
+	merges := 0
+	for _, commit := range estimate.Commits {
+		if len(commit.Parents) > 1 {
+			merges++
+		}
+	}
+	fmt.Fprintf(a.Err, "plain: warning: pushing %s would send %d commit(s) and about %s to %s\n", branch, commits, diff.Size(estimate.Bytes), remote)
+	if estimate.LFSBytes > 0 {
+		fmt.Fprintf(a.Err, "plain: along with up to %s of Git LFS files\n", diff.Size(estimate.LFSBytes))
+	}
+	if merges > 0 {
+		fmt.Fprintf(a.Err, "plain: %d of them are merges, so %s may contain another branch's history\n", merges, branch)
+	}
+	if commits > 0 {
+		fmt.Fprintf(a.Err, "plain: oldest: %s\n", line(estimate.Commits[commits-1]))
+		fmt.Fprintf(a.Err, "plain: newest: %s\n", line(estimate.Commits[0]))
+	}
+
+	if !promptable(cmd) {
+		return nil
+	}
+	input := bufio.NewScanner(cmd.InOrStdin())
+	for {
+		fmt.Fprint(a.Err, "plain: push anyway? [y/N, l to list the commits] ")
+		// Running out of input, as from /dev/null, means nobody is there to answer.
+		if !input.Scan() {
+			fmt.Fprintln(a.Err)
+			return nil
+		}
+		switch strings.ToLower(strings.TrimSpace(input.Text())) {
+		case "y", "yes":
+			return nil
+		case "l", "list":
+			for _, commit := range estimate.Commits {
+				fmt.Fprintf(a.Err, "  %s\n", line(commit))
+			}
+		default:
+			return errPushCancelled
+		}
+	}
+}
+
+// promptable reports whether cmd reads its input from a terminal, where a question it asks reaches
+// someone who can answer it.
+func promptable(cmd *cobra.Command) bool {
+	f, ok := cmd.InOrStdin().(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
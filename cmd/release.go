@@ -0,0 +1,248 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/sim-deos/plain/internal/app"
+	"github.com/sim-deos/plain/internal/forge"
+	"github.com/sim-deos/plain/internal/git"
+	"github.com/sim-deos/plain/internal/release"
+
+	"github.com/spf13/cobra"
+)
+
+func NewReleaseCmd(a *app.App) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "release <version>",
+		Short: "Tags a release and writes its notes",
+		Long: `Creates an annotated tag for version on HEAD, with release notes generated from the commits
+		since the previous tag. With --publish, the tag is pushed and a release is created on the forge
+		from the same notes, with every file matching the plain.releaseArtifact config patterns attached.
+		Publishing a tag that already exists creates its missing forge release.
+
+		In a monorepo, --scope releases one component: only commits touching its directory make it
+		into the notes, and its tags are prefixed with the directory's name (services/a/v1.2.0 becomes
+		a/v1.2.0), or with git config plain.<directory>.tagPrefix.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error { return runRelease(a, cmd, args) },
+	}
+	c.Flags().Bool("publish", false, "Push the tag and publish a release on the forge")
+	addScopeFlag(c)
+	return needsGit(locksRepo(a, c), "create and push the tag")
+}
+
+// releaseScope is the part of the repository a release covers: all of it, or one component of a monorepo.
+type releaseScope struct {
+	Dir       string // The component's slash separated directory, or "" for the whole repository
+	TagPrefix string // Prepended to versions to name the scope's tags
+}
+
+func addScopeFlag(c *cobra.Command) {
+	c.Flags().String("scope", "", "Release only the component in this directory")
+}
+
+// scopeFromFlags returns the scope selected with --scope.
+func scopeFromFlags(a *app.App, cmd *cobra.Command) (releaseScope, error) {
+	dir, _ := cmd.Flags().GetString("scope")
+	dir = strings.Trim(filepath.ToSlash(filepath.Clean(dir)), "/")
+	if dir == "" || dir == "." {
+		return releaseScope{}, nil
+	}
+
+	scope := releaseScope{Dir: dir, TagPrefix: path.Base(dir) + "/"}
+	configured, err := a.Git.GetConfigValues("plain." + dir + ".tagPrefix")
+	if err != nil {
+		return releaseScope{}, err
+	}
+	if len(configured) > 0 {
+		scope.TagPrefix = configured[len(configured)-1]
+	}
+	return scope, nil
+}
+
+// tag returns the name of the scope's tag for version, which may already carry the prefix.
+func (s releaseScope) tag(version string) string {
+	return s.TagPrefix + strings.TrimPrefix(version, s.TagPrefix)
+}
+
+// owns reports whether the tag name is one of the scope's versions. Tags of other components, which
+// have their own prefix, are never the whole repository's.
+func (s releaseScope) owns(name string) bool {
+	version, ok := strings.CutPrefix(name, s.TagPrefix)
+	return ok && !strings.Contains(version, "/")
+}
+
+// commits returns the commits in base..head that belong to the scope, where an empty base means all of head's history.
+func (s releaseScope) commits(repo *git.Repository, base, head string) ([]git.Commit, error) {
+	commits, err := repo.CommitsBetween(base, head)
+	if err != nil || s.Dir == "" {
+		return commits, err
+	}
+	return repo.CommitsTouching(commits, s.Dir)
+}
+
+func runRelease(a *app.App, cmd *cobra.Command, args []string) error {
+	publish, _ := cmd.Flags().GetBool("publish")
+
+	scope, err := scopeFromFlags(a, cmd)
+	if err != nil {
+		return err
+	}
+	version := scope.tag(args[0])
+
+	repo, err := git.OpenRepository()
+	if err != nil {
+		return err
+	}
+
+	_, err = repo.ResolveRevision("refs/tags/" + version)
+	exists := err == nil
+	if err != nil && !errors.Is(err, git.ErrUnknownRevision) {
+		return err
+	}
+	if exists && !publish {
+		return fmt.Errorf("%s is already tagged, use --publish to publish its release", version)
+	}
+
+	notes, err := releaseNotes(repo, scope, version, exists)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		if err := a.Git.CreateTag(version, version+"\n\n"+notes); err != nil {
+			return fmt.Errorf("failed to tag %s: %w", version, err)
+		}
+		fmt.Fprintf(a.Err, "plain: tagged %s\n\n", version)
+	}
+	fmt.Fprint(a.Out, notes)
+
+	if !publish {
+		return nil
+	}
+	return publishRelease(a, repo, scope, version, notes)
+}
+
+// releaseNotes generates notes for the version tag from the scope's commits since its previous tag. When
+// tagged is set, version is an existing tag; otherwise it is about to be created on HEAD.
+func releaseNotes(repo *git.Repository, scope releaseScope, version string, tagged bool) (string, error) {
+	// An existing tag's predecessor is searched for from its first parent, so the tag doesn't find itself.
+	head, searchFrom := "HEAD", "HEAD"
+	if tagged {
+		head = "refs/tags/" + version
+		history, err := git.GetHistoryForRevision(head)
+		if err != nil {
+			return "", err
+		}
+		searchFrom = ""
+		if len(history.Head.Parents) > 0 {
+			searchFrom = history.Head.Parents[0]
+		}
+	}
+
+	previous := ""
+	if searchFrom != "" {
+		tag, err := repo.NearestTag(searchFrom, scope.owns)
+		if err != nil && !errors.Is(err, git.ErrNoTag) {
+			return "", err
+		}
+		previous = tag
+	}
+
+	base := ""
+	if previous != "" {
+		base = "refs/tags/" + previous
+	}
+	commits, err := scope.commits(repo, base, head)
+	if err != nil {
+		return "", fmt.Errorf("failed to read release commits: %w", err)
+	}
+	return release.Notes(commits), nil
+}
+
+func publishRelease(a *app.App, repo *git.Repository, scope releaseScope, version, notes string) error {
+	artifacts, err := releaseArtifacts(a, repo.WorkTree, scope)
+	if err != nil {
+		return err
+	}
+
+	client, _, err := forgeClient(a, defaultRemote)
+	if err != nil {
+		return err
+	}
+
+	if err := a.Git.PushTag(defaultRemote, version); err != nil {
+		return fmt.Errorf("failed to push %s: %w", version, err)
+	}
+
+	// Semantic versions with a pre-release suffix, such as v1.2.0-rc.1, aren't final. The tag's scope,
+	// as in svc-a/v1.2.0, isn't part of the version.
+	parsed, _ := release.ParseVersion(strings.TrimPrefix(version, scope.TagPrefix))
+
+	ctx := context.Background()
+	published, err := client.CreateRelease(ctx, forge.ReleaseOptions{
+		Tag:        version,
+		Name:       version,
+		Body:       notes,
+		Prerelease: parsed.Prerelease != "",
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, artifact := range artifacts {
+		if err := uploadArtifact(ctx, client, published, artifact); err != nil {
+			return err
+		}
+		fmt.Fprintf(a.Err, "plain: attached %s\n", filepath.Base(artifact))
+	}
+	fmt.Fprintf(a.Err, "plain: published %s: %s\n", version, published.URL)
+	return nil
+}
+
+// releaseArtifacts returns the files matching the scope's release artifact patterns: plain.releaseArtifact
+// for the whole repository, or plain.<directory>.releaseArtifact relative to a component's directory.
+// A pattern that matches nothing is an error, so a missing build output isn't silently left out.
+func releaseArtifacts(a *app.App, root string, scope releaseScope) ([]string, error) {
+	key := "plain.releaseArtifact"
+	if scope.Dir != "" {
+		key = "plain." + scope.Dir + ".releaseArtifact"
+	}
+	patterns, err := a.Git.GetConfigValues(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(filepath.Join(root, filepath.FromSlash(scope.Dir), filepath.FromSlash(pattern)))
+		if err != nil {
+			return nil, fmt.Errorf("bad %s pattern %q: %w", key, pattern, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no release artifacts match %q", pattern)
+		}
+		paths = append(paths, matches...)
+	}
+	return paths, nil
+}
+
+func uploadArtifact(ctx context.Context, client forge.Client, published forge.Release, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return client.UploadReleaseAsset(ctx, published, filepath.Base(path), f, info.Size())
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/sim-deos/plain/internal/app"
+	"github.com/sim-deos/plain/internal/git"
+
+	"github.com/spf13/cobra"
+)
+
+func NewRestoreCmd(a *app.App) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "restore <file>",
+		Short: "Restores branches from a backup file",
+		Long: `Reads the branches and tags in a file written by plain backup, or by git bundle create, into
+		the repository. Missing branches are created, and branches the file has newer commits for are
+		fast-forwarded. A branch with commits of its own that the file doesn't have is left alone, as is
+		the checked out branch, and the commit the file has for it is printed so it can be compared or
+		merged by hand. Tags are created but never moved. To restore into a new directory instead, use
+		git clone <file> <dir>.
+
+		A file holding a single branch builds on commits it doesn't include, which the repository must
+		have already; fetch the branch it was based on first if it doesn't. --list prints what the file
+		holds without changing anything.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error { return runRestore(a, cmd, args[0]) },
+	}
+	c.Flags().Bool("list", false, "Print the file's branches and tags without restoring them")
+	return locksRepo(a, c)
+}
+
+func runRestore(a *app.App, cmd *cobra.Command, path string) error {
+	if list, _ := cmd.Flags().GetBool("list"); list {
+		bundle, err := git.ReadBundle(path)
+		if err != nil {
+			return err
+		}
+		for _, ref := range bundle.Refs {
+			fmt.Fprintf(a.Out, "%s %s\n", shortHash(ref.Hash), ref.Name)
+		}
+		for _, prerequisite := range bundle.Prerequisites {
+			fmt.Fprintf(a.Out, "needs %s %s\n", shortHash(prerequisite.Hash), prerequisite.Name)
+		}
+		return nil
+	}
+
+	repo, err := git.OpenRepository()
+	if err != nil {
+		return err
+	}
+	who, err := identity(a)
+	if err != nil {
+		return err
+	}
+	bundle, err := repo.Unbundle(path)
+	if errors.Is(err, git.ErrMissingPrerequisites) {
+		return fmt.Errorf("%s builds on commits this repository doesn't have, fetch the branch it was based on first: %w", path, err)
+	}
+	if err != nil {
+		return err
+	}
+	current, err := repo.CurrentBranch()
+	if err != nil {
+		return err
+	}
+
+	reason := "restore: from " + filepath.Base(path)
+	restored, skipped := 0, 0
+	for _, ref := range bundle.Refs {
+		branch, isBranch := strings.CutPrefix(ref.Name, "refs/heads/")
+		name := branch
+		if tag, isTag := strings.CutPrefix(ref.Name, "refs/tags/"); isTag {
+			name = "tag " + tag
+		} else if !isBranch {
+			continue // HEAD, and refs plain doesn't manage
+		}
+
+		old, err := repo.ResolveRevision(ref.Name)
+		exists := err == nil
+		if err != nil && !errors.Is(err, git.ErrUnknownRevision) {
+			return err
+		}
+		switch {
+		case old == ref.Hash:
+			continue
+		case isBranch && branch == current:
+			fmt.Fprintf(a.Err, "plain: left %s alone since it's checked out, the file has it at %s\n", name, shortHash(ref.Hash))
+			skipped++
+			continue
+		case !exists:
+			if err := repo.UpdateRef(ref.Name, git.ZeroHash, ref.Hash, who, reason); err != nil {
+				return err
+			}
+			fmt.Fprintf(a.Err, "plain: created %s at %s\n", name, shortHash(ref.Hash))
+			restored++
+			continue
+		case !isBranch:
+			fmt.Fprintf(a.Err, "plain: left %s alone, the file has it at %s\n", name, shortHash(ref.Hash))
+			skipped++
+			continue
+		}
+
+		ahead, behind, err := repo.AheadBehind(ref.Hash, old)
+		if err != nil {
+			return err
+		}
+		if behind > 0 {
+			fmt.Fprintf(a.Err, "plain: left %s alone, it has %d commit(s) the file doesn't; the file has it at %s\n", name, behind, shortHash(ref.Hash))
+			skipped++
+			continue
+		}
+		if err := repo.UpdateRef(ref.Name, old, ref.Hash, who, reason); err != nil {
+			return err
+		}
+		fmt.Fprintf(a.Err, "plain: fast-forwarded %s by %d commit(s)\n", name, ahead)
+		restored++
+	}
+	if restored == 0 && skipped == 0 {
+		fmt.Fprintf(a.Err, "plain: the repository already had everything in %s\n", path)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sim-deos/plain/internal/app"
+	"github.com/sim-deos/plain/internal/forge"
+	"github.com/sim-deos/plain/internal/git"
+
+	"github.com/spf13/cobra"
+)
+
+func NewRewriteCmd(a *app.App) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "rewrite [<branch>]",
+		Short: "Rewrites a branch's history to remove files or fix emails",
+		Long: `Rewrites every commit of a branch (the current one by default), removing files committed by
+		mistake with --remove-path, or replacing a wrong email address in authors and committers with
+		--fix-email old@example.com=new@example.com. Both can be repeated. When checkpoints get Gerrit
+		Change-Id trailers, rewritten commits keep theirs, and those without one are given one.
+		The result goes on a new branch, named with --to or <branch>-rewritten; the original branch is
+		left alone. Rewritten commits get new hashes, so review the result before replacing the original
+		with it, and expect to force push and ask collaborators to reset their copies.
+		Removing a secret from history doesn't make it secret again: anyone who fetched it may still have
+		it, so revoke or rotate it as well.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error { return runRewrite(a, cmd, args) },
+	}
+	c.Flags().StringArray("remove-path", nil, "A file or directory to remove from every commit")
+	c.Flags().StringArray("fix-email", nil, "An email to replace, as old=new")
+	c.Flags().String("to", "", "The branch to create with the result (default <branch>-rewritten)")
+	c.ValidArgsFunction = completeFeature
+	return locksRepo(a, c)
+}
+
+func runRewrite(a *app.App, cmd *cobra.Command, args []string) error {
+	paths, _ := cmd.Flags().GetStringArray("remove-path")
+	fixes, _ := cmd.Flags().GetStringArray("fix-email")
+	if len(paths) == 0 && len(fixes) == 0 {
+		return errors.New("nothing to rewrite, pass --remove-path or --fix-email")
+	}
+
+	opts := git.RewriteOptions{RemovePaths: paths, Emails: map[string]string{}}
+	for _, fix := range fixes {
+		old, new, ok := strings.Cut(fix, "=")
+		if !ok || old == "" || new == "" || strings.ContainsAny(new, "<>\n") {
+			return fmt.Errorf("invalid --fix-email %q, expected old@example.com=new@example.com", fix)
+		}
+		opts.Emails[old] = new
+	}
+
+	// Gerrit takes only commits with a Change-Id, so rewritten commits missing one are given one, made
+	// from the commit as it was so that rewriting the same history again gives the same ones.
+	changeIDs, err := changeIDsEnabled(a)
+	if err != nil {
+		return err
+	}
+	if changeIDs {
+		opts.Message = func(commit git.Commit) string {
+			return forge.WithChangeID(commit.Message, forge.NewChangeID(commit.Tree, commit.Parents, commit.Author, commit.Message))
+		}
+	}
+
+	repo, err := git.OpenRepository()
+	if err != nil {
+		return err
+	}
+	branch := ""
+	if len(args) == 1 {
+		branch = args[0]
+	} else if branch, err = repo.CurrentBranch(); err != nil {
+		return err
+	} else if branch == "" {
+		return withHint(errDetachedHead, "name the branch to rewrite")
+	}
+	target, _ := cmd.Flags().GetString("to")
+	if target == "" {
+		target = branch + "-rewritten"
+	}
+	if _, err := repo.ResolveRevision("refs/heads/" + target); err == nil {
+		return withHint(fmt.Errorf("%w: %s", errBranchExists, target), "choose another with --to")
+	}
+
+	result, err := repo.RewriteHistory("refs/heads/"+branch, opts)
+	if err != nil {
+		return fmt.Errorf("failed to rewrite %s: %w", branch, err)
+	}
+	if len(result.Rewritten) == 0 {
+		fmt.Fprintf(a.Err, "plain: nothing in %s matched, no branch created\n", branch)
+		return nil
+	}
+
+	who, err := identity(a)
+	if err != nil {
+		return err
+	}
+	if err := repo.UpdateRef("refs/heads/"+target, git.ZeroHash, result.Head, who, "rewrite: from "+branch); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.Err, "plain: rewrote %d commit(s) of %s onto the new branch %s\n", len(result.Rewritten), branch, target)
+	fmt.Fprintf(a.Err, "warning: %s is unchanged, and its commits, with what was removed, are still in its\n", branch)
+	fmt.Fprintln(a.Err, "  reflog, on any remote it was pushed to, and in every clone that fetched it")
+	if len(paths) > 0 {
+		fmt.Fprintln(a.Err, "warning: if a removed file held a secret, revoke or rotate it; rewriting doesn't unpublish it")
+	}
+	fmt.Fprintf(a.Err, "\nreview %s, then replace %s with it: git branch -f %s %s, and push with --force-with-lease\n", target, branch, branch, target)
+	return nil
+}
@@ -1,11 +1,32 @@
 package cmd
 
 import (
+	"log/slog"
+	"os"
+	"sync"
+
 	"github.com/sim-deos/plain/internal/app"
+	"github.com/sim-deos/plain/internal/git"
+	"github.com/sim-deos/plain/internal/settings"
+
 	"github.com/spf13/cobra"
 )
 
+// NewRootCmd returns the plain command, with every command under it. Commands write to a's Out and Err,
+// or to standard output and standard error when they aren't set, and log nothing unless logging is
+// turned on. Every key of plain's own in git config, however it's read, is overridden by its PLAIN_
+// environment variable.
 func NewRootCmd(a *app.App) *cobra.Command {
+	git.SetConfigOverride(settings.EnvOverride)
+	if a.Out == nil {
+		a.Out = os.Stdout
+	}
+	if a.Err == nil {
+		a.Err = os.Stderr
+	}
+	if a.Log == nil {
+		a.Log = slog.New(slog.DiscardHandler)
+	}
 	rootCmd := &cobra.Command{
 		Use:   "plain",
 		Short: "A brief description of your application",
@@ -20,9 +41,58 @@ func NewRootCmd(a *app.App) *cobra.Command {
 	rootCmd.AddCommand(
 		NewStartCmd(a),
 		NewPreviewCmd(a),
+		NewStatusCmd(a),
 		NewInitCmd(a),
 		NewDoneCmd(a),
 		NewCheckpointCmd(a),
+		NewPathCmd(a),
+		NewLostCmd(a),
+		NewListCmd(a),
+		NewAuthCmd(a),
+		NewDescribeCmd(a),
+		NewPublishCmd(a),
+		NewCommentsCmd(a),
+		NewChecksCmd(a),
+		NewReleaseCmd(a),
+		NewVersionCmd(a),
+		NewTutorialCmd(a),
+		NewPromptCmd(a),
+		NewExportCmd(a),
+		NewMetaCmd(a),
+		NewCopyCmd(a),
+		NewUndoCmd(a),
+		NewOntoCmd(a),
+		NewRewriteCmd(a),
+		NewDoctorCmd(a),
+		NewFingerprintCmd(a),
+		NewBackupCmd(a),
+		NewRestoreCmd(a),
+		NewBugreportCmd(a),
+		NewPairCmd(a),
+		NewSettingsTopic(),
 	)
+	rootCmd.SetOut(a.Out)
+	rootCmd.SetErr(a.Err)
+	config := sync.OnceValues(startupConfig)
+	addPerfFlag(rootCmd)
+	addBackendFlag(rootCmd, a, config)
+	addSettings(rootCmd, a)
+	addLogging(rootCmd, a, config)
+	silenceErrors(rootCmd)
+	if a.GitMissing {
+		disableGitCommands(rootCmd)
+	}
 	return rootCmd
 }
+
+// startupConfig reads the git config that the root command's hooks look plain's own keys up in before
+// any command runs: the repository's, or only the global config outside a repository. It reads the
+// files itself rather than running git config for each key, so a command as frequent as plain prompt
+// doesn't start git just to get going.
+func startupConfig() (*git.Config, error) {
+	config, err := git.GlobalConfig()
+	if repo, openErr := git.OpenRepository(); openErr == nil {
+		config, err = repo.Config()
+	}
+	return config, err
+}
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"strconv"
+
+	"github.com/sim-deos/plain/internal/app"
+	"github.com/sim-deos/plain/internal/git"
+	"github.com/sim-deos/plain/internal/settings"
+
+	"github.com/spf13/cobra"
+)
+
+// settingFlags are the flags whose defaults come from the settings, when they are set. Every --from and
+// --into, but for the settingExceptions, names the branch features start from or are finished into.
+var settingFlags = map[string]func(settings.Settings) string{
+	"from":         func(s settings.Settings) string { return s.Base },
+	"into":         func(s settings.Settings) string { return s.Base },
+	"merge-method": func(s settings.Settings) string { return string(s.MergeMethod) },
+	"stale-after":  func(s settings.Settings) string { return s.StaleAfter },
+	"json":         func(s settings.Settings) string { return outputFlag(s, "json") },
+	"porcelain":    func(s settings.Settings) string { return outputFlag(s, "porcelain") },
+}
+
+// settingExceptions are the flags, by command and name, that are named like one of the settingFlags but
+// mean something else. Without --from, onto takes the feature to have started from the branch it moves
+// onto, so defaulting it to branches.base would move features off a branch they were never on.
+var settingExceptions = map[string]bool{
+	"onto --from": true,
+}
+
+// outputFlag is what --json or --porcelain, named by format, defaults to under the output.format setting.
+func outputFlag(s settings.Settings, format string) string {
+	if s.Output == "" {
+		return ""
+	}
+	return strconv.FormatBool(s.Output == format)
+}
+
+// NewSettingsTopic is the help topic describing the team's rules, the user's defaults, and the order in
+// which they and everything else that sets them are consulted.
+func NewSettingsTopic() *cobra.Command {
+	return &cobra.Command{
+		Use:   "settings",
+		Short: "How the team's rules and your own defaults are set",
+		Long: `A team can commit its own rules in .plain/team.toml, which plain follows on top of the forge's:
+
+		  [merge]
+		  strategy = "squash"              # merge, rebase, or squash
+		  [branches]
+		  protected = ["main", "release/*"] # only take pull requests
+		  template = "{user}/{name}"       # how plain start names features
+		  [checks]
+		  required = ["build", "test"]
+
+		Any strategy but merge keeps history linear, and auto-merge uses it. Your git config can only
+		tighten these rules: plain.protectedBranch and plain.requiredCheck add to them, plain.mergeStrategy
+		may choose a stricter strategy, and plain.branchTemplate applies when the team has none.
+
+		Your own defaults go in .plain.toml at the root of the repository, or in plain/config.toml under
+		your config directory for every repository, with the repository's taking precedence:
+
+		  [branches]
+		  base = "develop"                 # what --from and --into default to, instead of main (but not onto --from)
+		  template = "{name}"              # how plain start names features, if nothing else says
+		  [done]
+		  merge-method = "squash"          # what --merge-method defaults to
+		  [display]
+		  charset = "ascii"                # as git config plain.charset
+		  date-format = "relative"         # as git config plain.dateFormat
+		  [list]
+		  stale-after = "2w"               # as git config plain.staleAfter
+		  [forge."github.example.com"]
+		  token-env = "WORK_GITHUB_TOKEN"  # where to find the host's token, in config.toml only
+
+		  [output]
+		  format = "json"                  # what --json and --porcelain default to: text, json, or porcelain
+
+		Each can also be set for a single run in the environment, which comes before both files:
+		PLAIN_BASE_BRANCH, PLAIN_BRANCH_TEMPLATE, PLAIN_MERGE_METHOD, PLAIN_CHARSET, PLAIN_DATE_FORMAT,
+		PLAIN_STALE_AFTER, and PLAIN_OUTPUT, or PLAIN_JSON=1 for JSON. Likewise every plain.* git config key can be set with
+		PLAIN_ and its name in upper snake case, as PLAIN_GIT_TIMEOUT sets plain.gitTimeout.
+
+		So a flag comes first, then the environment, then git config, then .plain.toml, then config.toml,
+		except that the team's rules always apply, and the base plain start recorded for a feature comes
+		before everything but --from and --into.`,
+	}
+}
+
+// addSettings has every command under root load the user's and the current repository's settings into
+// a before it runs, and take the defaults of its flags from them.
+func addSettings(root *cobra.Command, a *app.App) {
+	preRun := root.PersistentPreRunE
+	root.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if preRun != nil {
+			if err := preRun(cmd, args); err != nil {
+				return err
+			}
+		}
+		return applySettings(a, cmd)
+	}
+}
+
+// applySettings loads the settings into a and sets the flags cmd wasn't given to them. The flags are
+// still reported as not changed, so what else they default to, like the team's merge strategy, comes
+// before the settings.
+func applySettings(a *app.App, cmd *cobra.Command) error {
+	root := ""
+	if repo, err := git.OpenRepository(); err == nil {
+		root = repo.WorkTree
+	}
+	var err error
+	if a.Settings, err = settings.Load(root); err != nil {
+		return err
+	}
+	// Choosing an output format with either flag overrides the setting for both.
+	outputChosen := cmd.Flags().Changed("json") || cmd.Flags().Changed("porcelain")
+	for name, setting := range settingFlags {
+		flag := cmd.Flags().Lookup(name)
+		if flag == nil || flag.Changed || setting(a.Settings) == "" || settingExceptions[cmd.Name()+" --"+name] || outputChosen && (name == "json" || name == "porcelain") {
+			continue
+		}
+		if err := flag.Value.Set(setting(a.Settings)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
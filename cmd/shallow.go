@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+
+	"github.com/sim-deos/plain/internal/app"
+	"github.com/sim-deos/plain/internal/forge"
+	"github.com/sim-deos/plain/internal/git"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultDeepenBy is how many more commits of history are offered when a shallow clone's runs out.
+const defaultDeepenBy = 50
+
+// offerDeepen says that the history of rev stops where the shallow clone was cut off, and fetches more
+// of it from origin, reporting whether it did. It fetches by the command's --deepen flag when that is
+// set, and otherwise asks at a terminal; anywhere else it only says how to fetch more.
+func offerDeepen(a *app.App, cmd *cobra.Command, rev string) (bool, error) {
+	depth, _ := cmd.Flags().GetInt("deepen")
+	fmt.Fprintf(a.Err, "plain: the history of %s stops where the shallow clone was cut off\n", rev)
+	if depth <= 0 {
+		depth = defaultDeepenBy
+		if !promptable(cmd) {
+			fmt.Fprintf(a.Err, "plain: run again with --deepen %d to fetch more of it from %s\n", depth, defaultRemote)
+			return false, nil
+		}
+		fmt.Fprintf(a.Err, "plain: fetch %d more commits of history from %s? [y/N] ", depth, defaultRemote)
+		input := bufio.NewScanner(cmd.InOrStdin())
+		if !input.Scan() {
+			fmt.Fprintln(a.Err)
+			return false, nil
+		}
+		if answer := strings.ToLower(strings.TrimSpace(input.Text())); answer != "y" && answer != "yes" {
+			return false, nil
+		}
+	}
+
+	repo, err := git.OpenRepository()
+	if err != nil {
+		return false, err
+	}
+	url, err := remoteURL(defaultRemote)
+	if err != nil {
+		return false, err
+	}
+	if url == "" {
+		return false, fmt.Errorf("cannot fetch more history: %w: %s", git.ErrNoRemote, defaultRemote)
+	}
+	host, err := forge.RemoteHost(url)
+	if err != nil {
+		return false, fmt.Errorf("cannot fetch more history: %w", err)
+	}
+	client, err := newHTTPClient(a, host)
+	if err != nil {
+		return false, err
+	}
+	objects, err := repo.Deepen(client, defaultRemote, depth)
+	if err != nil {
+		return false, fmt.Errorf("cannot fetch more history from %s: %w", defaultRemote, err)
+	}
+	fmt.Fprintf(a.Err, "plain: fetched %d more commits of history (%d objects)\n", depth, objects)
+	return true, nil
+}
@@ -2,8 +2,11 @@ package cmd
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/sim-deos/plain/internal/app"
+	"github.com/sim-deos/plain/internal/git"
+	"github.com/sim-deos/plain/internal/meta"
 
 	"github.com/spf13/cobra"
 )
@@ -12,20 +15,54 @@ func NewStartCmd(a *app.App) *cobra.Command {
 	c := &cobra.Command{
 		Use:   "start",
 		Short: "Starts a new feature",
-		Long: `Starts a new faeture based off of the main branch by default to help starting a new feature quickly.
+		Long: `Starts a new feature based off of the main branch by default to help starting a new feature quickly.
 		To start a feature from a specific branch, use --from <branch-name>.
-		All feature names must be one word, use hyphens where needed.`,
+		All feature names must be one word, use hyphens where needed. When the team's .plain/team.toml
+		or git config plain.branchTemplate sets a branch template, such as {user}/{name}, the feature's
+		branch is named after it, with {user} standing for your user.email before the @. The branches.template
+		setting in .plain.toml or plain's config.toml does the same when neither sets one.
+		The branch the feature started from is recorded with it, along with the --issue it is linked to and
+		its --description, so plain preview, onto, and done know its base without being told with --from
+		or --into again. plain meta set changes the rest later.
+		A feature can't be started while a merge, rebase, or other git operation is in progress.`,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error { return runStart(a, cmd, args) },
 	}
 	c.Flags().StringP("from", "f", "main", "Base branch to start from")
-	return c
+	c.Flags().String("issue", "", "The issue the feature is linked to, e.g. PROJ-42")
+	c.Flags().String("description", "", "What the feature is for")
+	return needsGit(locksRepo(a, c), "check out the new feature")
 }
 
 func runStart(app *app.App, cmd *cobra.Command, args []string) error {
 	feature := args[0]
 	base, _ := cmd.Flags().GetString("from")
 
+	repo, err := git.OpenRepository()
+	if err != nil {
+		return err
+	}
+	if err := checkIdle(repo, "start a feature"); err != nil {
+		return err
+	}
+	policy, err := teamPolicy(app, repo)
+	if err != nil {
+		return err
+	}
+	if policy.BranchTemplate == "" {
+		policy.BranchTemplate = app.Settings.BranchTemplate
+	}
+	if policy.BranchTemplate != "" {
+		config, err := repo.Config()
+		if err != nil {
+			return err
+		}
+		email, _ := config.Get("user.email")
+		if feature, err = policy.FeatureName(feature, email); err != nil {
+			return err
+		}
+	}
+
 	if base == "here" {
 		currentBranch, err := app.Git.GetCurrentBranch()
 		if err != nil {
@@ -34,10 +71,33 @@ func runStart(app *app.App, cmd *cobra.Command, args []string) error {
 		base = currentBranch
 	}
 
+	if _, err := repo.ResolveRevision("refs/heads/" + feature); err == nil {
+		return withHint(fmt.Errorf("%w: %s", errBranchExists, feature), "switch to it with git switch, or choose another name")
+	}
 	if err := app.Git.CreateBranch(feature, base); err != nil {
 		return fmt.Errorf("failed to create branch: %w", err)
 	}
+	if err := recordFeature(app, cmd, repo, feature, base); err != nil {
+		fmt.Fprintf(app.Err, "plain: warning: failed to record the feature's metadata: %v\n", err)
+	}
 
-	fmt.Printf("plain: started a new feature called %s based off of %s\n", feature, base)
+	fmt.Fprintf(app.Err, "plain: started a new feature called %s based off of %s\n", feature, base)
 	return nil
 }
+
+// recordFeature records the metadata of a feature that has just started from base, replacing any left
+// behind by an earlier branch of the same name.
+func recordFeature(a *app.App, cmd *cobra.Command, repo *git.Repository, name, base string) error {
+	baseCommit, err := repo.ResolveRevision(base)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	feature := meta.Feature{Name: name, Base: base, BaseCommit: baseCommit, Created: now, Updated: now}
+	feature.Issue, _ = cmd.Flags().GetString("issue")
+	feature.Description, _ = cmd.Flags().GetString("description")
+	if who, err := identity(a); err == nil {
+		feature.Owner = who.Name
+	}
+	return meta.NewStore(repo.CommonDir).Save(feature)
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sim-deos/plain/internal/git"
+)
+
+// operationHints says how to finish or undo each operation that can be left in progress.
+var operationHints = map[git.Operation]string{
+	git.OperationMerge:      "commit the resolved conflicts with git commit, or run git merge --abort to undo it",
+	git.OperationRebase:     "resolve the conflicts and run plain onto --continue, or plain onto --abort to undo it",
+	git.OperationCherryPick: "resolve the conflicts and run plain copy --continue, or plain copy --abort to undo it",
+	git.OperationRevert:     "resolve the conflicts and run git revert --continue, or git revert --abort to undo it",
+	git.OperationBisect:     "finish it with git bisect reset",
+	git.OperationApplyMbox:  "resolve the conflicts and run git am --continue, or git am --abort to undo it",
+}
+
+// checkIdle fails when a merge, rebase, or other operation is in progress, saying how to finish it, as
+// doing something else, like action, in the middle of one leaves its half-done changes mixed up in it.
+func checkIdle(repo *git.Repository, action string) error {
+	state, err := repo.State()
+	if err != nil {
+		return err
+	}
+	if state.Operation == git.NoOperation {
+		return nil
+	}
+
+	what := "a " + string(state.Operation)
+	switch {
+	case state.Operation == git.OperationApplyMbox:
+		what = "git am"
+	case state.Operation == git.OperationRebase && strings.HasPrefix(state.Branch, "refs/heads/"):
+		what += " of " + strings.TrimPrefix(state.Branch, "refs/heads/")
+	case state.Commit != "":
+		what += " of " + shortHash(state.Commit)
+	}
+	return withHint(fmt.Errorf("cannot %s while %s is in progress", action, what), operationHints[state.Operation])
+}
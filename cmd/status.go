@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/sim-deos/plain/internal/app"
+	"github.com/sim-deos/plain/internal/display"
+	"github.com/sim-deos/plain/internal/git"
+
+	"github.com/spf13/cobra"
+)
+
+func NewStatusCmd(a *app.App) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "status",
+		Short: "Shows the changes in your work tree",
+		Long: `Lists the files that differ between the last commit, the index, and the work tree.
+
+		--porcelain prints a stable format for scripts. The first line is "# branch.head <name>", with
+		(detached) for a detached HEAD, and each further line is "<XY> <path>": X is how the index
+		differs from the last commit and Y how the work tree differs from the index, each one of
+		M (modified), A (added), D (deleted), or . (unchanged). Untracked paths are "??" and
+		conflicted ones "UU". --json prints the same information as JSON.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error { return runStatus(a, cmd, args) },
+	}
+	addOutputFlags(c)
+	return c
+}
+
+type statusJSON struct {
+	Branch  string            `json:"branch"`
+	Entries []statusEntryJSON `json:"entries"`
+}
+
+type statusEntryJSON struct {
+	Path     string `json:"path"`
+	Staged   string `json:"staged,omitempty"`
+	Unstaged string `json:"unstaged,omitempty"`
+}
+
+func runStatus(a *app.App, cmd *cobra.Command, args []string) error {
+	asJSON, _ := cmd.Flags().GetBool("json")
+	porcelain, _ := cmd.Flags().GetBool("porcelain")
+
+	status, err := a.Git.Status()
+	if err != nil {
+		return fmt.Errorf("failed to compute status: %w", err)
+	}
+	branch, entries := status.Branch.Head, status.Entries
+
+	switch {
+	case asJSON:
+		out := statusJSON{Branch: branch, Entries: []statusEntryJSON{}}
+		for _, entry := range entries {
+			out.Entries = append(out.Entries, statusEntryJSON{entry.Path, entry.Staged.String(), entry.Unstaged.String()})
+		}
+		return printJSON(a.Out, out)
+
+	case porcelain:
+		head := branch
+		if head == "" {
+			head = "(detached)"
+		}
+		fmt.Fprintf(a.Out, "# branch.head %s\n", head)
+		for _, entry := range entries {
+			code := string([]byte{entry.Staged.Code(), entry.Unstaged.Code()})
+			switch {
+			case entry.Unstaged == git.StatusUntracked:
+				code = "??"
+			case entry.Staged == git.StatusConflicted:
+				code = "UU"
+			}
+			fmt.Fprintf(a.Out, "%s %s\n", code, display.QuotePath(entry.Path))
+		}
+		return nil
+	}
+
+	if branch == "" {
+		fmt.Fprintln(a.Err, "plain: HEAD is detached")
+	} else {
+		fmt.Fprintf(a.Err, "plain: on %s\n", branch)
+	}
+	if len(entries) == 0 {
+		fmt.Fprintln(a.Out, "  nothing changed")
+		return nil
+	}
+	for _, entry := range entries {
+		state := entry.Unstaged.String()
+		switch {
+		case entry.Staged != 0 && entry.Unstaged != 0 && entry.Staged != git.StatusConflicted:
+			state = fmt.Sprintf("%s, then %s", entry.Staged, entry.Unstaged)
+		case entry.Staged != 0:
+			state = entry.Staged.String()
+		}
+		fmt.Fprintf(a.Out, "  %-12s %s\n", state, entry.Path)
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/sim-deos/plain/internal/app"
+	"github.com/sim-deos/plain/internal/git"
+	"github.com/sim-deos/plain/internal/team"
+)
+
+// teamPolicy returns the team's policy from .plain/team.toml, tightened by the user's git config, warning
+// about user settings it ignores for trying to relax it.
+func teamPolicy(a *app.App, repo *git.Repository) (team.Policy, error) {
+	policy, warnings, err := team.Load(repo)
+	if err != nil {
+		return team.Policy{}, err
+	}
+	for _, warning := range warnings {
+		fmt.Fprintf(a.Err, "plain: warning: %s\n", warning)
+	}
+	return policy, nil
+}
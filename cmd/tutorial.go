@@ -0,0 +1,295 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/sim-deos/plain/internal/app"
+	"github.com/sim-deos/plain/internal/git"
+
+	"github.com/spf13/cobra"
+)
+
+const tutorialFile = "hello.txt"
+
+func NewTutorialCmd(a *app.App) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "tutorial",
+		Short: "Practice plain in a throwaway repository",
+		Long: `Creates a practice repository in a temporary directory and walks you through a feature from
+		start to finish: plain start, editing a file, plain checkpoint, plain preview, and plain done.
+		Each step is checked before moving on, and nothing outside the practice repository is touched.
+		The repository is deleted afterwards unless --keep is given. Type quit at any prompt to stop.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error { return runTutorial(a, cmd, args) },
+	}
+	c.Flags().Bool("keep", false, "Keep the practice repository when the tutorial ends")
+	return needsGit(c, "set up the practice repository")
+}
+
+var errTutorialQuit = errors.New("tutorial stopped")
+
+// tutorial is a running tutorial session inside its practice repository.
+type tutorial struct {
+	a     *app.App
+	shell *git.ShellClient // What sets up the practice repository
+	dir   string
+	input *bufio.Scanner
+}
+
+func runTutorial(a *app.App, cmd *cobra.Command, args []string) error {
+	keep, _ := cmd.Flags().GetBool("keep")
+
+	dir, err := os.MkdirTemp("", "plain-tutorial-")
+	if err != nil {
+		return err
+	}
+	// The temp directory may be reached through a symlink, as on macOS, which git reports resolved.
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+	defer func() {
+		// Windows can't delete the working directory, so step out of it first.
+		os.Chdir(os.TempDir())
+		if keep {
+			fmt.Fprintf(a.Err, "plain: kept the practice repository at %s\n", dir)
+			return
+		}
+		os.RemoveAll(dir)
+	}()
+
+	t := &tutorial{a: a, shell: shellClient(a), dir: dir, input: bufio.NewScanner(cmd.InOrStdin())}
+	if err := t.setup(); err != nil {
+		return fmt.Errorf("failed to create the practice repository: %w", err)
+	}
+
+	fmt.Fprintf(a.Err, "plain: created a practice repository at %s\n\n", dir)
+	err = t.run()
+	if errors.Is(err, errTutorialQuit) {
+		fmt.Fprintln(a.Err, "plain: the tutorial stopped, run plain tutorial to start again")
+		return nil
+	}
+	return err
+}
+
+// setup moves into the practice repository and creates it with one commit on main.
+func (t *tutorial) setup() error {
+	if err := os.Chdir(t.dir); err != nil {
+		return err
+	}
+	if err := t.git([]string{"init", "--quiet"}, []string{"symbolic-ref", "HEAD", "refs/heads/main"}); err != nil {
+		return err
+	}
+
+	// Commits need an identity, which a new user may not have configured yet.
+	defaults := map[string]string{"user.name": "Plain Tutorial", "user.email": "tutorial@example.com"}
+	for key, value := range defaults {
+		values, err := t.a.Git.GetConfigValues(key)
+		if err != nil {
+			return err
+		}
+		if len(values) == 0 {
+			if err := t.git([]string{"config", key, value}); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := os.WriteFile(tutorialFile, []byte("Hello!\n"), 0o644); err != nil {
+		return err
+	}
+	return t.git([]string{"add", tutorialFile}, []string{"commit", "--quiet", "--message", "Say hello"})
+}
+
+// git runs each git command in the practice repository in turn.
+func (t *tutorial) git(commands ...[]string) error {
+	for _, args := range commands {
+		if err := t.shell.Run(args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shellClient returns the client that runs git for a, which under the native backend is the one it
+// falls back to.
+func shellClient(a *app.App) *git.ShellClient {
+	client := a.Git
+	if native, ok := client.(*git.NativeClient); ok {
+		client = native.Fallback
+	}
+	if shell, ok := client.(*git.ShellClient); ok {
+		return shell
+	}
+	return git.NewShellClient()
+}
+
+func (t *tutorial) run() error {
+	fmt.Fprintln(t.a.Err, `Welcome! plain keeps track of your work in features. Each feature lives on its own, so you can try
+things out without breaking what already works. This practice repository has one file, hello.txt.
+
+Step 1 of 5: start a feature. Give it a one word name of your choosing, like greeting.`)
+	if err := t.command("plain start greeting", "start", func() error {
+		branch, err := t.a.Git.GetCurrentBranch()
+		if err != nil {
+			return err
+		}
+		if branch == "main" {
+			return errors.New("you are still on main, start a feature to move off it")
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(t.a.Err, `
+Step 2 of 5: make a change. Open %s in your editor, change the
+text, and save it. Press Enter when you are done.
+`, filepath.Join(t.dir, tutorialFile))
+	if err := t.edit(); err != nil {
+		return err
+	}
+
+	head, err := t.head()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(t.a.Err, `
+Step 3 of 5: save your progress with a checkpoint. A checkpoint records every change you have made,
+so you can always come back to this point.`)
+	if err := t.command("plain checkpoint", "checkpoint", func() error {
+		current, err := t.head()
+		if err != nil {
+			return err
+		}
+		if current == head {
+			return errors.New("no checkpoint was made, make sure hello.txt is saved")
+		}
+		changes, err := t.status()
+		if err != nil {
+			return err
+		}
+		if len(changes) > 0 {
+			return fmt.Errorf("%s still has changes that aren't in a checkpoint", changes[0].Path)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(t.a.Err, `
+Step 4 of 5: look back over your feature. Preview lists everything the feature changed; add --patch
+to see the changes line by line.`)
+	if err := t.command("plain preview --patch", "preview", nil); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(t.a.Err, `
+Step 5 of 5: finish the feature. Done checks that everything is saved and wraps the feature up. In a
+real project, plain done --pr opens a pull request so others can review it.`)
+	if err := t.command("plain done", "done", nil); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(t.a.Err, `
+That's it! You started a feature, changed a file, checkpointed it, previewed it, and finished it.
+Run plain help to see everything else plain can do.`)
+	return nil
+}
+
+// command prompts until the user runs the plain subcommand sub and check passes. A nil check only
+// requires the command to succeed.
+func (t *tutorial) command(example, sub string, check func() error) error {
+	fmt.Fprintf(t.a.Err, "Type: %s\n", example)
+	for {
+		line, err := t.prompt("> ")
+		if err != nil {
+			return err
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "plain" || fields[1] != sub {
+			fmt.Fprintf(t.a.Err, "Not quite, this step needs plain %s. Try: %s\n", sub, example)
+			continue
+		}
+
+		if err := t.plain(fields[1:]); err != nil {
+			fmt.Fprintf(t.a.Err, "That didn't work (%v), try again: %s\n", err, example)
+			continue
+		}
+		if check == nil {
+			return nil
+		}
+		if err := check(); err != nil {
+			fmt.Fprintf(t.a.Err, "Almost: %v. Try again: %s\n", err, example)
+			continue
+		}
+		return nil
+	}
+}
+
+// edit waits until the work tree has changes.
+func (t *tutorial) edit() error {
+	for {
+		if _, err := t.prompt(""); err != nil {
+			return err
+		}
+		changes, err := t.status()
+		if err != nil {
+			return err
+		}
+		if len(changes) > 0 {
+			return nil
+		}
+		fmt.Fprintf(t.a.Err, "No changes yet. Edit and save %s, then press Enter.\n", tutorialFile)
+	}
+}
+
+// prompt reads a line of input, returning [errTutorialQuit] when the user quits or input ends.
+func (t *tutorial) prompt(prefix string) (string, error) {
+	fmt.Fprint(t.a.Err, prefix)
+	if !t.input.Scan() {
+		if err := t.input.Err(); err != nil {
+			return "", err
+		}
+		return "", errTutorialQuit
+	}
+	line := strings.TrimSpace(t.input.Text())
+	if line == "quit" || line == "exit" {
+		return "", errTutorialQuit
+	}
+	return line, nil
+}
+
+// plain runs this plain executable with args, connected to the terminal.
+func (t *tutorial) plain(args []string) error {
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	c := exec.Command(self, args...)
+	c.Stdout = t.a.Out
+	c.Stderr = t.a.Err
+	return c.Run()
+}
+
+func (t *tutorial) head() (string, error) {
+	repo, err := git.OpenRepository()
+	if err != nil {
+		return "", err
+	}
+	return repo.ResolveRevision("HEAD")
+}
+
+func (t *tutorial) status() ([]git.StatusEntry, error) {
+	repo, err := git.OpenRepository()
+	if err != nil {
+		return nil, err
+	}
+	return repo.Status(nil)
+}
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/sim-deos/plain/internal/app"
+	"github.com/sim-deos/plain/internal/git"
+
+	"github.com/spf13/cobra"
+)
+
+func NewUndoCmd(a *app.App) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "undo <commit>",
+		Short: "Undoes a commit with a new commit",
+		Long: `Adds a commit to the current feature that undoes the changes an earlier commit made, like
+		git revert. The earlier commit stays in the history, so this is safe for work already shared.
+		If later changes conflict with undoing it, the conflicts are left in the files for you to resolve;
+		then run plain undo --continue, or plain undo --abort to give up. To undo a merge, choose the
+		parent to go back to with --mainline, usually 1 for the branch that was merged into.
+		With --orig-head, puts the feature back where it was before the last plain onto, merge, or
+		reset instead, which git records in ORIG_HEAD. This rewrites the feature's history, so only do
+		it to work that hasn't been shared.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			resume, _ := cmd.Flags().GetBool("continue")
+			abort, _ := cmd.Flags().GetBool("abort")
+			orig, _ := cmd.Flags().GetBool("orig-head")
+			if resume || abort || orig {
+				return cobra.NoArgs(cmd, args)
+			}
+			return cobra.ExactArgs(1)(cmd, args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error { return runUndo(a, cmd, args) },
+	}
+	c.Flags().IntP("mainline", "m", 0, "The parent, counting from 1, a merge is undone back to")
+	c.Flags().Bool("continue", false, "Commit the undo once its conflicts are resolved")
+	c.Flags().Bool("abort", false, "Give up on an undo that stopped on conflicts")
+	c.Flags().Bool("orig-head", false, "Put the feature back where it was before the last onto, merge, or reset")
+	c.MarkFlagsMutuallyExclusive("continue", "abort", "orig-head")
+	return locksRepo(a, c)
+}
+
+func runUndo(a *app.App, cmd *cobra.Command, args []string) error {
+	repo, err := git.OpenRepository()
+	if err != nil {
+		return err
+	}
+
+	if abort, _ := cmd.Flags().GetBool("abort"); abort {
+		if err := repo.AbortPick(); err != nil {
+			return err
+		}
+		fmt.Fprintln(a.Err, "plain: gave up on the undo")
+		return nil
+	}
+
+	who, err := identity(a)
+	if err != nil {
+		return err
+	}
+
+	if orig, _ := cmd.Flags().GetBool("orig-head"); orig {
+		return undoToOrigHead(a, repo, who)
+	}
+
+	var hash string
+	if resume, _ := cmd.Flags().GetBool("continue"); resume {
+		hash, err = repo.ContinuePick(who)
+	} else {
+		mainline, _ := cmd.Flags().GetInt("mainline")
+		hash, err = repo.Revert(args[0], who, git.PickOptions{Mainline: mainline})
+	}
+
+	switch {
+	case errors.Is(err, git.ErrPickConflict):
+		return fmt.Errorf("%w\nresolve them, then run plain undo --continue, or plain undo --abort to give up", err)
+	case errors.Is(err, git.ErrEmptyPick):
+		fmt.Fprintln(a.Err, "plain: nothing to undo, the feature no longer has those changes")
+		return nil
+	case err != nil:
+		return fmt.Errorf("failed to undo: %w", err)
+	}
+	fmt.Fprintf(a.Err, "plain: undone by %s\n", hash[:7])
+	return nil
+}
+
+// undoToOrigHead resets the current branch to ORIG_HEAD.
+func undoToOrigHead(a *app.App, repo *git.Repository, who git.Signature) error {
+	orig, err := repo.OrigHead()
+	if errors.Is(err, git.ErrRefNotFound) {
+		return errors.New("nothing to go back to, no onto, merge, or reset has been done here")
+	}
+	if err != nil {
+		return err
+	}
+	head, err := repo.ResolveRevision("HEAD")
+	if err != nil {
+		return err
+	}
+	if head == orig {
+		fmt.Fprintln(a.Err, "plain: nothing to undo, the feature is already where it was")
+		return nil
+	}
+	if err := repo.ResetHard(orig, who, "reset: moving to ORIG_HEAD"); err != nil {
+		if errors.Is(err, git.ErrDirtyWorkTree) {
+			return fmt.Errorf("%w\ncheckpoint or discard them first", err)
+		}
+		return fmt.Errorf("failed to undo: %w", err)
+	}
+	fmt.Fprintf(a.Err, "plain: moved the feature back from %s to %s\n", head[:7], orig[:7])
+	return nil
+}
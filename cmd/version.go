@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/sim-deos/plain/internal/app"
+	"github.com/sim-deos/plain/internal/git"
+	"github.com/sim-deos/plain/internal/release"
+
+	"github.com/spf13/cobra"
+)
+
+func NewVersionCmd(a *app.App) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "version",
+		Short: "Works out the project's next version",
+		Long: `Helps pick version numbers from the project's history, using semantic versioning and
+		conventional commit messages (feat: ..., fix: ..., feat!: ...).`,
+	}
+
+	c.AddCommand(newVersionSuggestCmd(a))
+	return c
+}
+
+func newVersionSuggestCmd(a *app.App) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "suggest",
+		Short: "Suggests the next version from the commits since the last tag",
+		Long: `Reads the conventional commits since the most recent tag and suggests the next version:
+		a major bump for breaking changes, minor for features, and patch for fixes. With --apply, the
+		files listed in the plain.versionFile config are updated and committed, and the new version is
+		tagged with release notes.
+
+		With --scope, only commits touching that directory count, and versions are read from and tagged
+		with the component's tag prefix, as with plain release. Its version files are listed in
+		plain.<directory>.versionFile, relative to the directory.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error { return runVersionSuggest(a, cmd, args) },
+	}
+	c.Flags().Bool("apply", false, "Update the version files and tag the suggested version")
+	addScopeFlag(c)
+	return c
+}
+
+func runVersionSuggest(a *app.App, cmd *cobra.Command, args []string) error {
+	apply, _ := cmd.Flags().GetBool("apply")
+
+	scope, err := scopeFromFlags(a, cmd)
+	if err != nil {
+		return err
+	}
+
+	repo, err := git.OpenRepository()
+	if err != nil {
+		return err
+	}
+
+	current := release.Version{Prefix: "v"}
+	base := ""
+	tag, err := repo.NearestTag("HEAD", scope.owns)
+	switch {
+	case err == nil:
+		current, err = release.ParseVersion(strings.TrimPrefix(tag, scope.TagPrefix))
+		if err != nil {
+			return fmt.Errorf("the latest tag, %s, is not a semantic version", tag)
+		}
+		base = "refs/tags/" + tag
+	case !errors.Is(err, git.ErrNoTag):
+		return err
+	}
+
+	commits, err := scope.commits(repo, base, "HEAD")
+	if err != nil {
+		return fmt.Errorf("failed to read commits since %s: %w", current, err)
+	}
+
+	bump := release.BumpFor(commits)
+	if bump == release.NoBump {
+		fmt.Fprintf(a.Err, "plain: nothing to release since %s, %d commit(s) but no features or fixes\n", scope.tag(current.String()), len(commits))
+		return nil
+	}
+
+	next := current.Next(bump)
+	nextTag := scope.tag(next.String())
+	fmt.Fprintf(a.Err, "plain: %s -> %s (%s)\n", scope.tag(current.String()), nextTag, bump)
+	if !apply {
+		return nil
+	}
+
+	key := "plain.versionFile"
+	if scope.Dir != "" {
+		key = "plain." + scope.Dir + ".versionFile"
+	}
+	files, err := a.Git.GetConfigValues(key)
+	if err != nil {
+		return err
+	}
+
+	var paths []string
+	for _, file := range files {
+		rel := path.Join(scope.Dir, filepath.ToSlash(file))
+		if err := release.UpdateVersionFile(filepath.Join(repo.WorkTree, filepath.FromSlash(rel)), current, next); err != nil {
+			return err
+		}
+		fmt.Fprintf(a.Err, "plain: updated %s\n", rel)
+		paths = append(paths, filepath.Join(repo.WorkTree, filepath.FromSlash(rel)))
+	}
+
+	if len(paths) > 0 {
+		if err := a.Git.CommitPaths("chore(release): "+nextTag, paths); err != nil {
+			return fmt.Errorf("failed to commit version files: %w", err)
+		}
+	}
+
+	if err := a.Git.CreateTag(nextTag, nextTag+"\n\n"+release.Notes(commits)); err != nil {
+		return fmt.Errorf("failed to tag %s: %w", nextTag, err)
+	}
+	fmt.Fprintf(a.Err, "plain: tagged %s\n", nextTag)
+	return nil
+}
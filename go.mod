@@ -2,8 +2,13 @@ module github.com/sim-deos/plain
 
 go 1.24.1
 
+require (
+	github.com/spf13/cobra v1.9.1
+	golang.org/x/crypto v0.46.0
+	golang.org/x/sys v0.39.0
+)
+
 require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
-	github.com/spf13/cobra v1.9.1 // indirect
 	github.com/spf13/pflag v1.0.6 // indirect
 )
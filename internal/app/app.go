@@ -1,7 +1,29 @@
 package app
 
-import "github.com/sim-deos/plain/internal/git"
+import (
+	"io"
+	"log/slog"
+
+	"github.com/sim-deos/plain/internal/git"
+	"github.com/sim-deos/plain/internal/settings"
+)
 
 type App struct {
+	// Git is how commands work with git: a [git.ShellClient], or a [git.NativeClient] when git isn't
+	// installed or the native backend is chosen with --git-backend or plain.gitBackend.
 	Git git.Client
+
+	// GitMissing is set when the git command isn't installed, and Git is a [git.NativeClient].
+	GitMissing bool
+
+	// Settings are the user's and repository's defaults, loaded before any command runs.
+	Settings settings.Settings
+
+	// Out is where commands write what they produce, like lists, diffs, and JSON, which scripts may read.
+	// Err is where messages for the user go: what a command did, warnings, questions, and errors.
+	Out, Err io.Writer
+
+	// Log is where commands log what they do, for --verbose, --debug, and git config plain.logFile. It
+	// discards everything unless one of them is on.
+	Log *slog.Logger
 }
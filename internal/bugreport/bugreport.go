@@ -0,0 +1,278 @@
+// Package bugreport gathers what it takes to make sense of a problem someone reports: the versions of plain
+// and git, the platform, the shape of the repository, what happened in it lately, and the last error.
+// Nothing in a report names a branch, a file, a remote, or a person, so it can be attached to a public issue.
+package bugreport
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/sim-deos/plain/internal/git"
+)
+
+// DefaultOperations is how many of the latest moves of HEAD a report lists.
+const DefaultOperations = 20
+
+// Report is everything gathered for a bug report.
+type Report struct {
+	Created    time.Time
+	Plain      string       // plain's version, and the commit it was built from when known
+	Go         string       // The Go release plain was built with
+	Platform   string       // The operating system and architecture, e.g. linux/amd64
+	Git        string       // The installed git's version, or why there isn't one
+	Repo       *RepoStats   // The repository the report was made in, or nil outside of one
+	Operations []Operation  // The latest moves of HEAD, oldest first
+	LastError  *ErrorRecord // The last command that failed, or nil if none did
+}
+
+// RepoStats describes the shape of a repository without anything in it.
+type RepoStats struct {
+	Format         git.HashFormat
+	Commits        int // Commits reachable from any ref
+	Branches       int
+	RemoteBranches int
+	Tags           int
+	OtherRefs      int // Notes, stashes, and anything else under refs/
+	Remotes        int
+	LooseObjects   int
+	Packs          int
+	IndexEntries   int
+	Shallow        bool
+	LinkedWorktree bool          // The work tree was added with git worktree add
+	InProgress     git.Operation // What stopped partway, if anything
+}
+
+// Operation is one move of HEAD, with the names and messages of the reflog entry left out.
+type Operation struct {
+	Time time.Time
+	Kind string // What moved HEAD, e.g. commit, checkout, or rebase (finish)
+}
+
+// Gather makes a report about repo, which may be nil outside of a repository, listing up to n operations.
+// The last error is read from log, which may also be nil.
+func Gather(repo *git.Repository, n int, log *ErrorLog) (Report, error) {
+	r := Report{
+		Created:  time.Now(),
+		Plain:    plainVersion(),
+		Go:       runtime.Version(),
+		Platform: runtime.GOOS + "/" + runtime.GOARCH,
+		Git:      gitVersion(),
+	}
+	if log != nil {
+		if record, ok := log.Last(); ok {
+			r.LastError = &record
+		}
+	}
+	if repo == nil {
+		return r, nil
+	}
+
+	stats, err := Stats(repo)
+	if err != nil {
+		return Report{}, err
+	}
+	r.Repo = &stats
+	if r.Operations, err = Operations(repo, n); err != nil {
+		return Report{}, err
+	}
+	return r, nil
+}
+
+// plainVersion is the module version plain was built as, followed by the commit when the build recorded it.
+func plainVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	version := info.Main.Version
+	var revision, modified string
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value
+		}
+	}
+	if revision != "" {
+		version += " (" + revision[:min(len(revision), 12)]
+		if modified == "true" {
+			version += ", modified"
+		}
+		version += ")"
+	}
+	return version
+}
+
+func gitVersion() string {
+	v, err := git.InstalledGitVersion()
+	if errors.Is(err, git.ErrBadGitVersion) {
+		return err.Error()
+	}
+	if err != nil {
+		return "not installed"
+	}
+	return v.String()
+}
+
+// Stats counts what is in repo.
+func Stats(repo *git.Repository) (RepoStats, error) {
+	stats := RepoStats{Format: repo.Format, LinkedWorktree: repo.GitDir != repo.CommonDir}
+
+	refs, err := repo.Refs()
+	if err != nil {
+		return RepoStats{}, err
+	}
+	for name := range refs {
+		switch {
+		case strings.HasPrefix(name, "refs/heads/"):
+			stats.Branches++
+		case strings.HasPrefix(name, "refs/remotes/"):
+			stats.RemoteBranches++
+		case strings.HasPrefix(name, "refs/tags/"):
+			stats.Tags++
+		default:
+			stats.OtherRefs++
+		}
+	}
+	commits, err := repo.ReachableCommits()
+	if err != nil {
+		return RepoStats{}, err
+	}
+	stats.Commits = len(commits)
+
+	config, err := repo.Config()
+	if err != nil {
+		return RepoStats{}, err
+	}
+	stats.Remotes = len(config.Remotes())
+
+	objects := filepath.Join(repo.CommonDir, "objects")
+	dirs, err := os.ReadDir(objects)
+	if err != nil {
+		return RepoStats{}, err
+	}
+	for _, dir := range dirs {
+		if !dir.IsDir() || len(dir.Name()) != 2 {
+			continue
+		}
+		files, err := os.ReadDir(filepath.Join(objects, dir.Name()))
+		if err != nil {
+			return RepoStats{}, err
+		}
+		stats.LooseObjects += len(files)
+	}
+	packs, err := filepath.Glob(filepath.Join(objects, "pack", "*.pack"))
+	if err != nil {
+		return RepoStats{}, err
+	}
+	stats.Packs = len(packs)
+
+	idx, err := repo.Index()
+	if err != nil {
+		return RepoStats{}, err
+	}
+	stats.IndexEntries = len(idx.Entries)
+
+	if _, err := os.Stat(filepath.Join(repo.CommonDir, "shallow")); err == nil {
+		stats.Shallow = true
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return RepoStats{}, err
+	}
+	state, err := repo.State()
+	if err != nil {
+		return RepoStats{}, err
+	}
+	stats.InProgress = state.Operation
+	return stats, nil
+}
+
+// Operations returns the latest n moves of HEAD, oldest first, from its reflog.
+func Operations(repo *git.Repository, n int) ([]Operation, error) {
+	entries, err := repo.Reflog("HEAD")
+	if err != nil {
+		return nil, err
+	}
+	entries = entries[max(len(entries)-n, 0):]
+	ops := make([]Operation, 0, len(entries))
+	for _, entry := range entries {
+		ops = append(ops, Operation{Time: entry.Committer.Time, Kind: operationKind(entry.Message)})
+	}
+	return ops, nil
+}
+
+// operationKind keeps the command that wrote a reflog message and what it was doing, dropping what it
+// names: "checkout: moving from main to fix" is checkout, and "rebase (finish): returning to refs/heads/fix"
+// is rebase (finish).
+func operationKind(message string) string {
+	fields := strings.Fields(strings.SplitN(message, ":", 2)[0])
+	if len(fields) == 0 {
+		return "unknown"
+	}
+	kind := fields[0]
+	if len(fields) > 1 && strings.HasPrefix(fields[1], "(") && strings.HasSuffix(fields[1], ")") {
+		kind += " " + fields[1]
+	}
+	return kind
+}
+
+// Write writes the report as text meant to be pasted into an issue or attached to one.
+func (r Report) Write(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "plain bug report, %s\n\n", r.Created.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&b, "plain     %s\n", r.Plain)
+	fmt.Fprintf(&b, "go        %s\n", r.Go)
+	fmt.Fprintf(&b, "platform  %s\n", r.Platform)
+	fmt.Fprintf(&b, "git       %s\n", r.Git)
+
+	b.WriteString("\nrepository\n")
+	if s := r.Repo; s == nil {
+		b.WriteString("  not in a repository\n")
+	} else {
+		fmt.Fprintf(&b, "  format          %s\n", s.Format)
+		fmt.Fprintf(&b, "  commits         %d\n", s.Commits)
+		fmt.Fprintf(&b, "  refs            %d branches, %d remote branches, %d tags, %d other\n", s.Branches, s.RemoteBranches, s.Tags, s.OtherRefs)
+		fmt.Fprintf(&b, "  remotes         %d\n", s.Remotes)
+		fmt.Fprintf(&b, "  objects         %d loose, %d packs\n", s.LooseObjects, s.Packs)
+		fmt.Fprintf(&b, "  index entries   %d\n", s.IndexEntries)
+		fmt.Fprintf(&b, "  shallow         %t\n", s.Shallow)
+		fmt.Fprintf(&b, "  linked worktree %t\n", s.LinkedWorktree)
+		if s.InProgress != git.NoOperation {
+			fmt.Fprintf(&b, "  in progress     %s\n", s.InProgress)
+		}
+	}
+
+	if len(r.Operations) > 0 {
+		b.WriteString("\nrecent operations\n")
+		for _, op := range r.Operations {
+			fmt.Fprintf(&b, "  %s  %s\n", op.Time.UTC().Format(time.RFC3339), op.Kind)
+		}
+	}
+
+	b.WriteString("\nlast error\n")
+	if e := r.LastError; e == nil {
+		b.WriteString("  none recorded\n")
+	} else {
+		fmt.Fprintf(&b, "  %s  %s\n", e.Time.UTC().Format(time.RFC3339), e.Command)
+		fmt.Fprintf(&b, "  %s\n", e.Message)
+		for _, kind := range e.Chain {
+			fmt.Fprintf(&b, "    %s\n", kind)
+		}
+		if e.Stack != "" {
+			b.WriteString("\n")
+			for _, line := range strings.Split(strings.TrimRight(e.Stack, "\n"), "\n") {
+				fmt.Fprintf(&b, "  %s\n", line)
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
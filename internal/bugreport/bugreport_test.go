@@ -0,0 +1,113 @@
+package bugreport
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sim-deos/plain/internal/git"
+)
+
+func TestGather(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"objects", "refs/heads"} {
+		if err := os.MkdirAll(filepath.Join(root, ".git", dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, ".git", "HEAD"), []byte("ref: refs/heads/secret-branch\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(root)
+	repo, err := git.OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ann := git.Signature{Name: "Ann", Email: "ann@example.com", Time: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}
+	tree, err := git.NewTreeBuilder(repo.Encoder()).Write()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var head string
+	for i, message := range []string{"commit (initial): secret message", "commit: another secret", "rebase (finish): returning to refs/heads/secret-branch"} {
+		var parents []string
+		if head != "" {
+			parents = []string{head}
+		}
+		next, err := repo.CreateCommit(tree, parents, ann, ann, fmt.Sprintf("commit %d", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.UpdateRef("refs/heads/secret-branch", head, next, ann, message); err != nil {
+			t.Fatal(err)
+		}
+		head = next
+	}
+	if err := repo.UpdateRef("refs/tags/secret-tag", "", head, ann, "tag"); err != nil {
+		t.Fatal(err)
+	}
+
+	log := &ErrorLog{Dir: t.TempDir()}
+	if _, ok := log.Last(); ok {
+		t.Error("Last() of an empty log found a record")
+	}
+	failure := fmt.Errorf("failed to open secret.txt: %w", &fs.PathError{Op: "open", Path: "secret.txt", Err: fs.ErrNotExist})
+	if err := log.Record(NewErrorRecord("plain done", failure)); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := Gather(repo, 2, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := RepoStats{Format: git.SHA1, Commits: 3, Branches: 1, Tags: 1, LooseObjects: 4}
+	if report.Repo == nil || *report.Repo != want {
+		t.Errorf("Gather().Repo = %+v, want %+v", report.Repo, want)
+	}
+	var kinds []string
+	for _, op := range report.Operations {
+		kinds = append(kinds, op.Kind)
+	}
+	if !slices.Equal(kinds, []string{"commit", "rebase (finish)"}) {
+		t.Errorf("Gather().Operations = %q, want the last two without their messages", kinds)
+	}
+	if e := report.LastError; e == nil || e.Command != "plain done" || !slices.Equal(e.Chain, []string{"*fmt.wrapError", "*fs.PathError", "*errors.errorString"}) {
+		t.Errorf("Gather().LastError = %+v, want the recorded error and what it wraps", e)
+	}
+
+	var out strings.Builder
+	if err := report.Write(&out); err != nil {
+		t.Fatal(err)
+	}
+	text := out.String()
+	for _, leak := range []string{"secret-branch", "secret message", "secret-tag", "Ann", root} {
+		if strings.Contains(text, leak) {
+			t.Errorf("Write() mentions %q:\n%s", leak, text)
+		}
+	}
+	if !strings.Contains(text, "1 branches, 0 remote branches, 1 tags") || !strings.Contains(text, "rebase (finish)") {
+		t.Errorf("Write() =\n%s\nwant the repository's stats and operations", text)
+	}
+}
+
+func TestNewPanicRecord(t *testing.T) {
+	record := NewPanicRecord("plain status", errors.New("boom"), []byte("goroutine 1 [running]:\nmain.main()\n"))
+	if record.Message != "panic: boom" || !slices.Equal(record.Chain, []string{"*errors.errorString"}) || !strings.HasPrefix(record.Stack, "goroutine 1") {
+		t.Errorf("NewPanicRecord() = %+v", record)
+	}
+
+	var out strings.Builder
+	if err := (Report{LastError: &record}).Write(&out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "not in a repository") || !strings.Contains(out.String(), "  main.main()\n") {
+		t.Errorf("Write() =\n%s\nwant the stack of the panic outside a repository", out.String())
+	}
+}
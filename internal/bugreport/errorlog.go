@@ -0,0 +1,107 @@
+package bugreport
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const errorFile = "last-error.json"
+
+// ErrorRecord is a command that failed.
+type ErrorRecord struct {
+	Time    time.Time `json:"time"`
+	Command string    `json:"command"`         // The command without its arguments, e.g. plain done
+	Message string    `json:"message"`         // What the error said
+	Chain   []string  `json:"chain,omitempty"` // The type of the error and of every error it wraps, outermost first
+	Stack   string    `json:"stack,omitempty"` // Where the command panicked, or "" if it returned an error
+}
+
+// NewErrorRecord records that command failed with err.
+func NewErrorRecord(command string, err error) ErrorRecord {
+	return ErrorRecord{Time: time.Now(), Command: command, Message: err.Error(), Chain: errorChain(err, nil)}
+}
+
+// NewPanicRecord records that command panicked with value, which stack is the goroutine's stack from.
+func NewPanicRecord(command string, value any, stack []byte) ErrorRecord {
+	record := ErrorRecord{Time: time.Now(), Command: command, Message: fmt.Sprint("panic: ", value), Stack: string(stack)}
+	if err, ok := value.(error); ok {
+		record.Chain = errorChain(err, nil)
+	}
+	return record
+}
+
+// errorChain appends the types of err and the errors it wraps, depth first, to chain.
+func errorChain(err error, chain []string) []string {
+	chain = append(chain, fmt.Sprintf("%T", err))
+	switch e := err.(type) {
+	case interface{ Unwrap() error }:
+		if inner := e.Unwrap(); inner != nil {
+			chain = errorChain(inner, chain)
+		}
+	case interface{ Unwrap() []error }:
+		for _, inner := range e.Unwrap() {
+			chain = errorChain(inner, chain)
+		}
+	}
+	return chain
+}
+
+// ErrorLog keeps the last failed command, so a bug report made afterwards can include it.
+// A new ErrorLog is created by calling [NewErrorLog].
+type ErrorLog struct {
+	Dir string // Where the record is kept
+}
+
+// NewErrorLog returns an ErrorLog kept in the plain directory of the user's cache directory.
+func NewErrorLog() (*ErrorLog, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return &ErrorLog{Dir: filepath.Join(cacheDir, "plain")}, nil
+}
+
+// Last returns the last recorded failure. The boolean is false if none was recorded or the record is
+// unreadable.
+func (l *ErrorLog) Last() (ErrorRecord, bool) {
+	data, err := os.ReadFile(filepath.Join(l.Dir, errorFile))
+	if err != nil {
+		return ErrorRecord{}, false
+	}
+	var record ErrorRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return ErrorRecord{}, false
+	}
+	return record, true
+}
+
+// Record replaces the last recorded failure with record. The file is written under a temporary name and
+// renamed into place, so two commands failing at once leave one of them whole.
+func (l *ErrorLog) Record(record ErrorRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(l.Dir, 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(l.Dir, errorFile+".*")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	err = errors.Join(err, f.Close())
+	if err == nil {
+		err = os.Rename(f.Name(), filepath.Join(l.Dir, errorFile))
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
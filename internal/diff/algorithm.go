@@ -0,0 +1,231 @@
+package diff
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var ErrUnknownAlgorithm = errors.New("unknown diff algorithm")
+
+// Algorithm computes an edit script turning one list of lines into another.
+//
+// [Myers] finds a shortest script, but on heavily edited files it happily matches blank lines and
+// braces from unrelated code. [Patience] and [Histogram] anchor the diff on lines that are rare in both
+// texts first, which keeps moved or rewritten blocks together at the cost of sometimes longer scripts.
+type Algorithm func(a, b []string) []Line
+
+// ParseAlgorithm returns the algorithm with the given name, using git's names for diff.algorithm:
+// myers (or default, or minimal, which Myers always is here), patience, and histogram.
+func ParseAlgorithm(name string) (Algorithm, error) {
+	switch strings.ToLower(name) {
+	case "", "myers", "default", "minimal":
+		return Myers, nil
+	case "patience":
+		return Patience, nil
+	case "histogram":
+		return Histogram, nil
+	default:
+		return nil, fmt.Errorf("%w %q, expected myers, patience, or histogram", ErrUnknownAlgorithm, name)
+	}
+}
+
+// Diff compares the texts a and b line by line with alg, grouping the changes into hunks like [Diff].
+func (alg Algorithm) Diff(a, b []byte, context int) []Hunk {
+	return Hunks(alg(Lines(a), Lines(b)), context)
+}
+
+// Patience returns an edit script turning a into b using the patience algorithm: lines that appear
+// exactly once in each text are matched up in order, and the gaps between them are diffed recursively.
+// Gaps without such lines fall back to [Myers].
+func Patience(a, b []string) []Line {
+	return slide(trimmed(a, b, patience))
+}
+
+func patience(a, b []string) []Line {
+	if len(a) == 0 || len(b) == 0 {
+		return myers(a, b)
+	}
+
+	type occurrence struct{ inA, inB, posA, posB int }
+	counts := map[string]*occurrence{}
+	for i, line := range a {
+		if counts[line] == nil {
+			counts[line] = &occurrence{}
+		}
+		counts[line].inA++
+		counts[line].posA = i
+	}
+	for j, line := range b {
+		if o := counts[line]; o != nil {
+			o.inB++
+			o.posB = j
+		}
+	}
+
+	// The lines unique to both texts, in the order they appear in a.
+	var unique [][2]int
+	for i, line := range a {
+		if o := counts[line]; o.inA == 1 && o.inB == 1 {
+			unique = append(unique, [2]int{i, o.posB})
+		}
+	}
+	anchors := increasingByB(unique)
+	if len(anchors) == 0 {
+		return myers(a, b)
+	}
+
+	var script []Line
+	prevA, prevB := 0, 0
+	for _, anchor := range anchors {
+		script = append(script, trimmed(a[prevA:anchor[0]], b[prevB:anchor[1]], patience)...)
+		script = append(script, Line{Equal, a[anchor[0]]})
+		prevA, prevB = anchor[0]+1, anchor[1]+1
+	}
+	return append(script, trimmed(a[prevA:], b[prevB:], patience)...)
+}
+
+// increasingByB returns the longest subsequence of pairs whose second element increases, found with
+// patience sorting. The pairs' first elements must already increase.
+func increasingByB(pairs [][2]int) [][2]int {
+	var tops []int // Index into pairs of the smallest top of each pile
+	prev := make([]int, len(pairs))
+	for i, pair := range pairs {
+		pile, _ := slices.BinarySearchFunc(tops, pair[1], func(top, b int) int { return pairs[top][1] - b })
+		prev[i] = -1
+		if pile > 0 {
+			prev[i] = tops[pile-1]
+		}
+		if pile == len(tops) {
+			tops = append(tops, i)
+		} else {
+			tops[pile] = i
+		}
+	}
+
+	if len(tops) == 0 {
+		return nil
+	}
+	seq := make([][2]int, len(tops))
+	for i, k := len(tops)-1, tops[len(tops)-1]; k != -1; i, k = i-1, prev[k] {
+		seq[i] = pairs[k]
+	}
+	return seq
+}
+
+// maxHistogramChain bounds how often a line may repeat and still anchor a histogram diff, as in git.
+const maxHistogramChain = 64
+
+// Histogram returns an edit script turning a into b using the histogram algorithm, git's extension of
+// patience: the run of common lines containing the rarest line of a is matched first, and the texts on
+// either side of it are diffed recursively. Lines repeated too often to be useful anchors fall back
+// to [Myers].
+func Histogram(a, b []string) []Line {
+	return slide(trimmed(a, b, histogram))
+}
+
+func histogram(a, b []string) []Line {
+	if len(a) == 0 || len(b) == 0 {
+		return myers(a, b)
+	}
+
+	positions := map[string][]int{}
+	for i, line := range a {
+		positions[line] = append(positions[line], i)
+	}
+
+	bestA, bestB, bestLen, bestCount := 0, 0, 0, maxHistogramChain+1
+	for j := 0; j < len(b); {
+		next := j + 1
+		occurrences := positions[b[j]]
+		if len(occurrences) == 0 || len(occurrences) > bestCount {
+			j = next
+			continue
+		}
+
+		for _, i := range occurrences {
+			startA, startB, endA, endB := i, j, i+1, j+1
+			lowest := len(occurrences)
+			for startA > 0 && startB > 0 && a[startA-1] == b[startB-1] {
+				startA, startB = startA-1, startB-1
+				lowest = min(lowest, len(positions[a[startA]]))
+			}
+			for endA < len(a) && endB < len(b) && a[endA] == b[endB] {
+				lowest = min(lowest, len(positions[a[endA]]))
+				endA, endB = endA+1, endB+1
+			}
+			next = max(next, endB)
+
+			if lowest < bestCount || lowest == bestCount && endA-startA > bestLen {
+				bestA, bestB, bestLen, bestCount = startA, startB, endA-startA, lowest
+			}
+		}
+		j = next
+	}
+
+	if bestLen == 0 {
+		return myers(a, b)
+	}
+
+	script := trimmed(a[:bestA], b[:bestB], histogram)
+	for _, line := range a[bestA : bestA+bestLen] {
+		script = append(script, Line{Equal, line})
+	}
+	return append(script, trimmed(a[bestA+bestLen:], b[bestB+bestLen:], histogram)...)
+}
+
+// slide moves every run of only deletions or only insertions as far down as it goes while deleting or
+// inserting the same text, as git does. A moved block then ends where the code around it does, rather
+// than taking the closing brace or blank line above it.
+func slide(script []Line) []Line {
+	for i := 0; i < len(script); {
+		if script[i].Op == Equal {
+			i++
+			continue
+		}
+
+		op, end := script[i].Op, i
+		for end < len(script) && script[end].Op == op {
+			end++
+		}
+		if i > 0 && script[i-1].Op != Equal || end < len(script) && script[end].Op != Equal {
+			// Runs of deletions next to insertions replace lines, and stay where they are.
+			for end < len(script) && script[end].Op != Equal {
+				end++
+			}
+			i = end
+			continue
+		}
+
+		// Sliding past an equal line with the same text as the run's first line keeps both texts intact.
+		for end < len(script) && script[end].Op == Equal && script[end].Text == script[i].Text {
+			script[i].Op, script[end].Op = Equal, op
+			i, end = i+1, end+1
+		}
+		i = end
+	}
+	return script
+}
+
+// trimmed runs alg on a and b without their common prefix and suffix, which every algorithm would match anyway.
+func trimmed(a, b []string, alg Algorithm) []Line {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	script := make([]Line, 0, len(a)+len(b)-prefix-suffix)
+	for _, text := range a[:prefix] {
+		script = append(script, Line{Equal, text})
+	}
+	script = append(script, alg(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, text := range a[len(a)-suffix:] {
+		script = append(script, Line{Equal, text})
+	}
+	return script
+}
@@ -0,0 +1,104 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+)
+
+// Swapping two functions is the classic case where Myers matches up braces and blank lines from
+// unrelated code, while patience and histogram move one function as a block.
+const (
+	swapOld = `void copy(Chunk *src, Chunk *dst)
+{
+    check(src);
+    check(dst);
+
+    memcpy(dst->data, src->data, src->length);
+}
+
+int check(Chunk *chunk)
+{
+    if (chunk == NULL) return 0;
+
+    return chunk->length > 0;
+}
+`
+	swapNew = `int check(Chunk *chunk)
+{
+    if (chunk == NULL) return 0;
+
+    return chunk->length > 0;
+}
+
+void copy(Chunk *src, Chunk *dst)
+{
+    check(src);
+    check(dst);
+
+    memcpy(dst->data, src->data, src->length);
+}
+`
+)
+
+func TestParseAlgorithm(t *testing.T) {
+	for _, name := range []string{"", "myers", "Minimal", "patience", "histogram"} {
+		if _, err := ParseAlgorithm(name); err != nil {
+			t.Errorf("ParseAlgorithm(%q): %v", name, err)
+		}
+	}
+	if _, err := ParseAlgorithm("fastest"); err == nil {
+		t.Error("expected an unknown algorithm to be rejected")
+	}
+}
+
+func TestAlgorithmsMoveBlocks(t *testing.T) {
+	// Matches git diff --patience and --histogram, apart from the function name git adds to hunk headers.
+	want := `@@ -1,3 +1,10 @@
++int check(Chunk *chunk)
++{
++    if (chunk == NULL) return 0;
++
++    return chunk->length > 0;
++}
++
+ void copy(Chunk *src, Chunk *dst)
+ {
+     check(src);
+@@ -5,10 +12,3 @@
+ 
+     memcpy(dst->data, src->data, src->length);
+ }
+-
+-int check(Chunk *chunk)
+-{
+-    if (chunk == NULL) return 0;
+-
+-    return chunk->length > 0;
+-}
+`
+	for name, alg := range map[string]Algorithm{"patience": Patience, "histogram": Histogram} {
+		var got strings.Builder
+		for _, hunk := range alg.Diff([]byte(swapOld), []byte(swapNew), DefaultContext) {
+			got.WriteString(hunk.String())
+		}
+		if got.String() != want {
+			t.Errorf("%s:\n%s\nwant:\n%s", name, got.String(), want)
+		}
+	}
+
+	// Myers instead interleaves the two functions line by line.
+	if hunks := Diff([]byte(swapOld), []byte(swapNew), DefaultContext); len(hunks) != 1 || hunks[0].OldLines != 14 {
+		t.Errorf("expected Myers to rewrite the whole file in one hunk, got %d hunks", len(hunks))
+	}
+}
+
+func TestAlgorithmsRepeatedLines(t *testing.T) {
+	// Nothing is unique here, so patience falls back on Myers, and histogram anchors on the rarer x.
+	a := strings.Fields("a a x a a")
+	b := strings.Fields("a x a a b")
+	for name, alg := range map[string]Algorithm{"myers": Myers, "patience": Patience, "histogram": Histogram} {
+		if got, want := render(alg(a, b)), " a\n-a\n x\n a\n a\n+b\n"; got != want {
+			t.Errorf("%s:\n%s\nwant:\n%s", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package diff
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrHunkFailed = errors.New("hunk does not apply")
+
+// DefaultFuzz is how many lines of context at each end of a hunk may be ignored when the hunk doesn't
+// apply as is, the same as GNU patch.
+const DefaultFuzz = 2
+
+// ApplyError reports the hunk that [Apply] couldn't place.
+type ApplyError struct {
+	Hunk   int    // The failing hunk's position in the patch, counting from 0
+	Header string // The failing hunk's @@ line
+}
+
+func (e *ApplyError) Error() string {
+	return fmt.Sprintf("%v: hunk %d (%s)", ErrHunkFailed, e.Hunk+1, e.Header)
+}
+
+func (e *ApplyError) Unwrap() error { return ErrHunkFailed }
+
+// Apply applies hunks, in order, to text and returns the result.
+//
+// Each hunk is placed where its old lines, context included, match text: first at the line its header
+// names, then ever further above and below it, since earlier edits to the file may have moved it. Where
+// the earlier hunks landed shifts where later ones are looked for. If a hunk matches nowhere, up to fuzz
+// lines of context are dropped from either end and the search is repeated, as GNU patch does. A hunk that
+// still doesn't match fails with an [*ApplyError].
+func Apply(text []byte, hunks []Hunk, fuzz int) ([]byte, error) {
+	lines := Lines(text)
+	drift, minPos := 0, 0
+	for n, hunk := range hunks {
+		pos, old, new, ok := placeHunk(lines, hunk, drift, minPos, fuzz)
+		if !ok {
+			return nil, &ApplyError{Hunk: n, Header: hunk.Header()}
+		}
+
+		lines = append(lines[:pos], append(new, lines[pos+len(old):]...)...)
+		drift = pos - hunkStart(hunk)
+		minPos = pos + len(new)
+	}
+	return []byte(strings.Join(lines, "")), nil
+}
+
+// placeHunk finds where the hunk's old lines match lines, no earlier than minPos and as close as possible
+// to where the header and drift say, dropping up to fuzz context lines at either end when needed. It
+// returns the position along with the old and new lines that were matched and should replace them.
+func placeHunk(lines []string, hunk Hunk, drift, minPos, fuzz int) (int, []string, []string, bool) {
+	lead, trail := 0, 0
+	for lead < len(hunk.Lines) && hunk.Lines[lead].Op == Equal {
+		lead++
+	}
+	for trail < len(hunk.Lines)-lead && hunk.Lines[len(hunk.Lines)-1-trail].Op == Equal {
+		trail++
+	}
+
+	for f := 0; f <= fuzz; f++ {
+		dropLead, dropTrail := min(f, lead), min(f, trail)
+		if f > 0 && dropLead < f && dropTrail < f {
+			// Nothing more to drop.
+			break
+		}
+
+		var old, new []string
+		for _, line := range hunk.Lines[dropLead : len(hunk.Lines)-dropTrail] {
+			if line.Op != Insert {
+				old = append(old, line.Text)
+			}
+			if line.Op != Delete {
+				new = append(new, line.Text)
+			}
+		}
+
+		want := hunkStart(hunk) + drift + dropLead
+		last := len(lines) - len(old)
+		for distance := 0; want-distance >= minPos || want+distance <= last; distance++ {
+			for _, pos := range []int{want - distance, want + distance} {
+				if pos >= minPos && pos <= last && matchLines(lines[pos:], old) {
+					return pos, old, new, true
+				}
+			}
+		}
+	}
+	return 0, nil, nil, false
+}
+
+// hunkStart returns the index of the hunk's first old line, which for a hunk without old lines is where
+// its new lines go.
+func hunkStart(hunk Hunk) int {
+	if hunk.OldLines == 0 {
+		return hunk.OldStart
+	}
+	return hunk.OldStart - 1
+}
+
+func matchLines(lines, want []string) bool {
+	for i, line := range want {
+		if lines[i] != line {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,65 @@
+package diff
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// numbered returns n short lines, none of which repeat within 30 lines.
+func numbered(n int) string {
+	var b strings.Builder
+	for i := 1; i <= n; i++ {
+		b.WriteString(strings.Repeat("x", i%3) + string(rune('0'+i%10)) + "\n")
+	}
+	return b.String()
+}
+
+func TestApply(t *testing.T) {
+	old := numbered(30)
+	new := strings.Replace(strings.Replace(old, "x3\n", "three\n", 1), "xx5\n", "", 1)
+	hunks := Diff([]byte(old), []byte(new), DefaultContext)
+
+	got, err := Apply([]byte(old), hunks, 0)
+	if err != nil || string(got) != new {
+		t.Fatalf("Apply to the original text gave %q, %v", got, err)
+	}
+
+	// Lines added above the hunks move them, which the search makes up for.
+	shifted := "added\nmore\n" + old
+	got, err = Apply([]byte(shifted), hunks, 0)
+	if err != nil || string(got) != "added\nmore\n"+new {
+		t.Fatalf("Apply to shifted text gave %q, %v", got, err)
+	}
+}
+
+func TestApplyFuzz(t *testing.T) {
+	old := "a\nb\nc\nd\ne\nf\ng\n"
+	hunks := Diff([]byte(old), []byte("a\nb\nc\nD\ne\nf\ng\n"), DefaultContext)
+
+	// The outermost context lines no longer match, so the hunk only applies with fuzz.
+	edited := "A\nb\nc\nd\ne\nf\nG\n"
+	if _, err := Apply([]byte(edited), hunks, 0); !errors.Is(err, ErrHunkFailed) {
+		t.Fatalf("expected the hunk to fail without fuzz, got %v", err)
+	}
+	got, err := Apply([]byte(edited), hunks, 1)
+	if err != nil || string(got) != "A\nb\nc\nD\ne\nf\nG\n" {
+		t.Fatalf("Apply with fuzz gave %q, %v", got, err)
+	}
+
+	var applyErr *ApplyError
+	if _, err := Apply([]byte("unrelated\n"), hunks, DefaultFuzz); !errors.As(err, &applyErr) || applyErr.Hunk != 0 {
+		t.Fatalf("expected an ApplyError for the first hunk, got %v", err)
+	}
+}
+
+func TestApplyNoNewline(t *testing.T) {
+	hunks := Diff([]byte("a\nb"), []byte("a\nb\nc\n"), DefaultContext)
+	got, err := Apply([]byte("a\nb"), hunks, 0)
+	if err != nil || string(got) != "a\nb\nc\n" {
+		t.Fatalf("Apply gave %q, %v", got, err)
+	}
+	if got, err := Apply(nil, Diff(nil, []byte("new\n"), DefaultContext), 0); err != nil || string(got) != "new\n" {
+		t.Fatalf("Apply to an empty file gave %q, %v", got, err)
+	}
+}
@@ -0,0 +1,45 @@
+package diff
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// sniffLength is how much of a file is searched for a NUL byte, the same as git.
+const sniffLength = 8000
+
+// IsBinary guesses whether data is binary the way git does: it is if a NUL byte appears near the start.
+func IsBinary(data []byte) bool {
+	return bytes.IndexByte(data[:min(len(data), sniffLength)], 0) >= 0
+}
+
+// Size formats a byte count for people, such as 512B, 12KB, or 3.4MB, using powers of 1024.
+func Size(n int64) string {
+	if n < 0 {
+		return "-" + Size(-n)
+	}
+	if n < 1024 {
+		return fmt.Sprintf("%dB", n)
+	}
+
+	value, unit := float64(n)/1024, "KB"
+	for _, next := range []string{"MB", "GB", "TB"} {
+		if value < 1024 {
+			break
+		}
+		value, unit = value/1024, next
+	}
+	if value < 10 {
+		return fmt.Sprintf("%.1f%s", value, unit)
+	}
+	return fmt.Sprintf("%.0f%s", value, unit)
+}
+
+// SizeDelta formats the change in size from old to new bytes with its sign, such as +12KB or -512B.
+func SizeDelta(old, new int64) string {
+	delta := new - old
+	if delta < 0 {
+		return Size(delta)
+	}
+	return "+" + Size(delta)
+}
@@ -0,0 +1,38 @@
+package diff
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestIsBinary(t *testing.T) {
+	if IsBinary([]byte("plain text\n")) || IsBinary(nil) {
+		t.Error("text was taken for binary")
+	}
+	if !IsBinary([]byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")) {
+		t.Error("PNG header wasn't taken for binary")
+	}
+	// Like git, only the start of the file is searched.
+	late := append(bytes.Repeat([]byte("a"), sniffLength), 0)
+	if IsBinary(late) {
+		t.Error("a NUL past the sniffed prefix made the file binary")
+	}
+}
+
+func TestSizeDelta(t *testing.T) {
+	cases := []struct {
+		old, new int64
+		want     string
+	}{
+		{0, 0, "+0B"},
+		{100, 612, "+512B"},
+		{13 * 1024, 1024, "-12KB"},
+		{0, 1536, "+1.5KB"},
+		{0, 3 << 20, "+3.0MB"},
+	}
+	for _, c := range cases {
+		if got := SizeDelta(c.old, c.new); got != c.want {
+			t.Errorf("SizeDelta(%d, %d) = %q, want %q", c.old, c.new, got, c.want)
+		}
+	}
+}
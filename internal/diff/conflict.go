@@ -0,0 +1,108 @@
+package diff
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrMalformedConflict = errors.New("malformed conflict markers")
+
+// markerSize is the length of git's conflict markers, its default conflict-marker-size.
+const markerSize = 7
+
+// Conflict is one region of a file between conflict markers, as written by git merge or [Merge3].
+type Conflict struct {
+	Start, End  int // The 1-based lines of the <<<<<<< and >>>>>>> markers
+	OursLabel   string
+	TheirsLabel string
+	Ours        []string // Our version of the region, one line per entry with its newline
+	Theirs      []string
+	Base        []string // The common ancestor's version, present when the file was merged in diff3 style
+	HasBase     bool     // Whether there was a ||||||| section, which may be empty
+}
+
+// ParseConflicts finds the conflict regions in text, which may use either the merge or diff3 style. A
+// region that starts but never ends, or whose markers come in the wrong order, fails with
+// [ErrMalformedConflict]. Outside a region only <<<<<<< is a marker, so text such as a heading underlined
+// with equals signs is left alone.
+func ParseConflicts(text []byte) ([]Conflict, error) {
+	var conflicts []Conflict
+	var current *Conflict
+	section := &[]string{}
+
+	for i, line := range Lines(text) {
+		n := i + 1
+		kind, label := conflictMarker(line)
+		switch {
+		case kind == '<' && current == nil:
+			current = &Conflict{Start: n, OursLabel: label}
+			section = &current.Ours
+		case kind == '|' && current != nil && section == &current.Ours:
+			current.HasBase = true
+			section = &current.Base
+		case kind == '=' && current != nil && section != &current.Theirs:
+			section = &current.Theirs
+		case kind == '>' && current != nil && section == &current.Theirs:
+			current.End, current.TheirsLabel = n, label
+			conflicts = append(conflicts, *current)
+			current = nil
+		case kind != 0 && current != nil:
+			return nil, fmt.Errorf("%w: unexpected %s on line %d", ErrMalformedConflict, strings.Repeat(string(kind), markerSize), n)
+		case current != nil:
+			*section = append(*section, line)
+		}
+	}
+	if current != nil {
+		return nil, fmt.Errorf("%w: the conflict on line %d is never closed", ErrMalformedConflict, current.Start)
+	}
+	return conflicts, nil
+}
+
+// HasConflictMarkers reports whether text still contains a conflict, so isn't safe to mark resolved.
+func HasConflictMarkers(text []byte) bool {
+	conflicts, err := ParseConflicts(text)
+	return err != nil || len(conflicts) > 0
+}
+
+// ResolveConflicts replaces each conflict region in text with the lines choose returns for it, such as
+// its Ours or Theirs side, leaving the rest of text as it was.
+func ResolveConflicts(text []byte, choose func(Conflict) []string) ([]byte, error) {
+	conflicts, err := ParseConflicts(text)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := Lines(text)
+	var b strings.Builder
+	next := 0
+	for _, conflict := range conflicts {
+		writeLines(&b, lines[next:conflict.Start-1])
+		writeLines(&b, choose(conflict))
+		next = conflict.End
+	}
+	writeLines(&b, lines[next:])
+	return []byte(b.String()), nil
+}
+
+// conflictMarker returns which marker line is, as its repeated character, along with the label after
+// it. Lines that merely start with seven of the same character followed by more text aren't markers.
+func conflictMarker(line string) (byte, string) {
+	line = strings.TrimRight(line, "\r\n")
+	if len(line) < markerSize {
+		return 0, ""
+	}
+	kind := line[0]
+	if !strings.ContainsRune("<|=>", rune(kind)) || line[:markerSize] != strings.Repeat(string(kind), markerSize) {
+		return 0, ""
+	}
+	rest := line[markerSize:]
+	switch {
+	case rest == "":
+		return kind, ""
+	case rest[0] == ' ' && kind != '=':
+		return kind, rest[1:]
+	default:
+		return 0, ""
+	}
+}
@@ -0,0 +1,57 @@
+package diff
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestParseConflicts(t *testing.T) {
+	text := "Title\n=======\n" +
+		"<<<<<<< HEAD\nours\n=======\ntheirs\n>>>>>>> feature\n" +
+		"middle\n" +
+		"<<<<<<< ours\nA\n||||||| base\nB\n=======\n>>>>>>> theirs\n"
+
+	conflicts, err := ParseConflicts([]byte(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conflicts) != 2 {
+		t.Fatalf("expected 2 conflicts, got %+v", conflicts)
+	}
+
+	first := conflicts[0]
+	if first.Start != 3 || first.End != 7 || first.OursLabel != "HEAD" || first.TheirsLabel != "feature" || first.HasBase ||
+		!slices.Equal(first.Ours, []string{"ours\n"}) || !slices.Equal(first.Theirs, []string{"theirs\n"}) {
+		t.Errorf("unexpected first conflict %+v", first)
+	}
+	second := conflicts[1]
+	if !second.HasBase || !slices.Equal(second.Base, []string{"B\n"}) || len(second.Theirs) != 0 {
+		t.Errorf("unexpected diff3 conflict %+v", second)
+	}
+
+	for _, bad := range []string{
+		"<<<<<<< ours\nA\n",
+		"<<<<<<< ours\nA\n>>>>>>> theirs\n",
+		"<<<<<<< ours\n<<<<<<< again\n=======\n>>>>>>> theirs\n",
+	} {
+		if _, err := ParseConflicts([]byte(bad)); !errors.Is(err, ErrMalformedConflict) {
+			t.Errorf("ParseConflicts(%q) = %v, want ErrMalformedConflict", bad, err)
+		}
+	}
+}
+
+func TestResolveConflicts(t *testing.T) {
+	merged, conflicts := Merge3([]byte("a\nb\nc\n"), []byte("a\nours\nc\n"), []byte("a\ntheirs\nc\n"), "ours", "theirs")
+	if conflicts != 1 || !HasConflictMarkers(merged) {
+		t.Fatalf("expected a conflict, got %q", merged)
+	}
+
+	got, err := ResolveConflicts(merged, func(c Conflict) []string { return c.Theirs })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "a\ntheirs\nc\n" || HasConflictMarkers(got) {
+		t.Errorf("ResolveConflicts = %q", got)
+	}
+}
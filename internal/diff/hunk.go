@@ -0,0 +1,130 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultContext is how many unchanged lines surround each change in a hunk, as in git diff.
+const DefaultContext = 3
+
+// Hunk is a run of changes with the unchanged lines around them, as shown in a unified diff.
+type Hunk struct {
+	OldStart int // The first old line in the hunk, counting from 1, or the line before it when OldLines is 0
+	OldLines int // How many old lines the hunk covers
+	NewStart int // The first new line in the hunk, counting from 1, or the line before it when NewLines is 0
+	NewLines int // How many new lines the hunk covers
+	Lines    []Line
+}
+
+// Diff compares the texts a and b line by line with [Myers], returning hunks with context unchanged lines
+// around each change. Changes no more than twice context lines apart share a hunk. Identical texts have no hunks.
+func Diff(a, b []byte, context int) []Hunk {
+	return Algorithm(Myers).Diff(a, b, context)
+}
+
+// Stat counts the lines added and deleted turning the text a into b, like git diff --numstat.
+func Stat(a, b []byte) (added, deleted int) {
+	for _, line := range Myers(Lines(a), Lines(b)) {
+		switch line.Op {
+		case Insert:
+			added++
+		case Delete:
+			deleted++
+		}
+	}
+	return added, deleted
+}
+
+// Hunks groups the changes in an edit script into hunks with context unchanged lines around each change.
+func Hunks(script []Line, context int) []Hunk {
+	// oldBefore[i] and newBefore[i] count the old and new lines that come before script[i].
+	oldBefore := make([]int, len(script)+1)
+	newBefore := make([]int, len(script)+1)
+	for i, line := range script {
+		oldBefore[i+1], newBefore[i+1] = oldBefore[i], newBefore[i]
+		if line.Op != Insert {
+			oldBefore[i+1]++
+		}
+		if line.Op != Delete {
+			newBefore[i+1]++
+		}
+	}
+
+	var hunks []Hunk
+	for i := 0; i < len(script); {
+		if script[i].Op == Equal {
+			i++
+			continue
+		}
+
+		start, end := max(i-context, 0), i
+		for {
+			for end < len(script) && script[end].Op != Equal {
+				end++
+			}
+			next := end
+			for next < len(script) && script[next].Op == Equal {
+				next++
+			}
+			if next == len(script) || next-end > 2*context {
+				end = min(end+context, next)
+				break
+			}
+			end = next
+		}
+
+		hunk := Hunk{
+			OldStart: oldBefore[start] + 1,
+			OldLines: oldBefore[end] - oldBefore[start],
+			NewStart: newBefore[start] + 1,
+			NewLines: newBefore[end] - newBefore[start],
+			Lines:    script[start:end],
+		}
+		if hunk.OldLines == 0 {
+			hunk.OldStart--
+		}
+		if hunk.NewLines == 0 {
+			hunk.NewStart--
+		}
+		hunks = append(hunks, hunk)
+		i = end
+	}
+	return hunks
+}
+
+// Header returns the hunk's @@ line, without a newline.
+func (h Hunk) Header() string {
+	return fmt.Sprintf("@@ -%s +%s @@", hunkRange(h.OldStart, h.OldLines), hunkRange(h.NewStart, h.NewLines))
+}
+
+func hunkRange(start, lines int) string {
+	if lines == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, lines)
+}
+
+// String renders the hunk in unified diff format, marking a last line without a newline the way git does.
+func (h Hunk) String() string {
+	var b strings.Builder
+	b.WriteString(h.Header() + "\n")
+	for _, line := range h.Lines {
+		b.WriteString(line.Op.prefix() + line.Text)
+		if !strings.HasSuffix(line.Text, "\n") {
+			b.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+	return b.String()
+}
+
+func (op Op) prefix() string {
+	switch op {
+	case Delete:
+		return "-"
+	case Insert:
+		return "+"
+	default:
+		return " "
+	}
+}
@@ -0,0 +1,90 @@
+package diff
+
+import (
+	"slices"
+	"strings"
+)
+
+// Merge3 merges the changes ours and theirs each made to base, line by line like git merge-file.
+//
+// Where only one side changed a stretch of base, its change is taken. Where both changed it the same
+// way, the change is taken once. Otherwise the stretch is a conflict and both versions are kept between
+// git's markers, labelled with oursLabel and theirsLabel. The number of conflicts is returned along with
+// the merged text.
+func Merge3(base, ours, theirs []byte, oursLabel, theirsLabel string) ([]byte, int) {
+	baseLines, ourLines, theirLines := Lines(base), Lines(ours), Lines(theirs)
+	ourMatch := matches(Myers(baseLines, ourLines), len(baseLines))
+	theirMatch := matches(Myers(baseLines, theirLines), len(baseLines))
+
+	var b strings.Builder
+	conflicts := 0
+	i, j, k := 0, 0, 0
+	for {
+		// Find the next base line both sides kept, which ends the current stretch.
+		m := i
+		for m < len(baseLines) && (ourMatch[m] < 0 || theirMatch[m] < 0) {
+			m++
+		}
+		ourEnd, theirEnd := len(ourLines), len(theirLines)
+		if m < len(baseLines) {
+			ourEnd, theirEnd = ourMatch[m], theirMatch[m]
+		}
+
+		baseChunk, ourChunk, theirChunk := baseLines[i:m], ourLines[j:ourEnd], theirLines[k:theirEnd]
+		switch {
+		case slices.Equal(ourChunk, baseChunk):
+			writeLines(&b, theirChunk)
+		case slices.Equal(theirChunk, baseChunk), slices.Equal(ourChunk, theirChunk):
+			writeLines(&b, ourChunk)
+		default:
+			conflicts++
+			b.WriteString("<<<<<<< " + oursLabel + "\n")
+			writeConflictSide(&b, ourChunk)
+			b.WriteString("=======\n")
+			writeConflictSide(&b, theirChunk)
+			b.WriteString(">>>>>>> " + theirsLabel + "\n")
+		}
+
+		if m == len(baseLines) {
+			break
+		}
+		b.WriteString(baseLines[m])
+		i, j, k = m+1, ourEnd+1, theirEnd+1
+	}
+	return []byte(b.String()), conflicts
+}
+
+// matches maps each line of the old text of script to the index of the line it was kept as in the new
+// text, or -1 if it was deleted.
+func matches(script []Line, oldLen int) []int {
+	match := make([]int, oldLen)
+	i, j := 0, 0
+	for _, line := range script {
+		switch line.Op {
+		case Equal:
+			match[i] = j
+			i, j = i+1, j+1
+		case Delete:
+			match[i] = -1
+			i++
+		case Insert:
+			j++
+		}
+	}
+	return match
+}
+
+func writeLines(b *strings.Builder, lines []string) {
+	for _, line := range lines {
+		b.WriteString(line)
+	}
+}
+
+// writeConflictSide writes one side of a conflict, ending it with a newline so the marker after it
+// starts a line of its own.
+func writeConflictSide(b *strings.Builder, lines []string) {
+	writeLines(b, lines)
+	if n := len(lines); n > 0 && !strings.HasSuffix(lines[n-1], "\n") {
+		b.WriteString("\n")
+	}
+}
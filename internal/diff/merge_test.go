@@ -0,0 +1,27 @@
+package diff
+
+import "testing"
+
+func TestMerge3(t *testing.T) {
+	base := "a\nb\nc\nd\ne\n"
+	cases := []struct {
+		name         string
+		ours, theirs string
+		want         string
+		conflicts    int
+	}{
+		{"separate changes", "A\nb\nc\nd\ne\n", "a\nb\nc\nd\nE\n", "A\nb\nc\nd\nE\n", 0},
+		{"same change", "a\nB\nc\nd\ne\n", "a\nB\nc\nd\ne\n", "a\nB\nc\nd\ne\n", 0},
+		{"one side only", base, "a\nc\nd\ne\nf\n", "a\nc\nd\ne\nf\n", 0},
+		{
+			"conflict", "a\nours\nc\nd\ne\n", "a\ntheirs\nc\nd\ne\n",
+			"a\n<<<<<<< ours\nours\n=======\ntheirs\n>>>>>>> theirs\nc\nd\ne\n", 1,
+		},
+	}
+	for _, c := range cases {
+		got, conflicts := Merge3([]byte(base), []byte(c.ours), []byte(c.theirs), "ours", "theirs")
+		if string(got) != c.want || conflicts != c.conflicts {
+			t.Errorf("%s: got %q with %d conflicts, want %q with %d", c.name, got, conflicts, c.want, c.conflicts)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package diff
+
+import (
+	"bytes"
+	"slices"
+)
+
+// Op is what a diff line does to the old text.
+type Op int
+
+const (
+	Equal  Op = iota // The line is in both texts
+	Delete           // The line is only in the old text
+	Insert           // The line is only in the new text
+)
+
+// Line is one line of an edit script.
+type Line struct {
+	Op   Op
+	Text string // The line's content, including its newline unless it ends a text without one
+}
+
+// Lines splits data into lines, keeping each line's newline. A missing newline at the end leaves the
+// last line without one, so files that differ only there still compare unequal.
+func Lines(data []byte) []string {
+	var lines []string
+	for len(data) > 0 {
+		i := bytes.IndexByte(data, '\n') + 1
+		if i == 0 {
+			i = len(data)
+		}
+		lines = append(lines, string(data[:i]))
+		data = data[i:]
+	}
+	return lines
+}
+
+// Myers returns the shortest edit script turning a into b, using Myers' O(ND) algorithm. Deletions come
+// before insertions wherever both are possible, as in git's output.
+func Myers(a, b []string) []Line {
+	return slide(trimmed(a, b, myers))
+}
+
+// myers runs the greedy forward search, keeping the furthest reaching x of every diagonal k = x - y
+// after each edit count d so the path can be traced back afterwards.
+func myers(a, b []string) []Line {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+
+	// trace[d] holds v for diagonals -d-1 through d+1 as it was before step d.
+	var trace [][]int
+	for d := 0; d <= n+m; d++ {
+		trace = append(trace, slices.Clone(v[offset-d-1:offset+d+2]))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || k != d && v[offset+k-1] < v[offset+k+1] {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, a, b)
+			}
+		}
+	}
+	return nil
+}
+
+func backtrack(trace [][]int, a, b []string) []Line {
+	var script []Line
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := func(k int) int { return trace[d][k+d+1] }
+
+		k := x - y
+		prev := k - 1
+		if k == -d || k != d && v(k-1) < v(k+1) {
+			prev = k + 1
+		}
+		prevX := v(prev)
+		prevY := prevX - prev
+
+		for x > prevX && y > prevY {
+			x, y = x-1, y-1
+			script = append(script, Line{Equal, a[x]})
+		}
+		if d > 0 {
+			if x == prevX {
+				y--
+				script = append(script, Line{Insert, b[y]})
+			} else {
+				x--
+				script = append(script, Line{Delete, a[x]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+	slices.Reverse(script)
+	return script
+}
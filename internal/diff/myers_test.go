@@ -0,0 +1,161 @@
+package diff
+
+import (
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// render writes an edit script one line per entry, prefixed like a unified diff.
+func render(script []Line) string {
+	var b strings.Builder
+	for _, line := range script {
+		b.WriteString(line.Op.prefix() + strings.TrimSuffix(line.Text, "\n") + "\n")
+	}
+	return b.String()
+}
+
+func TestLines(t *testing.T) {
+	lines := Lines([]byte("a\nb\nc"))
+	if len(lines) != 3 || lines[0] != "a\n" || lines[2] != "c" {
+		t.Errorf("unexpected lines %q", lines)
+	}
+	if lines := Lines(nil); len(lines) != 0 {
+		t.Errorf("expected no lines, got %q", lines)
+	}
+}
+
+func TestMyers(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want string
+	}{
+		{"", "", ""},
+		{"a b c", "a b c", " a\n b\n c\n"},
+		{"", "a b", "+a\n+b\n"},
+		{"a b", "", "-a\n-b\n"},
+		{"a b c", "a x c", " a\n-b\n+x\n c\n"},
+		// The example from Myers' paper, which has an edit distance of 5.
+		{"a b c a b b a", "c b a b a c", "-a\n-b\n c\n+b\n a\n b\n-b\n a\n+c\n"},
+	}
+	for _, c := range cases {
+		got := render(Myers(strings.Fields(c.a), strings.Fields(c.b)))
+		if got != c.want {
+			t.Errorf("Myers(%q, %q):\n%s\nwant:\n%s", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func TestEditScripts(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	random := func() []string {
+		lines := make([]string, rng.IntN(12))
+		for i := range lines {
+			lines[i] = string(rune('a' + rng.IntN(3)))
+		}
+		return lines
+	}
+
+	for range 500 {
+		a, b := random(), random()
+		for name, alg := range map[string]Algorithm{"myers": Myers, "patience": Patience, "histogram": Histogram} {
+			script := alg(a, b)
+
+			var gotA, gotB []string
+			edits := 0
+			for _, line := range script {
+				if line.Op != Insert {
+					gotA = append(gotA, line.Text)
+				}
+				if line.Op != Delete {
+					gotB = append(gotB, line.Text)
+				}
+				if line.Op != Equal {
+					edits++
+				}
+			}
+			if !slices.Equal(gotA, a) || !slices.Equal(gotB, b) {
+				t.Fatalf("%s script for %q -> %q doesn't reproduce both texts", name, a, b)
+			}
+			// Only Myers promises a shortest script.
+			if want := len(a) + len(b) - 2*lcs(a, b); name == "myers" && edits != want {
+				t.Fatalf("script for %q -> %q has %d edits, want %d", a, b, edits, want)
+			}
+		}
+	}
+}
+
+// lcs returns the length of the longest common subsequence of a and b.
+func lcs(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	for i := range a {
+		cur := make([]int, len(b)+1)
+		for j := range b {
+			if a[i] == b[j] {
+				cur[j+1] = prev[j] + 1
+			} else {
+				cur[j+1] = max(prev[j+1], cur[j])
+			}
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func TestDiffHunks(t *testing.T) {
+	var old, new strings.Builder
+	for i := 1; i <= 20; i++ {
+		line := string(rune('a'+i-1)) + "\n"
+		old.WriteString(line)
+		switch i {
+		case 2, 7:
+			new.WriteString(strings.ToUpper(line))
+		case 18:
+		default:
+			new.WriteString(line)
+		}
+	}
+
+	hunks := Diff([]byte(old.String()), []byte(new.String()), DefaultContext)
+	if len(hunks) != 2 {
+		t.Fatalf("expected 2 hunks, got %d", len(hunks))
+	}
+
+	// The changes at lines 2 and 7 are close enough to share a hunk.
+	want := "@@ -1,10 +1,10 @@\n a\n-b\n+B\n c\n d\n e\n f\n-g\n+G\n h\n i\n j\n"
+	if got := hunks[0].String(); got != want {
+		t.Errorf("unexpected first hunk:\n%s\nwant:\n%s", got, want)
+	}
+	want = "@@ -15,6 +15,5 @@\n o\n p\n q\n-r\n s\n t\n"
+	if got := hunks[1].String(); got != want {
+		t.Errorf("unexpected second hunk:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestDiffEdges(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want string
+	}{
+		{"a\n", "a\n", ""},
+		{"", "a\n", "@@ -0,0 +1 @@\n+a\n"},
+		{"a\n", "", "@@ -1 +0,0 @@\n-a\n"},
+		{"a\nb", "a\nb\n", "@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n"},
+	}
+	for _, c := range cases {
+		var got strings.Builder
+		for _, hunk := range Diff([]byte(c.a), []byte(c.b), DefaultContext) {
+			got.WriteString(hunk.String())
+		}
+		if got.String() != c.want {
+			t.Errorf("Diff(%q, %q):\n%s\nwant:\n%s", c.a, c.b, got.String(), c.want)
+		}
+	}
+}
+
+func TestStat(t *testing.T) {
+	if added, deleted := Stat([]byte("a\nb\nc\n"), []byte("a\nB\nc\nd\n")); added != 2 || deleted != 1 {
+		t.Errorf("expected 2 added and 1 deleted, got %d and %d", added, deleted)
+	}
+}
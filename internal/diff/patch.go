@@ -0,0 +1,428 @@
+package diff
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var ErrMalformedPatch = errors.New("malformed patch")
+
+// devNull stands in for the missing side of an added or deleted file.
+const devNull = "/dev/null"
+
+// FilePatch is the change to one file in a unified patch, with the extended headers git adds.
+//
+// Paths are slash separated and carry no a/ or b/ prefix. An added file has no OldPath and a deleted one
+// no NewPath; a renamed file has both, and they differ. Modes are git file modes such as 0o100644, and 0
+// when the patch doesn't say.
+type FilePatch struct {
+	OldPath, NewPath string
+	OldMode, NewMode uint32
+	OldHash, NewHash string // The blob hashes from the index line, usually abbreviated
+	Similarity       int    // How alike a renamed file's old and new content are, as a percentage
+	Binary           bool   // The content is binary, so the patch has no hunks
+	Hunks            []Hunk
+}
+
+// IsNew reports whether the patch adds the file.
+func (p *FilePatch) IsNew() bool { return p.OldPath == "" }
+
+// IsDeleted reports whether the patch deletes the file.
+func (p *FilePatch) IsDeleted() bool { return p.NewPath == "" }
+
+// IsRename reports whether the patch moves the file to a new path.
+func (p *FilePatch) IsRename() bool {
+	return !p.IsNew() && !p.IsDeleted() && p.OldPath != p.NewPath
+}
+
+// Path returns the path the patch is about: the new path, or the old one for a deleted file.
+func (p *FilePatch) Path() string {
+	if p.IsDeleted() {
+		return p.OldPath
+	}
+	return p.NewPath
+}
+
+// Header returns the diff --git line and the extended header lines that follow it, up to and including
+// the index line, each ending in a newline.
+func (p *FilePatch) Header() string {
+	oldPath, newPath := p.OldPath, p.NewPath
+	if p.IsNew() {
+		oldPath = newPath
+	}
+	if p.IsDeleted() {
+		newPath = oldPath
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "diff --git %s %s\n", quotePatchPath("a/"+oldPath), quotePatchPath("b/"+newPath))
+	switch {
+	case p.IsNew():
+		fmt.Fprintf(&b, "new file mode %o\n", p.NewMode)
+	case p.IsDeleted():
+		fmt.Fprintf(&b, "deleted file mode %o\n", p.OldMode)
+	case p.OldMode != p.NewMode && p.OldMode != 0 && p.NewMode != 0:
+		fmt.Fprintf(&b, "old mode %o\nnew mode %o\n", p.OldMode, p.NewMode)
+	}
+	if p.IsRename() {
+		fmt.Fprintf(&b, "similarity index %d%%\n", p.Similarity)
+		fmt.Fprintf(&b, "rename from %s\nrename to %s\n", quotePatchPath(p.OldPath), quotePatchPath(p.NewPath))
+	}
+	// Like git, a change to the mode alone has no index line.
+	if p.OldHash != p.NewHash {
+		fmt.Fprintf(&b, "index %s..%s", p.OldHash, p.NewHash)
+		if p.OldMode == p.NewMode && p.NewMode != 0 {
+			fmt.Fprintf(&b, " %o", p.NewMode)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// String renders the patch the way git diff does.
+func (p *FilePatch) String() string {
+	return p.render(Hunk.String)
+}
+
+// WordString renders the patch like git diff --word-diff=plain, with each hunk shown by [Hunk.WordString].
+// The result is for reading, and can't be parsed back.
+func (p *FilePatch) WordString() string {
+	return p.render(Hunk.WordString)
+}
+
+func (p *FilePatch) render(hunkString func(Hunk) string) string {
+	var b strings.Builder
+	b.WriteString(p.Header())
+	if p.Binary {
+		fmt.Fprintf(&b, "Binary files %s and %s differ\n", p.sidePath("a/", p.OldPath), p.sidePath("b/", p.NewPath))
+		return b.String()
+	}
+	if len(p.Hunks) == 0 {
+		return b.String()
+	}
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", fileLinePath(p.sidePath("a/", p.OldPath)), fileLinePath(p.sidePath("b/", p.NewPath)))
+	for _, hunk := range p.Hunks {
+		b.WriteString(hunkString(hunk))
+	}
+	return b.String()
+}
+
+func (p *FilePatch) sidePath(prefix, path string) string {
+	if path == "" {
+		return devNull
+	}
+	return quotePatchPath(prefix + path)
+}
+
+// fileLinePath ends a --- or +++ path containing spaces with a tab, as git does, so it can't be mistaken
+// for a path followed by a timestamp.
+func fileLinePath(path string) string {
+	if strings.Contains(path, " ") {
+		return path + "\t"
+	}
+	return path
+}
+
+// quotePatchPath quotes a path the way git does in patch headers, when it holds characters that would
+// make the header ambiguous.
+func quotePatchPath(path string) string {
+	for _, c := range path {
+		if c == '"' || c == '\\' || c < ' ' || c == 0x7f {
+			return strconv.Quote(path)
+		}
+	}
+	return path
+}
+
+// ParsePatch reads every file patch in r, which may hold git's extended headers or plain unified diffs.
+// Lines before the first patch and between patches, such as the message of a mailed patch, are skipped.
+func ParsePatch(r io.Reader) ([]FilePatch, error) {
+	p := &patchParser{reader: bufio.NewReader(r)}
+
+	var patches []FilePatch
+	for p.next() {
+		switch {
+		case strings.HasPrefix(p.line, "diff --git "):
+			patch, err := p.parseGit()
+			if err != nil {
+				return nil, err
+			}
+			patches = append(patches, patch)
+		case strings.HasPrefix(p.line, "--- ") && p.peekPrefix("+++ "):
+			var patch FilePatch
+			if err := p.parseFileLines(&patch); err != nil {
+				return nil, err
+			}
+			if err := p.parseHunks(&patch); err != nil {
+				return nil, err
+			}
+			patches = append(patches, patch)
+		}
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return patches, nil
+}
+
+// patchParser reads a patch line by line, with one line of lookahead.
+type patchParser struct {
+	reader *bufio.Reader
+	err    error   // The first read error other than io.EOF
+	line   string  // The current line, without its newline
+	lineNo int     // The current line's number, counting from 1
+	peeked *string // The line after the current one, once peeked at
+}
+
+func (p *patchParser) next() bool {
+	if p.peeked == nil && !p.read() {
+		return false
+	}
+	p.line, p.peeked = *p.peeked, nil
+	p.lineNo++
+	return true
+}
+
+// peekPrefix reports whether the line after the current one starts with prefix.
+func (p *patchParser) peekPrefix(prefix string) bool {
+	if p.peeked == nil && !p.read() {
+		return false
+	}
+	return strings.HasPrefix(*p.peeked, prefix)
+}
+
+// read reads the next line into peeked. Only the newline is dropped, so carriage returns in files with
+// Windows line endings survive.
+func (p *patchParser) read() bool {
+	line, err := p.reader.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		if err != io.EOF {
+			p.err = err
+		}
+		return false
+	}
+	line = strings.TrimSuffix(line, "\n")
+	p.peeked = &line
+	return true
+}
+
+func (p *patchParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("%w: line %d: %s", ErrMalformedPatch, p.lineNo, fmt.Sprintf(format, args...))
+}
+
+// parseGit parses a patch starting at its diff --git line.
+func (p *patchParser) parseGit() (FilePatch, error) {
+	var patch FilePatch
+	oldPath, newPath, err := splitGitHeader(strings.TrimPrefix(p.line, "diff --git "))
+	if err != nil {
+		return patch, p.errorf("%v", err)
+	}
+	patch.OldPath, patch.NewPath = oldPath, newPath
+
+	for p.peekPrefix("") && !strings.HasPrefix(*p.peeked, "diff --git ") && !strings.HasPrefix(*p.peeked, "@@ ") {
+		p.next()
+		key, value, _ := strings.Cut(p.line, " ")
+		switch {
+		case strings.HasPrefix(p.line, "new file mode "):
+			patch.OldPath = ""
+			patch.NewMode, err = parseMode(strings.TrimPrefix(p.line, "new file mode "))
+		case strings.HasPrefix(p.line, "deleted file mode "):
+			patch.NewPath = ""
+			patch.OldMode, err = parseMode(strings.TrimPrefix(p.line, "deleted file mode "))
+		case strings.HasPrefix(p.line, "old mode "):
+			patch.OldMode, err = parseMode(strings.TrimPrefix(p.line, "old mode "))
+		case strings.HasPrefix(p.line, "new mode "):
+			patch.NewMode, err = parseMode(strings.TrimPrefix(p.line, "new mode "))
+		case strings.HasPrefix(p.line, "rename from "), strings.HasPrefix(p.line, "copy from "):
+			patch.OldPath, err = unquotePatchPath(p.line[strings.Index(p.line, "from ")+5:])
+		case strings.HasPrefix(p.line, "rename to "), strings.HasPrefix(p.line, "copy to "):
+			patch.NewPath, err = unquotePatchPath(p.line[strings.Index(p.line, "to ")+3:])
+		case strings.HasPrefix(p.line, "similarity index "):
+			patch.Similarity, err = strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(p.line, "similarity index "), "%"))
+		case key == "index":
+			hashes, mode, hasMode := strings.Cut(value, " ")
+			var ok bool
+			patch.OldHash, patch.NewHash, ok = strings.Cut(hashes, "..")
+			if !ok {
+				return patch, p.errorf("bad index line %q", p.line)
+			}
+			if hasMode {
+				patch.OldMode, err = parseMode(mode)
+				patch.NewMode = patch.OldMode
+			}
+		case strings.HasPrefix(p.line, "Binary files "), p.line == "GIT binary patch":
+			patch.Binary = true
+		case key == "---":
+			if err := p.parseFileLines(&patch); err != nil {
+				return patch, err
+			}
+		default:
+			// Anything else ends the header, like the blank line between patches in a series.
+			return patch, nil
+		}
+		if err != nil {
+			return patch, p.errorf("%v", err)
+		}
+	}
+	return patch, p.parseHunks(&patch)
+}
+
+// parseFileLines parses a --- line and the +++ line after it, overriding any paths seen so far.
+func (p *patchParser) parseFileLines(patch *FilePatch) error {
+	oldPath, err := patchSidePath(strings.TrimPrefix(p.line, "--- "), "a/")
+	if err != nil {
+		return p.errorf("%v", err)
+	}
+	if !p.next() || !strings.HasPrefix(p.line, "+++ ") {
+		return p.errorf("expected a +++ line after ---")
+	}
+	newPath, err := patchSidePath(strings.TrimPrefix(p.line, "+++ "), "b/")
+	if err != nil {
+		return p.errorf("%v", err)
+	}
+	patch.OldPath, patch.NewPath = oldPath, newPath
+	return nil
+}
+
+// parseHunks parses the hunks that follow the current line.
+func (p *patchParser) parseHunks(patch *FilePatch) error {
+	for p.peekPrefix("@@ ") {
+		p.next()
+		hunk, err := parseHunkHeader(p.line)
+		if err != nil {
+			return p.errorf("%v", err)
+		}
+
+		oldLeft, newLeft := hunk.OldLines, hunk.NewLines
+		for oldLeft > 0 || newLeft > 0 || p.peekPrefix(`\`) {
+			if !p.next() {
+				return p.errorf("hunk ends early, expected %d more old and %d more new lines", oldLeft, newLeft)
+			}
+			if strings.HasPrefix(p.line, `\`) {
+				// "\ No newline at end of file" applies to the line before it.
+				if n := len(hunk.Lines); n > 0 {
+					hunk.Lines[n-1].Text = strings.TrimSuffix(hunk.Lines[n-1].Text, "\n")
+				}
+				continue
+			}
+
+			op, text := Equal, p.line
+			if p.line != "" {
+				// Some editors strip the space off empty context lines, which git accepts too.
+				switch p.line[0] {
+				case ' ':
+				case '-':
+					op = Delete
+				case '+':
+					op = Insert
+				default:
+					return p.errorf("unexpected line in hunk %q", p.line)
+				}
+				text = p.line[1:]
+			}
+
+			if op != Insert {
+				oldLeft--
+			}
+			if op != Delete {
+				newLeft--
+			}
+			if oldLeft < 0 || newLeft < 0 {
+				return p.errorf("hunk has more lines than its header %q says", hunk.Header())
+			}
+			hunk.Lines = append(hunk.Lines, Line{op, text + "\n"})
+		}
+		patch.Hunks = append(patch.Hunks, hunk)
+	}
+	return nil
+}
+
+// parseHunkHeader parses an @@ -a,b +c,d @@ line, ignoring the section heading after it.
+func parseHunkHeader(line string) (Hunk, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 || fields[3] != "@@" || !strings.HasPrefix(fields[1], "-") || !strings.HasPrefix(fields[2], "+") {
+		return Hunk{}, fmt.Errorf("bad hunk header %q", line)
+	}
+
+	var hunk Hunk
+	var err error
+	if hunk.OldStart, hunk.OldLines, err = parseHunkRange(fields[1][1:]); err != nil {
+		return Hunk{}, fmt.Errorf("bad hunk header %q: %w", line, err)
+	}
+	if hunk.NewStart, hunk.NewLines, err = parseHunkRange(fields[2][1:]); err != nil {
+		return Hunk{}, fmt.Errorf("bad hunk header %q: %w", line, err)
+	}
+	return hunk, nil
+}
+
+func parseHunkRange(s string) (start, lines int, err error) {
+	startText, linesText, ok := strings.Cut(s, ",")
+	if start, err = strconv.Atoi(startText); err != nil {
+		return 0, 0, err
+	}
+	if !ok {
+		return start, 1, nil
+	}
+	lines, err = strconv.Atoi(linesText)
+	return start, lines, err
+}
+
+func parseMode(s string) (uint32, error) {
+	mode, err := strconv.ParseUint(strings.TrimSpace(s), 8, 32)
+	return uint32(mode), err
+}
+
+// splitGitHeader splits the two paths of a diff --git line, which may be quoted and, when not, may
+// contain spaces. Unquoted paths with spaces are only split unambiguously when both are the same.
+func splitGitHeader(s string) (oldPath, newPath string, err error) {
+	if strings.HasPrefix(s, `"`) {
+		quoted, err := strconv.QuotedPrefix(s)
+		if err != nil {
+			return "", "", err
+		}
+		oldPath, _ = strconv.Unquote(quoted)
+		newPath, err = unquotePatchPath(strings.TrimPrefix(s[len(quoted):], " "))
+		if err != nil {
+			return "", "", err
+		}
+		return strings.TrimPrefix(oldPath, "a/"), strings.TrimPrefix(newPath, "b/"), nil
+	}
+
+	// With equal paths, "a/<p> b/<p>" has the second path starting exactly halfway through.
+	if half := (len(s) - 1) / 2; len(s)%2 == 1 && s[half] == ' ' && strings.HasPrefix(s, "a/") && s[half+1:half+3] == "b/" && s[2:half] == s[half+3:] {
+		return s[2:half], s[half+3:], nil
+	}
+	i := strings.Index(s, " b/")
+	if i < 0 {
+		i = strings.LastIndex(s, " ")
+	}
+	if i < 0 {
+		return "", "", fmt.Errorf("bad diff --git line %q", s)
+	}
+	newPath, err = unquotePatchPath(s[i+1:])
+	return strings.TrimPrefix(s[:i], "a/"), strings.TrimPrefix(newPath, "b/"), err
+}
+
+// patchSidePath parses the path of a --- or +++ line, dropping prefix and any timestamp after a tab.
+// /dev/null gives "".
+func patchSidePath(s, prefix string) (string, error) {
+	if !strings.HasPrefix(s, `"`) {
+		s, _, _ = strings.Cut(s, "\t")
+	}
+	path, err := unquotePatchPath(strings.TrimRight(s, " "))
+	if err != nil || path == devNull {
+		return "", err
+	}
+	return strings.TrimPrefix(path, prefix), nil
+}
+
+func unquotePatchPath(s string) (string, error) {
+	if !strings.HasPrefix(s, `"`) {
+		return s, nil
+	}
+	return strconv.Unquote(s)
+}
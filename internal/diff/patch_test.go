@@ -0,0 +1,109 @@
+package diff
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// gitPatch is git diff -M output covering an edit, a deletion, a rename, a mode change, and a new file
+// whose path has a space.
+const gitPatch = "diff --git a/a.txt b/a.txt\n" +
+	"index 4cb29ea..047ece5 100644\n" +
+	"--- a/a.txt\n" +
+	"+++ b/a.txt\n" +
+	"@@ -1,3 +1,4 @@\n" +
+	" one\n" +
+	"-two\n" +
+	"+2\n" +
+	" three\n" +
+	"+four\n" +
+	"\\ No newline at end of file\n" +
+	"diff --git a/gone.txt b/gone.txt\n" +
+	"deleted file mode 100644\n" +
+	"index c1b0730..0000000\n" +
+	"--- a/gone.txt\n" +
+	"+++ /dev/null\n" +
+	"@@ -1 +0,0 @@\n" +
+	"-x\n" +
+	"\\ No newline at end of file\n" +
+	"diff --git a/mv.txt b/moved.txt\n" +
+	"similarity index 100%\n" +
+	"rename from mv.txt\n" +
+	"rename to moved.txt\n" +
+	"diff --git a/run.sh b/run.sh\n" +
+	"old mode 100644\n" +
+	"new mode 100755\n" +
+	"diff --git a/sp ace.txt b/sp ace.txt\n" +
+	"new file mode 100644\n" +
+	"index 0000000..3e75765\n" +
+	"--- /dev/null\n" +
+	"+++ b/sp ace.txt\t\n" +
+	"@@ -0,0 +1 @@\n" +
+	"+new\n"
+
+func TestParsePatchRoundTrip(t *testing.T) {
+	patches, err := ParsePatch(strings.NewReader(gitPatch))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(patches) != 5 {
+		t.Fatalf("expected 5 file patches, got %d", len(patches))
+	}
+
+	edit, deleted, renamed, chmod, added := patches[0], patches[1], patches[2], patches[3], patches[4]
+	if edit.Path() != "a.txt" || len(edit.Hunks) != 1 || edit.Hunks[0].Lines[4].Text != "four" {
+		t.Errorf("unexpected edit %+v", edit)
+	}
+	if !deleted.IsDeleted() || deleted.OldMode != 0o100644 {
+		t.Errorf("expected gone.txt to be deleted, got %+v", deleted)
+	}
+	if !renamed.IsRename() || renamed.OldPath != "mv.txt" || renamed.NewPath != "moved.txt" || renamed.Similarity != 100 {
+		t.Errorf("unexpected rename %+v", renamed)
+	}
+	if chmod.OldMode != 0o100644 || chmod.NewMode != 0o100755 {
+		t.Errorf("unexpected mode change %+v", chmod)
+	}
+	if !added.IsNew() || added.Path() != "sp ace.txt" || added.NewHash != "3e75765" {
+		t.Errorf("unexpected new file %+v", added)
+	}
+
+	var rendered strings.Builder
+	for _, patch := range patches {
+		rendered.WriteString(patch.String())
+	}
+	if rendered.String() != gitPatch {
+		t.Errorf("patches didn't render back to git's output:\n%s", rendered.String())
+	}
+}
+
+func TestParsePlainUnifiedDiff(t *testing.T) {
+	input := "From: someone\nSubject: fix\n\n--- old/main.c\t2024-01-01 10:00:00\n+++ new/main.c\t2024-01-02 10:00:00\n" +
+		"@@ -1,2 +1,2 @@ int main()\n-\treturn 1;\r\n+\treturn 0;\r\n\n"
+	patches, err := ParsePatch(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(patches) != 1 || patches[0].OldPath != "old/main.c" || patches[0].NewPath != "new/main.c" {
+		t.Fatalf("unexpected patches %+v", patches)
+	}
+	// The empty line is a context line whose leading space was stripped, and carriage returns survive.
+	lines := patches[0].Hunks[0].Lines
+	if len(lines) != 3 || lines[1].Text != "\treturn 0;\r\n" || lines[2] != (Line{Equal, "\n"}) {
+		t.Errorf("unexpected hunk lines %q", lines)
+	}
+}
+
+func TestParsePatchMalformed(t *testing.T) {
+	inputs := []string{
+		"diff --git a/x b/x\n--- a/x\n+++ b/x\n@@ -1,2 +1,2 @@\n-a\n+b\n",
+		"diff --git a/x b/x\n--- a/x\n+++ b/x\n@@ -1 +1 @@\n*a\n+b\n",
+		"diff --git a/x b/x\n--- a/x\n+++ b/x\n@@ bogus @@\n",
+		"diff --git a/x b/x\nindex abc\n",
+	}
+	for _, input := range inputs {
+		if _, err := ParsePatch(strings.NewReader(input)); !errors.Is(err, ErrMalformedPatch) {
+			t.Errorf("ParsePatch(%q): expected ErrMalformedPatch, got %v", input, err)
+		}
+	}
+}
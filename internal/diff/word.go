@@ -0,0 +1,124 @@
+package diff
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Words splits text into the tokens a word diff compares: runs of letters, digits, and underscores,
+// runs of whitespace other than newlines, and single characters of anything else. Newlines are tokens
+// of their own, so a change never swallows the end of a line. Joining the tokens gives back text.
+func Words(text string) []string {
+	var words []string
+	for len(text) > 0 {
+		r, size := utf8.DecodeRuneInString(text)
+		end := size
+		if class := wordClass(r); class != punctuation {
+			for end < len(text) {
+				next, size := utf8.DecodeRuneInString(text[end:])
+				if wordClass(next) != class {
+					break
+				}
+				end += size
+			}
+		}
+		words = append(words, text[:end])
+		text = text[end:]
+	}
+	return words
+}
+
+type class int
+
+const (
+	punctuation class = iota
+	word
+	space
+	newline
+)
+
+func wordClass(r rune) class {
+	switch {
+	case r == '\n':
+		return newline
+	case r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r):
+		return word
+	case unicode.IsSpace(r):
+		return space
+	default:
+		return punctuation
+	}
+}
+
+// WordDiff returns an edit script turning the text a into b word by word, with [Words] as the tokens
+// and [Myers] to match them. Neighbouring words with the same op share an entry, so each entry's Text is
+// a run of words rather than a line.
+func WordDiff(a, b string) []Line {
+	var script []Line
+	for _, token := range Myers(Words(a), Words(b)) {
+		if n := len(script); n > 0 && script[n-1].Op == token.Op {
+			script[n-1].Text += token.Text
+			continue
+		}
+		script = append(script, token)
+	}
+	return script
+}
+
+// WordString renders the hunk the way git diff --word-diff=plain does: lines carry no +/- prefix,
+// and each run of changed lines is shown once with the words it removed in [-...-] and the words it added
+// in {+...+}.
+func (h Hunk) WordString() string {
+	var b strings.Builder
+	b.WriteString(h.Header() + "\n")
+	for i := 0; i < len(h.Lines); {
+		if h.Lines[i].Op == Equal {
+			b.WriteString(h.Lines[i].Text)
+			if !strings.HasSuffix(h.Lines[i].Text, "\n") {
+				b.WriteString("\n")
+			}
+			i++
+			continue
+		}
+
+		var old, new strings.Builder
+		for ; i < len(h.Lines) && h.Lines[i].Op != Equal; i++ {
+			if h.Lines[i].Op == Delete {
+				old.WriteString(h.Lines[i].Text)
+			} else {
+				new.WriteString(h.Lines[i].Text)
+			}
+		}
+
+		var run strings.Builder
+		for _, part := range WordDiff(old.String(), new.String()) {
+			switch part.Op {
+			case Delete:
+				markWords(&run, "[-", "-]", part.Text)
+			case Insert:
+				markWords(&run, "{+", "+}", part.Text)
+			default:
+				run.WriteString(part.Text)
+			}
+		}
+		b.WriteString(run.String())
+		if !strings.HasSuffix(run.String(), "\n") {
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// markWords writes text between open and close, closing and reopening the marks around each newline so
+// that every output line reads on its own.
+func markWords(b *strings.Builder, open, close, text string) {
+	for i, line := range strings.Split(text, "\n") {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		if line != "" {
+			b.WriteString(open + line + close)
+		}
+	}
+}
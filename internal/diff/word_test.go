@@ -0,0 +1,39 @@
+package diff
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestWords(t *testing.T) {
+	got := Words("if (x_1 != y)  {\n\tgo();\n")
+	want := []string{"if", " ", "(", "x_1", " ", "!", "=", " ", "y", ")", "  ", "{", "\n", "\t", "go", "(", ")", ";", "\n"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Words split into %q, want %q", got, want)
+	}
+	if got := Words("héllo wörld"); !slices.Equal(got, []string{"héllo", " ", "wörld"}) {
+		t.Errorf("Words split non-ASCII text into %q", got)
+	}
+}
+
+func TestWordDiff(t *testing.T) {
+	got := WordDiff("return a + b\n", "return a * b\n")
+	want := []Line{{Equal, "return a "}, {Delete, "+"}, {Insert, "*"}, {Equal, " b\n"}}
+	if !slices.Equal(got, want) {
+		t.Errorf("WordDiff gave %q, want %q", got, want)
+	}
+}
+
+func TestHunkWordString(t *testing.T) {
+	old := "a\nfoo bar\nc\n"
+	new := "a\nfoo baz\nnew line\nc\n"
+	var got strings.Builder
+	for _, hunk := range Diff([]byte(old), []byte(new), DefaultContext) {
+		got.WriteString(hunk.WordString())
+	}
+	want := "@@ -1,3 +1,4 @@\na\nfoo [-bar-]{+baz+}\n{+new line+}\nc\n"
+	if got.String() != want {
+		t.Errorf("unexpected word diff:\n%s\nwant:\n%s", got.String(), want)
+	}
+}
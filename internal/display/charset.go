@@ -0,0 +1,71 @@
+package display
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+)
+
+var ErrUnknownSetting = errors.New("unknown display setting")
+
+// Charset holds the glyphs used to draw commit graphs and the shell prompt.
+type Charset struct {
+	Commit string // Marks a commit
+	Line   string // Connects a commit to the next one down
+	Ahead  string // Precedes how many commits a branch has that its upstream doesn't
+	Behind string // Precedes how many commits a branch's upstream has that it doesn't
+}
+
+var (
+	Unicode = Charset{Commit: "●", Line: "│", Ahead: "↑", Behind: "↓"}
+	ASCII   = Charset{Commit: "*", Line: "|", Ahead: "+", Behind: "-"}
+)
+
+// ParseCharset returns the charset for a plain.charset value: unicode, ascii, or auto (or empty), which
+// picks one with [DetectCharset].
+func ParseCharset(value string) (Charset, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "auto":
+		return DetectCharset(), nil
+	case "unicode", "utf-8", "utf8":
+		return Unicode, nil
+	case "ascii":
+		return ASCII, nil
+	default:
+		return Charset{}, fmt.Errorf("%w: charset %q, expected auto, unicode, or ascii", ErrUnknownSetting, value)
+	}
+}
+
+// DetectCharset returns [Unicode] if the terminal is likely to render it, and [ASCII] otherwise.
+func DetectCharset() Charset {
+	if supportsUnicode(runtime.GOOS, os.Getenv) {
+		return Unicode
+	}
+	return ASCII
+}
+
+// supportsUnicode guesses from the environment whether output will be shown with a font and encoding
+// that handle box drawing characters.
+//
+// The legacy Windows console mangles them, so Windows only gets Unicode in terminals known to cope.
+// Elsewhere the locale decides, which also covers CI logs and cron jobs that run under the C locale.
+func supportsUnicode(goos string, getenv func(string) string) bool {
+	if getenv("TERM") == "dumb" {
+		return false
+	}
+
+	if goos == "windows" {
+		return getenv("WT_SESSION") != "" || getenv("TERM_PROGRAM") != "" || getenv("ConEmuANSI") == "ON"
+	}
+
+	// The first of these that is set names the locale used for character encoding.
+	for _, name := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if locale := getenv(name); locale != "" {
+			locale = strings.ToLower(locale)
+			return strings.Contains(locale, "utf-8") || strings.Contains(locale, "utf8")
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package display
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DateFormat renders commit and comment times.
+type DateFormat struct {
+	layout   string // A time.Format layout, unused when relative
+	relative bool   // Times are shown as how long ago they were
+	local    bool   // Times are converted to the local time zone before formatting
+}
+
+// Date formats by plain.dateFormat name, covering the common regional orders alongside git's log.date names.
+var dateLayouts = map[string]string{
+	"iso":        "2006-01-02 15:04",
+	"iso-strict": time.RFC3339,
+	"rfc":        time.RFC1123Z,
+	"short":      "2006-01-02",
+	"us":         "01/02/2006 3:04 PM",
+	"eu":         "02.01.2006 15:04",
+	"uk":         "02/01/2006 15:04",
+}
+
+// DefaultDateFormat shows times like 2025-01-31 14:05, in the time zone they were recorded in.
+var DefaultDateFormat = DateFormat{layout: dateLayouts["iso"]}
+
+// ParseDateFormat returns the format for a plain.dateFormat value: iso (the default), iso-strict, rfc,
+// short, us, eu, uk, or relative. Adding a -local suffix, as in iso-local, shows times in the local time
+// zone rather than the one they were recorded in.
+func ParseDateFormat(value string) (DateFormat, error) {
+	name := strings.ToLower(strings.TrimSpace(value))
+	if name == "" {
+		return DefaultDateFormat, nil
+	}
+	if name == "relative" {
+		return DateFormat{relative: true}, nil
+	}
+
+	var format DateFormat
+	name, format.local = strings.CutSuffix(name, "-local")
+	layout, ok := dateLayouts[name]
+	if !ok {
+		return DateFormat{}, fmt.Errorf("%w: date format %q, expected iso, iso-strict, rfc, short, us, eu, uk, or relative", ErrUnknownSetting, value)
+	}
+	format.layout = layout
+	return format, nil
+}
+
+// Format renders t, where now is the current time that relative formats measure from.
+func (f DateFormat) Format(t, now time.Time) string {
+	if f.relative {
+		return Age(now.Sub(t))
+	}
+	if f.local {
+		t = t.Local()
+	}
+	return t.Format(f.layout)
+}
+
+// Age renders a duration the way people talk about it, e.g. "3 days ago".
+func Age(age time.Duration) string {
+	plural := func(n int, unit string) string {
+		if n == 1 {
+			return fmt.Sprintf("1 %s ago", unit)
+		}
+		return fmt.Sprintf("%d %ss ago", n, unit)
+	}
+
+	switch {
+	case age < time.Minute:
+		return "just now"
+	case age < time.Hour:
+		return plural(int(age.Minutes()), "minute")
+	case age < 24*time.Hour:
+		return plural(int(age.Hours()), "hour")
+	default:
+		return plural(int(age.Hours()/24), "day")
+	}
+}
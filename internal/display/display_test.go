@@ -0,0 +1,93 @@
+package display
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSupportsUnicode(t *testing.T) {
+	cases := []struct {
+		goos string
+		env  map[string]string
+		want bool
+	}{
+		{"linux", map[string]string{"LANG": "en_US.UTF-8"}, true},
+		{"linux", map[string]string{"LANG": "en_US.utf8", "TERM": "dumb"}, false},
+		// LC_ALL overrides LANG, as in the C library.
+		{"linux", map[string]string{"LC_ALL": "C", "LANG": "en_US.UTF-8"}, false},
+		{"darwin", map[string]string{"LC_CTYPE": "UTF-8"}, true},
+		{"linux", map[string]string{}, false},
+		{"windows", map[string]string{"LANG": "en_US.UTF-8"}, false},
+		{"windows", map[string]string{"WT_SESSION": "1f2e"}, true},
+	}
+	for _, c := range cases {
+		if got := supportsUnicode(c.goos, func(name string) string { return c.env[name] }); got != c.want {
+			t.Errorf("supportsUnicode(%s, %v) = %v, want %v", c.goos, c.env, got, c.want)
+		}
+	}
+}
+
+func TestParseCharset(t *testing.T) {
+	if got, _ := ParseCharset("ASCII"); got != ASCII {
+		t.Errorf("expected ascii, got %+v", got)
+	}
+	if got, _ := ParseCharset("unicode"); got != Unicode {
+		t.Errorf("expected unicode, got %+v", got)
+	}
+	if _, err := ParseCharset("emoji"); err == nil {
+		t.Error("expected an unknown charset to be rejected")
+	}
+}
+
+func TestDateFormat(t *testing.T) {
+	at := time.Date(2025, 3, 7, 14, 5, 0, 0, time.FixedZone("", 2*60*60))
+	now := at.Add(50 * time.Hour)
+
+	cases := map[string]string{
+		"":           "2025-03-07 14:05",
+		"short":      "2025-03-07",
+		"iso-strict": "2025-03-07T14:05:00+02:00",
+		"us":         "03/07/2025 2:05 PM",
+		"eu":         "07.03.2025 14:05",
+		"relative":   "2 days ago",
+	}
+	for name, want := range cases {
+		format, err := ParseDateFormat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := format.Format(at, now); got != want {
+			t.Errorf("%q: got %q, want %q", name, got, want)
+		}
+	}
+
+	local, err := ParseDateFormat("iso-local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := local.Format(at, now), at.Local().Format("2006-01-02 15:04"); got != want {
+		t.Errorf("iso-local: got %q, want %q", got, want)
+	}
+
+	if _, err := ParseDateFormat("klingon"); err == nil {
+		t.Error("expected an unknown date format to be rejected")
+	}
+}
+
+func TestQuotePath(t *testing.T) {
+	cases := map[string]string{
+		"cmd/main.go":     "cmd/main.go",
+		"docs/read me.md": "docs/read me.md",
+		"naïve.txt":       "naïve.txt",
+		"tab\there":       `"tab\there"`,
+		"new\nline":       `"new\nline"`,
+		`say "hi".txt`:    `"say \"hi\".txt"`,
+		" leading":        `" leading"`,
+		"":                `""`,
+	}
+	for p, want := range cases {
+		if got := QuotePath(p); got != want {
+			t.Errorf("QuotePath(%q) = %s, want %s", p, got, want)
+		}
+	}
+}
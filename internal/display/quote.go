@@ -0,0 +1,21 @@
+package display
+
+import (
+	"strconv"
+	"unicode"
+)
+
+// QuotePath returns p as it should appear in line-oriented output: unchanged if it is plain, or double
+// quoted with Go escapes if it holds quotes, backslashes, spaces at either end, or unprintable characters
+// that would otherwise break a line or field apart.
+func QuotePath(p string) string {
+	if p == "" {
+		return `""`
+	}
+	for i, r := range p {
+		if r == '"' || r == '\\' || !unicode.IsPrint(r) || (i == 0 || i == len(p)-1) && r == ' ' {
+			return strconv.Quote(p)
+		}
+	}
+	return p
+}
@@ -0,0 +1,149 @@
+// Package doctor looks for commits in a feature's history that are probably mistakes, such as ones made
+// with the wrong clock or the wrong email, and explains how to fix them.
+package doctor
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sim-deos/plain/internal/diff"
+	"github.com/sim-deos/plain/internal/git"
+)
+
+const (
+	DefaultMaxFiles  = 100
+	DefaultMaxBytes  = 5 << 20
+	DefaultClockSkew = 10 * time.Minute
+)
+
+// Kind says what is suspicious about a commit.
+type Kind string
+
+const (
+	FutureTimestamp  Kind = "future-timestamp"
+	IdentityMismatch Kind = "identity-mismatch"
+	LargeCommit      Kind = "large-commit"
+	FeatureMerge     Kind = "feature-merge"
+	OctopusMerge     Kind = "octopus-merge"
+)
+
+// Finding is one suspicious commit.
+type Finding struct {
+	Kind    Kind
+	Commit  git.Commit
+	Problem string // What is wrong, and why it matters
+	Fix     string // What to do about it
+}
+
+// Options tunes what [Check] considers suspicious. Zero values mean the defaults.
+type Options struct {
+	Now       time.Time     // The current time, to compare commit dates with
+	ClockSkew time.Duration // How far in the future a date may be before it's flagged, as clocks drift
+	Email     string        // The configured user.email, or "" to skip comparing identities
+	Base      string        // The branch the feature started from, for suggested fixes
+	MaxFiles  int           // How many files a commit may change before it's flagged as large
+	MaxBytes  int64         // How much new content a commit may add before it's flagged as large
+}
+
+// Check looks through commits, the history of a feature, for commits dated in the future, commits made
+// with an email other than opts.Email, commits that change many files or add a lot of data, and merges,
+// which a feature shouldn't need and which are worse with more than two parents. Findings are in the
+// order of commits, and a commit may have several.
+func Check(repo *git.Repository, commits []git.Commit, opts Options) ([]Finding, error) {
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+	if opts.ClockSkew == 0 {
+		opts.ClockSkew = DefaultClockSkew
+	}
+	if opts.MaxFiles == 0 {
+		opts.MaxFiles = DefaultMaxFiles
+	}
+	if opts.MaxBytes == 0 {
+		opts.MaxBytes = DefaultMaxBytes
+	}
+	if opts.Base == "" {
+		opts.Base = "main"
+	}
+
+	var findings []Finding
+	for _, commit := range commits {
+		add := func(kind Kind, problem, fix string) {
+			findings = append(findings, Finding{Kind: kind, Commit: commit, Problem: problem, Fix: fix})
+		}
+
+		if date := latest(commit.Author.Time, commit.Committer.Time); date.Sub(opts.Now) > opts.ClockSkew {
+			add(FutureTimestamp,
+				fmt.Sprintf("dated %s, %s in the future, so the clock was probably wrong when it was made; history sorted by date will show it in the wrong place", date.Format(time.RFC3339), date.Sub(opts.Now).Round(time.Minute)),
+				fmt.Sprintf("check the system clock, then redate the feature's commits with git rebase --reset-author-date %s", opts.Base))
+		}
+
+		if opts.Email != "" && !strings.EqualFold(commit.Committer.Email, opts.Email) {
+			add(IdentityMismatch,
+				fmt.Sprintf("committed as %s <%s>, but git config user.email is %s, so it won't be linked to your account", commit.Committer.Name, commit.Committer.Email, opts.Email),
+				fmt.Sprintf("if that address is wrong, run plain rewrite --fix-email %s=%s", commit.Committer.Email, opts.Email))
+		}
+
+		switch {
+		case len(commit.Parents) > 2:
+			add(OctopusMerge,
+				fmt.Sprintf("merges %d branches at once; many tools handle such merges poorly, and they can't be undone by reverting one parent", len(commit.Parents)),
+				fmt.Sprintf("merge the branches one at a time, or replay the feature without the merge with plain onto %s", opts.Base))
+			continue
+		case len(commit.Parents) == 2:
+			add(FeatureMerge,
+				"merges another branch into the feature, which makes its history harder to review and to move",
+				fmt.Sprintf("replay the feature's own commits on top of %s with plain onto %s instead of merging", opts.Base, opts.Base))
+			continue
+		}
+
+		files, size, err := commitSize(repo, commit)
+		if err != nil {
+			return nil, err
+		}
+		if files > opts.MaxFiles || size > opts.MaxBytes {
+			add(LargeCommit,
+				fmt.Sprintf("changes %d file(s) and adds about %s, which is hard to review and often means generated files or binaries were committed by mistake", files, diff.Size(size)),
+				"split it into smaller commits, or remove files committed by mistake with plain rewrite --remove-path")
+		}
+	}
+	return findings, nil
+}
+
+// commitSize returns how many files commit changes from its first parent, and the size of their new
+// content.
+func commitSize(repo *git.Repository, commit git.Commit) (int, int64, error) {
+	parentTree := ""
+	if len(commit.Parents) > 0 {
+		parent, err := repo.ReadCommit(commit.Parents[0])
+		if err != nil {
+			return 0, 0, err
+		}
+		parentTree = parent.Tree
+	}
+	changes, err := repo.DiffTrees(parentTree, commit.Tree)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var size int64
+	for _, change := range changes {
+		if change.Status == git.StatusDeleted || change.To.Mode == git.ModeSubmodule {
+			continue
+		}
+		data, err := repo.ReadBlob(change.To.Hash)
+		if err != nil {
+			return 0, 0, err
+		}
+		size += int64(len(data))
+	}
+	return len(changes), size, nil
+}
+
+func latest(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
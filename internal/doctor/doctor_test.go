@@ -0,0 +1,94 @@
+package doctor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/sim-deos/plain/internal/git"
+)
+
+func TestCheck(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"objects", "refs/heads"} {
+		if err := os.MkdirAll(filepath.Join(root, ".git", dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Chdir(root)
+	repo, err := git.OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	ann := git.Signature{Name: "Ann", Email: "ann@example.com", Time: now}
+	commit := func(message string, who git.Signature, files int, parents ...string) git.Commit {
+		t.Helper()
+		b := git.NewTreeBuilder(repo.Encoder())
+		for i := range files {
+			blob, err := repo.Encoder().Encode(git.BlobObject, []byte(fmt.Sprintf("file %d\n", i)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := b.Add(fmt.Sprintf("f%d.txt", i), git.ModeFile, blob); err != nil {
+				t.Fatal(err)
+			}
+		}
+		tree, err := b.Write()
+		if err != nil {
+			t.Fatal(err)
+		}
+		hash, err := repo.CreateCommit(tree, parents, who, who, message)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := repo.ReadCommit(hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	base := commit("base", ann, 1)
+	fine := commit("fine", ann, 2, base.Hash)
+	future := commit("future", git.Signature{Name: "Ann", Email: "ann@example.com", Time: now.Add(48 * time.Hour)}, 2, fine.Hash)
+	wrong := commit("wrong email", git.Signature{Name: "Ann", Email: "ann@laptop.local", Time: now}, 2, future.Hash)
+	large := commit("large", ann, 6, wrong.Hash)
+	merge := commit("merge main", ann, 6, large.Hash, base.Hash)
+	octopus := commit("octopus", ann, 6, merge.Hash, base.Hash, fine.Hash)
+
+	findings, err := Check(repo, []git.Commit{octopus, merge, large, wrong, future, fine}, Options{
+		Now:      now,
+		Email:    "ANN@example.com",
+		MaxFiles: 3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range findings {
+		if f.Problem == "" || f.Fix == "" {
+			t.Errorf("%s finding has no explanation or fix: %+v", f.Kind, f)
+		}
+		got = append(got, f.Commit.Message+": "+string(f.Kind))
+	}
+	want := []string{
+		"octopus: " + string(OctopusMerge),
+		"merge main: " + string(FeatureMerge),
+		"large: " + string(LargeCommit),
+		"wrong email: " + string(IdentityMismatch),
+		"future: " + string(FutureTimestamp),
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("findings = %q, want %q", got, want)
+	}
+
+	findings, err = Check(repo, []git.Commit{large}, Options{Now: now, MaxBytes: 10})
+	if err != nil || len(findings) != 1 || findings[0].Kind != LargeCommit {
+		t.Errorf("a commit adding more than MaxBytes gave %+v, %v", findings, err)
+	}
+}
@@ -0,0 +1,92 @@
+package export
+
+import (
+	"github.com/sim-deos/plain/internal/diff"
+	"github.com/sim-deos/plain/internal/git"
+)
+
+// History is the part of a repository written by an export.
+type History struct {
+	Commits []git.Commit            // Every commit reachable from a ref, newest first
+	Refs    map[string]string       // Every ref mapped to the hash it points to
+	Changes map[string][]FileChange // The files each commit changed, by commit hash
+}
+
+// FileChange is one file a commit changed compared to its first parent, like a line of git log --numstat.
+type FileChange struct {
+	Path      string
+	Status    git.FileStatus
+	Additions int  // Lines added, unknown for binary files and submodules
+	Deletions int  // Lines deleted, unknown for binary files and submodules
+	Binary    bool // Line counts are unknown because the content isn't text
+}
+
+// Collect reads the history reachable from every ref in repo, with the files each commit changed.
+// Merges are compared against their first parent only, and commits whose parents were cut off by a
+// shallow clone have no changes recorded.
+func Collect(repo *git.Repository) (History, error) {
+	commits, err := repo.ReachableCommits()
+	if err != nil {
+		return History{}, err
+	}
+	refs, err := repo.Refs()
+	if err != nil {
+		return History{}, err
+	}
+
+	trees := make(map[string]string, len(commits))
+	for _, commit := range commits {
+		trees[commit.Hash] = commit.Tree
+	}
+
+	history := History{Commits: commits, Refs: refs, Changes: map[string][]FileChange{}}
+	for _, commit := range commits {
+		var parentTree string
+		if len(commit.Parents) > 0 {
+			var ok bool
+			if parentTree, ok = trees[commit.Parents[0]]; !ok {
+				continue
+			}
+		}
+
+		changes, err := repo.DiffTrees(parentTree, commit.Tree)
+		if err != nil {
+			return History{}, err
+		}
+		for _, change := range changes {
+			fileChange, err := stat(repo, change)
+			if err != nil {
+				return History{}, err
+			}
+			history.Changes[commit.Hash] = append(history.Changes[commit.Hash], fileChange)
+		}
+	}
+	return history, nil
+}
+
+// stat counts the lines change added and deleted.
+func stat(repo *git.Repository, change git.Change) (FileChange, error) {
+	fileChange := FileChange{Path: change.Path, Status: change.Status}
+	if change.From.Mode == git.ModeSubmodule || change.To.Mode == git.ModeSubmodule {
+		fileChange.Binary = true
+		return fileChange, nil
+	}
+
+	var contents [2][]byte
+	for i, entry := range []git.TreeEntry{change.From, change.To} {
+		if entry.Hash == "" {
+			continue
+		}
+		data, err := repo.ReadBlob(entry.Hash)
+		if err != nil {
+			return FileChange{}, err
+		}
+		contents[i] = data
+	}
+	if diff.IsBinary(contents[0]) || diff.IsBinary(contents[1]) {
+		fileChange.Binary = true
+		return fileChange, nil
+	}
+	fileChange.Additions, fileChange.Deletions = diff.Stat(contents[0], contents[1])
+	return fileChange, nil
+}
@@ -0,0 +1,149 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+var ErrNoSQLite = errors.New("sqlite3 is not installed")
+
+// schema is the relational layout of an export. Times are Unix seconds, with the signer's time zone
+// kept separately as minutes east of UTC.
+const schema = `CREATE TABLE commits (
+  hash TEXT PRIMARY KEY,
+  tree TEXT NOT NULL,
+  author_name TEXT NOT NULL,
+  author_email TEXT NOT NULL,
+  author_time INTEGER NOT NULL,
+  author_tz INTEGER NOT NULL,
+  committer_name TEXT NOT NULL,
+  committer_email TEXT NOT NULL,
+  committer_time INTEGER NOT NULL,
+  committer_tz INTEGER NOT NULL,
+  subject TEXT NOT NULL,
+  message TEXT NOT NULL
+);
+CREATE TABLE parents (
+  commit_hash TEXT NOT NULL REFERENCES commits(hash),
+  parent_hash TEXT NOT NULL,
+  position INTEGER NOT NULL,
+  PRIMARY KEY (commit_hash, position)
+);
+CREATE TABLE refs (
+  name TEXT PRIMARY KEY,
+  kind TEXT NOT NULL,
+  hash TEXT NOT NULL
+);
+CREATE TABLE file_changes (
+  commit_hash TEXT NOT NULL REFERENCES commits(hash),
+  path TEXT NOT NULL,
+  status TEXT NOT NULL,
+  additions INTEGER,
+  deletions INTEGER,
+  PRIMARY KEY (commit_hash, path)
+);
+CREATE INDEX parents_by_parent ON parents(parent_hash);
+CREATE INDEX file_changes_by_path ON file_changes(path);
+`
+
+// WriteSQL writes history as a SQL script that creates and fills the export's tables in one transaction.
+// Line counts of binary files are NULL.
+func WriteSQL(w io.Writer, history History) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("BEGIN TRANSACTION;\n")
+	bw.WriteString(schema)
+
+	for _, commit := range history.Commits {
+		subject, _, _ := strings.Cut(commit.Message, "\n")
+		fmt.Fprintf(bw, "INSERT INTO commits VALUES (%s, %s, %s, %s, %d, %d, %s, %s, %d, %d, %s, %s);\n",
+			quote(commit.Hash), quote(commit.Tree),
+			quote(commit.Author.Name), quote(commit.Author.Email), commit.Author.Time.Unix(), zoneMinutes(commit.Author.Time),
+			quote(commit.Committer.Name), quote(commit.Committer.Email), commit.Committer.Time.Unix(), zoneMinutes(commit.Committer.Time),
+			quote(subject), quote(commit.Message))
+		for i, parent := range commit.Parents {
+			fmt.Fprintf(bw, "INSERT INTO parents VALUES (%s, %s, %d);\n", quote(commit.Hash), quote(parent), i)
+		}
+		for _, change := range history.Changes[commit.Hash] {
+			additions, deletions := "NULL", "NULL"
+			if !change.Binary {
+				additions, deletions = fmt.Sprint(change.Additions), fmt.Sprint(change.Deletions)
+			}
+			fmt.Fprintf(bw, "INSERT INTO file_changes VALUES (%s, %s, %s, %s, %s);\n",
+				quote(commit.Hash), quote(change.Path), quote(change.Status.String()), additions, deletions)
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(history.Refs)) {
+		fmt.Fprintf(bw, "INSERT INTO refs VALUES (%s, %s, %s);\n", quote(name), quote(refKind(name)), quote(history.Refs[name]))
+	}
+
+	bw.WriteString("COMMIT;\n")
+	return bw.Flush()
+}
+
+// WriteSQLite writes history to a new SQLite database at path, replacing any file already there, by
+// feeding [WriteSQL]'s script to the sqlite3 command. If sqlite3 isn't installed, [ErrNoSQLite] is returned.
+func WriteSQLite(path string, history History) error {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		return ErrNoSQLite
+	}
+
+	// The database is built next to its destination and renamed into place, so a failed export
+	// never leaves a half written database behind.
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	tmp.Close()
+	os.Remove(tmp.Name())
+	defer os.Remove(tmp.Name())
+
+	pr, pw := io.Pipe()
+	go func() { pw.CloseWithError(WriteSQL(pw, history)) }()
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("sqlite3", "-bail", tmp.Name())
+	cmd.Stdin, cmd.Stderr = pr, &stderr
+	if err := cmd.Run(); err != nil {
+		pr.CloseWithError(err)
+		return fmt.Errorf("sqlite3 failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// quote returns s as a SQL string literal.
+func quote(s string) string {
+	// SQLite's shell cuts text off at a NUL byte, which has no business in commit metadata anyway.
+	s = strings.ReplaceAll(s, "\x00", "")
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// zoneMinutes returns the offset of t's time zone from UTC in minutes.
+func zoneMinutes(t time.Time) int {
+	_, offset := t.Zone()
+	return offset / 60
+}
+
+// refKind classifies a ref by its namespace.
+func refKind(name string) string {
+	switch {
+	case strings.HasPrefix(name, "refs/heads/"):
+		return "branch"
+	case strings.HasPrefix(name, "refs/tags/"):
+		return "tag"
+	case strings.HasPrefix(name, "refs/remotes/"):
+		return "remote"
+	default:
+		return "other"
+	}
+}
@@ -0,0 +1,48 @@
+package export
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sim-deos/plain/internal/git"
+)
+
+func TestWriteSQL(t *testing.T) {
+	when := time.Date(2024, 3, 1, 12, 0, 0, 0, time.FixedZone("", 2*60*60))
+	sig := git.Signature{Name: "Ann O'Neil", Email: "ann@example.com", Time: when}
+	history := History{
+		Commits: []git.Commit{
+			{Hash: "c2", Tree: "t2", Parents: []string{"c1", "c0"}, Author: sig, Committer: sig, Message: "Merge it\n\nIt's done.\n"},
+		},
+		Refs: map[string]string{"refs/tags/v1": "c2", "refs/heads/main": "c2"},
+		Changes: map[string][]FileChange{
+			"c2": {
+				{Path: "main.go", Status: git.StatusModified, Additions: 3, Deletions: 1},
+				{Path: "logo.png", Status: git.StatusAdded, Binary: true},
+			},
+		},
+	}
+
+	var b strings.Builder
+	if err := WriteSQL(&b, history); err != nil {
+		t.Fatal(err)
+	}
+	script := b.String()
+
+	for _, want := range []string{
+		"INSERT INTO commits VALUES ('c2', 't2', 'Ann O''Neil', 'ann@example.com', 1709287200, 120, " +
+			"'Ann O''Neil', 'ann@example.com', 1709287200, 120, 'Merge it', 'Merge it\n\nIt''s done.\n');\n",
+		"INSERT INTO parents VALUES ('c2', 'c1', 0);\nINSERT INTO parents VALUES ('c2', 'c0', 1);\n",
+		"INSERT INTO file_changes VALUES ('c2', 'main.go', 'modified', 3, 1);\n",
+		"INSERT INTO file_changes VALUES ('c2', 'logo.png', 'added', NULL, NULL);\n",
+		"INSERT INTO refs VALUES ('refs/heads/main', 'branch', 'c2');\nINSERT INTO refs VALUES ('refs/tags/v1', 'tag', 'c2');\n",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("script is missing %q", want)
+		}
+	}
+	if !strings.HasPrefix(script, "BEGIN TRANSACTION;\n") || !strings.HasSuffix(script, "COMMIT;\n") {
+		t.Error("script isn't a single transaction")
+	}
+}
@@ -0,0 +1,151 @@
+// Package focus narrows the features plain shows in a shared repository down to the user's own.
+package focus
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+
+	"github.com/sim-deos/plain/internal/git"
+)
+
+// Key is the git config key that sets the focus. It may be given more than once, and a feature is in
+// focus when it matches any of the values.
+const Key = "plain.focus"
+
+// Mine is the plain.focus value for the features whose last commit the user, as git config user.email
+// names them, authored.
+const Mine = "mine"
+
+var ErrNoEmail = errors.New("plain.focus is mine, but user.email isn't set")
+
+// Focus is the set of features the user wants to see. A nil *Focus includes every feature.
+type Focus struct {
+	emails   []string // Authors whose features are in focus, lower cased
+	prefixes []string // Branch name prefixes, like ann/, whose features are in focus
+	always   string   // A branch that is in focus regardless, the checked out one
+}
+
+// Parse builds the focus from the plain.focus values: mine, an author's email address, or a branch name
+// prefix. email is the user's own address, needed only for mine, and current is a branch always in focus.
+// Without values it returns nil, which includes everything.
+func Parse(values []string, email, current string) (*Focus, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	f := &Focus{always: current}
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		switch {
+		case value == "":
+		case value == Mine:
+			if email == "" {
+				return nil, ErrNoEmail
+			}
+			f.emails = append(f.emails, strings.ToLower(email))
+		case strings.Contains(value, "@"):
+			f.emails = append(f.emails, strings.ToLower(value))
+		default:
+			f.prefixes = append(f.prefixes, value)
+		}
+	}
+	if len(f.emails) == 0 && len(f.prefixes) == 0 {
+		return nil, nil
+	}
+	return f, nil
+}
+
+// Load reads the focus from repo's git config, always including current, the checked out branch.
+func Load(repo *git.Repository, current string) (*Focus, error) {
+	config, err := repo.Config()
+	if err != nil {
+		return nil, err
+	}
+	email, _ := config.Get("user.email")
+	return Parse(config.GetAll(Key), email, current)
+}
+
+// Includes reports whether the feature named branch, whose tip is the commit it points to, is in focus.
+func (f *Focus) Includes(branch string, tip git.Commit) bool {
+	if f == nil || branch == f.always {
+		return true
+	}
+	for _, prefix := range f.prefixes {
+		if strings.HasPrefix(branch, prefix) {
+			return true
+		}
+	}
+	email := strings.ToLower(tip.Author.Email)
+	for _, want := range f.emails {
+		if email == want {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterRefs narrows before and after, snapshots of where refs pointed like [git.Repository.RefsAt]
+// takes, down to the branches in focus, local and remote-tracking alike. Other refs, such as tags, are
+// left out. A branch is judged by the commit it points to in after, or in before if it was deleted.
+func (f *Focus) FilterRefs(repo *git.Repository, before, after map[string]string) (map[string]string, map[string]string, error) {
+	if f == nil {
+		return before, after, nil
+	}
+	keptBefore, keptAfter := map[string]string{}, map[string]string{}
+	for name := range joinKeys(before, after) {
+		branch, ok := branchName(name)
+		if !ok {
+			continue
+		}
+		hash, ok := after[name]
+		if !ok {
+			hash = before[name]
+		}
+		// Only a focus on authors needs to read the tip.
+		var tip git.Commit
+		if len(f.emails) > 0 {
+			var err error
+			tip, err = repo.ReadCommit(hash)
+			if errors.Is(err, fs.ErrNotExist) {
+				// Like git.Repository.Branches, a branch whose tip is missing is skipped.
+				continue
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		if !f.Includes(branch, tip) {
+			continue
+		}
+		if hash, ok := before[name]; ok {
+			keptBefore[name] = hash
+		}
+		if hash, ok := after[name]; ok {
+			keptAfter[name] = hash
+		}
+	}
+	return keptBefore, keptAfter, nil
+}
+
+// joinKeys returns the names in either of a and b.
+func joinKeys(a, b map[string]string) map[string]bool {
+	names := map[string]bool{}
+	for _, refs := range []map[string]string{a, b} {
+		for name := range refs {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// branchName returns the name of the branch ref is, with the remote left off a remote-tracking branch.
+func branchName(ref string) (string, bool) {
+	if name, ok := strings.CutPrefix(ref, "refs/heads/"); ok {
+		return name, true
+	}
+	if rest, ok := strings.CutPrefix(ref, "refs/remotes/"); ok {
+		_, name, ok := strings.Cut(rest, "/")
+		return name, ok && name != "HEAD"
+	}
+	return "", false
+}
@@ -0,0 +1,97 @@
+package focus
+
+import (
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sim-deos/plain/internal/git"
+)
+
+func TestIncludes(t *testing.T) {
+	if f, err := Parse(nil, "", "main"); f != nil || err != nil || !f.Includes("anything", git.Commit{}) {
+		t.Fatalf("Parse() of no values = %v, %v, want everything included", f, err)
+	}
+	if _, err := Parse([]string{Mine}, "", "main"); !errors.Is(err, ErrNoEmail) {
+		t.Fatalf("Parse(mine) without an email = %v, want %v", err, ErrNoEmail)
+	}
+
+	f, err := Parse([]string{Mine, "bob/", "Cat@Example.com"}, "Ann@example.com", "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	by := func(email string) git.Commit { return git.Commit{Author: git.Signature{Email: email}} }
+	cases := []struct {
+		branch string
+		tip    git.Commit
+		want   bool
+	}{
+		{"login", by("ann@EXAMPLE.com"), true},
+		{"bob/search", by("dan@example.com"), true},
+		{"billing", by("cat@example.com"), true},
+		{"billing", by("dan@example.com"), false},
+		{"main", by("dan@example.com"), true},
+	}
+	for _, c := range cases {
+		if got := f.Includes(c.branch, c.tip); got != c.want {
+			t.Errorf("Includes(%q, %s) = %v, want %v", c.branch, c.tip.Author.Email, got, c.want)
+		}
+	}
+}
+
+func TestFilterRefs(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"objects", "refs/heads"} {
+		if err := os.MkdirAll(filepath.Join(root, ".git", dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, ".git", "HEAD"), []byte("ref: refs/heads/main\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(root)
+	repo, err := git.OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, err := git.NewTreeBuilder(repo.Encoder()).Write()
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit := func(email string) string {
+		t.Helper()
+		who := git.Signature{Name: "Someone", Email: email, Time: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}
+		hash, err := repo.CreateCommit(tree, nil, who, who, email)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hash
+	}
+	ann, dan := commit("ann@example.com"), commit("dan@example.com")
+
+	before := map[string]string{"refs/heads/old": ann, "refs/heads/theirs": dan}
+	after := map[string]string{
+		"refs/heads/theirs":       dan,
+		"refs/heads/login":        ann,
+		"refs/remotes/origin/ann": ann,
+		"refs/remotes/origin/dan": dan,
+		"refs/tags/v1":            ann,
+	}
+	f, err := Parse([]string{Mine}, "ann@example.com", "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keptBefore, keptAfter, err := f.FilterRefs(repo, before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"refs/heads/old": ann}; !maps.Equal(keptBefore, want) {
+		t.Errorf("FilterRefs() before = %v, want %v", keptBefore, want)
+	}
+	if want := map[string]string{"refs/heads/login": ann, "refs/remotes/origin/ann": ann}; !maps.Equal(keptAfter, want) {
+		t.Errorf("FilterRefs() after = %v, want %v", keptAfter, want)
+	}
+}
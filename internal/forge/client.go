@@ -0,0 +1,175 @@
+package forge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sim-deos/plain/internal/httpclient"
+)
+
+var (
+	ErrUnsupported   = errors.New("not supported by this forge")
+	ErrNoPullRequest = errors.New("no open pull request")
+)
+
+// MergeMethod is how a pull request's commits are brought into the base branch.
+type MergeMethod string
+
+const (
+	MergeCommit MergeMethod = "merge"  // Create a merge commit
+	Squash      MergeMethod = "squash" // Squash the feature into a single commit
+	Rebase      MergeMethod = "rebase" // Replay the feature's commits onto the base
+)
+
+// ParseMergeMethod parses a merge method from its name.
+func ParseMergeMethod(name string) (MergeMethod, error) {
+	switch method := MergeMethod(strings.ToLower(name)); method {
+	case MergeCommit, Squash, Rebase:
+		return method, nil
+	default:
+		return "", fmt.Errorf("unknown merge method %q, expected merge, squash, or rebase", name)
+	}
+}
+
+// PullRequestOptions describes a pull request to open.
+type PullRequestOptions struct {
+	Head  string // The branch with the changes
+	Base  string // The branch to merge into
+	Title string
+	Body  string
+	Draft bool // Open the pull request as a draft that cannot be merged yet
+}
+
+// PullRequest is a pull request (or merge request) on a forge.
+type PullRequest struct {
+	Number int    // The number shown to users, e.g. #12
+	ID     string // The forge's internal identifier, needed by some APIs
+	URL    string // Where the pull request can be viewed
+	Draft  bool
+}
+
+// ReviewComment is a single comment within a review thread.
+type ReviewComment struct {
+	Author    string
+	Body      string
+	CreatedAt time.Time
+}
+
+// ReviewThread is a conversation attached to a line of a pull request's diff.
+type ReviewThread struct {
+	ID       string // The forge's identifier for the thread, used to reply
+	Path     string // The file the thread is on, relative to the repository root
+	Line     int    // The line in the file's new version, or 0 if the line no longer exists
+	Resolved bool
+	Outdated bool // Whether the code the thread was left on has changed since
+	Comments []ReviewComment
+}
+
+// CheckStatus summarizes where a CI check stands.
+type CheckStatus int
+
+const (
+	CheckPending CheckStatus = iota + 1 // Queued or still running
+	CheckPassed                         // Finished successfully
+	CheckFailed                         // Finished with a failure, error, timeout, or cancellation
+	CheckSkipped                        // Skipped or finished neutral, neither blocking nor passing
+)
+
+var checkStatusName = map[CheckStatus]string{
+	CheckPending: "pending",
+	CheckPassed:  "passed",
+	CheckFailed:  "failed",
+	CheckSkipped: "skipped",
+}
+
+func (s CheckStatus) String() string {
+	return checkStatusName[s]
+}
+
+// Check is a single CI check or commit status reported on a commit.
+type Check struct {
+	ID         string // The forge's identifier for the check
+	Name       string
+	Status     CheckStatus
+	URL        string // Where the check's details and logs can be viewed
+	Rerunnable bool   // Whether the check can be re-triggered through the API
+}
+
+// ReleaseOptions describes a release to publish for an existing tag.
+type ReleaseOptions struct {
+	Tag        string // The tag the release is for, which must already be pushed
+	Name       string
+	Body       string // The release notes, in markdown
+	Prerelease bool
+}
+
+// Release is a published release on a forge.
+type Release struct {
+	ID        string
+	URL       string // Where the release can be viewed
+	uploadURL string // Where assets are uploaded, for forges with a separate upload endpoint
+}
+
+// BranchProtection is what a forge requires before changes land on a branch.
+type BranchProtection struct {
+	Protected        bool     // Whether the branch has any protection rules
+	PullRequestsOnly bool     // Changes must arrive through pull requests rather than direct pushes
+	RequiredReviews  int      // How many approving reviews a pull request needs
+	RequiredChecks   []string // The checks that must pass, by name
+	LinearHistory    bool     // Merge commits are rejected
+}
+
+// Client talks to a forge's API on behalf of one repository.
+type Client interface {
+	// CreatePullRequest opens a pull request.
+	CreatePullRequest(ctx context.Context, opts PullRequestOptions) (PullRequest, error)
+
+	// EnableAutoMerge has the forge merge pr with method once its required checks and reviews pass.
+	EnableAutoMerge(ctx context.Context, pr PullRequest, method MergeMethod) error
+
+	// FindPullRequest returns the open pull request for the head branch, or [ErrNoPullRequest].
+	FindPullRequest(ctx context.Context, head string) (PullRequest, error)
+
+	// ReviewThreads returns every review thread on pr, resolved or not.
+	ReviewThreads(ctx context.Context, pr PullRequest) ([]ReviewThread, error)
+
+	// ReplyToThread adds a comment to a review thread.
+	ReplyToThread(ctx context.Context, thread ReviewThread, body string) error
+
+	// Checks returns the CI checks reported for the commit with the given hash.
+	Checks(ctx context.Context, commit string) ([]Check, error)
+
+	// RerunCheck re-triggers a finished check.
+	RerunCheck(ctx context.Context, check Check) error
+
+	// BranchProtection returns the rules the forge enforces on branch. An unprotected branch, or one
+	// that doesn't exist on the forge, has none.
+	BranchProtection(ctx context.Context, branch string) (BranchProtection, error)
+
+	// CreateRelease publishes a release for a tag.
+	CreateRelease(ctx context.Context, opts ReleaseOptions) (Release, error)
+
+	// UploadReleaseAsset attaches a file named name, holding size bytes read from content, to release.
+	UploadReleaseAsset(ctx context.Context, release Release, name string, content io.Reader, size int64) error
+}
+
+// NewClient returns a client for repo on a forge of the given kind, authenticating with token. Requests
+// are sent with httpClient, or a retrying client from [httpclient.New] if it is nil.
+func NewClient(repo Repo, kind Kind, token string, httpClient *http.Client) (Client, error) {
+	if httpClient == nil {
+		httpClient = httpclient.New()
+	}
+	switch kind {
+	case GitHub:
+		return newGitHubClient(repo, token, httpClient), nil
+	case GitLab:
+		return newGitLabClient(repo, token, httpClient), nil
+	default:
+		return nil, fmt.Errorf("%w: %s pull requests", ErrUnsupported, kind)
+	}
+}
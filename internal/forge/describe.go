@@ -0,0 +1,230 @@
+package forge
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/sim-deos/plain/internal/git"
+)
+
+// templatePaths are where GitHub and GitLab look for pull and merge request templates, in priority order.
+var templatePaths = []string{
+	".github/pull_request_template.md",
+	"pull_request_template.md",
+	"docs/pull_request_template.md",
+	".gitlab/merge_request_templates/default.md",
+}
+
+var (
+	issueTrailer = regexp.MustCompile(`(?im)^(closes|fixes|resolves|refs?)[: ]+((?:[\w.-]+/[\w.-]+)?#\d+|[A-Z][A-Z0-9]+-\d+)`)
+	issueBranch  = regexp.MustCompile(`(?:^|/)(\d+|[A-Z][A-Z0-9]+-\d+)(?:-|$)`)
+	heading      = regexp.MustCompile(`^#{1,6}\s+(.+?)\s*#*$`)
+)
+
+// Description is a generated pull request title and body.
+type Description struct {
+	Title string
+	Body  string
+}
+
+// DescribeOptions holds everything a pull request description is generated from.
+type DescribeOptions struct {
+	Branch   string       // The feature branch, used for the title and to find a linked issue
+	Commits  []git.Commit // The feature's commits, newest first
+	Issue    string       // The linked issue (e.g. #42 or PROJ-42). Found from trailers and the branch name when empty.
+	Template string       // The repository's pull request template. A default layout is used when empty.
+}
+
+// Describe generates a pull request description from the feature's commits.
+//
+// When a template is given, its sections are filled in where plain knows what belongs there: summary
+// and description sections get the feature's commit messages, change sections get a list of commits,
+// and issue sections get a reference, closing the issue unless the trailer it was found in only refers
+// to it. Every other section is left as the template wrote it. A
+// Change-Id trailer in the feature's commits is kept at the end of the body.
+func Describe(opts DescribeOptions) Description {
+	verb, issue := "Closes", opts.Issue
+	if issue == "" {
+		verb, issue = findIssue(opts.Branch, opts.Commits)
+	}
+	if issue != "" && issue[0] >= '0' && issue[0] <= '9' {
+		issue = "#" + issue
+	}
+
+	desc := Description{Title: title(opts.Branch, opts.Commits)}
+	sections := map[string]string{
+		"summary": summary(opts.Commits),
+		"changes": changes(opts.Commits),
+	}
+	if issue != "" {
+		sections["issue"] = verb + " " + issue
+	}
+
+	if opts.Template == "" {
+		var b strings.Builder
+		b.WriteString("## Summary\n\n" + sections["summary"] + "\n")
+		if issue != "" {
+			b.WriteString("\n" + sections["issue"] + "\n")
+		}
+		desc.Body = b.String()
+	} else {
+		desc.Body = fillTemplate(opts.Template, sections)
+	}
+
+	// A squash merge made from the description then keeps the Change-Id the feature's change started
+	// with, so Gerrit still recognizes it.
+	for _, commit := range slices.Backward(opts.Commits) {
+		if id, ok := ChangeID(commit.Message); ok {
+			desc.Body = strings.TrimRight(desc.Body, "\n") + "\n\nChange-Id: " + id + "\n"
+			break
+		}
+	}
+	return desc
+}
+
+// FindTemplate returns the pull request template in the work tree at root, or "" if it has none.
+// Template names are matched without regard to case, as the forges do.
+func FindTemplate(root string) (string, error) {
+	for _, rel := range templatePaths {
+		dir := filepath.Join(root, filepath.FromSlash(filepath.Dir(rel)))
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return "", err
+		}
+
+		name := filepath.Base(rel)
+		index := slices.IndexFunc(entries, func(e fs.DirEntry) bool { return strings.EqualFold(e.Name(), name) })
+		if index == -1 {
+			continue
+		}
+
+		content, err := os.ReadFile(filepath.Join(dir, entries[index].Name()))
+		if err != nil {
+			return "", err
+		}
+		return string(content), nil
+	}
+	return "", nil
+}
+
+// fillTemplate inserts generated content under the template headings it recognizes.
+func fillTemplate(template string, sections map[string]string) string {
+	lines := strings.Split(template, "\n")
+	var out []string
+	used := map[string]bool{}
+
+	for i := 0; i < len(lines); i++ {
+		out = append(out, lines[i])
+
+		match := heading.FindStringSubmatch(lines[i])
+		if match == nil {
+			continue
+		}
+		kind := sectionKind(match[1])
+		content, ok := sections[kind]
+		if !ok || used[kind] {
+			continue
+		}
+		used[kind] = true
+
+		// Keep the template's guidance comments, then add the content in place of the empty section body.
+		for i+1 < len(lines) && !heading.MatchString(lines[i+1]) {
+			next := strings.TrimSpace(lines[i+1])
+			if next != "" && !strings.HasPrefix(next, "<!--") {
+				break
+			}
+			out = append(out, lines[i+1])
+			i++
+		}
+		out = append(out, content, "")
+	}
+
+	body := strings.Join(out, "\n")
+	if issue, ok := sections["issue"]; ok && !used["issue"] {
+		body = strings.TrimRight(body, "\n") + "\n\n" + issue + "\n"
+	}
+	return body
+}
+
+func sectionKind(name string) string {
+	name = strings.ToLower(name)
+	switch {
+	case strings.Contains(name, "summary"), strings.Contains(name, "description"), strings.Contains(name, "what"):
+		return "summary"
+	case strings.Contains(name, "change"):
+		return "changes"
+	case strings.Contains(name, "issue"), strings.Contains(name, "ticket"), strings.Contains(name, "related"):
+		return "issue"
+	default:
+		return ""
+	}
+}
+
+func title(branch string, commits []git.Commit) string {
+	if len(commits) == 1 {
+		subject, _, _ := strings.Cut(commits[0].Message, "\n")
+		return subject
+	}
+
+	name := branch[strings.LastIndex(branch, "/")+1:]
+	if match := issueBranch.FindStringSubmatch(name); match != nil {
+		name = strings.TrimPrefix(strings.TrimPrefix(name, match[1]), "-")
+	}
+	name = strings.NewReplacer("-", " ", "_", " ").Replace(name)
+	if name == "" {
+		return branch
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+func summary(commits []git.Commit) string {
+	if len(commits) == 1 {
+		_, body, _ := strings.Cut(commits[0].Message, "\n")
+		if body = strings.TrimSpace(stripTrailers(body)); body != "" {
+			return body
+		}
+	}
+	return changes(commits)
+}
+
+func changes(commits []git.Commit) string {
+	var b strings.Builder
+	for i := len(commits) - 1; i >= 0; i-- {
+		subject, _, _ := strings.Cut(commits[i].Message, "\n")
+		fmt.Fprintf(&b, "- %s\n", subject)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func stripTrailers(body string) string {
+	return changeIDTrailer.ReplaceAllString(issueTrailer.ReplaceAllString(body, ""), "")
+}
+
+// FindIssue returns the issue a feature is linked to, from a closing trailer in one of its commits
+// (Fixes #12) or else from the branch name (12-fix-login), or "" if there is none.
+func FindIssue(branch string, commits []git.Commit) string {
+	_, issue := findIssue(branch, commits)
+	return issue
+}
+
+// findIssue is [FindIssue], also returning the verb to reference the issue with: the trailer's, as
+// written, so Refs #12 stays a reference rather than closing the issue, or Closes for a branch name.
+func findIssue(branch string, commits []git.Commit) (verb, issue string) {
+	for _, commit := range commits {
+		if match := issueTrailer.FindStringSubmatch(commit.Message); match != nil {
+			return match[1], match[2]
+		}
+	}
+	if match := issueBranch.FindStringSubmatch(branch); match != nil {
+		return "Closes", match[1]
+	}
+	return "", ""
+}
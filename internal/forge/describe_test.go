@@ -0,0 +1,64 @@
+package forge
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sim-deos/plain/internal/git"
+)
+
+var describeCommits = []git.Commit{
+	{Message: "Handle expired sessions\n\nFixes #12"},
+	{Message: "Add login form"},
+}
+
+func TestDescribeDefaultLayout(t *testing.T) {
+	desc := Describe(DescribeOptions{Branch: "feature/31-login-page", Commits: describeCommits})
+
+	if desc.Title != "Login page" {
+		t.Fatalf("unexpected title %q", desc.Title)
+	}
+
+	expected := "## Summary\n\n- Add login form\n- Handle expired sessions\n\nFixes #12\n"
+	if desc.Body != expected {
+		t.Fatalf("unexpected body:\n%s", desc.Body)
+	}
+}
+
+func TestDescribeKeepsReferenceVerb(t *testing.T) {
+	desc := Describe(DescribeOptions{Branch: "login", Commits: []git.Commit{{Message: "Add login form\n\nRefs #12"}}})
+	if !strings.HasSuffix(desc.Body, "\nRefs #12\n") {
+		t.Fatalf("unexpected body:\n%s", desc.Body)
+	}
+}
+
+func TestDescribeTemplate(t *testing.T) {
+	template := "## Description\n<!-- What does this change? -->\n\n## Testing\nDescribe how you tested.\n"
+	desc := Describe(DescribeOptions{
+		Branch:   "PROJ-7-single",
+		Commits:  []git.Commit{{Message: "Refactor parser\n\nSplit tokenizing from parsing."}},
+		Template: template,
+	})
+
+	if desc.Title != "Refactor parser" {
+		t.Fatalf("unexpected title %q", desc.Title)
+	}
+	for _, want := range []string{"<!-- What does this change? -->", "Split tokenizing from parsing.", "Describe how you tested.", "Closes PROJ-7"} {
+		if !strings.Contains(desc.Body, want) {
+			t.Errorf("body is missing %q:\n%s", want, desc.Body)
+		}
+	}
+}
+
+func TestDescribeKeepsChangeID(t *testing.T) {
+	first, second := "I"+strings.Repeat("1", 40), "I"+strings.Repeat("2", 40)
+	desc := Describe(DescribeOptions{Branch: "login", Commits: []git.Commit{
+		{Message: "Handle expired sessions\n\nChange-Id: " + second},
+		{Message: "Add login form\n\nChange-Id: " + first},
+	}})
+
+	expected := "## Summary\n\n- Add login form\n- Handle expired sessions\n\nChange-Id: " + first + "\n"
+	if desc.Body != expected {
+		t.Fatalf("unexpected body:\n%s", desc.Body)
+	}
+}
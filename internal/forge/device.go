@@ -0,0 +1,126 @@
+package forge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sim-deos/plain/internal/httpclient"
+)
+
+var (
+	ErrDeviceCodeExpired = errors.New("device code expired before it was authorized")
+	ErrAccessDenied      = errors.New("authorization was denied")
+)
+
+const deviceGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+// DeviceCode is the code a user enters on the forge's website to authorize plain.
+type DeviceCode struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationURI string `json:"verification_uri"`
+	ExpiresIn       int    `json:"expires_in"`
+	Interval        int    `json:"interval"`
+}
+
+// DeviceFlow performs the OAuth 2.0 device authorization grant (RFC 8628) against a forge host.
+//
+// The flow lets a terminal program obtain a token without handling the user's password: plain requests a
+// code, the user enters it in their browser, and plain polls until the forge hands over a token.
+type DeviceFlow struct {
+	Host     string   // The forge host, e.g. github.com
+	Kind     Kind     // The forge software the host runs
+	ClientID string   // The OAuth application's client ID
+	Scopes   []string // The scopes to request
+	HTTP     *http.Client
+}
+
+// Start requests a device code for the user to authorize.
+func (f *DeviceFlow) Start(ctx context.Context) (DeviceCode, error) {
+	form := url.Values{"client_id": {f.ClientID}, "scope": {strings.Join(f.Scopes, " ")}}
+
+	var code DeviceCode
+	if err := f.post(ctx, f.endpoint("device"), form, &code); err != nil {
+		return DeviceCode{}, fmt.Errorf("forge: failed to request device code: %w", err)
+	}
+	if code.Interval <= 0 {
+		code.Interval = 5
+	}
+	return code, nil
+}
+
+// Wait polls the forge until the user authorizes code, returning the access token.
+func (f *DeviceFlow) Wait(ctx context.Context, code DeviceCode) (string, error) {
+	form := url.Values{"client_id": {f.ClientID}, "device_code": {code.DeviceCode}, "grant_type": {deviceGrantType}}
+	interval := time.Duration(code.Interval) * time.Second
+
+	for {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(interval):
+		}
+
+		var result struct {
+			AccessToken string `json:"access_token"`
+			Error       string `json:"error"`
+		}
+		if err := f.post(ctx, f.endpoint("token"), form, &result); err != nil {
+			return "", fmt.Errorf("forge: failed to poll for token: %w", err)
+		}
+
+		switch result.Error {
+		case "":
+			return result.AccessToken, nil
+		case "authorization_pending":
+		case "slow_down":
+			interval += 5 * time.Second
+		case "expired_token":
+			return "", ErrDeviceCodeExpired
+		case "access_denied":
+			return "", ErrAccessDenied
+		default:
+			return "", fmt.Errorf("forge: authorization failed: %s", result.Error)
+		}
+	}
+}
+
+func (f *DeviceFlow) endpoint(name string) string {
+	paths := map[Kind]map[string]string{
+		GitHub: {"device": "/login/device/code", "token": "/login/oauth/access_token"},
+		GitLab: {"device": "/oauth/authorize_device", "token": "/oauth/token"},
+	}
+	return "https://" + f.Host + paths[f.Kind][name]
+}
+
+func (f *DeviceFlow) post(ctx context.Context, endpoint string, form url.Values, result any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	client := f.HTTP
+	if client == nil {
+		client = httpclient.New()
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Pending authorizations are reported as 400s with an error field, which the caller inspects.
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
@@ -0,0 +1,66 @@
+package forge
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrUnknownForge = errors.New("unknown forge")
+
+// Kind identifies which forge software a host runs.
+type Kind int
+
+const (
+	// GitHub identifies github.com and GitHub Enterprise Server hosts.
+	GitHub Kind = iota + 1
+
+	// GitLab identifies gitlab.com and self-hosted GitLab instances.
+	GitLab
+)
+
+var kindName = map[Kind]string{
+	GitHub: "github",
+	GitLab: "gitlab",
+}
+
+func (k Kind) String() string {
+	return kindName[k]
+}
+
+func (k Kind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+func (k *Kind) UnmarshalText(text []byte) error {
+	kind, err := ParseKind(string(text))
+	if err != nil {
+		return err
+	}
+	*k = kind
+	return nil
+}
+
+// ParseKind parses a forge kind from its name, as used in flags and config.
+func ParseKind(name string) (Kind, error) {
+	for kind, kindName := range kindName {
+		if strings.EqualFold(name, kindName) {
+			return kind, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %s", ErrUnknownForge, name)
+}
+
+// DetectKind guesses the forge a host runs from its name, which covers the public forges and
+// self-hosted instances named after their software (e.g. gitlab.example.com).
+func DetectKind(host string) (Kind, error) {
+	host = strings.ToLower(host)
+	switch {
+	case strings.Contains(host, "github"):
+		return GitHub, nil
+	case strings.Contains(host, "gitlab"):
+		return GitLab, nil
+	default:
+		return 0, fmt.Errorf("%w: cannot tell which forge %s runs", ErrUnknownForge, host)
+	}
+}
@@ -0,0 +1,88 @@
+package forge
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sim-deos/plain/internal/git"
+)
+
+var (
+	changeIDTrailer = regexp.MustCompile(`(?m)^Change-Id:\s*(I[0-9a-f]{40})\s*$`)
+	trailerLine     = regexp.MustCompile(`^[A-Za-z0-9-]+:\s`)
+)
+
+// gerritDomains are the public Gerrit services, which serve every host under them.
+var gerritDomains = []string{"googlesource.com", "gerrithub.io"}
+
+// IsGerritHost reports whether host runs Gerrit: when it is one of configured, the hosts the user named,
+// or belongs to one of the public Gerrit services, like android.googlesource.com. Hosts are compared
+// whole, so a name that merely mentions Gerrit doesn't count.
+func IsGerritHost(host string, configured []string) bool {
+	host = strings.ToLower(host)
+	for _, name := range configured {
+		if strings.EqualFold(host, name) {
+			return true
+		}
+	}
+	for _, domain := range gerritDomains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// ChangeID returns the Gerrit Change-Id trailer of message, which Gerrit uses to tell that a pushed
+// commit is a new version of an existing change, and whether it has one.
+func ChangeID(message string) (string, bool) {
+	paragraphs := strings.Split(strings.TrimRight(message, "\n"), "\n\n")
+	if len(paragraphs) < 2 {
+		return "", false
+	}
+	match := changeIDTrailer.FindStringSubmatch(paragraphs[len(paragraphs)-1])
+	if match == nil {
+		return "", false
+	}
+	return match[1], true
+}
+
+// NewChangeID returns a Change-Id for a commit with the given tree, parents, author, and message, made
+// the way Gerrit's commit-msg hook makes them: as a hash that is unique to the commit being created.
+func NewChangeID(tree string, parents []string, author git.Signature, message string) string {
+	var b strings.Builder
+	b.WriteString("tree " + tree + "\n")
+	for _, parent := range parents {
+		b.WriteString("parent " + parent + "\n")
+	}
+	fmt.Fprintf(&b, "author %s <%s> %d\n\n%s", author.Name, author.Email, author.Time.Unix(), message)
+	return "I" + git.HashObject(git.BlobObject, []byte(b.String()))
+}
+
+// WithChangeID returns message with a Change-Id trailer for id, placed at the start of its trailers as
+// Gerrit's commit-msg hook places it, unless message already has a Change-Id.
+func WithChangeID(message, id string) string {
+	if _, ok := ChangeID(message); ok {
+		return message
+	}
+	message = strings.TrimRight(message, "\n")
+	trailer := "Change-Id: " + id
+
+	paragraphs := strings.Split(message, "\n\n")
+	last := paragraphs[len(paragraphs)-1]
+	if len(paragraphs) < 2 || !isTrailerBlock(last) {
+		return message + "\n\n" + trailer
+	}
+	paragraphs[len(paragraphs)-1] = trailer + "\n" + last
+	return strings.Join(paragraphs, "\n\n")
+}
+
+func isTrailerBlock(paragraph string) bool {
+	for _, line := range strings.Split(paragraph, "\n") {
+		if !trailerLine.MatchString(line) {
+			return false
+		}
+	}
+	return true
+}
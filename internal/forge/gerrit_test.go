@@ -0,0 +1,70 @@
+package forge
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sim-deos/plain/internal/git"
+)
+
+func TestWithChangeID(t *testing.T) {
+	author := git.Signature{Name: "Jane", Email: "jane@example.com", Time: time.Unix(1703123456, 0)}
+	id := NewChangeID("4b825dc642cb6eb9a060e54bf8d69288fbee4904", nil, author, "Checkpoint")
+	if len(id) != 41 || id[0] != 'I' {
+		t.Fatalf("NewChangeID() = %q", id)
+	}
+
+	cases := map[string]string{
+		"Checkpoint":               "Checkpoint\n\nChange-Id: " + id,
+		"Fix login\n\nIt broke.\n": "Fix login\n\nIt broke.\n\nChange-Id: " + id,
+		"Fix login\n\nPlain-Session: 1\nPlain-Issue: 4": "Fix login\n\nChange-Id: " + id + "\nPlain-Session: 1\nPlain-Issue: 4",
+	}
+	for message, want := range cases {
+		got := WithChangeID(message, id)
+		if got != want {
+			t.Errorf("WithChangeID(%q) = %q, want %q", message, got, want)
+		}
+		if found, ok := ChangeID(got); !ok || found != id {
+			t.Errorf("ChangeID(%q) = %q, %v", got, found, ok)
+		}
+	}
+
+	// An existing Change-Id is kept, so a rewritten commit still updates the same change.
+	existing := "Fix login\n\nChange-Id: I" + strings.Repeat("a", 40)
+	if got := WithChangeID(existing, id); got != existing {
+		t.Errorf("WithChangeID() replaced an existing Change-Id: %q", got)
+	}
+	if _, ok := ChangeID("Change-Id: I" + strings.Repeat("a", 40)); ok {
+		t.Error("a subject line is not a trailer")
+	}
+}
+
+func TestRemoteHost(t *testing.T) {
+	cases := map[string]string{
+		"ssh://jane@review.gerrithub.io:29418/project": "review.gerrithub.io",
+		"https://android.googlesource.com/platform":    "android.googlesource.com",
+		"git@github.com:octo/app.git":                  "github.com",
+	}
+	for remote, want := range cases {
+		host, err := RemoteHost(remote)
+		if err != nil || host != want {
+			t.Errorf("RemoteHost(%q) = %q, %v, want %q", remote, host, err, want)
+		}
+	}
+	hosts := map[string]bool{
+		"review.gerrithub.io":          true,
+		"android.googlesource.com":     true,
+		"review.example.com":           true,
+		"REVIEW.example.com":           true,
+		"github.com":                   false,
+		"gerrit-mirror.example.com":    false,
+		"notgooglesource.com":          false,
+		"review.example.com.evil.test": false,
+	}
+	for host, want := range hosts {
+		if got := IsGerritHost(host, []string{"review.example.com"}); got != want {
+			t.Errorf("IsGerritHost(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
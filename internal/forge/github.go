@@ -0,0 +1,467 @@
+package forge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sim-deos/plain/internal/httpclient"
+)
+
+// githubClient implements [Client] with GitHub's REST API, and its GraphQL API where REST has no equivalent.
+type githubClient struct {
+	repo    Repo
+	token   string
+	api     string // The REST API root, without a trailing slash
+	graphql string // The GraphQL endpoint
+	http    *http.Client
+}
+
+func newGitHubClient(repo Repo, token string, client *http.Client) *githubClient {
+	// GitHub Enterprise Server serves its API from the instance host rather than a separate api. host.
+	api, graphql := "https://api.github.com", "https://api.github.com/graphql"
+	if repo.Host != "github.com" {
+		api, graphql = "https://"+repo.Host+"/api/v3", "https://"+repo.Host+"/api/graphql"
+	}
+	return &githubClient{repo: repo, token: token, api: api, graphql: graphql, http: client}
+}
+
+func (c *githubClient) CreatePullRequest(ctx context.Context, opts PullRequestOptions) (PullRequest, error) {
+	request := map[string]any{
+		"head":  opts.Head,
+		"base":  opts.Base,
+		"title": opts.Title,
+		"body":  opts.Body,
+		"draft": opts.Draft,
+	}
+
+	var response struct {
+		Number  int    `json:"number"`
+		NodeID  string `json:"node_id"`
+		HTMLURL string `json:"html_url"`
+		Draft   bool   `json:"draft"`
+	}
+	path := fmt.Sprintf("/repos/%s/%s/pulls", c.repo.Owner, c.repo.Name)
+	if err := c.do(ctx, http.MethodPost, c.api+path, request, &response); err != nil {
+		return PullRequest{}, fmt.Errorf("forge: failed to create pull request: %w", err)
+	}
+
+	return PullRequest{Number: response.Number, ID: response.NodeID, URL: response.HTMLURL, Draft: response.Draft}, nil
+}
+
+func (c *githubClient) EnableAutoMerge(ctx context.Context, pr PullRequest, method MergeMethod) error {
+	query := `mutation($id: ID!, $method: PullRequestMergeMethod!) {
+		enablePullRequestAutoMerge(input: {pullRequestId: $id, mergeMethod: $method}) { clientMutationId }
+	}`
+	variables := map[string]any{"id": pr.ID, "method": strings.ToUpper(string(method))}
+	if err := c.query(ctx, query, variables, nil); err != nil {
+		return fmt.Errorf("forge: failed to enable auto-merge: %w", err)
+	}
+	return nil
+}
+
+func (c *githubClient) FindPullRequest(ctx context.Context, head string) (PullRequest, error) {
+	var response []struct {
+		Number  int    `json:"number"`
+		NodeID  string `json:"node_id"`
+		HTMLURL string `json:"html_url"`
+		Draft   bool   `json:"draft"`
+	}
+
+	// The head filter needs the owner of the fork the branch lives in, which for plain is the repository itself.
+	query := url.Values{"head": {c.repo.Owner + ":" + head}, "state": {"open"}}
+	path := fmt.Sprintf("/repos/%s/%s/pulls?%s", c.repo.Owner, c.repo.Name, query.Encode())
+	if err := c.do(ctx, http.MethodGet, c.api+path, nil, &response); err != nil {
+		return PullRequest{}, fmt.Errorf("forge: failed to find pull request: %w", err)
+	}
+	if len(response) == 0 {
+		return PullRequest{}, fmt.Errorf("%w for %s", ErrNoPullRequest, head)
+	}
+
+	pr := response[0]
+	return PullRequest{Number: pr.Number, ID: pr.NodeID, URL: pr.HTMLURL, Draft: pr.Draft}, nil
+}
+
+func (c *githubClient) ReviewThreads(ctx context.Context, pr PullRequest) ([]ReviewThread, error) {
+	query := `query($owner: String!, $name: String!, $number: Int!) {
+		repository(owner: $owner, name: $name) {
+			pullRequest(number: $number) {
+				reviewThreads(first: 100) {
+					nodes {
+						id path line isResolved isOutdated
+						comments(first: 50) { nodes { author { login } body createdAt } }
+					}
+				}
+			}
+		}
+	}`
+	variables := map[string]any{"owner": c.repo.Owner, "name": c.repo.Name, "number": pr.Number}
+
+	var data struct {
+		Repository struct {
+			PullRequest struct {
+				ReviewThreads struct {
+					Nodes []struct {
+						ID         string `json:"id"`
+						Path       string `json:"path"`
+						Line       int    `json:"line"`
+						IsResolved bool   `json:"isResolved"`
+						IsOutdated bool   `json:"isOutdated"`
+						Comments   struct {
+							Nodes []struct {
+								Author struct {
+									Login string `json:"login"`
+								} `json:"author"`
+								Body      string    `json:"body"`
+								CreatedAt time.Time `json:"createdAt"`
+							} `json:"nodes"`
+						} `json:"comments"`
+					} `json:"nodes"`
+				} `json:"reviewThreads"`
+			} `json:"pullRequest"`
+		} `json:"repository"`
+	}
+	if err := c.query(ctx, query, variables, &data); err != nil {
+		return nil, fmt.Errorf("forge: failed to fetch review threads: %w", err)
+	}
+
+	var threads []ReviewThread
+	for _, node := range data.Repository.PullRequest.ReviewThreads.Nodes {
+		thread := ReviewThread{ID: node.ID, Path: node.Path, Line: node.Line, Resolved: node.IsResolved, Outdated: node.IsOutdated}
+		for _, comment := range node.Comments.Nodes {
+			thread.Comments = append(thread.Comments, ReviewComment{Author: comment.Author.Login, Body: comment.Body, CreatedAt: comment.CreatedAt})
+		}
+		threads = append(threads, thread)
+	}
+	return threads, nil
+}
+
+func (c *githubClient) ReplyToThread(ctx context.Context, thread ReviewThread, body string) error {
+	query := `mutation($id: ID!, $body: String!) {
+		addPullRequestReviewThreadReply(input: {pullRequestReviewThreadId: $id, body: $body}) { clientMutationId }
+	}`
+	if err := c.query(ctx, query, map[string]any{"id": thread.ID, "body": body}, nil); err != nil {
+		return fmt.Errorf("forge: failed to reply: %w", err)
+	}
+	return nil
+}
+
+func (c *githubClient) Checks(ctx context.Context, commit string) ([]Check, error) {
+	var runs struct {
+		CheckRuns []struct {
+			ID         int64  `json:"id"`
+			Name       string `json:"name"`
+			Status     string `json:"status"`
+			Conclusion string `json:"conclusion"`
+			HTMLURL    string `json:"html_url"`
+		} `json:"check_runs"`
+	}
+	path := fmt.Sprintf("/repos/%s/%s/commits/%s/check-runs?per_page=100", c.repo.Owner, c.repo.Name, commit)
+	if err := c.do(ctx, http.MethodGet, c.api+path, nil, &runs); err != nil {
+		return nil, fmt.Errorf("forge: failed to fetch checks: %w", err)
+	}
+
+	// Older integrations report commit statuses instead of check runs.
+	var combined struct {
+		Statuses []struct {
+			Context   string `json:"context"`
+			State     string `json:"state"`
+			TargetURL string `json:"target_url"`
+		} `json:"statuses"`
+	}
+	path = fmt.Sprintf("/repos/%s/%s/commits/%s/status", c.repo.Owner, c.repo.Name, commit)
+	if err := c.do(ctx, http.MethodGet, c.api+path, nil, &combined); err != nil {
+		return nil, fmt.Errorf("forge: failed to fetch commit statuses: %w", err)
+	}
+
+	var checks []Check
+	for _, run := range runs.CheckRuns {
+		status := CheckPending
+		if run.Status == "completed" {
+			status = githubConclusion(run.Conclusion)
+		}
+		checks = append(checks, Check{
+			ID:         strconv.FormatInt(run.ID, 10),
+			Name:       run.Name,
+			Status:     status,
+			URL:        run.HTMLURL,
+			Rerunnable: status != CheckPending,
+		})
+	}
+	for _, s := range combined.Statuses {
+		status := CheckPending
+		switch s.State {
+		case "success":
+			status = CheckPassed
+		case "failure", "error":
+			status = CheckFailed
+		}
+		checks = append(checks, Check{Name: s.Context, Status: status, URL: s.TargetURL})
+	}
+	return checks, nil
+}
+
+func githubConclusion(conclusion string) CheckStatus {
+	switch conclusion {
+	case "success":
+		return CheckPassed
+	case "neutral", "skipped":
+		return CheckSkipped
+	default:
+		return CheckFailed
+	}
+}
+
+func (c *githubClient) RerunCheck(ctx context.Context, check Check) error {
+	if !check.Rerunnable {
+		return fmt.Errorf("%w: %s cannot be re-run", ErrUnsupported, check.Name)
+	}
+
+	// GitHub Actions jobs share their check run's ID and are re-run through the Actions API.
+	// Checks from other apps are asked to run again through the check run itself.
+	path := fmt.Sprintf("/repos/%s/%s/actions/jobs/%s/rerun", c.repo.Owner, c.repo.Name, check.ID)
+	err := c.do(ctx, http.MethodPost, c.api+path, nil, nil)
+	if err == nil {
+		return nil
+	}
+
+	path = fmt.Sprintf("/repos/%s/%s/check-runs/%s/rerequest", c.repo.Owner, c.repo.Name, check.ID)
+	if err := c.do(ctx, http.MethodPost, c.api+path, nil, nil); err != nil {
+		return fmt.Errorf("forge: failed to re-run %s: %w", check.Name, err)
+	}
+	return nil
+}
+
+func (c *githubClient) BranchProtection(ctx context.Context, branch string) (BranchProtection, error) {
+	var protection BranchProtection
+	checks := map[string]bool{}
+	addCheck := func(name string) {
+		if !checks[name] {
+			checks[name] = true
+			protection.RequiredChecks = append(protection.RequiredChecks, name)
+		}
+	}
+
+	// Classic protection rules. The branch's summary is readable by anyone who can read the repository,
+	// but the full rules only by admins, so the rest is read when GitHub allows it.
+	var summary struct {
+		Protected  bool `json:"protected"`
+		Protection struct {
+			RequiredStatusChecks struct {
+				Contexts []string `json:"contexts"`
+			} `json:"required_status_checks"`
+		} `json:"protection"`
+	}
+	path := fmt.Sprintf("/repos/%s/%s/branches/%s", c.repo.Owner, c.repo.Name, branch)
+	err := c.do(ctx, http.MethodGet, c.api+path, nil, &summary)
+	var apiErr *githubError
+	if errors.As(err, &apiErr) && apiErr.code == http.StatusNotFound {
+		return BranchProtection{}, nil
+	}
+	if err != nil {
+		return BranchProtection{}, fmt.Errorf("forge: failed to fetch branch %s: %w", branch, err)
+	}
+	protection.Protected = summary.Protected
+	for _, name := range summary.Protection.RequiredStatusChecks.Contexts {
+		addCheck(name)
+	}
+
+	if summary.Protected {
+		var rules struct {
+			RequiredStatusChecks *struct {
+				Contexts []string `json:"contexts"`
+			} `json:"required_status_checks"`
+			RequiredPullRequestReviews *struct {
+				RequiredApprovingReviewCount int `json:"required_approving_review_count"`
+			} `json:"required_pull_request_reviews"`
+			RequiredLinearHistory struct {
+				Enabled bool `json:"enabled"`
+			} `json:"required_linear_history"`
+		}
+		err := c.do(ctx, http.MethodGet, c.api+path+"/protection", nil, &rules)
+		switch {
+		case errors.As(err, &apiErr) && (apiErr.code == http.StatusForbidden || apiErr.code == http.StatusNotFound):
+		case err != nil:
+			return BranchProtection{}, fmt.Errorf("forge: failed to fetch protection of %s: %w", branch, err)
+		default:
+			if reviews := rules.RequiredPullRequestReviews; reviews != nil {
+				protection.PullRequestsOnly = true
+				protection.RequiredReviews = reviews.RequiredApprovingReviewCount
+			}
+			if rules.RequiredStatusChecks != nil {
+				for _, name := range rules.RequiredStatusChecks.Contexts {
+					addCheck(name)
+				}
+			}
+			protection.LinearHistory = rules.RequiredLinearHistory.Enabled
+		}
+	}
+
+	// Rulesets, which apply on top of classic protection and are readable by anyone.
+	var rulesets []struct {
+		Type       string `json:"type"`
+		Parameters struct {
+			RequiredApprovingReviewCount int `json:"required_approving_review_count"`
+			RequiredStatusChecks         []struct {
+				Context string `json:"context"`
+			} `json:"required_status_checks"`
+		} `json:"parameters"`
+	}
+	path = fmt.Sprintf("/repos/%s/%s/rules/branches/%s", c.repo.Owner, c.repo.Name, branch)
+	if err := c.do(ctx, http.MethodGet, c.api+path, nil, &rulesets); err != nil {
+		return BranchProtection{}, fmt.Errorf("forge: failed to fetch rules for %s: %w", branch, err)
+	}
+	for _, rule := range rulesets {
+		protection.Protected = true
+		switch rule.Type {
+		case "pull_request":
+			protection.PullRequestsOnly = true
+			protection.RequiredReviews = max(protection.RequiredReviews, rule.Parameters.RequiredApprovingReviewCount)
+		case "required_status_checks":
+			for _, check := range rule.Parameters.RequiredStatusChecks {
+				addCheck(check.Context)
+			}
+		case "required_linear_history":
+			protection.LinearHistory = true
+		}
+	}
+	return protection, nil
+}
+
+func (c *githubClient) CreateRelease(ctx context.Context, opts ReleaseOptions) (Release, error) {
+	request := map[string]any{
+		"tag_name":   opts.Tag,
+		"name":       opts.Name,
+		"body":       opts.Body,
+		"prerelease": opts.Prerelease,
+	}
+
+	var response struct {
+		ID        int64  `json:"id"`
+		HTMLURL   string `json:"html_url"`
+		UploadURL string `json:"upload_url"`
+	}
+	path := fmt.Sprintf("/repos/%s/%s/releases", c.repo.Owner, c.repo.Name)
+	if err := c.do(ctx, http.MethodPost, c.api+path, request, &response); err != nil {
+		return Release{}, fmt.Errorf("forge: failed to create release: %w", err)
+	}
+
+	// The upload URL is a URI template ending in {?name,label}.
+	uploadURL, _, _ := strings.Cut(response.UploadURL, "{")
+	return Release{ID: strconv.FormatInt(response.ID, 10), URL: response.HTMLURL, uploadURL: uploadURL}, nil
+}
+
+func (c *githubClient) UploadReleaseAsset(ctx context.Context, release Release, name string, content io.Reader, size int64) error {
+	target := release.uploadURL + "?" + url.Values{"name": {name}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, content)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	if err := c.send(req, nil); err != nil {
+		return fmt.Errorf("forge: failed to upload %s: %w", name, err)
+	}
+	return nil
+}
+
+// query runs a GraphQL query or mutation, decoding its data into result and reporting any GraphQL errors.
+func (c *githubClient) query(ctx context.Context, query string, variables map[string]any, result any) error {
+	var response struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := c.do(ctx, http.MethodPost, c.graphql, map[string]any{"query": query, "variables": variables}, &response); err != nil {
+		return err
+	}
+	if len(response.Errors) > 0 {
+		return errors.New(response.Errors[0].Message)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(response.Data, result)
+}
+
+// do sends a JSON request and decodes the JSON response into result.
+func (c *githubClient) do(ctx context.Context, method, url string, body, result any) error {
+	var reqBody io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return c.send(req, result)
+}
+
+// send authenticates and sends req, decoding the JSON response into result and turning error responses into errors.
+func (c *githubClient) send(req *http.Request, result any) error {
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		if err := httpclient.CheckRateLimit(resp); err != nil {
+			return err
+		}
+		var apiErr struct {
+			Message string `json:"message"`
+			Errors  []struct {
+				Message string `json:"message"`
+			} `json:"errors"`
+		}
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		err := &githubError{code: resp.StatusCode, status: resp.Status, message: apiErr.Message}
+		if len(apiErr.Errors) > 0 && apiErr.Errors[0].Message != "" {
+			err.detail = apiErr.Errors[0].Message
+		}
+		return err
+	}
+
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// githubError is an error response from GitHub's API.
+type githubError struct {
+	code    int    // The HTTP status code
+	status  string // The HTTP status line, e.g. "404 Not Found"
+	message string
+	detail  string // The first of the detailed errors GitHub sometimes adds
+}
+
+func (e *githubError) Error() string {
+	if e.detail != "" {
+		return fmt.Sprintf("%s: %s (%s)", e.status, e.message, e.detail)
+	}
+	return fmt.Sprintf("%s: %s", e.status, e.message)
+}
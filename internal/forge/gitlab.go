@@ -0,0 +1,440 @@
+package forge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sim-deos/plain/internal/httpclient"
+)
+
+// gitlabClient implements [Client] with GitLab's REST API, where pull requests are merge requests.
+type gitlabClient struct {
+	repo    Repo
+	token   string
+	web     string // The instance's web root, without a trailing slash
+	api     string // The REST API root, without a trailing slash
+	project string // The repository's path, escaped for use as its ID in API paths
+	http    *http.Client
+}
+
+func newGitLabClient(repo Repo, token string, client *http.Client) *gitlabClient {
+	web := "https://" + repo.Host
+	return &gitlabClient{
+		repo:    repo,
+		token:   token,
+		web:     web,
+		api:     web + "/api/v4",
+		project: url.PathEscape(repo.Owner + "/" + repo.Name),
+		http:    client,
+	}
+}
+
+// gitlabMergeRequest is the part of a merge request plain reads.
+type gitlabMergeRequest struct {
+	ID     int64  `json:"id"`
+	IID    int    `json:"iid"`
+	WebURL string `json:"web_url"`
+	Draft  bool   `json:"draft"`
+}
+
+func (mr gitlabMergeRequest) pullRequest() PullRequest {
+	return PullRequest{Number: mr.IID, ID: strconv.FormatInt(mr.ID, 10), URL: mr.WebURL, Draft: mr.Draft}
+}
+
+func (c *gitlabClient) CreatePullRequest(ctx context.Context, opts PullRequestOptions) (PullRequest, error) {
+	// GitLab marks drafts by their title.
+	title := opts.Title
+	if opts.Draft {
+		title = "Draft: " + title
+	}
+	request := map[string]any{
+		"source_branch":        opts.Head,
+		"target_branch":        opts.Base,
+		"title":                title,
+		"description":          opts.Body,
+		"remove_source_branch": true,
+	}
+
+	var response gitlabMergeRequest
+	if err := c.do(ctx, http.MethodPost, c.projectURL("/merge_requests"), request, &response); err != nil {
+		return PullRequest{}, fmt.Errorf("forge: failed to create merge request: %w", err)
+	}
+	return response.pullRequest(), nil
+}
+
+func (c *gitlabClient) EnableAutoMerge(ctx context.Context, pr PullRequest, method MergeMethod) error {
+	// Whether merge requests are rebased is a setting of the project rather than of each merge.
+	if method == Rebase {
+		project, err := c.readProject(ctx)
+		if err != nil {
+			return err
+		}
+		if project.MergeMethod == "merge" {
+			return fmt.Errorf("%w: %s merges with merge commits, so it can't rebase !%d", ErrUnsupported, c.repo, pr.Number)
+		}
+	}
+
+	request := map[string]any{"merge_when_pipeline_succeeds": true, "squash": method == Squash}
+	if err := c.do(ctx, http.MethodPut, c.projectURL(fmt.Sprintf("/merge_requests/%d/merge", pr.Number)), request, nil); err != nil {
+		return fmt.Errorf("forge: failed to enable auto-merge: %w", err)
+	}
+	return nil
+}
+
+func (c *gitlabClient) FindPullRequest(ctx context.Context, head string) (PullRequest, error) {
+	var response []gitlabMergeRequest
+	query := url.Values{"source_branch": {head}, "state": {"opened"}}
+	if err := c.do(ctx, http.MethodGet, c.projectURL("/merge_requests?"+query.Encode()), nil, &response); err != nil {
+		return PullRequest{}, fmt.Errorf("forge: failed to find merge request: %w", err)
+	}
+	if len(response) == 0 {
+		return PullRequest{}, fmt.Errorf("%w for %s", ErrNoPullRequest, head)
+	}
+	return response[0].pullRequest(), nil
+}
+
+// ReviewThreads returns the merge request's discussions on lines of its diff. A thread's ID is the merge
+// request's number and the discussion's ID, joined by a colon, since GitLab needs both to reply.
+func (c *gitlabClient) ReviewThreads(ctx context.Context, pr PullRequest) ([]ReviewThread, error) {
+	var discussions []struct {
+		ID    string `json:"id"`
+		Notes []struct {
+			Type   string `json:"type"`
+			Author struct {
+				Username string `json:"username"`
+			} `json:"author"`
+			Body      string    `json:"body"`
+			CreatedAt time.Time `json:"created_at"`
+			Resolved  bool      `json:"resolved"`
+			Position  *struct {
+				NewPath string `json:"new_path"`
+				NewLine int    `json:"new_line"`
+			} `json:"position"`
+		} `json:"notes"`
+	}
+	path := fmt.Sprintf("/merge_requests/%d/discussions?per_page=100", pr.Number)
+	if err := c.do(ctx, http.MethodGet, c.projectURL(path), nil, &discussions); err != nil {
+		return nil, fmt.Errorf("forge: failed to fetch review threads: %w", err)
+	}
+
+	var threads []ReviewThread
+	for _, discussion := range discussions {
+		if len(discussion.Notes) == 0 || discussion.Notes[0].Type != "DiffNote" || discussion.Notes[0].Position == nil {
+			continue
+		}
+		first := discussion.Notes[0]
+		thread := ReviewThread{
+			ID:       fmt.Sprintf("%d:%s", pr.Number, discussion.ID),
+			Path:     first.Position.NewPath,
+			Line:     first.Position.NewLine,
+			Resolved: first.Resolved,
+		}
+		for _, note := range discussion.Notes {
+			thread.Comments = append(thread.Comments, ReviewComment{Author: note.Author.Username, Body: note.Body, CreatedAt: note.CreatedAt})
+		}
+		threads = append(threads, thread)
+	}
+	return threads, nil
+}
+
+func (c *gitlabClient) ReplyToThread(ctx context.Context, thread ReviewThread, body string) error {
+	number, discussion, ok := strings.Cut(thread.ID, ":")
+	if !ok {
+		return fmt.Errorf("forge: %q is not a GitLab review thread", thread.ID)
+	}
+	path := fmt.Sprintf("/merge_requests/%s/discussions/%s/notes", number, discussion)
+	if err := c.do(ctx, http.MethodPost, c.projectURL(path), map[string]any{"body": body}, nil); err != nil {
+		return fmt.Errorf("forge: failed to reply: %w", err)
+	}
+	return nil
+}
+
+// Checks returns the commit's statuses, which include the jobs of its pipelines as well as statuses
+// reported by other services. Only pipeline jobs can be re-run.
+func (c *gitlabClient) Checks(ctx context.Context, commit string) ([]Check, error) {
+	var statuses []struct {
+		ID           int64  `json:"id"`
+		Name         string `json:"name"`
+		Status       string `json:"status"`
+		TargetURL    string `json:"target_url"`
+		AllowFailure bool   `json:"allow_failure"`
+	}
+	path := fmt.Sprintf("/repository/commits/%s/statuses?per_page=100", commit)
+	if err := c.do(ctx, http.MethodGet, c.projectURL(path), nil, &statuses); err != nil {
+		return nil, fmt.Errorf("forge: failed to fetch checks: %w", err)
+	}
+
+	var checks []Check
+	for _, s := range statuses {
+		status := CheckPending
+		switch s.Status {
+		case "success":
+			status = CheckPassed
+		case "failed", "canceled":
+			status = CheckFailed
+			if s.AllowFailure {
+				status = CheckSkipped
+			}
+		case "skipped", "manual":
+			status = CheckSkipped
+		}
+		checks = append(checks, Check{
+			ID:         strconv.FormatInt(s.ID, 10),
+			Name:       s.Name,
+			Status:     status,
+			URL:        s.TargetURL,
+			Rerunnable: status != CheckPending && strings.Contains(s.TargetURL, "/-/jobs/"),
+		})
+	}
+	return checks, nil
+}
+
+func (c *gitlabClient) RerunCheck(ctx context.Context, check Check) error {
+	if !check.Rerunnable {
+		return fmt.Errorf("%w: %s cannot be re-run", ErrUnsupported, check.Name)
+	}
+	if err := c.do(ctx, http.MethodPost, c.projectURL("/jobs/"+check.ID+"/retry"), nil, nil); err != nil {
+		return fmt.Errorf("forge: failed to re-run %s: %w", check.Name, err)
+	}
+	return nil
+}
+
+// BranchProtection reads GitLab's protected branches, whose names may have * wildcards, and the approval
+// rules for the branch. GitLab doesn't name the checks a merge needs, so RequiredChecks is always empty.
+func (c *gitlabClient) BranchProtection(ctx context.Context, branch string) (BranchProtection, error) {
+	var protection BranchProtection
+	var protected []struct {
+		Name             string `json:"name"`
+		PushAccessLevels []struct {
+			AccessLevel int `json:"access_level"`
+		} `json:"push_access_levels"`
+	}
+	if err := c.do(ctx, http.MethodGet, c.projectURL("/protected_branches?per_page=100"), nil, &protected); err != nil {
+		return BranchProtection{}, fmt.Errorf("forge: failed to fetch protected branches: %w", err)
+	}
+	for _, rule := range protected {
+		if !gitlabWildcardMatch(rule.Name, branch) {
+			continue
+		}
+		protection.Protected = true
+		// An access level of 0 is "No one", which leaves merge requests as the only way in.
+		protection.PullRequestsOnly = true
+		for _, level := range rule.PushAccessLevels {
+			if level.AccessLevel != 0 {
+				protection.PullRequestsOnly = false
+			}
+		}
+	}
+	if !protection.Protected {
+		return BranchProtection{}, nil
+	}
+
+	project, err := c.readProject(ctx)
+	if err != nil {
+		return BranchProtection{}, err
+	}
+	protection.LinearHistory = project.MergeMethod == "ff"
+
+	// Approval rules need a paid tier, so a forbidden or missing endpoint means there are none.
+	var rules []struct {
+		ApprovalsRequired int `json:"approvals_required"`
+		ProtectedBranches []struct {
+			Name string `json:"name"`
+		} `json:"protected_branches"`
+	}
+	err = c.do(ctx, http.MethodGet, c.projectURL("/approval_rules"), nil, &rules)
+	var apiErr *gitlabError
+	switch {
+	case errors.As(err, &apiErr) && (apiErr.code == http.StatusForbidden || apiErr.code == http.StatusNotFound):
+	case err != nil:
+		return BranchProtection{}, fmt.Errorf("forge: failed to fetch approval rules: %w", err)
+	}
+	for _, rule := range rules {
+		applies := len(rule.ProtectedBranches) == 0
+		for _, b := range rule.ProtectedBranches {
+			applies = applies || gitlabWildcardMatch(b.Name, branch)
+		}
+		if applies {
+			protection.RequiredReviews = max(protection.RequiredReviews, rule.ApprovalsRequired)
+		}
+	}
+	return protection, nil
+}
+
+// gitlabWildcardMatch reports whether branch matches a protected branch name, in which * stands for any
+// run of characters, slashes included.
+func gitlabWildcardMatch(pattern, branch string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == branch
+	}
+	if !strings.HasPrefix(branch, parts[0]) {
+		return false
+	}
+	branch = branch[len(parts[0]):]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(branch, part)
+		if i < 0 {
+			return false
+		}
+		branch = branch[i+len(part):]
+	}
+	return strings.HasSuffix(branch, parts[len(parts)-1])
+}
+
+// CreateRelease publishes a release for a tag. GitLab has no prereleases, so Prerelease is ignored.
+func (c *gitlabClient) CreateRelease(ctx context.Context, opts ReleaseOptions) (Release, error) {
+	request := map[string]any{
+		"tag_name":    opts.Tag,
+		"name":        opts.Name,
+		"description": opts.Body,
+	}
+
+	var response struct {
+		TagName string `json:"tag_name"`
+		Links   struct {
+			Self string `json:"self"`
+		} `json:"_links"`
+	}
+	if err := c.do(ctx, http.MethodPost, c.projectURL("/releases"), request, &response); err != nil {
+		return Release{}, fmt.Errorf("forge: failed to create release: %w", err)
+	}
+	return Release{ID: response.TagName, URL: response.Links.Self}, nil
+}
+
+// UploadReleaseAsset uploads the file to the project, then links it from the release, which is how GitLab
+// attaches files to releases.
+func (c *gitlabClient) UploadReleaseAsset(ctx context.Context, release Release, name string, content io.Reader, size int64) error {
+	// The multipart body is framed around content up front so its length is known without buffering it.
+	var frame bytes.Buffer
+	form := multipart.NewWriter(&frame)
+	if _, err := form.CreateFormFile("file", name); err != nil {
+		return err
+	}
+	head := bytes.Clone(frame.Bytes())
+	frame.Reset()
+	if err := form.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.projectURL("/uploads"), io.MultiReader(bytes.NewReader(head), content, &frame))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = int64(len(head)) + size + int64(frame.Len())
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	var upload struct {
+		URL      string `json:"url"`
+		FullPath string `json:"full_path"`
+	}
+	if err := c.send(req, &upload); err != nil {
+		return fmt.Errorf("forge: failed to upload %s: %w", name, err)
+	}
+	// Older instances only give the upload's path relative to the project.
+	link := c.web + upload.FullPath
+	if upload.FullPath == "" {
+		link = c.web + "/" + c.repo.Owner + "/" + c.repo.Name + upload.URL
+	}
+
+	path := "/releases/" + url.PathEscape(release.ID) + "/assets/links"
+	if err := c.do(ctx, http.MethodPost, c.projectURL(path), map[string]any{"name": name, "url": link}, nil); err != nil {
+		return fmt.Errorf("forge: failed to attach %s to the release: %w", name, err)
+	}
+	return nil
+}
+
+// gitlabProject is the part of a project's settings plain reads.
+type gitlabProject struct {
+	MergeMethod string `json:"merge_method"` // merge, rebase_merge, or ff
+}
+
+func (c *gitlabClient) readProject(ctx context.Context) (gitlabProject, error) {
+	var project gitlabProject
+	if err := c.do(ctx, http.MethodGet, c.projectURL(""), nil, &project); err != nil {
+		return gitlabProject{}, fmt.Errorf("forge: failed to fetch %s: %w", c.repo, err)
+	}
+	return project, nil
+}
+
+// projectURL returns the API URL of path under the repository's project.
+func (c *gitlabClient) projectURL(path string) string {
+	return c.api + "/projects/" + c.project + path
+}
+
+// do sends a JSON request and decodes the JSON response into result.
+func (c *gitlabClient) do(ctx context.Context, method, url string, body, result any) error {
+	var reqBody io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return c.send(req, result)
+}
+
+// send authenticates and sends req, decoding the JSON response into result and turning error responses into errors.
+func (c *gitlabClient) send(req *http.Request, result any) error {
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.token)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		if err := httpclient.CheckRateLimit(resp); err != nil {
+			return err
+		}
+		// GitLab reports errors as a message, which is sometimes an object of messages per field, or
+		// as an OAuth-style error.
+		var apiErr struct {
+			Message json.RawMessage `json:"message"`
+			Error   string          `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		message := apiErr.Error
+		if err := json.Unmarshal(apiErr.Message, &message); err != nil && len(apiErr.Message) > 0 {
+			message = string(apiErr.Message)
+		}
+		return &gitlabError{code: resp.StatusCode, status: resp.Status, message: message}
+	}
+
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// gitlabError is an error response from GitLab's API.
+type gitlabError struct {
+	code    int    // The HTTP status code
+	status  string // The HTTP status line, e.g. "404 Not Found"
+	message string
+}
+
+func (e *gitlabError) Error() string {
+	return fmt.Sprintf("%s: %s", e.status, e.message)
+}
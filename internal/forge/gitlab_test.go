@@ -0,0 +1,269 @@
+package forge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newTestGitLab returns a client for grp/sub/app whose API calls are served by handler.
+func newTestGitLab(t *testing.T, handler http.HandlerFunc) *gitlabClient {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	c := newGitLabClient(Repo{Host: "gitlab.example.com", Owner: "grp/sub", Name: "app"}, "test-token", srv.Client())
+	c.web, c.api = srv.URL, srv.URL+"/api/v4"
+	return c
+}
+
+func TestGitLabCreateDraftMergeRequest(t *testing.T) {
+	c := newTestGitLab(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/api/v4/projects/grp%2Fsub%2Fapp/merge_requests" || r.Header.Get("Authorization") != "Bearer test-token" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.EscapedPath())
+		}
+
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["title"] != "Draft: Login" || body["source_branch"] != "login" || body["target_branch"] != "main" {
+			t.Errorf("unexpected request body %v", body)
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": 901, "iid": 7, "web_url": "https://gitlab.example.com/grp/sub/app/-/merge_requests/7", "draft": true}`))
+	})
+
+	pr, err := c.CreatePullRequest(context.Background(), PullRequestOptions{Head: "login", Base: "main", Title: "Login", Draft: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pr.Number != 7 || pr.ID != "901" || !pr.Draft {
+		t.Fatalf("unexpected merge request %+v", pr)
+	}
+}
+
+func TestGitLabFindMergeRequest(t *testing.T) {
+	c := newTestGitLab(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("source_branch") != "login" || r.URL.Query().Get("state") != "opened" {
+			t.Errorf("unexpected query %q", r.URL.RawQuery)
+		}
+		w.Write([]byte(`[]`))
+	})
+
+	if _, err := c.FindPullRequest(context.Background(), "login"); !errors.Is(err, ErrNoPullRequest) {
+		t.Fatalf("expected ErrNoPullRequest, got %v", err)
+	}
+}
+
+func TestGitLabEnableAutoMerge(t *testing.T) {
+	var merges []map[string]any
+	mergeMethod := "merge"
+	c := newTestGitLab(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.EscapedPath(), "/projects/grp%2Fsub%2Fapp"):
+			w.Write([]byte(`{"merge_method": "` + mergeMethod + `"}`))
+		case r.Method == http.MethodPut && strings.HasSuffix(r.URL.Path, "/merge_requests/7/merge"):
+			var body map[string]any
+			json.NewDecoder(r.Body).Decode(&body)
+			merges = append(merges, body)
+			w.Write([]byte(`{}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	})
+
+	ctx := context.Background()
+	if err := c.EnableAutoMerge(ctx, PullRequest{Number: 7}, Squash); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.EnableAutoMerge(ctx, PullRequest{Number: 7}, Rebase); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("EnableAutoMerge(rebase) on a project making merge commits = %v, want %v", err, ErrUnsupported)
+	}
+	mergeMethod = "rebase_merge"
+	if err := c.EnableAutoMerge(ctx, PullRequest{Number: 7}, Rebase); err != nil {
+		t.Fatal(err)
+	}
+	if len(merges) != 2 || merges[0]["squash"] != true || merges[0]["merge_when_pipeline_succeeds"] != true || merges[1]["squash"] != false {
+		t.Fatalf("unexpected merges %v", merges)
+	}
+}
+
+func TestGitLabReviewThreads(t *testing.T) {
+	var reply map[string]any
+	c := newTestGitLab(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/merge_requests/7/discussions"):
+			w.Write([]byte(`[
+				{"id": "d1", "notes": [
+					{"type": "DiffNote", "author": {"username": "reviewer"}, "body": "Handle the error", "created_at": "2024-01-01T00:00:00Z",
+					 "resolved": false, "position": {"new_path": "main.go", "new_line": 12}},
+					{"type": "DiffNote", "author": {"username": "ann"}, "body": "Done", "created_at": "2024-01-02T00:00:00Z"}
+				]},
+				{"id": "d2", "notes": [{"type": null, "author": {"username": "bob"}, "body": "Looks good"}]}
+			]`))
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/merge_requests/7/discussions/d1/notes"):
+			json.NewDecoder(r.Body).Decode(&reply)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	})
+
+	threads, err := c.ReviewThreads(context.Background(), PullRequest{Number: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(threads) != 1 || threads[0].Path != "main.go" || threads[0].Line != 12 || threads[0].Resolved || len(threads[0].Comments) != 2 {
+		t.Fatalf("unexpected threads %+v", threads)
+	}
+	if threads[0].Comments[0].Author != "reviewer" {
+		t.Fatalf("unexpected comment %+v", threads[0].Comments[0])
+	}
+	if err := c.ReplyToThread(context.Background(), threads[0], "Fixed"); err != nil {
+		t.Fatal(err)
+	}
+	if reply["body"] != "Fixed" {
+		t.Fatalf("unexpected reply %v", reply)
+	}
+}
+
+func TestGitLabChecks(t *testing.T) {
+	c := newTestGitLab(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/repository/commits/abc123/statuses"):
+			w.Write([]byte(`[
+				{"id": 11, "name": "build", "status": "success", "target_url": "https://gitlab.example.com/grp/sub/app/-/jobs/11"},
+				{"id": 12, "name": "test", "status": "failed", "target_url": "https://gitlab.example.com/grp/sub/app/-/jobs/12"},
+				{"id": 13, "name": "lint", "status": "failed", "allow_failure": true, "target_url": "https://gitlab.example.com/grp/sub/app/-/jobs/13"},
+				{"id": 14, "name": "deploy", "status": "running", "target_url": "https://gitlab.example.com/grp/sub/app/-/jobs/14"},
+				{"id": 15, "name": "ci/external", "status": "failed", "target_url": "https://ci.example.com/runs/15"}
+			]`))
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/jobs/12/retry"):
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	})
+
+	checks, err := c.Checks(context.Background(), "abc123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		status     CheckStatus
+		rerunnable bool
+	}{{CheckPassed, true}, {CheckFailed, true}, {CheckSkipped, true}, {CheckPending, false}, {CheckFailed, false}}
+	if len(checks) != len(want) {
+		t.Fatalf("unexpected checks %+v", checks)
+	}
+	for i, w := range want {
+		if checks[i].Status != w.status || checks[i].Rerunnable != w.rerunnable {
+			t.Errorf("check %s = %s, rerunnable %t, want %s, %t", checks[i].Name, checks[i].Status, checks[i].Rerunnable, w.status, w.rerunnable)
+		}
+	}
+	if err := c.RerunCheck(context.Background(), checks[1]); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.RerunCheck(context.Background(), checks[4]); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("RerunCheck() of an external status = %v, want %v", err, ErrUnsupported)
+	}
+}
+
+func TestGitLabBranchProtection(t *testing.T) {
+	c := newTestGitLab(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/protected_branches"):
+			w.Write([]byte(`[
+				{"name": "main", "push_access_levels": [{"access_level": 0}]},
+				{"name": "release/*", "push_access_levels": [{"access_level": 40}]}
+			]`))
+		case strings.HasSuffix(r.URL.Path, "/approval_rules"):
+			w.Write([]byte(`[
+				{"approvals_required": 1, "protected_branches": []},
+				{"approvals_required": 2, "protected_branches": [{"name": "main"}]}
+			]`))
+		case strings.HasSuffix(r.URL.EscapedPath(), "/projects/grp%2Fsub%2Fapp"):
+			w.Write([]byte(`{"merge_method": "ff"}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	})
+
+	ctx := context.Background()
+	main, err := c.BranchProtection(ctx, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !main.Protected || !main.PullRequestsOnly || main.RequiredReviews != 2 || !main.LinearHistory {
+		t.Errorf("protection of main = %+v", main)
+	}
+	release, err := c.BranchProtection(ctx, "release/1.0/rc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !release.Protected || release.PullRequestsOnly || release.RequiredReviews != 1 {
+		t.Errorf("protection of release/1.0/rc = %+v", release)
+	}
+	if feature, err := c.BranchProtection(ctx, "login"); err != nil || feature.Protected {
+		t.Errorf("protection of an unprotected branch = %+v, %v", feature, err)
+	}
+}
+
+func TestGitLabReleaseWithAsset(t *testing.T) {
+	var link map[string]any
+	c := newTestGitLab(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/releases"):
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"tag_name": "v1.0.0", "_links": {"self": "https://gitlab.example.com/grp/sub/app/-/releases/v1.0.0"}}`))
+		case strings.HasSuffix(r.URL.Path, "/uploads"):
+			file, header, err := r.FormFile("file")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			content, _ := io.ReadAll(file)
+			if header.Filename != "app.tar.gz" || string(content) != "binary" {
+				t.Errorf("unexpected upload %s %q", header.Filename, content)
+			}
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"url": "/uploads/abc/app.tar.gz", "full_path": "/-/project/3/uploads/abc/app.tar.gz"}`))
+		case strings.HasSuffix(r.URL.Path, "/releases/v1.0.0/assets/links"):
+			json.NewDecoder(r.Body).Decode(&link)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	})
+
+	release, err := c.CreateRelease(context.Background(), ReleaseOptions{Tag: "v1.0.0", Name: "v1.0.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.UploadReleaseAsset(context.Background(), release, "app.tar.gz", strings.NewReader("binary"), 6); err != nil {
+		t.Fatal(err)
+	}
+	if link["name"] != "app.tar.gz" || link["url"] != c.web+"/-/project/3/uploads/abc/app.tar.gz" {
+		t.Fatalf("unexpected asset link %v", link)
+	}
+}
+
+func TestGitLabError(t *testing.T) {
+	c := newTestGitLab(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(`{"message": ["Another open merge request already exists for this source branch: !6"]}`))
+	})
+
+	_, err := c.CreatePullRequest(context.Background(), PullRequestOptions{Head: "login", Base: "main", Title: "Login"})
+	if err == nil || !strings.Contains(err.Error(), "Another open merge request") {
+		t.Fatalf("expected GitLab's message in the error, got %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+package forge
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+var ErrBadRemote = errors.New("cannot parse remote URL")
+
+// Repo identifies a repository hosted on a forge.
+type Repo struct {
+	Host  string // The forge host, e.g. github.com, with the port when its web server isn't on the default
+	Owner string // The user, organization, or group path owning the repository
+	Name  string // The repository name
+}
+
+func (r Repo) String() string {
+	return r.Host + "/" + r.Owner + "/" + r.Name
+}
+
+// ParseRemoteURL extracts the forge repository from a git remote URL, after any url.<base>.insteadOf
+// rewriting. It takes https:// and http:// URLs, ssh:// (also spelled git+ssh:// and ssh+git://) and
+// git:// URLs, and the scp-like [user@]host:path shorthand, as in git@github.com:owner/repo.git. Hosts are
+// lower cased, and the user, a trailing slash, and a .git suffix are dropped, so every way of writing a
+// remote names the same Repo. The port of an ssh:// or git:// URL is dropped too, since the forge's web
+// server and API aren't on it; that of an https:// URL is kept.
+func ParseRemoteURL(remote string) (Repo, error) {
+	host, path, err := splitRemoteURL(remote)
+	if err != nil {
+		return Repo{}, err
+	}
+
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	i := strings.LastIndex(path, "/")
+	if i <= 0 || i == len(path)-1 {
+		return Repo{}, fmt.Errorf("%w: %s", ErrBadRemote, remote)
+	}
+	return Repo{Host: host, Owner: path[:i], Name: path[i+1:]}, nil
+}
+
+// RemoteHost returns the host a git remote URL points to, for remotes that don't name an owner and
+// repository, like Gerrit's ssh://review.example.com:29418/project.
+func RemoteHost(remote string) (string, error) {
+	host, _, err := splitRemoteURL(remote)
+	return host, err
+}
+
+// splitRemoteURL splits a remote URL into its host, normalized as ParseRemoteURL describes, and path.
+// Local paths, file:// URLs, and anything else without a host are rejected.
+func splitRemoteURL(remote string) (host, path string, err error) {
+	bad := fmt.Errorf("%w: %s", ErrBadRemote, remote)
+	if scheme, _, ok := strings.Cut(remote, "://"); ok {
+		u, err := url.Parse(remote)
+		if err != nil || u.Hostname() == "" {
+			return "", "", bad
+		}
+		host = strings.ToLower(u.Hostname())
+		switch port := u.Port(); strings.ToLower(scheme) {
+		case "https", "http":
+			if port != "" && port != map[string]string{"https": "443", "http": "80"}[strings.ToLower(scheme)] {
+				host = net.JoinHostPort(host, port)
+			}
+		case "ssh", "git+ssh", "ssh+git", "git":
+		default:
+			return "", "", bad
+		}
+		return host, u.Path, nil
+	}
+
+	// Like git, take a colon before any slash to mean the scp-like form, and anything else to be a path.
+	// An IPv6 address is bracketed, as in git@[::1]:repo.git.
+	login, path, ok := strings.Cut(remote, ":")
+	if open := strings.IndexByte(remote, '['); open >= 0 && open < len(login) {
+		if end := strings.Index(remote, "]:"); end > open {
+			login, path, ok = remote[:end+1], remote[end+2:], true
+		}
+	}
+	host = strings.Trim(login[strings.LastIndex(login, "@")+1:], "[]")
+	// A single letter before the colon is a Windows drive, as in C:/repos/app.
+	if !ok || len(host) <= 1 || strings.ContainsAny(login, `/\`) {
+		return "", "", bad
+	}
+	return strings.ToLower(host), path, nil
+}
@@ -0,0 +1,229 @@
+package forge
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sim-deos/plain/internal/secret"
+)
+
+const (
+	tokenAccount = "token"
+	hostsFile    = "hosts.json"
+
+	// credentialHelperTimeout bounds how long git's credential helpers may take to answer.
+	credentialHelperTimeout = 10 * time.Second
+)
+
+// Login records how the user authenticated with a forge host. It never contains the token itself.
+type Login struct {
+	Host     string    `json:"host"`
+	Kind     Kind      `json:"kind"`
+	Method   string    `json:"method"` // "token" for a personal access token, "device" for OAuth device flow
+	LoggedIn time.Time `json:"logged_in"`
+}
+
+// Tokens manages forge tokens, keeping the tokens in a [secret.Store] and a list of logins beside
+// plain's config so status can be shown without reading any secrets.
+type Tokens struct {
+	Store secret.Store
+	Dir   string            // Where the list of logins is kept
+	Env   map[string]string // By host, an environment variable holding its token, which [Tokens.Find] tries first
+}
+
+// NewTokens returns a token manager backed by the default secret store.
+func NewTokens() (*Tokens, error) {
+	store, err := secret.Default()
+	if err != nil {
+		return nil, err
+	}
+
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	return &Tokens{Store: store, Dir: filepath.Join(configDir, "plain")}, nil
+}
+
+// Token returns the stored token for host, or [secret.ErrNotFound] if the user hasn't logged in.
+func (t *Tokens) Token(host string) (string, error) {
+	return t.Store.Get(service(host), tokenAccount)
+}
+
+// Find returns a token for host, on a forge of the given kind, and where it was found. A token in the
+// environment variable Env names for host comes first. Then for GitHub, one in $GH_TOKEN or $GITHUB_TOKEN,
+// or $GH_ENTERPRISE_TOKEN or $GITHUB_ENTERPRISE_TOKEN for hosts other than github.com, as for the GitHub
+// CLI; for GitLab, one in $GITLAB_TOKEN. Then comes the token saved by plain auth login, then for GitHub
+// the one the GitHub CLI keeps in its hosts.yml, and last whatever password git's credential helpers
+// have for https://host. It fails with [secret.ErrNotFound] if there is none.
+func (t *Tokens) Find(host string, kind Kind) (token, source string, err error) {
+	var names []string
+	if name := t.Env[host]; name != "" {
+		names = append(names, name)
+	}
+	switch {
+	case kind == GitLab:
+		names = append(names, "GITLAB_TOKEN")
+	case kind == GitHub && host == "github.com":
+		names = append(names, "GH_TOKEN", "GITHUB_TOKEN")
+	case kind == GitHub:
+		names = append(names, "GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN")
+	}
+	for _, name := range names {
+		if token := os.Getenv(name); token != "" {
+			return token, "$" + name, nil
+		}
+	}
+
+	token, err = t.Token(host)
+	if err == nil {
+		return token, "plain auth login", nil
+	}
+	if !errors.Is(err, secret.ErrNotFound) {
+		return "", "", err
+	}
+
+	if kind == GitHub {
+		if token, err := ghToken(host); err != nil {
+			return "", "", err
+		} else if token != "" {
+			return token, "the GitHub CLI", nil
+		}
+	}
+	if token := credentialHelperToken(host); token != "" {
+		return token, "git credential helper", nil
+	}
+	return "", "", secret.ErrNotFound
+}
+
+// ghToken returns the token the GitHub CLI keeps for host in its hosts.yml, or "" if it has none there.
+// Newer versions of the CLI keep tokens in the OS keychain instead, where plain can't see them.
+func ghToken(host string) (string, error) {
+	dir := os.Getenv("GH_CONFIG_DIR")
+	if dir == "" {
+		if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+			dir = filepath.Join(xdg, "gh")
+		} else if appData := os.Getenv("AppData"); runtime.GOOS == "windows" && appData != "" {
+			dir = filepath.Join(appData, "GitHub CLI")
+		} else if home, err := os.UserHomeDir(); err == nil {
+			dir = filepath.Join(home, ".config", "gh")
+		}
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "hosts.yml"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	// hosts.yml maps each host to its settings, one level deep, so it is read line by line rather than
+	// with a YAML parser.
+	current := ""
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		key, value, _ := strings.Cut(strings.TrimSpace(line), ":")
+		value = strings.Trim(strings.TrimSpace(value), `"'`)
+		switch {
+		case line == "" || strings.HasPrefix(strings.TrimSpace(line), "#"):
+		case !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t"):
+			current = strings.Trim(key, `"'`)
+		case current == host && key == "oauth_token" && value != "":
+			return value, nil
+		}
+	}
+	return "", scanner.Err()
+}
+
+// credentialHelperToken asks git's credential helpers for the password to https://host, as git would
+// before an HTTPS fetch, and returns it, or "" if they have none. Nobody is prompted for one, and a
+// helper that hangs anyway is killed after credentialHelperTimeout.
+func credentialHelperToken(host string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), credentialHelperTimeout)
+	defer cancel()
+	// Empty askpass programs are skipped rather than run, so git has nothing to ask with and fails, where
+	// one that answers, like true, would have git take its empty answer as the password.
+	cmd := exec.CommandContext(ctx, "git", "-c", "core.askPass=", "credential", "fill")
+	cmd.WaitDelay = time.Second
+	cmd.Stdin = strings.NewReader("protocol=https\nhost=" + host + "\n\n")
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_ASKPASS=", "SSH_ASKPASS=", "GCM_INTERACTIVE=never")
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		if password, ok := strings.CutPrefix(line, "password="); ok {
+			return password
+		}
+	}
+	return ""
+}
+
+// Save stores token for the login's host, replacing any previous login.
+func (t *Tokens) Save(login Login, token string) error {
+	if err := t.Store.Set(service(login.Host), tokenAccount, token); err != nil {
+		return err
+	}
+
+	logins, err := t.Logins()
+	if err != nil {
+		return err
+	}
+	logins = slices.DeleteFunc(logins, func(l Login) bool { return l.Host == login.Host })
+	return t.saveLogins(append(logins, login))
+}
+
+// Delete removes the token and login for host.
+func (t *Tokens) Delete(host string) error {
+	if err := t.Store.Delete(service(host), tokenAccount); err != nil {
+		return err
+	}
+
+	logins, err := t.Logins()
+	if err != nil {
+		return err
+	}
+	return t.saveLogins(slices.DeleteFunc(logins, func(l Login) bool { return l.Host == host }))
+}
+
+// Logins returns every host the user has logged in to.
+func (t *Tokens) Logins() ([]Login, error) {
+	data, err := os.ReadFile(filepath.Join(t.Dir, hostsFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var logins []Login
+	err = json.Unmarshal(data, &logins)
+	return logins, err
+}
+
+func (t *Tokens) saveLogins(logins []Login) error {
+	data, err := json.MarshalIndent(logins, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(t.Dir, 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(t.Dir, hostsFile), data, 0o600)
+}
+
+func service(host string) string {
+	return "plain:" + host
+}
@@ -0,0 +1,106 @@
+package forge
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sim-deos/plain/internal/secret"
+)
+
+func TestTokens(t *testing.T) {
+	dir := t.TempDir()
+	store, err := secret.NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens := &Tokens{Store: store, Dir: dir}
+
+	if err := tokens.Save(Login{Host: "github.com", Kind: GitHub, Method: "token"}, "ghp_one"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tokens.Save(Login{Host: "github.com", Kind: GitHub, Method: "device"}, "ghp_two"); err != nil {
+		t.Fatal(err)
+	}
+
+	logins, err := tokens.Logins()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logins) != 1 || logins[0].Method != "device" {
+		t.Fatalf("expected a single replaced login, got %+v", logins)
+	}
+
+	token, err := tokens.Token("github.com")
+	if err != nil || token != "ghp_two" {
+		t.Fatalf("expected the latest token, got %q (%v)", token, err)
+	}
+
+	if err := tokens.Delete("github.com"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tokens.Token("github.com"); !errors.Is(err, secret.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after logout, got %v", err)
+	}
+}
+
+func TestFindToken(t *testing.T) {
+	dir := t.TempDir()
+	store, err := secret.NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens := &Tokens{Store: store, Dir: dir}
+	for _, name := range []string{"GH_TOKEN", "GITHUB_TOKEN", "GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN"} {
+		t.Setenv(name, "")
+	}
+	t.Setenv("GH_CONFIG_DIR", dir)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(dir, "gitconfig"))
+	t.Chdir(dir)
+
+	if _, _, err := tokens.Find("github.com", GitHub); !errors.Is(err, secret.ErrNotFound) {
+		t.Errorf("Find() with no token anywhere = %v, want %v", err, secret.ErrNotFound)
+	}
+
+	helper := "[credential]\n\thelper = \"!f() { test \\\"$1\\\" = get && printf \\\"username=ann\\\\npassword=from-helper\\\\n\\\"; }; f\"\n"
+	if err := os.WriteFile(filepath.Join(dir, "gitconfig"), []byte(helper), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	hosts := "github.com:\n    user: ann\n    oauth_token: gho_cli\n    git_protocol: https\nghe.example.com:\n    user: ann\n"
+	if err := os.WriteFile(filepath.Join(dir, "hosts.yml"), []byte(hosts), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		host, token, source string
+	}{
+		{"github.com", "gho_cli", "the GitHub CLI"},
+		{"ghe.example.com", "from-helper", "git credential helper"},
+	}
+	for _, c := range cases {
+		if token, source, err := tokens.Find(c.host, GitHub); err != nil || token != c.token || source != c.source {
+			t.Errorf("Find(%q) = %q, %q, %v, want %q from %s", c.host, token, source, err, c.token, c.source)
+		}
+	}
+
+	if err := tokens.Save(Login{Host: "github.com", Kind: GitHub, Method: "token"}, "ghp_saved"); err != nil {
+		t.Fatal(err)
+	}
+	if token, _, err := tokens.Find("github.com", GitHub); err != nil || token != "ghp_saved" {
+		t.Errorf("Find() after logging in = %q, %v, want the saved token", token, err)
+	}
+	t.Setenv("GITHUB_TOKEN", "ghp_env")
+	if token, source, err := tokens.Find("github.com", GitHub); err != nil || token != "ghp_env" || source != "$GITHUB_TOKEN" {
+		t.Errorf("Find() with $GITHUB_TOKEN set = %q, %q, %v, want the environment's token", token, source, err)
+	}
+	if token, _, _ := tokens.Find("ghe.example.com", GitHub); token != "from-helper" {
+		t.Errorf("Find() of an enterprise host = %q, want $GITHUB_TOKEN left to github.com", token)
+	}
+
+	t.Setenv("WORK_TOKEN", "ghp_work")
+	tokens.Env = map[string]string{"github.com": "WORK_TOKEN"}
+	if token, source, err := tokens.Find("github.com", GitHub); err != nil || token != "ghp_work" || source != "$WORK_TOKEN" {
+		t.Errorf("Find() with Env naming $WORK_TOKEN = %q, %q, %v, want its token first", token, source, err)
+	}
+}
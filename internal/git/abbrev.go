@@ -0,0 +1,112 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+var ErrAmbiguousRevision = errors.New("ambiguous revision")
+
+// MinAbbrev is the fewest digits an abbreviated hash may have, as in git.
+const MinAbbrev = 4
+
+// isAbbrevHash reports whether s could be an abbreviated hash: at least [MinAbbrev] hex digits, and
+// shorter than a full hash.
+func isAbbrevHash(s string) bool {
+	if len(s) < MinAbbrev || len(s) >= 64 {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') && (c < 'A' || c > 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+// objectsWithPrefix returns the hashes of the objects, loose or packed, that start with prefix, sorted.
+func (repo *Repository) objectsWithPrefix(prefix string) ([]string, error) {
+	prefix = strings.ToLower(prefix)
+	objectsPath := filepath.Join(repo.CommonDir, "objects")
+	entries, err := os.ReadDir(filepath.Join(objectsPath, prefix[:2]))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	var found []string
+	for _, entry := range entries {
+		if hash := prefix[:2] + entry.Name(); strings.HasPrefix(hash, prefix) && isFullHash(hash) {
+			found = append(found, hash)
+		}
+	}
+
+	packs, err := openPacks(objectsPath, repo.Format)
+	if err != nil {
+		return nil, err
+	}
+	defer packs.Close()
+	packs.hashes(func(hash string) {
+		if strings.HasPrefix(hash, prefix) {
+			found = append(found, hash)
+		}
+	})
+	slices.Sort(found)
+	return slices.Compact(found), nil
+}
+
+// resolveAbbrev returns the object whose hash starts with prefix. More than one fails with
+// [ErrAmbiguousRevision], listing each candidate the way git does so the user can pick one.
+func (repo *Repository) resolveAbbrev(prefix string) (string, error) {
+	found, err := repo.objectsWithPrefix(prefix)
+	if err != nil {
+		return "", err
+	}
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("%w: %s", ErrUnknownRevision, prefix)
+	case 1:
+		return found[0], nil
+	}
+
+	r, err := newCommitReader(repo)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	var b strings.Builder
+	for _, hash := range found {
+		b.WriteString("\n  " + describeCandidate(r, hash, max(8, len(prefix)+4)))
+	}
+	return "", fmt.Errorf("%w: short object ID %s matches %d objects:%s", ErrAmbiguousRevision, prefix, len(found), b.String())
+}
+
+// describeCandidate returns a line telling an object apart from others with the same prefix, like
+// "1a2b3c4d commit 2024-03-01 - Fix login", showing width digits of its hash.
+func describeCandidate(r *commitReader, hash string, width int) string {
+	short := hash[:min(width, len(hash))]
+	header, closer, err := r.open(hash)
+	if err != nil {
+		return short + " (unreadable)"
+	}
+	if header.Kind == TagObject {
+		tag, err := r.d.DecodeTag(hash)
+		closer.Close()
+		if err == nil {
+			return fmt.Sprintf("%s tag %s", short, tag.Name)
+		}
+		return fmt.Sprintf("%s %s", short, header.Kind)
+	}
+	closer.Close()
+
+	if header.Kind == CommitObject {
+		if commit, ok, err := r.read(hash); err == nil && ok {
+			subject, _, _ := strings.Cut(commit.Message, "\n")
+			return fmt.Sprintf("%s commit %s - %s", short, commit.Committer.Time.Format("2006-01-02"), subject)
+		}
+	}
+	return fmt.Sprintf("%s %s", short, header.Kind)
+}
@@ -0,0 +1,51 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestResolveAbbreviated(t *testing.T) {
+	gitDir := newTestRepo(t)
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit := writeTestCommit(t, gitDir, "Fix login")
+	writeTestRef(t, gitDir, "refs/heads/main", commit)
+
+	for _, rev := range []string{commit[:4], commit[:12], strings.ToUpper(commit[:7])} {
+		if hash, err := repo.ResolveRevision(rev); err != nil || hash != commit {
+			t.Errorf("ResolveRevision(%s) = %s, %v, want %s", rev, hash, err, commit)
+		}
+	}
+	if _, err := repo.ResolveRevision(commit[:3]); !errors.Is(err, ErrUnknownRevision) {
+		t.Errorf("a three digit abbreviation: got %v", err)
+	}
+
+	// Write blobs until two share their first four digits with each other.
+	byPrefix := map[string]string{}
+	var prefix string
+	for i := 0; prefix == ""; i++ {
+		hash := writeTestObject(t, gitDir, BlobObject, fmt.Sprintf("blob %d\n", i))
+		if _, ok := byPrefix[hash[:4]]; ok {
+			prefix = hash[:4]
+		}
+		byPrefix[hash[:4]] = hash
+	}
+	_, err = repo.ResolveRevision(prefix)
+	if !errors.Is(err, ErrAmbiguousRevision) || strings.Count(err.Error(), " blob") != 2 {
+		t.Errorf("an ambiguous abbreviation: got %v", err)
+	}
+	if hash, err := repo.ResolveRevision(byPrefix[prefix][:12]); err != nil || hash != byPrefix[prefix] {
+		t.Errorf("a longer abbreviation = %s, %v, want %s", hash, err, byPrefix[prefix])
+	}
+
+	// A branch named like a hash wins over the abbreviation, as in git.
+	writeTestRef(t, gitDir, "refs/heads/"+prefix, commit)
+	if hash, err := repo.ResolveRevision(prefix); err != nil || hash != commit {
+		t.Errorf("ResolveRevision(%s) with a branch of that name = %s, %v", prefix, hash, err)
+	}
+}
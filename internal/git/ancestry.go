@@ -0,0 +1,435 @@
+package git
+
+import (
+	"container/heap"
+	"maps"
+	"slices"
+	"time"
+)
+
+// MaxAncestryChains is the most chains [AncestryChains] returns. Every merge between two commits can
+// double the chains between them, so there may be far more than could ever be shown.
+const MaxAncestryChains = 1000
+
+// AncestryChains returns the chains of commits in graph that lead from the commit from to the commit to.
+//
+// Each chain starts with from and ends with to, following child links forward through history, like the
+// commits shown by git log --ancestry-path, through the parents lineage selects. At most limit chains
+// are returned; a limit of zero or less returns them all, up to [MaxAncestryChains]. No chains are
+// returned if from is not an ancestor of to.
+func AncestryChains(graph map[string]Commit, from, to string, limit int, lineage Lineage) [][]string {
+	if limit <= 0 || limit > MaxAncestryChains {
+		limit = MaxAncestryChains
+	}
+
+	// reaches records whether a commit has from among its ancestors (or is from itself)
+	reaches := map[string]bool{}
+	var canReach func(hash string) bool
+	canReach = func(hash string) bool {
+		if result, ok := reaches[hash]; ok {
+			return result
+		}
+		reaches[hash] = false // guards against revisiting while the walk is in progress
+
+		commit, ok := graph[hash]
+		result := hash == from
+		if ok && !result {
+			for _, parent := range lineage.parents(commit) {
+				if canReach(parent) {
+					result = true
+				}
+			}
+		}
+		reaches[hash] = result
+		return result
+	}
+
+	if !canReach(to) {
+		return nil
+	}
+
+	var chains [][]string
+	var walk func(hash string, chain []string) bool
+	walk = func(hash string, chain []string) bool {
+		chain = append(chain, hash)
+		if hash == from {
+			found := slices.Clone(chain)
+			slices.Reverse(found)
+			chains = append(chains, found)
+			return len(chains) < limit
+		}
+
+		for _, parent := range lineage.parents(graph[hash]) {
+			if reaches[parent] && !walk(parent, chain) {
+				return false
+			}
+		}
+		return true
+	}
+	walk(to, nil)
+
+	return chains
+}
+
+// Lineage chooses which parents of a merge a walk through history follows.
+type Lineage int
+
+const (
+	AllParents  Lineage = iota // Every parent, so commits merged in from other branches are included
+	FirstParent                // Only the first, the line the merges were made on, like git log --first-parent
+)
+
+// parents returns the parents of commit to follow.
+func (l Lineage) parents(commit Commit) []string {
+	if l == FirstParent && len(commit.Parents) > 1 {
+		return commit.Parents[:1]
+	}
+	return commit.Parents
+}
+
+// CommitsBetween returns the commits reachable from head but not from base, newest first, like
+// git log base..head. Both may be any revision accepted by [GetHistoryForRevision]. An empty base
+// returns every commit reachable from head.
+func (repo *Repository) CommitsBetween(base, head string) ([]Commit, error) {
+	return repo.Log(base, head, AllParents)
+}
+
+// Log returns the commits reachable from head but not from base, newest first, like [CommitsBetween],
+// following the parents lineage selects from head. Everything base can reach is left out whichever
+// parents lead to it, so with [FirstParent] a feature that merged base in lists only its own commits and
+// the merges, like git log --first-parent base..head. Like [Repository.AheadBehind], the walk stops at
+// the history head shares with base rather than reading all of it.
+func (repo *Repository) Log(base, head string, lineage Lineage) ([]Commit, error) {
+	headHash, err := repo.ResolveRevision(head)
+	if err != nil {
+		return nil, err
+	}
+	if base == "" {
+		headHistory, err := repo.lineageFrom(headHash, lineage)
+		if err != nil {
+			return nil, err
+		}
+		return topoOrder(headHistory.Graph), nil
+	}
+	baseHash, err := repo.ResolveRevision(base)
+	if err != nil {
+		return nil, err
+	}
+
+	shallow, err := readShallow(repo.CommonDir)
+	if err != nil {
+		return nil, err
+	}
+	r, err := newCommitReader(repo)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	if headHash, err = r.peel(headHash); err != nil {
+		return nil, err
+	}
+	if baseHash, err = r.peel(baseHash); err != nil {
+		return nil, err
+	}
+	unique, _, err := r.freshCommits([]string{headHash}, []string{baseHash}, shallow)
+	if err != nil {
+		return nil, err
+	}
+
+	if lineage == FirstParent {
+		// Once the first-parent line reaches a commit base has, base has the rest of it too.
+		line := map[string]Commit{}
+		for commit, ok := unique[headHash]; ok; {
+			line[commit.Hash] = commit
+			if len(commit.Parents) == 0 {
+				break
+			}
+			commit, ok = unique[commit.Parents[0]]
+		}
+		unique = line
+	}
+	return topoOrder(unique), nil
+}
+
+// ReachableCommits returns every commit reachable from HEAD or any ref, newest first like git log --all
+// --topo-order.
+func (repo *Repository) ReachableCommits() ([]Commit, error) {
+	r, err := newCommitReader(repo)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	tips, err := repo.refTips(r)
+	if err != nil {
+		return nil, err
+	}
+
+	graph := map[string]Commit{}
+	for _, tip := range tips {
+		if _, ok := graph[tip]; ok {
+			continue
+		}
+		// Tags can point at trees and blobs, which have no history.
+		_, isCommit, err := r.read(tip)
+		if err != nil {
+			return nil, err
+		}
+		if !isCommit {
+			continue
+		}
+		history, err := repo.historyFrom(tip)
+		if err != nil {
+			return nil, err
+		}
+		maps.Copy(graph, history.Graph)
+	}
+	return topoOrder(graph), nil
+}
+
+// AheadBehind counts the commits reachable from local but not upstream (ahead), and from upstream but
+// not local (behind), like git rev-list --left-right --count local...upstream. Like [commitReader.freshCommits],
+// the walks stop at the history the two share, so the count costs about as much as the commits counted.
+func (repo *Repository) AheadBehind(local, upstream string) (ahead, behind int, err error) {
+	var hashes [2]string
+	for i, rev := range []string{local, upstream} {
+		if hashes[i], err = repo.ResolveRevision(rev); err != nil {
+			return 0, 0, err
+		}
+	}
+	shallow, err := readShallow(repo.CommonDir)
+	if err != nil {
+		return 0, 0, err
+	}
+	r, err := newCommitReader(repo)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer r.Close()
+
+	var counts [2]int
+	for i := range hashes {
+		fresh, _, err := r.freshCommits(hashes[i:i+1], hashes[1-i:2-i], shallow)
+		if err != nil {
+			return 0, 0, err
+		}
+		counts[i] = len(fresh)
+	}
+	return counts[0], counts[1], nil
+}
+
+// topoOrder sorts commits so that every commit comes before its parents, preferring the most recently
+// committed commit whenever several are ready, like git log --topo-order.
+func topoOrder(graph map[string]Commit) []Commit {
+	children := map[string]int{}
+	for _, commit := range graph {
+		for _, parent := range commit.Parents {
+			if _, ok := graph[parent]; ok {
+				children[parent]++
+			}
+		}
+	}
+
+	ready := &commitQueue{}
+	for hash, commit := range graph {
+		if children[hash] == 0 {
+			*ready = append(*ready, commit)
+		}
+	}
+	heap.Init(ready)
+
+	ordered := make([]Commit, 0, len(graph))
+	for ready.Len() > 0 {
+		// The queue pops the newest ready commit, breaking ties by hash so the order is stable between runs.
+		commit := heap.Pop(ready).(Commit)
+		ordered = append(ordered, commit)
+
+		for _, parent := range commit.Parents {
+			if _, ok := graph[parent]; !ok {
+				continue
+			}
+			children[parent]--
+			if children[parent] == 0 {
+				heap.Push(ready, graph[parent])
+			}
+		}
+	}
+	return ordered
+}
+
+// commitQueue holds commits newest first, for walks through history that stop once what's left is
+// older than what they look for.
+type commitQueue []Commit
+
+func (q commitQueue) Len() int { return len(q) }
+func (q commitQueue) Less(i, j int) bool {
+	if c := q[i].Committer.Time.Compare(q[j].Committer.Time); c != 0 {
+		return c > 0
+	}
+	return q[i].Hash < q[j].Hash
+}
+func (q commitQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *commitQueue) Push(x any)   { *q = append(*q, x.(Commit)) }
+func (q *commitQueue) Pop() any {
+	old := *q
+	commit := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return commit
+}
+
+// clockSkew is how far a commit's date may be before its parent's, for clocks that were off, before a
+// walk newest first misses it.
+const clockSkew = 24 * time.Hour
+
+// isAncestor reports whether the commit ancestor can be reached from the commit descendant, like git
+// merge-base --is-ancestor. Rather than reading all of history, the walk back from descendant goes
+// newest first and stops once every commit left was committed well before ancestor.
+func (repo *Repository) isAncestor(ancestor, descendant string) (bool, error) {
+	shallow, err := readShallow(repo.CommonDir)
+	if err != nil {
+		return false, err
+	}
+	r, err := newCommitReader(repo)
+	if err != nil {
+		return false, err
+	}
+	defer r.Close()
+
+	var commits [2]Commit
+	for i, hash := range []string{ancestor, descendant} {
+		if hash, err = r.peel(hash); err != nil {
+			return false, err
+		}
+		commit, ok, err := r.read(hash)
+		if err != nil || !ok {
+			return false, err
+		}
+		commits[i] = commit
+	}
+	target, cutoff := commits[0].Hash, commits[0].Committer.Time.Add(-clockSkew)
+
+	queue := &commitQueue{commits[1]}
+	seen := map[string]bool{commits[1].Hash: true}
+	for queue.Len() > 0 {
+		commit := heap.Pop(queue).(Commit)
+		if commit.Hash == target {
+			return true, nil
+		}
+		if commit.Committer.Time.Before(cutoff) || shallow[commit.Hash] {
+			continue
+		}
+		for _, parent := range commit.Parents {
+			if seen[parent] {
+				continue
+			}
+			seen[parent] = true
+			next, ok, err := r.read(parent)
+			if err != nil {
+				return false, err
+			}
+			if ok {
+				heap.Push(queue, next)
+			}
+		}
+	}
+	return false, nil
+}
+
+// freshCommits returns the commits reachable from tips but not from haves, like git rev-list tips --not
+// haves, along with the commits reachable from haves that they have as parents, which are what they
+// build on. Rather than reading all of history, both are walked together newest first, and the walk
+// stops once everything left is reachable from haves and was committed well before the oldest fresh
+// commit. The walk doesn't go past shallow commits; haves that aren't commits are ignored.
+func (r *commitReader) freshCommits(tips, haves []string, shallow map[string]bool) (fresh, boundary map[string]Commit, err error) {
+	seen := map[string]Commit{}
+	known := map[string]bool{}  // Reachable from haves
+	queued := map[string]bool{} // Waiting in the queue, to have their parents walked
+	pending := 0                // Queued commits that aren't known
+	queue := &commitQueue{}
+
+	// markKnown marks hash, and whatever of its history has been seen, as reachable from haves.
+	markKnown := func(hash string) {
+		stack := []string{hash}
+		for len(stack) > 0 {
+			hash := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if known[hash] {
+				continue
+			}
+			known[hash] = true
+			if queued[hash] {
+				pending--
+			}
+			for _, parent := range seen[hash].Parents {
+				if _, ok := seen[parent]; ok {
+					stack = append(stack, parent)
+				}
+			}
+		}
+	}
+	visit := func(hash string, isKnown bool) error {
+		if _, ok := seen[hash]; ok {
+			if isKnown {
+				markKnown(hash)
+			}
+			return nil
+		}
+		commit, ok, err := r.read(hash)
+		if err != nil || !ok {
+			return err
+		}
+		seen[hash], queued[hash], known[hash] = commit, true, isKnown
+		if !isKnown {
+			pending++
+		}
+		heap.Push(queue, commit)
+		return nil
+	}
+	for _, hash := range haves {
+		if err := visit(hash, true); err != nil {
+			return nil, nil, err
+		}
+	}
+	for _, hash := range tips {
+		if err := visit(hash, false); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var cutoff time.Time
+	for queue.Len() > 0 {
+		if pending == 0 && (cutoff.IsZero() || (*queue)[0].Committer.Time.Before(cutoff)) {
+			break
+		}
+		commit := heap.Pop(queue).(Commit)
+		delete(queued, commit.Hash)
+		isKnown := known[commit.Hash]
+		if !isKnown {
+			pending--
+			if at := commit.Committer.Time.Add(-clockSkew); cutoff.IsZero() || at.Before(cutoff) {
+				cutoff = at
+			}
+		}
+		if shallow[commit.Hash] {
+			continue
+		}
+		for _, parent := range commit.Parents {
+			if err := visit(parent, isKnown); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	fresh, boundary = map[string]Commit{}, map[string]Commit{}
+	for hash, commit := range seen {
+		if known[hash] || queued[hash] {
+			continue
+		}
+		fresh[hash] = commit
+		for _, parent := range commit.Parents {
+			if known[parent] {
+				boundary[parent] = seen[parent]
+			}
+		}
+	}
+	return fresh, boundary, nil
+}
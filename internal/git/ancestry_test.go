@@ -0,0 +1,277 @@
+package git
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestAncestryChains(t *testing.T) {
+	// a <- b <- d <- e
+	//  \-- c <-/
+	// x is unrelated to a
+	graph := map[string]Commit{
+		"a": {Hash: "a"},
+		"b": {Hash: "b", Parents: []string{"a"}},
+		"c": {Hash: "c", Parents: []string{"a"}},
+		"d": {Hash: "d", Parents: []string{"b", "c"}},
+		"e": {Hash: "e", Parents: []string{"d"}},
+		"x": {Hash: "x"},
+	}
+
+	chains := AncestryChains(graph, "a", "e", 0, AllParents)
+	expected := [][]string{{"a", "b", "d", "e"}, {"a", "c", "d", "e"}}
+	if !slices.EqualFunc(chains, expected, slices.Equal) {
+		t.Fatalf("expected chains %v, got %v", expected, chains)
+	}
+
+	if firstParent := AncestryChains(graph, "a", "e", 0, FirstParent); !slices.EqualFunc(firstParent, expected[:1], slices.Equal) {
+		t.Fatalf("expected chains %v following first parents, got %v", expected[:1], firstParent)
+	}
+
+	if limited := AncestryChains(graph, "a", "e", 1, AllParents); len(limited) != 1 {
+		t.Fatalf("expected 1 chain with a limit of 1, got %d", len(limited))
+	}
+
+	if none := AncestryChains(graph, "x", "e", 0, AllParents); none != nil {
+		t.Fatalf("expected no chains for an unrelated commit, got %v", none)
+	}
+
+	// Forty merges in a row make 2^40 chains, of which only the first MaxAncestryChains are returned.
+	graph = map[string]Commit{"0": {Hash: "0"}}
+	prev := "0"
+	for i := range 40 {
+		left, right, merge := fmt.Sprint(i, "l"), fmt.Sprint(i, "r"), fmt.Sprint(i, "m")
+		graph[left] = Commit{Hash: left, Parents: []string{prev}}
+		graph[right] = Commit{Hash: right, Parents: []string{prev}}
+		graph[merge] = Commit{Hash: merge, Parents: []string{left, right}}
+		prev = merge
+	}
+	if chains := AncestryChains(graph, "0", prev, 0, AllParents); len(chains) != MaxAncestryChains {
+		t.Fatalf("expected %d chains, got %d", MaxAncestryChains, len(chains))
+	}
+}
+
+func TestCommitsBetween(t *testing.T) {
+	gitDir := newTestRepo(t)
+	root := writeTestCommit(t, gitDir, "root")
+	base := writeTestCommit(t, gitDir, "base", root)
+	first := writeTestCommit(t, gitDir, "first", root)
+	feature := writeTestCommit(t, gitDir, "feature", first)
+	writeTestRef(t, gitDir, "refs/heads/main", base)
+	writeTestRef(t, gitDir, "refs/heads/feature", feature)
+
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commits, err := repo.CommitsBetween("main", "feature")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Both commits share a timestamp, so only the topological order decides which comes first.
+	if len(commits) != 2 || commits[0].Hash != feature || commits[1].Hash != first {
+		t.Fatalf("expected the feature commits newest first, got %v", commits)
+	}
+}
+
+func TestLog(t *testing.T) {
+	// main <- first <- merge <- last   (feature)
+	//     \-- side <-/
+	gitDir := newTestRepo(t)
+	root := writeTestCommit(t, gitDir, "root")
+	side := writeTestCommit(t, gitDir, "side", root)
+	first := writeTestCommit(t, gitDir, "first", root)
+	merge := writeTestCommit(t, gitDir, "merge", first, side)
+	last := writeTestCommit(t, gitDir, "last", merge)
+	writeTestRef(t, gitDir, "refs/heads/main", root)
+	writeTestRef(t, gitDir, "refs/heads/feature", last)
+
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		lineage Lineage
+		want    []string
+	}{
+		{AllParents, []string{"last", "merge", "first", "side"}},
+		{FirstParent, []string{"last", "merge", "first"}},
+	} {
+		commits, err := repo.Log("main", "feature", test.lineage)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, commit := range commits {
+			got = append(got, commit.Message)
+		}
+		slices.Sort(got[2:])
+		slices.Sort(test.want[2:])
+		if !slices.Equal(got, test.want) {
+			t.Errorf("Log with lineage %d = %q, want %q", test.lineage, got, test.want)
+		}
+	}
+
+	// Commits base can reach are left out even when only a second parent leads to them.
+	writeTestRef(t, gitDir, "refs/heads/main", side)
+	commits, err := repo.Log("main", "feature", FirstParent)
+	if err != nil || len(commits) != 3 {
+		t.Errorf("Log from side = %d commits, %v, want last, merge, and first", len(commits), err)
+	}
+}
+
+func TestAheadBehind(t *testing.T) {
+	gitDir := newTestRepo(t)
+	root := writeTestCommit(t, gitDir, "root")
+	upstream := writeTestCommit(t, gitDir, "upstream", root)
+	first := writeTestCommit(t, gitDir, "first", root)
+	local := writeTestCommit(t, gitDir, "local", first)
+	writeTestRef(t, gitDir, "refs/heads/feature", local)
+	writeTestRef(t, gitDir, "refs/remotes/origin/feature", upstream)
+
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ahead, behind, err := repo.AheadBehind("feature", "origin/feature")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ahead != 2 || behind != 1 {
+		t.Fatalf("expected 2 ahead and 1 behind, got %d and %d", ahead, behind)
+	}
+}
+
+func TestReachableCommits(t *testing.T) {
+	gitDir := newTestRepo(t)
+	root := writeTestCommit(t, gitDir, "root")
+	main := writeTestCommit(t, gitDir, "main", root)
+	feature := writeTestCommit(t, gitDir, "feature", root)
+	writeTestCommit(t, gitDir, "unreachable", root)
+	writeTestRef(t, gitDir, "refs/heads/main", main)
+	writeTestRef(t, gitDir, "refs/tags/v1", feature)
+	writeTestRef(t, gitDir, "HEAD", "ref: refs/heads/main")
+
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commits, err := repo.ReachableCommits()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 3 || commits[2].Hash != root {
+		t.Fatalf("expected the three reachable commits ending with the root, got %v", commits)
+	}
+
+	refs, err := repo.Refs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(refs) != 2 || refs["refs/tags/v1"] != feature {
+		t.Fatalf("unexpected refs %v", refs)
+	}
+}
+
+func TestOctopusMerge(t *testing.T) {
+	// root <- a <-\
+	//     \-- b <- m <- head
+	//      \- c <-/
+	gitDir := newTestRepo(t)
+	root := writeTestCommit(t, gitDir, "root")
+	a := writeTestCommit(t, gitDir, "a", root)
+	b := writeTestCommit(t, gitDir, "b", root)
+	c := writeTestCommit(t, gitDir, "c", root)
+	m := writeTestCommit(t, gitDir, "m", a, b, c)
+	head := writeTestCommit(t, gitDir, "head", m)
+	writeTestRef(t, gitDir, "refs/heads/main", head)
+	writeTestRef(t, gitDir, "HEAD", "ref: refs/heads/main")
+
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	merge, err := repo.ReadCommit(m)
+	if err != nil || !slices.Equal(merge.Parents, []string{a, b, c}) {
+		t.Fatalf("octopus merge read with parents %v, %v", merge.Parents, err)
+	}
+
+	// Every parent is followed, not just the first two.
+	commits, err := repo.CommitsBetween(a, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hashes []string
+	for _, commit := range commits {
+		hashes = append(hashes, commit.Hash)
+	}
+	if len(hashes) != 4 || hashes[0] != head || hashes[1] != m || !slices.Contains(hashes, b) || !slices.Contains(hashes, c) {
+		t.Errorf("commits between a and main are %v, want head, m, b, and c", hashes)
+	}
+
+	if base, err := repo.MergeBase(c, "main"); err != nil || base != c {
+		t.Errorf("merge base of the third parent and main is %s, %v, want %s", base, err, c)
+	}
+
+	history, err := repo.historyFrom(head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chains := AncestryChains(history.Graph, root, head, 0, AllParents); len(chains) != 3 {
+		t.Errorf("found %d chains from root through the octopus merge, want 3", len(chains))
+	}
+
+	visited := map[string]bool{}
+	err = repo.WalkObjects([]string{"main"}, ObjectVisitor{
+		OnCommit: func(commit Commit) error { visited[commit.Hash] = true; return nil },
+	})
+	if err != nil || len(visited) != 6 {
+		t.Errorf("walking main visited %d commits, %v, want 6", len(visited), err)
+	}
+}
+
+func TestFreshCommits(t *testing.T) {
+	// root <- a <- b (main)
+	//          \-- f1 <- f2 <- m (feature, merging main)
+	gitDir := newTestRepo(t)
+	root := writeTestCommit(t, gitDir, "root")
+	a := writeTestCommit(t, gitDir, "a", root)
+	b := writeTestCommit(t, gitDir, "b", a)
+	f1 := writeTestCommit(t, gitDir, "f1", a)
+	f2 := writeTestCommit(t, gitDir, "f2", f1)
+	m := writeTestCommit(t, gitDir, "m", f2, b)
+
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := newCommitReader(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	fresh, boundary, err := r.freshCommits([]string{m}, []string{b}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := slices.Sorted(maps.Keys(fresh)); !slices.Equal(got, slices.Sorted(slices.Values([]string{f1, f2, m}))) {
+		t.Errorf("fresh commits = %v, want f1, f2, and m", got)
+	}
+	if got := slices.Sorted(maps.Keys(boundary)); !slices.Equal(got, slices.Sorted(slices.Values([]string{a, b}))) {
+		t.Errorf("boundary = %v, want a and b", got)
+	}
+
+	if fresh, _, err := r.freshCommits([]string{f2}, []string{m}, nil); err != nil || len(fresh) != 0 {
+		t.Errorf("freshCommits() of a tip the haves reach = %v, %v, want none", fresh, err)
+	}
+	if fresh, boundary, err := r.freshCommits([]string{f1}, nil, nil); err != nil || len(fresh) != 3 || len(boundary) != 0 {
+		t.Errorf("freshCommits() without haves = %v, %v, %v, want the whole history", fresh, boundary, err)
+	}
+}
@@ -0,0 +1,288 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sim-deos/plain/internal/diff"
+)
+
+var (
+	ErrApplyConflict = errors.New("patch applied with conflicts")
+	ErrPatchMismatch = errors.New("patch does not match the files it changes")
+)
+
+// ApplyTarget is a set of flags for what [Repository.ApplyPatches] changes.
+type ApplyTarget int
+
+const (
+	ApplyWorkTree ApplyTarget = 1 << iota // Files in the work tree are patched
+	ApplyIndex                            // Staged files are patched
+
+	// Both patches the index and writes the result to the work tree too, like git apply --index.
+	ApplyBoth = ApplyWorkTree | ApplyIndex
+)
+
+// ApplyOptions controls how [Repository.ApplyPatches] applies patches.
+type ApplyOptions struct {
+	Target ApplyTarget
+	Fuzz   int // Context lines each hunk may ignore at either end, see [diff.Apply]
+
+	// ThreeWay falls back to a three-way merge for files whose hunks don't apply, using the blob the
+	// patch was made against (from its index line) as the merge base, like git apply --3way.
+	ThreeWay bool
+}
+
+// patchedFile is the outcome of applying one file patch, ready to be written.
+type patchedFile struct {
+	path      string
+	mode      FileMode
+	content   []byte
+	remove    string    // A path to remove, for deletions and renames
+	conflict  [3][]byte // Base, ours, and theirs when the three-way merge conflicted
+	conflicts int
+}
+
+// ApplyPatches applies patches to the work tree, the index, or both, without running git apply.
+//
+// Files are read from the index when it is a target, and from the work tree otherwise. Every patch is
+// applied in memory before anything is written, so a patch that doesn't apply leaves everything as it
+// was and fails with an error wrapping [ErrPatchMismatch] or [diff.ErrHunkFailed]. Binary patches aren't
+// supported.
+//
+// With opts.ThreeWay, files whose merge conflicts are still written, with conflict markers in the work
+// tree and their base, ours, and theirs versions staged as conflict stages, and [ErrApplyConflict]
+// is returned naming them.
+func (repo *Repository) ApplyPatches(patches []diff.FilePatch, opts ApplyOptions) error {
+	if opts.Target == 0 {
+		opts.Target = ApplyWorkTree
+	}
+
+	var idx *Index
+	if opts.Target&ApplyIndex != 0 {
+		var err error
+		if idx, err = repo.Index(); err != nil {
+			return err
+		}
+	}
+
+	var results []patchedFile
+	for _, patch := range patches {
+		result, err := repo.applyFilePatch(&patch, idx, opts)
+		if err != nil {
+			return fmt.Errorf("%s: %w", patch.Path(), err)
+		}
+		results = append(results, result)
+	}
+
+	var conflicted []string
+	for _, result := range results {
+		if err := repo.writePatched(result, idx, opts.Target); err != nil {
+			return err
+		}
+		if result.conflicts > 0 {
+			conflicted = append(conflicted, result.path)
+		}
+	}
+	if idx != nil {
+		if err := repo.WriteIndex(idx); err != nil {
+			return err
+		}
+	}
+
+	if len(conflicted) > 0 {
+		return fmt.Errorf("%w in %s", ErrApplyConflict, strings.Join(conflicted, ", "))
+	}
+	return nil
+}
+
+func (repo *Repository) applyFilePatch(patch *diff.FilePatch, idx *Index, opts ApplyOptions) (patchedFile, error) {
+	if patch.Binary {
+		return patchedFile{}, errors.New("binary patches are not supported")
+	}
+
+	result := patchedFile{path: patch.NewPath, mode: FileMode(patch.NewMode)}
+	if patch.IsDeleted() || patch.IsRename() {
+		result.remove = patch.OldPath
+	}
+
+	var current []byte
+	if patch.IsNew() {
+		_, exists, err := repo.readPatchTarget(patch.NewPath, idx)
+		if err != nil {
+			return patchedFile{}, err
+		}
+		if exists {
+			return patchedFile{}, fmt.Errorf("%w: it already exists", ErrPatchMismatch)
+		}
+	} else {
+		content, exists, err := repo.readPatchTarget(patch.OldPath, idx)
+		if err != nil {
+			return patchedFile{}, err
+		}
+		if !exists {
+			return patchedFile{}, fmt.Errorf("%w: it does not exist", ErrPatchMismatch)
+		}
+		current = content.data
+		if result.mode == 0 {
+			result.mode = content.mode
+		}
+	}
+	if result.mode == 0 {
+		result.mode = ModeFile
+	}
+
+	patched, err := diff.Apply(current, patch.Hunks, opts.Fuzz)
+	if err != nil && opts.ThreeWay && !patch.IsNew() {
+		var base []byte
+		if base, err = repo.readAbbreviatedBlob(patch.OldHash); err != nil {
+			return patchedFile{}, fmt.Errorf("cannot fall back to a three-way merge: %w", err)
+		}
+		theirs, err := diff.Apply(base, patch.Hunks, 0)
+		if err != nil {
+			return patchedFile{}, fmt.Errorf("%w: the patch doesn't apply to its own base %s", ErrPatchMismatch, patch.OldHash)
+		}
+		patched, result.conflicts = diff.Merge3(base, current, theirs, "ours", "theirs")
+		result.conflict = [3][]byte{base, current, theirs}
+	}
+	if err != nil {
+		return patchedFile{}, err
+	}
+
+	if patch.IsDeleted() {
+		if len(patched) != 0 {
+			return patchedFile{}, fmt.Errorf("%w: the file isn't empty after removing its lines", ErrPatchMismatch)
+		}
+		result.path = ""
+	}
+	result.content = patched
+	return result, nil
+}
+
+type patchTarget struct {
+	data []byte
+	mode FileMode
+}
+
+// readPatchTarget reads the file a patch applies to, from the index if idx is set or else the work tree.
+func (repo *Repository) readPatchTarget(path string, idx *Index) (patchTarget, bool, error) {
+	if idx != nil {
+		entry, ok := idx.Entry(path)
+		if !ok {
+			return patchTarget{}, false, nil
+		}
+		data, err := repo.ReadBlob(entry.Hash)
+		return patchTarget{data, entry.Mode}, true, err
+	}
+
+	full := filepath.Join(repo.WorkTree, filepath.FromSlash(path))
+	info, err := os.Lstat(full)
+	if errors.Is(err, fs.ErrNotExist) {
+		return patchTarget{}, false, nil
+	}
+	if err != nil {
+		return patchTarget{}, false, err
+	}
+	mode := worktreeMode(info)
+	if mode == ModeSymlink {
+		target, err := os.Readlink(full)
+		return patchTarget{[]byte(filepath.ToSlash(target)), mode}, true, err
+	}
+	data, err := os.ReadFile(full)
+	return patchTarget{data, mode}, true, err
+}
+
+// writePatched writes one patched file to the targets, removing the old path of a rename or deletion.
+func (repo *Repository) writePatched(result patchedFile, idx *Index, target ApplyTarget) error {
+	if result.remove != "" {
+		if target&ApplyWorkTree != 0 {
+			if err := os.Remove(filepath.Join(repo.WorkTree, filepath.FromSlash(result.remove))); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+		}
+		if idx != nil {
+			idx.Remove(result.remove)
+		}
+	}
+	if result.path == "" {
+		return nil
+	}
+
+	if target&ApplyWorkTree != 0 {
+		if err := repo.writeWorktreeFile(result.path, result.mode, result.content); err != nil {
+			return err
+		}
+	}
+	if idx == nil {
+		return nil
+	}
+
+	if result.conflicts > 0 {
+		idx.Remove(result.path)
+		for stage, content := range result.conflict {
+			hash, err := repo.Encoder().Encode(BlobObject, content)
+			if err != nil {
+				return err
+			}
+			idx.Add(IndexEntry{Path: result.path, Mode: result.mode, Hash: hash, Stage: stage + 1})
+		}
+		return nil
+	}
+	if target&ApplyWorkTree != 0 {
+		// Staging from the work tree records its stat data, so status doesn't rehash the file.
+		return repo.StagePath(idx, result.path)
+	}
+	hash, err := repo.Encoder().Encode(BlobObject, result.content)
+	if err != nil {
+		return err
+	}
+	idx.Add(IndexEntry{Path: result.path, Mode: result.mode, Hash: hash, Size: uint32(len(result.content))})
+	return nil
+}
+
+// writeWorktreeFile writes content to path in the work tree as a file or symlink according to mode.
+func (repo *Repository) writeWorktreeFile(path string, mode FileMode, content []byte) error {
+	full := filepath.Join(repo.WorkTree, filepath.FromSlash(path))
+	if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+		return err
+	}
+	if err := os.Remove(full); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if mode == ModeSymlink {
+		return os.Symlink(filepath.FromSlash(string(content)), full)
+	}
+
+	perm := fs.FileMode(0o644)
+	if mode == ModeExecutable {
+		perm = 0o755
+	}
+	return os.WriteFile(full, content, perm)
+}
+
+// readAbbreviatedBlob reads the blob whose hash starts with prefix, as found on a patch's index line.
+// Only loose objects are searched.
+func (repo *Repository) readAbbreviatedBlob(prefix string) ([]byte, error) {
+	if len(prefix) < 4 || strings.Trim(prefix, "0") == "" {
+		return nil, fmt.Errorf("the patch doesn't name its base blob")
+	}
+	if isFullHash(prefix) {
+		return repo.ReadBlob(prefix)
+	}
+
+	found, err := repo.objectsWithPrefix(prefix)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case len(found) > 1:
+		return nil, fmt.Errorf("base blob %s is ambiguous", prefix)
+	case len(found) == 0:
+		return nil, fmt.Errorf("base blob %s is not in the repository", prefix)
+	}
+	return repo.ReadBlob(found[0])
+}
@@ -0,0 +1,135 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/sim-deos/plain/internal/diff"
+)
+
+// stageTestFiles writes files to the work tree and stages them.
+func stageTestFiles(t *testing.T, repo *Repository, files map[string]string) {
+	t.Helper()
+	writeTestFiles(t, files)
+	idx, err := repo.Index()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path := range files {
+		if err := repo.StagePath(idx, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.WriteIndex(idx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testPatch builds the patch turning old into new at path, with the index line naming old's blob.
+func testPatch(path, old, new string) diff.FilePatch {
+	return diff.FilePatch{
+		OldPath: path,
+		NewPath: path,
+		OldHash: HashObject(BlobObject, []byte(old))[:7],
+		NewHash: HashObject(BlobObject, []byte(new))[:7],
+		Hunks:   diff.Diff([]byte(old), []byte(new), diff.DefaultContext),
+	}
+}
+
+func TestApplyPatches(t *testing.T) {
+	newTestRepo(t)
+	repo, _ := OpenRepository()
+	stageTestFiles(t, repo, map[string]string{"a.txt": "one\ntwo\nthree\n", "old.txt": "moved\n", "gone.txt": "bye\n"})
+
+	patches := []diff.FilePatch{
+		testPatch("a.txt", "one\ntwo\nthree\n", "one\n2\nthree\n"),
+		{OldPath: "gone.txt", OldMode: uint32(ModeFile), Hunks: diff.Diff([]byte("bye\n"), nil, diff.DefaultContext)},
+		{NewPath: "dir/new.txt", NewMode: uint32(ModeExecutable), Hunks: diff.Diff(nil, []byte("hi\n"), diff.DefaultContext)},
+		{OldPath: "old.txt", NewPath: "renamed.txt", Similarity: 100},
+	}
+	if err := repo.ApplyPatches(patches, ApplyOptions{Target: ApplyBoth}); err != nil {
+		t.Fatal(err)
+	}
+
+	for path, want := range map[string]string{"a.txt": "one\n2\nthree\n", "dir/new.txt": "hi\n", "renamed.txt": "moved\n"} {
+		if got, err := os.ReadFile(path); err != nil || string(got) != want {
+			t.Errorf("%s holds %q, %v, want %q", path, got, err, want)
+		}
+	}
+	for _, path := range []string{"gone.txt", "old.txt"} {
+		if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("expected %s to be removed, got %v", path, err)
+		}
+	}
+
+	idx, _ := repo.Index()
+	var staged []string
+	for _, entry := range idx.Entries {
+		staged = append(staged, entry.Path)
+	}
+	if got := strings.Join(staged, " "); got != "a.txt dir/new.txt renamed.txt" {
+		t.Errorf("unexpected staged paths %s", got)
+	}
+	if entry, _ := idx.Entry("dir/new.txt"); entry.Mode != ModeExecutable {
+		t.Errorf("expected dir/new.txt to be executable, got %s", entry.Mode)
+	}
+	entries, err := repo.Status(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if entry.Unstaged != 0 {
+			t.Errorf("expected the work tree to match the index, got %+v", entry)
+		}
+	}
+}
+
+func TestApplyPatchesAllOrNothing(t *testing.T) {
+	newTestRepo(t)
+	repo, _ := OpenRepository()
+	writeTestFiles(t, map[string]string{"a.txt": "a\n", "b.txt": "b\n"})
+
+	patches := []diff.FilePatch{testPatch("a.txt", "a\n", "A\n"), testPatch("b.txt", "x\n", "y\n")}
+	if err := repo.ApplyPatches(patches, ApplyOptions{}); !errors.Is(err, diff.ErrHunkFailed) {
+		t.Fatalf("expected ErrHunkFailed, got %v", err)
+	}
+	if got, _ := os.ReadFile("a.txt"); string(got) != "a\n" {
+		t.Errorf("expected a.txt to be left alone when another file fails, got %q", got)
+	}
+}
+
+func TestApplyPatchesThreeWay(t *testing.T) {
+	newTestRepo(t)
+	repo, _ := OpenRepository()
+	base := "1\n2\n3\n4\n5\n6\n7\n8\n9\n"
+	stageTestFiles(t, repo, map[string]string{"a.txt": base})
+
+	// The patch's context no longer matches, but the base blob it was made against is known.
+	writeTestFiles(t, map[string]string{"a.txt": "1\ntwo\n3\n4\n5\n6\n7\n8\n9\n"})
+	patch := testPatch("a.txt", base, "1\n2\n3\n4\nfive\n6\n7\n8\n9\n")
+	if err := repo.ApplyPatches([]diff.FilePatch{patch}, ApplyOptions{}); !errors.Is(err, diff.ErrHunkFailed) {
+		t.Fatalf("expected the patch to fail without --3way, got %v", err)
+	}
+	if err := repo.ApplyPatches([]diff.FilePatch{patch}, ApplyOptions{ThreeWay: true}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile("a.txt"); string(got) != "1\ntwo\n3\n4\nfive\n6\n7\n8\n9\n" {
+		t.Errorf("unexpected merge %q", got)
+	}
+
+	// Changing the same line both ways conflicts, leaving markers and conflict stages.
+	stageTestFiles(t, repo, map[string]string{"a.txt": "1\n2\n3\n4\nFIVE\n6\n7\n8\n9\n"})
+	err := repo.ApplyPatches([]diff.FilePatch{patch}, ApplyOptions{Target: ApplyBoth, ThreeWay: true})
+	if !errors.Is(err, ErrApplyConflict) {
+		t.Fatalf("expected ErrApplyConflict, got %v", err)
+	}
+	if got, _ := os.ReadFile("a.txt"); !strings.Contains(string(got), "<<<<<<< ours\nFIVE\n=======\nfive\n>>>>>>> theirs\n") {
+		t.Errorf("expected conflict markers, got %q", got)
+	}
+	idx, _ := repo.Index()
+	if len(idx.Entries) != 3 || idx.Entries[2].Stage != 3 {
+		t.Errorf("expected three conflict stages, got %+v", idx.Entries)
+	}
+}
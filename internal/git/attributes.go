@@ -0,0 +1,222 @@
+package git
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// attrUnset marks an attribute that a rule explicitly returned to the unspecified state ("!attr").
+const attrUnset = "\x00unset"
+
+// attrRule is a single pattern line from a gitattributes file.
+type attrRule struct {
+	base    string            // Directory the rule is relative to, slash separated. Empty for the repo root.
+	pattern string            // The pattern to match, trimmed of any leading slash.
+	attrs   map[string]string // Attribute name to value. Set attributes hold "true", unset ones "false".
+}
+
+// Attributes answers gitattributes queries for paths in a work tree.
+//
+// Rules are read from the root .gitattributes, from .gitattributes files in the directories of queried
+// paths, and from .git/info/attributes, with git's precedence: deeper files override shallower ones and
+// info/attributes overrides them all. A new Attributes is created by calling [LoadAttributes].
+type Attributes struct {
+	workTree string
+	root     []attrRule            // Rules added through config, which every file can override.
+	dirs     map[string][]attrRule // Rules per directory, loaded lazily as paths are queried.
+	info     []attrRule            // Rules from .git/info/attributes.
+}
+
+// LoadAttributes prepares gitattributes lookups for the work tree at workTree.
+// commonDir is the repository's shared git directory, which holds info/attributes.
+func LoadAttributes(workTree, commonDir string) (*Attributes, error) {
+	info, err := readAttrFile(filepath.Join(commonDir, "info", "attributes"), "")
+	if err != nil {
+		return nil, err
+	}
+	return &Attributes{workTree: workTree, dirs: map[string][]attrRule{}, info: info}, nil
+}
+
+// AddRule adds a rule with the lowest precedence, as if it were the first line of the root .gitattributes.
+//
+// This is used to layer configured defaults beneath what the repository itself declares.
+func (a *Attributes) AddRule(pattern string, attrs ...string) {
+	rule, ok := parseAttrLine(pattern+" "+strings.Join(attrs, " "), "")
+	if ok {
+		a.root = append(a.root, rule)
+	}
+}
+
+// Get returns the value of the attribute name for the slash separated path p, relative to the work tree.
+// Set attributes report "true" and unset ones "false". The boolean is false if the attribute is unspecified.
+func (a *Attributes) Get(p, name string) (string, bool) {
+	value := attrUnset
+	apply := func(rules []attrRule) {
+		for _, rule := range rules {
+			v, ok := rule.attrs[name]
+			if ok && rule.matches(p) {
+				value = v
+			}
+		}
+	}
+
+	apply(a.root)
+	for _, dir := range parentDirs(p) {
+		apply(a.rulesFor(dir))
+	}
+	apply(a.info)
+
+	if value == attrUnset {
+		return "", false
+	}
+	return value, true
+}
+
+// IsGenerated reports whether the path is marked as generated through the linguist-generated attribute.
+func (a *Attributes) IsGenerated(p string) bool {
+	value, ok := a.Get(p, "linguist-generated")
+	return ok && value != "false"
+}
+
+// IsLFS reports whether the path is stored with Git LFS, through the filter=lfs attribute.
+func (a *Attributes) IsLFS(p string) bool {
+	value, ok := a.Get(p, "filter")
+	return ok && value == "lfs"
+}
+
+// IsBinary reports what the attributes say about whether the path is diffed as binary content: set by
+// the binary macro or by -diff, or not by diff. Like git diff, it ignores text and -text, which only
+// govern line endings, and a diff driver, as in diff=lfs, doesn't say either way. The second result is
+// false if the attributes don't say either way, in which case the content has to be sniffed.
+func (a *Attributes) IsBinary(p string) (binary, ok bool) {
+	if value, set := a.Get(p, "binary"); set && value != "false" {
+		return true, true
+	}
+	if value, set := a.Get(p, "diff"); set && (value == "true" || value == "false") {
+		return value == "false", true
+	}
+	return false, false
+}
+
+func (a *Attributes) rulesFor(dir string) []attrRule {
+	if rules, ok := a.dirs[dir]; ok {
+		return rules
+	}
+
+	// Read errors are treated as an empty file so one unreadable directory doesn't break every lookup.
+	rules, _ := readAttrFile(filepath.Join(a.workTree, filepath.FromSlash(dir), ".gitattributes"), dir)
+	a.dirs[dir] = rules
+	return rules
+}
+
+func (r attrRule) matches(p string) bool {
+	rel := p
+	if r.base != "" {
+		if !strings.HasPrefix(p, r.base+"/") {
+			return false
+		}
+		rel = p[len(r.base)+1:]
+	}
+
+	if !strings.Contains(r.pattern, "/") {
+		return matchPattern(r.pattern, path.Base(rel))
+	}
+	return matchPattern(r.pattern, rel)
+}
+
+// parentDirs returns the directories containing p from the root down, starting with "" for the root itself.
+func parentDirs(p string) []string {
+	dirs := []string{""}
+	for i, c := range p {
+		if c == '/' {
+			dirs = append(dirs, p[:i])
+		}
+	}
+	return dirs
+}
+
+func readAttrFile(name, base string) ([]attrRule, error) {
+	f, err := os.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rules []attrRule
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if rule, ok := parseAttrLine(scanner.Text(), base); ok {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, scanner.Err()
+}
+
+func parseAttrLine(line, base string) (attrRule, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+		return attrRule{}, false
+	}
+
+	rule := attrRule{base: base, pattern: strings.TrimPrefix(fields[0], "/"), attrs: map[string]string{}}
+	if strings.HasPrefix(fields[0], "/") && !strings.Contains(rule.pattern, "/") {
+		// A leading slash anchors the pattern, which the matcher expresses as containing a slash.
+		rule.pattern = "./" + rule.pattern
+	}
+
+	for _, attr := range fields[1:] {
+		switch {
+		case strings.HasPrefix(attr, "-"):
+			rule.attrs[attr[1:]] = "false"
+		case strings.HasPrefix(attr, "!"):
+			rule.attrs[attr[1:]] = attrUnset
+		case strings.Contains(attr, "="):
+			name, value, _ := strings.Cut(attr, "=")
+			rule.attrs[name] = value
+		default:
+			rule.attrs[attr] = "true"
+		}
+	}
+	return rule, true
+}
+
+// matchPattern reports whether the slash separated name matches a gitignore style pattern.
+//
+// Segments are matched with [path.Match], and a "**" segment matches zero or more whole directories.
+func matchPattern(pattern, name string) bool {
+	pattern = strings.TrimPrefix(pattern, "./")
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := range name {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
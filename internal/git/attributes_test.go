@@ -0,0 +1,103 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAttributesGenerated(t *testing.T) {
+	root := t.TempDir()
+	gitDir := filepath.Join(root, ".git")
+	if err := os.MkdirAll(filepath.Join(gitDir, "info"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFiles(t, map[string]string{
+		filepath.Join(root, ".gitattributes"):        "*.pb.go linguist-generated\n/vendor/** linguist-generated=true\n",
+		filepath.Join(root, "api", ".gitattributes"): "keep.pb.go -linguist-generated\n",
+	})
+
+	attrs, err := LoadAttributes(root, gitDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	attrs.AddRule("docs/*.html", "linguist-generated")
+
+	cases := map[string]bool{
+		"service.pb.go":        true,
+		"api/v1/service.pb.go": true,
+		"api/keep.pb.go":       false,
+		"vendor/lib/lib.go":    true,
+		"pkg/vendor/lib.go":    false,
+		"docs/index.html":      true,
+		"main.go":              false,
+	}
+	for path, expected := range cases {
+		if actual := attrs.IsGenerated(path); actual != expected {
+			t.Errorf("IsGenerated(%q) = %t, expected %t", path, actual, expected)
+		}
+	}
+}
+
+func TestAttributesBinary(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, map[string]string{filepath.Join(root, ".gitattributes"): "*.png binary\n*.lock -diff\n*.svg text\n*.dat -text\n*.psd diff=lfs -text\n*.md diff=markdown\n*.txt diff\n"})
+
+	attrs, err := LoadAttributes(root, filepath.Join(root, ".git"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		path         string
+		binary, isOk bool
+	}{
+		{"logo.png", true, true},
+		{"go.lock", true, true},
+		{"icon.svg", false, false},
+		{"blob.dat", false, false},
+		{"cover.psd", false, false},
+		{"notes.txt", false, true},
+		{"README.md", false, false},
+		{"main.go", false, false},
+	}
+	for _, c := range cases {
+		binary, ok := attrs.IsBinary(c.path)
+		if binary != c.binary || ok != c.isOk {
+			t.Errorf("IsBinary(%q) = %t, %t, expected %t, %t", c.path, binary, ok, c.binary, c.isOk)
+		}
+	}
+}
+
+func TestMatchPattern(t *testing.T) {
+	cases := []struct {
+		pattern, name string
+		expected      bool
+	}{
+		{"*.go", "main.go", true},
+		{"a/**/b", "a/b", true},
+		{"a/**/b", "a/x/y/b", true},
+		{"a/*", "a/x/y", false},
+		{"**/gen", "x/gen", true},
+	}
+	for _, c := range cases {
+		if actual := matchPattern(c.pattern, c.name); actual != c.expected {
+			t.Errorf("matchPattern(%q, %q) = %t, expected %t", c.pattern, c.name, actual, c.expected)
+		}
+	}
+}
+
+func TestAttributesLFS(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, map[string]string{filepath.Join(root, ".gitattributes"): "*.psd filter=lfs diff=lfs merge=lfs -text\n*.zip filter=other\n"})
+
+	attrs, err := LoadAttributes(root, filepath.Join(root, ".git"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]bool{"art/cover.psd": true, "dist.zip": false, "main.go": false} {
+		if got := attrs.IsLFS(path); got != want {
+			t.Errorf("IsLFS(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package git
+
+import (
+	"errors"
+	"io/fs"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Branch is a local branch and the commit at its tip.
+type Branch struct {
+	Name string // The short name of the branch, e.g. main
+	Head Commit // The commit the branch points to
+}
+
+// LastActivity returns when the branch last moved forward, taken as the committer date of its tip.
+func (b Branch) LastActivity() time.Time {
+	return b.Head.Committer.Time
+}
+
+// IsStale reports whether the branch has seen no activity for longer than threshold as of now.
+func (b Branch) IsStale(threshold time.Duration, now time.Time) bool {
+	return now.Sub(b.LastActivity()) > threshold
+}
+
+// Branches returns every local branch sorted by name.
+// Branches whose tip commit is missing from the object store are skipped.
+func (repo *Repository) Branches() ([]Branch, error) {
+	refs, err := repo.listRefs("refs/heads/")
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := newCommitReader(repo)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	branches := make([]Branch, 0, len(refs))
+	for name, hash := range refs {
+		commit, ok, err := r.read(hash)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			branches = append(branches, Branch{Name: strings.TrimPrefix(name, "refs/heads/"), Head: commit})
+		}
+	}
+
+	slices.SortFunc(branches, func(a, b Branch) int { return strings.Compare(a.Name, b.Name) })
+	return branches, nil
+}
@@ -0,0 +1,54 @@
+package git
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBranches(t *testing.T) {
+	gitDir := newTestRepo(t)
+	head := writeTestCommit(t, gitDir, "tip")
+	writeTestRef(t, gitDir, "refs/heads/main", head)
+	writeTestRef(t, gitDir, "refs/heads/feature/login", head)
+
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	branches, err := repo.Branches()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(branches) != 2 || branches[0].Name != "feature/login" || branches[1].Name != "main" {
+		t.Fatalf("unexpected branches: %+v", branches)
+	}
+
+	// The test commit was made on 2023-12-21.
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if branches[0].IsStale(30*24*time.Hour, now) {
+		t.Fatal("branch should not be stale within the threshold")
+	}
+	if !branches[0].IsStale(7*24*time.Hour, now) {
+		t.Fatal("branch should be stale past the threshold")
+	}
+}
+
+func TestCurrentBranch(t *testing.T) {
+	gitDir := newTestRepo(t)
+	head := writeTestCommit(t, gitDir, "tip")
+	writeTestRef(t, gitDir, "HEAD", "ref: refs/heads/feature/login")
+
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if branch, err := repo.CurrentBranch(); err != nil || branch != "feature/login" {
+		t.Fatalf("expected feature/login, got %q (%v)", branch, err)
+	}
+
+	writeTestRef(t, gitDir, "HEAD", head)
+	if branch, err := repo.CurrentBranch(); err != nil || branch != "" {
+		t.Fatalf("expected no branch for a detached HEAD, got %q (%v)", branch, err)
+	}
+}
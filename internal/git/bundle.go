@@ -0,0 +1,334 @@
+package git
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrBadBundle            = errors.New("not a git bundle")
+	ErrEmptyBundle          = errors.New("nothing to bundle")
+	ErrMissingPrerequisites = errors.New("missing the commits the bundle builds on")
+)
+
+const (
+	bundleV2Signature = "# v2 git bundle"
+	bundleV3Signature = "# v3 git bundle"
+)
+
+// Bundle is the header of a bundle file, which holds refs and the objects they lead to in a single file,
+// so history can move between repositories without a network, as with git bundle. The header lists the
+// refs, and is followed by a pack of the objects.
+type Bundle struct {
+	Format HashFormat
+
+	// Prerequisites are the commits the bundled history builds on without including, which a repository
+	// must have already to unbundle it. Their Name is the commit's subject, for people to read.
+	Prerequisites []BundleRef
+
+	Refs []BundleRef
+}
+
+// BundleRef is a ref listed in a bundle, like refs/heads/main, and the object it points to.
+type BundleRef struct {
+	Hash string
+	Name string
+}
+
+// ReadBundle reads the header of the bundle file at path, without reading its objects.
+func ReadBundle(path string) (Bundle, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Bundle{}, err
+	}
+	defer f.Close()
+	return readBundleHeader(bufio.NewReader(f))
+}
+
+// readBundleHeader reads a bundle's header from br, leaving it at the start of the pack. Version 2
+// bundles always use SHA-1, while version 3 ones name their hash format in an @object-format line.
+func readBundleHeader(br *bufio.Reader) (Bundle, error) {
+	readLine := func() (string, error) {
+		line, err := br.ReadString('\n')
+		if errors.Is(err, io.EOF) {
+			return "", fmt.Errorf("%w: the header is truncated", ErrBadBundle)
+		}
+		return strings.TrimSuffix(line, "\n"), err
+	}
+
+	signature, err := readLine()
+	if err != nil {
+		return Bundle{}, err
+	}
+	if signature != bundleV2Signature && signature != bundleV3Signature {
+		return Bundle{}, ErrBadBundle
+	}
+
+	bundle := Bundle{Format: SHA1}
+	for {
+		line, err := readLine()
+		if err != nil {
+			return Bundle{}, err
+		}
+		if line == "" {
+			return bundle, nil
+		}
+
+		if capability, ok := strings.CutPrefix(line, "@"); ok && signature == bundleV3Signature {
+			key, value, _ := strings.Cut(capability, "=")
+			switch {
+			case key == "object-format" && value == "sha1":
+				bundle.Format = SHA1
+			case key == "object-format" && value == "sha256":
+				bundle.Format = SHA256
+			default:
+				return Bundle{}, fmt.Errorf("git: the bundle needs %s, which isn't supported", capability)
+			}
+			continue
+		}
+
+		rest, prerequisite := strings.CutPrefix(line, "-")
+		hash, name, _ := strings.Cut(rest, " ")
+		if len(hash) != bundle.Format.HexSize() || !isFullHash(hash) || (!prerequisite && name == "") {
+			return Bundle{}, fmt.Errorf("%w: malformed line %q", ErrBadBundle, line)
+		}
+		if prerequisite {
+			bundle.Prerequisites = append(bundle.Prerequisites, BundleRef{Hash: hash, Name: name})
+		} else {
+			bundle.Refs = append(bundle.Refs, BundleRef{Hash: hash, Name: name})
+		}
+	}
+}
+
+// writeHeader writes the bundle's header, in version 2 for SHA-1 as every git can read it, and in
+// version 3 for SHA-256, which version 2 can't express.
+func (b Bundle) writeHeader(w io.Writer) error {
+	var header strings.Builder
+	if b.Format == SHA256 {
+		header.WriteString(bundleV3Signature + "\n@object-format=sha256\n")
+	} else {
+		header.WriteString(bundleV2Signature + "\n")
+	}
+	for _, prerequisite := range b.Prerequisites {
+		fmt.Fprintf(&header, "%s\n", strings.TrimSpace("-"+prerequisite.Hash+" "+prerequisite.Name))
+	}
+	for _, ref := range b.Refs {
+		fmt.Fprintf(&header, "%s %s\n", ref.Hash, ref.Name)
+	}
+	header.WriteString("\n")
+	_, err := io.WriteString(w, header.String())
+	return err
+}
+
+// CreateBundle writes a bundle of refs, full names like refs/heads/main or HEAD, to w, along with the
+// history they lead to, like git bundle create. History reachable from the revisions in exclude is left
+// out, for handing over a branch to someone who has what it was based on; the commits it builds on
+// become the bundle's prerequisites. A ref may point to a commit or to an annotated tag of one.
+//
+// Objects are bundled as stored, without applying replace refs or grafts, so the history that is
+// unbundled is the one that was bundled. Bundling fails with [ErrEmptyBundle] if exclude leaves
+// nothing, since git refuses such bundles.
+func (repo *Repository) CreateBundle(w io.Writer, refs, exclude []string) (Bundle, error) {
+	defer phase("bundle")()
+	r := &commitReader{objectsPath: filepath.Join(repo.CommonDir, "objects"), format: repo.Format}
+	defer r.Close()
+	shallow, err := readShallow(repo.CommonDir)
+	if err != nil {
+		return Bundle{}, err
+	}
+
+	bundle := Bundle{Format: repo.Format}
+	var tips, tags []string
+	for _, name := range refs {
+		hash, err := repo.resolveSymbolic(name)
+		if err != nil {
+			return Bundle{}, err
+		}
+		bundle.Refs = append(bundle.Refs, BundleRef{Hash: hash, Name: name})
+
+		// Tags are followed to the commit, bundling each tag object on the way.
+		for depth := 0; ; depth++ {
+			header, closer, err := r.open(hash)
+			if err != nil {
+				return Bundle{}, fmt.Errorf("git: failed to read %s: %w", name, err)
+			}
+			if header.Kind != TagObject {
+				closer.Close()
+				if header.Kind != CommitObject {
+					return Bundle{}, fmt.Errorf("git: %s points to a %s, not a commit", name, header.Kind)
+				}
+				tips = append(tips, hash)
+				break
+			}
+			tag, err := r.d.DecodeTag(hash)
+			closer.Close()
+			if err != nil {
+				return Bundle{}, err
+			}
+			if depth == maxSymbolicDepth {
+				return Bundle{}, fmt.Errorf("git: tag nesting too deep at %s", hash)
+			}
+			if !slices.Contains(tags, hash) {
+				tags = append(tags, hash)
+			}
+			hash = tag.Object
+		}
+	}
+
+	var basis []string
+	for _, rev := range exclude {
+		hash, err := repo.ResolveRevision(rev)
+		if err != nil {
+			return Bundle{}, err
+		}
+		peeled, err := r.peel(hash)
+		if err != nil {
+			return Bundle{}, err
+		}
+		basis = append(basis, peeled)
+	}
+	known := BranchHistory{Graph: map[string]Commit{}, Shallow: map[string]bool{}}
+	if err := r.walk(&known, shallow, basis); err != nil {
+		return Bundle{}, err
+	}
+	all := BranchHistory{Graph: map[string]Commit{}, Shallow: map[string]bool{}}
+	for h, commit := range known.Graph {
+		all.Graph[h] = commit
+	}
+	if err := r.walk(&all, shallow, tips); err != nil {
+		return Bundle{}, err
+	}
+
+	fresh := map[string]Commit{}
+	for h, commit := range all.Graph {
+		if _, ok := known.Graph[h]; ok {
+			continue
+		}
+		if all.Shallow[h] {
+			return Bundle{}, fmt.Errorf("git: can't bundle %s, the history before it was never fetched", h)
+		}
+		fresh[h] = commit
+	}
+	if len(fresh) == 0 && len(tags) == 0 {
+		return Bundle{}, ErrEmptyBundle
+	}
+
+	// The receiver has the commits the fresh ones build on, along with everything in their trees, and
+	// so any tip that is among them.
+	seen := map[string]bool{}
+	prerequisite := func(hash string) error {
+		base, ok := known.Graph[hash]
+		if !ok || seen[hash] {
+			return nil
+		}
+		seen[hash] = true
+		subject, _, _ := strings.Cut(base.Message, "\n")
+		bundle.Prerequisites = append(bundle.Prerequisites, BundleRef{Hash: hash, Name: subject})
+		return collectTree(r, base.Tree, seen, nil)
+	}
+	for _, commit := range fresh {
+		for _, parent := range commit.Parents {
+			if err := prerequisite(parent); err != nil {
+				return Bundle{}, err
+			}
+		}
+	}
+	for _, tip := range tips {
+		if err := prerequisite(tip); err != nil {
+			return Bundle{}, err
+		}
+	}
+	slices.SortFunc(bundle.Prerequisites, func(a, b BundleRef) int { return strings.Compare(a.Hash, b.Hash) })
+
+	objects := tags
+	for _, commit := range topoOrder(fresh) {
+		objects = append(objects, commit.Hash)
+		err := collectTree(r, commit.Tree, seen, func(hash string) {
+			objects = append(objects, hash)
+		})
+		if err != nil {
+			return Bundle{}, err
+		}
+	}
+
+	if err := bundle.writeHeader(w); err != nil {
+		return Bundle{}, err
+	}
+	p, err := streamPack(w, repo.Format, len(objects))
+	if err != nil {
+		return Bundle{}, err
+	}
+	for _, hash := range objects {
+		kind, data, err := r.raw(hash)
+		if err != nil {
+			return Bundle{}, fmt.Errorf("git: failed to read %s: %w", hash, err)
+		}
+		if err := p.add(hash, kind, data); err != nil {
+			return Bundle{}, err
+		}
+	}
+	if _, err := p.end(); err != nil {
+		return Bundle{}, err
+	}
+	return bundle, nil
+}
+
+// Unbundle stores the objects in the bundle file at path in the repository, like git bundle unbundle,
+// and returns the bundle's header so the caller can decide which refs to update; no ref is changed. The
+// repository must have every prerequisite, or it fails with [ErrMissingPrerequisites] before anything is
+// written.
+//
+// Every object is named by hashing it, and the objects the repository lacks are stored in a new pack.
+// Deltas against objects the repository has, which git writes into bundles, are resolved on the way.
+func (repo *Repository) Unbundle(path string) (Bundle, error) {
+	defer phase("unbundle")()
+	f, err := os.Open(path)
+	if err != nil {
+		return Bundle{}, err
+	}
+	defer f.Close()
+	br := bufio.NewReader(f)
+	bundle, err := readBundleHeader(br)
+	if err != nil {
+		return Bundle{}, err
+	}
+	if bundle.Format != repo.Format {
+		return Bundle{}, fmt.Errorf("git: the bundle names objects with %s, but the repository uses %s", bundle.Format, repo.Format)
+	}
+
+	var missing []string
+	for _, prerequisite := range bundle.Prerequisites {
+		ok, err := repo.hasObject(prerequisite.Hash)
+		if err != nil {
+			return Bundle{}, err
+		}
+		if !ok {
+			missing = append(missing, prerequisite.Hash)
+		}
+	}
+	if len(missing) > 0 {
+		return bundle, fmt.Errorf("%w: %s", ErrMissingPrerequisites, strings.Join(missing, ", "))
+	}
+
+	bundled, _, err := repo.storePack(br)
+	if err != nil {
+		return Bundle{}, fmt.Errorf("git: failed to read the pack in %s: %w", filepath.Base(path), err)
+	}
+	for _, ref := range bundle.Refs {
+		if bundled[ref.Hash] {
+			continue
+		}
+		if ok, err := repo.hasObject(ref.Hash); err != nil {
+			return Bundle{}, err
+		} else if !ok {
+			return Bundle{}, fmt.Errorf("%w: %s points to %s, which it doesn't hold", ErrBadBundle, ref.Name, ref.Hash)
+		}
+	}
+	return bundle, nil
+}
@@ -0,0 +1,89 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBundle(t *testing.T) {
+	gitDir := newTestRepo(t)
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestObject(t, gitDir, TreeObject, "")
+	main := writeTestTreeCommit(t, repo, writeTestCommit(t, gitDir, "root"), maintenanceSignature, "base", map[string]string{"a.txt": "one\n", "dir/b.txt": "two\n"})
+	writeTestRef(t, gitDir, "refs/heads/main", main)
+	feature := writeTestTreeCommit(t, repo, main, maintenanceSignature, "feature", map[string]string{"a.txt": "changed\n", "dir/b.txt": "two\n"})
+	writeTestRef(t, gitDir, "refs/heads/feature", feature)
+	tag := writeTestObject(t, gitDir, TagObject, "object "+feature+"\ntype commit\ntag v1\ntagger A <a@example.com> 1703123456 +0000\n\nv1\n")
+	writeTestRef(t, gitDir, "refs/tags/v1", tag)
+	if _, err := repo.Repack(); err != nil {
+		t.Fatal(err) // bundles are read from packs as well
+	}
+
+	dir := t.TempDir()
+	create := func(name string, refs, exclude []string) (string, Bundle) {
+		t.Helper()
+		var b bytes.Buffer
+		bundle, err := repo.CreateBundle(&b, refs, exclude)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, b.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path, bundle
+	}
+	full, _ := create("full.bundle", []string{"refs/heads/main", "refs/heads/feature", "refs/tags/v1"}, nil)
+	handoff, bundle := create("feature.bundle", []string{"refs/heads/feature"}, []string{"main"})
+	if len(bundle.Prerequisites) != 1 || bundle.Prerequisites[0] != (BundleRef{Hash: main, Name: "base"}) {
+		t.Errorf("prerequisites of the feature bundle = %+v, want main", bundle.Prerequisites)
+	}
+	if _, err := repo.CreateBundle(&bytes.Buffer{}, []string{"refs/heads/main"}, []string{"feature"}); !errors.Is(err, ErrEmptyBundle) {
+		t.Errorf("CreateBundle() of nothing new = %v, want %v", err, ErrEmptyBundle)
+	}
+
+	header, err := ReadBundle(full)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(header.Refs) != 3 || header.Refs[2] != (BundleRef{Hash: tag, Name: "refs/tags/v1"}) || len(header.Prerequisites) != 0 {
+		t.Errorf("ReadBundle() = %+v, want three refs and no prerequisites", header)
+	}
+
+	// A new repository can't take the feature without main, but takes everything from the full bundle.
+	newTestRepo(t)
+	other, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Unbundle(handoff); !errors.Is(err, ErrMissingPrerequisites) {
+		t.Errorf("Unbundle() without the base = %v, want %v", err, ErrMissingPrerequisites)
+	}
+	if _, err := other.Unbundle(full); err != nil {
+		t.Fatal(err)
+	}
+	commit, err := other.ReadCommit(feature)
+	if err != nil || commit.Message != "feature" {
+		t.Errorf("ReadCommit() of an unbundled commit = %+v, %v", commit, err)
+	}
+	if data, err := other.ReadBlob(HashObject(BlobObject, []byte("two\n"))); err != nil || string(data) != "two\n" {
+		t.Errorf("ReadBlob() of an unbundled blob = %q, %v", data, err)
+	}
+	if report, err := other.Verify(); err != nil || !report.OK() || report.Packs != 1 {
+		t.Errorf("Verify() after unbundling = %+v, %v", report, err)
+	}
+
+	// The feature bundle now applies, and adds nothing new.
+	if _, err := other.Unbundle(handoff); err != nil {
+		t.Fatal(err)
+	}
+	if packs, _ := filepath.Glob(filepath.Join(other.CommonDir, "objects", "pack", "*.pack")); len(packs) != 1 {
+		t.Errorf("unbundling objects the repository had wrote %d packs, want 1", len(packs))
+	}
+}
@@ -0,0 +1,291 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrPickConflict = errors.New("stopped on conflicts")
+	ErrEmptyPick    = errors.New("the change is already present")
+	ErrNoPick       = errors.New("no cherry-pick or revert in progress")
+)
+
+const (
+	cherryPickHead = "CHERRY_PICK_HEAD"
+	revertHead     = "REVERT_HEAD"
+	mergeMsg       = "MERGE_MSG"
+)
+
+// PickOptions controls how [Repository.CherryPick] picks a commit.
+type PickOptions struct {
+	// Mainline is the parent, counting from 1, whose changes a merge commit is picked relative to, like
+	// git cherry-pick -m. It must be set for merge commits, and only for them.
+	Mainline int
+}
+
+// CherryPick applies the changes the commit rev made to HEAD and commits them as a new commit, keeping
+// the original author and message and recording who as the committer, like git cherry-pick. It returns
+// the new commit's hash.
+//
+// The changes are found by a three-way merge of rev against its parent onto HEAD, so they apply even
+// where HEAD has moved on. The index and tracked files must match HEAD. If the merge conflicts, the
+// conflicts are left in the index and work tree with rev recorded in CHERRY_PICK_HEAD, and an error
+// wrapping [ErrPickConflict] is returned naming the files; after resolving them, [Repository.ContinuePick]
+// commits the result and [Repository.AbortPick] gives up. If HEAD already has the changes,
+// [ErrEmptyPick] is returned and nothing is committed.
+func (repo *Repository) CherryPick(rev string, who Signature, opts PickOptions) (string, error) {
+	commit, base, err := repo.pickTarget(rev, opts.Mainline)
+	if err != nil {
+		return "", err
+	}
+	subject, _, _ := strings.Cut(commit.Message, "\n")
+	label := commit.Hash[:7] + " (" + subject + ")"
+	return repo.pick(commit, base, commit.Tree, label, cherryPickHead, commit.Author, who, commit.Message, "cherry-pick: "+subject)
+}
+
+// pickTarget reads the commit rev names, and the tree of the parent its changes are relative to.
+func (repo *Repository) pickTarget(rev string, mainline int) (Commit, string, error) {
+	hash, err := repo.ResolveRevision(rev)
+	if err != nil {
+		return Commit{}, "", err
+	}
+	commit, err := repo.ReadCommit(hash)
+	if err != nil {
+		return Commit{}, "", err
+	}
+
+	var parent string
+	switch {
+	case len(commit.Parents) > 1 && mainline == 0:
+		return Commit{}, "", fmt.Errorf("%s is a merge, choose the parent to compare against", rev)
+	case len(commit.Parents) > 1 && mainline > len(commit.Parents):
+		return Commit{}, "", fmt.Errorf("%s has no parent %d", rev, mainline)
+	case len(commit.Parents) > 1:
+		parent = commit.Parents[mainline-1]
+	case mainline != 0:
+		return Commit{}, "", fmt.Errorf("%s is not a merge, so has no parent to choose", rev)
+	case len(commit.Parents) == 1:
+		parent = commit.Parents[0]
+	}
+	if parent == "" {
+		return commit, "", nil
+	}
+
+	parentCommit, err := repo.ReadCommit(parent)
+	if err != nil {
+		return Commit{}, "", err
+	}
+	return commit, parentCommit.Tree, nil
+}
+
+// pick merges the changes from base to theirs onto HEAD and commits them with author and message, or
+// stops on conflicts recording commit in the state file named by stateFile.
+func (repo *Repository) pick(commit Commit, base, theirs, theirsLabel, stateFile string, author, who Signature, message, reason string) (string, error) {
+	if op := repo.activeOperation(); op != "" {
+		return "", fmt.Errorf("%w: %s exists", ErrOperationActive, op)
+	}
+	if err := repo.requireClean(); err != nil {
+		return "", err
+	}
+	return repo.applyPick(commit, base, theirs, theirsLabel, stateFile, author, who, message, reason)
+}
+
+// applyPick does the work of pick once it is known that nothing is in the way.
+func (repo *Repository) applyPick(commit Commit, base, theirs, theirsLabel, stateFile string, author, who Signature, message, reason string) (string, error) {
+	head, err := repo.ResolveRevision("HEAD")
+	if err != nil {
+		return "", err
+	}
+	headCommit, err := repo.ReadCommit(head)
+	if err != nil {
+		return "", err
+	}
+
+	merges, err := repo.mergeTrees(base, headCommit.Tree, theirs, "HEAD", theirsLabel)
+	if err != nil {
+		return "", err
+	}
+	if len(merges) == 0 {
+		return "", ErrEmptyPick
+	}
+
+	idx, err := repo.Index()
+	if err != nil {
+		return "", err
+	}
+	if err := repo.checkoutMerges(idx, merges); err != nil {
+		return "", err
+	}
+	if err := repo.WriteIndex(idx); err != nil {
+		return "", err
+	}
+
+	var conflicted []string
+	for _, m := range merges {
+		if m.conflict {
+			conflicted = append(conflicted, m.path)
+		}
+	}
+	if len(conflicted) > 0 {
+		if err := repo.writeOperationState(stateFile, commit.Hash, message, conflicted); err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("%w in %s", ErrPickConflict, strings.Join(conflicted, ", "))
+	}
+	return repo.commitIndex(idx, head, author, who, message, reason)
+}
+
+// ContinuePick commits a cherry-pick or revert that stopped on conflicts, once they are resolved, with
+// the message it would have had and who as the committer. A cherry-pick keeps its original author, while
+// who is the author of a revert. It returns the new commit's hash.
+func (repo *Repository) ContinuePick(who Signature) (string, error) {
+	stateFile, hash, err := repo.pickState()
+	if err != nil {
+		return "", err
+	}
+	commit, err := repo.ReadCommit(hash)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(filepath.Join(repo.GitDir, mergeMsg))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+	message := stripComments(string(data))
+	if message == "" {
+		message = commit.Message
+	}
+	subject, _, _ := strings.Cut(message, "\n")
+	author, reason := commit.Author, "cherry-pick: "+subject
+	if stateFile == revertHead {
+		author, reason = who, "revert: "+subject
+	}
+
+	idx, err := repo.Index()
+	if err != nil {
+		return "", err
+	}
+	head, err := repo.ResolveRevision("HEAD")
+	if err != nil {
+		return "", err
+	}
+	result, err := repo.commitIndex(idx, head, author, who, message, reason)
+	if err != nil {
+		return "", err
+	}
+	return result, repo.clearOperationState(stateFile)
+}
+
+// AbortPick gives up on a cherry-pick or revert that stopped on conflicts, putting the index and work tree back
+// the way HEAD has them.
+func (repo *Repository) AbortPick() error {
+	stateFile, _, err := repo.pickState()
+	if err != nil {
+		return err
+	}
+	idx, err := repo.Index()
+	if err != nil {
+		return err
+	}
+	if err := repo.resetToHead(idx); err != nil {
+		return err
+	}
+	if err := repo.WriteIndex(idx); err != nil {
+		return err
+	}
+	return repo.clearOperationState(stateFile)
+}
+
+// commitIndex commits idx on top of head and moves the current branch, or HEAD when detached, to it.
+func (repo *Repository) commitIndex(idx *Index, head string, author, who Signature, message, reason string) (string, error) {
+	tree, err := idx.WriteTree(repo.Encoder())
+	if err != nil {
+		return "", err
+	}
+	headCommit, err := repo.ReadCommit(head)
+	if err != nil {
+		return "", err
+	}
+	if tree == headCommit.Tree {
+		return "", ErrEmptyPick
+	}
+
+	hash, err := repo.CreateCommit(tree, []string{head}, author, who, message)
+	if err != nil {
+		return "", err
+	}
+	ref := "HEAD"
+	if target, err := repo.resolveRef("HEAD"); err == nil && strings.HasPrefix(target, "ref: ") {
+		ref = strings.TrimPrefix(target, "ref: ")
+	}
+	if err := repo.UpdateRef(ref, head, hash, who, reason); err != nil {
+		return "", err
+	}
+	return hash, nil
+}
+
+// activeOperation returns the state file of a merge, cherry-pick, revert, or rebase in progress, or ""
+// if there is none.
+func (repo *Repository) activeOperation() string {
+	for _, name := range []string{"MERGE_HEAD", cherryPickHead, revertHead, rebaseDir, "rebase-apply"} {
+		if _, err := os.Stat(filepath.Join(repo.GitDir, name)); err == nil {
+			return name
+		}
+	}
+	return ""
+}
+
+// pickState returns the state file of a stopped cherry-pick or revert, and the commit it names.
+func (repo *Repository) pickState() (string, string, error) {
+	for _, name := range []string{cherryPickHead, revertHead} {
+		data, err := os.ReadFile(filepath.Join(repo.GitDir, name))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return "", "", err
+		}
+		return name, strings.TrimSpace(string(data)), nil
+	}
+	return "", "", ErrNoPick
+}
+
+// writeOperationState records a stopped operation the way git does: the commit in stateFile, and the
+// message the commit will get in MERGE_MSG, listing the conflicts as comments.
+func (repo *Repository) writeOperationState(stateFile, hash, message string, conflicted []string) error {
+	var b strings.Builder
+	b.WriteString(strings.TrimRight(message, "\n") + "\n\n# Conflicts:\n")
+	for _, path := range conflicted {
+		b.WriteString("#\t" + path + "\n")
+	}
+	if err := os.WriteFile(filepath.Join(repo.GitDir, mergeMsg), []byte(b.String()), 0o644); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(repo.GitDir, stateFile), []byte(hash+"\n"), 0o644)
+}
+
+func (repo *Repository) clearOperationState(stateFile string) error {
+	for _, name := range []string{stateFile, mergeMsg} {
+		if err := os.Remove(filepath.Join(repo.GitDir, name)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// stripComments drops the # lines git adds to messages for the user's information, and trailing blank lines.
+func stripComments(message string) string {
+	var kept []string
+	for _, line := range strings.Split(message, "\n") {
+		if !strings.HasPrefix(line, "#") {
+			kept = append(kept, line)
+		}
+	}
+	return strings.TrimSpace(strings.Join(kept, "\n"))
+}
@@ -0,0 +1,97 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestTreeCommit stores a commit of files on top of parent without touching the work tree.
+func writeTestTreeCommit(t *testing.T, repo *Repository, parent string, author Signature, message string, files map[string]string) string {
+	t.Helper()
+	e := repo.Encoder()
+	b := NewTreeBuilder(e)
+	for path, content := range files {
+		hash, err := e.Encode(BlobObject, []byte(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := b.Add(path, ModeFile, hash); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tree, err := b.Write()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := repo.CreateCommit(tree, []string{parent}, author, author, message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func TestCherryPick(t *testing.T) {
+	newTestRepo(t)
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stageTestFiles(t, repo, map[string]string{"f.txt": "a\nb\nc\nd\ne\n", "keep.txt": "keep\n"})
+	commitTestIndex(t, repo)
+	root, _ := repo.ResolveRevision("HEAD")
+
+	other := Signature{Name: "Olga", Email: "olga@example.com", Time: time.Unix(1703120000, 0)}
+	who := Signature{Name: "Ann", Email: "ann@example.com", Time: time.Unix(1703130000, 0)}
+	theirs := writeTestTreeCommit(t, repo, root, other, "Change b", map[string]string{"f.txt": "a\nB\nc\nd\ne\n", "keep.txt": "keep\n", "new.txt": "new\n"})
+	conflicting := writeTestTreeCommit(t, repo, root, other, "Change b too", map[string]string{"f.txt": "a\nX\nc\nd\ne\n", "keep.txt": "keep\n"})
+
+	// Move main on, so the pick has to merge.
+	stageTestFiles(t, repo, map[string]string{"f.txt": "a\nb\nc\nd\nE\n"})
+	idx, _ := repo.Index()
+	if _, err := repo.commitIndex(idx, root, who, who, "Change e", "commit"); err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := repo.CherryPick(theirs, who, PickOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit, err := repo.ReadCommit(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if commit.Author.Name != "Olga" || commit.Committer.Name != "Ann" || commit.Message != "Change b" {
+		t.Errorf("picked commit has author %s, committer %s, message %q", commit.Author.Name, commit.Committer.Name, commit.Message)
+	}
+	if data, _ := os.ReadFile("f.txt"); string(data) != "a\nB\nc\nd\nE\n" {
+		t.Errorf("f.txt = %q, want both changes", data)
+	}
+	if entries, err := repo.Status(nil); err != nil || len(entries) != 0 {
+		t.Errorf("expected a clean status after picking, got %+v, %v", entries, err)
+	}
+	if _, err := repo.CherryPick(theirs, who, PickOptions{}); !errors.Is(err, ErrEmptyPick) {
+		t.Errorf("picking the same change again: got %v", err)
+	}
+
+	if _, err := repo.CherryPick(conflicting, who, PickOptions{}); !errors.Is(err, ErrPickConflict) {
+		t.Fatalf("expected a conflict, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(repo.GitDir, "CHERRY_PICK_HEAD")); err != nil {
+		t.Error("CHERRY_PICK_HEAD wasn't written")
+	}
+	if _, err := repo.CherryPick(theirs, who, PickOptions{}); !errors.Is(err, ErrOperationActive) {
+		t.Errorf("picking during a stopped pick: got %v", err)
+	}
+	if err := repo.AbortPick(); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile("f.txt"); string(data) != "a\nB\nc\nd\nE\n" {
+		t.Errorf("f.txt = %q after aborting", data)
+	}
+	if entries, err := repo.Status(nil); err != nil || len(entries) != 0 {
+		t.Errorf("expected a clean status after aborting, got %+v, %v", entries, err)
+	}
+}
@@ -1,9 +1,14 @@
 package git
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"os"
+	"io"
 	"os/exec"
+	"strings"
+	"sync"
+	"time"
 )
 
 type Client interface {
@@ -11,54 +16,134 @@ type Client interface {
 	IsBranchDirty() (bool, error)
 	GetCurrentBranch() (string, error)
 
+	// Returns the checked out branch, how it compares with its upstream, and every path that differs
+	// from HEAD, including untracked ones.
+	Status() (WorkTreeStatus, error)
+
 	// Create a new branch off of the 'from' branch. If from == 'here', will get the current
 	// branch and base the new branch off of it.
 	CreateBranch(name, from string) error
 	SwitchBranch(name string) error
+
+	// Returns the paths, relative to the repo root, that changed on the current branch since it forked from base.
+	ChangedFiles(base string) ([]string, error)
+
+	// Returns the hash of the best common ancestor of the two revisions, where the current branch forked from base.
+	MergeBase(a, b string) (string, error)
+
+	// Returns every value set for the given git config key, or nil if it is not set. A key given a value
+	// by [SetConfigOverride] has only that one.
+	GetConfigValues(key string) ([]string, error)
+
+	// Merge the revision into the current branch with git's full merge engine, committing the result
+	// unless it conflicts.
+	Merge(rev string) error
+
+	// Push the branch to the remote, setting the remote branch as its upstream.
+	Push(remote, branch string) error
+
+	// Commit the given paths, which must already be tracked, leaving anything else staged uncommitted.
+	CommitPaths(message string, paths []string) error
+
+	// Create an annotated tag on HEAD with the given message.
+	CreateTag(name, message string) error
+
+	// Push the tag to the remote.
+	PushTag(remote, tag string) error
+
+	// Fetch the remote's branches and tags without touching the work tree or printing progress.
+	Fetch(remote string) error
+
+	// Push the ref to the ref of the same name on the remote.
+	PushRef(remote, ref string) error
+
+	// Fetch the remote's ref into dest, overwriting it. Returns ErrRemoteRefNotFound if the remote doesn't have it.
+	FetchRef(remote, ref, dest string) error
 }
 
-type ShellClient struct{}
+var ErrRemoteRefNotFound = errors.New("the remote does not have the ref")
+
+// ShellClient is a [Client] that runs the git command. It works with gits as old as 2.20, using older
+// equivalents of the commands and flags newer gits introduced.
+type ShellClient struct {
+	// Context cancels every git command the client runs once it is done. When nil, commands run until
+	// they finish or time out.
+	Context context.Context
+
+	// Timeout bounds how long a git command may run before it is killed and fails with a [*TimeoutError],
+	// or 0 to let commands run for as long as they take.
+	Timeout time.Duration
+
+	// Stdout and Stderr are where the output of git commands that show it goes, like the progress of a
+	// push. When nil, it goes to the process's standard output and standard error.
+	Stdout, Stderr io.Writer
+
+	versionOnce sync.Once
+	version     GitVersion // The installed git's version, or the zero GitVersion if it couldn't be told
+}
 
 func NewShellClient() *ShellClient {
-	return &ShellClient{}
+	return &ShellClient{Timeout: DefaultTimeout}
+}
+
+// supports reports whether the installed git is the release since or later. A git whose version can't
+// be told is taken to be recent.
+func (c *ShellClient) supports(since GitVersion) bool {
+	c.versionOnce.Do(func() {
+		c.version, _ = InstalledGitVersion()
+	})
+	return c.version == GitVersion{} || c.version.AtLeast(since)
 }
 
 func (c *ShellClient) Init() error {
-	gitCmd := exec.Command("git", "init")
+	gitCmd, done := c.command("init")
 
-	gitCmd.Stdout = os.Stdout
-	gitCmd.Stderr = os.Stderr
+	gitCmd.Stdout = c.stdout()
+	gitCmd.Stderr = c.stderr()
 
-	return gitCmd.Run()
+	return done(gitCmd.Run())
 }
 
 func (c *ShellClient) IsBranchDirty() (bool, error) {
-	gitCmd := exec.Command("git", "diff", "--quiet", "--ignore-submodules", "HEAD")
-	_, err := gitCmd.Output()
-	code := err.Error()
+	gitCmd, done := c.command("diff", "--quiet", "--ignore-submodules", "HEAD")
+	err := done(gitCmd.Run())
 
-	if code == "exit status 1" {
+	// git diff --quiet exits with status 1 when there are changes
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
 		return true, nil
 	}
-
-	if code == "exit status 0" {
-		return false, nil
+	if err != nil {
+		return true, err
 	}
+	return false, nil
+}
 
-	return true, err
+func (c *ShellClient) Status() (WorkTreeStatus, error) {
+	output, err := c.output("status", "--porcelain=v2", "--branch", "-z", "--untracked-files=all")
+	if err != nil {
+		return WorkTreeStatus{}, err
+	}
+	return ParseStatusV2(output)
 }
 
 func (c *ShellClient) GetCurrentBranch() (string, error) {
-	gitBranchCmd := exec.Command("git", "branch", "--show-current")
+	if !c.supports(gitBranchShowCurrent) {
+		// symbolic-ref fails with status 1 when HEAD is detached, where --show-current prints nothing.
+		output, err := c.output("symbolic-ref", "--quiet", "--short", "HEAD")
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return "", nil
+		}
+		return strings.TrimSpace(string(output)), err
+	}
 
-	output, err := gitBranchCmd.Output()
+	// A detached HEAD prints nothing, not even a newline.
+	output, err := c.output("branch", "--show-current")
 	if err != nil {
 		return "", err
 	}
-
-	output = output[:len(output)-1] // trim trailing newline
-
-	return string(output), nil
+	return strings.TrimSpace(string(output)), nil
 }
 
 func (c *ShellClient) CreateBranch(name, from string) error {
@@ -74,14 +159,132 @@ func (c *ShellClient) CreateBranch(name, from string) error {
 
 	gitArgs = append(gitArgs, from)
 
-	gitCmd := exec.Command("git", gitArgs...)
-	gitCmd.Stdout = os.Stdout
-	gitCmd.Stderr = os.Stderr
+	gitCmd, done := c.command(gitArgs...)
+	gitCmd.Stdout = c.stdout()
+	gitCmd.Stderr = c.stderr()
 
-	return gitCmd.Run()
+	return done(gitCmd.Run())
 }
 
 func (c *ShellClient) SwitchBranch(name string) error {
-	fmt.Println("switch not yet implemented")
+	args := []string{"switch", "--quiet", name}
+	if !c.supports(gitSwitch) {
+		// The trailing -- keeps checkout from taking a branch named like a file for the file.
+		args = []string{"checkout", "--quiet", name, "--"}
+	}
+	gitCmd, done := c.command(args...)
+	gitCmd.Stderr = c.stderr()
+
+	return done(gitCmd.Run())
+}
+
+func (c *ShellClient) ChangedFiles(base string) ([]string, error) {
+	// -z leaves paths unquoted and ends each with a NUL, so paths with spaces or newlines come through whole.
+	output, err := c.output("diff", "--name-only", "-z", base+"...HEAD")
+	if err != nil {
+		return nil, err
+	}
+	paths := strings.Split(string(output), "\x00")
+	return paths[:len(paths)-1], nil
+}
+
+func (c *ShellClient) MergeBase(a, b string) (string, error) {
+	output, err := c.output("merge-base", a, b)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+func (c *ShellClient) GetConfigValues(key string) ([]string, error) {
+	if value, ok := overridden(key); ok {
+		return []string{value}, nil
+	}
+	output, err := c.output("config", "--get-all", key)
+
+	// git config exits with status 1 when the key is not set
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(strings.TrimSpace(string(output)), "\n"), nil
+}
+
+func (c *ShellClient) Merge(rev string) error {
+	gitCmd, done := c.command("merge", "--no-edit", rev)
+	gitCmd.Stdout = c.stdout()
+	gitCmd.Stderr = c.stderr()
+
+	return done(gitCmd.Run())
+}
+
+func (c *ShellClient) Push(remote, branch string) error {
+	gitCmd, done := c.command("push", "--set-upstream", remote, branch)
+	gitCmd.Stdout = c.stdout()
+	gitCmd.Stderr = c.stderr()
+
+	return done(gitCmd.Run())
+}
+
+func (c *ShellClient) CommitPaths(message string, paths []string) error {
+	gitCmd, done := c.command(append([]string{"commit", "--quiet", "--message", message, "--"}, paths...)...)
+	gitCmd.Stderr = c.stderr()
+
+	return done(gitCmd.Run())
+}
+
+func (c *ShellClient) CreateTag(name, message string) error {
+	gitCmd, done := c.command("tag", "--annotate", "--cleanup=verbatim", "--file=-", name)
+	gitCmd.Stdin = strings.NewReader(message)
+	gitCmd.Stderr = c.stderr()
+
+	return done(gitCmd.Run())
+}
+
+func (c *ShellClient) PushTag(remote, tag string) error {
+	gitCmd, done := c.command("push", remote, "refs/tags/"+tag)
+	gitCmd.Stdout = c.stdout()
+	gitCmd.Stderr = c.stderr()
+
+	return done(gitCmd.Run())
+}
+
+func (c *ShellClient) Fetch(remote string) error {
+	gitCmd, done := c.command(c.fetchArgs(remote)...)
+	return done(gitCmd.Run())
+}
+
+func (c *ShellClient) PushRef(remote, ref string) error {
+	gitCmd, done := c.command("push", remote, ref+":"+ref)
+	gitCmd.Stdout = c.stdout()
+	gitCmd.Stderr = c.stderr()
+
+	return done(gitCmd.Run())
+}
+
+func (c *ShellClient) FetchRef(remote, ref, dest string) error {
+	var stderr strings.Builder
+	gitCmd, done := c.command(c.fetchArgs(remote, "+"+ref+":"+dest)...)
+	gitCmd.Stderr = &stderr
+
+	err := done(gitCmd.Run())
+	if err != nil && strings.Contains(stderr.String(), "couldn't find remote ref") {
+		return fmt.Errorf("%w: %s %s", ErrRemoteRefNotFound, remote, ref)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
 	return nil
 }
+
+// fetchArgs returns the arguments to quietly fetch from remote, leaving FETCH_HEAD alone where git can.
+func (c *ShellClient) fetchArgs(remote string, refspecs ...string) []string {
+	args := []string{"fetch", "--quiet"}
+	if c.supports(gitNoWriteFetchHead) {
+		args = append(args, "--no-write-fetch-head")
+	}
+	return append(append(args, remote), refspecs...)
+}
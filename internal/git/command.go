@@ -0,0 +1,117 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultTimeout bounds how long a git command a [ShellClient] runs may take, long enough for a big
+	// fetch or push on a slow connection.
+	DefaultTimeout = 10 * time.Minute
+
+	// commandWaitDelay is how long a killed git command's children, like ssh or a credential helper, may
+	// keep its output open before it is given up on.
+	commandWaitDelay = 5 * time.Second
+)
+
+// TimeoutError reports a git command that was killed because it ran longer than it was allowed to, as
+// one waiting on a credential prompt nobody can answer would.
+type TimeoutError struct {
+	Args    []string // The arguments git was run with, e.g. fetch --quiet origin
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("git %s timed out after %s", e.Args[0], e.Timeout)
+}
+
+// gitCommand returns a git command running args, which is killed once ctx is done or, unless timeout is
+// 0, timeout passes. done must be called with the command's error once it has finished: it releases the
+// timer, and reports a command killed for running too long as a [*TimeoutError] and one killed because
+// ctx is done as ctx's error. Any other error is returned unchanged, so an [*exec.ExitError] can still
+// be told apart.
+func gitCommand(ctx context.Context, timeout time.Duration, args ...string) (cmd *exec.Cmd, done func(error) error) {
+	limited, cancel := ctx, context.CancelFunc(func() {})
+	if timeout > 0 {
+		limited, cancel = context.WithTimeout(ctx, timeout)
+	}
+	cmd = exec.CommandContext(limited, "git", args...)
+	cmd.WaitDelay = commandWaitDelay
+	started := time.Now()
+	return cmd, func(err error) error {
+		defer cancel()
+		switch {
+		case err == nil:
+		case ctx.Err() != nil:
+			err = ctx.Err()
+		case errors.Is(limited.Err(), context.DeadlineExceeded):
+			err = &TimeoutError{Args: args, Timeout: timeout}
+		}
+		logCommand(args, time.Since(started), err)
+		return err
+	}
+}
+
+// logCommand logs that git ran with args for elapsed, and failed with err unless it's nil.
+func logCommand(args []string, elapsed time.Duration, err error) {
+	if !logging(slog.LevelInfo) {
+		return
+	}
+	attrs := []any{"args", strings.Join(args, " "), "elapsed", elapsed.Round(time.Millisecond)}
+	if err != nil {
+		attrs = append(attrs, "err", err)
+	}
+	logAt(slog.LevelInfo, "ran git", attrs...)
+}
+
+// command returns a git command running args for the client, as [gitCommand] does with its Context and
+// Timeout.
+func (c *ShellClient) command(args ...string) (*exec.Cmd, func(error) error) {
+	ctx := c.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return gitCommand(ctx, c.Timeout, args...)
+}
+
+// stdout returns where the output git commands show goes.
+func (c *ShellClient) stdout() io.Writer {
+	if c.Stdout == nil {
+		return os.Stdout
+	}
+	return c.Stdout
+}
+
+// stderr returns where the errors and progress git commands show go.
+func (c *ShellClient) stderr() io.Writer {
+	if c.Stderr == nil {
+		return os.Stderr
+	}
+	return c.Stderr
+}
+
+// output runs git with args for the client and returns what it printed, like [exec.Cmd.Output].
+func (c *ShellClient) output(args ...string) ([]byte, error) {
+	cmd, done := c.command(args...)
+	output, err := cmd.Output()
+	return output, done(err)
+}
+
+// Run runs git with args for the client, for the odd command no method covers, like setting up a
+// repository from scratch. What git printed is in the error if it fails.
+func (c *ShellClient) Run(args ...string) error {
+	cmd, done := c.command(args...)
+	output, err := cmd.CombinedOutput()
+	if err = done(err); err != nil {
+		return fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGitCommand(t *testing.T) {
+	if !GitInstalled() {
+		t.Skip("git isn't installed")
+	}
+
+	// hash-object waits for its input until the pipe is closed, like a command stuck on a prompt.
+	hung := func(ctx context.Context, timeout time.Duration) error {
+		cmd, done := gitCommand(ctx, timeout, "hash-object", "--stdin")
+		stdin, input, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer input.Close()
+		defer stdin.Close()
+		cmd.Stdin = stdin
+		return done(cmd.Run())
+	}
+	var timeout *TimeoutError
+	if err := hung(context.Background(), 50*time.Millisecond); !errors.As(err, &timeout) || timeout.Args[0] != "hash-object" {
+		t.Errorf("a command running past its timeout failed with %v, want a *TimeoutError", err)
+	} else if want := "git hash-object timed out after 50ms"; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err, want)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if err := hung(ctx, time.Minute); !errors.Is(err, context.Canceled) {
+		t.Errorf("a command whose context is canceled failed with %v, want %v", err, context.Canceled)
+	}
+
+	// Failures of the command itself are left as they are.
+	cmd, done := gitCommand(context.Background(), time.Minute, "no-such-command")
+	var exitErr *exec.ExitError
+	if err := done(cmd.Run()); !errors.As(err, &exitErr) {
+		t.Errorf("a failing command returned %v, want an *exec.ExitError", err)
+	}
+	cmd, done = gitCommand(context.Background(), 0, "version")
+	if err := done(cmd.Run()); err != nil {
+		t.Errorf("git version without a timeout = %v", err)
+	}
+}
+
+func TestShellClientRun(t *testing.T) {
+	if !GitInstalled() {
+		t.Skip("git isn't installed")
+	}
+	c := NewShellClient()
+	if err := c.Run("version"); err != nil {
+		t.Errorf("Run(version) = %v", err)
+	}
+	var exitErr *exec.ExitError
+	if err := c.Run("no-such-command"); !errors.As(err, &exitErr) || !strings.Contains(err.Error(), "no-such-command") {
+		t.Errorf("Run() of a failing command = %v, want an *exec.ExitError saying what git printed", err)
+	}
+}
+
+func TestShellClientChangedFiles(t *testing.T) {
+	if !GitInstalled() {
+		t.Skip("git isn't installed")
+	}
+	t.Chdir(t.TempDir())
+	c := NewShellClient()
+	commit := func(message string, files ...string) {
+		t.Helper()
+		for _, name := range files {
+			if err := os.WriteFile(name, []byte(message+"\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		args := append([]string{"add", "--"}, files...)
+		if err := c.Run(args...); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Run("-c", "user.name=Ann", "-c", "user.email=ann@example.com", "commit", "--quiet", "--message", message); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.Run("init", "--quiet"); err != nil {
+		t.Fatal(err)
+	}
+	commit("base", "a.txt")
+	if err := c.Run("branch", "base"); err != nil {
+		t.Fatal(err)
+	}
+	commit("feature", "with space.txt", "with\nnewline.txt")
+
+	files, err := c.ChangedFiles("base")
+	if want := []string{"with\nnewline.txt", "with space.txt"}; err != nil || !slices.Equal(files, want) {
+		t.Errorf("ChangedFiles() = %q, %v, want %q", files, err, want)
+	}
+}
+
+func TestShellClientGetCurrentBranch(t *testing.T) {
+	if !GitInstalled() {
+		t.Skip("git isn't installed")
+	}
+	t.Chdir(t.TempDir())
+	c := NewShellClient()
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"symbolic-ref", "HEAD", "refs/heads/main"},
+		{"-c", "user.name=Ann", "-c", "user.email=ann@example.com", "commit", "--quiet", "--allow-empty", "--message", "base"},
+	} {
+		if err := c.Run(args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if branch, err := c.GetCurrentBranch(); err != nil || branch != "main" {
+		t.Errorf("GetCurrentBranch() = %q, %v, want main", branch, err)
+	}
+	if err := c.Run("checkout", "--quiet", "--detach"); err != nil {
+		t.Fatal(err)
+	}
+	if branch, err := c.GetCurrentBranch(); err != nil || branch != "" {
+		t.Errorf("GetCurrentBranch() with HEAD detached = %q, %v, want none", branch, err)
+	}
+}
+
+func TestShellClientIsBranchDirty(t *testing.T) {
+	if !GitInstalled() {
+		t.Skip("git isn't installed")
+	}
+	t.Chdir(t.TempDir())
+	c := NewShellClient()
+	if err := os.WriteFile("a.txt", []byte("a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"add", "a.txt"},
+		{"-c", "user.name=Ann", "-c", "user.email=ann@example.com", "commit", "--quiet", "--message", "base"},
+	} {
+		if err := c.Run(args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A clean work tree has git diff succeed, with no error to inspect.
+	if dirty, err := c.IsBranchDirty(); err != nil || dirty {
+		t.Errorf("IsBranchDirty() of a clean work tree = %v, %v, want false", dirty, err)
+	}
+	if err := os.WriteFile("a.txt", []byte("b\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if dirty, err := c.IsBranchDirty(); err != nil || !dirty {
+		t.Errorf("IsBranchDirty() with a change = %v, %v, want true", dirty, err)
+	}
+}
@@ -0,0 +1,109 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCommit = errors.New("invalid commit")
+
+// CreateCommit serializes a commit and stores it in the object store, returning its hash.
+//
+// The message is stored with a trailing newline, as git commit does. Nothing is checked out and no ref
+// is moved, so callers point a branch at the result themselves. When the repository's config sets
+// commit.gpgsign, the commit is signed as [Repository.CommitSigner] describes, like git commit does.
+func (repo *Repository) CreateCommit(tree string, parents []string, author, committer Signature, message string) (string, error) {
+	signer, err := repo.CommitSigner(committer)
+	if err != nil {
+		return "", err
+	}
+	return repo.createCommit(tree, parents, author, committer, message, signer)
+}
+
+// createCommit is [Repository.CreateCommit] signing with signer, or not at all if it is nil, whatever the
+// config says. Git doesn't sign the commits that record stashes and notes, and neither does plain.
+func (repo *Repository) createCommit(tree string, parents []string, author, committer Signature, message string, signer CommitSigner) (string, error) {
+	commit := Commit{
+		Tree:      tree,
+		Parents:   parents,
+		Author:    author,
+		Committer: committer,
+		Message:   strings.TrimSuffix(message, "\n"),
+	}
+	if signer != nil {
+		var err error
+		if commit, err = repo.SignCommit(commit, signer); err != nil {
+			return "", err
+		}
+	}
+
+	data, err := EncodeCommit(commit)
+	if err != nil {
+		return "", err
+	}
+	return repo.Encoder().Encode(CommitObject, data)
+}
+
+// EncodeCommit writes a commit in git's canonical format, without the object header. It is the inverse
+// of [Decoder.DecodeCommit]: a commit it decoded encodes back to the same bytes, and so the same hash,
+// which lets rewritten or re-signed commits change only what they mean to.
+//
+// The tree, parents, author, and committer are written first, followed by c.Headers in order, a blank
+// line, and the message with the newline decoding stripped put back unless c.Unterminated is set.
+func EncodeCommit(c Commit) ([]byte, error) {
+	if !isFullHash(c.Tree) {
+		return nil, fmt.Errorf("%w: tree %q is not a full hash", ErrInvalidCommit, c.Tree)
+	}
+	for _, parent := range c.Parents {
+		if !isFullHash(parent) {
+			return nil, fmt.Errorf("%w: parent %q is not a full hash", ErrInvalidCommit, parent)
+		}
+	}
+	for _, sig := range []Signature{c.Author, c.Committer} {
+		if sig.Name == "" || strings.ContainsAny(sig.Name+sig.Email, "<>\n") {
+			return nil, fmt.Errorf("%w: malformed identity %q <%s>", ErrInvalidCommit, sig.Name, sig.Email)
+		}
+	}
+	for _, header := range c.Headers {
+		if header.Name == "" || strings.ContainsAny(header.Name, " \n") {
+			return nil, fmt.Errorf("%w: malformed header name %q", ErrInvalidCommit, header.Name)
+		}
+	}
+
+	var b bytes.Buffer
+	b.WriteString("tree " + c.Tree + "\n")
+	for _, parent := range c.Parents {
+		b.WriteString("parent " + parent + "\n")
+	}
+	b.WriteString("author " + formatSignature(c.Author) + "\n")
+	b.WriteString("committer " + formatSignature(c.Committer) + "\n")
+	for _, header := range c.Headers {
+		b.WriteString(header.Name + " " + strings.ReplaceAll(header.Value, "\n", "\n ") + "\n")
+	}
+	b.WriteString("\n")
+	b.WriteString(c.Message)
+	if !c.Unterminated {
+		b.WriteString("\n")
+	}
+	return b.Bytes(), nil
+}
+
+// formatSignature writes an identity in git's "Name <email> 1703123456 +0000" form.
+//
+// Decoded signatures keep the offset exactly as it was written in their zone's name, so that oddities
+// like -0000 survive a round trip.
+func formatSignature(sig Signature) string {
+	offset := sig.Time.Format("-0700")
+	if name, seconds := sig.Time.Zone(); len(name) == 5 && (name[0] == '+' || name[0] == '-') {
+		if parsed, err := time.Parse("-0700", name); err == nil {
+			if _, parsedSeconds := parsed.Zone(); parsedSeconds == seconds {
+				offset = name
+			}
+		}
+	}
+	return sig.Name + " <" + sig.Email + "> " + strconv.FormatInt(sig.Time.Unix(), 10) + " " + offset
+}
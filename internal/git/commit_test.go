@@ -0,0 +1,117 @@
+package git
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCreateCommit(t *testing.T) {
+	gitDir := newTestRepo(t)
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parent := writeTestCommit(t, gitDir, "parent")
+	sig := Signature{
+		Name:  "Jane Smith",
+		Email: "jane.smith@example.com",
+		Time:  time.Unix(1703123457, 0).In(time.FixedZone("", -5*3600)),
+	}
+	emptyTree := "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+
+	hash, err := repo.CreateCommit(emptyTree, []string{parent}, sig, sig, "checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Matches git hash-object -t commit for the same content.
+	if hash != "cdbe711d7dd1e182d7b979853b11fc7f32b05e6e" {
+		t.Fatalf("unexpected commit hash %s", hash)
+	}
+
+	writeTestRef(t, gitDir, "refs/heads/main", hash)
+	history, err := GetHistoryFor("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	head := history.Head
+	if head.Message != "checkpoint" || !slices.Equal(head.Parents, []string{parent}) || head.Tree != emptyTree {
+		t.Fatalf("commit did not round trip: %+v", head)
+	}
+	if !head.Author.Time.Equal(sig.Time) || head.Author.Time.Format("-0700") != "-0500" {
+		t.Fatalf("author time did not round trip: %s", head.Author.Time)
+	}
+}
+
+func TestCreateCommitRejectsBadInput(t *testing.T) {
+	newTestRepo(t)
+	repo, _ := OpenRepository()
+	sig := Signature{Name: "Jane", Email: "jane@example.com", Time: time.Now()}
+
+	if _, err := repo.CreateCommit("not-a-hash", nil, sig, sig, "msg"); !errors.Is(err, ErrInvalidCommit) {
+		t.Fatalf("expected ErrInvalidCommit for a bad tree, got %v", err)
+	}
+
+	bad := Signature{Name: "Jane <evil>", Email: "jane@example.com", Time: time.Now()}
+	if _, err := repo.CreateCommit("4b825dc642cb6eb9a060e54bf8d69288fbee4904", nil, bad, sig, "msg"); !errors.Is(err, ErrInvalidCommit) {
+		t.Fatalf("expected ErrInvalidCommit for a bad identity, got %v", err)
+	}
+}
+
+func TestEncodeCommitRoundTrip(t *testing.T) {
+	gitDir := newTestRepo(t)
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	head := "tree 4b825dc642cb6eb9a060e54bf8d69288fbee4904\n" +
+		"parent 1111111111111111111111111111111111111111\n" +
+		"parent 2222222222222222222222222222222222222222\n" +
+		"author Jane Smith <jane@example.com> 1703123457 -0500\n" +
+		"committer Bob <bob@example.com> 1703123999 +0530\n"
+	bodies := map[string]string{
+		"plain":         head + "\nFix the thing\n\nLonger explanation.\n",
+		"trailing":      head + "\nKeep blank lines\n\n\n",
+		"unterminated":  head + "\nNo newline at the end",
+		"empty message": head + "\n",
+		"negative zero": strings.Replace(head, "+0530", "-0000", 1) + "\nmsg\n",
+		"signed": head +
+			"encoding ISO-8859-1\n" +
+			"gpgsig -----BEGIN PGP SIGNATURE-----\n" +
+			" \n" +
+			" iQEzBAABCAAdFiEE\n" +
+			" -----END PGP SIGNATURE-----\n" +
+			"\nSigned\n",
+	}
+
+	for name, body := range bodies {
+		hash := writeTestObject(t, gitDir, CommitObject, body)
+		commit, err := repo.ReadCommit(hash)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		encoded, err := EncodeCommit(commit)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if string(encoded) != body {
+			t.Errorf("%s: encoded as\n%q\nwant\n%q", name, encoded, body)
+		}
+	}
+
+	hash := writeTestObject(t, gitDir, CommitObject, bodies["signed"])
+	commit, _ := repo.ReadCommit(hash)
+	want := []CommitHeader{
+		{Name: "encoding", Value: "ISO-8859-1"},
+		{Name: "gpgsig", Value: "-----BEGIN PGP SIGNATURE-----\n\niQEzBAABCAAdFiEE\n-----END PGP SIGNATURE-----"},
+	}
+	if !slices.Equal(commit.Headers, want) || commit.Message != "Signed" {
+		t.Errorf("decoded headers %+v and message %q", commit.Headers, commit.Message)
+	}
+}
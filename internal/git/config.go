@@ -0,0 +1,659 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+var ErrBadConfig = errors.New("bad config")
+
+// maxIncludeDepth is how deeply config files may include each other, the limit git uses to stop include
+// cycles.
+const maxIncludeDepth = 10
+
+// ConfigEntry is one variable set in a config file.
+type ConfigEntry struct {
+	Key     string // section.name or section.subsection.name, with the section and name lowercased
+	Value   string
+	NoValue bool   // The variable was given without "=", which git reads as true
+	File    string // The file that set it
+}
+
+// Config is git configuration read from the files git reads, in the same order, so later entries
+// override earlier ones. A new Config is created by calling [Repository.Config] or [GlobalConfig].
+type Config struct {
+	entries []ConfigEntry
+}
+
+// RemoteConfig is a remote as configured in remote.<name>.* variables.
+type RemoteConfig struct {
+	Name     string
+	URLs     []string // Rewritten as url.<base>.insteadOf says
+	PushURLs []string // Rewritten likewise, or without any, the URLs as url.<base>.pushInsteadOf rewrites them
+	Fetch    []string // Refspecs fetched by default
+}
+
+// GlobalConfig reads the configuration that applies outside any repository: the system config, then
+// $XDG_CONFIG_HOME/git/config (or ~/.config/git/config), then ~/.gitconfig, following includes.
+// GIT_CONFIG_SYSTEM, GIT_CONFIG_NOSYSTEM, and GIT_CONFIG_GLOBAL are honoured as git does.
+func GlobalConfig() (*Config, error) {
+	return loadConfig(nil)
+}
+
+// Config reads the configuration that applies in this repository: the [GlobalConfig] files, then the
+// repository's own config and, when extensions.worktreeConfig is on, the worktree's config.worktree.
+// Conditional includes are resolved against this repository.
+func (repo *Repository) Config() (*Config, error) {
+	return loadConfig(repo)
+}
+
+func loadConfig(repo *Repository) (*Config, error) {
+	l := configLoader{repo: repo}
+
+	noSystem, ok := parseConfigBool(os.Getenv("GIT_CONFIG_NOSYSTEM"))
+	if !ok {
+		return nil, fmt.Errorf("%w: GIT_CONFIG_NOSYSTEM = %q is not a boolean", ErrBadConfig, os.Getenv("GIT_CONFIG_NOSYSTEM"))
+	}
+
+	var files []string
+	if !noSystem {
+		system := os.Getenv("GIT_CONFIG_SYSTEM")
+		if system == "" {
+			system = "/etc/gitconfig"
+		}
+		files = append(files, system)
+	}
+	if global := os.Getenv("GIT_CONFIG_GLOBAL"); global != "" {
+		files = append(files, global)
+	} else {
+		xdg := os.Getenv("XDG_CONFIG_HOME")
+		home, err := os.UserHomeDir()
+		if xdg == "" && err == nil {
+			xdg = filepath.Join(home, ".config")
+		}
+		if xdg != "" {
+			files = append(files, filepath.Join(xdg, "git", "config"))
+		}
+		if err == nil {
+			files = append(files, filepath.Join(home, ".gitconfig"))
+		}
+	}
+	if repo != nil {
+		files = append(files, filepath.Join(repo.CommonDir, "config"))
+	}
+	for _, file := range files {
+		if err := l.load(file, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	c := &Config{entries: l.entries}
+	if repo != nil {
+		if worktree, _ := c.Bool("extensions.worktreeConfig"); worktree {
+			if err := l.load(filepath.Join(repo.GitDir, "config.worktree"), 0); err != nil {
+				return nil, err
+			}
+			c.entries = l.entries
+		}
+	}
+	return c, nil
+}
+
+// ParseConfig parses the config file data, named file in errors and in the returned entries, without
+// following its includes.
+func ParseConfig(data []byte, file string) ([]ConfigEntry, error) {
+	var entries []ConfigEntry
+	err := parseConfig(data, file, func(entry ConfigEntry) error {
+		entries = append(entries, entry)
+		return nil
+	})
+	return entries, err
+}
+
+// configOverride is asked for every key before the config files; see [SetConfigOverride].
+var configOverride atomic.Pointer[func(key string) (string, bool)]
+
+// SetConfigOverride has every lookup of a key, through a [Config] or a [Client]'s GetConfigValues, ask
+// override first, for the whole process. When override reports a value, it is the key's only value, as
+// when a program lets environment variables override its git config. Pass nil to read only the files,
+// which is the default.
+func SetConfigOverride(override func(key string) (string, bool)) {
+	if override == nil {
+		configOverride.Store(nil)
+		return
+	}
+	configOverride.Store(&override)
+}
+
+// overridden returns the value [SetConfigOverride] gives key, if any.
+func overridden(key string) (string, bool) {
+	override := configOverride.Load()
+	if override == nil {
+		return "", false
+	}
+	return (*override)(key)
+}
+
+// Get returns the last value set for key, like git config --get, and whether it is set at all.
+// Keys are matched as git matches them: ignoring the case of the section and name.
+func (c *Config) Get(key string) (string, bool) {
+	if value, ok := overridden(key); ok {
+		return value, true
+	}
+	key = normalizeConfigKey(key)
+	for i := len(c.entries) - 1; i >= 0; i-- {
+		if c.entries[i].Key == key {
+			return c.entries[i].Value, true
+		}
+	}
+	return "", false
+}
+
+// GetAll returns every value set for key, in the order they were read, or nil if it isn't set.
+func (c *Config) GetAll(key string) []string {
+	if value, ok := overridden(key); ok {
+		return []string{value}
+	}
+	key = normalizeConfigKey(key)
+	var values []string
+	for _, entry := range c.entries {
+		if entry.Key == key {
+			values = append(values, entry.Value)
+		}
+	}
+	return values
+}
+
+// Entries returns every variable set, in the order they were read.
+func (c *Config) Entries() []ConfigEntry {
+	return c.entries
+}
+
+// Bool returns key as a boolean: true, yes, on, 1, or no value at all are true, and false, no, off, 0,
+// or an empty value are false. Unset keys are false.
+func (c *Config) Bool(key string) (bool, error) {
+	if value, ok := overridden(key); ok {
+		on, ok := parseConfigBool(value)
+		if !ok {
+			return false, fmt.Errorf("%w: %s = %q is not a boolean", ErrBadConfig, key, value)
+		}
+		return on, nil
+	}
+	key = normalizeConfigKey(key)
+	for i := len(c.entries) - 1; i >= 0; i-- {
+		entry := c.entries[i]
+		if entry.Key != key {
+			continue
+		}
+		if entry.NoValue {
+			return true, nil
+		}
+		if on, ok := parseConfigBool(entry.Value); ok {
+			return on, nil
+		}
+		return false, fmt.Errorf("%w: %s = %q in %s is not a boolean", ErrBadConfig, key, entry.Value, entry.File)
+	}
+	return false, nil
+}
+
+// parseConfigBool reads a boolean the way git does, reporting false if value isn't one.
+func parseConfigBool(value string) (bool, bool) {
+	switch strings.ToLower(value) {
+	case "true", "yes", "on", "1":
+		return true, true
+	case "false", "no", "off", "0", "":
+		return false, true
+	}
+	return false, false
+}
+
+// Int returns key as an integer, which may carry a k, m, or g suffix for multiples of 1024, or def if it
+// isn't set.
+func (c *Config) Int(key string, def int64) (int64, error) {
+	value, ok := c.Get(key)
+	if !ok {
+		return def, nil
+	}
+	multiplier := int64(1)
+	if value != "" {
+		switch strings.ToLower(value[len(value)-1:]) {
+		case "k":
+			multiplier = 1 << 10
+		case "m":
+			multiplier = 1 << 20
+		case "g":
+			multiplier = 1 << 30
+		}
+		if multiplier != 1 {
+			value = value[:len(value)-1]
+		}
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s = %q is not a number", ErrBadConfig, key, value)
+	}
+	return n * multiplier, nil
+}
+
+// Path returns key as a path, with a leading ~/ expanded to the home directory, or "" if it isn't set.
+func (c *Config) Path(key string) (string, error) {
+	value, _ := c.Get(key)
+	return expandHome(value)
+}
+
+// DefaultBranch returns init.defaultBranch, the branch new repositories start on, or master.
+func (c *Config) DefaultBranch() string {
+	if branch, _ := c.Get("init.defaultBranch"); branch != "" {
+		return branch
+	}
+	return "master"
+}
+
+// ObjectFormat returns the hash format extensions.objectFormat names, SHA-1 if it isn't set.
+func (c *Config) ObjectFormat() (HashFormat, error) {
+	value, _ := c.Get("extensions.objectFormat")
+	switch strings.ToLower(value) {
+	case "", "sha1":
+		return SHA1, nil
+	case "sha256":
+		return SHA256, nil
+	}
+	return 0, fmt.Errorf("%w: unknown object format %q", ErrBadConfig, value)
+}
+
+// Remotes returns every remote with a remote.<name>.* variable, in the order they first appear.
+func (c *Config) Remotes() []RemoteConfig {
+	var remotes []RemoteConfig
+	index := map[string]int{}
+	for _, entry := range c.entries {
+		rest, ok := strings.CutPrefix(entry.Key, "remote.")
+		dot := strings.LastIndexByte(rest, '.')
+		if !ok || dot <= 0 {
+			continue
+		}
+		name, variable := rest[:dot], rest[dot+1:]
+		i, ok := index[name]
+		if !ok {
+			i = len(remotes)
+			index[name] = i
+			remotes = append(remotes, RemoteConfig{Name: name})
+		}
+		switch variable {
+		case "url":
+			remotes[i].URLs = append(remotes[i].URLs, entry.Value)
+		case "pushurl":
+			remotes[i].PushURLs = append(remotes[i].PushURLs, entry.Value)
+		case "fetch":
+			remotes[i].Fetch = append(remotes[i].Fetch, entry.Value)
+		}
+	}
+
+	// As with git, push URLs are rewritten by insteadOf, and without any, the URLs as pushInsteadOf
+	// rewrites them are pushed to.
+	for i := range remotes {
+		r := &remotes[i]
+		for j, url := range r.PushURLs {
+			r.PushURLs[j] = c.RewriteURL(url, false)
+		}
+		addPushURLs := len(r.PushURLs) == 0
+		for j, url := range r.URLs {
+			if pushURL, ok := c.rewriteURL(url, ".pushinsteadof"); ok && addPushURLs {
+				r.PushURLs = append(r.PushURLs, pushURL)
+			}
+			r.URLs[j] = c.RewriteURL(url, false)
+		}
+	}
+	return remotes
+}
+
+// RewriteURL returns url as git config url.<base>.insteadOf rewrites it: the longest insteadOf value url
+// starts with is replaced by its base. For a URL to push to, url.<base>.pushInsteadOf is tried first.
+func (c *Config) RewriteURL(url string, push bool) string {
+	if push {
+		if rewritten, ok := c.rewriteURL(url, ".pushinsteadof"); ok {
+			return rewritten
+		}
+	}
+	rewritten, _ := c.rewriteURL(url, ".insteadof")
+	return rewritten
+}
+
+// rewriteURL rewrites url with the longest of the url.<base><variable> values that it starts with,
+// reporting whether one did.
+func (c *Config) rewriteURL(url, variable string) (string, bool) {
+	var base, prefix string
+	found := false
+	for _, entry := range c.entries {
+		rest, ok := strings.CutPrefix(entry.Key, "url.")
+		if !ok || entry.NoValue {
+			continue
+		}
+		if b, ok := strings.CutSuffix(rest, variable); ok && strings.HasPrefix(url, entry.Value) && (!found || len(entry.Value) > len(prefix)) {
+			base, prefix, found = b, entry.Value, true
+		}
+	}
+	if !found {
+		return url, false
+	}
+	return base + url[len(prefix):], true
+}
+
+// configLoader reads config files, splicing included files in where they are included.
+type configLoader struct {
+	repo    *Repository // The repository conditional includes are checked against, or nil
+	entries []ConfigEntry
+}
+
+// load reads the config file at name, which may be missing, and the files it includes.
+func (l *configLoader) load(name string, depth int) error {
+	if depth > maxIncludeDepth {
+		return fmt.Errorf("%w: includes nested more than %d deep at %s", ErrBadConfig, maxIncludeDepth, name)
+	}
+	data, err := os.ReadFile(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return parseConfig(data, name, func(entry ConfigEntry) error {
+		l.entries = append(l.entries, entry)
+
+		var include bool
+		if entry.Key == "include.path" {
+			include = true
+		} else if rest, ok := strings.CutPrefix(entry.Key, "includeif."); ok {
+			if condition, ok := strings.CutSuffix(rest, ".path"); ok {
+				if include, err = l.matches(condition, name); err != nil {
+					return err
+				}
+			}
+		}
+		if !include || entry.Value == "" {
+			return nil
+		}
+
+		path, err := expandHome(entry.Value)
+		if err != nil {
+			return err
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(name), path)
+		}
+		return l.load(path, depth+1)
+	})
+}
+
+// matches reports whether the includeIf condition holds, for an include in the file from. Conditions
+// plain doesn't know, like git's hasconfig:, never hold.
+func (l *configLoader) matches(condition, from string) (bool, error) {
+	kind, pattern, ok := strings.Cut(condition, ":")
+	if !ok || l.repo == nil {
+		return false, nil
+	}
+
+	switch kind {
+	case "gitdir", "gitdir/i":
+		pattern, err := expandHome(pattern)
+		if err != nil {
+			return false, err
+		}
+		if rest, ok := strings.CutPrefix(pattern, "./"); ok {
+			pattern = filepath.Join(filepath.Dir(from), rest)
+		}
+		pattern = filepath.ToSlash(pattern)
+		if !strings.HasPrefix(pattern, "/") {
+			pattern = "**/" + pattern
+		}
+		if strings.HasSuffix(pattern, "/") {
+			pattern += "**"
+		}
+		gitDir := filepath.ToSlash(l.repo.GitDir)
+		if kind == "gitdir/i" {
+			pattern, gitDir = strings.ToLower(pattern), strings.ToLower(gitDir)
+		}
+		return matchPattern(pattern, gitDir), nil
+
+	case "onbranch":
+		head, err := l.repo.resolveRef("HEAD")
+		if err != nil {
+			return false, nil
+		}
+		branch, ok := strings.CutPrefix(head, "ref: refs/heads/")
+		if !ok {
+			return false, nil
+		}
+		if strings.HasSuffix(pattern, "/") {
+			pattern += "**"
+		}
+		return matchPattern(pattern, branch), nil
+	}
+	return false, nil
+}
+
+// parseConfig parses the config file data, calling emit with every variable in it.
+func parseConfig(data []byte, file string, emit func(ConfigEntry) error) error {
+	// Like git, allow a UTF-8 byte order mark, which some Windows editors save files with.
+	s := strings.TrimPrefix(string(data), "\ufeff")
+	line := 1
+	bad := func(format string, args ...any) error {
+		return fmt.Errorf("%w: line %d in %s: %s", ErrBadConfig, line, file, fmt.Sprintf(format, args...))
+	}
+	skipComment := func(i int) int {
+		for i < len(s) && s[i] != '\n' {
+			i++
+		}
+		return i
+	}
+
+	section := ""
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case c == '\n':
+			line++
+			i++
+			continue
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+			continue
+		case c == '#' || c == ';':
+			i = skipComment(i)
+			continue
+		case c == '[':
+			var err error
+			if section, i, err = parseConfigSection(s, i+1); err != nil {
+				return bad("%v", err)
+			}
+			continue
+		}
+
+		start := i
+		for i < len(s) && (isAlnum(s[i]) || s[i] == '-') {
+			i++
+		}
+		name := strings.ToLower(s[start:i])
+		if name == "" || !isLetter(name[0]) {
+			return bad("invalid variable name")
+		}
+		if section == "" {
+			return bad("variable %s outside any section", name)
+		}
+		entry := ConfigEntry{Key: section + "." + name, File: file}
+
+		for i < len(s) && (s[i] == ' ' || s[i] == '\t' || s[i] == '\r') {
+			i++
+		}
+		switch {
+		case i >= len(s) || s[i] == '\n':
+			entry.NoValue = true
+		case s[i] == '#' || s[i] == ';':
+			entry.NoValue = true
+			i = skipComment(i)
+		case s[i] == '=':
+			value, next, lines, err := parseConfigValue(s, i+1)
+			if err != nil {
+				return bad("%v", err)
+			}
+			entry.Value, i = value, next
+			line += lines
+		default:
+			return bad("expected = after %s", name)
+		}
+		if err := emit(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseConfigSection parses a section header starting just after its "[", returning the section as it
+// prefixes keys and the position after the closing "]".
+func parseConfigSection(s string, i int) (string, int, error) {
+	start := i
+	for i < len(s) && (isAlnum(s[i]) || s[i] == '-' || s[i] == '.') {
+		i++
+	}
+	name := strings.ToLower(s[start:i])
+	if name == "" {
+		return "", i, errors.New("invalid section name")
+	}
+	if i < len(s) && s[i] == ']' {
+		// The deprecated [section.subsection] form, where the subsection is lowercased too.
+		return name, i + 1, nil
+	}
+	if strings.Contains(name, ".") {
+		return "", i, errors.New("invalid section name")
+	}
+
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+		i++
+	}
+	if i >= len(s) || s[i] != '"' {
+		return "", i, errors.New("expected ] or a quoted subsection")
+	}
+	var sub strings.Builder
+	for i++; ; i++ {
+		if i >= len(s) || s[i] == '\n' {
+			return "", i, errors.New("unterminated subsection")
+		}
+		if s[i] == '"' {
+			break
+		}
+		if s[i] == '\\' && i+1 < len(s) && s[i+1] != '\n' {
+			i++
+		}
+		sub.WriteByte(s[i])
+	}
+	if i+1 >= len(s) || s[i+1] != ']' {
+		return "", i, errors.New("expected ] after the subsection")
+	}
+	return name + "." + sub.String(), i + 2, nil
+}
+
+// parseConfigValue parses a value starting just after its "=", returning it along with the position of
+// the newline ending it and how many line continuations it spans.
+func parseConfigValue(s string, i int) (string, int, int, error) {
+	var b strings.Builder
+	spaces := 0 // Unquoted whitespace, kept as spaces only if something follows it
+	quoted := false
+	lines := 0
+	flush := func() {
+		if b.Len() > 0 {
+			b.WriteString(strings.Repeat(" ", spaces))
+		}
+		spaces = 0
+	}
+	add := func(c byte) {
+		flush()
+		b.WriteByte(c)
+	}
+
+	for ; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\n':
+			if quoted {
+				return "", i, lines, errors.New("unterminated quote")
+			}
+			return b.String(), i, lines, nil
+		case c == '\\':
+			i++
+			if i < len(s) && s[i] == '\r' && i+1 < len(s) && s[i+1] == '\n' {
+				i++
+			}
+			if i >= len(s) {
+				return "", i, lines, errors.New("backslash at end of file")
+			}
+			switch s[i] {
+			case '\n':
+				lines++
+			case 'n':
+				add('\n')
+			case 't':
+				add('\t')
+			case 'b':
+				add('\b')
+			case '\\', '"':
+				add(s[i])
+			default:
+				return "", i, lines, fmt.Errorf("invalid escape \\%c", s[i])
+			}
+		case c == '"':
+			quoted = !quoted
+			flush()
+		case quoted:
+			b.WriteByte(c)
+		case c == '#' || c == ';':
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+			return b.String(), i, lines, nil
+		case c == ' ' || c == '\t' || c == '\r':
+			spaces++
+		default:
+			add(c)
+		}
+	}
+	if quoted {
+		return "", i, lines, errors.New("unterminated quote")
+	}
+	return b.String(), i, lines, nil
+}
+
+// normalizeConfigKey lowercases the section and name of a config key, leaving any subsection as is.
+func normalizeConfigKey(key string) string {
+	first := strings.IndexByte(key, '.')
+	last := strings.LastIndexByte(key, '.')
+	if first < 0 {
+		return strings.ToLower(key)
+	}
+	return strings.ToLower(key[:first]) + key[first:last] + strings.ToLower(key[last:])
+}
+
+// expandHome replaces a leading ~/ in path with the user's home directory.
+func expandHome(path string) (string, error) {
+	rest, ok := strings.CutPrefix(path, "~/")
+	if !ok {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, rest), nil
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isAlnum(c byte) bool {
+	return isLetter(c) || c >= '0' && c <= '9'
+}
@@ -0,0 +1,235 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	data := []byte(`# A comment
+[core]
+	bare = false ; trailing comment
+	autocrlf
+[Remote "Origin"]
+	url = "git@example.com:team/app.git"
+	fetch = +refs/heads/*:refs/remotes/Origin/*
+[alias]
+	lg = log --graph \
+	  --oneline
+	say = "  hello \"world\"  " # kept quoted spaces
+	tab = a\tb
+[branch.Main]
+	remote=origin
+`)
+	entries, err := ParseConfig(data, "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Config{entries: entries}
+
+	cases := map[string]string{
+		"core.bare":          "false",
+		"CORE.Bare":          "false",
+		"remote.Origin.url":  "git@example.com:team/app.git",
+		"alias.lg":           "log --graph    --oneline",
+		"alias.say":          `  hello "world"  `,
+		"alias.tab":          "a\tb",
+		"branch.main.remote": "origin",
+	}
+	for key, want := range cases {
+		if got, ok := c.Get(key); !ok || got != want {
+			t.Errorf("Get(%q) = %q, %v, want %q", key, got, ok, want)
+		}
+	}
+	if _, ok := c.Get("remote.origin.url"); ok {
+		t.Error("subsections should be case sensitive")
+	}
+	if autocrlf, err := c.Bool("core.autocrlf"); !autocrlf || err != nil {
+		t.Errorf("a variable without a value should be true, got %v, %v", autocrlf, err)
+	}
+	if remotes := c.Remotes(); len(remotes) != 1 || remotes[0].Name != "Origin" || len(remotes[0].Fetch) != 1 {
+		t.Errorf("Remotes() = %+v", remotes)
+	}
+
+	for _, bad := range []string{"[core\nbare = true\n", "bare = true\n", "[core]\n\tname = \"open\n", "[core]\n\t9lives = 1\n"} {
+		if _, err := ParseConfig([]byte(bad), "bad"); !errors.Is(err, ErrBadConfig) {
+			t.Errorf("ParseConfig(%q) = %v, want ErrBadConfig", bad, err)
+		}
+	}
+
+	bom, err := ParseConfig([]byte("\ufeff[core]\n\tbare = true\n"), "bom")
+	if err != nil || len(bom) != 1 || bom[0].Key != "core.bare" {
+		t.Errorf("ParseConfig with a byte order mark = %+v, %v", bom, err)
+	}
+}
+
+func TestGlobalConfigNoSystem(t *testing.T) {
+	dir := t.TempDir()
+	system := filepath.Join(dir, "gitconfig")
+	if err := os.WriteFile(system, []byte("[plain]\n\tgitBackend = native\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GIT_CONFIG_SYSTEM", system)
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(dir, "none"))
+
+	for value, read := range map[string]bool{"": true, "false": true, "0": true, "true": false, "1": false} {
+		t.Setenv("GIT_CONFIG_NOSYSTEM", value)
+		c, err := GlobalConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := c.Get("plain.gitBackend"); ok != read {
+			t.Errorf("with GIT_CONFIG_NOSYSTEM=%q, reading the system config = %v, want %v", value, ok, read)
+		}
+	}
+
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "maybe")
+	if _, err := GlobalConfig(); !errors.Is(err, ErrBadConfig) {
+		t.Errorf("expected ErrBadConfig for a GIT_CONFIG_NOSYSTEM that isn't a boolean, got %v", err)
+	}
+}
+
+func TestSetConfigOverride(t *testing.T) {
+	entries, err := ParseConfig([]byte("[plain]\n\tgenerated = a\n\tgenerated = b\n\tnotify = false\n\tsize = 1\n"), "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Config{entries: entries}
+	overrides := map[string]string{"plain.generated": "c", "plain.notify": "yes", "plain.size": "2k"}
+	SetConfigOverride(func(key string) (string, bool) {
+		value, ok := overrides[key]
+		return value, ok
+	})
+	defer SetConfigOverride(nil)
+
+	if got := c.GetAll("plain.generated"); !slices.Equal(got, []string{"c"}) {
+		t.Errorf("GetAll() = %q, want only the override", got)
+	}
+	if on, err := c.Bool("plain.notify"); !on || err != nil {
+		t.Errorf("Bool() = %v, %v, want the override", on, err)
+	}
+	if n, err := c.Int("plain.size", 0); n != 2048 || err != nil {
+		t.Errorf("Int() = %d, %v, want the override", n, err)
+	}
+	overrides["plain.notify"] = "maybe"
+	if _, err := c.Bool("plain.notify"); !errors.Is(err, ErrBadConfig) {
+		t.Errorf("Bool() of a bad override = %v, want %v", err, ErrBadConfig)
+	}
+}
+
+func TestRewriteURL(t *testing.T) {
+	entries, err := ParseConfig([]byte(`[url "git@github.com:"]
+	insteadOf = gh:
+	pushInsteadOf = https://github.com/
+[url "https://github.com/"]
+	insteadOf = gh:
+[url "https://mirror.example.com/team/"]
+	insteadOf = gh:team/
+[remote "origin"]
+	url = gh:team/app
+[remote "upstream"]
+	url = https://github.com/octo/app
+[remote "fork"]
+	url = gh:me/app
+	pushurl = gh:me/app-push
+`), "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Config{entries: entries}
+
+	cases := []struct {
+		url  string
+		push bool
+		want string
+	}{
+		{"gh:octo/app", false, "git@github.com:octo/app"},
+		{"gh:team/app", false, "https://mirror.example.com/team/app"},
+		{"https://github.com/octo/app", true, "git@github.com:octo/app"},
+		{"https://github.com/octo/app", false, "https://github.com/octo/app"},
+		{"gh:octo/app", true, "git@github.com:octo/app"},
+	}
+	for _, tt := range cases {
+		if got := c.RewriteURL(tt.url, tt.push); got != tt.want {
+			t.Errorf("RewriteURL(%q, %v) = %q, want %q", tt.url, tt.push, got, tt.want)
+		}
+	}
+
+	want := []RemoteConfig{
+		{Name: "origin", URLs: []string{"https://mirror.example.com/team/app"}},
+		{Name: "upstream", URLs: []string{"https://github.com/octo/app"}, PushURLs: []string{"git@github.com:octo/app"}},
+		{Name: "fork", URLs: []string{"git@github.com:me/app"}, PushURLs: []string{"git@github.com:me/app-push"}},
+	}
+	if got := c.Remotes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Remotes() = %+v, want %+v", got, want)
+	}
+}
+
+func TestConfigIncludes(t *testing.T) {
+	gitDir := newTestRepo(t)
+	writeTestRef(t, gitDir, "HEAD", "ref: refs/heads/feature/login")
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg"))
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_CONFIG_GLOBAL", "")
+
+	files := map[string]string{
+		filepath.Join(home, "xdg", "git", "config"): "[user]\n\tname = XDG\n\temail = xdg@example.com\n",
+		filepath.Join(home, ".gitconfig"): "[user]\n\tname = Global\n[include]\n\tpath = ~/extra.inc\n" +
+			"[includeIf \"gitdir:" + filepath.Dir(gitDir) + "/\"]\n\tpath = work.inc\n" +
+			"[includeIf \"gitdir:/elsewhere/\"]\n\tpath = never.inc\n",
+		filepath.Join(home, "extra.inc"):                   "[init]\n\tdefaultBranch = trunk\n",
+		filepath.Join(home, "work.inc"):                    "[user]\n\temail = jane@work.example.com\n",
+		filepath.Join(home, "never.inc"):                   "[user]\n\temail = never@example.com\n",
+		filepath.Join(gitDir, "config"):                    "[core]\n\trepositoryformatversion = 1\n[extensions]\n\tobjectFormat = sha256\n[includeIf \"onbranch:feature/\"]\n\tpath = ../feature.inc\n",
+		filepath.Join(filepath.Dir(gitDir), "feature.inc"): "[plain]\n\tstaleAfter = 3\n",
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.Format != SHA256 {
+		t.Errorf("repo.Format = %s, want sha256", repo.Format)
+	}
+	c, err := repo.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name, _ := c.Get("user.name"); name != "Global" {
+		t.Errorf("user.name = %q, want ~/.gitconfig to override the XDG config", name)
+	}
+	if email, _ := c.Get("user.email"); email != "jane@work.example.com" {
+		t.Errorf("user.email = %q, want the gitdir include's", email)
+	}
+	if branch := c.DefaultBranch(); branch != "trunk" {
+		t.Errorf("DefaultBranch() = %q, want trunk from the include", branch)
+	}
+	if stale, err := c.Int("plain.staleAfter", 0); stale != 3 || err != nil {
+		t.Errorf("plain.staleAfter = %d, %v, want 3 from the onbranch include", stale, err)
+	}
+	if emails := c.GetAll("user.email"); !slices.Equal(emails, []string{"xdg@example.com", "jane@work.example.com"}) {
+		t.Errorf("GetAll(user.email) = %q", emails)
+	}
+
+	global, err := GlobalConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if email, _ := global.Get("user.email"); email != "xdg@example.com" {
+		t.Errorf("global user.email = %q, gitdir includes shouldn't apply outside a repository", email)
+	}
+}
@@ -0,0 +1,110 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sim-deos/plain/internal/diff"
+)
+
+var (
+	ErrNotConflicted   = errors.New("path is not conflicted")
+	ErrConflictMarkers = errors.New("file still has conflict markers")
+)
+
+// ConflictKind describes how the two sides of a conflict changed a path, as git status words it.
+type ConflictKind int
+
+const (
+	BothModified  ConflictKind = iota // Both sides changed a file they had in common
+	BothAdded                         // Both sides added the file, with different content
+	DeletedByUs                       // We deleted the file that they changed
+	DeletedByThem                     // They deleted the file that we changed
+	AddedByUs                         // Only we have the file, and it conflicts with something of theirs
+	AddedByThem                       // Only they have the file, and it conflicts with something of ours
+)
+
+var conflictKindName = map[ConflictKind]string{
+	BothModified:  "both modified",
+	BothAdded:     "both added",
+	DeletedByUs:   "deleted by us",
+	DeletedByThem: "deleted by them",
+	AddedByUs:     "added by us",
+	AddedByThem:   "added by them",
+}
+
+func (k ConflictKind) String() string {
+	return conflictKindName[k]
+}
+
+// Conflict is a path with unresolved merge conflict stages in the index. A side is nil when it doesn't
+// have the file, such as the base of a file both sides added.
+type Conflict struct {
+	Path   string
+	Base   *IndexEntry // Stage 1, the common ancestor's version
+	Ours   *IndexEntry // Stage 2, the version on the branch being merged into
+	Theirs *IndexEntry // Stage 3, the version being merged in
+}
+
+// Kind classifies the conflict by which sides have the file.
+func (c Conflict) Kind() ConflictKind {
+	switch {
+	case c.Ours == nil && c.Theirs != nil && c.Base != nil:
+		return DeletedByUs
+	case c.Theirs == nil && c.Ours != nil && c.Base != nil:
+		return DeletedByThem
+	case c.Base == nil && c.Ours != nil && c.Theirs != nil:
+		return BothAdded
+	case c.Theirs == nil:
+		return AddedByUs
+	case c.Ours == nil:
+		return AddedByThem
+	default:
+		return BothModified
+	}
+}
+
+// Conflicts returns the index's conflicted paths with their stages, sorted by path.
+func (idx *Index) Conflicts() []Conflict {
+	var conflicts []Conflict
+	for _, entry := range idx.Entries {
+		if entry.Stage == 0 {
+			continue
+		}
+		if n := len(conflicts); n == 0 || conflicts[n-1].Path != entry.Path {
+			conflicts = append(conflicts, Conflict{Path: entry.Path})
+		}
+		c := &conflicts[len(conflicts)-1]
+		switch entry.Stage {
+		case 1:
+			c.Base = &entry
+		case 2:
+			c.Ours = &entry
+		case 3:
+			c.Theirs = &entry
+		}
+	}
+	return conflicts
+}
+
+// MarkResolved stages the work tree's version of the conflicted path in idx, dropping its conflict stages
+// like git add. A path whose file was deleted is resolved as deleted. If the file still has conflict
+// markers, [ErrConflictMarkers] is returned and idx is left alone; [Repository.StagePath] stages it
+// regardless. The caller writes idx with [Repository.WriteIndex].
+func (repo *Repository) MarkResolved(idx *Index, path string) error {
+	conflicted := false
+	for _, entry := range idx.Entries {
+		conflicted = conflicted || entry.Path == path && entry.Stage != 0
+	}
+	if !conflicted {
+		return fmt.Errorf("%w: %s", ErrNotConflicted, path)
+	}
+
+	data, err := os.ReadFile(filepath.Join(repo.WorkTree, filepath.FromSlash(path)))
+	if err == nil && diff.HasConflictMarkers(data) {
+		return fmt.Errorf("%w: %s", ErrConflictMarkers, path)
+	}
+	return repo.StagePath(idx, path)
+}
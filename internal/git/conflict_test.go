@@ -0,0 +1,71 @@
+package git
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestConflicts(t *testing.T) {
+	idx := &Index{Version: 2}
+	for _, entry := range []IndexEntry{
+		{Path: "clean.txt", Hash: "c"},
+		{Path: "edited.txt", Stage: 1, Hash: "b"},
+		{Path: "edited.txt", Stage: 2, Hash: "o"},
+		{Path: "edited.txt", Stage: 3, Hash: "t"},
+		{Path: "new.txt", Stage: 2, Hash: "o"},
+		{Path: "new.txt", Stage: 3, Hash: "t"},
+		{Path: "removed.txt", Stage: 1, Hash: "b"},
+		{Path: "removed.txt", Stage: 3, Hash: "t"},
+	} {
+		idx.Add(entry)
+	}
+
+	conflicts := idx.Conflicts()
+	if len(conflicts) != 3 {
+		t.Fatalf("expected 3 conflicts, got %+v", conflicts)
+	}
+	want := []struct {
+		path string
+		kind ConflictKind
+	}{{"edited.txt", BothModified}, {"new.txt", BothAdded}, {"removed.txt", DeletedByUs}}
+	for i, w := range want {
+		if conflicts[i].Path != w.path || conflicts[i].Kind() != w.kind {
+			t.Errorf("conflict %d is %s (%s), want %s (%s)", i, conflicts[i].Path, conflicts[i].Kind(), w.path, w.kind)
+		}
+	}
+	if conflicts[0].Theirs == nil || conflicts[0].Theirs.Hash != "t" {
+		t.Errorf("theirs stage of edited.txt = %+v", conflicts[0].Theirs)
+	}
+}
+
+func TestMarkResolved(t *testing.T) {
+	newTestRepo(t)
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idx := &Index{Version: 2}
+	for stage := 1; stage <= 3; stage++ {
+		idx.Add(IndexEntry{Path: "a.txt", Mode: ModeFile, Hash: HashObject(BlobObject, []byte("x\n")), Stage: stage})
+	}
+
+	writeTestFiles(t, map[string]string{"a.txt": "<<<<<<< ours\nx\n=======\ny\n>>>>>>> theirs\n", "b.txt": "b\n"})
+	if err := repo.MarkResolved(idx, "a.txt"); !errors.Is(err, ErrConflictMarkers) {
+		t.Fatalf("resolving a file with markers: got %v", err)
+	}
+	if err := repo.MarkResolved(idx, "b.txt"); !errors.Is(err, ErrNotConflicted) {
+		t.Errorf("resolving a path without conflicts: got %v", err)
+	}
+
+	writeTestFiles(t, map[string]string{"a.txt": "y\n"})
+	if err := repo.MarkResolved(idx, "a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if len(idx.Conflicts()) != 0 {
+		t.Errorf("conflict stages left after resolving: %+v", idx.Conflicts())
+	}
+	if entry, ok := idx.Entry("a.txt"); !ok || entry.Hash != HashObject(BlobObject, []byte("y\n")) {
+		t.Errorf("resolved entry = %+v, %v", entry, ok)
+	}
+}
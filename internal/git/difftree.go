@@ -0,0 +1,113 @@
+package git
+
+import "strings"
+
+// Change is a difference between two trees at a single path.
+type Change struct {
+	Path   string     // The slash separated path of the changed file
+	Status FileStatus // [StatusAdded], [StatusDeleted], or [StatusModified]
+	From   TreeEntry  // The entry in the old tree, zero when added
+	To     TreeEntry  // The entry in the new tree, zero when deleted
+}
+
+// DiffTrees compares the trees with hashes a and b and returns every file that differs, in the order git
+// diff-tree -r lists them. Either hash may be empty to compare against an empty tree, as for a root commit.
+//
+// Subtrees with equal hashes are skipped without being read. A path that turns from a file into a
+// directory, or back, is reported as the deletion of one and the addition of the other's files.
+func (repo *Repository) DiffTrees(a, b string) ([]Change, error) {
+	r, err := newCommitReader(repo)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var changes []Change
+	if err := r.diffTrees(a, b, "", &changes); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+func (r *commitReader) diffTrees(a, b, prefix string, changes *[]Change) error {
+	if a == b {
+		return nil
+	}
+
+	from, err := r.treeOrEmpty(a)
+	if err != nil {
+		return err
+	}
+	to, err := r.treeOrEmpty(b)
+	if err != nil {
+		return err
+	}
+
+	for len(from) > 0 || len(to) > 0 {
+		var c int
+		switch {
+		case len(from) == 0:
+			c = 1
+		case len(to) == 0:
+			c = -1
+		default:
+			c = strings.Compare(treeSortKey(from[0]), treeSortKey(to[0]))
+		}
+
+		switch {
+		case c < 0:
+			if err := r.diffEntries(&from[0], nil, prefix, changes); err != nil {
+				return err
+			}
+			from = from[1:]
+		case c > 0:
+			if err := r.diffEntries(nil, &to[0], prefix, changes); err != nil {
+				return err
+			}
+			to = to[1:]
+		default:
+			if err := r.diffEntries(&from[0], &to[0], prefix, changes); err != nil {
+				return err
+			}
+			from, to = from[1:], to[1:]
+		}
+	}
+	return nil
+}
+
+// diffEntries records the changes between two entries with the same name, either of which may be missing.
+func (r *commitReader) diffEntries(from, to *TreeEntry, prefix string, changes *[]Change) error {
+	entry := from
+	if entry == nil {
+		entry = to
+	}
+	path := prefix + entry.Name
+
+	if entry.Mode == ModeTree {
+		var a, b string
+		if from != nil {
+			a = from.Hash
+		}
+		if to != nil {
+			b = to.Hash
+		}
+		return r.diffTrees(a, b, path+"/", changes)
+	}
+
+	switch {
+	case from == nil:
+		*changes = append(*changes, Change{Path: path, Status: StatusAdded, To: *to})
+	case to == nil:
+		*changes = append(*changes, Change{Path: path, Status: StatusDeleted, From: *from})
+	case *from != *to:
+		*changes = append(*changes, Change{Path: path, Status: StatusModified, From: *from, To: *to})
+	}
+	return nil
+}
+
+func (r *commitReader) treeOrEmpty(hash string) ([]TreeEntry, error) {
+	if hash == "" {
+		return nil, nil
+	}
+	return r.tree(hash)
+}
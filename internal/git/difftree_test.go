@@ -0,0 +1,86 @@
+package git
+
+import "testing"
+
+func TestDiffTrees(t *testing.T) {
+	newTestRepo(t)
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := repo.Encoder()
+	blob := func(content string) string {
+		hash, err := e.Encode(BlobObject, []byte(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hash
+	}
+	tree := func(files map[string]TreeEntry) string {
+		b := NewTreeBuilder(e)
+		for path, entry := range files {
+			if err := b.Add(path, entry.Mode, entry.Hash); err != nil {
+				t.Fatal(err)
+			}
+		}
+		hash, err := b.Write()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hash
+	}
+
+	old := tree(map[string]TreeEntry{
+		"README.md":     {Mode: ModeFile, Hash: blob("readme")},
+		"run.sh":        {Mode: ModeFile, Hash: blob("run")},
+		"lib":           {Mode: ModeFile, Hash: blob("lib was a file")},
+		"src/main.go":   {Mode: ModeFile, Hash: blob("main")},
+		"src/old.go":    {Mode: ModeFile, Hash: blob("old")},
+		"vendor/x/x.go": {Mode: ModeFile, Hash: blob("x")},
+	})
+	updated := tree(map[string]TreeEntry{
+		"README.md":     {Mode: ModeFile, Hash: blob("readme")},
+		"run.sh":        {Mode: ModeExecutable, Hash: blob("run")},
+		"lib/lib.go":    {Mode: ModeFile, Hash: blob("lib")},
+		"src/main.go":   {Mode: ModeFile, Hash: blob("main v2")},
+		"src/new.go":    {Mode: ModeFile, Hash: blob("new")},
+		"vendor/x/x.go": {Mode: ModeFile, Hash: blob("x")},
+	})
+
+	changes, err := repo.DiffTrees(old, updated)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []struct {
+		path   string
+		status FileStatus
+	}{
+		{"lib", StatusDeleted},
+		{"lib/lib.go", StatusAdded},
+		{"run.sh", StatusModified},
+		{"src/main.go", StatusModified},
+		{"src/new.go", StatusAdded},
+		{"src/old.go", StatusDeleted},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("expected %d changes, got %+v", len(expected), changes)
+	}
+	for i, want := range expected {
+		if changes[i].Path != want.path || changes[i].Status != want.status {
+			t.Errorf("change %d: expected %s %s, got %s %s", i, want.status, want.path, changes[i].Status, changes[i].Path)
+		}
+	}
+	if changes[2].From.Mode != ModeFile || changes[2].To.Mode != ModeExecutable {
+		t.Errorf("expected run.sh's mode change to be reported, got %+v", changes[2])
+	}
+
+	added, err := repo.DiffTrees("", old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(added) != 6 || added[0].Status != StatusAdded {
+		t.Fatalf("expected every file added against an empty tree, got %+v", added)
+	}
+}
@@ -0,0 +1,96 @@
+package git
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// Encoder compresses git objects and writes them into a loose object store, the counterpart to [Decoder].
+//
+// Objects are written to a temporary file and renamed into place, so readers never see a partially
+// written object and concurrent writers of the same object are harmless. A new Encoder is created by
+// calling [NewEncoder], or [Repository.Encoder] for a repository's own object store.
+type Encoder struct {
+	objectsPath string
+	format      HashFormat
+}
+
+// NewEncoder returns an Encoder writing into the objects directory at objectsPath, naming objects with format.
+func NewEncoder(objectsPath string, format HashFormat) *Encoder {
+	return &Encoder{objectsPath: objectsPath, format: format}
+}
+
+// Encoder returns an Encoder that writes into this repository's object store.
+func (repo *Repository) Encoder() *Encoder {
+	format := repo.Format
+	if format == 0 {
+		format = SHA1
+	}
+	return NewEncoder(filepath.Join(repo.CommonDir, "objects"), format)
+}
+
+// Encode stores an object of the given kind holding data and returns its hash.
+// Storing an object that already exists leaves the existing file untouched.
+func (e *Encoder) Encode(kind GitObjectKind, data []byte) (string, error) {
+	return e.EncodeStream(kind, int64(len(data)), bytes.NewReader(data))
+}
+
+// EncodeStream stores an object whose content of size bytes is read from r and returns its hash.
+// Fails with [ErrSizeMismatch] if r does not hold exactly size bytes, in which case nothing is stored.
+func (e *Encoder) EncodeStream(kind GitObjectKind, size int64, r io.Reader) (string, error) {
+	tmp, err := os.CreateTemp(e.objectsPath, "tmp_obj_")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed into place
+
+	hash, err := e.write(tmp, kind, size, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(e.objectsPath, hash[:2])
+	final := filepath.Join(dir, hash[2:])
+	if _, err := os.Stat(final); err == nil {
+		return hash, nil
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	// Loose objects are immutable, and git creates them read only.
+	if err := os.Chmod(tmp.Name(), 0o444); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), final); err != nil && !errors.Is(err, fs.ErrExist) {
+		return "", err
+	}
+	return hash, nil
+}
+
+func (e *Encoder) write(dst io.Writer, kind GitObjectKind, size int64, r io.Reader) (string, error) {
+	zw := zlib.NewWriter(dst)
+	hasher := NewObjectHasher(e.format, kind, size)
+
+	header := kind.String() + " " + strconv.FormatInt(size, 10) + "\x00"
+	if _, err := io.WriteString(zw, header); err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(io.MultiWriter(zw, hasher), r); err != nil {
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+	return hasher.Sum()
+}
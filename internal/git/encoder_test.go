@@ -0,0 +1,62 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEncoderRoundTrip(t *testing.T) {
+	objectsPath := t.TempDir()
+	e := NewEncoder(objectsPath, SHA1)
+
+	hash, err := e.Encode(BlobObject, []byte("hello\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash != "ce013625030ba8dba906f756967f9e9ca394464a" {
+		t.Fatalf("unexpected hash %s", hash)
+	}
+
+	// Writing the same object again must succeed even though the stored file is read only.
+	if again, err := e.Encode(BlobObject, []byte("hello\n")); err != nil || again != hash {
+		t.Fatalf("re-encoding failed: %s (%v)", again, err)
+	}
+
+	f, err := os.Open(filepath.Join(objectsPath, hash[:2], hash[2:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	d, err := NewDecoder(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	header, err := d.Header()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.Kind != BlobObject || header.Size != 6 {
+		t.Fatalf("unexpected header %+v", header)
+	}
+}
+
+func TestEncoderSizeMismatchStoresNothing(t *testing.T) {
+	objectsPath := t.TempDir()
+	e := NewEncoder(objectsPath, SHA1)
+
+	_, err := e.EncodeStream(BlobObject, 100, strings.NewReader("short"))
+	if !errors.Is(err, ErrSizeMismatch) {
+		t.Fatalf("expected ErrSizeMismatch, got %v", err)
+	}
+
+	entries, _ := os.ReadDir(objectsPath)
+	if len(entries) != 0 {
+		t.Fatalf("expected an empty object store, found %d entries", len(entries))
+	}
+}
@@ -0,0 +1,390 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrNoRemote          = errors.New("no such remote")
+	ErrUnsupportedRemote = errors.New("unsupported remote URL")
+	ErrNotShallow        = errors.New("repository is not a shallow clone")
+)
+
+// RemoteRef is a ref a remote advertises.
+type RemoteRef struct {
+	Name   string // The full ref name, e.g. refs/heads/main
+	Hash   string
+	Peeled string // The object an annotated tag points to, or "" for any other ref
+	Target string // The ref a symbolic ref points to, or "" if it isn't symbolic
+}
+
+// RefUpdate is a local ref a fetch moved, or would have moved.
+type RefUpdate struct {
+	Name string
+	Old  string // Where the ref was, or ZeroHash if it is new
+	New  string
+}
+
+// FetchResult is what [Repository.Fetch] did.
+type FetchResult struct {
+	Updated  []RefUpdate // Refs created or moved
+	Rejected []RefUpdate // Refs left alone because the update isn't a fast-forward, or they are checked out
+	Objects  int         // How many objects were downloaded that the repository didn't have
+}
+
+// Fetch downloads refs from the remote, which is either the name of a configured remote or a URL, and
+// stores them locally as refspecs say, like git fetch.
+//
+// With no refspecs, the remote's remote.<name>.fetch refspecs are used, and tags pointing into what was
+// fetched follow along, as git fetch does by default. A refspec without a wildcard names a single ref,
+// which fails with [ErrRemoteRefNotFound] when the remote doesn't have it. Refs are only moved when they
+// fast-forward or their refspec is forced with +, and existing tags and the checked-out branch are never
+// moved; anything else is reported as rejected.
+//
+// The remote is spoken to with git's protocol v2, through client for https:// and http:// URLs, and over
+// SSH for ssh:// and scp-like git@host:path URLs, as described at [dialSSH]. Other URLs fail with
+// [ErrUnsupportedRemote]. Ref updates are recorded in the reflog as made by who.
+func (repo *Repository) Fetch(client *http.Client, remote string, refspecs []string, who Signature) (FetchResult, error) {
+	defer phase("fetch")()
+	config, err := repo.lookupRemote(remote)
+	if err != nil {
+		return FetchResult{}, err
+	}
+	followTags := refspecs == nil
+	if followTags {
+		refspecs = config.Fetch
+	}
+	specs := make([]Refspec, len(refspecs))
+	for i, spec := range refspecs {
+		if specs[i], err = ParseRefspec(spec); err != nil {
+			return FetchResult{}, err
+		}
+	}
+
+	conn, err := dialUploadPack(client, config.URLs[0], repo.Format)
+	if err != nil {
+		return FetchResult{}, err
+	}
+	defer conn.Close()
+	var prefixes []string
+	for _, spec := range specs {
+		if !spec.Negative {
+			prefixes = append(prefixes, refPrefixes(spec.Src)...)
+		}
+	}
+	if followTags {
+		prefixes = append(prefixes, "refs/tags/")
+	}
+	advertised, err := conn.lsRefs(prefixes)
+	if err != nil {
+		return FetchResult{}, err
+	}
+
+	var plan []RefUpdate
+	forced := map[string]bool{}
+	for _, spec := range specs {
+		if spec.Negative {
+			continue
+		}
+		found := false
+		for _, ref := range advertised {
+			dst, ok := matchRefspec(spec, ref.Name)
+			if !ok || excluded(specs, ref.Name) {
+				continue
+			}
+			found = true
+			if dst != "" {
+				plan = append(plan, RefUpdate{Name: dst, New: ref.Hash})
+				forced[dst] = spec.Force
+			}
+		}
+		if !found && !strings.Contains(spec.Src, "*") {
+			return FetchResult{}, fmt.Errorf("%w: %s %s", ErrRemoteRefNotFound, remote, spec.Src)
+		}
+	}
+
+	var result FetchResult
+	var wants []string
+	for _, update := range plan {
+		if slices.Contains(wants, update.New) {
+			continue
+		}
+		if ok, err := repo.hasObject(update.New); err != nil {
+			return FetchResult{}, err
+		} else if !ok {
+			wants = append(wants, update.New)
+		}
+	}
+	if len(wants) > 0 {
+		haves, err := repo.fetchHaves()
+		if err != nil {
+			return FetchResult{}, err
+		}
+		shallow, err := readShallow(repo.CommonDir)
+		if err != nil {
+			return FetchResult{}, err
+		}
+		pack, update, err := conn.fetch(wants, haves, shallow, 0, followTags)
+		if err != nil {
+			return FetchResult{}, err
+		}
+		_, result.Objects, err = repo.storePack(pack)
+		pack.Close()
+		if err != nil {
+			return FetchResult{}, fmt.Errorf("git: failed to read the pack from %s: %w", remote, err)
+		}
+		if err := repo.updateShallow(update); err != nil {
+			return result, err
+		}
+	}
+
+	if followTags {
+		// The remote sent the tags pointing into the pack along with it; tags pointing at commits the
+		// repository already had are taken as well.
+		for _, ref := range advertised {
+			if !strings.HasPrefix(ref.Name, "refs/tags/") {
+				continue
+			}
+			if _, err := repo.resolveRef(ref.Name); !errors.Is(err, ErrRefNotFound) {
+				continue
+			}
+			if ok, err := repo.hasObject(ref.Hash); err != nil {
+				return FetchResult{}, err
+			} else if ok {
+				plan = append(plan, RefUpdate{Name: ref.Name, New: ref.Hash})
+			}
+		}
+	}
+
+	head, _ := repo.resolveRef("HEAD")
+	for _, update := range plan {
+		update.Old, err = repo.resolveRef(update.Name)
+		if errors.Is(err, ErrRefNotFound) {
+			update.Old = ZeroHash
+		} else if err != nil {
+			return result, err
+		}
+		if update.Old == update.New {
+			continue
+		}
+
+		reason := "storing head"
+		if update.Old != ZeroHash {
+			if head == "ref: "+update.Name || strings.HasPrefix(update.Name, "refs/tags/") && !forced[update.Name] {
+				result.Rejected = append(result.Rejected, update)
+				continue
+			}
+			fastForward, err := repo.isAncestor(update.Old, update.New)
+			if err != nil {
+				return result, err
+			}
+			switch {
+			case fastForward:
+				reason = "fast-forward"
+			case !forced[update.Name]:
+				result.Rejected = append(result.Rejected, update)
+				continue
+			default:
+				reason = "forced-update"
+			}
+		}
+		if err := repo.UpdateRef(update.Name, update.Old, update.New, who, "fetch "+remote+": "+reason); err != nil {
+			return result, err
+		}
+		result.Updated = append(result.Updated, update)
+	}
+	return result, nil
+}
+
+// Deepen fetches depth more generations of the history a shallow clone was cut off at from the remote,
+// which is either the name of a configured remote or a URL, like git fetch --deepen, and returns how many
+// objects were downloaded. The remote's branches, as its remote.<name>.fetch refspecs name them, or its
+// HEAD for a URL, are where the history is followed from; no refs are moved. Once the remote has no
+// history left behind a commit, the commit stops being shallow, and when none are left the repository
+// is a full clone. A repository that isn't shallow fails with [ErrNotShallow].
+func (repo *Repository) Deepen(client *http.Client, remote string, depth int) (int, error) {
+	defer phase("deepen")()
+	if depth <= 0 {
+		return 0, fmt.Errorf("git: cannot deepen history by %d commits", depth)
+	}
+	shallow, err := readShallow(repo.CommonDir)
+	if err != nil {
+		return 0, err
+	}
+	if len(shallow) == 0 {
+		return 0, ErrNotShallow
+	}
+	config, err := repo.lookupRemote(remote)
+	if err != nil {
+		return 0, err
+	}
+	var specs []Refspec
+	var prefixes []string
+	for _, src := range config.Fetch {
+		spec, err := ParseRefspec(src)
+		if err != nil {
+			return 0, err
+		}
+		specs = append(specs, spec)
+		if !spec.Negative {
+			prefixes = append(prefixes, refPrefixes(spec.Src)...)
+		}
+	}
+	if len(specs) == 0 {
+		prefixes = []string{"HEAD"}
+	}
+
+	conn, err := dialUploadPack(client, config.URLs[0], repo.Format)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	advertised, err := conn.lsRefs(prefixes)
+	if err != nil {
+		return 0, err
+	}
+	var wants []string
+	for _, ref := range advertised {
+		matched := ref.Name == "HEAD" && len(specs) == 0
+		for _, spec := range specs {
+			if _, ok := matchRefspec(spec, ref.Name); ok && !spec.Negative && !excluded(specs, ref.Name) {
+				matched = true
+			}
+		}
+		if matched && !slices.Contains(wants, ref.Hash) {
+			wants = append(wants, ref.Hash)
+		}
+	}
+	if len(wants) == 0 {
+		return 0, fmt.Errorf("%w: %s has no branches to deepen history from", ErrRemoteRefNotFound, remote)
+	}
+
+	haves, err := repo.fetchHaves()
+	if err != nil {
+		return 0, err
+	}
+	pack, update, err := conn.fetch(wants, haves, shallow, depth, false)
+	if err != nil {
+		return 0, err
+	}
+	_, objects, err := repo.storePack(pack)
+	pack.Close()
+	if err != nil {
+		return 0, fmt.Errorf("git: failed to read the pack from %s: %w", remote, err)
+	}
+	return objects, repo.updateShallow(update)
+}
+
+// lookupRemote returns the configured remote named remote, which has at least one URL. A URL is taken as a
+// remote of its own, with no refspecs. Either way, URLs are rewritten as url.<base>.insteadOf says.
+func (repo *Repository) lookupRemote(remote string) (RemoteConfig, error) {
+	config, err := repo.Config()
+	if err != nil {
+		return RemoteConfig{}, err
+	}
+	if _, ok := parseSSHURL(remote); ok || strings.Contains(remote, "://") {
+		r := RemoteConfig{URLs: []string{config.RewriteURL(remote, false)}}
+		if pushURL := config.RewriteURL(remote, true); pushURL != r.URLs[0] {
+			r.PushURLs = []string{pushURL}
+		}
+		return r, nil
+	}
+	for _, r := range config.Remotes() {
+		if r.Name == remote && len(r.URLs) > 0 {
+			return r, nil
+		}
+	}
+	return RemoteConfig{}, fmt.Errorf("%w: %s", ErrNoRemote, remote)
+}
+
+// fetchHaves returns the commits the refs point to, which a remote leaves out of the pack it sends
+// along with everything reachable from them. The most recently committed come first, as those are the
+// likeliest to be what the remote's branches were last fetched as, and to save the most.
+func (repo *Repository) fetchHaves() ([]string, error) {
+	refs, err := repo.Refs()
+	if err != nil {
+		return nil, err
+	}
+	r, err := newCommitReader(repo)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	seen := map[string]bool{}
+	var haves []Commit
+	for _, hash := range refs {
+		if !isFullHash(hash) {
+			continue
+		}
+		// Tags are offered as the commits they point to. A ref to a missing object offers nothing.
+		hash, err = r.peel(hash)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if seen[hash] {
+			continue
+		}
+		seen[hash] = true
+		commit, ok, err := r.read(hash)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			haves = append(haves, commit)
+		}
+	}
+	slices.SortFunc(haves, func(a, b Commit) int {
+		if c := b.Committer.Time.Compare(a.Committer.Time); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Hash, b.Hash)
+	})
+	hashes := make([]string, len(haves))
+	for i, commit := range haves {
+		hashes[i] = commit.Hash
+	}
+	return hashes, nil
+}
+
+// refPrefixes returns the prefixes to ask a remote for refs under to learn about every ref src matches.
+func refPrefixes(src string) []string {
+	prefix, _, wildcard := strings.Cut(src, "*")
+	if wildcard || strings.HasPrefix(src, "refs/") || src == "HEAD" {
+		return []string{prefix}
+	}
+	return []string{"refs/" + src, "refs/heads/" + src, "refs/tags/" + src}
+}
+
+// matchRefspec is [Refspec.Map], also letting a refspec name a single branch or tag by its short name,
+// as in main:refs/remotes/origin/main.
+func matchRefspec(spec Refspec, ref string) (string, bool) {
+	if dst, ok := spec.Map(ref); ok {
+		return dst, true
+	}
+	if strings.Contains(spec.Src, "*") || strings.HasPrefix(spec.Src, "refs/") {
+		return "", false
+	}
+	for _, prefix := range []string{"refs/", "refs/heads/", "refs/tags/"} {
+		if ref == prefix+spec.Src {
+			return spec.Dst, true
+		}
+	}
+	return "", false
+}
+
+// excluded reports whether a negative refspec among specs rules ref out.
+func excluded(specs []Refspec, ref string) bool {
+	return slices.ContainsFunc(specs, func(spec Refspec) bool {
+		_, ok := spec.Map(ref)
+		return spec.Negative && ok
+	})
+}
@@ -0,0 +1,338 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serveUploadPack serves the refs of server over protocol v2, answering every fetch with a pack of all of
+// its objects, and records the lines of each request.
+func serveUploadPack(t *testing.T, server *Repository, refs map[string]string, requests *[][]string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			if r.URL.Path != "/repo.git/info/refs" || r.Header.Get("Git-Protocol") != "version=2" {
+				http.NotFound(w, r)
+				return
+			}
+			var service pktWriter
+			service.line("# service=git-upload-pack\n")
+			service.flush()
+			w.Write(append(service.buf, testAdvertisement()...))
+			return
+		}
+		lines, err := readTestRequest(newPktReader(r.Body))
+		if err != nil {
+			t.Errorf("reading the request: %v", err)
+			return
+		}
+		*requests = append(*requests, lines)
+		w.Write(answerTestRequest(t, server, refs, lines))
+	}))
+}
+
+// testAdvertisement is the capability advertisement of the test servers.
+func testAdvertisement() []byte {
+	var w pktWriter
+	for _, line := range []string{"version 2", "agent=test", "ls-refs=unborn", "fetch=shallow", "object-format=sha1"} {
+		w.line(line + "\n")
+	}
+	w.flush()
+	return w.buf
+}
+
+// readTestRequest reads the lines of a protocol v2 request, up to the flush that ends it.
+func readTestRequest(p *pktReader) ([]string, error) {
+	var lines []string
+	for {
+		kind, data, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		if kind == pktFlush {
+			return lines, nil
+		}
+		if kind == pktData {
+			lines = append(lines, strings.TrimSuffix(string(data), "\n"))
+		}
+	}
+}
+
+// answerTestRequest answers ls-refs with refs, and fetch with a pack of every object in server, once the
+// client is done negotiating or one of its haves is in server. A fetch that deepens history has its
+// shallow commits deepened by one generation.
+func answerTestRequest(t *testing.T, server *Repository, refs map[string]string, lines []string) []byte {
+	var reply pktWriter
+	switch lines[0] {
+	case "command=ls-refs":
+		for _, name := range slices.Sorted(maps.Keys(refs)) {
+			reply.line(refs[name] + " " + name + "\n")
+		}
+		reply.flush()
+	case "command=fetch":
+		if !slices.Contains(lines, "done") {
+			reply.line("acknowledgments\n")
+			ready := false
+			for _, line := range lines {
+				if hash, ok := strings.CutPrefix(line, "have "); ok {
+					if has, _ := server.hasObject(hash); has {
+						reply.line("ACK " + hash + "\n")
+						ready = true
+					}
+				}
+			}
+			if !ready {
+				reply.line("NAK\n")
+				reply.flush()
+				return reply.buf
+			}
+			reply.line("ready\n")
+			reply.delim()
+		}
+		objects, err := server.reachableObjects()
+		if err != nil {
+			t.Error(err)
+			return nil
+		}
+		var pack bytes.Buffer
+		pw, _ := streamPack(&pack, SHA1, len(objects))
+		reader := &commitReader{objectsPath: filepath.Join(server.CommonDir, "objects"), format: SHA1}
+		defer reader.Close()
+		for hash := range objects {
+			kind, data, err := reader.raw(hash)
+			if err != nil {
+				t.Error(err)
+				return nil
+			}
+			pw.add(hash, kind, data)
+		}
+		pw.end()
+
+		if slices.Contains(lines, "deepen 1") {
+			reply.line("shallow-info\n")
+			for _, line := range lines {
+				hash, ok := strings.CutPrefix(line, "shallow ")
+				if !ok {
+					continue
+				}
+				reply.line("unshallow " + hash + "\n")
+				commit, err := server.ReadCommit(hash)
+				if err != nil {
+					t.Error(err)
+					return nil
+				}
+				for _, parent := range commit.Parents {
+					if grandparent, err := server.ReadCommit(parent); err == nil && len(grandparent.Parents) > 0 {
+						reply.line("shallow " + parent + "\n")
+					}
+				}
+			}
+			reply.delim()
+		}
+		reply.line("packfile\n")
+		reply.line("\x02counting objects\n")
+		for chunk := range slices.Chunk(pack.Bytes(), 1000) {
+			reply.line("\x01" + string(chunk))
+		}
+		reply.flush()
+	}
+	return reply.buf
+}
+
+// writeTestServer writes a repository for the test servers to serve, returning its refs.
+func writeTestServer(t *testing.T) (*Repository, map[string]string) {
+	t.Helper()
+	serverDir := newTestRepo(t)
+	server, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestObject(t, serverDir, TreeObject, "")
+	base := writeTestTreeCommit(t, server, writeTestCommit(t, serverDir, "root"), maintenanceSignature, "base", map[string]string{"a.txt": "one\n"})
+	main := writeTestTreeCommit(t, server, base, maintenanceSignature, "main", map[string]string{"a.txt": "two\n"})
+	writeTestRef(t, serverDir, "refs/heads/main", main)
+	tag := writeTestObject(t, serverDir, TagObject, "object "+main+"\ntype commit\ntag v1\ntagger A <a@example.com> 1703123456 +0000\n\nv1\n")
+	writeTestRef(t, serverDir, "refs/tags/v1", tag)
+	return server, map[string]string{"refs/heads/main": main, "refs/heads/old": base, "refs/tags/v1": tag}
+}
+
+func TestFetch(t *testing.T) {
+	server, refs := writeTestServer(t)
+	main, base, tag := refs["refs/heads/main"], refs["refs/heads/old"], refs["refs/tags/v1"]
+
+	var requests [][]string
+	srv := serveUploadPack(t, server, refs, &requests)
+	defer srv.Close()
+
+	gitDir := newTestRepo(t)
+	writeTestRef(t, gitDir, "HEAD", "ref: refs/heads/main")
+	writeTestFiles(t, map[string]string{".git/config": fmt.Sprintf("[remote \"origin\"]\n\turl = %s/repo.git/\n\tfetch = +refs/heads/*:refs/remotes/origin/*\n", srv.URL)})
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := repo.Fetch(srv.Client(), "origin", nil, maintenanceSignature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Updated) != 3 || len(result.Rejected) != 0 || result.Objects == 0 {
+		t.Errorf("Fetch() = %+v, want the two branches and the tag", result)
+	}
+	for name, want := range map[string]string{"refs/remotes/origin/main": main, "refs/remotes/origin/old": base, "refs/tags/v1": tag} {
+		if got, err := repo.ResolveRevision(name); err != nil || got != want {
+			t.Errorf("%s = %s, %v, want %s", name, got, err, want)
+		}
+	}
+	if commit, err := repo.ReadCommit(main); err != nil || commit.Message != "main" {
+		t.Errorf("ReadCommit() of a fetched commit = %+v, %v", commit, err)
+	}
+	if fetch := requests[len(requests)-1]; !slices.Contains(fetch, "want "+main) || !slices.Contains(fetch, "include-tag") || fetch[len(fetch)-1] != "done" {
+		t.Errorf("fetch request = %q, want main and tags", fetch)
+	}
+
+	// With everything fetched, fetching again only lists the refs.
+	requests = nil
+	if result, err := repo.Fetch(srv.Client(), "origin", nil, maintenanceSignature); err != nil || len(result.Updated) != 0 || len(requests) != 1 {
+		t.Errorf("Fetch() again = %+v, %v after %d requests, want nothing after listing refs", result, err, len(requests))
+	}
+
+	writeTestRef(t, gitDir, "refs/heads/main", main)
+	writeTestRef(t, gitDir, "refs/heads/feature", main)
+	result, err = repo.Fetch(srv.Client(), srv.URL+"/repo.git", []string{"old:refs/heads/feature", "+old:refs/heads/main"}, maintenanceSignature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Updated) != 0 || len(result.Rejected) != 2 {
+		t.Errorf("Fetch() moving refs backwards = %+v, want both rejected", result)
+	}
+	if _, err := repo.Fetch(srv.Client(), "origin", []string{"+refs/heads/old:refs/heads/feature"}, maintenanceSignature); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := repo.ResolveRevision("feature"); got != base {
+		t.Errorf("feature after a forced fetch = %s, want %s", got, base)
+	}
+
+	if _, err := repo.Fetch(srv.Client(), "origin", []string{"refs/heads/gone:refs/heads/gone"}, maintenanceSignature); !errors.Is(err, ErrRemoteRefNotFound) {
+		t.Errorf("Fetch() of a missing ref = %v, want %v", err, ErrRemoteRefNotFound)
+	}
+	if _, err := repo.Fetch(srv.Client(), "file:///srv/repo.git", nil, maintenanceSignature); !errors.Is(err, ErrUnsupportedRemote) {
+		t.Errorf("Fetch() from a file URL = %v, want %v", err, ErrUnsupportedRemote)
+	}
+	if _, err := repo.Fetch(srv.Client(), "upstream", nil, maintenanceSignature); !errors.Is(err, ErrNoRemote) {
+		t.Errorf("Fetch() from an unknown remote = %v, want %v", err, ErrNoRemote)
+	}
+}
+
+func TestFetchNegotiates(t *testing.T) {
+	server, refs := writeTestServer(t)
+	main, base := refs["refs/heads/main"], refs["refs/heads/old"]
+
+	var requests [][]string
+	srv := serveUploadPack(t, server, refs, &requests)
+	defer srv.Close()
+
+	// Haves are offered newest first, so the commit the server has comes after the local ones when
+	// they're newer, and the negotiation goes on for another round, and before them when they're older.
+	for _, c := range []struct {
+		offset time.Duration
+		rounds int
+	}{{time.Hour, 2}, {-time.Hour, 1}} {
+		gitDir := newTestRepo(t)
+		writeTestFiles(t, map[string]string{".git/config": fmt.Sprintf("[remote \"origin\"]\n\turl = %s/repo.git\n", srv.URL)})
+		repo, err := OpenRepository()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The test server always sends everything, so what the repository has in common with it is
+		// written the same way instead of fetched.
+		writeTestObject(t, gitDir, TreeObject, "")
+		if common := writeTestTreeCommit(t, repo, writeTestCommit(t, gitDir, "root"), maintenanceSignature, "base", map[string]string{"a.txt": "one\n"}); common != base {
+			t.Fatalf("local copy of base = %s, want %s", common, base)
+		}
+		writeTestRef(t, gitDir, "refs/remotes/origin/old", base)
+		local := maintenanceSignature
+		local.Time = local.Time.Add(c.offset)
+		for i := range havesPerRound + 8 {
+			writeTestRef(t, gitDir, fmt.Sprintf("refs/heads/local-%d", i), writeTestTreeCommit(t, repo, base, local, "local", map[string]string{"n": fmt.Sprint(i)}))
+		}
+
+		requests = nil
+		if _, err := repo.Fetch(srv.Client(), "origin", []string{"refs/heads/main:refs/remotes/origin/main"}, maintenanceSignature); err != nil {
+			t.Fatal(err)
+		}
+		fetches := requests[1:]
+		if len(fetches) != c.rounds {
+			t.Errorf("with local commits %s newer: %d fetch requests, want %d", c.offset, len(fetches), c.rounds)
+			continue
+		}
+		if first := fetches[0]; slices.Contains(first, "done") || len(first) > 6+havesPerRound {
+			t.Errorf("first fetch request = %q, want at most %d haves and no done", first, havesPerRound)
+		}
+		if last := fetches[len(fetches)-1]; !slices.Contains(last, "have "+base) {
+			t.Errorf("last fetch request = %q, want it to offer %s", last, base)
+		}
+		if got, err := repo.ResolveRevision("refs/remotes/origin/main"); err != nil || got != main {
+			t.Errorf("origin/main = %s, %v, want %s", got, err, main)
+		}
+	}
+}
+
+func TestDeepen(t *testing.T) {
+	server, refs := writeTestServer(t)
+	main, base := refs["refs/heads/main"], refs["refs/heads/old"]
+
+	var requests [][]string
+	srv := serveUploadPack(t, server, refs, &requests)
+	defer srv.Close()
+
+	gitDir := newTestRepo(t)
+	writeTestFiles(t, map[string]string{".git/config": fmt.Sprintf("[remote \"origin\"]\n\turl = %s/repo.git\n\tfetch = +refs/heads/main:refs/remotes/origin/main\n", srv.URL)})
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Deepen(srv.Client(), "origin", 1); !errors.Is(err, ErrNotShallow) {
+		t.Errorf("Deepen() of a full clone = %v, want %v", err, ErrNotShallow)
+	}
+
+	// The test server always sends everything, so the clone is made shallow by hand after fetching.
+	if _, err := repo.Fetch(srv.Client(), "origin", nil, maintenanceSignature); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFiles(t, map[string]string{".git/shallow": main + "\n"})
+
+	requests = nil
+	if _, err := repo.Deepen(srv.Client(), "origin", 1); err != nil {
+		t.Fatal(err)
+	}
+	fetch := requests[len(requests)-1]
+	for _, line := range []string{"want " + main, "shallow " + main, "deepen 1", "deepen-relative"} {
+		if !slices.Contains(fetch, line) {
+			t.Errorf("fetch request = %q, want %q", fetch, line)
+		}
+	}
+	if shallow, err := readShallow(repo.CommonDir); err != nil || !reflect.DeepEqual(shallow, map[string]bool{base: true}) {
+		t.Errorf("shallow commits after deepening = %v, %v, want %s", shallow, err, base)
+	}
+
+	// The root commit has no parents to cut off, so deepening past base leaves a full clone.
+	if _, err := repo.Deepen(srv.Client(), "origin", 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(gitDir, "shallow")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("shallow file after deepening to the root = %v, want it removed", err)
+	}
+}
@@ -0,0 +1,68 @@
+package git
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// Fingerprint identifies the state of a repository that anything computed from its history or index
+// depends on. Two fingerprints are equal only if nothing plain reads has changed in between, short of
+// edits to the work tree, which would take a walk over every file to notice.
+type Fingerprint struct {
+	Head  string    // The commit HEAD points to, or "" on a branch without commits yet
+	Index time.Time // When the index was last written, or the zero time without one
+	Refs  string    // A checksum of every ref, of where HEAD points, and of any operation in progress
+}
+
+// String returns the fingerprint as a single token, for caches to store and compare.
+func (f Fingerprint) String() string {
+	return fmt.Sprintf("%s-%d-%s", f.Head, f.Index.UnixNano(), f.Refs)
+}
+
+// Fingerprint returns the repository's current [Fingerprint]. It only reads HEAD, the refs, and the
+// index's modification time, never objects, so it is cheap enough to check on every prompt or to poll.
+func (repo *Repository) Fingerprint() (Fingerprint, error) {
+	var f Fingerprint
+	head, err := repo.resolveRef("HEAD")
+	if err != nil && !errors.Is(err, ErrRefNotFound) {
+		return Fingerprint{}, err
+	}
+	if f.Head, err = repo.resolveSymbolic("HEAD"); err != nil && !errors.Is(err, ErrRefNotFound) {
+		return Fingerprint{}, err
+	}
+
+	if info, err := os.Stat(filepath.Join(repo.GitDir, "index")); err == nil {
+		f.Index = info.ModTime()
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return Fingerprint{}, err
+	}
+
+	refs, err := repo.listRefs("refs/")
+	if err != nil {
+		return Fingerprint{}, err
+	}
+	state, err := repo.State()
+	if err != nil {
+		return Fingerprint{}, err
+	}
+	names := make([]string, 0, len(refs))
+	for name := range refs {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	h := sha1.New()
+	fmt.Fprintf(h, "HEAD %s\n%+v\n", head, state)
+	for _, name := range names {
+		fmt.Fprintf(h, "%s %s\n", name, refs[name])
+	}
+	f.Refs = hex.EncodeToString(h.Sum(nil))
+	return f, nil
+}
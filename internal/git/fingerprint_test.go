@@ -0,0 +1,57 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFingerprint(t *testing.T) {
+	gitDir := newTestRepo(t)
+	writeTestRef(t, gitDir, "HEAD", "ref: refs/heads/main")
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := map[string]string{}
+	check := func(step string) {
+		t.Helper()
+		f, err := repo.Fingerprint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		again, err := repo.Fingerprint()
+		if err != nil || again != f {
+			t.Fatalf("%s: fingerprint changed without the repository changing: %v, %v", step, f, again)
+		}
+		if previous, ok := seen[f.String()]; ok {
+			t.Errorf("%s: fingerprint %s is the same as after %s", step, f, previous)
+		}
+		seen[f.String()] = step
+	}
+
+	check("an unborn branch")
+	first := writeTestCommit(t, gitDir, "first")
+	writeTestRef(t, gitDir, "refs/heads/main", first)
+	check("the first commit")
+	writeTestRef(t, gitDir, "refs/remotes/origin/main", first)
+	check("a fetch")
+	writeTestRef(t, gitDir, "refs/heads/feature", first)
+	writeTestRef(t, gitDir, "HEAD", "ref: refs/heads/feature")
+	check("switching to a branch on the same commit")
+	writeTestRef(t, gitDir, "MERGE_HEAD", first)
+	check("starting a merge")
+
+	index := filepath.Join(gitDir, "index")
+	if err := os.WriteFile(index, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(index, time.Now(), time.Unix(1703123456, 0)); err != nil {
+		t.Fatal(err)
+	}
+	check("staging")
+	if f, _ := repo.Fingerprint(); f.Head != first || !f.Index.Equal(time.Unix(1703123456, 0)) {
+		t.Errorf("Fingerprint() = %+v, want HEAD at %s and the index's modification time", f, first)
+	}
+}
@@ -3,14 +3,14 @@ package git
 import (
 	"bufio"
 	"bytes"
+	"cmp"
 	"compress/zlib"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
-	"io/fs"
-	"os"
-	"path/filepath"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -53,6 +53,9 @@ var (
 	bTree      = []byte("tree")
 	bAuthor    = []byte("author")
 	bCommitter = []byte("committer")
+	bObject    = []byte("object")
+	bType      = []byte("type")
+	bTagger    = []byte("tagger")
 )
 
 var gitObjectName = map[GitObjectKind]string{
@@ -70,8 +73,14 @@ func (obj GitObjectKind) String() string {
 //
 // It holds the head commit and a DAG representation of the rest of the branches history.
 type BranchHistory struct {
-	Head  Commit            // The head of the branch
-	Graph map[string]Commit // A DAG in the form of an adjacecny list to access the rest of the branches history.
+	Head    Commit            // The head of the branch
+	Graph   map[string]Commit // A DAG in the form of an adjacecny list to access the rest of the branches history.
+	Shallow map[string]bool   // Commits in Graph whose parents were cut off by a shallow clone.
+}
+
+// Truncated reports whether the history stops early because the repository is a shallow clone.
+func (h BranchHistory) Truncated() bool {
+	return len(h.Shallow) > 0
 }
 
 // ObjectHeader represents the header of a git objects file.
@@ -88,6 +97,21 @@ type Commit struct {
 	Author    Signature // The author of the commit
 	Committer Signature // The committer of this commit
 	Parents   []string  // This commits parents
+
+	// Headers holds the headers after the committer that plain doesn't interpret, such as gpgsig,
+	// mergetag, and encoding, in the order they were stored.
+	Headers []CommitHeader
+
+	// Unterminated is set when the stored message doesn't end in a newline, which git itself never
+	// writes, so that [EncodeCommit] can reproduce it.
+	Unterminated bool
+}
+
+// CommitHeader is a header line of a commit object. A Value spanning several lines, like a signature,
+// has its lines joined by "\n", without the space git puts at the start of each continuation line.
+type CommitHeader struct {
+	Name  string
+	Value string
 }
 
 // Returns the commits display name (the first 7 characters of the commits hash)
@@ -100,6 +124,16 @@ func (c Commit) IsLeaf() bool {
 	return len(c.Parents) == 0
 }
 
+// Tag represents an annotated git tag object.
+type Tag struct {
+	Hash    string        // The hash of the tag object itself
+	Object  string        // The hash of the object being tagged
+	Kind    GitObjectKind // The kind of object being tagged
+	Name    string        // The name of the tag
+	Tagger  Signature     // Who created the tag
+	Message string        // The tag message
+}
+
 // Represents the aignature on a commit.
 //
 // Git signatures are made up of the name and email of the committer as well as the time that the commit was committed.
@@ -116,6 +150,8 @@ type Signature struct {
 type Decoder struct {
 	zr io.ReadCloser
 	br *bufio.Reader
+
+	hashSize int // The size of the raw hashes in trees, when they aren't SHA-1
 }
 
 // Creates a new Decoder.
@@ -137,16 +173,39 @@ func NewDecoder(src io.Reader) (*Decoder, error) {
 // Reset resets all internal state and primes the decoder to start reading from src.
 // Will fail is src is not zlib compressed
 func (d *Decoder) Reset(src io.Reader) error {
-	err := d.zr.(zlib.Resetter).Reset(src, nil)
-	if err != nil {
+	if d.zr == nil {
+		z, err := zlib.NewReader(src)
+		if err != nil {
+			return err
+		}
+		d.zr = z
+	} else if err := d.zr.(zlib.Resetter).Reset(src, nil); err != nil {
 		return err
 	}
-	d.br.Reset(d.zr)
+	if d.br == nil {
+		d.br = bufio.NewReader(d.zr)
+	} else {
+		d.br.Reset(d.zr)
+	}
 	return nil
 }
 
+// resetInflated primes the decoder to read an object whose content is already decompressed, such as one
+// read from a pack, which stores objects without the header a loose object starts with.
+func (d *Decoder) resetInflated(kind GitObjectKind, content []byte) {
+	r := io.MultiReader(strings.NewReader(kind.String()+" "+strconv.Itoa(len(content))+"\x00"), bytes.NewReader(content))
+	if d.br == nil {
+		d.br = bufio.NewReader(r)
+	} else {
+		d.br.Reset(r)
+	}
+}
+
 // Closes the Decoder.
 func (d *Decoder) Close() error {
+	if d.zr == nil {
+		return nil
+	}
 	return d.zr.Close()
 }
 
@@ -166,24 +225,27 @@ func (d *Decoder) Header() (ObjectHeader, error) {
 	}
 	line = line[:len(line)-1]
 
+	// commit 262
+	name, sizeBytes, ok := bytes.Cut(line, []byte{' '})
+	if !ok || len(sizeBytes) == 0 {
+		return ObjectHeader{}, fmt.Errorf("parse: malformed object header: %q", line)
+	}
+
 	var kind GitObjectKind
 	switch {
-	case bytes.HasPrefix(line, bCommit):
+	case bytes.Equal(name, bCommit):
 		kind = CommitObject
-	case bytes.HasPrefix(line, bTree):
+	case bytes.Equal(name, bTree):
 		kind = TreeObject
-	case bytes.HasPrefix(line, bBlob):
+	case bytes.Equal(name, bBlob):
 		kind = BlobObject
-	case bytes.HasPrefix(line, bTag):
+	case bytes.Equal(name, bTag):
 		kind = TagObject
 	default:
 		return ObjectHeader{}, fmt.Errorf("%w: %q", ErrUnknownObject, line)
 	}
 
-	// commit 262
-	sepIndex := slices.Index(line, ' ')
-
-	size, err := bytesToInt64(line[sepIndex+1:])
+	size, err := bytesToInt64(sizeBytes)
 	if err != nil {
 		return ObjectHeader{}, err
 	}
@@ -209,6 +271,13 @@ func (d *Decoder) DecodeCommit(hash string) (Commit, error) {
 			break
 		}
 
+		// Lines starting with a space continue the value of the header before them.
+		if lineBytes[0] == ' ' && len(commit.Headers) > 0 {
+			last := &commit.Headers[len(commit.Headers)-1]
+			last.Value += "\n" + string(lineBytes[1:])
+			continue
+		}
+
 		sepIndex := slices.Index(lineBytes, ' ')
 		if sepIndex == -1 {
 			return Commit{}, fmt.Errorf("parse: line did not contain canonical separator: %s", lineBytes)
@@ -221,27 +290,18 @@ func (d *Decoder) DecodeCommit(hash string) (Commit, error) {
 		case bytes.Equal(header, bParent):
 			commit.Parents = append(commit.Parents, string(value))
 		case bytes.Equal(header, bAuthor), bytes.Equal(header, bCommitter):
-			emailStartIndex := slices.Index(value, '<')
-			emailEndIndex := slices.Index(value, '>')
-
-			email := string(value[emailStartIndex+1 : emailEndIndex])
-			name := string(value[:emailStartIndex-1])
-			timestamp, err := parseGitUnixTs(value[emailEndIndex+2:])
+			sig, err := parseSignature(value)
 			if err != nil {
 				return Commit{}, fmt.Errorf("parse: failed to parse commit due to time error %w", err)
 			}
 
-			sig := Signature{
-				Name:  name,
-				Email: email,
-				Time:  timestamp,
-			}
-
 			if bytes.HasPrefix(header, bAuthor) {
 				commit.Author = sig
 			} else {
 				commit.Committer = sig
 			}
+		default:
+			commit.Headers = append(commit.Headers, CommitHeader{Name: string(header), Value: string(value)})
 		}
 	}
 
@@ -250,128 +310,239 @@ func (d *Decoder) DecodeCommit(hash string) (Commit, error) {
 		return Commit{}, fmt.Errorf("parse: failed to parse commit message for %s. %w", hash, err)
 	}
 	commit.Message = strings.TrimSuffix(string(message), "\n")
+	commit.Unterminated = !bytes.HasSuffix(message, []byte("\n"))
 	return commit, nil
 }
 
-// Returns a path to the .git directory in this repo.
-// Will return an error of called from outside a git repository.
-func FindGitDir() (string, error) {
-	cwd, err := os.Getwd()
-	if err != nil {
-		return "", err
-	}
-
-	var gitDir string
-	var info fs.FileInfo
+// Reads, decodes, and returns the current git object as a tag.
+// Once this method is called, Header() will result in an error.
+func (d *Decoder) DecodeTag(hash string) (Tag, error) {
+	tag := Tag{Hash: hash}
 	for {
-		gitDir = filepath.Join(cwd, ".git")
-		info, err = os.Stat(gitDir)
+		lineBytes, err := d.br.ReadSlice('\n')
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return Tag{}, err
+		}
+		lineBytes = lineBytes[:len(lineBytes)-1]
 
-		if err == nil {
+		if len(lineBytes) == 0 {
 			break
 		}
 
-		if !errors.Is(err, fs.ErrNotExist) {
-			return "", err
+		sepIndex := slices.Index(lineBytes, ' ')
+		if sepIndex == -1 {
+			return Tag{}, fmt.Errorf("parse: line did not contain canonical separator: %s", lineBytes)
 		}
 
-		up := filepath.Dir(cwd)
-		if up == cwd {
-			return "", ErrNotRepo
+		header, value := lineBytes[:sepIndex], lineBytes[sepIndex+1:]
+		switch {
+		case bytes.Equal(header, bObject):
+			tag.Object = string(value)
+		case bytes.Equal(header, bType):
+			kind, ok := parseObjectKind(value)
+			if !ok {
+				return Tag{}, fmt.Errorf("%w: %q", ErrUnknownObject, value)
+			}
+			tag.Kind = kind
+		case bytes.Equal(header, bTag):
+			tag.Name = string(value)
+		case bytes.Equal(header, bTagger):
+			sig, err := parseSignature(value)
+			if err != nil {
+				return Tag{}, fmt.Errorf("parse: failed to parse tag due to time error %w", err)
+			}
+			tag.Tagger = sig
 		}
-		cwd = up
 	}
 
-	if !info.IsDir() {
-		fileBytes, err := os.ReadFile(gitDir)
+	message, err := io.ReadAll(d.br)
+	if err != nil {
+		return Tag{}, fmt.Errorf("parse: failed to parse tag message for %s. %w", hash, err)
+	}
+	tag.Message = strings.TrimSuffix(string(message), "\n")
+	return tag, nil
+}
+
+// Reads, decodes, and returns the entries of the current git object as a tree.
+// Once this method is called, Header() will result in an error.
+func (d *Decoder) DecodeTree() ([]TreeEntry, error) {
+	var entries []TreeEntry
+	for {
+		modeBytes, err := d.br.ReadSlice(' ')
+		if err == io.EOF && len(modeBytes) == 0 {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse: truncated tree entry: %w", err)
+		}
+		mode, err := strconv.ParseUint(string(modeBytes[:len(modeBytes)-1]), 8, 32)
 		if err != nil {
-			return "", err
+			return nil, fmt.Errorf("parse: bad tree entry mode %q", modeBytes)
+		}
+
+		name, err := d.br.ReadString('\x00')
+		if err != nil {
+			return nil, fmt.Errorf("parse: truncated tree entry: %w", err)
+		}
+
+		raw := make([]byte, cmp.Or(d.hashSize, SHA1.HexSize()/2))
+		if _, err := io.ReadFull(d.br, raw); err != nil {
+			return nil, fmt.Errorf("parse: truncated hash for tree entry %s: %w", name, err)
+		}
+
+		entries = append(entries, TreeEntry{
+			Mode: FileMode(mode),
+			Name: name[:len(name)-1],
+			Hash: hex.EncodeToString(raw),
+		})
+	}
+}
+
+// Reads and returns the contents of the current git object as a blob.
+// Once this method is called, Header() will result in an error.
+func (d *Decoder) DecodeBlob() ([]byte, error) {
+	data, err := io.ReadAll(d.br)
+	if err != nil {
+		return nil, fmt.Errorf("parse: truncated blob: %w", err)
+	}
+	return data, nil
+}
+
+func parseObjectKind(name []byte) (GitObjectKind, bool) {
+	for kind, kindName := range gitObjectName {
+		if string(name) == kindName {
+			return kind, true
 		}
-		gitDir = strings.TrimSpace(strings.TrimPrefix(string(fileBytes), "gitdir: "))
 	}
+	return 0, false
+}
 
-	return filepath.Abs(gitDir)
+// Returns a path to the .git directory in this repo.
+// Will return an error of called from outside a git repository.
+//
+// In a linked worktree this is the worktree's own git directory; see [Repository] for locating shared data.
+func FindGitDir() (string, error) {
+	repo, err := OpenRepository()
+	if err != nil {
+		return "", err
+	}
+	return repo.GitDir, nil
 }
 
 // Get the [BranchHistory] for the given branch.
+//
+// Replace refs (refs/replace/*) and info/grafts are honored, so the history matches what stock git shows.
 func GetHistoryFor(branch string) (BranchHistory, error) {
-	gitDir, err := FindGitDir()
+	repo, err := OpenRepository()
 	if err != nil {
 		return BranchHistory{}, err
 	}
 
-	branchPath := filepath.Join(gitDir, "refs", "heads", branch)
-	headBytes, err := os.ReadFile(branchPath)
+	headCommitStr, err := repo.resolveRef("refs/heads/" + branch)
 	if err != nil {
 		return BranchHistory{}, err
 	}
+	return repo.historyFrom(headCommitStr)
+}
 
-	headCommitStr := strings.TrimSpace(string(headBytes))
-	objectsPath := filepath.Join(gitDir, "objects")
-	headCommitPath := filepath.Join(objectsPath, headCommitStr[:2], headCommitStr[2:])
+// Get the [BranchHistory] reachable from rev, which may be a branch, tag, remote branch, HEAD, or full commit hash.
+func GetHistoryForRevision(rev string) (BranchHistory, error) {
+	return GetLineageForRevision(rev, AllParents)
+}
 
-	objStream, _ := os.Open(headCommitPath)
-	d, err := NewDecoder(objStream)
+// GetLineageForRevision is [GetHistoryForRevision], following only the parents lineage selects.
+func GetLineageForRevision(rev string, lineage Lineage) (BranchHistory, error) {
+	repo, err := OpenRepository()
 	if err != nil {
-		return BranchHistory{}, fmt.Errorf("git: failed to init object decoder: %w", err)
+		return BranchHistory{}, err
 	}
-	defer d.Close()
 
-	header, err := d.Header()
+	hash, err := repo.ResolveRevision(rev)
 	if err != nil {
-		return BranchHistory{}, fmt.Errorf("git: failed to parse header: %w", err)
+		return BranchHistory{}, err
 	}
+	return repo.lineageFrom(hash, lineage)
+}
 
-	if header.Kind != CommitObject {
-		return BranchHistory{}, errors.New("start file not a commit")
+func (repo *Repository) historyFrom(headCommitStr string) (BranchHistory, error) {
+	return repo.lineageFrom(headCommitStr, AllParents)
+}
+
+// lineageFrom is historyFrom, following only the parents lineage selects.
+func (repo *Repository) lineageFrom(headCommitStr string, lineage Lineage) (BranchHistory, error) {
+	shallow, err := readShallow(repo.CommonDir)
+	if err != nil {
+		return BranchHistory{}, fmt.Errorf("git: failed to read shallow file: %w", err)
 	}
 
-	headCommitObj, err := d.DecodeCommit(headCommitStr)
+	r, err := newCommitReader(repo)
+	if err != nil {
+		return BranchHistory{}, err
+	}
+	defer r.Close()
+
+	headCommitStr, err = r.peel(headCommitStr)
 	if err != nil {
 		return BranchHistory{}, fmt.Errorf("failed to parse head: %w", err)
 	}
 
-	graph := BranchHistory{Head: headCommitObj, Graph: map[string]Commit{headCommitStr: headCommitObj}}
-	stack := slices.Clone(headCommitObj.Parents)
-	for len(stack) > 0 {
-		currCommitHash := stack[len(stack)-1] // get last element
-		stack = stack[:len(stack)-1]          // remove it (pop)
+	headCommitObj, ok, err := r.read(headCommitStr)
+	if err != nil {
+		return BranchHistory{}, fmt.Errorf("failed to parse head: %w", err)
+	}
+	if !ok {
+		return BranchHistory{}, errors.New("start file not a commit")
+	}
 
-		commitPath := filepath.Join(objectsPath, currCommitHash[:2], currCommitHash[2:])
+	graph := BranchHistory{
+		Head:    headCommitObj,
+		Graph:   map[string]Commit{headCommitStr: headCommitObj},
+		Shallow: map[string]bool{},
+	}
 
-		objStream, err = os.Open(commitPath)
-		if err != nil {
-			return BranchHistory{}, err
-		}
+	var stack []string
+	if shallow[headCommitStr] {
+		graph.Shallow[headCommitStr] = true
+	} else {
+		stack = slices.Clone(lineage.parents(headCommitObj))
+	}
 
-		d.Reset(objStream)
-		header, err := d.Header()
-		if err != nil {
-			return BranchHistory{}, err
-		}
+	if err := r.walkLineage(&graph, shallow, stack, lineage); err != nil {
+		return BranchHistory{}, err
+	}
 
-		if header.Kind != CommitObject {
-			continue
-		}
+	return graph, nil
+}
 
-		commit, err := d.DecodeCommit(currCommitHash)
-		if err != nil {
-			return BranchHistory{}, err
-		}
+// parseSignature parses an identity in git's "Name <email> 1703123456 +0000" form.
+func parseSignature(value []byte) (Signature, error) {
+	emailStartIndex := slices.Index(value, '<')
+	emailEndIndex := slices.Index(value, '>')
+	if emailStartIndex < 1 || emailEndIndex < emailStartIndex || emailEndIndex+2 > len(value) {
+		return Signature{}, fmt.Errorf("parse: malformed signature: %s", value)
+	}
 
-		graph.Graph[currCommitHash] = commit
-		for _, parent := range commit.Parents {
-			if _, ok := graph.Graph[parent]; !ok {
-				stack = append(stack, parent)
-			}
-		}
+	timestamp, err := parseGitUnixTs(value[emailEndIndex+2:])
+	if err != nil {
+		return Signature{}, err
 	}
 
-	return graph, nil
+	return Signature{
+		Name:  string(value[:emailStartIndex-1]),
+		Email: string(value[emailStartIndex+1 : emailEndIndex]),
+		Time:  timestamp,
+	}, nil
 }
 
 func parseGitUnixTs(timestamp []byte) (time.Time, error) {
+	// 1703123456 +0000
 	sepIndex := slices.Index(timestamp, ' ')
+	if sepIndex < 1 || len(timestamp) < sepIndex+6 {
+		return time.Time{}, fmt.Errorf("parse: malformed git timestamp: %s", timestamp)
+	}
 
 	var sign int
 	if timestamp[sepIndex+1] == '-' {
@@ -0,0 +1,77 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// versionTimeout bounds how long git version may take, which answers at once unless something is wrong.
+const versionTimeout = 10 * time.Second
+
+var ErrBadGitVersion = errors.New("unrecognized git version")
+
+// GitVersion is a release of the git command, like 2.39.5.
+type GitVersion struct {
+	Major, Minor, Patch int
+}
+
+// The releases that introduced the commands and flags [ShellClient] uses, which it avoids on older gits.
+var (
+	gitBranchShowCurrent = GitVersion{2, 22, 0} // git branch --show-current
+	gitSwitch            = GitVersion{2, 23, 0} // git switch
+	gitNoWriteFetchHead  = GitVersion{2, 29, 0} // git fetch --no-write-fetch-head
+)
+
+// ParseGitVersion parses the output of git version, like "git version 2.39.5", allowing for what
+// distributors append, as in "git version 2.39.5 (Apple Git-154)" or "git version 2.45.1.windows.1".
+func ParseGitVersion(output string) (GitVersion, error) {
+	fields := strings.Fields(output)
+	if len(fields) < 3 || fields[0] != "git" || fields[1] != "version" {
+		return GitVersion{}, fmt.Errorf("%w: %q", ErrBadGitVersion, strings.TrimSpace(output))
+	}
+	var v GitVersion
+	parts := strings.SplitN(fields[2], ".", 4)
+	for i, field := range []*int{&v.Major, &v.Minor, &v.Patch} {
+		if i >= len(parts) {
+			break
+		}
+		n, err := strconv.Atoi(parts[i])
+		if err != nil {
+			if i < 2 {
+				return GitVersion{}, fmt.Errorf("%w: %q", ErrBadGitVersion, strings.TrimSpace(output))
+			}
+			break // A release candidate, like 2.45.0-rc1
+		}
+		*field = n
+	}
+	return v, nil
+}
+
+// AtLeast reports whether v is the release other or a later one.
+func (v GitVersion) AtLeast(other GitVersion) bool {
+	if v.Major != other.Major {
+		return v.Major > other.Major
+	}
+	if v.Minor != other.Minor {
+		return v.Minor > other.Minor
+	}
+	return v.Patch >= other.Patch
+}
+
+func (v GitVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// InstalledGitVersion asks the git command on the PATH for its version, giving up after a few seconds.
+func InstalledGitVersion() (GitVersion, error) {
+	cmd, done := gitCommand(context.Background(), versionTimeout, "version")
+	output, err := cmd.Output()
+	if err := done(err); err != nil {
+		return GitVersion{}, err
+	}
+	return ParseGitVersion(string(output))
+}
@@ -0,0 +1,35 @@
+package git
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseGitVersion(t *testing.T) {
+	tests := []struct {
+		output string
+		want   GitVersion
+	}{
+		{"git version 2.39.5\n", GitVersion{2, 39, 5}},
+		{"git version 2.39.5 (Apple Git-154)\n", GitVersion{2, 39, 5}},
+		{"git version 2.45.1.windows.1\n", GitVersion{2, 45, 1}},
+		{"git version 2.45.0-rc1\n", GitVersion{2, 45, 0}},
+		{"git version 2.20\n", GitVersion{2, 20, 0}},
+	}
+	for _, tt := range tests {
+		got, err := ParseGitVersion(tt.output)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseGitVersion(%q) = %v, %v, want %v", tt.output, got, err, tt.want)
+		}
+	}
+	for _, output := range []string{"", "hub version 2.14.2", "git version two"} {
+		if _, err := ParseGitVersion(output); !errors.Is(err, ErrBadGitVersion) {
+			t.Errorf("ParseGitVersion(%q) = %v, want %v", output, err, ErrBadGitVersion)
+		}
+	}
+
+	old := GitVersion{2, 20, 1}
+	if old.AtLeast(gitSwitch) || !old.AtLeast(GitVersion{2, 20, 0}) || !(GitVersion{3, 0, 0}).AtLeast(gitNoWriteFetchHead) {
+		t.Error("AtLeast() misorders versions")
+	}
+}
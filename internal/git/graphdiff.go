@@ -0,0 +1,166 @@
+package git
+
+import (
+	"errors"
+	"io/fs"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// RefChange is a ref that differs between two snapshots of the refs.
+type RefChange struct {
+	Name   string
+	Old    string // The commit the ref pointed to before, or "" if it didn't exist
+	New    string // The commit it points to now, or "" if it was deleted
+	Forced bool   // The ref moved to a commit that doesn't contain Old, dropping commits from its history
+}
+
+// GraphDiff is what changed between two snapshots of the history graph.
+type GraphDiff struct {
+	Commits []Commit // Commits reachable in the new snapshot but not the old one, newest first
+	Refs    []RefChange
+}
+
+// RefsAt returns where every ref under refs/ pointed at time t, read back from the reflogs, as a snapshot
+// for [Repository.DiffGraphs]. Symbolic refs are left out, since they follow the refs they name. A ref
+// without a reflog, like most tags, is assumed not to have moved; refs deleted since t, whose reflogs git
+// deletes with them, are missing.
+func (repo *Repository) RefsAt(t time.Time) (map[string]string, error) {
+	refs, err := repo.listRefs("refs/")
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := map[string]string{}
+	for name, hash := range refs {
+		if strings.HasPrefix(hash, "ref: ") {
+			continue
+		}
+		entries, err := repo.Reflog(name)
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) == 0 {
+			snapshot[name] = hash
+			continue
+		}
+
+		// The value before the first update after t, or after the last update if none came later.
+		at := entries[len(entries)-1].New
+		for _, entry := range entries {
+			if entry.Committer.Time.After(t) {
+				at = entry.Old
+				break
+			}
+		}
+		if strings.Trim(at, "0") != "" {
+			snapshot[name] = at
+		}
+	}
+	return snapshot, nil
+}
+
+// DiffGraphs compares two snapshots of the refs, such as [Repository.RefsAt] before and after a fetch,
+// and returns the commits that appeared in the history and the refs that were created, moved, or
+// deleted. Annotated tags are compared by the commits they point to, and symbolic refs are ignored.
+// lineage chooses the parents followed from the new refs, so [FirstParent] leaves out commits that
+// appeared only by being merged into a branch.
+func (repo *Repository) DiffGraphs(old, new map[string]string, lineage Lineage) (GraphDiff, error) {
+	r, err := newCommitReader(repo)
+	if err != nil {
+		return GraphDiff{}, err
+	}
+	defer r.Close()
+	shallow, err := readShallow(repo.CommonDir)
+	if err != nil {
+		return GraphDiff{}, err
+	}
+
+	// reach walks the history of the commits the refs point to. Refs to missing objects are skipped.
+	reach := func(refs map[string]string, lineage Lineage) (map[string]Commit, error) {
+		history := BranchHistory{Graph: map[string]Commit{}, Shallow: map[string]bool{}}
+		var tips []string
+		for _, hash := range refs {
+			if strings.HasPrefix(hash, "ref: ") {
+				continue
+			}
+			peeled, err := r.peel(hash)
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			tips = append(tips, peeled)
+		}
+		if err := r.walkLineage(&history, shallow, tips, lineage); err != nil {
+			return nil, err
+		}
+		return history.Graph, nil
+	}
+
+	before, err := reach(old, AllParents)
+	if err != nil {
+		return GraphDiff{}, err
+	}
+	after, err := reach(new, lineage)
+	if err != nil {
+		return GraphDiff{}, err
+	}
+
+	var result GraphDiff
+	appeared := map[string]Commit{}
+	for hash, commit := range after {
+		if _, ok := before[hash]; !ok {
+			appeared[hash] = commit
+		}
+	}
+	result.Commits = topoOrder(appeared)
+
+	names := slices.Sorted(maps.Keys(old))
+	for name := range new {
+		if _, ok := old[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		change := RefChange{Name: name, Old: old[name], New: new[name]}
+		if change.Old == change.New || strings.HasPrefix(change.Old, "ref: ") || strings.HasPrefix(change.New, "ref: ") {
+			continue
+		}
+		if change.Old != "" && change.New != "" {
+			forced, err := droppedHistory(r, shallow, change.Old, change.New)
+			if err != nil {
+				return GraphDiff{}, err
+			}
+			change.Forced = forced
+		}
+		result.Refs = append(result.Refs, change)
+	}
+	return result, nil
+}
+
+// droppedHistory reports whether the commit old isn't in the history of new, so moving a ref from old to
+// new was a forced update. An old commit that has since been pruned counts as dropped.
+func droppedHistory(r *commitReader, shallow map[string]bool, old, new string) (bool, error) {
+	var tips [2]string
+	for i, hash := range []string{old, new} {
+		peeled, err := r.peel(hash)
+		if errors.Is(err, fs.ErrNotExist) {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		tips[i] = peeled
+	}
+	history := BranchHistory{Graph: map[string]Commit{}, Shallow: map[string]bool{}}
+	if err := r.walk(&history, shallow, []string{tips[1]}); err != nil {
+		return false, err
+	}
+	_, kept := history.Graph[tips[0]]
+	return !kept, nil
+}
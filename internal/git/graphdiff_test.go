@@ -0,0 +1,83 @@
+package git
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDiffGraphs(t *testing.T) {
+	gitDir := newTestRepo(t)
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := writeTestCommit(t, gitDir, "base")
+	mainNext := writeTestCommit(t, gitDir, "main next", base)
+	side := writeTestCommit(t, gitDir, "side", base)
+
+	start := time.Unix(1703120000, 0)
+	at := func(minutes int) Signature {
+		return Signature{Name: "Jane", Email: "jane@example.com", Time: start.Add(time.Duration(minutes) * time.Minute)}
+	}
+	update := func(name, old, new string, minutes int) {
+		t.Helper()
+		if err := repo.UpdateRef(name, old, new, at(minutes), "fetch"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	update("refs/remotes/origin/main", ZeroHash, base, 0)
+	update("refs/remotes/origin/main", base, mainNext, 10)
+	update("refs/remotes/origin/side", ZeroHash, side, 10)
+
+	before, err := repo.RefsAt(at(5).Time)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"refs/remotes/origin/main": base}; !reflect.DeepEqual(before, want) {
+		t.Fatalf("RefsAt = %v, want %v", before, want)
+	}
+	now, err := repo.Refs()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := repo.DiffGraphs(before, now, AllParents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var appeared []string
+	for _, commit := range changes.Commits {
+		appeared = append(appeared, commit.Hash)
+	}
+	if len(appeared) != 2 || !slices.Contains(appeared, mainNext) || !slices.Contains(appeared, side) {
+		t.Errorf("new commits = %v, want %s and %s", appeared, mainNext, side)
+	}
+	wantRefs := []RefChange{
+		{Name: "refs/remotes/origin/main", Old: base, New: mainNext},
+		{Name: "refs/remotes/origin/side", New: side},
+	}
+	if !reflect.DeepEqual(changes.Refs, wantRefs) {
+		t.Errorf("ref changes = %+v, want %+v", changes.Refs, wantRefs)
+	}
+
+	// Rewriting main onto side drops mainNext from its history.
+	update("refs/remotes/origin/main", mainNext, side, 20)
+	before, err = repo.RefsAt(at(15).Time)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now, _ = repo.Refs()
+	changes, err = repo.DiffGraphs(before, now, AllParents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes.Commits) != 0 {
+		t.Errorf("expected no new commits, got %d", len(changes.Commits))
+	}
+	wantRefs = []RefChange{{Name: "refs/remotes/origin/main", Old: mainNext, New: side, Forced: true}}
+	if !reflect.DeepEqual(changes.Refs, wantRefs) {
+		t.Errorf("ref changes = %+v, want %+v", changes.Refs, wantRefs)
+	}
+}
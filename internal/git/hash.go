@@ -0,0 +1,110 @@
+package git
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strconv"
+)
+
+var ErrSizeMismatch = errors.New("object size does not match its header")
+
+// HashFormat identifies the hash function a repository uses to name its objects.
+//
+// Repositories use SHA-1 unless they were created with --object-format=sha256.
+type HashFormat int
+
+const (
+	// SHA1 names objects with 40 hex character SHA-1 hashes.
+	SHA1 HashFormat = iota + 1
+
+	// SHA256 names objects with 64 hex character SHA-256 hashes.
+	SHA256
+)
+
+var hashFormatName = map[HashFormat]string{
+	SHA1:   "sha1",
+	SHA256: "sha256",
+}
+
+func (f HashFormat) String() string {
+	return hashFormatName[f]
+}
+
+// HexSize returns the length of a hash in this format when written as hex.
+func (f HashFormat) HexSize() int {
+	if f == SHA256 {
+		return sha256.Size * 2
+	}
+	return sha1.Size * 2
+}
+
+func (f HashFormat) new() hash.Hash {
+	if f == SHA256 {
+		return sha256.New()
+	}
+	return sha1.New()
+}
+
+// HashObject returns the SHA-1 name git gives an object of the given kind holding data,
+// the same value git hash-object prints.
+func HashObject(kind GitObjectKind, data []byte) string {
+	return SHA1.HashObject(kind, data)
+}
+
+// HashObject returns the name git gives an object of the given kind holding data in this format.
+func (f HashFormat) HashObject(kind GitObjectKind, data []byte) string {
+	h := NewObjectHasher(f, kind, int64(len(data)))
+	h.Write(data)
+	name, _ := h.Sum()
+	return name
+}
+
+// HashObjectStream returns the name of an object whose content of size bytes is read from r.
+// Fails with [ErrSizeMismatch] if r does not hold exactly size bytes.
+func (f HashFormat) HashObjectStream(kind GitObjectKind, size int64, r io.Reader) (string, error) {
+	h := NewObjectHasher(f, kind, size)
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return h.Sum()
+}
+
+// ObjectHasher computes an object's name as its content is written to it.
+//
+// Git hashes the object header ("blob 12\x00") ahead of the content, so the size must be known up front.
+// A new ObjectHasher is created by calling [NewObjectHasher].
+type ObjectHasher struct {
+	h       hash.Hash
+	size    int64
+	written int64
+}
+
+// NewObjectHasher starts hashing an object of the given kind whose content is size bytes long.
+func NewObjectHasher(f HashFormat, kind GitObjectKind, size int64) *ObjectHasher {
+	h := f.new()
+	io.WriteString(h, kind.String())
+	h.Write([]byte{' '})
+	io.WriteString(h, strconv.FormatInt(size, 10))
+	h.Write([]byte{0})
+	return &ObjectHasher{h: h, size: size}
+}
+
+// Write adds content to the object being hashed. It never returns an error.
+func (o *ObjectHasher) Write(p []byte) (int, error) {
+	o.written += int64(len(p))
+	return o.h.Write(p)
+}
+
+// Sum returns the object's name as hex.
+// Fails with [ErrSizeMismatch] if the content written doesn't match the size given up front.
+func (o *ObjectHasher) Sum() (string, error) {
+	if o.written != o.size {
+		return "", fmt.Errorf("%w: expected %d bytes, got %d", ErrSizeMismatch, o.size, o.written)
+	}
+	return hex.EncodeToString(o.h.Sum(nil)), nil
+}
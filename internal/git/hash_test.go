@@ -0,0 +1,41 @@
+package git
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestHashObject(t *testing.T) {
+	cases := []struct {
+		format   HashFormat
+		kind     GitObjectKind
+		data     string
+		expected string
+	}{
+		{SHA1, BlobObject, "hello\n", "ce013625030ba8dba906f756967f9e9ca394464a"},
+		{SHA1, TreeObject, "", "4b825dc642cb6eb9a060e54bf8d69288fbee4904"},
+		{SHA256, BlobObject, "hello\n", "2cf8d83d9ee29543b34a87727421fdecb7e3f3a183d337639025de576db9ebb4"},
+	}
+
+	for _, c := range cases {
+		if actual := c.format.HashObject(c.kind, []byte(c.data)); actual != c.expected {
+			t.Errorf("%s hash of %s %q: expected %s, got %s", c.format, c.kind, c.data, c.expected, actual)
+		}
+
+		streamed, err := c.format.HashObjectStream(c.kind, int64(len(c.data)), strings.NewReader(c.data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if streamed != c.expected {
+			t.Errorf("streamed %s hash of %s %q: expected %s, got %s", c.format, c.kind, c.data, c.expected, streamed)
+		}
+	}
+}
+
+func TestHashObjectStreamSizeMismatch(t *testing.T) {
+	_, err := SHA1.HashObjectStream(BlobObject, 10, strings.NewReader("short"))
+	if !errors.Is(err, ErrSizeMismatch) {
+		t.Fatalf("expected ErrSizeMismatch, got %v", err)
+	}
+}
@@ -0,0 +1,218 @@
+package git
+
+import (
+	"compress/zlib"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestRepo creates an empty .git directory in a temp dir and changes into it.
+func newTestRepo(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	gitDir := filepath.Join(root, ".git")
+	for _, dir := range []string{"objects", "refs/heads"} {
+		if err := os.MkdirAll(filepath.Join(gitDir, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Chdir(root)
+	return gitDir
+}
+
+// writeTestObject stores a loose object in gitDir and returns its hash.
+func writeTestObject(t *testing.T, gitDir string, kind GitObjectKind, body string) string {
+	t.Helper()
+	raw := fmt.Sprintf("%s %d\x00%s", kind, len(body), body)
+	hash := HashObject(kind, []byte(body))
+
+	dir := filepath.Join(gitDir, "objects", hash[:2])
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(filepath.Join(dir, hash[2:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	zw := zlib.NewWriter(f)
+	if _, err := zw.Write([]byte(raw)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+// writeTestCommit stores a commit with the given message and parents and returns its hash.
+func writeTestCommit(t *testing.T, gitDir, message string, parents ...string) string {
+	t.Helper()
+	var b strings.Builder
+	b.WriteString("tree 4b825dc642cb6eb9a060e54bf8d69288fbee4904\n")
+	for _, p := range parents {
+		fmt.Fprintf(&b, "parent %s\n", p)
+	}
+	b.WriteString("author John Doe <john.doe@example.com> 1703123456 +0000\n")
+	b.WriteString("committer John Doe <john.doe@example.com> 1703123456 +0000\n")
+	fmt.Fprintf(&b, "\n%s\n", message)
+	return writeTestObject(t, gitDir, CommitObject, b.String())
+}
+
+// writeTestRef points refs/<name> at hash.
+func writeTestRef(t *testing.T, gitDir, name, hash string) {
+	t.Helper()
+	path := filepath.Join(gitDir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(hash+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHistoryComplete(t *testing.T) {
+	gitDir := newTestRepo(t)
+	first := writeTestCommit(t, gitDir, "first")
+	second := writeTestCommit(t, gitDir, "second", first)
+	writeTestRef(t, gitDir, "refs/heads/main", second)
+
+	history, err := GetHistoryFor("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(history.Graph) != 2 {
+		t.Fatalf("expected 2 commits in history, got %d", len(history.Graph))
+	}
+	if history.Truncated() {
+		t.Fatal("complete history should not be truncated")
+	}
+}
+
+func TestHistoryShallow(t *testing.T) {
+	gitDir := newTestRepo(t)
+	missing := strings.Repeat("a", 40)
+	boundary := writeTestCommit(t, gitDir, "boundary", missing)
+	head := writeTestCommit(t, gitDir, "head", boundary)
+	writeTestRef(t, gitDir, "refs/heads/main", head)
+
+	if err := os.WriteFile(filepath.Join(gitDir, "shallow"), []byte(boundary+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := GetHistoryFor("main")
+	if err != nil {
+		t.Fatalf("shallow history should not fail: %s", err)
+	}
+
+	if !history.Truncated() {
+		t.Fatal("expected history to be truncated")
+	}
+	if !history.Shallow[boundary] {
+		t.Fatalf("expected %s to be marked shallow", boundary[:7])
+	}
+	if _, ok := history.Graph[missing]; ok {
+		t.Fatal("missing parent should not be in the graph")
+	}
+}
+
+func TestHistoryReplaceRefs(t *testing.T) {
+	gitDir := newTestRepo(t)
+	old := writeTestCommit(t, gitDir, "old root")
+	replacement := writeTestCommit(t, gitDir, "rewritten root")
+	head := writeTestCommit(t, gitDir, "head", old)
+	writeTestRef(t, gitDir, "refs/heads/main", head)
+
+	// A replace ref whose target isn't an object name is skipped rather than followed.
+	writeTestRef(t, gitDir, "refs/replace/"+head, "x")
+	packed := "# pack-refs with: peeled fully-peeled sorted\n" + replacement + " refs/replace/" + old + "\n"
+	if err := os.WriteFile(filepath.Join(gitDir, "packed-refs"), []byte(packed), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := GetHistoryFor("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual := history.Graph[old].Message
+	if actual != "rewritten root" {
+		t.Fatalf("expected replaced commit message, got %q", actual)
+	}
+	if actual := history.Graph[head].Message; actual != "head" {
+		t.Fatalf("expected the bad replace ref to be ignored, got %q", actual)
+	}
+
+	t.Setenv("GIT_NO_REPLACE_OBJECTS", "1")
+	history, err = GetHistoryFor("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := history.Graph[old].Message; actual != "old root" {
+		t.Fatalf("expected GIT_NO_REPLACE_OBJECTS to turn off replacement, got %q", actual)
+	}
+}
+
+func TestHistoryGrafts(t *testing.T) {
+	gitDir := newTestRepo(t)
+	root := writeTestCommit(t, gitDir, "root")
+	middle := writeTestCommit(t, gitDir, "middle", root)
+	other := writeTestCommit(t, gitDir, "other")
+	head := writeTestCommit(t, gitDir, "head", middle)
+	writeTestRef(t, gitDir, "refs/heads/main", head)
+
+	os.MkdirAll(filepath.Join(gitDir, "info"), 0o755)
+	grafts := middle + " " + other + "\n"
+	if err := os.WriteFile(filepath.Join(gitDir, "info", "grafts"), []byte(grafts), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := GetHistoryFor("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := history.Graph[root]; ok {
+		t.Fatal("grafted away parent should not be in the history")
+	}
+	if _, ok := history.Graph[other]; !ok {
+		t.Fatal("grafted parent should be in the history")
+	}
+}
+
+func TestHistoryLinkedWorktree(t *testing.T) {
+	gitDir := newTestRepo(t)
+	head := writeTestCommit(t, gitDir, "shared")
+	writeTestRef(t, gitDir, "refs/heads/feature", head)
+
+	// Lay out a linked worktree the way git worktree add does and move into it.
+	wtGitDir := filepath.Join(gitDir, "worktrees", "feature")
+	os.MkdirAll(wtGitDir, 0o755)
+	os.WriteFile(filepath.Join(wtGitDir, "commondir"), []byte("../..\n"), 0o644)
+	os.WriteFile(filepath.Join(wtGitDir, "HEAD"), []byte("ref: refs/heads/feature\n"), 0o644)
+
+	wt := t.TempDir()
+	os.WriteFile(filepath.Join(wt, ".git"), []byte("gitdir: "+wtGitDir+"\n"), 0o644)
+	t.Chdir(wt)
+
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.GitDir != wtGitDir || repo.CommonDir != gitDir {
+		t.Fatalf("expected git dir %s and common dir %s, got %s and %s", wtGitDir, gitDir, repo.GitDir, repo.CommonDir)
+	}
+
+	history, err := GetHistoryForRevision("HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if history.Head.Hash != head {
+		t.Fatalf("expected HEAD to resolve to %s, got %s", head[:7], history.Head.DisName())
+	}
+}
@@ -0,0 +1,196 @@
+package git
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ignoreRule is a single pattern line from a gitignore file.
+type ignoreRule struct {
+	base    string // Directory the rule is relative to, slash separated. Empty for the repo root.
+	pattern string // The pattern to match, trimmed of any leading or trailing slash.
+	negate  bool   // Set for "!pattern", which re-includes paths an earlier rule ignored.
+	dirOnly bool   // Set for "pattern/", which only matches directories.
+}
+
+// Ignore answers gitignore queries for paths in a work tree.
+//
+// Rules are read from core.excludesFile, .git/info/exclude, and the .gitignore files in the directories
+// of queried paths, with git's precedence: later sources override earlier ones, deeper .gitignore files
+// override shallower ones, and within a file the last matching line wins. A new Ignore is created by
+// calling [LoadIgnore].
+type Ignore struct {
+	workTree string
+	global   []ignoreRule            // Rules from core.excludesFile.
+	info     []ignoreRule            // Rules from .git/info/exclude.
+	dirs     map[string][]ignoreRule // Rules per directory, loaded lazily as paths are queried.
+}
+
+// LoadIgnore prepares gitignore lookups for the work tree at workTree.
+//
+// commonDir is the repository's shared git directory, which holds info/exclude. excludesFile is the
+// value of core.excludesFile, where a leading ~/ stands for the home directory; when empty, git's default
+// of $XDG_CONFIG_HOME/git/ignore (or ~/.config/git/ignore) is used.
+func LoadIgnore(workTree, commonDir, excludesFile string) (*Ignore, error) {
+	if excludesFile == "" {
+		excludesFile = defaultExcludesFile()
+	} else if rest, ok := strings.CutPrefix(excludesFile, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		excludesFile = filepath.Join(home, rest)
+	}
+
+	var global []ignoreRule
+	if excludesFile != "" {
+		var err error
+		global, err = readIgnoreFile(excludesFile, "")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	info, err := readIgnoreFile(filepath.Join(commonDir, "info", "exclude"), "")
+	if err != nil {
+		return nil, err
+	}
+	return &Ignore{workTree: workTree, global: global, info: info, dirs: map[string][]ignoreRule{}}, nil
+}
+
+func defaultExcludesFile() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "git", "ignore")
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, ".config", "git", "ignore")
+	}
+	return ""
+}
+
+// IsIgnored reports whether the slash separated path p, relative to the work tree, is ignored.
+// isDir tells whether p is a directory, which directory-only patterns ("build/") depend on.
+//
+// As in git, a path inside an ignored directory is ignored even if a rule tries to re-include it.
+func (ig *Ignore) IsIgnored(p string, isDir bool) bool {
+	for i, c := range p {
+		if c == '/' && ig.matches(p[:i], true) {
+			return true
+		}
+	}
+	return ig.matches(p, isDir)
+}
+
+// matches reports whether the rules ignore p itself, without looking at its parent directories.
+func (ig *Ignore) matches(p string, isDir bool) bool {
+	ignored := false
+	apply := func(rules []ignoreRule) {
+		for _, rule := range rules {
+			if rule.matches(p, isDir) {
+				ignored = !rule.negate
+			}
+		}
+	}
+
+	apply(ig.global)
+	apply(ig.info)
+	for _, dir := range parentDirs(p) {
+		apply(ig.rulesFor(dir))
+	}
+	return ignored
+}
+
+func (ig *Ignore) rulesFor(dir string) []ignoreRule {
+	if rules, ok := ig.dirs[dir]; ok {
+		return rules
+	}
+
+	// Read errors are treated as an empty file, like unreadable .gitattributes files.
+	rules, _ := readIgnoreFile(filepath.Join(ig.workTree, filepath.FromSlash(dir), ".gitignore"), dir)
+	ig.dirs[dir] = rules
+	return rules
+}
+
+func (r ignoreRule) matches(p string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+
+	rel := p
+	if r.base != "" {
+		if !strings.HasPrefix(p, r.base+"/") {
+			return false
+		}
+		rel = p[len(r.base)+1:]
+	}
+
+	if !strings.Contains(r.pattern, "/") {
+		return matchPattern(r.pattern, path.Base(rel))
+	}
+	return matchPattern(r.pattern, rel)
+}
+
+func readIgnoreFile(name, base string) ([]ignoreRule, error) {
+	f, err := os.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if rule, ok := parseIgnoreLine(scanner.Text(), base); ok {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, scanner.Err()
+}
+
+func parseIgnoreLine(line, base string) (ignoreRule, bool) {
+	// Trailing spaces are dropped unless the last one is escaped with a backslash.
+	trimmed := strings.TrimRight(line, " ")
+	if strings.HasSuffix(trimmed, "\\") && len(trimmed) < len(line) {
+		trimmed += " "
+	}
+	line = trimmed
+
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+
+	rule := ignoreRule{base: base}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+
+	anchored := strings.HasPrefix(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	if line == "" {
+		return ignoreRule{}, false
+	}
+
+	// Git accepts [!...] for negated character classes, which path.Match spells [^...].
+	rule.pattern = strings.ReplaceAll(line, "[!", "[^")
+	if anchored && !strings.Contains(rule.pattern, "/") {
+		// A leading slash anchors the pattern, which the matcher expresses as containing a slash.
+		rule.pattern = "./" + rule.pattern
+	}
+	return rule, true
+}
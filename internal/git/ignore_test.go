@@ -0,0 +1,94 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestIgnore(t *testing.T) {
+	root := t.TempDir()
+	gitDir := filepath.Join(root, ".git")
+	os.MkdirAll(filepath.Join(gitDir, "info"), 0o755)
+	os.MkdirAll(filepath.Join(root, "web"), 0o755)
+
+	global := filepath.Join(root, "global-ignore")
+	os.WriteFile(global, []byte(".DS_Store\n*.swp\n"), 0o644)
+	os.WriteFile(filepath.Join(gitDir, "info", "exclude"), []byte("/scratch\n"), 0o644)
+	os.WriteFile(filepath.Join(root, ".gitignore"), []byte("# build outputs\n*.log\n!keep.log\nbuild/\n/todo.txt\nlogs/**/*.gz\n\\#notes\n"), 0o644)
+	os.WriteFile(filepath.Join(root, "web", ".gitignore"), []byte("node_modules\n!*.swp\ndist/\n!dist/keep.js\n"), 0o644)
+
+	ig, err := LoadIgnore(root, gitDir, global)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{"debug.log", false, true},
+		{"web/debug.log", false, true},
+		{"keep.log", false, false},
+		{"build", true, true},
+		{"build", false, false},
+		{"src/build/out.o", false, true},
+		{"todo.txt", false, true},
+		{"web/todo.txt", false, false},
+		{"logs/2024/01/app.gz", false, true},
+		{"#notes", false, true},
+		{".DS_Store", false, true},
+		{"a.swp", false, true},
+		{"web/a.swp", false, false},
+		{"scratch", true, true},
+		{"web/node_modules/lib/index.js", false, true},
+		// A file can't be re-included once its directory is ignored.
+		{"web/dist/keep.js", false, true},
+		{"main.go", false, false},
+	}
+	for _, c := range cases {
+		if actual := ig.IsIgnored(c.path, c.isDir); actual != c.expected {
+			t.Errorf("IsIgnored(%q, %t) = %t, expected %t", c.path, c.isDir, actual, c.expected)
+		}
+	}
+}
+
+func TestStatusSkipsIgnored(t *testing.T) {
+	newTestRepo(t)
+	repo, _ := OpenRepository()
+	writeTestFiles(t, map[string]string{
+		".gitignore":        "*.log\nvendor/\n",
+		"app.log":           "log\n",
+		"vendor/lib/lib.go": "package lib\n",
+		"main.go":           "package main\n",
+		"tracked.log":       "log\n",
+	})
+
+	idx, _ := repo.Index()
+	if err := repo.StagePath(idx, "tracked.log"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.WriteIndex(idx); err != nil {
+		t.Fatal(err)
+	}
+
+	ig, err := LoadIgnore(repo.WorkTree, repo.CommonDir, filepath.Join(repo.WorkTree, "no-such-file"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := repo.Status(ig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var paths []string
+	for _, entry := range entries {
+		paths = append(paths, entry.Path)
+	}
+	expected := []string{".gitignore", "main.go", "tracked.log"}
+	if !slices.Equal(paths, expected) {
+		t.Fatalf("expected %v, got %v", expected, paths)
+	}
+}
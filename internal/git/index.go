@@ -0,0 +1,272 @@
+package git
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var (
+	ErrBadIndex   = errors.New("malformed index")
+	ErrSplitIndex = errors.New("split index not supported")
+)
+
+var indexSignature = []byte("DIRC")
+
+// Index entry flag bits, from the 16 bit flags field and the extended flags that follow it in v3+.
+const (
+	indexFlagAssumeValid  = 0x8000
+	indexFlagExtended     = 0x4000
+	indexFlagStageMask    = 0x3000
+	indexFlagStageShift   = 12
+	indexFlagNameMask     = 0x0fff
+	indexFlagSkipWorktree = 0x4000
+	indexFlagIntentToAdd  = 0x2000
+)
+
+// Index is the staging area (.git/index): the set of files that the next commit will be made of.
+//
+// Versions 2 through 4 of the format are supported. Extensions are kept as raw bytes so the index
+// can be written back without losing them. A sparse index stores each directory outside the sparse
+// checkout as a single entry, whose mode is [ModeTree] and whose path ends in a slash. Split indexes,
+// whose entries live partly in a shared index file, are not supported.
+type Index struct {
+	Version    uint32           // The on disk format version, 2, 3, or 4
+	Entries    []IndexEntry     // Staged files, sorted by path and then stage
+	Extensions []IndexExtension // Optional data such as the cached tree (TREE) and resolve undo (REUC)
+}
+
+// IndexEntry is a single staged file.
+//
+// Besides the staged content, each entry caches the file's stat data from when it was staged,
+// which lets status skip rehashing files that haven't been touched since.
+type IndexEntry struct {
+	Path  string   // The slash separated path relative to the work tree root, ending in a slash for a sparse directory
+	Mode  FileMode // The file's mode
+	Hash  string   // The staged blob, commit for submodules, or tree for sparse directories
+	Stage int      // 0 normally, or 1 (base), 2 (ours), or 3 (theirs) while a conflict is unresolved
+
+	CTime time.Time // When the file's metadata last changed
+	MTime time.Time // When the file's content last changed
+	Dev   uint32
+	Ino   uint32
+	UID   uint32
+	GID   uint32
+	Size  uint32 // The file's size, truncated to 32 bits
+
+	AssumeValid  bool // Set by update-index --assume-unchanged
+	SkipWorktree bool // Set for paths outside a sparse checkout
+	IntentToAdd  bool // Set by git add -N
+}
+
+// IndexExtension is an optional section of the index, identified by a four letter signature.
+type IndexExtension struct {
+	Signature string
+	Data      []byte
+}
+
+// Index reads the repository's index. A repository without an index yet has an empty one.
+func (repo *Repository) Index() (*Index, error) {
+	defer phase("index")()
+	data, err := os.ReadFile(filepath.Join(repo.GitDir, "index"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Index{Version: 2}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return DecodeIndex(data, repo.Format)
+}
+
+// DecodeIndex parses the contents of an index file whose hashes are in format, verifying its trailing
+// checksum. An all zero checksum, which git writes when index.skipHash is set, is not verified.
+//
+// A split index fails with [ErrSplitIndex], since only its changes to the shared index are stored in it.
+func DecodeIndex(data []byte, format HashFormat) (*Index, error) {
+	const headerSize = 12
+	hashSize := format.HexSize() / 2
+	if len(data) < headerSize+hashSize || !bytes.Equal(data[:4], indexSignature) {
+		return nil, fmt.Errorf("%w: missing DIRC signature", ErrBadIndex)
+	}
+
+	body, checksum := data[:len(data)-hashSize], data[len(data)-hashSize:]
+	if !bytes.Equal(checksum, make([]byte, hashSize)) {
+		h := format.new()
+		h.Write(body)
+		if !bytes.Equal(h.Sum(nil), checksum) {
+			return nil, fmt.Errorf("%w: checksum mismatch", ErrBadIndex)
+		}
+	}
+
+	idx := &Index{Version: binary.BigEndian.Uint32(data[4:8])}
+	if idx.Version < 2 || idx.Version > 4 {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrBadIndex, idx.Version)
+	}
+
+	count := binary.BigEndian.Uint32(data[8:12])
+	r := &indexReader{data: body, pos: headerSize, hashSize: hashSize}
+	idx.Entries = make([]IndexEntry, 0, count)
+
+	var previous string
+	for range count {
+		entry, err := r.entry(idx.Version, previous)
+		if err != nil {
+			return nil, err
+		}
+		idx.Entries = append(idx.Entries, entry)
+		previous = entry.Path
+	}
+
+	for r.pos < len(body) {
+		if len(body)-r.pos < 8 {
+			return nil, fmt.Errorf("%w: truncated extension header", ErrBadIndex)
+		}
+		signature := string(body[r.pos : r.pos+4])
+		size := int(binary.BigEndian.Uint32(body[r.pos+4 : r.pos+8]))
+		r.pos += 8
+		if size > len(body)-r.pos {
+			return nil, fmt.Errorf("%w: truncated %s extension", ErrBadIndex, signature)
+		}
+		if signature == "link" {
+			return nil, fmt.Errorf("git: %w, turn it off with git update-index --no-split-index", ErrSplitIndex)
+		}
+		idx.Extensions = append(idx.Extensions, IndexExtension{Signature: signature, Data: bytes.Clone(body[r.pos : r.pos+size])})
+		r.pos += size
+	}
+
+	return idx, nil
+}
+
+// Entry returns the stage 0 entry for path, or false if the path isn't staged or is conflicted.
+func (idx *Index) Entry(path string) (IndexEntry, bool) {
+	for _, entry := range idx.Entries {
+		if entry.Path == path && entry.Stage == 0 {
+			return entry, true
+		}
+	}
+	return IndexEntry{}, false
+}
+
+type indexReader struct {
+	data     []byte
+	pos      int
+	hashSize int
+}
+
+func (r *indexReader) entry(version uint32, previous string) (IndexEntry, error) {
+	// Ten 32 bit stat and mode words, the hash, then 16 bits of flags.
+	fixedSize := 40 + r.hashSize + 2
+	start := r.pos
+	if len(r.data)-r.pos < fixedSize {
+		return IndexEntry{}, fmt.Errorf("%w: truncated entry", ErrBadIndex)
+	}
+
+	word := func(i int) uint32 { return binary.BigEndian.Uint32(r.data[start+i*4:]) }
+	entry := IndexEntry{
+		CTime: time.Unix(int64(word(0)), int64(word(1))),
+		MTime: time.Unix(int64(word(2)), int64(word(3))),
+		Dev:   word(4),
+		Ino:   word(5),
+		Mode:  FileMode(word(6)),
+		UID:   word(7),
+		GID:   word(8),
+		Size:  word(9),
+		Hash:  hex.EncodeToString(r.data[start+40 : start+40+r.hashSize]),
+	}
+
+	flags := binary.BigEndian.Uint16(r.data[start+40+r.hashSize:])
+	entry.AssumeValid = flags&indexFlagAssumeValid != 0
+	entry.Stage = int(flags&indexFlagStageMask) >> indexFlagStageShift
+	r.pos += fixedSize
+
+	if flags&indexFlagExtended != 0 {
+		if version < 3 || len(r.data)-r.pos < 2 {
+			return IndexEntry{}, fmt.Errorf("%w: unexpected extended flags", ErrBadIndex)
+		}
+		extended := binary.BigEndian.Uint16(r.data[r.pos:])
+		entry.SkipWorktree = extended&indexFlagSkipWorktree != 0
+		entry.IntentToAdd = extended&indexFlagIntentToAdd != 0
+		r.pos += 2
+	}
+
+	if version == 4 {
+		// Paths are prefix compressed: strip N bytes from the previous path, then append the stored suffix.
+		strip, err := r.varint()
+		if err != nil {
+			return IndexEntry{}, err
+		}
+		if strip > len(previous) {
+			return IndexEntry{}, fmt.Errorf("%w: bad path compression", ErrBadIndex)
+		}
+		suffix, err := r.cstring()
+		if err != nil {
+			return IndexEntry{}, err
+		}
+		entry.Path = previous[:len(previous)-strip] + suffix
+		return entry, checkSparseDir(entry)
+	}
+
+	path, err := r.cstring()
+	if err != nil {
+		return IndexEntry{}, err
+	}
+	entry.Path = path
+
+	// Entries are NUL padded to a multiple of eight bytes, and cstring already consumed one NUL.
+	entryLen := r.pos - start
+	r.pos += (8 - entryLen%8) % 8
+	if r.pos > len(r.data) {
+		return IndexEntry{}, fmt.Errorf("%w: truncated entry padding", ErrBadIndex)
+	}
+
+	if nameLen := int(flags & indexFlagNameMask); nameLen != indexFlagNameMask && nameLen != len(path) {
+		return IndexEntry{}, fmt.Errorf("%w: path length mismatch for %s", ErrBadIndex, path)
+	}
+	return entry, checkSparseDir(entry)
+}
+
+// checkSparseDir rejects directory entries that aren't a sparse index's skipped directories, and
+// directory-like paths that aren't directory entries.
+func checkSparseDir(entry IndexEntry) error {
+	dir := entry.Mode == ModeTree
+	if dir != strings.HasSuffix(entry.Path, "/") || dir && !entry.SkipWorktree {
+		return fmt.Errorf("%w: bad sparse directory %s", ErrBadIndex, entry.Path)
+	}
+	return nil
+}
+
+func (r *indexReader) cstring() (string, error) {
+	end := bytes.IndexByte(r.data[r.pos:], 0)
+	if end == -1 {
+		return "", fmt.Errorf("%w: unterminated path", ErrBadIndex)
+	}
+	s := string(r.data[r.pos : r.pos+end])
+	r.pos += end + 1
+	return s, nil
+}
+
+// varint reads git's offset varint, where each continuation also adds one to avoid redundant encodings.
+func (r *indexReader) varint() (int, error) {
+	if r.pos >= len(r.data) {
+		return 0, fmt.Errorf("%w: truncated varint", ErrBadIndex)
+	}
+	c := r.data[r.pos]
+	r.pos++
+	value := int(c & 0x7f)
+	for c&0x80 != 0 {
+		if r.pos >= len(r.data) {
+			return 0, fmt.Errorf("%w: truncated varint", ErrBadIndex)
+		}
+		c = r.data[r.pos]
+		r.pos++
+		value = ((value + 1) << 7) | int(c&0x7f)
+	}
+	return value, nil
+}
@@ -0,0 +1,278 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// The fixtures in testdata were written by git for a work tree holding a.txt, dir/b.txt, and an
+// executable dir/c.sh. index-v3 and index-v4 also have new.txt added with git add -N, and
+// index-conflict has a.txt conflicted by a merge. index-sha256 is from a SHA-256 repository,
+// index-sparse is a sparse index with dir/ outside the sparse checkout, and index-split was split
+// with git update-index --split-index.
+func readTestIndex(t *testing.T, name string) *Index {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx, err := DecodeIndex(data, SHA1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return idx
+}
+
+func TestDecodeIndexVersions(t *testing.T) {
+	for _, name := range []string{"index-v2", "index-v3", "index-v4"} {
+		idx := readTestIndex(t, name)
+
+		paths := []string{"a.txt", "dir/b.txt", "dir/c.sh", "new.txt"}
+		if name == "index-v2" {
+			paths = paths[:3]
+		}
+		if len(idx.Entries) != len(paths) {
+			t.Fatalf("%s: expected %d entries, got %d", name, len(paths), len(idx.Entries))
+		}
+		for i, path := range paths {
+			if idx.Entries[i].Path != path {
+				t.Errorf("%s: entry %d is %q, expected %q", name, i, idx.Entries[i].Path, path)
+			}
+		}
+
+		b, ok := idx.Entry("dir/b.txt")
+		if !ok || b.Hash != "61780798228d17af2d34fce4cfbdf35556832472" || b.Mode != ModeFile || b.Size != 2 {
+			t.Errorf("%s: unexpected dir/b.txt entry %+v", name, b)
+		}
+		if c, _ := idx.Entry("dir/c.sh"); c.Mode != ModeExecutable {
+			t.Errorf("%s: expected dir/c.sh to be executable, got %s", name, c.Mode)
+		}
+		if b.MTime.IsZero() {
+			t.Errorf("%s: expected stat data to be read", name)
+		}
+
+		if name != "index-v2" {
+			if added, _ := idx.Entry("new.txt"); !added.IntentToAdd {
+				t.Errorf("%s: expected new.txt to be intent-to-add", name)
+			}
+		}
+	}
+}
+
+func TestDecodeIndexConflict(t *testing.T) {
+	idx := readTestIndex(t, "index-conflict")
+
+	var stages []int
+	for _, entry := range idx.Entries {
+		if entry.Path == "a.txt" {
+			stages = append(stages, entry.Stage)
+		}
+	}
+	if len(stages) != 3 || stages[0] != 1 || stages[1] != 2 || stages[2] != 3 {
+		t.Fatalf("expected a.txt at stages 1, 2, 3, got %v", stages)
+	}
+	if _, ok := idx.Entry("a.txt"); ok {
+		t.Fatal("a conflicted path should have no stage 0 entry")
+	}
+}
+
+func TestDecodeIndexCorrupt(t *testing.T) {
+	data, _ := os.ReadFile(filepath.Join("testdata", "index-v2"))
+	data[20] ^= 0xff
+	if _, err := DecodeIndex(data, SHA1); !errors.Is(err, ErrBadIndex) {
+		t.Fatalf("expected ErrBadIndex for a corrupted index, got %v", err)
+	}
+}
+
+func TestDecodeIndexSHA256(t *testing.T) {
+	data, _ := os.ReadFile(filepath.Join("testdata", "index-sha256"))
+	if _, err := DecodeIndex(data, SHA1); !errors.Is(err, ErrBadIndex) {
+		t.Fatalf("expected ErrBadIndex reading a SHA-256 index as SHA-1, got %v", err)
+	}
+
+	idx, err := DecodeIndex(data, SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(idx.Entries) != 3 || idx.Entries[2].Path != "dir/c.sh" {
+		t.Fatalf("unexpected entries %+v", idx.Entries)
+	}
+	if b, _ := idx.Entry("dir/b.txt"); b.Hash != "9b69d308c97f2c5933fdd0e8ce04acce91c09cb969e36a1f86756fc5a5d3323a" {
+		t.Errorf("unexpected dir/b.txt hash %s", b.Hash)
+	}
+}
+
+func TestDecodeIndexSkipHash(t *testing.T) {
+	data, _ := os.ReadFile(filepath.Join("testdata", "index-v2"))
+	clear(data[len(data)-20:])
+	idx, err := DecodeIndex(data, SHA1)
+	if err != nil {
+		t.Fatalf("expected a null checksum to be accepted, got %v", err)
+	}
+	if len(idx.Entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(idx.Entries))
+	}
+}
+
+func TestDecodeIndexSplit(t *testing.T) {
+	data, _ := os.ReadFile(filepath.Join("testdata", "index-split"))
+	if _, err := DecodeIndex(data, SHA1); !errors.Is(err, ErrSplitIndex) {
+		t.Fatalf("expected ErrSplitIndex, got %v", err)
+	}
+}
+
+func TestDecodeIndexSparse(t *testing.T) {
+	idx := readTestIndex(t, "index-sparse")
+	if len(idx.Entries) != 2 {
+		t.Fatalf("expected a.txt and dir/, got %+v", idx.Entries)
+	}
+	dir := idx.Entries[1]
+	if dir.Path != "dir/" || dir.Mode != ModeTree || !dir.SkipWorktree || dir.Hash != "954738915d5cd14139a5dc2a6007d25df8552f71" {
+		t.Fatalf("unexpected sparse directory entry %+v", dir)
+	}
+
+	newTestRepo(t)
+	repo, _ := OpenRepository()
+	tree, err := idx.WriteTree(repo.Encoder())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The same tree as the fully expanded index-v2, as git write-tree gives for both.
+	if tree != "6831414730fcf3331d79ced84b2dc0eacfcb1463" {
+		t.Errorf("unexpected tree %s", tree)
+	}
+}
+
+func TestEncodeIndexRoundTrip(t *testing.T) {
+	formats := map[string]HashFormat{
+		"index-v2": SHA1, "index-v3": SHA1, "index-v4": SHA1, "index-conflict": SHA1,
+		"index-sparse": SHA1, "index-sha256": SHA256,
+	}
+	for name, format := range formats {
+		data, err := os.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		idx, err := DecodeIndex(data, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		encoded, err := EncodeIndex(idx, format)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !bytes.Equal(encoded, data) {
+			t.Errorf("%s: re-encoding an unchanged index should reproduce it byte for byte", name)
+		}
+	}
+
+	idx := readTestIndex(t, "index-v2")
+	if _, err := EncodeIndex(idx, SHA256); !errors.Is(err, ErrBadIndex) {
+		t.Errorf("expected ErrBadIndex writing SHA-1 entries into a SHA-256 index, got %v", err)
+	}
+	idx.Extensions = append(idx.Extensions, IndexExtension{Signature: "link", Data: make([]byte, 20)})
+	if _, err := EncodeIndex(idx, SHA1); !errors.Is(err, ErrSplitIndex) {
+		t.Errorf("expected ErrSplitIndex writing an index with a link extension, got %v", err)
+	}
+}
+
+func TestIndexAddResolvesConflict(t *testing.T) {
+	idx := readTestIndex(t, "index-conflict")
+	ours := idx.Entries[1]
+
+	idx.Add(IndexEntry{Path: "a.txt", Mode: ModeFile, Hash: ours.Hash})
+	if entry, ok := idx.Entry("a.txt"); !ok || entry.Hash != ours.Hash {
+		t.Fatalf("expected a.txt to be staged at stage 0, got %+v", idx.Entries)
+	}
+	for _, entry := range idx.Entries {
+		if entry.Stage != 0 {
+			t.Fatalf("expected the conflict stages to be dropped, got %+v", entry)
+		}
+	}
+}
+
+func TestWriteIndex(t *testing.T) {
+	gitDir := newTestRepo(t)
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.MkdirAll("dir", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("dir/b.txt", []byte("b\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("a.txt", []byte("a\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	idx, err := repo.Index()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"dir/b.txt", "a.txt", "gone.txt"} {
+		if err := repo.StagePath(idx, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	idx.Extensions = append(idx.Extensions, IndexExtension{Signature: "TREE", Data: []byte("stale")})
+	idx.Remove("missing")
+	if err := repo.WriteIndex(idx); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := repo.Index()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Entries) != 2 || got.Entries[0].Path != "a.txt" || got.Entries[1].Path != "dir/b.txt" {
+		t.Fatalf("expected a.txt and dir/b.txt to be staged in order, got %+v", got.Entries)
+	}
+	if got.Entries[0].Mode != ModeExecutable {
+		t.Errorf("expected a.txt to be staged as executable, got %s", got.Entries[0].Mode)
+	}
+	if b := got.Entries[1]; b.Hash != "61780798228d17af2d34fce4cfbdf35556832472" || b.Size != 2 {
+		t.Errorf("unexpected dir/b.txt entry %+v", b)
+	}
+	if _, err := os.Stat(filepath.Join(gitDir, "objects", "61", "780798228d17af2d34fce4cfbdf35556832472")); err != nil {
+		t.Errorf("expected the blob to be stored: %v", err)
+	}
+
+	got.Extensions = []IndexExtension{{Signature: "TREE", Data: []byte("stale")}}
+	got.Add(IndexEntry{Path: "c.txt", Mode: ModeFile, Hash: got.Entries[1].Hash})
+	if len(got.Extensions) != 0 {
+		t.Errorf("expected the cached tree to be dropped once entries change, got %+v", got.Extensions)
+	}
+
+	if err := os.WriteFile(filepath.Join(gitDir, "index.lock"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.WriteIndex(got); !errors.Is(err, ErrIndexLocked) {
+		t.Fatalf("expected ErrIndexLocked, got %v", err)
+	}
+}
+
+func TestIndexWriteTree(t *testing.T) {
+	idx := readTestIndex(t, "index-v3")
+	conflicted := readTestIndex(t, "index-conflict")
+	newTestRepo(t)
+	repo, _ := OpenRepository()
+
+	tree, err := idx.WriteTree(repo.Encoder())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Matches git write-tree for the fixture, which leaves out the intent-to-add new.txt.
+	if tree != "6831414730fcf3331d79ced84b2dc0eacfcb1463" {
+		t.Errorf("unexpected tree %s", tree)
+	}
+
+	if _, err := conflicted.WriteTree(repo.Encoder()); !errors.Is(err, ErrUnmerged) {
+		t.Fatalf("expected ErrUnmerged, got %v", err)
+	}
+}
@@ -0,0 +1,272 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"slices"
+)
+
+// indexedObject is an object found in a pack by [indexPack].
+type indexedObject struct {
+	hash   string
+	kind   GitObjectKind
+	offset int64 // Where the object's entry starts in the pack
+	end    int64 // Where the next entry starts
+}
+
+// packedEntry is an entry in a pack being indexed, before its deltas are applied.
+type packedEntry struct {
+	typ      byte
+	size     int64  // The size of the object, or of the delta
+	data     int64  // Where the entry's compressed content starts
+	base     int64  // Where the entry an OFS delta builds on starts
+	baseHash string // The object a REF delta builds on
+}
+
+// countingReader counts the bytes read through it. Being an [io.ByteReader], it keeps zlib from
+// reading past the end of a compressed stream, so the count lands on the entry after it.
+type countingReader struct {
+	r *bufio.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingReader) ReadByte() (byte, error) {
+	b, err := c.r.ReadByte()
+	if err == nil {
+		c.n++
+	}
+	return b, err
+}
+
+// indexPack names every object in the pack of size bytes in f, like one received from another
+// repository or stored in a bundle, applying deltas as it goes, and returns them in the order the pack
+// holds them. The pack's checksum must match. The pack is read from f rather than held in memory, a
+// whole object at a time, and only the objects deltas were recently applied to are kept.
+//
+// A pack may be thin, holding deltas against objects it leaves out because the receiver has them
+// already; those are read with external, which fails with [fs.ErrNotExist] for objects it doesn't
+// have. Their hashes are returned as well, so they can be added to the pack to make it whole.
+func indexPack(f io.ReaderAt, size int64, format HashFormat, external func(hash string) (GitObjectKind, []byte, error)) ([]indexedObject, []string, error) {
+	hashSize := int64(format.HexSize() / 2)
+	if size < 12+hashSize {
+		return nil, nil, fmt.Errorf("%w: missing pack header", ErrBadPack)
+	}
+	bodySize := size - hashSize
+	header := make([]byte, 12)
+	if _, err := f.ReadAt(header, 0); err != nil {
+		return nil, nil, err
+	}
+	if string(header[:4]) != "PACK" {
+		return nil, nil, fmt.Errorf("%w: missing pack header", ErrBadPack)
+	}
+	if version := binary.BigEndian.Uint32(header[4:]); version != 2 && version != 3 {
+		return nil, nil, fmt.Errorf("%w: unsupported version %d", ErrBadPack, version)
+	}
+	count := int(binary.BigEndian.Uint32(header[8:]))
+	sum := format.new()
+	if _, err := io.Copy(sum, io.NewSectionReader(f, 0, bodySize)); err != nil {
+		return nil, nil, err
+	}
+	checksum := make([]byte, hashSize)
+	if _, err := f.ReadAt(checksum, bodySize); err != nil {
+		return nil, nil, err
+	}
+	if !bytes.Equal(sum.Sum(nil), checksum) {
+		return nil, nil, fmt.Errorf("%w: checksum mismatch", ErrBadPack)
+	}
+
+	// The first pass finds where every entry starts and names the whole objects; deltas wait for the
+	// second, since one may come before the object it builds on.
+	objects := make([]indexedObject, 0, count)
+	entries := make([]packedEntry, 0, count)
+	byOffset := map[int64]int{}
+	r := &countingReader{r: bufio.NewReader(io.NewSectionReader(f, 12, bodySize-12)), n: 12}
+	truncated := fmt.Errorf("%w: truncated", ErrBadPack)
+	for i := range count {
+		start := r.n
+		c, err := r.ReadByte()
+		if err != nil {
+			return nil, nil, truncated
+		}
+		typ := (c >> 4) & 7
+		e := packedEntry{typ: typ, size: int64(c & 0x0f), base: -1}
+		for shift := 4; c&0x80 != 0; shift += 7 {
+			if c, err = r.ReadByte(); err != nil || shift > 56 {
+				return nil, nil, truncated
+			}
+			e.size |= int64(c&0x7f) << shift
+		}
+
+		switch typ {
+		case packCommit, packTree, packBlob, packTag:
+		case packOfsDelta:
+			if c, err = r.ReadByte(); err != nil {
+				return nil, nil, truncated
+			}
+			distance := int64(c & 0x7f)
+			for c&0x80 != 0 {
+				if c, err = r.ReadByte(); err != nil || distance > start {
+					return nil, nil, truncated
+				}
+				distance = (distance+1)<<7 | int64(c&0x7f)
+			}
+			if _, ok := byOffset[start-distance]; !ok {
+				return nil, nil, fmt.Errorf("%w: no object at delta base offset %d", ErrBadPack, start-distance)
+			}
+			e.base = start - distance
+		case packRefDelta:
+			raw := make([]byte, hashSize)
+			if _, err := io.ReadFull(r, raw); err != nil {
+				return nil, nil, truncated
+			}
+			e.baseHash = hex.EncodeToString(raw)
+		default:
+			return nil, nil, fmt.Errorf("%w: unknown object type %d", ErrBadPack, typ)
+		}
+
+		e.data = r.n
+		object := indexedObject{offset: start}
+		zr, err := zlib.NewReader(r)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: object at offset %d: %v", ErrBadPack, start, err)
+		}
+		if e.base < 0 && e.baseHash == "" {
+			object.kind = GitObjectKind(typ)
+			object.hash, err = format.HashObjectStream(object.kind, e.size, zr)
+		} else {
+			var n int64
+			if n, err = io.Copy(io.Discard, zr); err == nil && n != e.size {
+				err = fmt.Errorf("%w: expected %d bytes, got %d", ErrSizeMismatch, e.size, n)
+			}
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: object at offset %d: %v", ErrBadPack, start, err)
+		}
+		observe(func(o Observer) { o.Inflated(e.size) })
+		object.end = r.n
+		byOffset[start] = i
+		objects = append(objects, object)
+		entries = append(entries, e)
+	}
+	if r.n != bodySize {
+		return nil, nil, fmt.Errorf("%w: %d bytes after the last object", ErrBadPack, bodySize-r.n)
+	}
+
+	index := map[string]int{}
+	for i, object := range objects {
+		if object.hash != "" {
+			index[object.hash] = i
+		}
+	}
+	cache := newDeltaBaseCache(deltaBaseCacheLimit)
+	var thin []string
+
+	// content rebuilds entry i, reporting false if a REF delta's base hasn't been found yet, which may
+	// be another delta later in the pack.
+	var content func(i, depth int) (GitObjectKind, []byte, bool, error)
+	content = func(i, depth int) (GitObjectKind, []byte, bool, error) {
+		e := entries[i]
+		if kind, data, ok := cache.get(baseKey{offset: objects[i].offset}); ok {
+			return kind, data, true, nil
+		}
+		if depth > maxDeltaDepth {
+			return 0, nil, false, fmt.Errorf("%w: delta chain too long", ErrBadPack)
+		}
+
+		var baseKind GitObjectKind
+		var base []byte
+		switch {
+		case e.base >= 0:
+			var ok bool
+			var err error
+			if baseKind, base, ok, err = content(byOffset[e.base], depth+1); !ok || err != nil {
+				return 0, nil, ok, err
+			}
+		case e.baseHash != "":
+			if j, ok := index[e.baseHash]; ok {
+				var err error
+				if baseKind, base, ok, err = content(j, depth+1); !ok || err != nil {
+					return 0, nil, ok, err
+				}
+				break
+			}
+			if external == nil {
+				return 0, nil, false, nil
+			}
+			var err error
+			baseKind, base, err = external(e.baseHash)
+			if errors.Is(err, fs.ErrNotExist) {
+				return 0, nil, false, nil
+			}
+			if err != nil {
+				return 0, nil, false, err
+			}
+			if !slices.Contains(thin, e.baseHash) {
+				thin = append(thin, e.baseHash)
+			}
+		}
+
+		data, err := inflate(bufio.NewReader(io.NewSectionReader(f, e.data, objects[i].end-e.data)), e.size)
+		if err != nil {
+			return 0, nil, false, fmt.Errorf("%w: object at offset %d: %v", ErrBadPack, objects[i].offset, err)
+		}
+		kind := GitObjectKind(e.typ)
+		if e.base >= 0 || e.baseHash != "" {
+			if data, err = applyDelta(base, data); err != nil {
+				return 0, nil, false, err
+			}
+			kind = baseKind
+		}
+		cache.add(baseKey{offset: objects[i].offset}, kind, data)
+		return kind, data, true, nil
+	}
+
+	for pending := count; pending > 0; {
+		left := 0
+		var missing string
+		for i := range objects {
+			if objects[i].hash != "" {
+				continue
+			}
+			kind, data, ok, err := content(i, 0)
+			if err != nil {
+				return nil, nil, err
+			}
+			if !ok {
+				left++
+				if entries[i].baseHash != "" {
+					missing = entries[i].baseHash
+				}
+				continue
+			}
+			objects[i].kind, objects[i].hash = kind, format.HashObject(kind, data)
+			index[objects[i].hash] = i
+		}
+		if left == pending {
+			return nil, nil, fmt.Errorf("%w: delta base %s is missing", ErrBadPack, missing)
+		}
+		pending = left
+	}
+	return objects, thin, nil
+}
+
+// crc returns the CRC-32 of the object's entry in the pack f, as a pack index records it.
+func (o indexedObject) crc(f io.ReaderAt) (uint32, error) {
+	h := crc32.NewIEEE()
+	_, err := io.Copy(h, io.NewSectionReader(f, o.offset, o.end-o.offset))
+	return h.Sum32(), err
+}
@@ -0,0 +1,320 @@
+package git
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	ErrIndexLocked = errors.New("index is locked by another process")
+	ErrUnmerged    = errors.New("index has unresolved conflicts")
+)
+
+// Extensions that describe the entries themselves, and so go stale once an entry changes. Git
+// rebuilds all of them on demand, so dropping them is always safe.
+var entryDependentExtensions = []string{
+	"TREE", // Cached tree hashes for each directory
+	"UNTR", // Untracked file cache
+	"FSMN", // Filesystem monitor state
+	"EOIE", // Offset of the end of the entries
+	"IEOT", // Offsets of entry blocks, for threaded loading
+}
+
+// Add stages entry, replacing an entry at the same path and stage. Adding a stage 0 entry also
+// drops the path's conflict stages, the way git add marks a conflict resolved.
+func (idx *Index) Add(entry IndexEntry) {
+	idx.invalidate()
+	idx.Entries = slices.DeleteFunc(idx.Entries, func(e IndexEntry) bool {
+		return e.Path == entry.Path && (e.Stage == entry.Stage || entry.Stage == 0)
+	})
+
+	i, _ := slices.BinarySearchFunc(idx.Entries, entry, compareIndexEntries)
+	idx.Entries = slices.Insert(idx.Entries, i, entry)
+}
+
+// Remove unstages path at every stage, and returns false if it wasn't staged.
+func (idx *Index) Remove(path string) bool {
+	n := len(idx.Entries)
+	idx.Entries = slices.DeleteFunc(idx.Entries, func(e IndexEntry) bool { return e.Path == path })
+	if len(idx.Entries) == n {
+		return false
+	}
+	idx.invalidate()
+	return true
+}
+
+func (idx *Index) invalidate() {
+	idx.Extensions = slices.DeleteFunc(idx.Extensions, func(ext IndexExtension) bool {
+		return slices.Contains(entryDependentExtensions, ext.Signature)
+	})
+}
+
+func compareIndexEntries(a, b IndexEntry) int {
+	if c := strings.Compare(a.Path, b.Path); c != 0 {
+		return c
+	}
+	return a.Stage - b.Stage
+}
+
+// WriteTree stores the trees for the staged files with e and returns the root tree's hash, like
+// git write-tree. Intent-to-add entries are left out, and an index with conflicts fails with [ErrUnmerged].
+func (idx *Index) WriteTree(e *Encoder) (string, error) {
+	b := NewTreeBuilder(e)
+	for _, entry := range idx.Entries {
+		if entry.Stage != 0 {
+			return "", fmt.Errorf("%w: %s", ErrUnmerged, entry.Path)
+		}
+		if entry.IntentToAdd {
+			continue
+		}
+		if err := b.Add(entry.Path, entry.Mode, entry.Hash); err != nil {
+			return "", err
+		}
+	}
+	return b.Write()
+}
+
+// StagePath hashes the work tree file at path (slash separated, relative to the work tree root) into
+// the object store and stages it in idx. If the file no longer exists, path is removed from idx instead.
+//
+// Only the stat fields Go exposes portably are recorded, so git may rehash the file once to refresh them.
+func (repo *Repository) StagePath(idx *Index, path string) error {
+	full := filepath.Join(repo.WorkTree, filepath.FromSlash(path))
+	info, err := os.Lstat(full)
+	if errors.Is(err, fs.ErrNotExist) {
+		idx.Remove(path)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	entry := IndexEntry{
+		Path:  path,
+		Mode:  ModeFile,
+		CTime: info.ModTime(),
+		MTime: info.ModTime(),
+		Size:  uint32(info.Size()),
+	}
+
+	switch {
+	case info.Mode()&fs.ModeSymlink != 0:
+		target, err := os.Readlink(full)
+		if err != nil {
+			return err
+		}
+		entry.Mode = ModeSymlink
+		entry.Hash, err = repo.Encoder().Encode(BlobObject, []byte(filepath.ToSlash(target)))
+		if err != nil {
+			return err
+		}
+	case info.Mode().IsRegular():
+		if info.Mode()&0o111 != 0 {
+			entry.Mode = ModeExecutable
+		}
+		f, err := os.Open(full)
+		if err != nil {
+			return err
+		}
+		entry.Hash, err = repo.Encoder().EncodeStream(BlobObject, info.Size(), f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("git: failed to stage %s: %w", path, err)
+		}
+	default:
+		return fmt.Errorf("git: cannot stage %s, it is not a file or symlink", path)
+	}
+
+	idx.Add(entry)
+	return nil
+}
+
+// WriteIndex replaces the repository's index with idx.
+//
+// The new index is written to index.lock and renamed into place, so concurrent readers see either the
+// old or the new index. If another process holds the lock, [ErrIndexLocked] is returned.
+func (repo *Repository) WriteIndex(idx *Index) error {
+	data, err := EncodeIndex(idx, repo.Format)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(repo.GitDir, "index")
+	lock, err := os.OpenFile(path+".lock", os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if errors.Is(err, fs.ErrExist) {
+		return fmt.Errorf("%w: index.lock exists", ErrIndexLocked)
+	}
+	if err != nil {
+		return err
+	}
+
+	committed := false
+	defer func() {
+		if !committed {
+			lock.Close()
+			os.Remove(lock.Name())
+		}
+	}()
+
+	if _, err := lock.Write(data); err != nil {
+		return err
+	}
+	if err := lock.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(lock.Name(), path); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
+
+// EncodeIndex serializes idx in its version's on disk format with hashes in format, followed by the
+// checksum.
+//
+// Entries needing extended flags are only representable from version 3, so a version 2 index holding
+// one is written as version 3, as git does. An index with a split index's link extension fails with
+// [ErrSplitIndex], since writing it without the shared index's entries would lose them.
+func EncodeIndex(idx *Index, format HashFormat) ([]byte, error) {
+	version := idx.Version
+	if version < 2 || version > 4 {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrBadIndex, version)
+	}
+	if slices.ContainsFunc(idx.Extensions, func(ext IndexExtension) bool { return ext.Signature == "link" }) {
+		return nil, fmt.Errorf("git: %w, turn it off with git update-index --no-split-index", ErrSplitIndex)
+	}
+	if version == 2 && slices.ContainsFunc(idx.Entries, needsExtendedFlags) {
+		version = 3
+	}
+
+	var buf bytes.Buffer
+	buf.Write(indexSignature)
+	buf.Write(binary.BigEndian.AppendUint32(nil, version))
+	buf.Write(binary.BigEndian.AppendUint32(nil, uint32(len(idx.Entries))))
+
+	var previous string
+	for i, entry := range idx.Entries {
+		if i > 0 && compareIndexEntries(idx.Entries[i-1], entry) >= 0 {
+			return nil, fmt.Errorf("%w: entries out of order at %s", ErrBadIndex, entry.Path)
+		}
+		if err := writeIndexEntry(&buf, version, format, entry, previous); err != nil {
+			return nil, err
+		}
+		previous = entry.Path
+	}
+
+	for _, ext := range idx.Extensions {
+		if len(ext.Signature) != 4 {
+			return nil, fmt.Errorf("%w: bad extension signature %q", ErrBadIndex, ext.Signature)
+		}
+		buf.WriteString(ext.Signature)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(len(ext.Data))))
+		buf.Write(ext.Data)
+	}
+
+	h := format.new()
+	h.Write(buf.Bytes())
+	buf.Write(h.Sum(nil))
+	return buf.Bytes(), nil
+}
+
+func needsExtendedFlags(entry IndexEntry) bool {
+	return entry.SkipWorktree || entry.IntentToAdd
+}
+
+func writeIndexEntry(buf *bytes.Buffer, version uint32, format HashFormat, entry IndexEntry, previous string) error {
+	hash, err := hex.DecodeString(entry.Hash)
+	if err != nil || len(entry.Hash) != format.HexSize() {
+		return fmt.Errorf("%w: bad hash %q for %s", ErrBadIndex, entry.Hash, entry.Path)
+	}
+	if entry.Path == "" || strings.IndexByte(entry.Path, 0) != -1 {
+		return fmt.Errorf("%w: bad path %q", ErrBadIndex, entry.Path)
+	}
+	if entry.Stage < 0 || entry.Stage > 3 {
+		return fmt.Errorf("%w: bad stage %d for %s", ErrBadIndex, entry.Stage, entry.Path)
+	}
+
+	start := buf.Len()
+	for _, word := range []uint32{
+		indexTime(entry.CTime), indexNanos(entry.CTime),
+		indexTime(entry.MTime), indexNanos(entry.MTime),
+		entry.Dev, entry.Ino, uint32(entry.Mode), entry.UID, entry.GID, entry.Size,
+	} {
+		buf.Write(binary.BigEndian.AppendUint32(nil, word))
+	}
+	buf.Write(hash)
+
+	flags := uint16(min(len(entry.Path), indexFlagNameMask))
+	flags |= uint16(entry.Stage) << indexFlagStageShift
+	if entry.AssumeValid {
+		flags |= indexFlagAssumeValid
+	}
+	if needsExtendedFlags(entry) {
+		flags |= indexFlagExtended
+	}
+	buf.Write(binary.BigEndian.AppendUint16(nil, flags))
+
+	if needsExtendedFlags(entry) {
+		var extended uint16
+		if entry.SkipWorktree {
+			extended |= indexFlagSkipWorktree
+		}
+		if entry.IntentToAdd {
+			extended |= indexFlagIntentToAdd
+		}
+		buf.Write(binary.BigEndian.AppendUint16(nil, extended))
+	}
+
+	if version == 4 {
+		common := 0
+		for common < len(previous) && common < len(entry.Path) && previous[common] == entry.Path[common] {
+			common++
+		}
+		buf.Write(appendOffsetVarint(nil, len(previous)-common))
+		buf.WriteString(entry.Path[common:])
+		buf.WriteByte(0)
+		return nil
+	}
+
+	buf.WriteString(entry.Path)
+	// At least one NUL terminates the path, then more pad the entry to a multiple of eight bytes.
+	entryLen := buf.Len() - start
+	buf.Write(make([]byte, 8-entryLen%8))
+	return nil
+}
+
+func indexTime(t time.Time) uint32 {
+	if t.IsZero() {
+		return 0
+	}
+	return uint32(t.Unix())
+}
+
+func indexNanos(t time.Time) uint32 {
+	if t.IsZero() {
+		return 0
+	}
+	return uint32(t.Nanosecond())
+}
+
+// appendOffsetVarint appends value in git's offset varint encoding, the inverse of [indexReader.varint].
+func appendOffsetVarint(dst []byte, value int) []byte {
+	var tmp [16]byte
+	pos := len(tmp) - 1
+	tmp[pos] = byte(value & 0x7f)
+	for value >>= 7; value > 0; value >>= 7 {
+		value--
+		pos--
+		tmp[pos] = 0x80 | byte(value&0x7f)
+	}
+	return append(dst, tmp[pos:]...)
+}
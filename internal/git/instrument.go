@@ -0,0 +1,166 @@
+package git
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Observer receives measurements from the git layer as it works, for programs embedding it to feed
+// their own metrics, and for plain --perf to report. Its methods are called synchronously by whatever
+// goroutine is doing the work, so they must be quick and safe for concurrent use.
+//
+// An Observer is installed for the whole process with [SetObserver]. [Metrics] is an Observer that adds
+// everything up.
+type Observer interface {
+	// ObjectRead is called for every object read from the object store, with its kind and size, and
+	// whether it came from a pack rather than a loose file.
+	ObjectRead(kind GitObjectKind, size int64, packed bool)
+
+	// Inflated is called with how many bytes were decompressed: the content of a loose object, or each
+	// object and delta a packed object was rebuilt from.
+	Inflated(n int64)
+
+	// CacheLookup is called whenever a cache is consulted, with whether it had the answer. The cache is
+	// named, like "stat" for the file sizes and times in the index that spare status hashing files.
+	CacheLookup(cache string, hit bool)
+
+	// PhaseDone is called when a phase of work, like "index" or "history", ends, with how long it took.
+	PhaseDone(phase string, elapsed time.Duration)
+}
+
+var observer atomic.Pointer[Observer]
+
+// SetObserver installs o to receive measurements from every repository, replacing any installed
+// before. Pass nil to stop measuring, which is the default and costs next to nothing.
+func SetObserver(o Observer) {
+	if o == nil {
+		observer.Store(nil)
+		return
+	}
+	observer.Store(&o)
+}
+
+// observe calls fn with the installed Observer, if there is one.
+func observe(fn func(o Observer)) {
+	if o := observer.Load(); o != nil {
+		fn(*o)
+	}
+}
+
+// phase starts timing the named phase, returning the function that ends it, for use as
+// defer phase("index")().
+func phase(name string) func() {
+	o := observer.Load()
+	if o == nil {
+		return func() {}
+	}
+	start := time.Now()
+	return func() {
+		(*o).PhaseDone(name, time.Since(start))
+	}
+}
+
+var logger atomic.Pointer[slog.Logger]
+
+// SetLogger has the git layer log what it does to l: every git command a [ShellClient] runs, with how
+// long it took and how it failed, at [slog.LevelInfo], and every object file read, at [slog.LevelDebug].
+// Pass nil to stop logging, which is the default.
+func SetLogger(l *slog.Logger) {
+	logger.Store(l)
+}
+
+// logging reports whether the installed logger, if there is one, logs records at level. Calls on hot
+// paths check it first, to spare building the record's attributes.
+func logging(level slog.Level) bool {
+	l := logger.Load()
+	return l != nil && l.Enabled(context.Background(), level)
+}
+
+// logAt logs msg with args at level to the installed logger, if there is one.
+func logAt(level slog.Level, msg string, args ...any) {
+	if l := logger.Load(); l != nil {
+		l.Log(context.Background(), level, msg, args...)
+	}
+}
+
+// Stats is what a [Metrics] has added up.
+type Stats struct {
+	Objects  map[GitObjectKind]int // How many objects of each kind were read
+	Packed   int                   // How many of them came from packs
+	Bytes    int64                 // The total size of the objects read
+	Inflated int64                 // How many bytes were decompressed
+
+	Hits   map[string]int // Cache lookups that had the answer, by cache
+	Misses map[string]int // Cache lookups that didn't, by cache
+
+	Phases map[string]time.Duration // The time spent in each phase, added up over every time it ran
+	Runs   map[string]int           // How many times each phase ran
+}
+
+// Metrics is an [Observer] that adds up every measurement.
+//
+// A new Metrics is created by calling [NewMetrics].
+type Metrics struct {
+	mu    sync.Mutex
+	stats Stats
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{stats: Stats{
+		Objects: map[GitObjectKind]int{},
+		Hits:    map[string]int{},
+		Misses:  map[string]int{},
+		Phases:  map[string]time.Duration{},
+		Runs:    map[string]int{},
+	}}
+}
+
+func (m *Metrics) ObjectRead(kind GitObjectKind, size int64, packed bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stats.Objects[kind]++
+	m.stats.Bytes += size
+	if packed {
+		m.stats.Packed++
+	}
+}
+
+func (m *Metrics) Inflated(n int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stats.Inflated += n
+}
+
+func (m *Metrics) CacheLookup(cache string, hit bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if hit {
+		m.stats.Hits[cache]++
+	} else {
+		m.stats.Misses[cache]++
+	}
+}
+
+func (m *Metrics) PhaseDone(phase string, elapsed time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stats.Phases[phase] += elapsed
+	m.stats.Runs[phase]++
+}
+
+// Stats returns a copy of what has been added up so far.
+func (m *Metrics) Stats() Stats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats := m.stats
+	stats.Objects = maps.Clone(m.stats.Objects)
+	stats.Hits = maps.Clone(m.stats.Hits)
+	stats.Misses = maps.Clone(m.stats.Misses)
+	stats.Phases = maps.Clone(m.stats.Phases)
+	stats.Runs = maps.Clone(m.stats.Runs)
+	return stats
+}
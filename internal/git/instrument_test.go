@@ -0,0 +1,85 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	gitDir := newTestRepo(t)
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestObject(t, gitDir, TreeObject, "")
+	root := writeTestCommit(t, gitDir, "root")
+	if _, err := repo.Repack(); err != nil {
+		t.Fatal(err)
+	}
+	writeTestRef(t, gitDir, "refs/heads/main", writeTestCommit(t, gitDir, "tip", root))
+
+	metrics := NewMetrics()
+	SetObserver(metrics)
+	defer SetObserver(nil)
+	if _, err := GetHistoryFor("main"); err != nil {
+		t.Fatal(err)
+	}
+	SetObserver(nil)
+	if _, err := GetHistoryFor("main"); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := metrics.Stats()
+	// The tip is read twice, once to peel it and once to decode it.
+	if stats.Objects[CommitObject] != 3 || stats.Packed != 1 {
+		t.Errorf("Stats() objects = %v with %d packed, want the loose tip twice and its packed parent", stats.Objects, stats.Packed)
+	}
+	if stats.Inflated == 0 || stats.Bytes == 0 {
+		t.Errorf("Stats() = %d bytes read and %d inflated, want both counted", stats.Bytes, stats.Inflated)
+	}
+	if stats.Runs["history"] != 1 || stats.Runs["packs"] != 1 {
+		t.Errorf("Stats() phases = %v, want one history walk reading the packs once", stats.Runs)
+	}
+}
+
+func TestLogger(t *testing.T) {
+	gitDir := newTestRepo(t)
+	writeTestObject(t, gitDir, TreeObject, "")
+	root := writeTestCommit(t, gitDir, "root")
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Repack(); err != nil {
+		t.Fatal(err)
+	}
+	writeTestRef(t, gitDir, "refs/heads/main", writeTestCommit(t, gitDir, "tip", root))
+
+	var buf bytes.Buffer
+	SetLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	defer SetLogger(nil)
+	if _, err := GetHistoryFor("main"); err != nil {
+		t.Fatal(err)
+	}
+	if GitInstalled() {
+		cmd, done := gitCommand(context.Background(), 0, "version")
+		done(cmd.Run())
+	}
+	SetLogger(nil)
+	if _, err := GetHistoryFor("main"); err != nil {
+		t.Fatal(err)
+	}
+
+	log := buf.String()
+	// The loose tip is read twice, once to peel it and once to decode it, and its parent once from the pack.
+	if n := strings.Count(log, `msg="read object"`); n != 3 || !strings.Contains(log, filepath.Join("objects", "pack")) {
+		t.Errorf("logged %d object reads, want the loose tip twice and its packed parent once:\n%s", n, log)
+	}
+	if GitInstalled() && !strings.Contains(log, `msg="ran git" args=version`) {
+		t.Errorf("git version wasn't logged:\n%s", log)
+	}
+}
@@ -0,0 +1,83 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// maxLFSPointerSize is the largest a blob can be and still be an LFS pointer, as git-lfs decides.
+const maxLFSPointerSize = 1024
+
+// LFSPointer is what Git LFS stores in a repository in place of a large file, which it keeps elsewhere.
+type LFSPointer struct {
+	OID  string // The SHA-256 of the file's content, in hex
+	Size int64  // The size of the file, in bytes
+}
+
+// ParseLFSPointer reads data as an LFS pointer, reporting whether it is one: a short text of sorted
+// key-value lines, starting with the spec version and naming the file's sha256 oid and size.
+func ParseLFSPointer(data []byte) (LFSPointer, bool) {
+	if len(data) == 0 || len(data) > maxLFSPointerSize || !bytes.HasSuffix(data, []byte("\n")) {
+		return LFSPointer{}, false
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	switch lines[0] {
+	case "version https://git-lfs.github.com/spec/v1", "version https://hawser.github.com/spec/v1":
+	default:
+		return LFSPointer{}, false
+	}
+
+	var p LFSPointer
+	sized := false
+	previous := ""
+	for _, line := range lines[1:] {
+		key, value, ok := strings.Cut(line, " ")
+		if !ok || key <= previous || key == "version" {
+			return LFSPointer{}, false
+		}
+		previous = key
+		switch key {
+		case "oid":
+			oid, ok := strings.CutPrefix(value, "sha256:")
+			if !ok || len(oid) != 64 || !isFullHash(oid) {
+				return LFSPointer{}, false
+			}
+			p.OID = oid
+		case "size":
+			size, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || size < 0 {
+				return LFSPointer{}, false
+			}
+			p.Size, sized = size, true
+		}
+	}
+	return p, p.OID != "" && sized
+}
+
+// HasLFSObject reports whether the file an LFS pointer stands for has been downloaded into the
+// repository's LFS store, .git/lfs/objects, or lfs.storage when git config sets it.
+func (repo *Repository) HasLFSObject(p LFSPointer) (bool, error) {
+	config, err := repo.Config()
+	if err != nil {
+		return false, err
+	}
+	store := filepath.Join(repo.CommonDir, "lfs")
+	if custom, err := config.Path("lfs.storage"); err != nil {
+		return false, err
+	} else if custom != "" {
+		store = custom
+		if !filepath.IsAbs(store) {
+			store = filepath.Join(repo.CommonDir, store)
+		}
+	}
+	info, err := os.Stat(filepath.Join(store, "objects", p.OID[:2], p.OID[2:4], p.OID))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil && info.Size() == p.Size, err
+}
@@ -0,0 +1,58 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testLFSOID = "4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393"
+
+func TestParseLFSPointer(t *testing.T) {
+	pointer := "version https://git-lfs.github.com/spec/v1\noid sha256:" + testLFSOID + "\nsize 2469606195\n"
+	if p, ok := ParseLFSPointer([]byte(pointer)); !ok || p != (LFSPointer{OID: testLFSOID, Size: 2469606195}) {
+		t.Errorf("ParseLFSPointer() = %+v, %v, want the oid and size", p, ok)
+	}
+	extended := "version https://git-lfs.github.com/spec/v1\next-0-foo sha256:" + testLFSOID + "\noid sha256:" + testLFSOID + "\nsize 12\n"
+	if _, ok := ParseLFSPointer([]byte(extended)); !ok {
+		t.Error("ParseLFSPointer() of a pointer with an extension = false, want true")
+	}
+
+	for _, data := range []string{
+		"",
+		"hello\n",
+		strings.TrimSuffix(pointer, "\n"),
+		"version https://git-lfs.github.com/spec/v1\nsize 12\noid sha256:" + testLFSOID + "\n",
+		"version https://git-lfs.github.com/spec/v1\noid sha256:" + testLFSOID + "\n",
+		"version https://git-lfs.github.com/spec/v1\noid md5:0123\nsize 12\n",
+		"version https://git-lfs.github.com/spec/v1\noid sha256:" + testLFSOID + "\nsize -1\n",
+		pointer + strings.Repeat("x", maxLFSPointerSize) + "\n",
+	} {
+		if p, ok := ParseLFSPointer([]byte(data)); ok {
+			t.Errorf("ParseLFSPointer(%q) = %+v, want no pointer", data, p)
+		}
+	}
+}
+
+func TestHasLFSObject(t *testing.T) {
+	gitDir := newTestRepo(t)
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := LFSPointer{OID: testLFSOID, Size: 5}
+	if ok, err := repo.HasLFSObject(p); ok || err != nil {
+		t.Errorf("HasLFSObject() before downloading = %v, %v, want false", ok, err)
+	}
+	dir := filepath.Join(gitDir, "lfs", "objects", testLFSOID[:2], testLFSOID[2:4])
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, testLFSOID), []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := repo.HasLFSObject(p); !ok || err != nil {
+		t.Errorf("HasLFSObject() after downloading = %v, %v, want true", ok, err)
+	}
+}
@@ -0,0 +1,166 @@
+package git
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// LostCommits returns commits that are no longer reachable from any branch, tag, or HEAD: work left behind
+// by deleted branches, resets, and amends. Only the newest commit of each lost line of work is returned,
+// since its ancestors come back with it, ordered from most to least recently committed.
+//
+// Candidates are found in reflogs and by scanning loose objects, like git fsck --lost-found.
+// Commits that only exist inside packfiles and are not mentioned in a reflog are not found.
+func (repo *Repository) LostCommits() ([]Commit, error) {
+	r, err := newCommitReader(repo)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	shallow, err := readShallow(repo.CommonDir)
+	if err != nil {
+		return nil, err
+	}
+
+	tips, err := repo.refTips(r)
+	if err != nil {
+		return nil, err
+	}
+
+	reachable := BranchHistory{Graph: map[string]Commit{}, Shallow: map[string]bool{}}
+	if err := r.walk(&reachable, shallow, tips); err != nil {
+		return nil, err
+	}
+
+	candidates, err := repo.lostCandidates()
+	if err != nil {
+		return nil, err
+	}
+
+	lost := map[string]Commit{}
+	for _, hash := range candidates {
+		if _, ok := reachable.Graph[hash]; ok {
+			continue
+		}
+
+		commit, ok, err := r.read(hash)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue // already pruned, or stored in a pack
+		}
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			lost[hash] = commit
+		}
+	}
+
+	// A lost commit that is the parent of another lost commit is recovered along with its child.
+	for _, commit := range lost {
+		for _, parent := range commit.Parents {
+			delete(lost, parent)
+		}
+	}
+
+	result := make([]Commit, 0, len(lost))
+	for _, commit := range lost {
+		result = append(result, commit)
+	}
+	slices.SortFunc(result, func(a, b Commit) int {
+		return b.Committer.Time.Compare(a.Committer.Time)
+	})
+	return result, nil
+}
+
+// refTips returns the commits pointed to by HEAD and every ref, with tags peeled.
+func (repo *Repository) refTips(r *commitReader) ([]string, error) {
+	refs, err := repo.listRefs("refs/")
+	if err != nil {
+		return nil, err
+	}
+
+	var tips []string
+	if head, err := repo.resolveSymbolic("HEAD"); err == nil {
+		tips = append(tips, head)
+	}
+	for _, hash := range refs {
+		peeled, err := r.peel(hash)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		tips = append(tips, peeled)
+	}
+	return tips, nil
+}
+
+// lostCandidates returns every commit hash mentioned in a reflog along with every loose object hash.
+func (repo *Repository) lostCandidates() ([]string, error) {
+	names, err := repo.reflogNames()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	var candidates []string
+	add := func(hash string) {
+		if isFullHash(hash) && !isZeroHash(hash) && !seen[hash] {
+			seen[hash] = true
+			candidates = append(candidates, hash)
+		}
+	}
+
+	for _, name := range names {
+		entries, err := repo.Reflog(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			add(entry.Old)
+			add(entry.New)
+		}
+	}
+
+	objects, err := repo.looseObjects()
+	if err != nil {
+		return nil, err
+	}
+	for _, hash := range objects {
+		add(hash)
+	}
+	return candidates, nil
+}
+
+// looseObjects returns the hash of every object stored loose in the object store.
+func (repo *Repository) looseObjects() ([]string, error) {
+	objectsPath := filepath.Join(repo.CommonDir, "objects")
+	dirs, err := os.ReadDir(objectsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var hashes []string
+	for _, dir := range dirs {
+		if !dir.IsDir() || len(dir.Name()) != 2 {
+			continue // skips info/ and pack/
+		}
+
+		files, err := os.ReadDir(filepath.Join(objectsPath, dir.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			hash := dir.Name() + file.Name()
+			if isFullHash(hash) {
+				hashes = append(hashes, hash)
+			}
+		}
+	}
+	return hashes, nil
+}
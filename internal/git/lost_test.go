@@ -0,0 +1,46 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLostCommits(t *testing.T) {
+	gitDir := newTestRepo(t)
+	root := writeTestCommit(t, gitDir, "root")
+	main := writeTestCommit(t, gitDir, "main", root)
+	abandoned := writeTestCommit(t, gitDir, "abandoned", root)
+	abandonedTip := writeTestCommit(t, gitDir, "abandoned tip", abandoned)
+	writeTestRef(t, gitDir, "refs/heads/main", main)
+	os.WriteFile(filepath.Join(gitDir, "HEAD"), []byte("ref: refs/heads/main\n"), 0o644)
+
+	// The abandoned work is only mentioned in the HEAD reflog, which also references a pruned commit.
+	os.MkdirAll(filepath.Join(gitDir, "logs"), 0o755)
+	pruned := "1111111111111111111111111111111111111111"
+	reflog := fmt.Sprintf("%s %s John Doe <john.doe@example.com> 1703123456 +0000\tcommit: abandoned tip\n", abandoned, abandonedTip) +
+		fmt.Sprintf("%s %s John Doe <john.doe@example.com> 1703123456 +0000\treset: moving to main\n", pruned, main)
+	os.WriteFile(filepath.Join(gitDir, "logs", "HEAD"), []byte(reflog), 0o644)
+
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := repo.Reflog("HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[1].Message != "reset: moving to main" {
+		t.Fatalf("unexpected reflog entries: %+v", entries)
+	}
+
+	lost, err := repo.LostCommits()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lost) != 1 || lost[0].Hash != abandonedTip {
+		t.Fatalf("expected only %s to be lost, got %v", abandonedTip[:7], lost)
+	}
+}
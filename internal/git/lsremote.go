@@ -0,0 +1,42 @@
+package git
+
+import (
+	"net/http"
+	"strings"
+)
+
+// ListRemoteRefs lists the refs of remote, a configured remote's name or a URL, as git ls-remote does.
+// Only the remote's ref advertisement is read and no objects are downloaded, so it is cheap enough to
+// check whether a branch exists on the remote, or where it is now, without fetching. With prefixes, such
+// as refs/heads/, only refs starting with one of them are listed; HEAD is listed only without any, or
+// when asked for by name.
+//
+// The remote is spoken to as [Repository.Fetch] describes.
+func (repo *Repository) ListRemoteRefs(client *http.Client, remote string, prefixes ...string) ([]RemoteRef, error) {
+	defer phase("ls-remote")()
+	config, err := repo.lookupRemote(remote)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := dialUploadPack(client, config.URLs[0], repo.Format)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	advertised, err := conn.lsRefs(prefixes)
+	if err != nil || len(prefixes) == 0 {
+		return advertised, err
+	}
+
+	// ref-prefix only lets the server leave refs out, so those it sent anyway are dropped here.
+	refs := advertised[:0]
+	for _, ref := range advertised {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(ref.Name, prefix) {
+				refs = append(refs, ref)
+				break
+			}
+		}
+	}
+	return refs, nil
+}
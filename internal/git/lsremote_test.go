@@ -0,0 +1,50 @@
+package git
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestListRemoteRefs(t *testing.T) {
+	server, refs := writeTestServer(t)
+	var requests [][]string
+	srv := serveUploadPack(t, server, refs, &requests)
+	defer srv.Close()
+
+	newTestRepo(t)
+	writeTestFiles(t, map[string]string{".git/config": fmt.Sprintf("[remote \"origin\"]\n\turl = %s/repo.git\n", srv.URL)})
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := repo.ListRemoteRefs(srv.Client(), "origin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != len(refs) {
+		t.Errorf("ListRemoteRefs() = %+v, want all %d refs", all, len(refs))
+	}
+	for _, ref := range all {
+		if ref.Hash != refs[ref.Name] {
+			t.Errorf("ListRemoteRefs() has %s at %s, want %s", ref.Name, ref.Hash, refs[ref.Name])
+		}
+	}
+
+	// The test server ignores ref-prefix, so the refs are narrowed down after it answers.
+	branches, err := repo.ListRemoteRefs(srv.Client(), srv.URL+"/repo.git", "refs/heads/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(branches) != 2 || branches[0].Name != "refs/heads/main" || branches[1].Name != "refs/heads/old" {
+		t.Errorf("ListRemoteRefs(refs/heads/) = %+v, want main and old", branches)
+	}
+	for _, request := range requests {
+		if request[0] != "command=ls-refs" {
+			t.Errorf("ListRemoteRefs() sent %q, want only ls-refs and no fetch", request[0])
+		}
+	}
+	if last := requests[len(requests)-1]; last[len(last)-1] != "ref-prefix refs/heads/" {
+		t.Errorf("ListRemoteRefs(refs/heads/) sent %q, want the prefix passed on", last)
+	}
+}
@@ -0,0 +1,347 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultPruneExpiry is how old an unreachable loose object must be before [Repository.Prune] deletes
+// it, matching git's gc.pruneExpire. Anything younger may belong to a command that is still running.
+const DefaultPruneExpiry = 14 * 24 * time.Hour
+
+// PruneResult is what [Repository.Prune] deleted.
+type PruneResult struct {
+	Objects int   // How many unreachable loose objects were deleted
+	Bytes   int64 // How much space they took up on disk
+}
+
+// Prune deletes the loose objects that are unreachable and were last written before expire, along with
+// temporary files left behind by interrupted writes, like git prune --expire. An object is reachable
+// from HEAD and the index of every worktree, from every ref, reflog entry, and pseudo ref such as
+// ORIG_HEAD or FETCH_HEAD, through the commits, trees, and tags that lead to it. Replace refs and
+// grafts are ignored, so the objects they hide are kept. Packed objects are never deleted.
+func (repo *Repository) Prune(expire time.Time) (PruneResult, error) {
+	defer phase("prune")()
+	reachable, err := repo.reachableObjects()
+	if err != nil {
+		return PruneResult{}, err
+	}
+
+	var result PruneResult
+	objectsPath := filepath.Join(repo.CommonDir, "objects")
+	err = repo.eachLooseFile(func(path, hash string, info fs.FileInfo) error {
+		if hash != "" && reachable[hash] || !info.ModTime().Before(expire) {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		if hash != "" {
+			result.Objects++
+			result.Bytes += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+	return result, removeEmptyFanout(objectsPath)
+}
+
+// RepackResult is what [Repository.Repack] packed.
+type RepackResult struct {
+	Objects int    // How many loose objects were moved into the pack
+	Pack    string // The path of the new pack, or "" if there was nothing to pack
+}
+
+// Repack moves every loose object into a single new pack, like git repack -d without -a, so reading
+// them takes one open file instead of one each. Objects already in a pack are left alone. Each object
+// is checked against its hash on the way in, and a damaged one stops the repack before anything is
+// deleted. The loose copies are only removed once the pack and its index are in place.
+func (repo *Repository) Repack() (RepackResult, error) {
+	defer phase("repack")()
+	objectsPath := filepath.Join(repo.CommonDir, "objects")
+	packs, err := openPacks(objectsPath, repo.Format)
+	if err != nil {
+		return RepackResult{}, err
+	}
+	defer packs.Close()
+
+	loose, err := repo.looseObjects()
+	if err != nil {
+		return RepackResult{}, err
+	}
+	var hashes []string
+	for _, hash := range loose {
+		if len(hash) == repo.Format.HexSize() && !packs.has(hash) {
+			hashes = append(hashes, hash)
+		}
+	}
+
+	var result RepackResult
+	if len(hashes) > 0 {
+		w, err := newPackWriter(objectsPath, repo.Format, len(hashes))
+		if err != nil {
+			return RepackResult{}, err
+		}
+		var d *Decoder
+		defer func() {
+			if d != nil {
+				d.Close()
+			}
+		}()
+		for _, hash := range hashes {
+			header, data, bad, err := repo.readVerified(&d, hash, true)
+			if err == nil && bad != nil {
+				err = fmt.Errorf("git: not packing damaged object %s: %s", hash, bad.Detail)
+			}
+			if err == nil {
+				err = w.add(hash, header.Kind, data)
+			}
+			if err != nil {
+				w.abort()
+				return RepackResult{}, err
+			}
+		}
+		if result.Pack, err = w.finish(); err != nil {
+			return RepackResult{}, err
+		}
+		result.Objects = len(hashes)
+	}
+
+	// Objects that were already packed are removed too, as git repack -d does.
+	for _, hash := range loose {
+		if err := os.Remove(repo.objectPath(hash)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return result, err
+		}
+	}
+	return result, removeEmptyFanout(objectsPath)
+}
+
+// eachLooseFile calls fn for every file in the loose object directories, with the hash it stores, or
+// "" for the temporary files objects are written to before being renamed into place.
+func (repo *Repository) eachLooseFile(fn func(path, hash string, info fs.FileInfo) error) error {
+	objectsPath := filepath.Join(repo.CommonDir, "objects")
+	dirs, err := os.ReadDir(objectsPath)
+	if err != nil {
+		return err
+	}
+	for _, dir := range dirs {
+		if !dir.IsDir() || len(dir.Name()) != 2 {
+			continue // skips info/ and pack/
+		}
+		files, err := os.ReadDir(filepath.Join(objectsPath, dir.Name()))
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			hash := dir.Name() + file.Name()
+			if !isFullHash(hash) {
+				if !strings.HasPrefix(file.Name(), "tmp_obj_") {
+					continue
+				}
+				hash = ""
+			}
+			info, err := file.Info()
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if err := fn(filepath.Join(objectsPath, dir.Name(), file.Name()), hash, info); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// removeEmptyFanout removes the loose object directories that no longer hold anything.
+func removeEmptyFanout(objectsPath string) error {
+	dirs, err := os.ReadDir(objectsPath)
+	if err != nil {
+		return err
+	}
+	for _, dir := range dirs {
+		if !dir.IsDir() || len(dir.Name()) != 2 {
+			continue
+		}
+		path := filepath.Join(objectsPath, dir.Name())
+		if files, err := os.ReadDir(path); err == nil && len(files) == 0 {
+			if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// reachableObjects returns every object reachable from the roots [Repository.Prune] keeps. Objects that
+// are missing, like the parents of a shallow clone's commits, are skipped, but one that can't be read
+// fails the walk, since whatever it points to can't be told apart from garbage.
+func (repo *Repository) reachableObjects() (map[string]bool, error) {
+	roots, err := repo.objectRoots()
+	if err != nil {
+		return nil, err
+	}
+
+	// Objects are read as they're stored, since replaced and grafted ones must be kept too.
+	r := &commitReader{objectsPath: filepath.Join(repo.CommonDir, "objects"), format: repo.Format}
+	defer r.Close()
+
+	reachable := map[string]bool{}
+	stack := roots
+	for len(stack) > 0 {
+		hash := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if reachable[hash] {
+			continue
+		}
+
+		header, closer, err := r.open(hash)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("git: failed to read %s: %w", hash, err)
+		}
+		reachable[hash] = true
+
+		switch header.Kind {
+		case CommitObject:
+			var commit Commit
+			commit, err = r.d.DecodeCommit(hash)
+			stack = append(append(stack, commit.Tree), commit.Parents...)
+		case TagObject:
+			var tag Tag
+			tag, err = r.d.DecodeTag(hash)
+			stack = append(stack, tag.Object)
+		case TreeObject:
+			var entries []TreeEntry
+			entries, err = r.d.DecodeTree()
+			for _, entry := range entries {
+				switch entry.Mode {
+				case ModeSubmodule:
+				case ModeTree:
+					stack = append(stack, entry.Hash)
+				default:
+					// Blobs don't point anywhere, so there's no need to read them.
+					reachable[entry.Hash] = true
+				}
+			}
+		}
+		closer.Close()
+		if err != nil {
+			return nil, fmt.Errorf("git: failed to read %s: %w", hash, err)
+		}
+	}
+	return reachable, nil
+}
+
+// objectRoots returns the objects everything reachable is reached from: for the main worktree and
+// every linked one, HEAD, its reflog, the pseudo refs in its git directory, and the staged files in
+// its index, along with every ref and reflog they share.
+func (repo *Repository) objectRoots() ([]string, error) {
+	var roots []string
+	add := func(hash string) {
+		if isFullHash(hash) && !isZeroHash(hash) {
+			roots = append(roots, hash)
+		}
+	}
+
+	refs, err := repo.listRefs("refs/")
+	if err != nil {
+		return nil, err
+	}
+	for _, hash := range refs {
+		add(hash)
+	}
+	names, err := repo.reflogNames()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		if name == "HEAD" {
+			continue // read for each worktree below
+		}
+		entries, err := repo.Reflog(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			add(entry.Old)
+			add(entry.New)
+		}
+	}
+
+	worktrees := []*Repository{{GitDir: repo.CommonDir, CommonDir: repo.CommonDir, Format: repo.Format}}
+	linked, err := os.ReadDir(filepath.Join(repo.CommonDir, "worktrees"))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	for _, dir := range linked {
+		if dir.IsDir() {
+			worktrees = append(worktrees, &Repository{GitDir: filepath.Join(repo.CommonDir, "worktrees", dir.Name()), CommonDir: repo.CommonDir, Format: repo.Format})
+		}
+	}
+
+	for _, wt := range worktrees {
+		entries, err := wt.Reflog("HEAD")
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			add(entry.Old)
+			add(entry.New)
+		}
+
+		// HEAD and the pseudo refs like ORIG_HEAD and FETCH_HEAD, which hold a hash at the start of
+		// each line; a symbolic HEAD's branch is among the refs already.
+		files, err := os.ReadDir(wt.GitDir)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			if name := file.Name(); file.IsDir() || (name != "HEAD" && !isPseudoRef(name)) {
+				continue
+			}
+			data, err := os.ReadFile(filepath.Join(wt.GitDir, file.Name()))
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return nil, err
+			}
+			scanner := bufio.NewScanner(bytes.NewReader(data))
+			for scanner.Scan() {
+				hash, _, _ := strings.Cut(scanner.Text(), "\t")
+				add(strings.TrimSpace(hash))
+			}
+		}
+		for _, prefix := range []string{"refs/worktree/", "refs/bisect/", "refs/rewritten/"} {
+			refs, err := wt.listRefs(prefix)
+			if err != nil {
+				return nil, err
+			}
+			for _, hash := range refs {
+				add(hash)
+			}
+		}
+
+		idx, err := wt.Index()
+		if err != nil {
+			return nil, fmt.Errorf("git: failed to read the index in %s: %w", wt.GitDir, err)
+		}
+		for _, entry := range idx.Entries {
+			if entry.Mode != ModeSubmodule {
+				add(entry.Hash)
+			}
+		}
+	}
+	return roots, nil
+}
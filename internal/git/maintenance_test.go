@@ -0,0 +1,170 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var maintenanceSignature = Signature{Name: "A", Email: "a@example.com", Time: time.Unix(1703123456, 0).UTC()}
+
+func TestPrune(t *testing.T) {
+	gitDir := newTestRepo(t)
+	writeTestRef(t, gitDir, "HEAD", "ref: refs/heads/main")
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept := map[string]string{}
+	keep := func(why, hash string) string {
+		kept[hash] = why
+		return hash
+	}
+
+	keep("the empty tree", writeTestObject(t, gitDir, TreeObject, ""))
+	root := keep("an ancestor of main", writeTestCommit(t, gitDir, "root"))
+	main := keep("main", writeTestCommit(t, gitDir, "main", root))
+	writeTestRef(t, gitDir, "refs/heads/main", main)
+	tag := keep("a tag", writeTestObject(t, gitDir, TagObject, "object "+main+"\ntype commit\ntag v1\n\nv1\n"))
+	writeTestRef(t, gitDir, "refs/tags/v1", tag)
+	reset := keep("the reflog", writeTestCommit(t, gitDir, "reset away"))
+	os.MkdirAll(filepath.Join(gitDir, "logs"), 0o755)
+	os.WriteFile(filepath.Join(gitDir, "logs", "HEAD"), []byte(fmt.Sprintf("%s %s A <a@example.com> 1703123456 +0000\treset: moving to main\n", reset, main)), 0o644)
+	orig := keep("ORIG_HEAD", writeTestCommit(t, gitDir, "before the rebase"))
+	writeTestRef(t, gitDir, origHead, orig)
+	blob := keep("a blob in a tree", writeTestObject(t, gitDir, BlobObject, "tracked\n"))
+	tree := keep("a tree", writeTestObject(t, gitDir, TreeObject, treeEntry("100644", "a.txt", blob)))
+	writeTestRef(t, gitDir, "refs/heads/feature", keep("feature", writeTestTreeCommit(t, repo, main, maintenanceSignature, "feature", map[string]string{"a.txt": "tracked\n"})))
+	if tree != mustTree(t, repo, "refs/heads/feature") {
+		t.Fatal("expected the feature's tree to be the one written by hand")
+	}
+	stageTestFiles(t, repo, map[string]string{"staged.txt": "staged\n"})
+	kept[HashObject(BlobObject, []byte("staged\n"))] = "the index"
+
+	garbage := []string{
+		writeTestCommit(t, gitDir, "dropped"),
+		writeTestObject(t, gitDir, BlobObject, "dropped\n"),
+	}
+	young := writeTestObject(t, gitDir, BlobObject, "just written\n")
+	tmp := filepath.Join(gitDir, "objects", garbage[0][:2], "tmp_obj_123")
+	os.WriteFile(tmp, []byte("partial"), 0o644)
+
+	old := time.Now().Add(-30 * 24 * time.Hour)
+	os.Chtimes(tmp, old, old)
+	for _, hash := range garbage {
+		os.Chtimes(repo.objectPath(hash), old, old)
+	}
+	for hash := range kept {
+		os.Chtimes(repo.objectPath(hash), old, old)
+	}
+
+	result, err := repo.Prune(time.Now().Add(-DefaultPruneExpiry))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Objects != len(garbage) {
+		t.Errorf("Prune deleted %d objects, want %d", result.Objects, len(garbage))
+	}
+	for hash, why := range kept {
+		if _, err := os.Stat(repo.objectPath(hash)); err != nil {
+			t.Errorf("Prune deleted %s, which is reachable from %s", hash, why)
+		}
+	}
+	for _, hash := range garbage {
+		if _, err := os.Stat(repo.objectPath(hash)); err == nil {
+			t.Errorf("Prune kept unreachable %s", hash)
+		}
+	}
+	if _, err := os.Stat(repo.objectPath(young)); err != nil {
+		t.Error("Prune deleted an unreachable object younger than the expiry")
+	}
+	if _, err := os.Stat(tmp); err == nil {
+		t.Error("Prune kept an old temporary object file")
+	}
+}
+
+func mustTree(t *testing.T, repo *Repository, rev string) string {
+	t.Helper()
+	hash, err := repo.ResolveRevision(rev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit, err := repo.ReadCommit(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return commit.Tree
+}
+
+func TestRepack(t *testing.T) {
+	gitDir := newTestRepo(t)
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestObject(t, gitDir, TreeObject, "")
+	base := writeTestTreeCommit(t, repo, writeTestCommit(t, gitDir, "root"), maintenanceSignature, "base", map[string]string{"a.txt": "one\n", "dir/b.txt": "two\n"})
+	writeTestRef(t, gitDir, "refs/heads/main", base)
+
+	result, err := repo.Repack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Objects != 7 || result.Pack == "" {
+		t.Fatalf("Repack() = %+v, want 7 objects packed", result)
+	}
+	if loose, _ := repo.looseObjects(); len(loose) != 0 {
+		t.Errorf("Repack left %d loose objects", len(loose))
+	}
+
+	// Everything reads the same from the pack.
+	commit, err := repo.ReadCommit(base)
+	if err != nil || commit.Message != "base" {
+		t.Fatalf("ReadCommit from a pack = %+v, %v", commit, err)
+	}
+	if hash, err := repo.ResolveRevision(base[:7]); err != nil || hash != base {
+		t.Errorf("ResolveRevision(%s) from a pack = %s, %v", base[:7], hash, err)
+	}
+	data, err := repo.ReadBlob(HashObject(BlobObject, []byte("two\n")))
+	if err != nil || string(data) != "two\n" {
+		t.Errorf("ReadBlob from a pack = %q, %v", data, err)
+	}
+
+	// Objects written afterwards go into a second pack, leaving the first alone.
+	next := writeTestTreeCommit(t, repo, base, maintenanceSignature, "next", map[string]string{"a.txt": "three\n"})
+	again, err := repo.Repack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Objects != 3 || again.Pack == result.Pack {
+		t.Errorf("second Repack() = %+v, want 3 objects in a new pack", again)
+	}
+	if _, err := repo.ReadCommit(next); err != nil {
+		t.Error(err)
+	}
+	if nothing, err := repo.Repack(); err != nil || nothing.Objects != 0 || nothing.Pack != "" {
+		t.Errorf("Repack() with nothing loose = %+v, %v", nothing, err)
+	}
+}
+
+func TestRepackRefusesDamagedObjects(t *testing.T) {
+	gitDir := newTestRepo(t)
+	writeTestObject(t, gitDir, BlobObject, "fine\n")
+	damaged := "0123456789abcdef0123456789abcdef01234567"
+	writeRawObject(t, gitDir, damaged, []byte("blob 3\x00abc"))
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Repack(); err == nil {
+		t.Fatal("expected Repack to refuse a damaged object")
+	}
+	if loose, _ := repo.looseObjects(); len(loose) != 2 {
+		t.Errorf("a failed Repack left %d loose objects, want both kept", len(loose))
+	}
+	if packs, _ := filepath.Glob(filepath.Join(gitDir, "objects", "pack", "*")); len(packs) != 0 {
+		t.Errorf("a failed Repack left %v behind", packs)
+	}
+}
@@ -0,0 +1,110 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrNoMergeBase = errors.New("no common ancestor")
+
+// MergeResult is what [Repository.FastForward] did.
+type MergeResult int
+
+const (
+	MergeUpToDate      MergeResult = iota // The branch already contains the revision, nothing changed
+	MergeFastForwarded                    // The branch was behind and now points at the revision
+	MergeNeeded                           // The histories diverged, or the branch is checked out, so nothing changed
+)
+
+// MergeBase returns the best common ancestor of the revisions a and b, like git merge-base: of all the
+// commits both can reach, one no other common ancestor descends from. When there are several, as after
+// criss-cross merges, the most recently committed is returned. If the histories share no commit,
+// [ErrNoMergeBase] is returned.
+func (repo *Repository) MergeBase(a, b string) (string, error) {
+	var graphs [2]map[string]Commit
+	for i, rev := range []string{a, b} {
+		hash, err := repo.ResolveRevision(rev)
+		if err != nil {
+			return "", err
+		}
+		history, err := repo.historyFrom(hash)
+		if err != nil {
+			return "", err
+		}
+		graphs[i] = history.Graph
+	}
+
+	common := map[string]Commit{}
+	for hash, commit := range graphs[0] {
+		if _, ok := graphs[1][hash]; ok {
+			common[hash] = commit
+		}
+	}
+
+	// Common ancestors that another common ancestor can reach aren't the best; topoOrder puts
+	// descendants first, so walking in that order marks them before they're considered.
+	covered := map[string]bool{}
+	var best *Commit
+	for _, commit := range topoOrder(common) {
+		if !covered[commit.Hash] && (best == nil || commit.Committer.Time.After(best.Committer.Time)) {
+			best = &commit
+		}
+		for _, parent := range commit.Parents {
+			covered[parent] = true
+		}
+	}
+	if best == nil {
+		return "", fmt.Errorf("%w between %s and %s", ErrNoMergeBase, a, b)
+	}
+	return best.Hash, nil
+}
+
+// FastForward brings the branch up to rev when that takes no merge, by moving the branch's ref and
+// recording who and why in its reflog, as git merge --ff-only does.
+//
+// If the branch already contains rev, [MergeUpToDate] is returned; if rev contains the branch, the branch
+// is moved and [MergeFastForwarded] is returned. Otherwise the histories diverged and [MergeNeeded] is
+// returned, leaving the branch for a real merge. A branch checked out in this worktree is never moved,
+// since its files and index would then be stale, so it always needs the merge engine unless up to date.
+func (repo *Repository) FastForward(branch, rev string, who Signature) (MergeResult, error) {
+	ref := "refs/heads/" + branch
+	current, err := repo.resolveRef(ref)
+	if err != nil {
+		return MergeNeeded, err
+	}
+	target, err := repo.ResolveRevision(rev)
+	if err != nil {
+		return MergeNeeded, err
+	}
+	r, err := newCommitReader(repo)
+	if err != nil {
+		return MergeNeeded, err
+	}
+	target, err = r.peel(target)
+	r.Close()
+	if err != nil {
+		return MergeNeeded, err
+	}
+
+	base, err := repo.MergeBase(current, target)
+	if errors.Is(err, ErrNoMergeBase) {
+		return MergeNeeded, nil
+	}
+	if err != nil {
+		return MergeNeeded, err
+	}
+	switch {
+	case base == target:
+		return MergeUpToDate, nil
+	case base != current:
+		return MergeNeeded, nil
+	}
+
+	if head, err := repo.resolveRef("HEAD"); err == nil && head == "ref: "+ref {
+		return MergeNeeded, nil
+	}
+	if err := repo.UpdateRef(ref, current, target, who, "merge "+rev+": Fast-forward"); err != nil {
+		return MergeNeeded, err
+	}
+	return MergeFastForwarded, nil
+}
@@ -0,0 +1,75 @@
+package git
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMergeBase(t *testing.T) {
+	gitDir := newTestRepo(t)
+	root := writeTestCommit(t, gitDir, "root")
+	fork := writeTestCommit(t, gitDir, "fork", root)
+	main := writeTestCommit(t, gitDir, "main", fork)
+	feature := writeTestCommit(t, gitDir, "feature", fork)
+	unrelated := writeTestCommit(t, gitDir, "unrelated")
+
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct{ a, b, want string }{
+		{main, feature, fork},
+		{feature, main, fork},
+		{main, fork, fork},
+		{main, main, main},
+	} {
+		if got, err := repo.MergeBase(c.a, c.b); err != nil || got != c.want {
+			t.Errorf("MergeBase(%.7s, %.7s) = %.7s, %v, want %.7s", c.a, c.b, got, err, c.want)
+		}
+	}
+	if _, err := repo.MergeBase(main, unrelated); !errors.Is(err, ErrNoMergeBase) {
+		t.Errorf("unrelated histories: got %v", err)
+	}
+}
+
+func TestFastForward(t *testing.T) {
+	gitDir := newTestRepo(t)
+	root := writeTestCommit(t, gitDir, "root")
+	feature := writeTestCommit(t, gitDir, "feature", root)
+	other := writeTestCommit(t, gitDir, "other", root)
+	writeTestRef(t, gitDir, "refs/heads/main", root)
+	writeTestRef(t, gitDir, "refs/heads/feature", feature)
+	writeTestRef(t, gitDir, "refs/heads/other", other)
+	writeTestRef(t, gitDir, "HEAD", "ref: refs/heads/feature")
+
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	who := Signature{Name: "Ann", Email: "ann@example.com", Time: time.Unix(1703123456, 0)}
+
+	if result, err := repo.FastForward("main", "feature", who); err != nil || result != MergeFastForwarded {
+		t.Fatalf("FastForward(main, feature) = %v, %v", result, err)
+	}
+	if hash, _ := repo.ResolveRevision("main"); hash != feature {
+		t.Errorf("main is at %s, want %s", hash, feature)
+	}
+	log, err := repo.Reflog("refs/heads/main")
+	if err != nil || len(log) != 1 || !strings.Contains(log[0].Message, "Fast-forward") {
+		t.Errorf("expected a fast-forward reflog entry, got %+v, %v", log, err)
+	}
+
+	if result, err := repo.FastForward("main", "feature", who); err != nil || result != MergeUpToDate {
+		t.Errorf("merging again = %v, %v, want up to date", result, err)
+	}
+	if result, err := repo.FastForward("main", "other", who); err != nil || result != MergeNeeded {
+		t.Errorf("diverged histories = %v, %v, want a merge", result, err)
+	}
+	// feature is checked out, so moving it would leave the work tree behind.
+	writeTestRef(t, gitDir, "refs/heads/feature", root)
+	if result, err := repo.FastForward("feature", "main", who); err != nil || result != MergeNeeded {
+		t.Errorf("checked out branch = %v, %v, want a merge", result, err)
+	}
+}
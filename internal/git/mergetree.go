@@ -0,0 +1,297 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/sim-deos/plain/internal/diff"
+)
+
+var (
+	ErrDirtyWorkTree   = errors.New("work tree has uncommitted changes")
+	ErrWouldOverwrite  = errors.New("untracked file would be overwritten")
+	ErrOperationActive = errors.New("another operation is in progress")
+)
+
+// pathMerge is the outcome of a three-way merge for one path whose result differs from ours.
+type pathMerge struct {
+	path     string
+	stages   [3]*TreeEntry // Base, ours, and theirs, nil where the side doesn't have the path
+	result   *TreeEntry    // The merged entry, nil when the path is deleted or conflicted
+	content  []byte        // The merged file, or for a conflict what the work tree gets
+	conflict bool
+}
+
+// mergeTrees merges the changes from base to theirs into ours, all tree hashes ("" for an empty tree), and
+// returns every path whose result differs from ours, sorted by path.
+//
+// Paths changed on one side take that side's version. Files both sides changed are merged line by line
+// with [diff.Merge3], with conflicting regions between markers labelled oursLabel and theirsLabel. Anything
+// else both sides changed differently, such as a file one side deleted and the other edited, or binary
+// files, is a conflict whose work tree file is ours, or theirs if we don't have it.
+func (repo *Repository) mergeTrees(base, ours, theirs, oursLabel, theirsLabel string) ([]pathMerge, error) {
+	var trees [3]map[string]TreeEntry
+	for i, hash := range []string{base, ours, theirs} {
+		trees[i] = map[string]TreeEntry{}
+		if hash == "" {
+			continue
+		}
+		files, err := repo.ReadTreeFiles(hash)
+		if err != nil {
+			return nil, err
+		}
+		trees[i] = files
+	}
+
+	var paths []string
+	for _, tree := range trees {
+		for path := range tree {
+			paths = append(paths, path)
+		}
+	}
+	slices.Sort(paths)
+	paths = slices.Compact(paths)
+
+	var merges []pathMerge
+	for _, path := range paths {
+		var m pathMerge
+		m.path = path
+		for i, tree := range trees {
+			if entry, ok := tree[path]; ok {
+				m.stages[i] = &entry
+			}
+		}
+		b, o, t := m.stages[0], m.stages[1], m.stages[2]
+
+		switch {
+		case sameEntry(o, t), sameEntry(b, t):
+			continue
+		case sameEntry(b, o):
+			m.result = t
+		case o != nil && t != nil && isRegular(o.Mode) && isRegular(t.Mode) && (b == nil || isRegular(b.Mode)):
+			if err := repo.mergeFiles(&m, oursLabel, theirsLabel); err != nil {
+				return nil, err
+			}
+			if m.result != nil && sameEntry(m.result, o) {
+				continue
+			}
+		default:
+			m.conflict = true
+			side := o
+			if side == nil {
+				side = t
+			}
+			if side.Mode != ModeSubmodule {
+				data, err := repo.ReadBlob(side.Hash)
+				if err != nil {
+					return nil, err
+				}
+				m.content = data
+			}
+		}
+		merges = append(merges, m)
+	}
+	return merges, nil
+}
+
+// mergeFiles merges the contents of a file both sides changed, marking m as a conflict if the lines or
+// the modes clash or the file is binary.
+func (repo *Repository) mergeFiles(m *pathMerge, oursLabel, theirsLabel string) error {
+	var data [3][]byte
+	for i, entry := range m.stages {
+		if entry == nil {
+			continue
+		}
+		blob, err := repo.ReadBlob(entry.Hash)
+		if err != nil {
+			return err
+		}
+		data[i] = blob
+	}
+	b, o, t := m.stages[0], m.stages[1], m.stages[2]
+
+	mode, modeClash := o.Mode, false
+	switch {
+	case o.Mode == t.Mode:
+	case b != nil && o.Mode == b.Mode:
+		mode = t.Mode
+	case b != nil && t.Mode == b.Mode:
+	default:
+		modeClash = true
+	}
+
+	if diff.IsBinary(data[0]) || diff.IsBinary(data[1]) || diff.IsBinary(data[2]) {
+		m.conflict, m.content = true, data[1]
+		return nil
+	}
+	merged, conflicts := diff.Merge3(data[0], data[1], data[2], oursLabel, theirsLabel)
+	m.content = merged
+	if conflicts > 0 || modeClash {
+		m.conflict = true
+		return nil
+	}
+
+	hash, err := repo.Encoder().Encode(BlobObject, merged)
+	if err != nil {
+		return err
+	}
+	m.result = &TreeEntry{Mode: mode, Name: o.Name, Hash: hash}
+	return nil
+}
+
+// checkoutMerges writes the outcome of mergeTrees to idx and the work tree. Conflicted paths get their
+// versions staged as conflict stages 1 to 3, and their content written to the work tree.
+//
+// Nothing is written if a path the merge creates exists untracked in the work tree; the merge fails with
+// [ErrWouldOverwrite] instead. The caller writes idx with [Repository.WriteIndex].
+func (repo *Repository) checkoutMerges(idx *Index, merges []pathMerge) error {
+	for _, m := range merges {
+		if m.stages[1] != nil || m.result == nil && !m.conflict {
+			continue
+		}
+		if _, err := os.Lstat(filepath.Join(repo.WorkTree, filepath.FromSlash(m.path))); err == nil {
+			return fmt.Errorf("%w: %s", ErrWouldOverwrite, m.path)
+		}
+	}
+
+	for _, m := range merges {
+		switch {
+		case m.conflict:
+			side := m.stages[1]
+			if side == nil {
+				side = m.stages[2]
+			}
+			if side.Mode != ModeSubmodule {
+				if err := repo.writeWorktreeFile(m.path, side.Mode, m.content); err != nil {
+					return err
+				}
+			}
+			idx.Remove(m.path)
+			for i, entry := range m.stages {
+				if entry != nil {
+					idx.Add(IndexEntry{Path: m.path, Mode: entry.Mode, Hash: entry.Hash, Stage: i + 1})
+				}
+			}
+
+		case m.result == nil:
+			if err := removeWorktreeFile(repo.WorkTree, m.path); err != nil {
+				return err
+			}
+			idx.Remove(m.path)
+
+		case m.result.Mode == ModeSubmodule:
+			idx.Add(IndexEntry{Path: m.path, Mode: m.result.Mode, Hash: m.result.Hash})
+
+		default:
+			content := m.content
+			if content == nil {
+				data, err := repo.ReadBlob(m.result.Hash)
+				if err != nil {
+					return err
+				}
+				content = data
+			}
+			if err := repo.writeWorktreeFile(m.path, m.result.Mode, content); err != nil {
+				return err
+			}
+			if err := repo.StagePath(idx, m.path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// requireClean fails with [ErrDirtyWorkTree] if the index or a tracked file differs from HEAD.
+// Untracked files don't count.
+func (repo *Repository) requireClean() error {
+	entries, err := repo.Status(nil)
+	if err != nil {
+		return err
+	}
+	var dirty []string
+	for _, entry := range entries {
+		if entry.Staged != 0 || entry.Unstaged != 0 && entry.Unstaged != StatusUntracked {
+			dirty = append(dirty, entry.Path)
+		}
+	}
+	if len(dirty) > 0 {
+		return fmt.Errorf("%w: %s", ErrDirtyWorkTree, strings.Join(dirty, ", "))
+	}
+	return nil
+}
+
+// resetToHead makes idx and the work tree match HEAD again for every path they differ on, discarding
+// merge results and conflicts. Untracked files are left alone.
+func (repo *Repository) resetToHead(idx *Index) error {
+	head, err := repo.headFiles()
+	if err != nil {
+		return err
+	}
+
+	var stale []string
+	for _, entry := range idx.Entries {
+		if _, ok := head[entry.Path]; !ok {
+			stale = append(stale, entry.Path)
+		}
+	}
+	for _, path := range slices.Compact(stale) {
+		if err := removeWorktreeFile(repo.WorkTree, path); err != nil {
+			return err
+		}
+		idx.Remove(path)
+	}
+
+	for path, entry := range head {
+		if staged, ok := idx.Entry(path); ok && staged.Hash == entry.Hash && staged.Mode == entry.Mode {
+			if status, err := repo.worktreeStatus(staged, 0); err == nil && status == 0 {
+				continue
+			}
+		}
+		if entry.Mode == ModeSubmodule {
+			idx.Add(IndexEntry{Path: path, Mode: entry.Mode, Hash: entry.Hash})
+			continue
+		}
+		data, err := repo.ReadBlob(entry.Hash)
+		if err != nil {
+			return err
+		}
+		if err := repo.writeWorktreeFile(path, entry.Mode, data); err != nil {
+			return err
+		}
+		if err := repo.StagePath(idx, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sameEntry(a, b *TreeEntry) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Mode == b.Mode && a.Hash == b.Hash
+}
+
+func isRegular(mode FileMode) bool {
+	return mode == ModeFile || mode == ModeExecutable
+}
+
+// removeWorktreeFile deletes the file at path, along with any directories left empty by it.
+func removeWorktreeFile(workTree, path string) error {
+	full := filepath.Join(workTree, filepath.FromSlash(path))
+	if err := os.Remove(full); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	for dir := filepath.Dir(full); dir != workTree && strings.HasPrefix(dir, workTree); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,337 @@
+package git
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/sim-deos/plain/internal/httpclient"
+)
+
+var ErrNoGit = errors.New("git isn't installed")
+
+// GitInstalled reports whether the git command can be found on the PATH.
+func GitInstalled() bool {
+	_, err := exec.LookPath("git")
+	return err == nil
+}
+
+// NativeClient is a [Client] that works without the git command, by reading the repository in the
+// current directory itself. It can tell which branch is checked out, whether it is dirty, what it
+// changed, read config, and fetch from and push to https and ssh remotes, but not switch branches,
+// merge, commit, or tag; those are handed to Fallback, or fail with [ErrNoGit] without one.
+type NativeClient struct {
+	// HTTP is the client https remotes are reached with. When nil, [httpclient.New] is used.
+	HTTP *http.Client
+
+	// Fallback does what the native client can't yet, like merging or fetching from a local path, so
+	// it can be used where git is installed. When nil, those fail with [ErrNoGit].
+	Fallback Client
+}
+
+func NewNativeClient() *NativeClient {
+	return &NativeClient{}
+}
+
+func unsupported(what string) error {
+	return fmt.Errorf("%w, and plain can't %s without it", ErrNoGit, what)
+}
+
+func (c *NativeClient) Init() error {
+	if c.Fallback != nil {
+		return c.Fallback.Init()
+	}
+	return unsupported("create repositories")
+}
+
+// IsBranchDirty reports whether any tracked file differs from HEAD, in the index or the work tree, like
+// git diff --quiet HEAD. Untracked files don't count.
+func (c *NativeClient) IsBranchDirty() (bool, error) {
+	repo, err := OpenRepository()
+	if err != nil {
+		return true, err
+	}
+	entries, err := repo.Status(nil)
+	if err != nil {
+		return true, err
+	}
+	for _, entry := range entries {
+		if entry.Unstaged != StatusUntracked {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Status lists what differs like [Repository.Status], leaving out what the repository's ignore rules and
+// core.excludesFile ignore, and compares the branch with its upstream like [Repository.AheadBehind]. An
+// upstream that was never fetched is named but not compared, as git status does. Renames aren't detected,
+// so a moved path is a deletion and an addition.
+func (c *NativeClient) Status() (WorkTreeStatus, error) {
+	repo, err := OpenRepository()
+	if err != nil {
+		return WorkTreeStatus{}, err
+	}
+	config, err := repo.Config()
+	if err != nil {
+		return WorkTreeStatus{}, err
+	}
+	excludesFile, _ := config.Get("core.excludesFile")
+	ignore, err := LoadIgnore(repo.WorkTree, repo.CommonDir, excludesFile)
+	if err != nil {
+		return WorkTreeStatus{}, err
+	}
+
+	var status WorkTreeStatus
+	if status.Entries, err = repo.Status(ignore); err != nil {
+		return WorkTreeStatus{}, err
+	}
+	if status.Branch.Head, err = repo.CurrentBranch(); err != nil {
+		return WorkTreeStatus{}, err
+	}
+	status.Branch.Commit, err = repo.ResolveRevision("HEAD")
+	if errors.Is(err, ErrRefNotFound) {
+		// A branch without commits yet, which has nothing to compare with its upstream either.
+		return status, nil
+	} else if err != nil {
+		return WorkTreeStatus{}, err
+	}
+	if status.Branch.Head == "" {
+		return status, nil
+	}
+
+	up, err := config.Upstream(status.Branch.Head)
+	if errors.Is(err, ErrNoUpstream) || err == nil && up.Ref == "" {
+		return status, nil
+	} else if err != nil {
+		return WorkTreeStatus{}, err
+	}
+	status.Branch.Upstream = strings.TrimPrefix(strings.TrimPrefix(up.Ref, "refs/remotes/"), "refs/heads/")
+	status.Branch.Ahead, status.Branch.Behind, err = repo.AheadBehind("HEAD", up.Ref)
+	if errors.Is(err, ErrUnknownRevision) {
+		return status, nil
+	}
+	return status, err
+}
+
+func (c *NativeClient) GetCurrentBranch() (string, error) {
+	repo, err := OpenRepository()
+	if err != nil {
+		return "", err
+	}
+	return repo.CurrentBranch()
+}
+
+func (c *NativeClient) CreateBranch(name, from string) error {
+	if c.Fallback != nil {
+		return c.Fallback.CreateBranch(name, from)
+	}
+	return unsupported("check out a new branch")
+}
+
+func (c *NativeClient) SwitchBranch(name string) error {
+	if c.Fallback != nil {
+		return c.Fallback.SwitchBranch(name)
+	}
+	return unsupported("switch branches")
+}
+
+// ChangedFiles lists the files that differ between HEAD and where it forked from base, like
+// git diff --name-only base...HEAD, without detecting renames.
+func (c *NativeClient) ChangedFiles(base string) ([]string, error) {
+	repo, err := OpenRepository()
+	if err != nil {
+		return nil, err
+	}
+	fork, err := repo.MergeBase(base, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	trees := [2]string{}
+	for i, rev := range []string{fork, "HEAD"} {
+		hash, err := repo.ResolveRevision(rev)
+		if err != nil {
+			return nil, err
+		}
+		commit, err := repo.ReadCommit(hash)
+		if err != nil {
+			return nil, err
+		}
+		trees[i] = commit.Tree
+	}
+	changes, err := repo.DiffTrees(trees[0], trees[1])
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, len(changes))
+	for i, change := range changes {
+		paths[i] = change.Path
+	}
+	return paths, nil
+}
+
+func (c *NativeClient) MergeBase(a, b string) (string, error) {
+	repo, err := OpenRepository()
+	if err != nil {
+		return "", err
+	}
+	return repo.MergeBase(a, b)
+}
+
+// GetConfigValues reads the repository's config, or only the global config outside a repository.
+func (c *NativeClient) GetConfigValues(key string) ([]string, error) {
+	var config *Config
+	repo, err := OpenRepository()
+	switch {
+	case errors.Is(err, ErrNotRepo):
+		config, err = GlobalConfig()
+	case err == nil:
+		config, err = repo.Config()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return config.GetAll(key), nil
+}
+
+func (c *NativeClient) Merge(rev string) error {
+	if c.Fallback != nil {
+		return c.Fallback.Merge(rev)
+	}
+	return unsupported("merge")
+}
+
+// Push pushes the branch to the branch of the same name on the remote with [Repository.Push], so only
+// https and ssh remotes work without Fallback, and makes that its upstream.
+func (c *NativeClient) Push(remote, branch string) error {
+	ref := "refs/heads/" + branch
+	repo, err := c.push(remote, ref+":"+ref)
+	if c.handOff(err) {
+		return c.Fallback.Push(remote, branch)
+	}
+	if err != nil {
+		return err
+	}
+	return repo.setUpstream(branch, remote, ref)
+}
+
+func (c *NativeClient) CommitPaths(message string, paths []string) error {
+	if c.Fallback != nil {
+		return c.Fallback.CommitPaths(message, paths)
+	}
+	return unsupported("commit files")
+}
+
+func (c *NativeClient) CreateTag(name, message string) error {
+	if c.Fallback != nil {
+		return c.Fallback.CreateTag(name, message)
+	}
+	return unsupported("create tags")
+}
+
+func (c *NativeClient) PushTag(remote, tag string) error {
+	_, err := c.push(remote, "refs/tags/"+tag)
+	if c.handOff(err) {
+		return c.Fallback.PushTag(remote, tag)
+	}
+	return err
+}
+
+// Fetch fetches the remote's branches and tags with [Repository.Fetch], so only https and ssh remotes
+// work without Fallback.
+func (c *NativeClient) Fetch(remote string) error {
+	err := c.fetch(remote, nil)
+	if c.handOff(err) {
+		return c.Fallback.Fetch(remote)
+	}
+	return err
+}
+
+func (c *NativeClient) PushRef(remote, ref string) error {
+	_, err := c.push(remote, ref+":"+ref)
+	if c.handOff(err) {
+		return c.Fallback.PushRef(remote, ref)
+	}
+	return err
+}
+
+func (c *NativeClient) FetchRef(remote, ref, dest string) error {
+	err := c.fetch(remote, []string{"+" + ref + ":" + dest})
+	if c.handOff(err) {
+		return c.Fallback.FetchRef(remote, ref, dest)
+	}
+	return err
+}
+
+// handOff reports whether err is from a remote the native client can't reach, which Fallback can.
+func (c *NativeClient) handOff(err error) bool {
+	return c.Fallback != nil && errors.Is(err, ErrUnsupportedRemote)
+}
+
+func (c *NativeClient) fetch(remote string, refspecs []string) error {
+	repo, err := OpenRepository()
+	if err != nil {
+		return err
+	}
+	config, err := repo.Config()
+	if err != nil {
+		return err
+	}
+	_, err = repo.Fetch(c.httpClient(), remote, refspecs, reflogIdentity(config))
+	if errors.Is(err, ErrUnsupportedRemote) {
+		return fmt.Errorf("%w: %w", ErrNoGit, err)
+	}
+	return err
+}
+
+// push pushes refspec to the remote, failing with [ErrPushRejected] if the remote's ref wasn't updated,
+// and returns the repository pushed from.
+func (c *NativeClient) push(remote, refspec string) (*Repository, error) {
+	repo, err := OpenRepository()
+	if err != nil {
+		return nil, err
+	}
+	config, err := repo.Config()
+	if err != nil {
+		return nil, err
+	}
+	result, err := repo.Push(c.httpClient(), remote, []string{refspec}, reflogIdentity(config))
+	if errors.Is(err, ErrUnsupportedRemote) {
+		return nil, fmt.Errorf("%w: %w", ErrNoGit, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Rejected) > 0 {
+		rejected := result.Rejected[0]
+		return nil, fmt.Errorf("%w: %s (%s)", ErrPushRejected, rejected.Name, rejected.Reason)
+	}
+	return repo, nil
+}
+
+func (c *NativeClient) httpClient() *http.Client {
+	if c.HTTP != nil {
+		return c.HTTP
+	}
+	return httpclient.New()
+}
+
+// reflogIdentity is who ref updates are recorded as made by, which like git falls back to the login
+// name and host when user.name and user.email aren't set.
+func reflogIdentity(config *Config) Signature {
+	who := Signature{Time: time.Now()}
+	who.Name, _ = config.Get("user.name")
+	who.Email, _ = config.Get("user.email")
+	if who.Name == "" || who.Email == "" {
+		login := cmp.Or(loginName(), "unknown")
+		host, _ := os.Hostname()
+		who.Name = cmp.Or(who.Name, login)
+		who.Email = cmp.Or(who.Email, login+"@"+cmp.Or(host, "localhost"))
+	}
+	return who
+}
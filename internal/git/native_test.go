@@ -0,0 +1,93 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestNativeClient(t *testing.T) {
+	gitDir := newTestRepo(t)
+	writeTestRef(t, gitDir, "HEAD", "ref: refs/heads/feature")
+	if err := os.WriteFile(filepath.Join(gitDir, "config"), []byte("[plain]\n\tgenerated = a\n\tgenerated = b\n[branch \"feature\"]\n\tremote = .\n\tmerge = refs/heads/main\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestObject(t, gitDir, TreeObject, "")
+	main := writeTestTreeCommit(t, repo, writeTestCommit(t, gitDir, "root"), maintenanceSignature, "base", map[string]string{"a.txt": "one\n", "b.txt": "two\n"})
+	writeTestRef(t, gitDir, "refs/heads/main", main)
+	feature := writeTestTreeCommit(t, repo, main, maintenanceSignature, "feature", map[string]string{"a.txt": "changed\n", "b.txt": "two\n"})
+	writeTestRef(t, gitDir, "refs/heads/feature", feature)
+
+	c := NewNativeClient()
+	if branch, err := c.GetCurrentBranch(); err != nil || branch != "feature" {
+		t.Errorf("GetCurrentBranch() = %q, %v", branch, err)
+	}
+	if base, err := c.MergeBase("main", "HEAD"); err != nil || base != main {
+		t.Errorf("MergeBase() = %s, %v, want %s", base, err, main)
+	}
+	if files, err := c.ChangedFiles("main"); err != nil || !slices.Equal(files, []string{"a.txt"}) {
+		t.Errorf("ChangedFiles() = %v, %v", files, err)
+	}
+	if values, err := c.GetConfigValues("plain.generated"); err != nil || !slices.Equal(values, []string{"a", "b"}) {
+		t.Errorf("GetConfigValues() = %v, %v", values, err)
+	}
+
+	// Nothing is staged, so both of HEAD's files are staged for deletion.
+	if dirty, err := c.IsBranchDirty(); err != nil || !dirty {
+		t.Errorf("IsBranchDirty() = %v, %v, want dirty", dirty, err)
+	}
+	stageTestFiles(t, repo, map[string]string{"a.txt": "changed\n", "b.txt": "two\n"})
+	writeTestFiles(t, map[string]string{"untracked.txt": "new\n"})
+	if dirty, err := c.IsBranchDirty(); err != nil || dirty {
+		t.Errorf("IsBranchDirty() with only an untracked file = %v, %v, want clean", dirty, err)
+	}
+	status, err := c.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (BranchStatus{Head: "feature", Commit: feature, Upstream: "main", Ahead: 1}); status.Branch != want {
+		t.Errorf("Status().Branch = %+v, want %+v", status.Branch, want)
+	}
+	if want := []StatusEntry{{Path: "untracked.txt", Unstaged: StatusUntracked}}; !slices.Equal(status.Entries, want) {
+		t.Errorf("Status().Entries = %+v, want %+v", status.Entries, want)
+	}
+
+	if err := c.SwitchBranch("main"); !errors.Is(err, ErrNoGit) {
+		t.Errorf("SwitchBranch() = %v, want %v", err, ErrNoGit)
+	}
+
+	// With a fallback, what the native client can't do is handed to it.
+	fallback := &recordingClient{}
+	c.Fallback = fallback
+	if err := c.SwitchBranch("main"); err != nil {
+		t.Errorf("SwitchBranch() with a fallback = %v", err)
+	}
+	if err := c.Fetch("file:///srv/repo.git"); err != nil {
+		t.Errorf("Fetch() from a file URL with a fallback = %v", err)
+	}
+	if want := []string{"SwitchBranch main", "Fetch file:///srv/repo.git"}; !slices.Equal(fallback.calls, want) {
+		t.Errorf("fallback calls = %q, want %q", fallback.calls, want)
+	}
+}
+
+// recordingClient is a [Client] that records the calls made to it.
+type recordingClient struct {
+	Client
+	calls []string
+}
+
+func (c *recordingClient) SwitchBranch(name string) error {
+	c.calls = append(c.calls, "SwitchBranch "+name)
+	return nil
+}
+
+func (c *recordingClient) Fetch(remote string) error {
+	c.calls = append(c.calls, "Fetch "+remote)
+	return nil
+}
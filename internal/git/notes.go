@@ -0,0 +1,170 @@
+package git
+
+import (
+	"errors"
+	"strings"
+)
+
+// DefaultNotesRef is where git notes keeps notes unless told otherwise.
+const DefaultNotesRef = "refs/notes/commits"
+
+// notesTree holds the notes in a notes ref: the path of every note, keyed by the object it's attached
+// to, and every other file in the tree by its own path.
+type notesTree struct {
+	commit string // The notes commit, or "" if the ref doesn't exist
+	notes  map[string]TreeEntry
+	paths  map[string]string
+	other  map[string]TreeEntry
+}
+
+// readNotes reads the notes tree of the notes ref. Notes are stored in files named after the hash of
+// their object, which git splits into directories like 1a/2b3c... once there are many of them.
+func (repo *Repository) readNotes(ref string) (notesTree, error) {
+	nt := notesTree{notes: map[string]TreeEntry{}, paths: map[string]string{}, other: map[string]TreeEntry{}}
+	hash, err := repo.resolveRef(ref)
+	if errors.Is(err, ErrRefNotFound) {
+		return nt, nil
+	}
+	if err != nil {
+		return notesTree{}, err
+	}
+	commit, err := repo.ReadCommit(hash)
+	if err != nil {
+		return notesTree{}, err
+	}
+	nt.commit = hash
+
+	r, err := newCommitReader(repo)
+	if err != nil {
+		return notesTree{}, err
+	}
+	defer r.Close()
+	files := map[string]TreeEntry{}
+	if err := r.flatten(commit.Tree, "", files); err != nil {
+		return notesTree{}, err
+	}
+	for path, entry := range files {
+		if object := strings.ReplaceAll(path, "/", ""); isFullHash(object) {
+			nt.notes[object] = entry
+			nt.paths[object] = path
+		} else {
+			nt.other[path] = entry
+		}
+	}
+	return nt, nil
+}
+
+// Notes returns the text of every note in the notes ref, keyed by the hash of the object it's attached
+// to. An empty ref means [DefaultNotesRef].
+func (repo *Repository) Notes(ref string) (map[string]string, error) {
+	if ref == "" {
+		ref = DefaultNotesRef
+	}
+	nt, err := repo.readNotes(ref)
+	if err != nil {
+		return nil, err
+	}
+	notes := make(map[string]string, len(nt.notes))
+	for object, entry := range nt.notes {
+		data, err := repo.ReadBlob(entry.Hash)
+		if err != nil {
+			return nil, err
+		}
+		notes[object] = strings.TrimSuffix(string(data), "\n")
+	}
+	return notes, nil
+}
+
+// Note returns the text of the note in the notes ref attached to the object rev names, like git notes
+// show, and whether there is one. An empty ref means [DefaultNotesRef].
+func (repo *Repository) Note(ref, rev string) (string, bool, error) {
+	if ref == "" {
+		ref = DefaultNotesRef
+	}
+	object, err := repo.ResolveRevision(rev)
+	if err != nil {
+		return "", false, err
+	}
+	nt, err := repo.readNotes(ref)
+	if err != nil {
+		return "", false, err
+	}
+	entry, ok := nt.notes[object]
+	if !ok {
+		return "", false, nil
+	}
+	data, err := repo.ReadBlob(entry.Hash)
+	if err != nil {
+		return "", false, err
+	}
+	return strings.TrimSuffix(string(data), "\n"), true, nil
+}
+
+// SetNote attaches note to the object rev names in the notes ref, replacing any note it had, like git
+// notes add -f, or removes its note if note is empty. An empty ref means [DefaultNotesRef]. Each change
+// is a commit on the notes ref, so notes have a history and can be pushed like branches.
+func (repo *Repository) SetNote(ref, rev, note string, who Signature) error {
+	if ref == "" {
+		ref = DefaultNotesRef
+	}
+	object, err := repo.ResolveRevision(rev)
+	if err != nil {
+		return err
+	}
+	nt, err := repo.readNotes(ref)
+	if err != nil {
+		return err
+	}
+	if _, ok := nt.notes[object]; !ok && note == "" {
+		return nil
+	}
+
+	b := NewTreeBuilder(repo.Encoder())
+	for path, entry := range nt.other {
+		if err := b.Add(path, entry.Mode, entry.Hash); err != nil {
+			return err
+		}
+	}
+	for noted, entry := range nt.notes {
+		if noted == object {
+			continue
+		}
+		if err := b.Add(nt.paths[noted], entry.Mode, entry.Hash); err != nil {
+			return err
+		}
+	}
+
+	message := "Notes removed by 'plain'"
+	if note != "" {
+		message = "Notes added by 'plain'"
+		if !strings.HasSuffix(note, "\n") {
+			note += "\n"
+		}
+		blob, err := repo.Encoder().Encode(BlobObject, []byte(note))
+		if err != nil {
+			return err
+		}
+		path := object
+		if old, ok := nt.paths[object]; ok {
+			path = old
+		}
+		if err := b.Add(path, ModeFile, blob); err != nil {
+			return err
+		}
+	}
+	tree, err := b.Write()
+	if err != nil {
+		return err
+	}
+
+	var parents []string
+	old := ZeroHash
+	if nt.commit != "" {
+		parents, old = []string{nt.commit}, nt.commit
+	}
+	hash, err := repo.createCommit(tree, parents, who, who, message, nil)
+	if err != nil {
+		return err
+	}
+	return repo.UpdateRef(ref, old, hash, who, "notes: "+message)
+}
@@ -0,0 +1,91 @@
+package git
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNotes(t *testing.T) {
+	gitDir := newTestRepo(t)
+	first := writeTestCommit(t, gitDir, "first")
+	second := writeTestCommit(t, gitDir, "second", first)
+	writeTestRef(t, gitDir, "refs/heads/main", second)
+	writeTestRef(t, gitDir, "HEAD", "ref: refs/heads/main")
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	who := Signature{Name: "Ann", Email: "ann@example.com", Time: time.Unix(1703130000, 0)}
+
+	if _, ok, err := repo.Note("", "HEAD"); ok || err != nil {
+		t.Fatalf("Note() before any notes = %v, %v", ok, err)
+	}
+	if err := repo.SetNote("", "HEAD", "Reviewed", who); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SetNote("", first, "Plain-Checkpoint: true\nPlain-Issue: 42", who); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SetNote("", "HEAD", "Reviewed twice", who); err != nil {
+		t.Fatal(err)
+	}
+	if note, ok, err := repo.Note("", "main"); !ok || err != nil || note != "Reviewed twice" {
+		t.Errorf("Note(main) = %q, %v, %v, want the replaced note", note, ok, err)
+	}
+
+	notes, err := repo.Notes("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notes) != 2 || notes[first] != "Plain-Checkpoint: true\nPlain-Issue: 42" {
+		t.Errorf("Notes() = %q", notes)
+	}
+	log, err := repo.Reflog(DefaultNotesRef)
+	if err != nil || len(log) != 3 {
+		t.Errorf("notes ref has %d reflog entries (%v), want 3", len(log), err)
+	}
+
+	if err := repo.SetNote("", "HEAD", "", who); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := repo.Note("", "HEAD"); ok {
+		t.Error("HEAD still has a note after removing it")
+	}
+	if notes, _ := repo.Notes(""); len(notes) != 1 {
+		t.Errorf("Notes() after removing one = %q", notes)
+	}
+}
+
+func TestNotesFanout(t *testing.T) {
+	gitDir := newTestRepo(t)
+	commit := writeTestCommit(t, gitDir, "first")
+	writeTestRef(t, gitDir, "refs/heads/main", commit)
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	who := Signature{Name: "Ann", Email: "ann@example.com", Time: time.Unix(1703130000, 0)}
+
+	// Git splits note paths into directories once a notes tree grows large.
+	blob, _ := repo.Encoder().Encode(BlobObject, []byte("fanned out\n"))
+	b := NewTreeBuilder(repo.Encoder())
+	if err := b.Add(commit[:2]+"/"+commit[2:], ModeFile, blob); err != nil {
+		t.Fatal(err)
+	}
+	tree, _ := b.Write()
+	notesCommit, err := repo.CreateCommit(tree, nil, who, who, "Notes added by 'git notes add'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestRef(t, gitDir, "refs/notes/review", notesCommit)
+
+	if note, ok, err := repo.Note("refs/notes/review", commit); note != "fanned out" || !ok || err != nil {
+		t.Errorf("Note() = %q, %v, %v", note, ok, err)
+	}
+	if err := repo.SetNote("refs/notes/review", commit, "updated", who); err != nil {
+		t.Fatal(err)
+	}
+	if note, _, _ := repo.Note("refs/notes/review", commit); note != "updated" {
+		t.Errorf("Note() after updating = %q", note)
+	}
+}
@@ -0,0 +1,47 @@
+package git
+
+import "fmt"
+
+// ReadCommit decodes the commit with the given hash, applying replace refs and grafts like [GetHistoryForRevision].
+func (repo *Repository) ReadCommit(hash string) (Commit, error) {
+	r, err := newCommitReader(repo)
+	if err != nil {
+		return Commit{}, err
+	}
+	defer r.Close()
+
+	commit, ok, err := r.read(hash)
+	if err != nil {
+		return Commit{}, err
+	}
+	if !ok {
+		return Commit{}, fmt.Errorf("git: %s is not a commit", hash)
+	}
+	return commit, nil
+}
+
+// ReadBlob returns the contents of the blob with the given hash, such as a file in a tree.
+func (repo *Repository) ReadBlob(hash string) ([]byte, error) {
+	r, err := newCommitReader(repo)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return r.blob(hash)
+}
+
+// ReadTreeFiles returns every file in the tree with the given hash and its subtrees, keyed by slash
+// separated path.
+func (repo *Repository) ReadTreeFiles(hash string) (map[string]TreeEntry, error) {
+	r, err := newCommitReader(repo)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	files := map[string]TreeEntry{}
+	if err := r.flatten(hash, "", files); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
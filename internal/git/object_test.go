@@ -0,0 +1,36 @@
+package git
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadObjects(t *testing.T) {
+	gitDir := newTestRepo(t)
+	repo, _ := OpenRepository()
+
+	blob := writeTestObject(t, gitDir, BlobObject, "hello\n")
+	data, err := repo.ReadBlob(blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "hello\n" {
+		t.Errorf("unexpected blob contents %q", data)
+	}
+
+	commit := writeTestCommit(t, gitDir, "first")
+	c, err := repo.ReadCommit(commit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Hash != commit || c.Message != "first" || c.Tree != "4b825dc642cb6eb9a060e54bf8d69288fbee4904" {
+		t.Errorf("unexpected commit %+v", c)
+	}
+
+	if _, err := repo.ReadBlob(commit); err == nil || !strings.Contains(err.Error(), "not a blob") {
+		t.Errorf("expected a commit to be rejected as a blob, got %v", err)
+	}
+	if _, err := repo.ReadCommit(blob); err == nil {
+		t.Error("expected a blob to be rejected as a commit")
+	}
+}
@@ -0,0 +1,550 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"container/list"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+var ErrBadPack = errors.New("malformed pack")
+
+var packIndexSignature = []byte("\377tOc")
+
+// Object types as recorded in a pack entry's header, where whole objects use the same numbers as
+// [GitObjectKind]. The deltas store an object as the changes from another one, named by its offset
+// earlier in the pack or by its hash.
+const (
+	packCommit   = 1
+	packTree     = 2
+	packBlob     = 3
+	packTag      = 4
+	packOfsDelta = 6
+	packRefDelta = 7
+)
+
+// maxDeltaDepth bounds how long a chain of deltas may be, so a corrupt pack whose deltas form a loop
+// fails instead of recursing forever. Git itself never writes chains longer than 4095.
+const maxDeltaDepth = 10000
+
+// packIndex is a version 2 pack index (.idx), which lists the objects in a pack sorted by hash along
+// with where each one starts in the pack.
+type packIndex struct {
+	data     []byte
+	count    int
+	hashSize int // The size of a raw hash, in bytes
+}
+
+// readPackIndex reads the pack index at path, for a repository naming objects with format.
+func readPackIndex(path string, format HashFormat) (*packIndex, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	hashSize := format.HexSize() / 2
+	if len(data) < 8+256*4 || !bytes.Equal(data[:4], packIndexSignature) || binary.BigEndian.Uint32(data[4:]) != 2 {
+		return nil, fmt.Errorf("%w: %s is not a version 2 pack index", ErrBadPack, filepath.Base(path))
+	}
+	count := int(binary.BigEndian.Uint32(data[8+255*4:]))
+	// The names, CRCs, and offsets of every object, then the pack's and the index's own checksums.
+	if len(data) < 8+256*4+count*(hashSize+8)+2*hashSize {
+		return nil, fmt.Errorf("%w: %s is truncated", ErrBadPack, filepath.Base(path))
+	}
+	for b, previous := 0, uint32(0); b < 256; b++ {
+		if n := binary.BigEndian.Uint32(data[8+b*4:]); n >= previous {
+			previous = n
+		} else {
+			return nil, fmt.Errorf("%w: %s has a corrupt fan-out table", ErrBadPack, filepath.Base(path))
+		}
+	}
+	return &packIndex{data: data, count: count, hashSize: hashSize}, nil
+}
+
+// name returns the raw hash of the i-th object in the index.
+func (idx *packIndex) name(i int) []byte {
+	start := 8 + 256*4 + i*idx.hashSize
+	return idx.data[start : start+idx.hashSize]
+}
+
+// offset returns where the i-th object in the index starts in the pack. Offsets past 2GiB don't fit
+// in the main table, which then points into a table of 8 byte offsets after it.
+func (idx *packIndex) offset(i int) int64 {
+	offsets := 8 + 256*4 + idx.count*(idx.hashSize+4)
+	offset := binary.BigEndian.Uint32(idx.data[offsets+i*4:])
+	if offset&0x80000000 == 0 {
+		return int64(offset)
+	}
+	large := offsets + idx.count*4 + int(offset&0x7fffffff)*8
+	if large+8 > len(idx.data) {
+		return -1
+	}
+	return int64(binary.BigEndian.Uint64(idx.data[large:]))
+}
+
+// find returns the offset in the pack of the object with the given raw hash.
+func (idx *packIndex) find(raw []byte) (int64, bool) {
+	lo := 0
+	if raw[0] > 0 {
+		lo = int(binary.BigEndian.Uint32(idx.data[8+(int(raw[0])-1)*4:]))
+	}
+	hi := int(binary.BigEndian.Uint32(idx.data[8+int(raw[0])*4:]))
+	for lo < hi {
+		mid := (lo + hi) / 2
+		switch bytes.Compare(idx.name(mid), raw) {
+		case 0:
+			return idx.offset(mid), true
+		case -1:
+			lo = mid + 1
+		default:
+			hi = mid
+		}
+	}
+	return 0, false
+}
+
+// packFile is a pack and its index.
+type packFile struct {
+	path    string // The .pack file
+	index   *packIndex
+	f       *os.File // Opened on first read
+	offsets []int64  // Where every entry starts, in order, then where the checksum does; read on first use
+}
+
+// packStore reads objects from the packs in an objects directory.
+//
+// A new packStore is created by calling [openPacks], and must be closed.
+type packStore struct {
+	format HashFormat
+	packs  []*packFile
+	bases  *deltaBaseCache // Created on the first read of a delta
+}
+
+// openPacks reads the index of every pack in the objects directory at objectsPath. A pack without an
+// index is ignored, as git does, since it is usually still being written.
+func openPacks(objectsPath string, format HashFormat) (*packStore, error) {
+	defer phase("packs")()
+	if format == 0 {
+		format = SHA1
+	}
+	indexes, err := filepath.Glob(filepath.Join(objectsPath, "pack", "*.idx"))
+	if err != nil {
+		return nil, err
+	}
+	s := &packStore{format: format}
+	for _, path := range indexes {
+		pack := strings.TrimSuffix(path, ".idx") + ".pack"
+		if _, err := os.Stat(pack); errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		index, err := readPackIndex(path, format)
+		if err != nil {
+			return nil, err
+		}
+		s.packs = append(s.packs, &packFile{path: pack, index: index})
+	}
+	return s, nil
+}
+
+// Close closes the packs that were read from.
+func (s *packStore) Close() error {
+	var errs []error
+	for _, pack := range s.packs {
+		if pack.f != nil {
+			errs = append(errs, pack.f.Close())
+			pack.f = nil
+		}
+	}
+	s.bases = nil
+	return errors.Join(errs...)
+}
+
+// has reports whether the object named hash is in one of the packs.
+func (s *packStore) has(hash string) bool {
+	_, _, ok := s.locate(hash)
+	return ok
+}
+
+func (s *packStore) locate(hash string) (*packFile, int64, bool) {
+	raw, err := hex.DecodeString(hash)
+	if err != nil || len(raw) != s.format.HexSize()/2 {
+		return nil, 0, false
+	}
+	for _, pack := range s.packs {
+		if offset, ok := pack.index.find(raw); ok {
+			return pack, offset, true
+		}
+	}
+	return nil, 0, false
+}
+
+// diskSize returns how many bytes the packed object named hash takes up in its pack, compressed and
+// perhaps stored as a delta, from where its entry starts to where the next one does.
+func (s *packStore) diskSize(hash string) (int64, bool) {
+	pack, offset, ok := s.locate(hash)
+	if !ok {
+		return 0, false
+	}
+	if pack.offsets == nil {
+		info, err := os.Stat(pack.path)
+		if err != nil {
+			return 0, false
+		}
+		offsets := make([]int64, 0, pack.index.count+1)
+		for i := range pack.index.count {
+			offsets = append(offsets, pack.index.offset(i))
+		}
+		offsets = append(offsets, info.Size()-int64(s.format.HexSize()/2))
+		slices.Sort(offsets)
+		pack.offsets = offsets
+	}
+	i, found := slices.BinarySearch(pack.offsets, offset)
+	if !found || i+1 == len(pack.offsets) {
+		return 0, false
+	}
+	return pack.offsets[i+1] - offset, true
+}
+
+// hashes calls fn with the hash of every object in the packs. An object in several packs is passed
+// once for each.
+func (s *packStore) hashes(fn func(hash string)) {
+	for _, pack := range s.packs {
+		for i := range pack.index.count {
+			fn(hex.EncodeToString(pack.index.name(i)))
+		}
+	}
+}
+
+// read returns the kind and content of the packed object named hash, applying any deltas. An object
+// that isn't in any pack fails with [fs.ErrNotExist], like a missing loose object.
+func (s *packStore) read(hash string) (GitObjectKind, []byte, error) {
+	pack, offset, ok := s.locate(hash)
+	if !ok {
+		return 0, nil, fmt.Errorf("git: object %s: %w", hash, fs.ErrNotExist)
+	}
+	if logging(slog.LevelDebug) {
+		logAt(slog.LevelDebug, "read object", "path", pack.path, "offset", offset, "hash", hash)
+	}
+	kind, data, err := s.readAt(pack, offset, 0)
+	if err != nil {
+		return 0, nil, fmt.Errorf("git: failed to read %s from %s: %w", hash, filepath.Base(pack.path), err)
+	}
+	return kind, data, nil
+}
+
+// size returns the size of the packed object named hash. Only the start of its entry is read, so an
+// object stored as a delta is sized without applying it.
+func (s *packStore) size(hash string) (int64, error) {
+	pack, offset, ok := s.locate(hash)
+	if !ok {
+		return 0, fmt.Errorf("git: object %s: %w", hash, fs.ErrNotExist)
+	}
+	br, typ, size, err := s.entry(pack, offset)
+	if err != nil {
+		return 0, err
+	}
+	switch typ {
+	case packOfsDelta:
+		for {
+			c, err := br.ReadByte()
+			if err != nil {
+				return 0, err
+			}
+			if c&0x80 == 0 {
+				break
+			}
+		}
+	case packRefDelta:
+		if _, err := br.Discard(s.format.HexSize() / 2); err != nil {
+			return 0, err
+		}
+	default:
+		return size, nil
+	}
+
+	// A delta starts with the sizes of its base and of the object it makes.
+	zr, err := zlib.NewReader(br)
+	if err != nil {
+		return 0, err
+	}
+	defer zr.Close()
+	delta := bufio.NewReader(zr)
+	if _, err := binary.ReadUvarint(delta); err != nil {
+		return 0, fmt.Errorf("%w: truncated delta", ErrBadPack)
+	}
+	target, err := binary.ReadUvarint(delta)
+	if err != nil {
+		return 0, fmt.Errorf("%w: truncated delta", ErrBadPack)
+	}
+	return int64(target), nil
+}
+
+// entry reads the type and size of the entry at offset in pack, returning a reader of what follows.
+func (s *packStore) entry(pack *packFile, offset int64) (*bufio.Reader, byte, int64, error) {
+	if pack.f == nil {
+		f, err := os.Open(pack.path)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		pack.f = f
+	}
+	if offset < 12 {
+		return nil, 0, 0, fmt.Errorf("%w: bad offset %d", ErrBadPack, offset)
+	}
+
+	br := bufio.NewReader(io.NewSectionReader(pack.f, offset, 1<<62))
+	c, err := br.ReadByte()
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	typ := (c >> 4) & 7
+	size := int64(c & 0x0f)
+	for shift := 4; c&0x80 != 0; shift += 7 {
+		if c, err = br.ReadByte(); err != nil {
+			return nil, 0, 0, err
+		}
+		size |= int64(c&0x7f) << shift
+	}
+	return br, typ, size, nil
+}
+
+// readAt reads the object whose entry starts at offset in pack. One read as the base of a delta, at a
+// depth past 0, is kept for the next delta built on it, and returned from there, so it mustn't be changed.
+func (s *packStore) readAt(pack *packFile, offset int64, depth int) (GitObjectKind, []byte, error) {
+	if depth > maxDeltaDepth {
+		return 0, nil, fmt.Errorf("%w: delta chain too long", ErrBadPack)
+	}
+	key := baseKey{pack, offset}
+	if depth > 0 && s.bases != nil {
+		if kind, data, ok := s.bases.get(key); ok {
+			return kind, data, nil
+		}
+	}
+	br, typ, size, err := s.entry(pack, offset)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var baseKind GitObjectKind
+	var base []byte
+	switch typ {
+	case packCommit, packTree, packBlob, packTag:
+		data, err := inflate(br, size)
+		if err == nil && depth > 0 {
+			s.cacheBase(key, GitObjectKind(typ), data)
+		}
+		return GitObjectKind(typ), data, err
+	case packOfsDelta:
+		c, err := br.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		distance := int64(c & 0x7f)
+		for c&0x80 != 0 {
+			if c, err = br.ReadByte(); err != nil {
+				return 0, nil, err
+			}
+			distance = (distance+1)<<7 | int64(c&0x7f)
+		}
+		baseKind, base, err = s.readAt(pack, offset-distance, depth+1)
+		if err != nil {
+			return 0, nil, err
+		}
+	case packRefDelta:
+		raw := make([]byte, s.format.HexSize()/2)
+		if _, err := io.ReadFull(br, raw); err != nil {
+			return 0, nil, err
+		}
+		basePack, baseOffset, ok := s.locate(hex.EncodeToString(raw))
+		if !ok {
+			return 0, nil, fmt.Errorf("%w: delta base %x is missing", ErrBadPack, raw)
+		}
+		baseKind, base, err = s.readAt(basePack, baseOffset, depth+1)
+		if err != nil {
+			return 0, nil, err
+		}
+	default:
+		return 0, nil, fmt.Errorf("%w: unknown object type %d", ErrBadPack, typ)
+	}
+
+	delta, err := inflate(br, size)
+	if err != nil {
+		return 0, nil, err
+	}
+	data, err := applyDelta(base, delta)
+	if err == nil && depth > 0 {
+		s.cacheBase(key, baseKind, data)
+	}
+	return baseKind, data, err
+}
+
+// cacheBase keeps an object read as the base of a delta for the next delta built on it.
+func (s *packStore) cacheBase(key baseKey, kind GitObjectKind, data []byte) {
+	if s.bases == nil {
+		s.bases = newDeltaBaseCache(deltaBaseCacheLimit)
+	}
+	s.bases.add(key, kind, data)
+}
+
+// inflate decompresses the zlib stream at the start of r, which must hold exactly size bytes.
+func inflate(r io.Reader, size int64) ([]byte, error) {
+	zr, err := zlib.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	data, err := io.ReadAll(io.LimitReader(zr, size+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) != size {
+		return nil, fmt.Errorf("%w: expected %d bytes, got %d", ErrSizeMismatch, size, len(data))
+	}
+	observe(func(o Observer) { o.Inflated(size) })
+	return data, nil
+}
+
+// applyDelta rebuilds an object from its base and a delta, which starts with the sizes of both and
+// continues with instructions to copy a range of the base or to insert new bytes.
+func applyDelta(base, delta []byte) ([]byte, error) {
+	varint := func() (int, bool) {
+		n, shift := 0, 0
+		for len(delta) > 0 {
+			c := delta[0]
+			delta = delta[1:]
+			n |= int(c&0x7f) << shift
+			shift += 7
+			if c&0x80 == 0 {
+				return n, true
+			}
+		}
+		return 0, false
+	}
+	baseSize, ok := varint()
+	if !ok || baseSize != len(base) {
+		return nil, fmt.Errorf("%w: delta is for a base of a different size", ErrBadPack)
+	}
+	size, ok := varint()
+	if !ok {
+		return nil, fmt.Errorf("%w: truncated delta", ErrBadPack)
+	}
+
+	// The size is only trusted as far as the delta could plausibly make, so a corrupt one can't claim
+	// more memory than it is worth; out still grows to the size if the instructions really build that.
+	out := make([]byte, 0, min(size, len(base)+len(delta)))
+	for len(delta) > 0 {
+		op := delta[0]
+		delta = delta[1:]
+		switch {
+		case op&0x80 != 0:
+			// The low 4 bits say which bytes of the offset follow, the next 3 which bytes of the size.
+			var offset, n int
+			for i := range 7 {
+				if op&(1<<i) == 0 {
+					continue
+				}
+				if len(delta) == 0 {
+					return nil, fmt.Errorf("%w: truncated delta", ErrBadPack)
+				}
+				if i < 4 {
+					offset |= int(delta[0]) << (8 * i)
+				} else {
+					n |= int(delta[0]) << (8 * (i - 4))
+				}
+				delta = delta[1:]
+			}
+			if n == 0 {
+				n = 0x10000
+			}
+			if offset+n > len(base) {
+				return nil, fmt.Errorf("%w: delta copies past the end of its base", ErrBadPack)
+			}
+			if len(out)+n > size {
+				return nil, fmt.Errorf("%w: delta makes more than %d bytes", ErrBadPack, size)
+			}
+			out = append(out, base[offset:offset+n]...)
+		case op != 0:
+			if int(op) > len(delta) {
+				return nil, fmt.Errorf("%w: truncated delta", ErrBadPack)
+			}
+			if len(out)+int(op) > size {
+				return nil, fmt.Errorf("%w: delta makes more than %d bytes", ErrBadPack, size)
+			}
+			out = append(out, delta[:op]...)
+			delta = delta[op:]
+		default:
+			return nil, fmt.Errorf("%w: reserved delta instruction", ErrBadPack)
+		}
+	}
+	if len(out) != size {
+		return nil, fmt.Errorf("%w: delta produced %d bytes instead of %d", ErrBadPack, len(out), size)
+	}
+	return out, nil
+}
+
+// deltaBaseCache keeps the objects deltas were most recently applied to, keyed by where they start in
+// their pack, so the objects along a chain of deltas aren't each rebuilt from the start of the chain.
+// It holds at most limit bytes, dropping the least recently used objects first.
+type deltaBaseCache struct {
+	limit, size int
+	order       *list.List // Of *cachedBase, the most recently used first
+	items       map[baseKey]*list.Element
+}
+
+// baseKey is where an object in a [deltaBaseCache] starts: the pack, nil while one is being indexed,
+// and the offset in it.
+type baseKey struct {
+	pack   *packFile
+	offset int64
+}
+
+type cachedBase struct {
+	key  baseKey
+	kind GitObjectKind
+	data []byte
+}
+
+// deltaBaseCacheLimit is how much a [deltaBaseCache] holds, enough for the trees and source files
+// deltas are usually made of without holding on to much memory.
+const deltaBaseCacheLimit = 32 << 20
+
+func newDeltaBaseCache(limit int) *deltaBaseCache {
+	return &deltaBaseCache{limit: limit, order: list.New(), items: map[baseKey]*list.Element{}}
+}
+
+func (c *deltaBaseCache) get(key baseKey) (GitObjectKind, []byte, bool) {
+	e, ok := c.items[key]
+	if !ok {
+		return 0, nil, false
+	}
+	c.order.MoveToFront(e)
+	base := e.Value.(*cachedBase)
+	return base.kind, base.data, true
+}
+
+func (c *deltaBaseCache) add(key baseKey, kind GitObjectKind, data []byte) {
+	if len(data) > c.limit/4 {
+		// One large object would push out everything else.
+		return
+	}
+	if _, ok := c.items[key]; ok {
+		return
+	}
+	c.items[key] = c.order.PushFront(&cachedBase{key: key, kind: kind, data: data})
+	c.size += len(data)
+	for c.size > c.limit {
+		oldest := c.order.Remove(c.order.Back()).(*cachedBase)
+		delete(c.items, oldest.key)
+		c.size -= len(oldest.data)
+	}
+}
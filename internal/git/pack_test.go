@@ -0,0 +1,178 @@
+package git
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestApplyDelta(t *testing.T) {
+	base := []byte("the quick brown fox")
+	// Sizes 19 and 23, copy "the quick " (offset 0, size 10), insert "red ", copy "fox" (offset 16, size 3),
+	// then insert " jumps".
+	delta := []byte{19, 23, 0x90, 10, 4, 'r', 'e', 'd', ' ', 0x91, 16, 3, 6, ' ', 'j', 'u', 'm', 'p', 's'}
+	got, err := applyDelta(base, delta)
+	if err != nil || string(got) != "the quick red fox jumps" {
+		t.Errorf("applyDelta() = %q, %v", got, err)
+	}
+
+	for name, delta := range map[string][]byte{
+		"wrong base size":     {18, 3, 3, 'a', 'b', 'c'},
+		"copy past the end":   {19, 5, 0x91, 16, 5},
+		"wrong result size":   {19, 4, 3, 'a', 'b', 'c'},
+		"truncated insert":    {19, 3, 3, 'a'},
+		"reserved":            {19, 0, 0},
+		"missing result size": {19},
+		"more than its size":  {19, 2, 3, 'a', 'b', 'c'},
+		"implausible size":    {19, 0xff, 0xff, 0xff, 0xff, 0x0f, 3, 'a', 'b', 'c'},
+	} {
+		if _, err := applyDelta(base, delta); !errors.Is(err, ErrBadPack) {
+			t.Errorf("%s: got %v, want %v", name, err, ErrBadPack)
+		}
+	}
+}
+
+func TestMakeDelta(t *testing.T) {
+	var lines []string
+	for i := range 200 {
+		lines = append(lines, fmt.Sprintf("line %d of the file, %x\n", i, i*2654435761))
+	}
+	base := []byte(strings.Join(lines, ""))
+	for name, target := range map[string]string{
+		"unchanged": string(base),
+		"appended":  string(base) + "one more line\n",
+		"edited":    strings.Replace(string(base), "line 100 ", "the hundredth line ", 1),
+		"cut":       strings.Join(lines[50:150], ""),
+		"rewritten": "nothing in common\n",
+		"empty":     "",
+	} {
+		delta := makeDelta(base, []byte(target))
+		got, err := applyDelta(base, delta)
+		if err != nil || string(got) != target {
+			t.Errorf("%s: applyDelta(makeDelta()) = %d bytes, %v, want the target back", name, len(got), err)
+		}
+		if name != "rewritten" && name != "empty" && len(delta) > 100 {
+			t.Errorf("%s: delta is %d bytes, want it to copy from the base", name, len(delta))
+		}
+	}
+}
+
+// testPackEntry encodes a pack entry of the given type holding content, with extra, the base of a
+// delta, between its header and the compressed content.
+func testPackEntry(typ byte, extra, content []byte) []byte {
+	var b bytes.Buffer
+	c := typ<<4 | byte(len(content)&0x0f)
+	for size := len(content) >> 4; size > 0; size >>= 7 {
+		b.WriteByte(c | 0x80)
+		c = byte(size & 0x7f)
+	}
+	b.WriteByte(c)
+	b.Write(extra)
+	zw := zlib.NewWriter(&b)
+	zw.Write(content)
+	zw.Close()
+	return b.Bytes()
+}
+
+// testThinPack returns a pack of a blob, an OFS delta against it, and a REF delta against the blob
+// "hello\n", which it leaves out, along with its entries and the missing base's hash.
+func testThinPack() ([]byte, [][]byte, string) {
+	base := HashObject(BlobObject, []byte("hello\n"))
+	raw, _ := hex.DecodeString(base)
+	entries := [][]byte{
+		testPackEntry(packBlob, nil, []byte("the quick brown fox")),
+		nil, // the OFS delta, which needs the first entry's size
+		// "hello" from a base the pack leaves out, then " world\n".
+		testPackEntry(packRefDelta, raw, []byte{6, 12, 0x90, 5, 7, ' ', 'w', 'o', 'r', 'l', 'd', '\n'}),
+	}
+	entries[1] = testPackEntry(packOfsDelta, []byte{byte(len(entries[0]))}, []byte{19, 23, 0x90, 10, 4, 'r', 'e', 'd', ' ', 0x91, 16, 3, 6, ' ', 'j', 'u', 'm', 'p', 's'})
+
+	pack := []byte("PACK")
+	pack = binary.BigEndian.AppendUint32(pack, 2)
+	pack = binary.BigEndian.AppendUint32(pack, uint32(len(entries)))
+	for _, entry := range entries {
+		pack = append(pack, entry...)
+	}
+	sum := sha1.Sum(pack)
+	return append(pack, sum[:]...), entries, base
+}
+
+func TestIndexPack(t *testing.T) {
+	pack, entries, base := testThinPack()
+	external := func(hash string) (GitObjectKind, []byte, error) {
+		if hash == base {
+			return BlobObject, []byte("hello\n"), nil
+		}
+		return 0, nil, fmt.Errorf("%s: %w", hash, fs.ErrNotExist)
+	}
+	objects, thin, err := indexPack(bytes.NewReader(pack), int64(len(pack)), SHA1, external)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offset := int64(12)
+	for i, want := range []string{"the quick brown fox", "the quick red fox jumps", "hello world\n"} {
+		if objects[i].kind != BlobObject || objects[i].hash != HashObject(BlobObject, []byte(want)) || objects[i].offset != offset {
+			t.Errorf("object %d = %s %s at %d, want the blob %q at %d", i, objects[i].kind, objects[i].hash, objects[i].offset, want, offset)
+		}
+		offset += int64(len(entries[i]))
+	}
+	if len(thin) != 1 || thin[0] != base {
+		t.Errorf("thin bases = %v, want %s", thin, base)
+	}
+
+	if _, _, err := indexPack(bytes.NewReader(pack), int64(len(pack)), SHA1, nil); !errors.Is(err, ErrBadPack) {
+		t.Errorf("indexPack() without the thin base = %v, want %v", err, ErrBadPack)
+	}
+	pack[len(pack)-1] ^= 1
+	if _, _, err := indexPack(bytes.NewReader(pack), int64(len(pack)), SHA1, external); !errors.Is(err, ErrBadPack) {
+		t.Errorf("indexPack() with a bad checksum = %v, want %v", err, ErrBadPack)
+	}
+}
+
+func TestStorePackFixesThin(t *testing.T) {
+	gitDir := newTestRepo(t)
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pack, _, base := testThinPack()
+	writeTestObject(t, gitDir, BlobObject, "hello\n")
+
+	held, stored, err := repo.storePack(bytes.NewReader(pack))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(held) != 3 || stored != 3 {
+		t.Errorf("storePack() = %d objects, %d new, want 3 new", len(held), stored)
+	}
+
+	// The pack was made whole, so the delta reads without the loose base.
+	os.Remove(repo.objectPath(base))
+	packs, err := openPacks(filepath.Join(repo.CommonDir, "objects"), SHA1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer packs.Close()
+	if _, data, err := packs.read(HashObject(BlobObject, []byte("hello world\n"))); err != nil || string(data) != "hello world\n" {
+		t.Errorf("reading the REF delta = %q, %v", data, err)
+	}
+	if !packs.has(base) {
+		t.Errorf("the stored pack doesn't hold the thin base %s", base)
+	}
+
+	if _, stored, err := repo.storePack(bytes.NewReader(pack)); err != nil || stored != 0 {
+		t.Errorf("storePack() again = %d new, %v, want nothing new", stored, err)
+	}
+	if kept, _ := filepath.Glob(filepath.Join(repo.CommonDir, "objects", "pack", "*")); len(kept) != 2 {
+		t.Errorf("pack directory = %v, want one pack and its index", kept)
+	}
+}
@@ -0,0 +1,431 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// packWriter writes a version 2 pack, storing each object whole unless it's added as a delta against
+// another, which only a pack sent to another repository needs.
+//
+// A pack kept in an objects directory, along with its index, is started by calling [newPackWriter]; one
+// sent elsewhere, like into a bundle, by calling [streamPack]. Objects are added with add, and the pack is
+// ended by finish or end respectively, or thrown away by abort.
+type packWriter struct {
+	objectsPath string
+	format      HashFormat
+	f           *os.File // The temporary file the pack is written to, unless it's streamed
+	w           *bufio.Writer
+	sum         hash.Hash // The checksum of everything written so far, which ends the pack
+	offset      int64
+	entries     []packEntry
+}
+
+type packEntry struct {
+	raw    []byte // The object's hash
+	crc    uint32 // The CRC-32 of the object's entry in the pack, as the index records
+	offset int64
+}
+
+// newPackWriter starts a pack of count objects in the objects directory at objectsPath.
+func newPackWriter(objectsPath string, format HashFormat, count int) (*packWriter, error) {
+	dir := filepath.Join(objectsPath, "pack")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.CreateTemp(dir, "tmp_pack_")
+	if err != nil {
+		return nil, err
+	}
+	p, err := streamPack(f, format, count)
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	p.objectsPath, p.f = objectsPath, f
+	return p, nil
+}
+
+// streamPack starts a pack of count objects written to dst, without an index.
+func streamPack(dst io.Writer, format HashFormat, count int) (*packWriter, error) {
+	p := &packWriter{format: format, w: bufio.NewWriter(dst), sum: format.new()}
+	header := make([]byte, 12)
+	copy(header, "PACK")
+	binary.BigEndian.PutUint32(header[4:], 2)
+	binary.BigEndian.PutUint32(header[8:], uint32(count))
+	if err := p.write(header); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *packWriter) write(data []byte) error {
+	p.sum.Write(data)
+	p.offset += int64(len(data))
+	_, err := p.w.Write(data)
+	return err
+}
+
+// add appends the object named hash, of the given kind and content, to the pack.
+func (p *packWriter) add(hash string, kind GitObjectKind, data []byte) error {
+	return p.addEntry(hash, byte(kind), nil, data)
+}
+
+// addDelta appends the object named hash to the pack as delta, made by [makeDelta], against the object
+// named base, which the pack's receiver must have, in the pack or already.
+func (p *packWriter) addDelta(hash, base string, delta []byte) error {
+	raw, err := hex.DecodeString(base)
+	if err != nil {
+		return err
+	}
+	return p.addEntry(hash, packRefDelta, raw, delta)
+}
+
+// addEntry appends an entry of type typ for the object named hash to the pack, with prefix, like the
+// base of a delta, between its header and its compressed data.
+func (p *packWriter) addEntry(hash string, typ byte, prefix, data []byte) error {
+	raw, err := hex.DecodeString(hash)
+	if err != nil {
+		return err
+	}
+
+	// The type and size, 4 bits of the size in the first byte and 7 in each after it.
+	var entry bytes.Buffer
+	size := len(data)
+	c := typ<<4 | byte(size&0x0f)
+	for size >>= 4; size > 0; size >>= 7 {
+		entry.WriteByte(c | 0x80)
+		c = byte(size & 0x7f)
+	}
+	entry.WriteByte(c)
+	entry.Write(prefix)
+	zw := zlib.NewWriter(&entry)
+	zw.Write(data)
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	p.entries = append(p.entries, packEntry{raw: raw, crc: crc32.ChecksumIEEE(entry.Bytes()), offset: p.offset})
+	return p.write(entry.Bytes())
+}
+
+// finish ends the pack with its checksum, writes its index, and moves both into place as
+// pack-<checksum>.pack and .idx, returning the pack's path. The index is moved last, since git ignores
+// a pack until it has one.
+func (p *packWriter) finish() (string, error) {
+	checksum, err := p.end()
+	if err != nil {
+		p.abort()
+		return "", err
+	}
+	return p.install(checksum)
+}
+
+// install moves the ended pack, with the given checksum, into place along with an index of its entries.
+func (p *packWriter) install(checksum []byte) (string, error) {
+	if err := p.f.Sync(); err != nil {
+		p.abort()
+		return "", err
+	}
+	if err := p.f.Close(); err != nil {
+		os.Remove(p.f.Name())
+		return "", err
+	}
+
+	base := filepath.Join(p.objectsPath, "pack", "pack-"+hex.EncodeToString(checksum))
+	index, err := os.CreateTemp(filepath.Dir(base), "tmp_idx_")
+	if err != nil {
+		os.Remove(p.f.Name())
+		return "", err
+	}
+	defer os.Remove(index.Name()) // no-op once renamed into place
+	defer os.Remove(p.f.Name())
+
+	err = p.writeIndex(index, checksum)
+	if closeErr := index.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+
+	// Packs are immutable, and git creates them read only.
+	for _, name := range []string{p.f.Name(), index.Name()} {
+		if err := os.Chmod(name, 0o444); err != nil {
+			return "", err
+		}
+	}
+	if err := os.Rename(p.f.Name(), base+".pack"); err != nil {
+		return "", err
+	}
+	if err := os.Rename(index.Name(), base+".idx"); err != nil {
+		return "", err
+	}
+	return base + ".pack", nil
+}
+
+// writeIndex writes a version 2 index for the pack with the given checksum: a fan-out table counting
+// the objects by the first byte of their hash, then their hashes, CRCs, and offsets in hash order.
+func (p *packWriter) writeIndex(dst io.Writer, checksum []byte) error {
+	slices.SortFunc(p.entries, func(a, b packEntry) int {
+		return bytes.Compare(a.raw, b.raw)
+	})
+
+	sum := p.format.new()
+	w := bufio.NewWriter(io.MultiWriter(dst, sum))
+	put32 := func(n uint32) {
+		binary.Write(w, binary.BigEndian, n)
+	}
+
+	w.Write(packIndexSignature)
+	put32(2)
+	var fanout [256]uint32
+	for _, entry := range p.entries {
+		fanout[entry.raw[0]]++
+	}
+	total := uint32(0)
+	for _, n := range fanout {
+		total += n
+		put32(total)
+	}
+	for _, entry := range p.entries {
+		w.Write(entry.raw)
+	}
+	for _, entry := range p.entries {
+		put32(entry.crc)
+	}
+	var large []int64
+	for _, entry := range p.entries {
+		if entry.offset < 0x80000000 {
+			put32(uint32(entry.offset))
+			continue
+		}
+		put32(0x80000000 | uint32(len(large)))
+		large = append(large, entry.offset)
+	}
+	for _, offset := range large {
+		binary.Write(w, binary.BigEndian, uint64(offset))
+	}
+	w.Write(checksum)
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	_, err := dst.Write(sum.Sum(nil))
+	return err
+}
+
+// end ends the pack with its checksum, which it returns, and flushes what's buffered.
+func (p *packWriter) end() ([]byte, error) {
+	checksum := p.sum.Sum(nil)
+	if _, err := p.w.Write(checksum); err != nil {
+		return nil, err
+	}
+	return checksum, p.w.Flush()
+}
+
+// abort throws away the partly written pack, if it was going into an objects directory.
+func (p *packWriter) abort() {
+	if p.f != nil {
+		p.f.Close()
+		os.Remove(p.f.Name())
+	}
+}
+
+// storePack reads a pack from r that may be thin, with deltas against objects the repository already
+// has, and keeps it in the repository, like git index-pack --fix-thin. The pack is written to a
+// temporary file as it's read, and indexed from there, so it is never held in memory; the objects its
+// deltas build on are appended to it, so it stands on its own. It returns the hashes of every object
+// the pack holds, and how many of them the repository didn't have. A pack holding nothing new isn't kept.
+func (repo *Repository) storePack(r io.Reader) (map[string]bool, int, error) {
+	objectsPath := filepath.Join(repo.CommonDir, "objects")
+	dir := filepath.Join(objectsPath, "pack")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, 0, err
+	}
+	f, err := os.CreateTemp(dir, "tmp_pack_")
+	if err != nil {
+		return nil, 0, err
+	}
+	p := &packWriter{objectsPath: objectsPath, format: repo.Format, f: f}
+	size, err := io.Copy(f, r)
+	if err != nil {
+		p.abort()
+		return nil, 0, err
+	}
+
+	reader := &commitReader{objectsPath: objectsPath, format: repo.Format}
+	defer reader.Close()
+	objects, thin, err := indexPack(f, size, repo.Format, reader.raw)
+	if err != nil {
+		p.abort()
+		return nil, 0, err
+	}
+
+	packs, err := openPacks(objectsPath, repo.Format)
+	if err != nil {
+		p.abort()
+		return nil, 0, err
+	}
+	defer packs.Close()
+
+	held := map[string]bool{}
+	stored := 0
+	for _, object := range objects {
+		if held[object.hash] {
+			continue
+		}
+		held[object.hash] = true
+		crc, err := object.crc(f)
+		if err != nil {
+			p.abort()
+			return nil, 0, err
+		}
+		raw, _ := hex.DecodeString(object.hash)
+		p.entries = append(p.entries, packEntry{raw: raw, crc: crc, offset: object.offset})
+		if _, err := os.Stat(repo.objectPath(object.hash)); err != nil && !packs.has(object.hash) {
+			stored++
+		}
+	}
+	if stored == 0 {
+		p.abort()
+		return held, 0, nil
+	}
+
+	checksum, err := p.fixThin(size, thin, reader.raw)
+	if err != nil {
+		p.abort()
+		return nil, 0, err
+	}
+	if _, err := p.install(checksum); err != nil {
+		return nil, 0, err
+	}
+	return held, stored, nil
+}
+
+// fixThin appends the objects named by thin, read with read, to the pack of size bytes being kept,
+// and updates its object count and checksum, which it returns.
+func (p *packWriter) fixThin(size int64, thin []string, read func(hash string) (GitObjectKind, []byte, error)) ([]byte, error) {
+	hashSize := int64(p.format.HexSize() / 2)
+	if len(thin) == 0 {
+		checksum := make([]byte, hashSize)
+		_, err := p.f.ReadAt(checksum, size-hashSize)
+		return checksum, err
+	}
+
+	p.offset = size - hashSize
+	if _, err := p.f.Seek(p.offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	p.w, p.sum = bufio.NewWriter(p.f), p.format.new()
+	for _, hash := range thin {
+		kind, data, err := read(hash)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.add(hash, kind, data); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.w.Flush(); err != nil {
+		return nil, err
+	}
+
+	var header [12]byte
+	if _, err := p.f.ReadAt(header[:], 0); err != nil {
+		return nil, err
+	}
+	count := binary.BigEndian.Uint32(header[8:]) + uint32(len(thin))
+	binary.BigEndian.PutUint32(header[8:], count)
+	if _, err := p.f.WriteAt(header[:], 0); err != nil {
+		return nil, err
+	}
+	sum := p.format.new()
+	if _, err := io.Copy(sum, io.NewSectionReader(p.f, 0, p.offset)); err != nil {
+		return nil, err
+	}
+	checksum := sum.Sum(nil)
+	_, err := p.f.WriteAt(checksum, p.offset)
+	return checksum, err
+}
+
+// deltaBlock is how many bytes [makeDelta] looks for in the base at a time, and so the shortest stretch
+// it copies from it.
+const deltaBlock = 16
+
+// maxDeltaSource is the largest object a delta is made for, or against, since both are held in memory.
+const maxDeltaSource = 16 << 20
+
+// makeDelta returns a delta that turns base into target, in the form [applyDelta] reads: stretches of
+// target found in base are copied from it, and the rest is inserted. Every block of base is indexed, and
+// a match is extended as far as it goes either way, which finds the edits a new version of a file makes
+// to the old, if not the smallest delta possible.
+func makeDelta(base, target []byte) []byte {
+	delta := binary.AppendUvarint(nil, uint64(len(base)))
+	delta = binary.AppendUvarint(delta, uint64(len(target)))
+
+	index := map[string]int{}
+	for i := 0; i+deltaBlock <= len(base); i += deltaBlock {
+		if _, ok := index[string(base[i:i+deltaBlock])]; !ok {
+			index[string(base[i:i+deltaBlock])] = i
+		}
+	}
+
+	inserted := 0 // Where the stretch of target waiting to be inserted starts
+	insert := func(end int) {
+		for inserted < end {
+			n := min(end-inserted, 0x7f)
+			delta = append(append(delta, byte(n)), target[inserted:inserted+n]...)
+			inserted += n
+		}
+	}
+	for i := 0; i+deltaBlock <= len(target); {
+		offset, ok := index[string(target[i:i+deltaBlock])]
+		if !ok {
+			i++
+			continue
+		}
+		start, end := i, i+deltaBlock
+		for start > inserted && offset > 0 && base[offset-1] == target[start-1] {
+			start--
+			offset--
+		}
+		for end < len(target) && offset+end-start < len(base) && base[offset+end-start] == target[end] {
+			end++
+		}
+		insert(start)
+
+		// A copy takes up to 4 bytes of offset and 3 of size, each left out when it's zero.
+		for start < end {
+			n := min(end-start, 0xffffff)
+			op := len(delta)
+			delta = append(delta, 0x80)
+			for b := range 4 {
+				if v := byte(offset >> (8 * b)); v != 0 {
+					delta[op] |= 1 << b
+					delta = append(delta, v)
+				}
+			}
+			for b := range 3 {
+				if v := byte(n >> (8 * b)); v != 0 {
+					delta[op] |= 1 << (4 + b)
+					delta = append(delta, v)
+				}
+			}
+			start += n
+			offset += n
+		}
+		i, inserted = end, end
+	}
+	insert(len(target))
+	return delta
+}
@@ -0,0 +1,45 @@
+package git
+
+import (
+	"errors"
+	"io/fs"
+)
+
+// CommitsTouching returns the commits that change anything at or below the slash separated path,
+// keeping their order, like git log -- path. Each commit is compared with its first parent, so a merge
+// only counts when it brings changes to path into the first parent's history. A commit whose parent is
+// missing, as at the edge of a shallow clone, is compared with an empty tree.
+func (repo *Repository) CommitsTouching(commits []Commit, path string) ([]Commit, error) {
+	r, err := newCommitReader(repo)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var touching []Commit
+	for _, commit := range commits {
+		entry, ok, err := r.entryAt(commit.Tree, path)
+		if err != nil {
+			return nil, err
+		}
+
+		var parentEntry TreeEntry
+		parentOk := false
+		if len(commit.Parents) > 0 {
+			parent, isCommit, err := r.read(commit.Parents[0])
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return nil, err
+			}
+			if err == nil && isCommit {
+				if parentEntry, parentOk, err = r.entryAt(parent.Tree, path); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		if ok != parentOk || entry != parentEntry {
+			touching = append(touching, commit)
+		}
+	}
+	return touching, nil
+}
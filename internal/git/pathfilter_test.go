@@ -0,0 +1,53 @@
+package git
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCommitsTouching(t *testing.T) {
+	newTestRepo(t)
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := repo.Encoder()
+	sig := Signature{Name: "Jane", Email: "jane@example.com", Time: time.Unix(1703123457, 0)}
+	var parents []string
+	commit := func(files map[string]string, message string) Commit {
+		t.Helper()
+		b := NewTreeBuilder(e)
+		for path, content := range files {
+			hash, err := e.Encode(BlobObject, []byte(content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			b.Add(path, ModeFile, hash)
+		}
+		tree, err := b.Write()
+		if err != nil {
+			t.Fatal(err)
+		}
+		hash, err := repo.CreateCommit(tree, parents, sig, sig, message)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := Commit{Hash: hash, Tree: tree, Parents: parents, Message: message}
+		parents = []string{hash}
+		return c
+	}
+
+	first := commit(map[string]string{"services/a/main.go": "a", "services/b/main.go": "b"}, "add services")
+	second := commit(map[string]string{"services/a/main.go": "a", "services/b/main.go": "b2"}, "change b")
+	third := commit(map[string]string{"services/a/main.go": "a2", "services/b/main.go": "b2"}, "change a")
+	fourth := commit(map[string]string{"services/b/main.go": "b2"}, "remove a")
+
+	touching, err := repo.CommitsTouching([]Commit{fourth, third, second, first}, "services/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(touching) != 3 || touching[0].Hash != fourth.Hash || touching[1].Hash != third.Hash || touching[2].Hash != first.Hash {
+		t.Fatalf("expected the commits adding, changing, and removing services/a, got %v", touching)
+	}
+}
@@ -0,0 +1,92 @@
+package git
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+var ErrBadPktLine = errors.New("malformed pkt-line")
+
+// maxPktLen is the largest pkt-line git sends, counting its four byte length.
+const maxPktLen = 65520
+
+// A pktKind tells a pkt-line carrying data apart from the special packets that delimit messages.
+type pktKind int
+
+const (
+	pktData        pktKind = iota
+	pktFlush               // 0000, which ends a message
+	pktDelim               // 0001, which separates the sections of a protocol v2 message
+	pktResponseEnd         // 0002, which ends a protocol v2 response over a stateless transport
+)
+
+// pktWriter buffers a message of pkt-lines, git's wire framing in which each line is preceded by its
+// length as four hex digits.
+type pktWriter struct {
+	buf []byte
+}
+
+// line appends s, which is a whole line including any trailing newline, as one pkt-line.
+func (w *pktWriter) line(s string) {
+	w.buf = fmt.Appendf(w.buf, "%04x%s", len(s)+4, s)
+}
+
+func (w *pktWriter) delim() {
+	w.buf = append(w.buf, "0001"...)
+}
+
+func (w *pktWriter) flush() {
+	w.buf = append(w.buf, "0000"...)
+}
+
+// pktReader reads the pkt-lines of a response one at a time.
+type pktReader struct {
+	r    *bufio.Reader
+	data []byte
+}
+
+func newPktReader(r io.Reader) *pktReader {
+	return &pktReader{r: bufio.NewReader(r), data: make([]byte, maxPktLen)}
+}
+
+// next reads the next packet. The data is only valid until the following call.
+func (p *pktReader) next() (pktKind, []byte, error) {
+	var length [4]byte
+	if _, err := io.ReadFull(p.r, length[:]); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, nil, err
+	}
+	n, err := strconv.ParseUint(string(length[:]), 16, 16)
+	if err != nil {
+		return 0, nil, fmt.Errorf("%w: length %q", ErrBadPktLine, length)
+	}
+	switch {
+	case n < 3:
+		return pktKind(n + 1), nil, nil
+	case n == 3 || n > maxPktLen:
+		return 0, nil, fmt.Errorf("%w: length %d", ErrBadPktLine, n)
+	}
+	data := p.data[:n-4]
+	if _, err := io.ReadFull(p.r, data); err != nil {
+		return 0, nil, err
+	}
+	return pktData, data, nil
+}
+
+// line reads the next packet as a line of text without its trailing newline, returning "" and false
+// for a flush, delimiter, or response end packet.
+func (p *pktReader) line() (string, bool, error) {
+	kind, data, err := p.next()
+	if err != nil || kind != pktData {
+		return "", false, err
+	}
+	if n := len(data); n > 0 && data[n-1] == '\n' {
+		data = data[:n-1]
+	}
+	return string(data), true, nil
+}
@@ -0,0 +1,143 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var ErrBadStatus = errors.New("cannot parse git status output")
+
+// WorkTreeStatus is where the checked out branch stands and how the index and work tree differ from it,
+// as git status --porcelain=v2 --branch reports.
+type WorkTreeStatus struct {
+	Branch  BranchStatus
+	Entries []StatusEntry // Every path that differs, as [Repository.Status] lists them
+}
+
+// BranchStatus is the checked out branch and how it compares with its upstream.
+type BranchStatus struct {
+	Head     string // The branch checked out, or "" when HEAD is detached
+	Commit   string // The commit HEAD is at, or "" on a branch without commits yet
+	Upstream string // The upstream, as git shows it, e.g. origin/main, or "" if there is none
+	Ahead    int    // Commits on the branch its upstream doesn't have
+	Behind   int    // Commits on the upstream the branch doesn't have
+}
+
+// Dirty reports whether any tracked path differs from HEAD, like [Client.IsBranchDirty]. Untracked paths
+// don't count.
+func (s WorkTreeStatus) Dirty() bool {
+	for _, entry := range s.Entries {
+		if entry.Unstaged != StatusUntracked {
+			return true
+		}
+	}
+	return false
+}
+
+// Conflicts returns the paths with unresolved merge conflicts.
+func (s WorkTreeStatus) Conflicts() []string {
+	var paths []string
+	for _, entry := range s.Entries {
+		if entry.Staged == StatusConflicted {
+			paths = append(paths, entry.Path)
+		}
+	}
+	return paths
+}
+
+// ParseStatusV2 parses the output of git status --porcelain=v2 --branch -z, in which every line ends in
+// a NUL and a renamed path is followed by the path it was renamed from. Ignored paths, which --ignored
+// adds, are left out, and a type change is reported as a modification.
+func ParseStatusV2(data []byte) (WorkTreeStatus, error) {
+	var status WorkTreeStatus
+	fields := bytes.Split(data, []byte{0})
+	for i := 0; i < len(fields); i++ {
+		line := string(fields[i])
+		if line == "" {
+			continue
+		}
+		bad := fmt.Errorf("%w: %q", ErrBadStatus, line)
+
+		if header, ok := strings.CutPrefix(line, "# "); ok {
+			key, value, _ := strings.Cut(header, " ")
+			switch key {
+			case "branch.oid":
+				if value != "(initial)" {
+					status.Branch.Commit = value
+				}
+			case "branch.head":
+				if value != "(detached)" {
+					status.Branch.Head = value
+				}
+			case "branch.upstream":
+				status.Branch.Upstream = value
+			case "branch.ab":
+				ahead, behind, ok := strings.Cut(value, " ")
+				var err error
+				if !ok {
+					return WorkTreeStatus{}, bad
+				}
+				if status.Branch.Ahead, err = strconv.Atoi(strings.TrimPrefix(ahead, "+")); err != nil {
+					return WorkTreeStatus{}, bad
+				}
+				if status.Branch.Behind, err = strconv.Atoi(strings.TrimPrefix(behind, "-")); err != nil {
+					return WorkTreeStatus{}, bad
+				}
+			}
+			continue
+		}
+
+		// Changed entries carry their path after a fixed number of fields, since the path may contain spaces.
+		var entry StatusEntry
+		switch line[0] {
+		case '1', '2', 'u':
+			n := map[byte]int{'1': 8, '2': 9, 'u': 10}[line[0]]
+			parts := strings.SplitN(line, " ", n+1)
+			if len(parts) != n+1 || len(parts[1]) != 2 {
+				return WorkTreeStatus{}, bad
+			}
+			entry.Path = parts[n]
+			if line[0] == 'u' {
+				entry.Staged = StatusConflicted
+				break
+			}
+			entry.Staged, entry.Unstaged = porcelainStatus(parts[1][0]), porcelainStatus(parts[1][1])
+			if line[0] == '2' {
+				i++
+				if i == len(fields) {
+					return WorkTreeStatus{}, bad
+				}
+				entry.From = string(fields[i])
+			}
+		case '?':
+			entry.Path, entry.Unstaged = strings.TrimPrefix(line, "? "), StatusUntracked
+		case '!':
+			continue
+		default:
+			return WorkTreeStatus{}, bad
+		}
+		status.Entries = append(status.Entries, entry)
+	}
+	return status, nil
+}
+
+// porcelainStatus is the FileStatus a porcelain status letter stands for. A copy is a new path.
+func porcelainStatus(code byte) FileStatus {
+	switch code {
+	case 'M', 'T':
+		return StatusModified
+	case 'A', 'C':
+		return StatusAdded
+	case 'D':
+		return StatusDeleted
+	case 'R':
+		return StatusRenamed
+	case 'U':
+		return StatusConflicted
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,65 @@
+package git
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseStatusV2(t *testing.T) {
+	output := strings.Join([]string{
+		"# branch.oid 0f3e1c2a9b8d7e6f5a4b3c2d1e0f9a8b7c6d5e4f",
+		"# branch.head feature",
+		"# branch.upstream origin/feature",
+		"# branch.ab +2 -1",
+		"1 .M N... 100644 100644 100644 aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa src/main.go",
+		"1 A. N... 000000 100644 100644 0000000000000000000000000000000000000000 bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb notes with spaces.txt",
+		"1 D. N... 100644 000000 000000 cccccccccccccccccccccccccccccccccccccccc 0000000000000000000000000000000000000000 gone.txt",
+		"2 R. N... 100644 100644 100644 dddddddddddddddddddddddddddddddddddddddd dddddddddddddddddddddddddddddddddddddddd R100 docs/new.md",
+		"docs/old.md",
+		"u UU N... 100644 100644 100644 100644 eeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee ffffffffffffffffffffffffffffffffffffffff 1111111111111111111111111111111111111111 conflict.txt",
+		"? scratch.txt",
+		"! build/out.o",
+		"",
+	}, "\x00")
+	status, err := ParseStatusV2([]byte(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (BranchStatus{Head: "feature", Commit: "0f3e1c2a9b8d7e6f5a4b3c2d1e0f9a8b7c6d5e4f", Upstream: "origin/feature", Ahead: 2, Behind: 1}); status.Branch != want {
+		t.Errorf("Branch = %+v, want %+v", status.Branch, want)
+	}
+	want := []StatusEntry{
+		{Path: "src/main.go", Unstaged: StatusModified},
+		{Path: "notes with spaces.txt", Staged: StatusAdded},
+		{Path: "gone.txt", Staged: StatusDeleted},
+		{Path: "docs/new.md", Staged: StatusRenamed, From: "docs/old.md"},
+		{Path: "conflict.txt", Staged: StatusConflicted},
+		{Path: "scratch.txt", Unstaged: StatusUntracked},
+	}
+	if !slices.Equal(status.Entries, want) {
+		t.Errorf("Entries = %+v, want %+v", status.Entries, want)
+	}
+	if !status.Dirty() {
+		t.Error("Dirty() = false, want true")
+	}
+	if conflicts := status.Conflicts(); !slices.Equal(conflicts, []string{"conflict.txt"}) {
+		t.Errorf("Conflicts() = %q", conflicts)
+	}
+
+	// A detached HEAD on an unborn branch, with nothing but untracked files.
+	status, err = ParseStatusV2([]byte("# branch.oid (initial)\x00# branch.head (detached)\x00? a.txt\x00"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Branch != (BranchStatus{}) || status.Dirty() {
+		t.Errorf("ParseStatusV2() = %+v, want no branch and a clean tree", status)
+	}
+
+	for _, bad := range []string{"1 .M N... 100644 src/main.go\x00", "# branch.ab +x -1\x00", "2 R. N... 100644 100644 100644 d d R100 new.md", "X what\x00"} {
+		if _, err := ParseStatusV2([]byte(bad)); !errors.Is(err, ErrBadStatus) {
+			t.Errorf("ParseStatusV2(%q) = %v, want %v", bad, err, ErrBadStatus)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	origHead  = "ORIG_HEAD"
+	fetchHead = "FETCH_HEAD"
+)
+
+// isPseudoRef reports whether name is one of the refs git keeps directly in the git directory, like
+// ORIG_HEAD or MERGE_HEAD, rather than under refs/.
+func isPseudoRef(name string) bool {
+	if name == "HEAD" || !strings.HasSuffix(name, "_HEAD") {
+		return false
+	}
+	for _, c := range name {
+		if c != '_' && (c < 'A' || c > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// resolvePseudoRef returns the commit the pseudo ref name points to. FETCH_HEAD, and MERGE_HEAD during
+// an octopus merge, list several commits, of which git uses the first.
+func (repo *Repository) resolvePseudoRef(name string) (string, error) {
+	if name == fetchHead {
+		fetched, err := repo.FetchHead()
+		if err != nil {
+			return "", err
+		}
+		if len(fetched) == 0 {
+			return "", fmt.Errorf("%w: %s", ErrRefNotFound, name)
+		}
+		return fetched[0].Hash, nil
+	}
+	target, err := repo.resolveSymbolic(name)
+	if err != nil {
+		return "", err
+	}
+	target, _, _ = strings.Cut(target, "\n")
+	return strings.TrimSpace(target), nil
+}
+
+// OrigHead returns the commit HEAD was on before the last operation that moved it a long way, like a
+// rebase, merge, or reset, which git records in ORIG_HEAD so it can be undone. Without one it fails
+// with [ErrRefNotFound].
+func (repo *Repository) OrigHead() (string, error) {
+	return repo.resolvePseudoRef(origHead)
+}
+
+// FetchedRef is a ref git fetch recorded in FETCH_HEAD.
+type FetchedRef struct {
+	Hash     string
+	ForMerge bool   // Set for the refs git pull would merge, unset for those marked not-for-merge
+	Kind     string // "branch", "tag", or "" for other refs, and for a remote's HEAD
+	Name     string // The ref as the remote names it, e.g. main, v1.0, or refs/pull/1/head
+	Remote   string // Where it was fetched from, as a URL or path
+}
+
+// FetchHead returns what the last git fetch fetched, in the order it wrote them to FETCH_HEAD, or
+// nothing if there is no FETCH_HEAD.
+func (repo *Repository) FetchHead() ([]FetchedRef, error) {
+	data, err := os.ReadFile(filepath.Join(repo.GitDir, fetchHead))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var fetched []FetchedRef
+	for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		// Each line is "<hash>\t[not-for-merge]\t<description>", where the description is like
+		// "branch 'main' of https://example.com/repo", or just the URL for the remote's HEAD.
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 || !isFullHash(fields[0]) {
+			return nil, fmt.Errorf("git: malformed FETCH_HEAD line: %q", line)
+		}
+		ref := FetchedRef{Hash: fields[0], ForMerge: fields[1] != "not-for-merge", Remote: fields[2]}
+		desc := fields[2]
+		for _, kind := range []string{"branch ", "tag ", ""} {
+			quoted, ok := strings.CutPrefix(desc, kind+"'")
+			if !ok {
+				continue
+			}
+			if name, remote, ok := strings.Cut(quoted, "' of "); ok {
+				ref.Kind, ref.Name, ref.Remote = strings.TrimSpace(kind), name, remote
+			}
+			break
+		}
+		fetched = append(fetched, ref)
+	}
+	return fetched, nil
+}
+
+// MergeMsg is the message git prepared in MERGE_MSG for the commit that will conclude a merge,
+// cherry-pick, revert, or rebase step that stopped on conflicts.
+type MergeMsg struct {
+	Message   string   // The message, without the comments git adds for the user
+	Conflicts []string // The paths git listed as conflicted
+}
+
+// MergeMessage reads MERGE_MSG, and reports whether there is one.
+func (repo *Repository) MergeMessage() (MergeMsg, bool, error) {
+	data, err := os.ReadFile(filepath.Join(repo.GitDir, mergeMsg))
+	if errors.Is(err, fs.ErrNotExist) {
+		return MergeMsg{}, false, nil
+	}
+	if err != nil {
+		return MergeMsg{}, false, err
+	}
+
+	msg := MergeMsg{Message: stripComments(string(data))}
+	inConflicts := false
+	for _, line := range strings.Split(string(data), "\n") {
+		switch {
+		case line == "# Conflicts:":
+			inConflicts = true
+		case inConflicts && strings.HasPrefix(line, "#\t"):
+			msg.Conflicts = append(msg.Conflicts, strings.TrimPrefix(line, "#\t"))
+		case inConflicts && line != "#":
+			inConflicts = false
+		}
+	}
+	return msg, true, nil
+}
+
+// ResetHard moves the current branch, or HEAD when detached, to rev and makes the index and work tree
+// match it, like git reset --hard, recording who and reason in the reflog. The commit HEAD was on is
+// kept in ORIG_HEAD, so the reset can be undone in turn. Uncommitted changes to tracked files would be
+// lost, so they stop it with [ErrDirtyWorkTree]; untracked files are left alone.
+func (repo *Repository) ResetHard(rev string, who Signature, reason string) error {
+	if op := repo.activeOperation(); op != "" {
+		return fmt.Errorf("%w: %s exists", ErrOperationActive, op)
+	}
+	if err := repo.requireClean(); err != nil {
+		return err
+	}
+	target, err := repo.ResolveRevision(rev)
+	if err != nil {
+		return err
+	}
+	if _, err := repo.ReadCommit(target); err != nil {
+		return err
+	}
+	head, err := repo.ResolveRevision("HEAD")
+	if err != nil {
+		return err
+	}
+
+	ref := "HEAD"
+	if symbolic, err := repo.resolveRef("HEAD"); err == nil && strings.HasPrefix(symbolic, "ref: ") {
+		ref = strings.TrimPrefix(symbolic, "ref: ")
+	}
+	if err := repo.UpdateRef(ref, head, target, who, reason); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(repo.GitDir, origHead), []byte(head+"\n"), 0o644); err != nil {
+		return err
+	}
+
+	idx, err := repo.Index()
+	if err != nil {
+		return err
+	}
+	if err := repo.resetToHead(idx); err != nil {
+		return err
+	}
+	return repo.WriteIndex(idx)
+}
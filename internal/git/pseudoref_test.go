@@ -0,0 +1,119 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestFetchHead(t *testing.T) {
+	gitDir := newTestRepo(t)
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fetched, err := repo.FetchHead(); err != nil || fetched != nil {
+		t.Fatalf("FetchHead without a fetch = %v, %v", fetched, err)
+	}
+	if _, err := repo.ResolveRevision("FETCH_HEAD"); !errors.Is(err, ErrUnknownRevision) {
+		t.Errorf("resolving a missing FETCH_HEAD: got %v", err)
+	}
+
+	main := writeTestCommit(t, gitDir, "main")
+	tag := writeTestCommit(t, gitDir, "tag")
+	pull := writeTestCommit(t, gitDir, "pull")
+	data := main + "\t\tbranch 'main' of https://example.com/repo\n" +
+		tag + "\tnot-for-merge\ttag 'v1.0' of https://example.com/repo\n" +
+		pull + "\tnot-for-merge\t'refs/pull/1/head' of https://example.com/repo\n" +
+		main + "\tnot-for-merge\thttps://example.com/other\n"
+	if err := os.WriteFile(filepath.Join(gitDir, "FETCH_HEAD"), []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	fetched, err := repo.FetchHead()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []FetchedRef{
+		{Hash: main, ForMerge: true, Kind: "branch", Name: "main", Remote: "https://example.com/repo"},
+		{Hash: tag, Kind: "tag", Name: "v1.0", Remote: "https://example.com/repo"},
+		{Hash: pull, Name: "refs/pull/1/head", Remote: "https://example.com/repo"},
+		{Hash: main, Remote: "https://example.com/other"},
+	}
+	if !slices.Equal(fetched, want) {
+		t.Errorf("FetchHead = %+v, want %+v", fetched, want)
+	}
+	if hash, err := repo.ResolveRevision("FETCH_HEAD"); err != nil || hash != main {
+		t.Errorf("FETCH_HEAD resolved to %s, %v, want %s", hash, err, main)
+	}
+}
+
+func TestMergeMessage(t *testing.T) {
+	gitDir := newTestRepo(t)
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, err := repo.MergeMessage(); ok || err != nil {
+		t.Fatalf("MergeMessage without MERGE_MSG = %v, %v", ok, err)
+	}
+
+	data := "Merge branch 'feature'\n\n# Conflicts:\n#\ta.txt\n#\tdir/b.txt\n#\n# It looks like you may be committing a merge.\n"
+	if err := os.WriteFile(filepath.Join(gitDir, "MERGE_MSG"), []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	msg, ok, err := repo.MergeMessage()
+	if err != nil || !ok || msg.Message != "Merge branch 'feature'" || !slices.Equal(msg.Conflicts, []string{"a.txt", "dir/b.txt"}) {
+		t.Errorf("MergeMessage = %+v, %v, %v", msg, ok, err)
+	}
+}
+
+func TestResetHard(t *testing.T) {
+	newTestRepo(t)
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stageTestFiles(t, repo, map[string]string{"f.txt": "one\n"})
+	commitTestIndex(t, repo)
+	first, _ := repo.ResolveRevision("HEAD")
+	if _, err := repo.OrigHead(); !errors.Is(err, ErrRefNotFound) {
+		t.Errorf("OrigHead before any reset: got %v", err)
+	}
+
+	who := Signature{Name: "Ann", Email: "ann@example.com", Time: time.Unix(1703130000, 0)}
+	stageTestFiles(t, repo, map[string]string{"f.txt": "two\n", "new.txt": "new\n"})
+	idx, _ := repo.Index()
+	second, err := repo.commitIndex(idx, first, who, who, "second", "commit")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeTestFiles(t, map[string]string{"f.txt": "dirty\n"})
+	if err := repo.ResetHard(first, who, "reset: moving to "+first); !errors.Is(err, ErrDirtyWorkTree) {
+		t.Fatalf("resetting over uncommitted changes: got %v", err)
+	}
+	writeTestFiles(t, map[string]string{"f.txt": "two\n"})
+
+	if err := repo.ResetHard(first, who, "reset: moving to "+first); err != nil {
+		t.Fatal(err)
+	}
+	if head, _ := repo.ResolveRevision("main"); head != first {
+		t.Errorf("main is at %s after the reset, want %s", head, first)
+	}
+	if data, _ := os.ReadFile("f.txt"); string(data) != "one\n" {
+		t.Errorf("f.txt = %q after the reset", data)
+	}
+	if _, err := os.Stat("new.txt"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("new.txt survived the reset: %v", err)
+	}
+	if orig, err := repo.OrigHead(); err != nil || orig != second {
+		t.Errorf("OrigHead = %s, %v, want %s", orig, err, second)
+	}
+	if orig, err := repo.ResolveRevision("ORIG_HEAD"); err != nil || orig != second {
+		t.Errorf("ORIG_HEAD resolved to %s, %v, want %s", orig, err, second)
+	}
+}
@@ -0,0 +1,432 @@
+package git
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+var ErrPushRejected = errors.New("push rejected")
+
+// PushResult is what [Repository.Push] did.
+type PushResult struct {
+	Updated  []RefUpdate     // Refs on the remote created, moved, or deleted, which leaves New the zero hash
+	Rejected []PushRejection // Refs on the remote left alone
+	Objects  int             // How many objects were sent
+}
+
+// PushRejection is a ref on the remote that a push didn't update, and why.
+type PushRejection struct {
+	RefUpdate
+	Reason string // e.g. non-fast-forward, or what the remote said
+}
+
+// Push updates refs on the remote, which is either the name of a configured remote or a URL, from local
+// ones as refspecs say, like git push.
+//
+// A refspec src:dst sets the remote's dst to what src resolves to locally, where src is a ref or any
+// revision, and dst is src's full name when left out. A dst that isn't a full name is the remote's branch
+// or tag of that name, or else a branch, or a tag if src is one. The refspec :dst deletes dst, which fails
+// with [ErrRemoteRefNotFound] if the remote doesn't have it. Wildcards aren't supported.
+//
+// A ref is only moved when the update fast-forwards it, or its refspec is forced with +, and a tag the
+// remote has is only replaced when forced, as git push does; anything else is rejected, along with the
+// updates the remote itself refuses. Every update names what the remote's ref pointed to when the push
+// started, so a ref someone else pushed to in the meantime is left alone too.
+//
+// The objects the remote doesn't have are sent in a single pack, leaving out everything reachable from
+// the refs it advertised. Once the remote has taken the updates, the remote-tracking refs that a
+// configured remote's fetch refspecs map them to are moved along, recorded in the reflog as made by who.
+// The remote is reached as with [Repository.Fetch], at remote.<name>.pushurl if one is set.
+func (repo *Repository) Push(client *http.Client, remote string, refspecs []string, who Signature) (PushResult, error) {
+	defer phase("push")()
+	config, err := repo.lookupRemote(remote)
+	if err != nil {
+		return PushResult{}, err
+	}
+	conn, err := dialReceivePack(client, cmp.Or(slices.Concat(config.PushURLs, config.URLs)...), repo.Format)
+	if err != nil {
+		return PushResult{}, err
+	}
+	defer conn.Close()
+
+	zero := strings.Repeat("0", repo.Format.HexSize())
+	var result PushResult
+	var commands []pushCommand
+	var tips []string
+	for _, spec := range refspecs {
+		rest, force := strings.CutPrefix(spec, "+")
+		src, dst, _ := strings.Cut(rest, ":")
+		if strings.Contains(rest, "*") || src == "" && dst == "" {
+			return PushResult{}, fmt.Errorf("%w: %q", ErrBadRefspec, spec)
+		}
+
+		command := pushCommand{new: zero}
+		srcRef := ""
+		if src != "" {
+			if srcRef, command.new, err = repo.pushSource(src); err != nil {
+				return PushResult{}, err
+			}
+			if dst == "" && srcRef == "" {
+				return PushResult{}, fmt.Errorf("%w: %q names no ref to push to", ErrBadRefspec, spec)
+			}
+		}
+		command.ref = conn.qualify(cmp.Or(dst, srcRef), srcRef)
+		command.old = cmp.Or(conn.refs[command.ref], zero)
+		update := RefUpdate{Name: command.ref, Old: command.old, New: command.new}
+		if src == "" && command.old == zero {
+			return PushResult{}, fmt.Errorf("%w: %s %s", ErrRemoteRefNotFound, remote, command.ref)
+		}
+		if command.old == command.new {
+			continue
+		}
+
+		reason, err := repo.refusePush(conn, command, force)
+		if err != nil {
+			return PushResult{}, err
+		}
+		if reason != "" {
+			result.Rejected = append(result.Rejected, PushRejection{RefUpdate: update, Reason: reason})
+			continue
+		}
+		commands = append(commands, command)
+		if command.new != zero {
+			tips = append(tips, command.new)
+		}
+	}
+	if len(commands) == 0 {
+		return result, nil
+	}
+
+	// The remote expects a pack unless it is only asked to delete refs.
+	var pack io.Reader
+	if len(tips) > 0 {
+		_, noThin := conn.capabilities["no-thin"]
+		haves := slices.Concat(slices.Collect(maps.Values(conn.refs)), conn.haves)
+		stream, objects, err := repo.pushPack(tips, haves, !noThin)
+		if err != nil {
+			return PushResult{}, err
+		}
+		defer stream.Close()
+		pack, result.Objects = stream, objects
+	}
+	refused, err := conn.push(commands, pack)
+	if err != nil {
+		return PushResult{}, err
+	}
+
+	var specs []Refspec
+	for _, value := range config.Fetch {
+		spec, err := ParseRefspec(value)
+		if err != nil {
+			return result, err
+		}
+		specs = append(specs, spec)
+	}
+	for _, command := range commands {
+		update := RefUpdate{Name: command.ref, Old: command.old, New: command.new}
+		if reason, ok := refused[command.ref]; ok {
+			result.Rejected = append(result.Rejected, PushRejection{RefUpdate: update, Reason: reason})
+			continue
+		}
+		result.Updated = append(result.Updated, update)
+		if command.new == zero || excluded(specs, command.ref) {
+			continue
+		}
+		for _, spec := range specs {
+			if tracking, ok := spec.Map(command.ref); ok && !spec.Negative && tracking != "" {
+				if err := repo.UpdateRef(tracking, "", command.new, who, "update by push"); err != nil {
+					return result, err
+				}
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
+// pushSource resolves src, the source of a push refspec, returning the full name of the ref it names, or ""
+// for a revision that isn't a ref, and the object it points to. HEAD names the checked-out branch.
+func (repo *Repository) pushSource(src string) (string, string, error) {
+	candidates := []string{src, "refs/heads/" + src, "refs/tags/" + src}
+	if src == "HEAD" {
+		head, err := repo.resolveRef("HEAD")
+		if err != nil {
+			return "", "", err
+		}
+		candidates = []string{strings.TrimPrefix(head, "ref: ")}
+	} else if strings.HasPrefix(src, "refs/") {
+		candidates = candidates[:1]
+	}
+	for _, name := range candidates {
+		if !strings.HasPrefix(name, "refs/") {
+			continue
+		}
+		hash, err := repo.resolveSymbolic(name)
+		if err == nil {
+			return name, hash, nil
+		}
+		if !errors.Is(err, ErrRefNotFound) {
+			return "", "", err
+		}
+	}
+	hash, err := repo.ResolveRevision(src)
+	return "", hash, err
+}
+
+// qualify turns dst, where a push goes, into a full ref name: the remote's branch or tag of that name, or
+// else a new branch, or a new tag when srcRef, the local ref pushed, is one.
+func (c *receivePack) qualify(dst, srcRef string) string {
+	if strings.HasPrefix(dst, "refs/") {
+		return dst
+	}
+	for _, prefix := range []string{"refs/heads/", "refs/tags/"} {
+		if _, ok := c.refs[prefix+dst]; ok {
+			return prefix + dst
+		}
+	}
+	if strings.HasPrefix(srcRef, "refs/tags/") {
+		return "refs/tags/" + dst
+	}
+	return "refs/heads/" + dst
+}
+
+// refusePush returns why command shouldn't be sent, or "" if it can be: a remote that doesn't allow
+// deleting refs, a tag that exists on the remote, a ref that has moved to commits the repository doesn't
+// have yet, or one that wouldn't fast-forward. Only the remote's permission counts when forced.
+func (repo *Repository) refusePush(conn *receivePack, command pushCommand, force bool) (string, error) {
+	if isZeroHash(command.new) {
+		if _, ok := conn.capabilities["delete-refs"]; !ok {
+			return "the remote doesn't allow deleting refs", nil
+		}
+		return "", nil
+	}
+	if force || isZeroHash(command.old) {
+		return "", nil
+	}
+	if strings.HasPrefix(command.ref, "refs/tags/") {
+		return "already exists", nil
+	}
+	if ok, err := repo.hasObject(command.old); err != nil {
+		return "", err
+	} else if !ok {
+		return "fetch first", nil
+	}
+	base, err := repo.MergeBase(command.old, command.new)
+	if err != nil && !errors.Is(err, ErrNoMergeBase) {
+		return "", err
+	}
+	if base != command.old {
+		return "non-fast-forward", nil
+	}
+	return "", nil
+}
+
+// pushPack returns a pack of the objects reachable from tips, commits or annotated tags of them, that
+// aren't reachable from haves, which is what a remote with haves needs to take tips, and how many objects
+// are in it. Haves the repository doesn't have are ignored. Like [Repository.CreateBundle], only the trees
+// of the commits the new ones build on are taken as known, and history is only walked as far back as
+// the new commits go.
+//
+// The pack is written as it's read, rather than held in memory, and must be closed. A new version of a
+// file or directory is sent as a delta against the old one where that is much smaller, and if thin is
+// set, the old one may be left out of the pack for the remote to supply, as git push does.
+func (repo *Repository) pushPack(tips, haves []string, thin bool) (io.ReadCloser, int, error) {
+	r := &commitReader{objectsPath: filepath.Join(repo.CommonDir, "objects"), format: repo.Format}
+	objects, err := repo.pushObjects(r, tips, haves)
+	if err != nil {
+		r.Close()
+		return nil, 0, err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		defer r.Close()
+		pw.CloseWithError(writeDeltaPack(pw, r, repo.Format, objects, thin))
+	}()
+	return pr, len(objects), nil
+}
+
+// deltaObject is an object to send in a pack, and the object it is likely a small change to, if any.
+type deltaObject struct {
+	hash, base string
+}
+
+// pushObjects returns the objects [Repository.pushPack] sends, oldest commit first, so that the objects
+// a commit changes come before the changes to them.
+func (repo *Repository) pushObjects(r *commitReader, tips, haves []string) ([]deltaObject, error) {
+	shallow, err := readShallow(repo.CommonDir)
+	if err != nil {
+		return nil, err
+	}
+
+	held := map[string]bool{}
+	var basis []string
+	for _, hash := range haves {
+		if ok, err := repo.hasObject(hash); err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
+		held[hash] = true
+		peeled, err := r.peel(hash)
+		if err != nil {
+			return nil, err
+		}
+		basis = append(basis, peeled)
+	}
+
+	var objects []deltaObject
+	var commits []string
+	for _, hash := range tips {
+		for depth := 0; !held[hash]; depth++ {
+			header, closer, err := r.open(hash)
+			if err != nil {
+				return nil, fmt.Errorf("git: failed to read %s: %w", hash, err)
+			}
+			if header.Kind != TagObject {
+				closer.Close()
+				if header.Kind != CommitObject {
+					return nil, fmt.Errorf("git: %s is a %s, not a commit", hash, header.Kind)
+				}
+				commits = append(commits, hash)
+				break
+			}
+			tag, err := r.d.DecodeTag(hash)
+			closer.Close()
+			if err != nil {
+				return nil, err
+			}
+			if depth == maxSymbolicDepth {
+				return nil, fmt.Errorf("git: tag nesting too deep at %s", hash)
+			}
+			held[hash] = true
+			objects = append(objects, deltaObject{hash: hash})
+			hash = tag.Object
+		}
+	}
+
+	fresh, boundary, err := r.freshCommits(commits, basis, shallow)
+	if err != nil {
+		return nil, err
+	}
+	for hash := range fresh {
+		if shallow[hash] {
+			return nil, fmt.Errorf("git: can't push %s, the history before it was never fetched", hash)
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, base := range boundary {
+		if err := collectTree(r, base.Tree, seen, nil); err != nil {
+			return nil, err
+		}
+	}
+	for _, commit := range slices.Backward(topoOrder(fresh)) {
+		objects = append(objects, deltaObject{hash: commit.Hash})
+		var base string
+		if len(commit.Parents) > 0 {
+			if parent, ok := fresh[commit.Parents[0]]; ok {
+				base = parent.Tree
+			} else if parent, ok := boundary[commit.Parents[0]]; ok {
+				base = parent.Tree
+			}
+		}
+		err := collectChanges(r, commit.Tree, base, seen, func(hash, base string) {
+			objects = append(objects, deltaObject{hash: hash, base: base})
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return objects, nil
+}
+
+// collectChanges is [collectTree] for the tree of a commit, pairing each object it finds with the one at
+// the same path in base, the tree of the commit's first parent, or "" where there is none.
+func collectChanges(r *commitReader, hash, base string, seen map[string]bool, found func(hash, base string)) error {
+	if seen[hash] {
+		return nil
+	}
+	seen[hash] = true
+	found(hash, base)
+
+	entries, err := r.tree(hash)
+	if err != nil {
+		return err
+	}
+	old := map[string]TreeEntry{}
+	if base != "" {
+		baseEntries, err := r.tree(base)
+		if err != nil {
+			return err
+		}
+		for _, entry := range baseEntries {
+			old[entry.Name] = entry
+		}
+	}
+	for _, entry := range entries {
+		var oldHash string
+		if before, ok := old[entry.Name]; ok && (before.Mode == ModeTree) == (entry.Mode == ModeTree) && before.Mode != ModeSubmodule {
+			oldHash = before.Hash
+		}
+		switch {
+		case entry.Mode == ModeTree:
+			if err := collectChanges(r, entry.Hash, oldHash, seen, found); err != nil {
+				return err
+			}
+		case entry.Mode != ModeSubmodule && !seen[entry.Hash]:
+			seen[entry.Hash] = true
+			found(entry.Hash, oldHash)
+		}
+	}
+	return nil
+}
+
+// writeDeltaPack writes objects to w as a pack, each one as a delta against its base where that is less
+// than half its size and the receiver has the base: it was written earlier in the pack, or thin is set
+// and the base isn't in the pack at all, since it's then among what the receiver has already.
+func writeDeltaPack(w io.Writer, r *commitReader, format HashFormat, objects []deltaObject, thin bool) error {
+	inPack := map[string]bool{}
+	for _, object := range objects {
+		inPack[object.hash] = true
+	}
+	p, err := streamPack(w, format, len(objects))
+	if err != nil {
+		return err
+	}
+
+	written := map[string]bool{}
+	for _, object := range objects {
+		kind, data, err := r.raw(object.hash)
+		if err != nil {
+			return fmt.Errorf("git: failed to read %s: %w", object.hash, err)
+		}
+		written[object.hash] = true
+		if object.base != "" && (written[object.base] || thin && !inPack[object.base]) && len(data) <= maxDeltaSource {
+			baseKind, base, err := r.raw(object.base)
+			if err != nil {
+				return fmt.Errorf("git: failed to read %s: %w", object.base, err)
+			}
+			if baseKind == kind && len(base) <= maxDeltaSource {
+				if delta := makeDelta(base, data); len(delta) < len(data)/2 {
+					if err := p.addDelta(object.hash, object.base, delta); err != nil {
+						return err
+					}
+					continue
+				}
+			}
+		}
+		if err := p.add(object.hash, kind, data); err != nil {
+			return err
+		}
+	}
+	_, err = p.end()
+	return err
+}
@@ -0,0 +1,242 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// serveReceivePack serves server's refs to git-receive-pack over HTTP in protocol v0, storing pushed
+// packs and updating refs as told, except that it refuses to touch refs/heads/protected. It records the
+// commands of each push.
+func serveReceivePack(t *testing.T, server *Repository, pushes *[][]string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			if r.URL.Path != "/repo.git/info/refs" || r.URL.Query().Get("service") != "git-receive-pack" {
+				http.NotFound(w, r)
+				return
+			}
+			refs, err := server.Refs()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			var adv pktWriter
+			adv.line("# service=git-receive-pack\n")
+			adv.flush()
+			capabilities := "\x00report-status delete-refs side-band-64k quiet object-format=sha1 agent=test\n"
+			if len(refs) == 0 {
+				adv.line(ZeroHash + " capabilities^{}" + capabilities)
+			}
+			for i, name := range slices.Sorted(maps.Keys(refs)) {
+				line := refs[name] + " " + name
+				if i == 0 {
+					line += capabilities
+				}
+				adv.line(line + "\n")
+			}
+			adv.flush()
+			w.Write(adv.buf)
+			return
+		}
+
+		p := newPktReader(r.Body)
+		commands, err := readTestRequest(p)
+		if err != nil {
+			t.Errorf("reading the commands: %v", err)
+			return
+		}
+		commands[0], _, _ = strings.Cut(commands[0], "\x00")
+		*pushes = append(*pushes, commands)
+		if pack, _ := io.ReadAll(p.r); len(pack) > 0 {
+			if _, _, err := server.storePack(bytes.NewReader(pack)); err != nil {
+				t.Errorf("storing the pack: %v", err)
+				return
+			}
+		}
+
+		var report pktWriter
+		report.line("unpack ok\n")
+		for _, command := range commands {
+			fields := strings.Fields(command)
+			switch {
+			case fields[2] == "refs/heads/protected":
+				report.line("ng " + fields[2] + " protected branch\n")
+			case fields[1] == ZeroHash:
+				os.Remove(filepath.Join(server.GitDir, filepath.FromSlash(fields[2])))
+				report.line("ok " + fields[2] + "\n")
+			case server.UpdateRef(fields[2], fields[0], fields[1], maintenanceSignature, "push") != nil:
+				report.line("ng " + fields[2] + " failed to lock\n")
+			default:
+				report.line("ok " + fields[2] + "\n")
+			}
+		}
+		report.flush()
+		var reply pktWriter
+		reply.line("\x01" + string(report.buf))
+		reply.flush()
+		w.Write(reply.buf)
+	}))
+}
+
+func TestPush(t *testing.T) {
+	serverDir := newTestRepo(t)
+	server, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pushes [][]string
+	srv := serveReceivePack(t, server, &pushes)
+	defer srv.Close()
+
+	gitDir := newTestRepo(t)
+	writeTestFiles(t, map[string]string{".git/config": fmt.Sprintf("[remote \"origin\"]\n\turl = %s/repo.git\n\tfetch = +refs/heads/*:refs/remotes/origin/*\n", srv.URL)})
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestObject(t, gitDir, TreeObject, "")
+	base := writeTestTreeCommit(t, repo, writeTestCommit(t, gitDir, "root"), maintenanceSignature, "base", map[string]string{"a.txt": "one\n"})
+	feature := writeTestTreeCommit(t, repo, base, maintenanceSignature, "feature", map[string]string{"a.txt": "two\n", "b.txt": "new\n"})
+	writeTestRef(t, gitDir, "refs/heads/main", base)
+	writeTestRef(t, gitDir, "refs/heads/feature", feature)
+	writeTestRef(t, gitDir, "HEAD", "ref: refs/heads/feature")
+
+	push := func(refspecs ...string) PushResult {
+		t.Helper()
+		result, err := repo.Push(srv.Client(), "origin", refspecs, maintenanceSignature)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	result := push("main", "HEAD")
+	if len(result.Updated) != 2 || len(result.Rejected) != 0 || result.Objects == 0 {
+		t.Errorf("Push() of new branches = %+v, want both created", result)
+	}
+	for name, want := range map[string]string{"refs/heads/main": base, "refs/heads/feature": feature} {
+		if got, err := server.ResolveRevision(name); err != nil || got != want {
+			t.Errorf("the server's %s = %s, %v, want %s", name, got, err, want)
+		}
+		if got, err := repo.ResolveRevision(strings.Replace(name, "heads", "remotes/origin", 1)); err != nil || got != want {
+			t.Errorf("the remote-tracking ref of %s = %s, %v, want %s", name, got, err, want)
+		}
+	}
+	if commit, err := server.ReadCommit(feature); err != nil {
+		t.Errorf("the server's ReadCommit() of the pushed commit = %v", err)
+	} else if files, err := server.ReadTreeFiles(commit.Tree); err != nil || len(files) != 2 {
+		t.Errorf("the server's tree of the pushed commit = %v, %v, want both files", files, err)
+	}
+	if got := pushes[0]; !slices.Contains(got, ZeroHash+" "+base+" refs/heads/main") {
+		t.Errorf("push commands = %q, want main created", got)
+	}
+
+	// Nothing changed, so nothing is sent.
+	pushes = nil
+	if result := push("main", "feature"); len(result.Updated) != 0 || len(pushes) != 0 {
+		t.Errorf("Push() again = %+v after %d pushes, want nothing", result, len(pushes))
+	}
+
+	next := writeTestTreeCommit(t, repo, feature, maintenanceSignature, "next", map[string]string{"a.txt": "three\n"})
+	writeTestRef(t, gitDir, "refs/heads/feature", next)
+	if result := push("feature"); len(result.Updated) != 1 || result.Objects != 3 {
+		t.Errorf("Push() of one more commit = %+v, want only it, its tree, and its blob sent", result)
+	}
+	if got := pushes[0]; !slices.Equal(got, []string{feature + " " + next + " refs/heads/feature"}) {
+		t.Errorf("push commands = %q, want feature moved from where the server had it", got)
+	}
+
+	// main can't go back to an unrelated commit unless forced.
+	other := writeTestTreeCommit(t, repo, writeTestCommit(t, gitDir, "unrelated"), maintenanceSignature, "other", map[string]string{"c.txt": "other\n"})
+	writeTestRef(t, gitDir, "refs/heads/other", other)
+	if result := push("other:main"); len(result.Rejected) != 1 || result.Rejected[0].Reason != "non-fast-forward" {
+		t.Errorf("Push() of an unrelated commit = %+v, want it rejected", result)
+	}
+	if result := push("+other:main"); len(result.Updated) != 1 {
+		t.Errorf("Push() forcing an unrelated commit = %+v, want it taken", result)
+	}
+
+	// Someone else moved main on the server.
+	elsewhere := writeTestCommit(t, serverDir, "elsewhere", other)
+	writeTestRef(t, serverDir, "refs/heads/main", elsewhere)
+	if result := push("feature:main"); len(result.Rejected) != 1 || result.Rejected[0].Reason != "fetch first" {
+		t.Errorf("Push() over commits the repository doesn't have = %+v, want it rejected", result)
+	}
+
+	writeTestRef(t, gitDir, "refs/tags/v1", base)
+	push("v1")
+	if result := push("feature:v1"); len(result.Rejected) != 1 || result.Rejected[0].Reason != "already exists" {
+		t.Errorf("Push() moving a tag = %+v, want it rejected", result)
+	}
+	if result := push("feature:protected"); len(result.Rejected) != 1 || result.Rejected[0].Reason != "protected branch" {
+		t.Errorf("Push() to a protected branch = %+v, want the server's reason", result)
+	}
+	if result := push(":refs/heads/feature"); len(result.Updated) != 1 || result.Updated[0].New != ZeroHash {
+		t.Errorf("Push() deleting a branch = %+v, want it deleted", result)
+	}
+	if _, err := repo.Push(srv.Client(), "origin", []string{":gone"}, maintenanceSignature); !errors.Is(err, ErrRemoteRefNotFound) {
+		t.Errorf("Push() deleting a missing branch = %v, want %v", err, ErrRemoteRefNotFound)
+	}
+}
+
+func TestPushPackDeltas(t *testing.T) {
+	gitDir := newTestRepo(t)
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file strings.Builder
+	for i := range 500 {
+		fmt.Fprintf(&file, "line %d of the file, %x\n", i, i*2654435761)
+	}
+	writeTestObject(t, gitDir, TreeObject, "")
+	base := writeTestTreeCommit(t, repo, writeTestCommit(t, gitDir, "root"), maintenanceSignature, "base", map[string]string{"a.txt": file.String()})
+	next := writeTestTreeCommit(t, repo, base, maintenanceSignature, "next", map[string]string{"a.txt": file.String() + "one more line\n"})
+	old, err := repo.ReadCommit(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldFiles, err := repo.ReadTreeFiles(old.Tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := newCommitReader(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	for _, thin := range []bool{true, false} {
+		pack, count, err := repo.pushPack([]string{next}, []string{base}, thin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(pack)
+		pack.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		objects, bases, err := indexPack(bytes.NewReader(data), int64(len(data)), repo.Format, r.raw)
+		if err != nil || count != 3 || len(objects) != 3 {
+			t.Fatalf("thin %t: pushPack() = %d objects, indexed as %d, %v, want the commit, its tree, and its blob", thin, count, len(objects), err)
+		}
+		var want []string
+		if thin {
+			want = []string{oldFiles["a.txt"].Hash}
+		}
+		if !slices.Equal(bases, want) {
+			t.Errorf("thin %t: pack builds on %v, want %v", thin, bases, want)
+		}
+	}
+}
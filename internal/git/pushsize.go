@@ -0,0 +1,170 @@
+package git
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PushEstimate is roughly what pushing a commit to a remote would send.
+type PushEstimate struct {
+	Commits []Commit // The commits the remote doesn't have, newest first
+	Objects int      // How many commits, trees, and blobs the remote doesn't have
+	Bytes   int64    // Their compressed size on disk, loose or packed, a stand-in for the size of the pack sent
+
+	// LFSBytes is the size of the Git LFS files the new blobs point to, which git lfs uploads alongside
+	// the push unless the LFS server has them already.
+	LFSBytes int64
+}
+
+// EstimatePush works out what pushing rev to remote would send, assuming the remote has what its
+// remote-tracking branches (refs/remotes/<remote>/) say it has, and nothing at all if there are none.
+//
+// Only the trees of the commits the new history builds on are taken as known to the remote, rather than
+// every tree it has, so a file restored from older history counts as new.
+func (repo *Repository) EstimatePush(rev, remote string) (PushEstimate, error) {
+	hash, err := repo.ResolveRevision(rev)
+	if err != nil {
+		return PushEstimate{}, err
+	}
+	refs, err := repo.listRefs("refs/remotes/" + remote + "/")
+	if err != nil {
+		return PushEstimate{}, err
+	}
+
+	r, err := newCommitReader(repo)
+	if err != nil {
+		return PushEstimate{}, err
+	}
+	defer r.Close()
+	shallow, err := readShallow(repo.CommonDir)
+	if err != nil {
+		return PushEstimate{}, err
+	}
+
+	var tips []string
+	for _, tip := range refs {
+		if strings.HasPrefix(tip, "ref: ") {
+			continue
+		}
+		peeled, err := r.peel(tip)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return PushEstimate{}, err
+		}
+		tips = append(tips, peeled)
+	}
+	known := BranchHistory{Graph: map[string]Commit{}, Shallow: map[string]bool{}}
+	if err := r.walk(&known, shallow, tips); err != nil {
+		return PushEstimate{}, err
+	}
+
+	// Walking from rev on top of the known history stops wherever it reaches a commit the remote has.
+	all := BranchHistory{Graph: map[string]Commit{}, Shallow: map[string]bool{}}
+	for h, commit := range known.Graph {
+		all.Graph[h] = commit
+	}
+	if err := r.walk(&all, shallow, []string{hash}); err != nil {
+		return PushEstimate{}, err
+	}
+	fresh := map[string]Commit{}
+	for h, commit := range all.Graph {
+		if _, ok := known.Graph[h]; !ok {
+			fresh[h] = commit
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, commit := range fresh {
+		for _, parent := range commit.Parents {
+			if base, ok := known.Graph[parent]; ok {
+				if err := collectTree(r, base.Tree, seen, nil); err != nil {
+					return PushEstimate{}, err
+				}
+			}
+		}
+	}
+
+	packs, err := openPacks(filepath.Join(repo.CommonDir, "objects"), repo.Format)
+	if err != nil {
+		return PushEstimate{}, err
+	}
+	defer packs.Close()
+
+	estimate := PushEstimate{Commits: topoOrder(fresh)}
+	lfs := map[string]bool{}
+	var failed error
+	count := func(hash string) {
+		estimate.Objects++
+		size := int64(-1)
+		if info, err := os.Stat(repo.objectPath(hash)); err == nil {
+			estimate.Bytes += info.Size()
+			if header, closer, err := r.open(hash); err == nil {
+				size = header.Size
+				closer.Close()
+			}
+		} else if disk, ok := packs.diskSize(hash); ok {
+			estimate.Bytes += disk
+			if size, err = packs.size(hash); err != nil {
+				size = -1
+			}
+		}
+
+		// Only a blob small enough to be an LFS pointer is read to see whether it is one.
+		if size < 0 || size > maxLFSPointerSize || failed != nil {
+			return
+		}
+		kind, data, err := r.raw(hash)
+		if err != nil {
+			failed = err
+			return
+		}
+		if p, ok := ParseLFSPointer(data); ok && kind == BlobObject && !lfs[p.OID] {
+			lfs[p.OID] = true
+			estimate.LFSBytes += p.Size
+		}
+	}
+	for _, commit := range estimate.Commits {
+		count(commit.Hash)
+		if err := collectTree(r, commit.Tree, seen, count); err != nil {
+			return PushEstimate{}, err
+		}
+	}
+	return estimate, failed
+}
+
+// collectTree adds the tree hash and the trees and blobs beneath it to seen, calling found, if it isn't
+// nil, with each one that wasn't there already. Submodule commits live in other repositories and are
+// skipped.
+func collectTree(r *commitReader, hash string, seen map[string]bool, found func(hash string)) error {
+	if seen[hash] {
+		return nil
+	}
+	seen[hash] = true
+	if found != nil {
+		found(hash)
+	}
+
+	entries, err := r.tree(hash)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		switch {
+		case entry.Mode == ModeTree:
+			if err := collectTree(r, entry.Hash, seen, found); err != nil {
+				return err
+			}
+		case entry.Mode != ModeSubmodule && !seen[entry.Hash]:
+			seen[entry.Hash] = true
+			if found != nil {
+				found(entry.Hash)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package git
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEstimatePush(t *testing.T) {
+	gitDir := newTestRepo(t)
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	who := Signature{Name: "Ann", Email: "ann@example.com", Time: time.Unix(1703130000, 0)}
+
+	blob, _ := repo.Encoder().Encode(BlobObject, []byte("package main\n"))
+	b := NewTreeBuilder(repo.Encoder())
+	if err := b.Add("src/main.go", ModeFile, blob); err != nil {
+		t.Fatal(err)
+	}
+	tree, _ := b.Write()
+	root, err := repo.CreateCommit(tree, nil, who, who, "root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := writeTestTreeCommit(t, repo, root, who, "base", map[string]string{"src/main.go": "package main\n", "README": "hello\n"})
+	one := writeTestTreeCommit(t, repo, base, who, "one", map[string]string{"src/main.go": "package main\n", "README": "hello again\n"})
+	two := writeTestTreeCommit(t, repo, one, who, "two", map[string]string{"src/main.go": "package main\n", "README": "hello again\n", "docs/a.md": "a\n"})
+	writeTestRef(t, gitDir, "refs/heads/feature", two)
+	writeTestRef(t, gitDir, "HEAD", "ref: refs/heads/feature")
+
+	// Without remote-tracking branches, everything is new.
+	estimate, err := repo.EstimatePush("feature", "origin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(estimate.Commits) != 4 || estimate.Commits[0].Hash != two || estimate.Bytes == 0 {
+		t.Errorf("estimate with nothing known is %d commits, %d bytes", len(estimate.Commits), estimate.Bytes)
+	}
+
+	writeTestRef(t, gitDir, "refs/remotes/origin/main", base)
+	writeTestRef(t, gitDir, "refs/remotes/origin/HEAD", "ref: refs/remotes/origin/main")
+	estimate, err = repo.EstimatePush("feature", "origin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var messages []string
+	for _, c := range estimate.Commits {
+		messages = append(messages, c.Message)
+	}
+	if len(messages) != 2 || messages[0] != "two" || messages[1] != "one" {
+		t.Errorf("estimate has commits %q, want two and one", messages)
+	}
+	// Two commits, their two root trees, the new README, docs, and a.md; src and main.go are known.
+	if estimate.Objects != 7 {
+		t.Errorf("estimate has %d objects, want 7", estimate.Objects)
+	}
+
+	// Packed objects take up about the same room, if not less.
+	loose := estimate.Bytes
+	if _, err := repo.Repack(); err != nil {
+		t.Fatal(err)
+	}
+	estimate, err = repo.EstimatePush("feature", "origin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if estimate.Objects != 7 || estimate.Bytes == 0 || estimate.Bytes > loose+7*32 {
+		t.Errorf("estimate of packed objects is %d objects, %d bytes, want 7 objects in about %d bytes", estimate.Objects, estimate.Bytes, loose)
+	}
+
+	writeTestRef(t, gitDir, "refs/remotes/origin/feature", two)
+	estimate, err = repo.EstimatePush("feature", "origin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(estimate.Commits) != 0 || estimate.Objects != 0 || estimate.Bytes != 0 {
+		t.Errorf("estimate of a pushed branch is %+v, want nothing", estimate)
+	}
+}
+
+func TestEstimatePushLFS(t *testing.T) {
+	gitDir := newTestRepo(t)
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	who := Signature{Name: "Ann", Email: "ann@example.com", Time: time.Unix(1703130000, 0)}
+	pointer := func(oid string, size int) string {
+		return "version https://git-lfs.github.com/spec/v1\noid sha256:" + strings.Repeat(oid, 64) + "\nsize " + strconv.Itoa(size) + "\n"
+	}
+	readme, _ := repo.Encoder().Encode(BlobObject, []byte("hello\n"))
+	b := NewTreeBuilder(repo.Encoder())
+	if err := b.Add("README", ModeFile, readme); err != nil {
+		t.Fatal(err)
+	}
+	tree, _ := b.Write()
+	root, err := repo.CreateCommit(tree, nil, who, who, "root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tip := writeTestTreeCommit(t, repo, root, who, "add videos", map[string]string{
+		"README": "hello\n", "intro.mp4": pointer("a", 30<<20), "copy.mp4": pointer("a", 30<<20), "outro.mp4": pointer("b", 5<<20),
+	})
+	writeTestRef(t, gitDir, "refs/heads/feature", tip)
+	writeTestRef(t, gitDir, "refs/remotes/origin/main", root)
+
+	// The same file committed twice is uploaded once.
+	for _, packed := range []bool{false, true} {
+		if packed {
+			if _, err := repo.Repack(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		estimate, err := repo.EstimatePush("feature", "origin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if estimate.LFSBytes != 35<<20 {
+			t.Errorf("estimate with packed objects %v has %d bytes of LFS files, want %d", packed, estimate.LFSBytes, 35<<20)
+		}
+	}
+}
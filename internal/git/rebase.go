@@ -0,0 +1,381 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var ErrNoRebase = errors.New("no rebase in progress")
+
+const (
+	rebaseDir  = "rebase-merge"
+	rebaseHead = "REBASE_HEAD"
+	rebaseTodo = "git-rebase-todo"
+
+	// detachedHeadName is what git records as head-name when a rebase starts from a detached HEAD.
+	detachedHeadName = "detached HEAD"
+)
+
+// rebaseState is the part of .git/rebase-merge that says where a rebase started and where it's going.
+type rebaseState struct {
+	headName string // The branch being rebased, e.g. refs/heads/feature, or detachedHeadName
+	onto     string
+	origHead string
+	ontoName string // What onto was named when the rebase started, or "" if git started it
+}
+
+// Rebase replays the commits on HEAD that aren't in upstream onto the commit onto, oldest first with the
+// cherry-pick machinery, and moves the current branch to the result, like git rebase --onto onto
+// upstream. An empty onto means upstream. It returns the new HEAD, which is HEAD itself when its
+// commits already sit on onto.
+//
+// Merge commits are left out, so the result is linear, and commits whose changes onto already has are
+// dropped. The index and tracked files must match HEAD. Progress is kept in .git/rebase-merge in git's
+// layout, so git status reports the rebase and git rebase --abort can undo it. If a commit conflicts, the
+// rebase stops with it recorded in REBASE_HEAD and an error wrapping [ErrPickConflict]; once the
+// conflicts are resolved, [Repository.ContinueRebase] carries on, and [Repository.AbortRebase] puts
+// everything back as it was.
+func (repo *Repository) Rebase(upstream, onto string, who Signature) (string, error) {
+	if op := repo.activeOperation(); op != "" {
+		return "", fmt.Errorf("%w: %s exists", ErrOperationActive, op)
+	}
+	if err := repo.requireClean(); err != nil {
+		return "", err
+	}
+
+	head, err := repo.ResolveRevision("HEAD")
+	if err != nil {
+		return "", err
+	}
+	headName := detachedHeadName
+	if target, err := repo.resolveRef("HEAD"); err == nil && strings.HasPrefix(target, "ref: ") {
+		headName = strings.TrimPrefix(target, "ref: ")
+	}
+	upstreamHash, err := repo.ResolveRevision(upstream)
+	if err != nil {
+		return "", err
+	}
+	if onto == "" {
+		onto = upstream
+	}
+	ontoHash, err := repo.ResolveRevision(onto)
+	if err != nil {
+		return "", err
+	}
+
+	commits, err := repo.CommitsBetween(upstreamHash, head)
+	if err != nil {
+		return "", err
+	}
+	var todo []string
+	for _, commit := range slices.Backward(commits) {
+		if len(commit.Parents) <= 1 {
+			subject, _, _ := strings.Cut(commit.Message, "\n")
+			todo = append(todo, "pick "+commit.Hash+" "+subject)
+		}
+	}
+	if head == ontoHash || len(todo) == len(commits) && len(commits) > 0 && slices.Equal(commits[len(commits)-1].Parents, []string{ontoHash}) {
+		return head, nil
+	}
+
+	dir := filepath.Join(repo.GitDir, rebaseDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	files := map[string]string{
+		"head-name": headName,
+		"onto":      ontoHash,
+		"onto-name": onto,
+		"orig-head": head,
+		rebaseTodo:  strings.Join(todo, "\n"),
+		"done":      "",
+		"msgnum":    "0",
+		"end":       strconv.Itoa(len(todo)),
+	}
+	for name, content := range files {
+		if content != "" {
+			content += "\n"
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			os.RemoveAll(dir)
+			return "", err
+		}
+	}
+	if err := os.WriteFile(filepath.Join(repo.GitDir, origHead), []byte(head+"\n"), 0o644); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+
+	if err := repo.checkoutDetached(ontoHash, who, "rebase (start): checkout "+onto); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	return repo.replay(who)
+}
+
+// ContinueRebase carries on with a rebase that stopped on conflicts, once they are resolved: it commits
+// the stopped commit with its original author, unless resolving it left nothing to commit, and replays
+// the rest. It returns the new HEAD.
+func (repo *Repository) ContinueRebase(who Signature) (string, error) {
+	if _, err := repo.readRebaseState(); err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(filepath.Join(repo.GitDir, rebaseHead))
+	if errors.Is(err, fs.ErrNotExist) {
+		// Interrupted between commits rather than stopped on one.
+		if err := repo.requireClean(); err != nil {
+			return "", err
+		}
+		return repo.replay(who)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	commit, err := repo.ReadCommit(strings.TrimSpace(string(data)))
+	if err != nil {
+		return "", err
+	}
+	message, err := os.ReadFile(filepath.Join(repo.GitDir, mergeMsg))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+	msg := stripComments(string(message))
+	if msg == "" {
+		msg = commit.Message
+	}
+	subject, _, _ := strings.Cut(msg, "\n")
+
+	idx, err := repo.Index()
+	if err != nil {
+		return "", err
+	}
+	head, err := repo.ResolveRevision("HEAD")
+	if err != nil {
+		return "", err
+	}
+	if _, err := repo.commitIndex(idx, head, commit.Author, who, msg, "rebase (continue): "+subject); err != nil && !errors.Is(err, ErrEmptyPick) {
+		return "", err
+	}
+	if err := repo.clearOperationState(rebaseHead); err != nil {
+		return "", err
+	}
+	return repo.replay(who)
+}
+
+// AbortRebase gives up on a rebase, checking out the branch as it was before the rebase started and
+// discarding the commits replayed so far.
+func (repo *Repository) AbortRebase(who Signature) error {
+	state, err := repo.readRebaseState()
+	if err != nil {
+		return err
+	}
+
+	if state.headName == detachedHeadName {
+		err = repo.UpdateRef("HEAD", "", state.origHead, who, "rebase (abort): returning to "+state.origHead)
+	} else {
+		err = repo.attachHead(state.headName, who, "rebase (abort): returning to "+state.headName)
+	}
+	if err != nil {
+		return err
+	}
+
+	idx, err := repo.Index()
+	if err != nil {
+		return err
+	}
+	if err := repo.resetToHead(idx); err != nil {
+		return err
+	}
+	if err := repo.WriteIndex(idx); err != nil {
+		return err
+	}
+	if err := repo.clearOperationState(rebaseHead); err != nil {
+		return err
+	}
+	return os.RemoveAll(filepath.Join(repo.GitDir, rebaseDir))
+}
+
+// replay picks the commits left in the todo list one by one, then finishes the rebase.
+func (repo *Repository) replay(who Signature) (string, error) {
+	dir := filepath.Join(repo.GitDir, rebaseDir)
+	for {
+		todo, err := readLines(filepath.Join(dir, rebaseTodo))
+		if err != nil {
+			return "", err
+		}
+		if len(todo) == 0 {
+			return repo.finishRebase(who)
+		}
+
+		// The line moves to done before it's picked, as git does, so a stop leaves it there.
+		line := todo[0]
+		if err := repo.advanceRebase(line, todo[1:]); err != nil {
+			return "", err
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "pick" {
+			return "", fmt.Errorf("git: unsupported rebase step %q", line)
+		}
+
+		commit, base, err := repo.pickTarget(fields[1], 0)
+		if err != nil {
+			return "", err
+		}
+		subject, _, _ := strings.Cut(commit.Message, "\n")
+		label := commit.Hash[:7] + " (" + subject + ")"
+		_, err = repo.applyPick(commit, base, commit.Tree, label, rebaseHead, commit.Author, who, commit.Message, "rebase (pick): "+subject)
+		switch {
+		case errors.Is(err, ErrEmptyPick):
+			// onto already has this change.
+		case errors.Is(err, ErrPickConflict):
+			if err := os.WriteFile(filepath.Join(dir, "stopped-sha"), []byte(commit.Hash+"\n"), 0o644); err != nil {
+				return "", err
+			}
+			return "", fmt.Errorf("%s %s: %w", commit.Hash[:7], subject, err)
+		case err != nil:
+			return "", err
+		}
+	}
+}
+
+// advanceRebase moves line from the todo list to the done list and counts it.
+func (repo *Repository) advanceRebase(line string, rest []string) error {
+	dir := filepath.Join(repo.GitDir, rebaseDir)
+	f, err := os.OpenFile(filepath.Join(dir, "done"), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(line + "\n"); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	done, err := readLines(filepath.Join(dir, "done"))
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "msgnum"), []byte(strconv.Itoa(len(done))+"\n"), 0o644); err != nil {
+		return err
+	}
+	remaining := strings.Join(rest, "\n")
+	if remaining != "" {
+		remaining += "\n"
+	}
+	return os.WriteFile(filepath.Join(dir, rebaseTodo), []byte(remaining), 0o644)
+}
+
+// finishRebase moves the rebased branch to HEAD, checks it out again, and removes the rebase's state.
+func (repo *Repository) finishRebase(who Signature) (string, error) {
+	state, err := repo.readRebaseState()
+	if err != nil {
+		return "", err
+	}
+	head, err := repo.ResolveRevision("HEAD")
+	if err != nil {
+		return "", err
+	}
+
+	if state.headName != detachedHeadName {
+		if err := repo.UpdateRef(state.headName, state.origHead, head, who, "rebase (finish): "+state.headName+" onto "+state.onto); err != nil {
+			return "", err
+		}
+		if err := repo.attachHead(state.headName, who, "rebase (finish): returning to "+state.headName); err != nil {
+			return "", err
+		}
+	}
+	return head, os.RemoveAll(filepath.Join(repo.GitDir, rebaseDir))
+}
+
+func (repo *Repository) readRebaseState() (rebaseState, error) {
+	dir := filepath.Join(repo.GitDir, rebaseDir)
+	var values [3]string
+	for i, name := range []string{"head-name", "onto", "orig-head"} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if errors.Is(err, fs.ErrNotExist) {
+			return rebaseState{}, ErrNoRebase
+		}
+		if err != nil {
+			return rebaseState{}, err
+		}
+		values[i] = strings.TrimSpace(string(data))
+	}
+	state := rebaseState{headName: values[0], onto: values[1], origHead: values[2]}
+
+	// git doesn't record the name, and ignores the file plain records it in.
+	data, err := os.ReadFile(filepath.Join(dir, "onto-name"))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return rebaseState{}, err
+	}
+	state.ontoName = strings.TrimSpace(string(data))
+	return state, nil
+}
+
+// RebaseOnto returns what the rebase in progress moves the branch onto, as it was named to
+// [Repository.Rebase], or "" for a rebase git started. It fails with [ErrNoRebase] if there is none.
+func (repo *Repository) RebaseOnto() (string, error) {
+	state, err := repo.readRebaseState()
+	return state.ontoName, err
+}
+
+// checkoutDetached switches the index and work tree from HEAD to the commit hash and detaches HEAD at
+// it. Untracked files in the way stop it with [ErrWouldOverwrite] before anything is written.
+func (repo *Repository) checkoutDetached(hash string, who Signature, reason string) error {
+	head, err := repo.ResolveRevision("HEAD")
+	if err != nil {
+		return err
+	}
+	var trees [2]string
+	for i, rev := range []string{head, hash} {
+		commit, err := repo.ReadCommit(rev)
+		if err != nil {
+			return err
+		}
+		trees[i] = commit.Tree
+	}
+
+	// Merging the target onto an unchanged HEAD takes the target's version of every path.
+	merges, err := repo.mergeTrees(trees[0], trees[0], trees[1], "HEAD", hash)
+	if err != nil {
+		return err
+	}
+	idx, err := repo.Index()
+	if err != nil {
+		return err
+	}
+	if err := repo.checkoutMerges(idx, merges); err != nil {
+		return err
+	}
+	if err := repo.WriteIndex(idx); err != nil {
+		return err
+	}
+	return repo.UpdateRef("HEAD", "", hash, who, reason)
+}
+
+// readLines returns the non-empty lines of the file at path, or nil if it doesn't exist.
+func readLines(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
@@ -0,0 +1,94 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRebase(t *testing.T) {
+	newTestRepo(t)
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stageTestFiles(t, repo, map[string]string{"f.txt": "a\nb\nc\nd\ne\n"})
+	commitTestIndex(t, repo)
+	root, _ := repo.ResolveRevision("HEAD")
+
+	who := Signature{Name: "Ann", Email: "ann@example.com", Time: time.Unix(1703130000, 0)}
+	other := Signature{Name: "Olga", Email: "olga@example.com", Time: time.Unix(1703120000, 0)}
+	upstream := writeTestTreeCommit(t, repo, root, other, "Change e", map[string]string{"f.txt": "a\nb\nc\nd\nE\n"})
+	conflicting := writeTestTreeCommit(t, repo, root, other, "Change b too", map[string]string{"f.txt": "a\nX\nc\nd\ne\n"})
+	writeTestRef(t, repo.GitDir, "refs/heads/up", upstream)
+	writeTestRef(t, repo.GitDir, "refs/heads/clash", conflicting)
+
+	// Two commits on main to replay.
+	stageTestFiles(t, repo, map[string]string{"f.txt": "a\nB\nc\nd\ne\n"})
+	idx, _ := repo.Index()
+	first, err := repo.commitIndex(idx, root, who, who, "Change b", "commit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stageTestFiles(t, repo, map[string]string{"g.txt": "new\n"})
+	idx, _ = repo.Index()
+	if _, err := repo.commitIndex(idx, first, who, who, "Add g", "commit"); err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := repo.Rebase("up", "", who)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commits, err := repo.CommitsBetween("up", "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 2 || commits[0].Hash != hash || commits[0].Message != "Add g" || !slices.Equal(commits[1].Parents, []string{upstream}) {
+		t.Fatalf("main isn't the two commits replayed on up: %+v", commits)
+	}
+	if branch, _ := repo.CurrentBranch(); branch != "main" {
+		t.Errorf("HEAD is on %q after the rebase, want main", branch)
+	}
+	if data, _ := os.ReadFile("f.txt"); string(data) != "a\nB\nc\nd\nE\n" {
+		t.Errorf("f.txt = %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(repo.GitDir, rebaseDir)); !os.IsNotExist(err) {
+		t.Error("rebase-merge wasn't removed")
+	}
+	if again, err := repo.Rebase("up", "", who); err != nil || again != hash {
+		t.Errorf("rebasing again = %s, %v, want nothing to happen", again, err)
+	}
+
+	// Onto clash, the first commit conflicts; aborting puts main back.
+	if _, err := repo.Rebase("up", "clash", who); !errors.Is(err, ErrPickConflict) {
+		t.Fatalf("expected a conflict, got %v", err)
+	}
+	if branch, _ := repo.CurrentBranch(); branch != "" {
+		t.Errorf("HEAD should be detached while the rebase is stopped, is on %q", branch)
+	}
+	if state, err := repo.readRebaseState(); err != nil || state.headName != "refs/heads/main" || state.origHead != hash {
+		t.Errorf("rebase state = %+v, %v", state, err)
+	}
+	if onto, err := repo.RebaseOnto(); err != nil || onto != "clash" {
+		t.Errorf("RebaseOnto() = %q, %v, want clash", onto, err)
+	}
+	if err := repo.AbortRebase(who); err != nil {
+		t.Fatal(err)
+	}
+	if head, _ := repo.ResolveRevision("HEAD"); head != hash {
+		t.Errorf("HEAD = %s after aborting, want %s", head, hash)
+	}
+	if branch, _ := repo.CurrentBranch(); branch != "main" {
+		t.Errorf("HEAD is on %q after aborting, want main", branch)
+	}
+	if entries, err := repo.Status(nil); err != nil || len(entries) != 0 {
+		t.Errorf("expected a clean status after aborting, got %+v, %v", entries, err)
+	}
+	if err := repo.AbortRebase(who); !errors.Is(err, ErrNoRebase) {
+		t.Errorf("aborting twice: got %v", err)
+	}
+}
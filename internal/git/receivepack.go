@@ -0,0 +1,206 @@
+package git
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// receivePack is a conversation in git's protocol v0 with a remote's git-receive-pack, which takes a pack
+// and a list of ref updates, as git push sends them. Pushing has no protocol v2.
+//
+// A receivePack is started by calling [dialReceivePack], which reads the remote's refs and capabilities.
+type receivePack struct {
+	t            transport
+	remote       string // The remote's URL, for errors
+	format       HashFormat
+	refs         map[string]string // The remote's refs, mapped to the objects they point to
+	haves        []string          // Objects the remote has without a ref in it, from its alternates
+	capabilities map[string]string
+}
+
+// pushCommand is one ref update to ask git-receive-pack for. Old is what the remote's ref must still point
+// to for the update to go ahead, so a push never overwrites something pushed after the refs were read.
+type pushCommand struct {
+	ref      string
+	old, new string // The zero hash for a ref that is created, or deleted, respectively
+}
+
+// dialReceivePack connects to the git-receive-pack of the remote at remoteURL the way [dialUploadPack]
+// connects to its git-upload-pack.
+func dialReceivePack(client *http.Client, remoteURL string, format HashFormat) (*receivePack, error) {
+	c := &receivePack{remote: remoteURL, format: format}
+	var advertisement io.ReadCloser
+	var err error
+	c.t, advertisement, err = dialService(client, remoteURL, "git-receive-pack", "")
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.readAdvertisement(advertisement)
+	advertisement.Close()
+	if err != nil {
+		c.t.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// readAdvertisement reads the refs the remote starts with, the first of which also carries its
+// capabilities after a NUL. A remote without refs lists its capabilities on a made up capabilities^{}.
+func (c *receivePack) readAdvertisement(r io.Reader) error {
+	p := newPktReader(r)
+	failed := func(err error) error {
+		var message *remoteMessage
+		if errors.As(err, &message) {
+			return message
+		}
+		return fmt.Errorf("%w: %s didn't advertise its refs: %w", ErrRemoteProtocol, c.remote, err)
+	}
+	line, ok, err := p.line()
+	if err == nil && ok && strings.HasPrefix(line, "# service=") {
+		// Over HTTP, the advertisement starts with the service it is for.
+		if _, _, err = p.line(); err == nil {
+			line, ok, err = p.line()
+		}
+	}
+	if err != nil {
+		return failed(err)
+	}
+	if ok && strings.HasPrefix(line, "version ") {
+		if line != "version 1" {
+			return fmt.Errorf("%w: %s answered with %s of the protocol", ErrRemoteProtocol, c.remote, line)
+		}
+		if line, ok, err = p.line(); err != nil {
+			return failed(err)
+		}
+	}
+
+	c.refs = map[string]string{}
+	c.capabilities = map[string]string{}
+	for first := true; ok; first = false {
+		ref, capabilities, _ := strings.Cut(line, "\x00")
+		if first {
+			for _, capability := range strings.Fields(capabilities) {
+				name, value, _ := strings.Cut(capability, "=")
+				c.capabilities[name] = value
+			}
+		}
+		hash, name, _ := strings.Cut(ref, " ")
+		if !isFullHash(hash) || name == "" {
+			return fmt.Errorf("%w: bad ref %q", ErrRemoteProtocol, ref)
+		}
+		switch {
+		case name == "capabilities^{}":
+		case name == ".have":
+			c.haves = append(c.haves, hash)
+		default:
+			c.refs[name] = hash
+		}
+		if line, ok, err = p.line(); err != nil {
+			return failed(err)
+		}
+	}
+
+	if _, ok := c.capabilities["report-status"]; !ok {
+		return fmt.Errorf("%w: %s can't report whether a push worked", ErrRemoteProtocol, c.remote)
+	}
+	if remote := c.capabilities["object-format"]; remote != "" && remote != c.format.String() || remote == "" && c.format != SHA1 {
+		return fmt.Errorf("git: %s names objects with %s, but the repository uses %s", c.remote, cmp.Or(remote, SHA1.String()), c.format)
+	}
+	return nil
+}
+
+// push sends commands, followed by pack unless every command deletes a ref, and returns the ref updates
+// the remote refused, mapped to why. The pack is streamed to the remote as it's read. An error means the
+// remote took none of them.
+func (c *receivePack) push(commands []pushCommand, pack io.Reader) (map[string]string, error) {
+	capabilities := []string{"report-status", "agent=plain"}
+	for _, optional := range []string{"side-band-64k", "quiet"} {
+		if _, ok := c.capabilities[optional]; ok {
+			capabilities = append(capabilities, optional)
+		}
+	}
+	if c.format != SHA1 {
+		capabilities = append(capabilities, "object-format="+c.format.String())
+	}
+	var w pktWriter
+	for i, command := range commands {
+		line := command.old + " " + command.new + " " + command.ref
+		if i == 0 {
+			line += "\x00" + strings.Join(capabilities, " ")
+		}
+		w.line(line + "\n")
+	}
+	w.flush()
+	var request io.Reader = bytes.NewReader(w.buf)
+	if pack != nil {
+		request = io.MultiReader(request, pack)
+	}
+
+	body, err := c.t.roundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	// With side-band-64k the report comes in band 1, among progress messages and errors.
+	var report io.Reader = body
+	if _, ok := c.capabilities["side-band-64k"]; ok {
+		var buf bytes.Buffer
+		p := newPktReader(body)
+		for {
+			kind, data, err := p.next()
+			if err != nil {
+				return nil, fmt.Errorf("%w: pushing: %w", ErrRemoteProtocol, err)
+			}
+			if kind != pktData {
+				break
+			}
+			if len(data) == 0 {
+				continue
+			}
+			switch data[0] {
+			case 1:
+				buf.Write(data[1:])
+			case 2:
+			case 3:
+				return nil, fmt.Errorf("git: the remote failed: %s", strings.TrimSpace(string(data[1:])))
+			default:
+				return nil, fmt.Errorf("%w: unknown sideband %d", ErrRemoteProtocol, data[0])
+			}
+		}
+		report = &buf
+	}
+
+	p := newPktReader(report)
+	line, ok, err := p.line()
+	if err != nil || !ok {
+		return nil, fmt.Errorf("%w: %s didn't report on the push: %w", ErrRemoteProtocol, c.remote, cmp.Or(err, io.ErrUnexpectedEOF))
+	}
+	if status, _ := strings.CutPrefix(line, "unpack "); status != "ok" {
+		return nil, fmt.Errorf("git: %s failed to store the pushed objects: %s", c.remote, status)
+	}
+	rejected := map[string]string{}
+	for {
+		line, ok, err := p.line()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s didn't report on the push: %w", ErrRemoteProtocol, c.remote, err)
+		}
+		if !ok {
+			return rejected, nil
+		}
+		if rest, ok := strings.CutPrefix(line, "ng "); ok {
+			ref, reason, _ := strings.Cut(rest, " ")
+			rejected[ref] = cmp.Or(reason, "rejected")
+		}
+	}
+}
+
+func (c *receivePack) Close() error {
+	return c.t.Close()
+}
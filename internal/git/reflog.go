@@ -0,0 +1,90 @@
+package git
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ReflogEntry is a single line of a ref's reflog, recording one update of the ref.
+type ReflogEntry struct {
+	Old       string    // The hash the ref pointed to before the update
+	New       string    // The hash the ref pointed to after the update
+	Committer Signature // Who made the update, and when
+	Message   string    // Why the ref moved, e.g. "commit: fix typo" or "checkout: moving from a to b"
+}
+
+// Reflog returns the reflog of the ref name (e.g. HEAD or refs/heads/main), oldest entry first.
+// A ref without a reflog returns no entries.
+func (repo *Repository) Reflog(name string) ([]ReflogEntry, error) {
+	f, err := os.Open(repo.reflogPath(name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []ReflogEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		entry, err := parseReflogLine(scanner.Text())
+		if err != nil {
+			return nil, fmt.Errorf("git: failed to read reflog for %s: %w", name, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// reflogNames returns the name of every ref that has a reflog.
+func (repo *Repository) reflogNames() ([]string, error) {
+	names := []string{"HEAD"}
+
+	root := filepath.Join(repo.CommonDir, "logs")
+	err := filepath.WalkDir(filepath.Join(root, "refs"), func(path string, entry fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fs.SkipAll
+		}
+		if err != nil || entry.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		names = append(names, filepath.ToSlash(rel))
+		return nil
+	})
+	return names, err
+}
+
+func (repo *Repository) reflogPath(name string) string {
+	if isPerWorktreeRef(name) {
+		return filepath.Join(repo.GitDir, "logs", filepath.FromSlash(name))
+	}
+	return filepath.Join(repo.CommonDir, "logs", filepath.FromSlash(name))
+}
+
+// parseReflogLine parses "<old> <new> <name> <<email>> <time> <tz>\t<message>".
+func parseReflogLine(line string) (ReflogEntry, error) {
+	identity, message, _ := strings.Cut(line, "\t")
+
+	fields := strings.SplitN(identity, " ", 3)
+	if len(fields) != 3 {
+		return ReflogEntry{}, fmt.Errorf("parse: malformed reflog line: %q", line)
+	}
+
+	sig, err := parseSignature([]byte(fields[2]))
+	if err != nil {
+		return ReflogEntry{}, err
+	}
+
+	return ReflogEntry{Old: fields[0], New: fields[1], Committer: sig, Message: message}, nil
+}
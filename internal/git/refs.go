@@ -0,0 +1,312 @@
+package git
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrRefNotFound      = errors.New("reference not found")
+	ErrUnknownRevision  = errors.New("unknown revision")
+	ErrSymbolicRefDepth = errors.New("symbolic ref nesting too deep")
+)
+
+// maxSymbolicDepth bounds how many symbolic refs are followed, matching git's limit.
+const maxSymbolicDepth = 5
+
+// ResolveRevision turns a user supplied revision (a branch, tag, remote branch, HEAD, pseudo ref like
+// ORIG_HEAD or FETCH_HEAD, or full hash) into the hash it names.
+//
+// Revisions are tried in git's order: HEAD and pseudo refs, a full hash that exists in the object store,
+// then the name under refs/, refs/tags/, refs/heads/, refs/remotes/, as a remote's HEAD, and finally as
+// a hash abbreviated to at least 4 digits. An abbreviation shared by several objects fails with
+// [ErrAmbiguousRevision], listing them.
+//
+// Any of them may be followed by ancestor suffixes, as in main~2 or HEAD^2: ~N goes back N first
+// parents, ^N takes the Nth parent, and ^0 the commit itself, with N defaulting to 1.
+func (repo *Repository) ResolveRevision(rev string) (string, error) {
+	// Ref names can't hold ~ or ^, so the first of either starts the suffixes.
+	if i := strings.IndexAny(rev, "~^"); i > 0 {
+		hash, err := repo.resolveName(rev[:i])
+		if err != nil {
+			return "", err
+		}
+		return repo.resolveAncestor(rev, hash, rev[i:])
+	}
+	return repo.resolveName(rev)
+}
+
+// resolveAncestor follows the ~N and ^N suffixes of rev from the commit hash names.
+func (repo *Repository) resolveAncestor(rev, hash, suffixes string) (string, error) {
+	r, err := newCommitReader(repo)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	unknown := fmt.Errorf("%w: %s", ErrUnknownRevision, rev)
+	parents := func(hash string) ([]string, error) {
+		commit, ok, err := r.read(hash)
+		if err == nil && !ok {
+			err = unknown
+		}
+		return commit.Parents, err
+	}
+
+	for suffixes != "" {
+		op, end := suffixes[0], 1
+		for end < len(suffixes) && suffixes[end] >= '0' && suffixes[end] <= '9' {
+			end++
+		}
+		n := 1
+		if end > 1 {
+			if n, err = strconv.Atoi(suffixes[1:end]); err != nil {
+				return "", unknown
+			}
+		}
+		if op != '~' && op != '^' {
+			return "", unknown
+		}
+		suffixes = suffixes[end:]
+
+		if hash, err = r.peel(hash); err != nil {
+			return "", err
+		}
+		if op == '^' {
+			list, err := parents(hash)
+			if err != nil {
+				return "", err
+			}
+			if n > len(list) {
+				return "", unknown
+			}
+			if n > 0 {
+				hash = list[n-1]
+			}
+			continue
+		}
+		for range n {
+			list, err := parents(hash)
+			if err != nil {
+				return "", err
+			}
+			if len(list) == 0 {
+				return "", unknown
+			}
+			hash = list[0]
+		}
+	}
+	return hash, nil
+}
+
+// resolveName resolves rev without any ancestor suffixes, as [Repository.ResolveRevision] describes.
+func (repo *Repository) resolveName(rev string) (string, error) {
+	if rev == "HEAD" {
+		return repo.resolveSymbolic("HEAD")
+	}
+	if isPseudoRef(rev) {
+		hash, err := repo.resolvePseudoRef(rev)
+		if errors.Is(err, ErrRefNotFound) {
+			return "", fmt.Errorf("%w: %s", ErrUnknownRevision, rev)
+		}
+		return hash, err
+	}
+
+	if isFullHash(rev) {
+		if ok, err := repo.hasObject(rev); err != nil {
+			return "", err
+		} else if ok {
+			return rev, nil
+		}
+	}
+
+	candidates := []string{"refs/" + rev, "refs/tags/" + rev, "refs/heads/" + rev, "refs/remotes/" + rev, "refs/remotes/" + rev + "/HEAD"}
+	if strings.HasPrefix(rev, "refs/") {
+		candidates = []string{rev}
+	}
+
+	for _, name := range candidates {
+		hash, err := repo.resolveSymbolic(name)
+		if err == nil {
+			return hash, nil
+		}
+		if !errors.Is(err, ErrRefNotFound) {
+			return "", err
+		}
+	}
+	if isAbbrevHash(rev) {
+		return repo.resolveAbbrev(rev)
+	}
+	return "", fmt.Errorf("%w: %s", ErrUnknownRevision, rev)
+}
+
+// resolveSymbolic resolves name like [resolveRef] but follows "ref: " indirections, as found in HEAD.
+func (repo *Repository) resolveSymbolic(name string) (string, error) {
+	for range maxSymbolicDepth {
+		target, err := repo.resolveRef(name)
+		if err != nil {
+			return "", err
+		}
+
+		next, ok := strings.CutPrefix(target, "ref: ")
+		if !ok {
+			return target, nil
+		}
+		name = next
+	}
+	return "", fmt.Errorf("%w: %s", ErrSymbolicRefDepth, name)
+}
+
+// CurrentBranch returns the short name of the branch HEAD points to, or "" if HEAD is detached. Unlike
+// asking git, it only reads HEAD, which makes it cheap enough to call on every shell prompt.
+func (repo *Repository) CurrentBranch() (string, error) {
+	head, err := repo.resolveRef("HEAD")
+	if err != nil {
+		return "", err
+	}
+	target, ok := strings.CutPrefix(head, "ref: ")
+	if !ok {
+		return "", nil
+	}
+	return strings.TrimPrefix(target, "refs/heads/"), nil
+}
+
+func isFullHash(s string) bool {
+	if len(s) != 40 && len(s) != 64 {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// resolveRef returns the hash a fully qualified ref (e.g. refs/heads/main) points to.
+//
+// Loose refs take precedence over packed-refs, matching git. Symbolic refs are not followed.
+func (repo *Repository) resolveRef(name string) (string, error) {
+	refBytes, err := os.ReadFile(repo.refPath(name))
+	if err == nil {
+		return strings.TrimSpace(string(refBytes)), nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+
+	packed, err := readPackedRefs(repo.CommonDir)
+	if err != nil {
+		return "", err
+	}
+	if hash, ok := packed[name]; ok {
+		return hash, nil
+	}
+	return "", fmt.Errorf("%w: %s", ErrRefNotFound, name)
+}
+
+// Refs returns every ref under refs/, such as refs/heads/main or refs/tags/v1.0, mapped to the hash it
+// points to. Annotated tags map to the tag object rather than the commit it tags.
+func (repo *Repository) Refs() (map[string]string, error) {
+	return repo.listRefs("refs/")
+}
+
+// listRefs returns every ref under prefix (e.g. refs/replace/) mapped to the hash it points to.
+func (repo *Repository) listRefs(prefix string) (map[string]string, error) {
+	packed, err := readPackedRefs(repo.CommonDir)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := map[string]string{}
+	for name, hash := range packed {
+		if strings.HasPrefix(name, prefix) {
+			refs[name] = hash
+		}
+	}
+
+	base := repo.CommonDir
+	if isPerWorktreeRef(prefix) {
+		base = repo.GitDir
+	}
+	root := filepath.Join(base, filepath.FromSlash(prefix))
+	err = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fs.SkipAll
+		}
+		if err != nil || entry.IsDir() || strings.HasSuffix(path, ".lock") {
+			return err
+		}
+
+		refBytes, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(base, path)
+		if err != nil {
+			return err
+		}
+		refs[filepath.ToSlash(rel)] = strings.TrimSpace(string(refBytes))
+		return nil
+	})
+	return refs, err
+}
+
+// refPath returns where the loose file for the ref name lives.
+//
+// HEAD, pseudo refs like ORIG_HEAD, and refs under refs/worktree/, refs/bisect/, and refs/rewritten/ belong
+// to a single worktree. Everything else is shared through the common directory.
+func (repo *Repository) refPath(name string) string {
+	if isPerWorktreeRef(name) {
+		return filepath.Join(repo.GitDir, filepath.FromSlash(name))
+	}
+	return filepath.Join(repo.CommonDir, filepath.FromSlash(name))
+}
+
+func isPerWorktreeRef(name string) bool {
+	if !strings.Contains(name, "/") {
+		return true
+	}
+	for _, prefix := range []string{"refs/worktree/", "refs/bisect/", "refs/rewritten/"} {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// readPackedRefs parses .git/packed-refs into a map of ref names to hashes.
+// Peeled tag lines (^hash) and comments are skipped.
+func readPackedRefs(gitDir string) (map[string]string, error) {
+	refs := map[string]string{}
+
+	f, err := os.Open(filepath.Join(gitDir, "packed-refs"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return refs, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || line[0] == '#' || line[0] == '^' {
+			continue
+		}
+
+		hash, name, ok := strings.Cut(line, " ")
+		if !ok {
+			return nil, fmt.Errorf("git: malformed packed-refs line: %q", line)
+		}
+		refs[name] = hash
+	}
+	return refs, scanner.Err()
+}
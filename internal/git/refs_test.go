@@ -0,0 +1,47 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestResolveRevisionAncestors(t *testing.T) {
+	gitDir := newTestRepo(t)
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := writeTestCommit(t, gitDir, "root")
+	side := writeTestCommit(t, gitDir, "side", root)
+	middle := writeTestCommit(t, gitDir, "middle", root)
+	merge := writeTestCommit(t, gitDir, "merge", middle, side)
+	writeTestRef(t, gitDir, "refs/heads/main", merge)
+	tag := writeTestObject(t, gitDir, TagObject, fmt.Sprintf("object %s\ntype commit\ntag v1\ntagger Jane <jane@example.com> 1703123457 +0000\n\nv1\n", merge))
+	writeTestRef(t, gitDir, "refs/tags/v1", tag)
+
+	cases := map[string]string{
+		"main~":         middle,
+		"main~1":        middle,
+		"main~2":        root,
+		"main^":         middle,
+		"main^2":        side,
+		"main^2~1":      root,
+		"main^^":        root,
+		"main~0":        merge,
+		"v1^0":          merge,
+		"v1~1":          middle,
+		merge[:7] + "^": middle,
+	}
+	for rev, want := range cases {
+		if hash, err := repo.ResolveRevision(rev); err != nil || hash != want {
+			t.Errorf("ResolveRevision(%s) = %s, %v, want %s", rev, hash, err, want)
+		}
+	}
+
+	for _, rev := range []string{"main~3", "main^3", "main~x", "main^{tree}", "missing~1"} {
+		if _, err := repo.ResolveRevision(rev); !errors.Is(err, ErrUnknownRevision) {
+			t.Errorf("ResolveRevision(%s) = %v, want ErrUnknownRevision", rev, err)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrRefLocked  = errors.New("reference is locked by another process")
+	ErrRefChanged = errors.New("reference changed since it was read")
+)
+
+// ZeroHash is the all zero SHA-1 git uses to stand for "no object", e.g. as the old value of a new ref.
+var ZeroHash = strings.Repeat("0", 40)
+
+// UpdateRef moves the ref name (e.g. refs/heads/main) to newHash, but only if it currently points to oldHash.
+//
+// Pass [ZeroHash] as oldHash to require that the ref doesn't exist yet, or "" to skip the check. The ref is
+// locked with git's .lock file protocol while it is compared and written, so a concurrent git process or
+// plain invocation either sees the ref before or after the update, and never a torn write. If the lock is
+// held, [ErrRefLocked] is returned; if the ref moved, [ErrRefChanged].
+//
+// The update is recorded in the ref's reflog, and in HEAD's reflog when HEAD points to the ref, with who
+// as the identity and message as the reason. Updating a symbolic ref replaces it rather than the ref it
+// points to, so updating HEAD while a branch is checked out detaches it.
+func (repo *Repository) UpdateRef(name, oldHash, newHash string, who Signature, message string) error {
+	if !isFullHash(newHash) || isZeroHash(newHash) {
+		return fmt.Errorf("git: cannot point %s at %q", name, newHash)
+	}
+
+	path := repo.refPath(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	lock, err := os.OpenFile(path+".lock", os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if errors.Is(err, fs.ErrExist) {
+		return fmt.Errorf("%w: %s.lock exists", ErrRefLocked, name)
+	}
+	if err != nil {
+		return err
+	}
+
+	committed := false
+	defer func() {
+		if !committed {
+			lock.Close()
+			os.Remove(lock.Name())
+		}
+	}()
+
+	current, err := repo.resolveRef(name)
+	if err == nil && strings.HasPrefix(current, "ref: ") {
+		// A symbolic ref, like HEAD on a branch, is compared by the commit it leads to.
+		current, err = repo.resolveSymbolic(name)
+	}
+	if errors.Is(err, ErrRefNotFound) {
+		current = ZeroHash
+	} else if err != nil {
+		return err
+	}
+
+	if oldHash != "" && !(current == oldHash || isZeroHash(current) && isZeroHash(oldHash)) {
+		return fmt.Errorf("%w: %s is at %s, expected %s", ErrRefChanged, name, current, oldHash)
+	}
+
+	if _, err := lock.WriteString(newHash + "\n"); err != nil {
+		return err
+	}
+	if err := lock.Sync(); err != nil {
+		return err
+	}
+	if err := lock.Close(); err != nil {
+		return err
+	}
+
+	entry := ReflogEntry{Old: current, New: newHash, Committer: who, Message: message}
+	if err := repo.appendReflog(name, entry); err != nil {
+		return err
+	}
+	if head, err := repo.resolveRef("HEAD"); err == nil && head == "ref: "+name {
+		if err := repo.appendReflog("HEAD", entry); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Rename(lock.Name(), path); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
+
+// attachHead points HEAD at the branch ref (e.g. refs/heads/main), checking it out without touching the
+// index or work tree, and records the move in HEAD's reflog.
+func (repo *Repository) attachHead(ref string, who Signature, message string) error {
+	old, err := repo.resolveSymbolic("HEAD")
+	if err != nil {
+		return err
+	}
+	hash, err := repo.resolveRef(ref)
+	if err != nil {
+		return err
+	}
+
+	path := repo.refPath("HEAD")
+	lock, err := os.OpenFile(path+".lock", os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if errors.Is(err, fs.ErrExist) {
+		return fmt.Errorf("%w: HEAD.lock exists", ErrRefLocked)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := lock.WriteString("ref: " + ref + "\n"); err != nil {
+		lock.Close()
+		os.Remove(lock.Name())
+		return err
+	}
+	if err := lock.Close(); err != nil {
+		os.Remove(lock.Name())
+		return err
+	}
+
+	if err := repo.appendReflog("HEAD", ReflogEntry{Old: old, New: hash, Committer: who, Message: message}); err != nil {
+		os.Remove(lock.Name())
+		return err
+	}
+	return os.Rename(lock.Name(), path)
+}
+
+// appendReflog adds entry to the reflog of name. Like git's default core.logAllRefUpdates, logs are
+// kept for HEAD, branches, remote-tracking branches, notes, and the stash, whose reflog holds every
+// stash but the latest, and for any ref that already has one.
+func (repo *Repository) appendReflog(name string, entry ReflogEntry) error {
+	path := repo.reflogPath(name)
+
+	logged := name == "HEAD" || name == stashRef
+	for _, prefix := range []string{"refs/heads/", "refs/remotes/", "refs/notes/"} {
+		logged = logged || strings.HasPrefix(name, prefix)
+	}
+	if _, err := os.Stat(path); err != nil && !logged {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// Reflog messages are a single line.
+	message := strings.ReplaceAll(strings.TrimSpace(entry.Message), "\n", " ")
+	_, err = fmt.Fprintf(f, "%s %s %s\t%s\n", entry.Old, entry.New, formatSignature(entry.Committer), message)
+	return err
+}
+
+func isZeroHash(hash string) bool {
+	return hash != "" && strings.Trim(hash, "0") == ""
+}
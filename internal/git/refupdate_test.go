@@ -0,0 +1,69 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUpdateRef(t *testing.T) {
+	gitDir := newTestRepo(t)
+	os.WriteFile(filepath.Join(gitDir, "HEAD"), []byte("ref: refs/heads/main\n"), 0o644)
+	first := writeTestCommit(t, gitDir, "first")
+	second := writeTestCommit(t, gitDir, "second", first)
+
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	who := Signature{Name: "Jane Smith", Email: "jane.smith@example.com", Time: time.Unix(1703123457, 0).UTC()}
+
+	if err := repo.UpdateRef("refs/heads/main", ZeroHash, first, who, "branch: Created from first"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Creating again must fail because the ref now exists.
+	if err := repo.UpdateRef("refs/heads/main", ZeroHash, second, who, "again"); !errors.Is(err, ErrRefChanged) {
+		t.Fatalf("expected ErrRefChanged, got %v", err)
+	}
+
+	if err := repo.UpdateRef("refs/heads/main", first, second, who, "commit: second"); err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := repo.resolveRef("refs/heads/main")
+	if err != nil || hash != second {
+		t.Fatalf("expected main at %s, got %s (%v)", second[:7], hash, err)
+	}
+
+	for _, name := range []string{"refs/heads/main", "HEAD"} {
+		entries, err := repo.Reflog(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 2 || entries[1].Old != first || entries[1].New != second || entries[1].Message != "commit: second" {
+			t.Fatalf("unexpected %s reflog: %+v", name, entries)
+		}
+	}
+}
+
+func TestUpdateRefLocked(t *testing.T) {
+	gitDir := newTestRepo(t)
+	commit := writeTestCommit(t, gitDir, "commit")
+	repo, _ := OpenRepository()
+
+	lockPath := filepath.Join(gitDir, "refs", "heads", "main.lock")
+	os.WriteFile(lockPath, nil, 0o644)
+
+	err := repo.UpdateRef("refs/heads/main", "", commit, Signature{Name: "Jane", Email: "jane@example.com"}, "update")
+	if !errors.Is(err, ErrRefLocked) {
+		t.Fatalf("expected ErrRefLocked, got %v", err)
+	}
+
+	// Someone else's lock must be left alone.
+	if _, err := os.Stat(lockPath); err != nil {
+		t.Fatal("lock held by another process was removed")
+	}
+}
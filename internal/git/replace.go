@@ -0,0 +1,337 @@
+package git
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// commitReader loads commits from the object store, applying replace refs and grafts.
+//
+// A replaced commit keeps its original hash but takes the content of its replacement, and a grafted
+// commit has its parents overridden, which is how stock git presents both.
+type commitReader struct {
+	objectsPath string
+	format      HashFormat
+	replace     map[string]string   // Original hash to replacement hash, from refs/replace/*.
+	grafts      map[string][]string // Commit hash to the parents it should have, from info/grafts.
+	d           *Decoder
+	packs       *packStore // Read the first time an object isn't found loose
+}
+
+// newCommitReader returns a reader for repo's objects. Replace refs are ignored when GIT_NO_REPLACE_OBJECTS
+// is set, and like git, ones whose name or target isn't an object name are skipped.
+func newCommitReader(repo *Repository) (*commitReader, error) {
+	replace := map[string]string{}
+	if _, ok := os.LookupEnv("GIT_NO_REPLACE_OBJECTS"); !ok {
+		refs, err := repo.listRefs("refs/replace/")
+		if err != nil {
+			return nil, fmt.Errorf("git: failed to read replace refs: %w", err)
+		}
+		for name, hash := range refs {
+			original := strings.TrimPrefix(name, "refs/replace/")
+			if !isFormatHash(repo.Format, original) || !isFormatHash(repo.Format, hash) {
+				logAt(slog.LevelWarn, "skipped bad replace ref", "ref", name, "target", hash)
+				continue
+			}
+			replace[original] = hash
+		}
+	}
+
+	grafts, err := readGrafts(repo.CommonDir)
+	if err != nil {
+		return nil, fmt.Errorf("git: failed to read grafts: %w", err)
+	}
+
+	return &commitReader{
+		objectsPath: filepath.Join(repo.CommonDir, "objects"),
+		format:      repo.Format,
+		replace:     replace,
+		grafts:      grafts,
+	}, nil
+}
+
+// read decodes the commit with the given hash. The boolean is false if the object is not a commit.
+func (r *commitReader) read(hash string) (Commit, bool, error) {
+	header, closer, err := r.open(hash)
+	if err != nil {
+		return Commit{}, false, err
+	}
+	defer closer.Close()
+
+	if header.Kind != CommitObject {
+		return Commit{}, false, nil
+	}
+
+	commit, err := r.d.DecodeCommit(hash)
+	if err != nil {
+		return Commit{}, false, err
+	}
+
+	if parents, ok := r.grafts[hash]; ok {
+		commit.Parents = parents
+	}
+	return commit, true, nil
+}
+
+// tree decodes the tree object with the given hash.
+func (r *commitReader) tree(hash string) ([]TreeEntry, error) {
+	header, closer, err := r.open(hash)
+	if err != nil {
+		return nil, err
+	}
+	defer closer.Close()
+
+	if header.Kind != TreeObject {
+		return nil, fmt.Errorf("git: %s is a %s, not a tree", hash, header.Kind)
+	}
+	return r.d.DecodeTree()
+}
+
+// blob reads the contents of the blob object with the given hash.
+func (r *commitReader) blob(hash string) ([]byte, error) {
+	header, closer, err := r.open(hash)
+	if err != nil {
+		return nil, err
+	}
+	defer closer.Close()
+
+	if header.Kind != BlobObject {
+		return nil, fmt.Errorf("git: %s is a %s, not a blob", hash, header.Kind)
+	}
+	return r.d.DecodeBlob()
+}
+
+// raw reads the kind and content of the object with the given hash, whatever its kind.
+func (r *commitReader) raw(hash string) (GitObjectKind, []byte, error) {
+	header, closer, err := r.open(hash)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer closer.Close()
+
+	data, err := r.d.DecodeBlob()
+	return header.Kind, data, err
+}
+
+// flatten reads the tree with the given hash and every tree beneath it into files, keyed by slash
+// separated path with prefix prepended.
+func (r *commitReader) flatten(hash, prefix string, files map[string]TreeEntry) error {
+	entries, err := r.tree(hash)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.Mode == ModeTree {
+			if err := r.flatten(entry.Hash, prefix+entry.Name+"/", files); err != nil {
+				return err
+			}
+			continue
+		}
+		files[prefix+entry.Name] = entry
+	}
+	return nil
+}
+
+// entryAt returns the entry at the slash separated path within the tree with the given hash.
+// The boolean is false if nothing exists at path.
+func (r *commitReader) entryAt(tree, path string) (TreeEntry, bool, error) {
+	entry := TreeEntry{Mode: ModeTree, Hash: tree}
+	for _, name := range strings.Split(strings.Trim(path, "/"), "/") {
+		if entry.Mode != ModeTree {
+			return TreeEntry{}, false, nil
+		}
+		entries, err := r.tree(entry.Hash)
+		if err != nil {
+			return TreeEntry{}, false, err
+		}
+		i := slices.IndexFunc(entries, func(e TreeEntry) bool { return e.Name == name })
+		if i == -1 {
+			return TreeEntry{}, false, nil
+		}
+		entry = entries[i]
+	}
+	return entry, true, nil
+}
+
+// peel follows annotated tags from hash until it reaches an object that is not a tag, returning that object's hash.
+func (r *commitReader) peel(hash string) (string, error) {
+	for range maxSymbolicDepth {
+		header, closer, err := r.open(hash)
+		if err != nil {
+			return "", err
+		}
+		if header.Kind != TagObject {
+			closer.Close()
+			return hash, nil
+		}
+
+		tag, err := r.d.DecodeTag(hash)
+		closer.Close()
+		if err != nil {
+			return "", err
+		}
+		hash = tag.Object
+	}
+	return "", fmt.Errorf("git: tag nesting too deep at %s", hash)
+}
+
+// open primes the decoder with the object stored under hash, or its replacement, and reads its header.
+// Objects that aren't stored loose are looked for in the packs. The returned closer must be closed once
+// the object has been decoded.
+func (r *commitReader) open(hash string) (ObjectHeader, io.Closer, error) {
+	stored := hash
+	if replacement, ok := r.replace[hash]; ok {
+		stored = replacement
+	}
+	if !isFormatHash(r.format, stored) {
+		return ObjectHeader{}, nil, fmt.Errorf("git: bad object name %q", stored)
+	}
+
+	path := filepath.Join(r.objectsPath, stored[:2], stored[2:])
+	objStream, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return r.openPacked(stored)
+	}
+	if err != nil {
+		return ObjectHeader{}, nil, err
+	}
+	if logging(slog.LevelDebug) {
+		logAt(slog.LevelDebug, "read object", "path", path)
+	}
+
+	if r.d == nil {
+		r.d, err = NewDecoder(objStream)
+		if err != nil {
+			objStream.Close()
+			return ObjectHeader{}, nil, fmt.Errorf("git: failed to init object decoder: %w", err)
+		}
+		r.d.hashSize = r.format.HexSize() / 2
+	} else if err := r.d.Reset(objStream); err != nil {
+		objStream.Close()
+		return ObjectHeader{}, nil, err
+	}
+
+	header, err := r.d.Header()
+	if err != nil {
+		objStream.Close()
+		return ObjectHeader{}, nil, fmt.Errorf("git: failed to parse header: %w", err)
+	}
+	observe(func(o Observer) {
+		o.ObjectRead(header.Kind, header.Size, false)
+		o.Inflated(header.Size)
+	})
+	return header, objStream, nil
+}
+
+// openPacked primes the decoder with the packed object named hash, failing with [fs.ErrNotExist] if no
+// pack has it.
+func (r *commitReader) openPacked(hash string) (ObjectHeader, io.Closer, error) {
+	if r.packs == nil {
+		packs, err := openPacks(r.objectsPath, r.format)
+		if err != nil {
+			return ObjectHeader{}, nil, fmt.Errorf("git: failed to read packs: %w", err)
+		}
+		r.packs = packs
+	}
+	kind, data, err := r.packs.read(hash)
+	if err != nil {
+		return ObjectHeader{}, nil, err
+	}
+
+	if r.d == nil {
+		r.d = &Decoder{hashSize: r.format.HexSize() / 2}
+	}
+	r.d.resetInflated(kind, data)
+	header, err := r.d.Header()
+	if err != nil {
+		return ObjectHeader{}, nil, fmt.Errorf("git: failed to parse header: %w", err)
+	}
+	observe(func(o Observer) { o.ObjectRead(header.Kind, header.Size, true) })
+	return header, io.NopCloser(nil), nil
+}
+
+// walk reads every commit reachable from the hashes on stack into history.Graph, skipping commits already
+// present. Walking stops at commits in shallow, which are recorded in history.Shallow.
+func (r *commitReader) walk(history *BranchHistory, shallow map[string]bool, stack []string) error {
+	return r.walkLineage(history, shallow, stack, AllParents)
+}
+
+// walkLineage is walk, following only the parents lineage selects.
+func (r *commitReader) walkLineage(history *BranchHistory, shallow map[string]bool, stack []string, lineage Lineage) error {
+	defer phase("history")()
+	for len(stack) > 0 {
+		currCommitHash := stack[len(stack)-1] // get last element
+		stack = stack[:len(stack)-1]          // remove it (pop)
+
+		if _, ok := history.Graph[currCommitHash]; ok {
+			continue
+		}
+
+		commit, ok, err := r.read(currCommitHash)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		history.Graph[currCommitHash] = commit
+
+		// Parents of a shallow commit were never fetched, so stop walking here.
+		if shallow[currCommitHash] {
+			history.Shallow[currCommitHash] = true
+			continue
+		}
+
+		for _, parent := range lineage.parents(commit) {
+			if _, ok := history.Graph[parent]; !ok {
+				stack = append(stack, parent)
+			}
+		}
+	}
+	return nil
+}
+
+func (r *commitReader) Close() error {
+	var errs []error
+	if r.packs != nil {
+		errs = append(errs, r.packs.Close())
+	}
+	if r.d != nil {
+		errs = append(errs, r.d.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// readGrafts parses .git/info/grafts, where each line is a commit hash followed by the parents it should have.
+// A commit listed without parents becomes a root.
+func readGrafts(gitDir string) (map[string][]string, error) {
+	grafts := map[string][]string{}
+
+	f, err := os.Open(filepath.Join(gitDir, "info", "grafts"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return grafts, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		grafts[fields[0]] = fields[1:]
+	}
+	return grafts, scanner.Err()
+}
@@ -0,0 +1,129 @@
+package git
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Repository describes where the pieces of a git repository live on disk.
+//
+// A regular checkout keeps everything in one .git directory. A linked worktree (git worktree add) has its
+// own git directory for HEAD, the index, and per-worktree refs, and shares objects, branches, and config
+// with the main repository through the common directory. For a regular checkout GitDir and CommonDir are equal.
+type Repository struct {
+	WorkTree  string     // The root of the checked out files
+	GitDir    string     // The git directory belonging to this worktree
+	CommonDir string     // The git directory shared by every worktree of the repository
+	Format    HashFormat // How objects are named, from extensions.objectFormat in the repository's config
+}
+
+// OpenRepository locates the repository containing the current working directory.
+// Will return [ErrNotRepo] if called from outside a git repository.
+func OpenRepository() (*Repository, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	var gitDir string
+	var info fs.FileInfo
+	for {
+		gitDir = filepath.Join(cwd, ".git")
+		info, err = os.Stat(gitDir)
+
+		if err == nil {
+			break
+		}
+
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+
+		up := filepath.Dir(cwd)
+		if up == cwd {
+			return nil, ErrNotRepo
+		}
+		cwd = up
+	}
+
+	if !info.IsDir() {
+		gitDir, err = readPointerFile(gitDir, "gitdir: ")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	gitDir, err = filepath.Abs(gitDir)
+	if err != nil {
+		return nil, err
+	}
+
+	commonDir := gitDir
+	if pointer, err := readPointerFile(filepath.Join(gitDir, "commondir"), ""); err == nil {
+		commonDir = filepath.Clean(pointer)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	repo := &Repository{WorkTree: cwd, GitDir: gitDir, CommonDir: commonDir}
+	if repo.Format, err = readObjectFormat(commonDir); err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+// readObjectFormat returns the hash format the repository with the given common directory uses. Git
+// only honours extensions in the repository's own config, so the other config files aren't read.
+func readObjectFormat(commonDir string) (HashFormat, error) {
+	name := filepath.Join(commonDir, "config")
+	data, err := os.ReadFile(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return SHA1, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	entries, err := ParseConfig(data, name)
+	if err != nil {
+		return 0, err
+	}
+	return (&Config{entries: entries}).ObjectFormat()
+}
+
+// objectPath returns where the loose object with the given hash is stored.
+func (repo *Repository) objectPath(hash string) string {
+	return filepath.Join(repo.CommonDir, "objects", hash[:2], hash[2:])
+}
+
+// hasObject reports whether the object named hash is stored, either loose or in a pack.
+func (repo *Repository) hasObject(hash string) (bool, error) {
+	if _, err := os.Stat(repo.objectPath(hash)); err == nil {
+		return true, nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return false, err
+	}
+	packs, err := openPacks(filepath.Join(repo.CommonDir, "objects"), repo.Format)
+	if err != nil {
+		return false, err
+	}
+	defer packs.Close()
+	return packs.has(hash), nil
+}
+
+// readPointerFile reads a file holding a path to another location, such as a .git file or commondir.
+// Relative paths are resolved against the directory containing the file.
+func readPointerFile(name, prefix string) (string, error) {
+	fileBytes, err := os.ReadFile(name)
+	if err != nil {
+		return "", err
+	}
+
+	target := strings.TrimSpace(strings.TrimPrefix(string(fileBytes), prefix))
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(name), target)
+	}
+	return target, nil
+}
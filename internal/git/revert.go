@@ -0,0 +1,32 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Revert commits a new commit on HEAD that undoes the changes the commit rev made, with who as its author
+// and committer, like git revert. It returns the new commit's hash.
+//
+// It is a cherry-pick in reverse: the changes from rev back to its parent are merged onto HEAD, so later
+// commits touching the same files are kept where they don't overlap. A merge commit is reverted relative
+// to the parent opts.Mainline chooses, undoing what the other parents brought in. Conflicts stop the
+// revert with rev recorded in REVERT_HEAD and an error wrapping [ErrPickConflict], to be finished by
+// [Repository.ContinuePick] or given up by [Repository.AbortPick]. If HEAD doesn't have the changes any
+// more, [ErrEmptyPick] is returned.
+func (repo *Repository) Revert(rev string, who Signature, opts PickOptions) (string, error) {
+	commit, parent, err := repo.pickTarget(rev, opts.Mainline)
+	if err != nil {
+		return "", err
+	}
+
+	subject, _, _ := strings.Cut(commit.Message, "\n")
+	message := "Revert \"" + subject + "\"\n\nThis reverts commit " + commit.Hash
+	if opts.Mainline > 0 {
+		message += fmt.Sprintf(", reversing\nchanges made to %s", commit.Parents[opts.Mainline-1])
+	}
+	message += "."
+
+	label := "parent of " + commit.Hash[:7] + " (" + subject + ")"
+	return repo.pick(commit, commit.Tree, parent, label, revertHead, who, who, message, "revert: "+subject)
+}
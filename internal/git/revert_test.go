@@ -0,0 +1,85 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRevert(t *testing.T) {
+	newTestRepo(t)
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stageTestFiles(t, repo, map[string]string{"f.txt": "a\nb\nc\nd\ne\n"})
+	commitTestIndex(t, repo)
+	who := Signature{Name: "Ann", Email: "ann@example.com", Time: time.Unix(1703130000, 0)}
+
+	commitFiles := func(files map[string]string, message string) string {
+		t.Helper()
+		stageTestFiles(t, repo, files)
+		head, _ := repo.ResolveRevision("HEAD")
+		idx, _ := repo.Index()
+		hash, err := repo.commitIndex(idx, head, who, who, message, "commit")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hash
+	}
+	changeB := commitFiles(map[string]string{"f.txt": "a\nB\nc\nd\ne\n"}, "Change b")
+	commitFiles(map[string]string{"f.txt": "a\nB\nc\nd\nE\n"}, "Change e")
+
+	hash, err := repo.Revert(changeB, who, PickOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile("f.txt"); string(data) != "a\nb\nc\nd\nE\n" {
+		t.Errorf("f.txt = %q, want only b reverted", data)
+	}
+	commit, err := repo.ReadCommit(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Revert \"Change b\"\n\nThis reverts commit " + changeB + "."
+	if commit.Message != want || commit.Author.Name != "Ann" {
+		t.Errorf("revert has message %q by %s, want %q", commit.Message, commit.Author.Name, want)
+	}
+	if _, err := repo.Revert(changeB, who, PickOptions{}); !errors.Is(err, ErrEmptyPick) {
+		t.Errorf("reverting twice: got %v", err)
+	}
+
+	// Merge a side branch that adds a file, then undo the merge.
+	head, _ := repo.ResolveRevision("HEAD")
+	side := writeTestTreeCommit(t, repo, head, who, "Add new", map[string]string{"f.txt": "a\nb\nc\nd\nE\n", "new.txt": "new\n"})
+	stageTestFiles(t, repo, map[string]string{"new.txt": "new\n"})
+	idx, _ := repo.Index()
+	tree, err := idx.WriteTree(repo.Encoder())
+	if err != nil {
+		t.Fatal(err)
+	}
+	merge, err := repo.CreateCommit(tree, []string{head, side}, who, who, "Merge side")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.UpdateRef("refs/heads/main", head, merge, who, "merge"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.Revert(merge, who, PickOptions{}); err == nil {
+		t.Error("expected reverting a merge without a mainline to fail")
+	}
+	hash, err = repo.Revert(merge, who, PickOptions{Mainline: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat("new.txt"); !os.IsNotExist(err) {
+		t.Error("new.txt should be removed by undoing the merge")
+	}
+	commit, _ = repo.ReadCommit(hash)
+	if !strings.Contains(commit.Message, "reversing\nchanges made to "+head+".") {
+		t.Errorf("merge revert message = %q", commit.Message)
+	}
+}
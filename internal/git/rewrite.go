@@ -0,0 +1,169 @@
+package git
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// RewriteOptions says what [Repository.RewriteHistory] changes in every commit.
+type RewriteOptions struct {
+	// RemovePaths are files or directories, as slash separated paths from the top of the work tree,
+	// removed from every commit they appear in.
+	RemovePaths []string
+
+	// Emails maps author and committer email addresses, compared ignoring case, to the addresses that
+	// replace them.
+	Emails map[string]string
+
+	// Message, when set, returns the message for a commit that is being rewritten for one of the reasons
+	// above, given the commit as it was, such as to add a trailer it is missing. Commits that don't
+	// otherwise change keep their messages.
+	Message func(commit Commit) string
+}
+
+// RewriteResult is the outcome of [Repository.RewriteHistory].
+type RewriteResult struct {
+	Head      string            // The rewritten commit the history ends at
+	Rewritten map[string]string // Old hash to new hash of every commit that changed
+}
+
+// RewriteHistory rewrites every commit reachable from rev as opts says, like git filter-repo, and returns
+// the rewritten head. No ref is moved: the result is only reachable through the returned hash.
+//
+// A commit changes when its tree, identities, or parents do, so everything built on a rewritten commit
+// is rewritten too. Changed commits lose their signatures, which no longer match, but keep their other
+// headers, messages, and dates. Directories left empty by removed paths are dropped.
+func (repo *Repository) RewriteHistory(rev string, opts RewriteOptions) (RewriteResult, error) {
+	hash, err := repo.ResolveRevision(rev)
+	if err != nil {
+		return RewriteResult{}, err
+	}
+	history, err := repo.historyFrom(hash)
+	if err != nil {
+		return RewriteResult{}, err
+	}
+
+	r, err := newCommitReader(repo)
+	if err != nil {
+		return RewriteResult{}, err
+	}
+	defer r.Close()
+
+	rw := rewriter{repo: repo, r: r, remove: map[string]bool{}, trees: map[string]string{}}
+	for _, path := range opts.RemovePaths {
+		path = strings.Trim(path, "/")
+		if path == "" {
+			return RewriteResult{}, fmt.Errorf("git: cannot remove the whole tree")
+		}
+		rw.remove[path] = true
+	}
+	emails := map[string]string{}
+	for old, new := range opts.Emails {
+		emails[strings.ToLower(old)] = new
+	}
+
+	result := RewriteResult{Head: history.Head.Hash, Rewritten: map[string]string{}}
+	for _, commit := range slices.Backward(topoOrder(history.Graph)) {
+		rewritten := commit
+		if rewritten.Tree, err = rw.tree(commit.Tree, ""); err != nil {
+			return RewriteResult{}, err
+		}
+		rewritten.Parents = make([]string, len(commit.Parents))
+		for i, parent := range commit.Parents {
+			rewritten.Parents[i] = parent
+			if mapped, ok := result.Rewritten[parent]; ok {
+				rewritten.Parents[i] = mapped
+			}
+		}
+		for _, sig := range []*Signature{&rewritten.Author, &rewritten.Committer} {
+			if email, ok := emails[strings.ToLower(sig.Email)]; ok {
+				sig.Email = email
+			}
+		}
+
+		if rewritten.Tree == commit.Tree && slices.Equal(rewritten.Parents, commit.Parents) &&
+			rewritten.Author == commit.Author && rewritten.Committer == commit.Committer {
+			continue
+		}
+		if opts.Message != nil {
+			rewritten.Message = opts.Message(commit)
+		}
+		rewritten.Headers = slices.DeleteFunc(slices.Clone(commit.Headers), func(h CommitHeader) bool {
+			return h.Name == "gpgsig" || h.Name == "gpgsig-sha256"
+		})
+		data, err := EncodeCommit(rewritten)
+		if err != nil {
+			return RewriteResult{}, fmt.Errorf("git: cannot rewrite %s: %w", commit.Hash, err)
+		}
+		newHash, err := repo.Encoder().Encode(CommitObject, data)
+		if err != nil {
+			return RewriteResult{}, err
+		}
+		result.Rewritten[commit.Hash] = newHash
+	}
+
+	if mapped, ok := result.Rewritten[result.Head]; ok {
+		result.Head = mapped
+	}
+	return result, nil
+}
+
+// rewriter removes paths from trees, remembering the trees it has rewritten since most are shared
+// between neighbouring commits.
+type rewriter struct {
+	repo   *Repository
+	r      *commitReader
+	remove map[string]bool
+	trees  map[string]string // prefix and tree hash to the rewritten tree's hash
+}
+
+// tree returns the hash of the tree hash, found at prefix ("" or a directory path ending in a slash),
+// without the removed paths, or "" if nothing is left of a subtree.
+func (rw *rewriter) tree(hash, prefix string) (string, error) {
+	affected := false
+	for path := range rw.remove {
+		affected = affected || strings.HasPrefix(path, prefix)
+	}
+	if !affected {
+		return hash, nil
+	}
+	key := prefix + "\x00" + hash
+	if rewritten, ok := rw.trees[key]; ok {
+		return rewritten, nil
+	}
+
+	entries, err := rw.r.tree(hash)
+	if err != nil {
+		return "", err
+	}
+	var kept []TreeEntry
+	for _, entry := range entries {
+		path := prefix + entry.Name
+		if rw.remove[path] {
+			continue
+		}
+		if entry.Mode == ModeTree {
+			if entry.Hash, err = rw.tree(entry.Hash, path+"/"); err != nil {
+				return "", err
+			}
+			if entry.Hash == "" {
+				continue
+			}
+		}
+		kept = append(kept, entry)
+	}
+
+	rewritten := ""
+	if len(kept) > 0 || prefix == "" {
+		data, err := serializeTree(kept)
+		if err != nil {
+			return "", err
+		}
+		if rewritten, err = rw.repo.Encoder().Encode(TreeObject, data); err != nil {
+			return "", err
+		}
+	}
+	rw.trees[key] = rewritten
+	return rewritten, nil
+}
@@ -0,0 +1,90 @@
+package git
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRewriteHistory(t *testing.T) {
+	newTestRepo(t)
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stageTestFiles(t, repo, map[string]string{"README": "hi\n"})
+	commitTestIndex(t, repo)
+	root, _ := repo.ResolveRevision("HEAD")
+
+	right := Signature{Name: "Ann", Email: "ann@example.com", Time: time.Unix(1703130000, 0)}
+	wrong := Signature{Name: "Ann", Email: "Ann@Laptop.local", Time: time.Unix(1703130000, 0)}
+	leak := writeTestTreeCommit(t, repo, root, wrong, "Add config", map[string]string{
+		"README": "hi\n", "config/secret.env": "TOKEN=x\n", "config/app.toml": "a = 1\n",
+	})
+	tip := writeTestTreeCommit(t, repo, leak, right, "Add docs", map[string]string{
+		"README": "hi\n", "config/secret.env": "TOKEN=x\n", "config/app.toml": "a = 1\n", "docs/guide.md": "guide\n",
+	})
+
+	result, err := repo.RewriteHistory(tip, RewriteOptions{
+		RemovePaths: []string{"config/secret.env"},
+		Emails:      map[string]string{"ann@laptop.local": "ann@example.com"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Rewritten) != 2 || result.Rewritten[tip] != result.Head {
+		t.Fatalf("rewritten = %v, head %s", result.Rewritten, result.Head)
+	}
+	if _, ok := result.Rewritten[root]; ok {
+		t.Error("the root commit didn't change but was rewritten")
+	}
+
+	head, err := repo.ReadCommit(result.Head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := repo.ReadTreeFiles(head.Tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := files["config/secret.env"]; ok || len(files) != 3 {
+		t.Errorf("rewritten tree has %v", files)
+	}
+	parent, err := repo.ReadCommit(head.Parents[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parent.Hash != result.Rewritten[leak] || parent.Author.Email != "ann@example.com" || parent.Message != "Add config" {
+		t.Errorf("rewritten parent = %+v", parent)
+	}
+	if !parent.Author.Time.Equal(wrong.Time) {
+		t.Errorf("author date changed to %s", parent.Author.Time)
+	}
+
+	// Removing a whole directory drops it from the tree.
+	result, err = repo.RewriteHistory(tip, RewriteOptions{RemovePaths: []string{"config/"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	head, _ = repo.ReadCommit(result.Head)
+	files, _ = repo.ReadTreeFiles(head.Tree)
+	if len(files) != 2 {
+		t.Errorf("tree without config has %v", files)
+	}
+
+	// Only the commits rewritten anyway get new messages.
+	result, err = repo.RewriteHistory(tip, RewriteOptions{
+		Emails:  map[string]string{"ann@laptop.local": "ann@example.com"},
+		Message: func(commit Commit) string { return commit.Message + "\n\nChange-Id: I1" },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	head, _ = repo.ReadCommit(result.Head)
+	parent, _ = repo.ReadCommit(head.Parents[0])
+	if head.Message != "Add docs\n\nChange-Id: I1" || parent.Message != "Add config\n\nChange-Id: I1" {
+		t.Errorf("messages = %q, %q", head.Message, parent.Message)
+	}
+	if _, ok := result.Rewritten[root]; ok {
+		t.Error("the root commit didn't change but was given a new message")
+	}
+}
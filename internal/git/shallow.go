@@ -0,0 +1,84 @@
+package git
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// readShallow returns the set of commits listed in the repository's shallow file.
+//
+// A shallow clone records its graft points in .git/shallow, one hash per line. The parents of these
+// commits are not present in the object store. A repository without a shallow file yields an empty set.
+func readShallow(gitDir string) (map[string]bool, error) {
+	shallow := map[string]bool{}
+
+	f, err := os.Open(filepath.Join(gitDir, "shallow"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return shallow, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		hash := strings.TrimSpace(scanner.Text())
+		if hash != "" {
+			shallow[hash] = true
+		}
+	}
+	return shallow, scanner.Err()
+}
+
+// updateShallow records the shallow-info a remote sent with a pack in the repository's shallow file,
+// removing the file once no commit in it is left cut off. Like git, the file is replaced through
+// shallow.lock.
+func (repo *Repository) updateShallow(update shallowUpdate) error {
+	if len(update.shallow) == 0 && len(update.unshallow) == 0 {
+		return nil
+	}
+	shallow, err := readShallow(repo.CommonDir)
+	if err != nil {
+		return err
+	}
+	for _, hash := range update.unshallow {
+		delete(shallow, hash)
+	}
+	for _, hash := range update.shallow {
+		shallow[hash] = true
+	}
+
+	path := filepath.Join(repo.CommonDir, "shallow")
+	lock, err := os.OpenFile(path+".lock", os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if errors.Is(err, fs.ErrExist) {
+		return fmt.Errorf("git: cannot update the shallow file: %s.lock exists", path)
+	}
+	if err != nil {
+		return err
+	}
+	defer os.Remove(lock.Name())
+	for _, hash := range slices.Sorted(maps.Keys(shallow)) {
+		if _, err := lock.WriteString(hash + "\n"); err != nil {
+			lock.Close()
+			return err
+		}
+	}
+	if err := lock.Close(); err != nil {
+		return err
+	}
+	if len(shallow) == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	return os.Rename(lock.Name(), path)
+}
@@ -0,0 +1,202 @@
+package git
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+var ErrSigningFailed = errors.New("failed to sign")
+
+// CommitSigner signs the encoding of a commit, returning the armored signature to store in its header.
+type CommitSigner interface {
+	Sign(payload []byte) (string, error)
+}
+
+// GPGSigner signs with gpg, or another program that takes the same arguments, as git commit -S does.
+type GPGSigner struct {
+	Program string // The program to run, gpg when empty
+	Key     string // The key to sign with, as gpg's --local-user takes it
+}
+
+// Sign makes a detached, armored signature of payload, checking that the program reports having made
+// one, since gpg can exit successfully without.
+func (s GPGSigner) Sign(payload []byte) (string, error) {
+	program := cmp.Or(s.Program, "gpg")
+	cmd := exec.Command(program, "--status-fd=2", "-bsau", s.Key)
+	cmd.Stdin = bytes.NewReader(payload)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err := cmd.Run()
+	if err == nil && !strings.Contains("\n"+stderr.String(), "\n[GNUPG:] SIG_CREATED ") {
+		err = errors.New("it made no signature")
+	}
+	if err != nil {
+		return "", fmt.Errorf("%w with %s using %s: %w%s", ErrSigningFailed, program, s.Key, err, signerOutput(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// signerOutput returns what a signing program said on standard error, other than gpg's status lines, to
+// explain a failure.
+func signerOutput(stderr string) string {
+	var lines []string
+	for _, line := range strings.Split(stderr, "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "[GNUPG:]") {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return "\n" + strings.Join(lines, "\n")
+}
+
+// SSHSigner signs with ssh-keygen -Y sign, or another program that takes the same arguments, as git does
+// with gpg.format ssh.
+type SSHSigner struct {
+	Program string // The program to run, ssh-keygen when empty
+	Key     string // A private key file, or a public key file or literal public key the agent holds
+}
+
+// Sign makes an SSH signature of payload in the git namespace. A literal key, given as "key::" and the
+// public key or as the public key alone, is written to a file for ssh-keygen, which then signs with the
+// agent.
+func (s SSHSigner) Sign(payload []byte) (string, error) {
+	program := cmp.Or(s.Program, "ssh-keygen")
+	dir, err := os.MkdirTemp("", "plain-sign-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	args := []string{"-Y", "sign", "-n", "git", "-f", s.Key}
+	if key, ok := literalSSHKey(s.Key); ok {
+		args[5] = filepath.Join(dir, "key.pub")
+		if err := os.WriteFile(args[5], []byte(key+"\n"), 0o600); err != nil {
+			return "", err
+		}
+		args = append(args, "-U")
+	}
+	buffer := filepath.Join(dir, "payload")
+	if err := os.WriteFile(buffer, payload, 0o600); err != nil {
+		return "", err
+	}
+	cmd := exec.Command(program, append(args, buffer)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	signature, readErr := os.ReadFile(buffer + ".sig")
+	if err == nil && readErr != nil {
+		err = errors.New("it made no signature")
+	}
+	if err != nil {
+		return "", fmt.Errorf("%w with %s using %s: %w%s", ErrSigningFailed, program, s.Key, err, signerOutput(stderr.String()))
+	}
+	return string(signature), nil
+}
+
+// literalSSHKey returns the public key in a user.signingkey that gives one literally rather than naming a
+// file, as git recognizes them.
+func literalSSHKey(key string) (string, bool) {
+	if rest, ok := strings.CutPrefix(key, "key::"); ok {
+		return rest, true
+	}
+	return key, strings.HasPrefix(key, "ssh-")
+}
+
+// CommitSigner returns how the repository's config asks for commits to be signed, for committer, or nil
+// if commit.gpgsign isn't set. gpg.format chooses between openpgp, run with gpg.program (or
+// gpg.openpgp.program); x509, run with gpg.x509.program, gpgsm by default; and ssh, run with
+// gpg.ssh.program, ssh-keygen by default. The key is user.signingkey, or for gpg committer's name and
+// email without one, and for ssh the first key gpg.ssh.defaultKeyCommand prints.
+func (repo *Repository) CommitSigner(committer Signature) (CommitSigner, error) {
+	config, err := repo.Config()
+	if err != nil {
+		return nil, err
+	}
+	if sign, err := config.Bool("commit.gpgsign"); err != nil || !sign {
+		return nil, err
+	}
+	key, _ := config.Get("user.signingkey")
+	switch format, _ := config.Get("gpg.format"); format {
+	case "", "openpgp", "x509":
+		return GPGSigner{Program: signingProgram(config, format), Key: cmp.Or(key, committer.Name+" <"+committer.Email+">")}, nil
+	case "ssh":
+		if _, literal := literalSSHKey(key); key != "" && !literal {
+			if key, err = config.Path("user.signingkey"); err != nil {
+				return nil, err
+			}
+		}
+		if key == "" {
+			if key, err = defaultSSHSigningKey(config); err != nil {
+				return nil, err
+			}
+		}
+		return SSHSigner{Program: signingProgram(config, format), Key: key}, nil
+	default:
+		return nil, fmt.Errorf("git: gpg.format %q isn't supported", format)
+	}
+}
+
+// signingProgram returns the program that signs and verifies in format, one of the values of gpg.format:
+// gpg.<format>.program, or gpg.program for openpgp, or the program git runs by default.
+func signingProgram(config *Config, format string) string {
+	format = cmp.Or(format, "openpgp")
+	program, _ := config.Get("gpg." + format + ".program")
+	if program == "" && format == "openpgp" {
+		program, _ = config.Get("gpg.program")
+	}
+	return cmp.Or(program, map[string]string{"openpgp": "gpg", "x509": "gpgsm", "ssh": "ssh-keygen"}[format])
+}
+
+// defaultSSHSigningKey runs gpg.ssh.defaultKeyCommand, such as ssh-add -L, for the key to sign with when
+// user.signingkey isn't set, taking the first literal key it prints.
+func defaultSSHSigningKey(config *Config) (string, error) {
+	command, _ := config.Get("gpg.ssh.defaultKeyCommand")
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return "", errors.New("git: signing with ssh needs user.signingkey or gpg.ssh.defaultKeyCommand")
+	}
+	out, err := exec.Command(args[0], args[1:]...).Output()
+	if err != nil {
+		return "", fmt.Errorf("git: gpg.ssh.defaultKeyCommand failed: %w", err)
+	}
+	line, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	if _, ok := literalSSHKey(strings.TrimSpace(line)); !ok {
+		return "", errors.New("git: gpg.ssh.defaultKeyCommand printed no ssh key")
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// SignCommit returns c with a signature by signer over the rest of it added, in the header git reads
+// signatures over commits named with the repository's hash format from.
+func (repo *Repository) SignCommit(c Commit, signer CommitSigner) (Commit, error) {
+	name := repo.signatureHeader()
+	c.Headers = slices.DeleteFunc(slices.Clone(c.Headers), func(h CommitHeader) bool { return h.Name == name })
+	payload, err := EncodeCommit(c)
+	if err != nil {
+		return Commit{}, err
+	}
+	signature, err := signer.Sign(payload)
+	if err != nil {
+		return Commit{}, err
+	}
+	c.Headers = append(c.Headers, CommitHeader{Name: name, Value: strings.TrimSuffix(signature, "\n")})
+	return c, nil
+}
+
+// signatureHeader is the commit header git keeps signatures over commits in, for the repository's hash
+// format.
+func (repo *Repository) signatureHeader() string {
+	if repo.Format == SHA256 {
+		return "gpgsig-sha256"
+	}
+	return "gpgsig"
+}
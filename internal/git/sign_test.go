@@ -0,0 +1,175 @@
+package git
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/pem"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// writeTestSigner writes a stand-in for gpg that records its arguments and what it signed in dir, and
+// answers with a fixed signature, unless status is empty, in which case it claims to but doesn't sign.
+func writeTestSigner(t *testing.T, dir, status string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the stand-in for gpg is a shell script")
+	}
+	script := "#!/bin/sh\necho \"$@\" > " + filepath.Join(dir, "args") + "\ncat > " + filepath.Join(dir, "payload") + "\n" +
+		"echo '" + status + "' >&2\nprintf -- '-----BEGIN PGP SIGNATURE-----\\n\\nc2lnbmVk\\n-----END PGP SIGNATURE-----\\n'\n"
+	program := filepath.Join(dir, "fake-gpg")
+	if err := os.WriteFile(program, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return program
+}
+
+func TestCreateSignedCommit(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	gitDir := newTestRepo(t)
+	dir := t.TempDir()
+	program := writeTestSigner(t, dir, "[GNUPG:] SIG_CREATED D 22 8 00 1700000000 ABCDEF")
+	writeTestFiles(t, map[string]string{".git/config": "[commit]\n\tgpgsign = true\n[gpg]\n\tprogram = " + program + "\n"})
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := writeTestObject(t, gitDir, TreeObject, "")
+
+	hash, err := repo.CreateCommit(tree, nil, maintenanceSignature, maintenanceSignature, "signed\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit, err := repo.ReadCommit(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := CommitHeader{Name: "gpgsig", Value: "-----BEGIN PGP SIGNATURE-----\n\nc2lnbmVk\n-----END PGP SIGNATURE-----"}
+	if len(commit.Headers) != 1 || commit.Headers[0] != want {
+		t.Errorf("the commit's headers = %q, want the signature", commit.Headers)
+	}
+	if args, _ := os.ReadFile(filepath.Join(dir, "args")); !strings.Contains(string(args), "-bsau "+maintenanceSignature.Name+" <"+maintenanceSignature.Email+">") {
+		t.Errorf("gpg was run with %q, want the committer as the key without user.signingkey", args)
+	}
+	commit.Headers = nil
+	unsigned, _ := EncodeCommit(commit)
+	if payload, _ := os.ReadFile(filepath.Join(dir, "payload")); string(payload) != string(unsigned) {
+		t.Errorf("gpg signed %q, want the commit without its signature", payload)
+	}
+
+	// Stashes aren't signed, as with git.
+	if _, err := repo.createCommit(tree, nil, maintenanceSignature, maintenanceSignature, "stash", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	writeTestFiles(t, map[string]string{".git/config": "[commit]\n\tgpgsign = true\n[user]\n\tsigningkey = ABCDEF\n[gpg]\n\tprogram = " + writeTestSigner(t, dir, "") + "\n"})
+	if _, err := repo.CreateCommit(tree, nil, maintenanceSignature, maintenanceSignature, "unsigned"); !errors.Is(err, ErrSigningFailed) {
+		t.Errorf("CreateCommit() when gpg made no signature = %v, want %v", err, ErrSigningFailed)
+	}
+	if args, _ := os.ReadFile(filepath.Join(dir, "args")); !strings.Contains(string(args), "-bsau ABCDEF") {
+		t.Errorf("gpg was run with %q, want user.signingkey", args)
+	}
+
+	writeTestFiles(t, map[string]string{".git/config": "[commit]\n\tgpgsign = true\n[gpg]\n\tformat = unknown\n"})
+	if _, err := repo.CreateCommit(tree, nil, maintenanceSignature, maintenanceSignature, "unsigned"); err == nil {
+		t.Error("CreateCommit() with an unknown gpg.format succeeded")
+	}
+}
+
+func TestCreateSSHSignedCommit(t *testing.T) {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen isn't installed")
+	}
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	gitDir := newTestRepo(t)
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := writeTestObject(t, gitDir, TreeObject, "")
+
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey, err := ssh.NewPublicKey(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(private, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "id_ed25519")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	allowed := filepath.Join(dir, "allowed_signers")
+	if err := os.WriteFile(allowed, []byte("signer "+string(ssh.MarshalAuthorizedKey(publicKey))), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// verify checks the commit's signature with ssh-keygen, as git verify-commit would.
+	verify := func(hash string) {
+		t.Helper()
+		commit, err := repo.ReadCommit(hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(commit.Headers) != 1 || commit.Headers[0].Name != "gpgsig" || !strings.HasPrefix(commit.Headers[0].Value, "-----BEGIN SSH SIGNATURE-----") {
+			t.Fatalf("the commit's headers = %q, want an ssh signature", commit.Headers)
+		}
+		signature := filepath.Join(t.TempDir(), "sig")
+		if err := os.WriteFile(signature, []byte(commit.Headers[0].Value+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		commit.Headers = nil
+		payload, _ := EncodeCommit(commit)
+		cmd := exec.Command("ssh-keygen", "-Y", "verify", "-f", allowed, "-I", "signer", "-n", "git", "-s", signature)
+		cmd.Stdin = bytes.NewReader(payload)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Errorf("ssh-keygen -Y verify = %v\n%s", err, out)
+		}
+	}
+
+	writeTestFiles(t, map[string]string{".git/config": "[commit]\n\tgpgsign = true\n[gpg]\n\tformat = ssh\n[user]\n\tsigningkey = " + filepath.ToSlash(keyFile) + "\n"})
+	hash, err := repo.CreateCommit(tree, nil, maintenanceSignature, maintenanceSignature, "signed with a key file\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	verify(hash)
+
+	// A literal key is signed with by the agent.
+	keyring := agent.NewKeyring()
+	if err := keyring.Add(agent.AddedKey{PrivateKey: private}); err != nil {
+		t.Fatal(err)
+	}
+	serveTestAgent(t, keyring)
+	literal := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(publicKey)))
+	writeTestFiles(t, map[string]string{".git/config": "[commit]\n\tgpgsign = true\n[gpg]\n\tformat = ssh\n[user]\n\tsigningkey = key::" + literal + "\n"})
+	hash, err = repo.CreateCommit(tree, nil, maintenanceSignature, maintenanceSignature, "signed by the agent\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	verify(hash)
+
+	writeTestFiles(t, map[string]string{".git/config": "[commit]\n\tgpgsign = true\n[gpg]\n\tformat = ssh\n"})
+	if _, err := repo.CreateCommit(tree, nil, maintenanceSignature, maintenanceSignature, "unsigned"); err == nil {
+		t.Error("CreateCommit() with ssh signing but no key succeeded")
+	}
+	writeTestFiles(t, map[string]string{".git/config": "[commit]\n\tgpgsign = true\n[gpg]\n\tformat = ssh\n[user]\n\tsigningkey = " + filepath.ToSlash(filepath.Join(dir, "missing")) + "\n"})
+	if _, err := repo.CreateCommit(tree, nil, maintenanceSignature, maintenanceSignature, "unsigned"); !errors.Is(err, ErrSigningFailed) {
+		t.Errorf("CreateCommit() with a missing key file = %v, want %v", err, ErrSigningFailed)
+	}
+}
@@ -0,0 +1,79 @@
+package git
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// smartHTTP carries git's smart HTTP protocol, in which every request to the service is a POST of its own.
+//
+// A smartHTTP is created by calling [dialSmartHTTP].
+type smartHTTP struct {
+	client   *http.Client
+	url      string // The repository's URL, without a trailing slash
+	service  string // git-upload-pack or git-receive-pack
+	protocol string // What is asked for in the Git-Protocol header, e.g. version=2, or "" for protocol v0
+}
+
+// dialSmartHTTP asks the server at remoteURL for the advertisement of service, in protocol, e.g.
+// version=2, or in protocol v0 if it is "", returning the transport and the body of the advertisement.
+// A nil client is [http.DefaultClient].
+func dialSmartHTTP(client *http.Client, remoteURL, service, protocol string) (*smartHTTP, io.ReadCloser, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	c := &smartHTTP{client: client, url: strings.TrimSuffix(remoteURL, "/"), service: service, protocol: protocol}
+	req, err := http.NewRequest(http.MethodGet, c.url+"/info/refs?service="+service, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	c.setProtocol(req)
+	body, err := c.do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c, body, nil
+}
+
+// roundTrip posts a request to the server and returns the body of the response.
+func (c *smartHTTP) roundTrip(request io.Reader) (io.ReadCloser, error) {
+	req, err := http.NewRequest(http.MethodPost, c.url+"/"+c.service, request)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-"+c.service+"-request")
+	req.Header.Set("Accept", "application/x-"+c.service+"-result")
+	c.setProtocol(req)
+	return c.do(req)
+}
+
+func (c *smartHTTP) setProtocol(req *http.Request) {
+	if c.protocol != "" {
+		req.Header.Set("Git-Protocol", c.protocol)
+	}
+}
+
+func (c *smartHTTP) do(req *http.Request) (io.ReadCloser, error) {
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		resp.Body.Close()
+		return nil, fmt.Errorf("git: %s refused access (%s)", c.url, resp.Status)
+	case resp.StatusCode == http.StatusNotFound:
+		resp.Body.Close()
+		return nil, fmt.Errorf("git: no repository at %s", c.url)
+	case resp.StatusCode != http.StatusOK:
+		resp.Body.Close()
+		return nil, fmt.Errorf("git: %s answered %s", c.url, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+func (c *smartHTTP) Close() error {
+	return nil
+}
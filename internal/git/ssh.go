@@ -0,0 +1,346 @@
+package git
+
+import (
+	"cmp"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+var (
+	ErrUnknownHost     = errors.New("host isn't in known_hosts")
+	ErrHostKeyMismatch = errors.New("host key doesn't match known_hosts")
+)
+
+// sshDialTimeout bounds how long connecting to an SSH remote may take, up to it accepting the session.
+const sshDialTimeout = 30 * time.Second
+
+// sshEndpoint is where an SSH remote is, and the path of the repository on it.
+type sshEndpoint struct {
+	user string // Who to log in as, or "" for the local user
+	host string
+	port string // The port, or "" for 22
+	path string // Relative to the user's home directory unless it starts with a slash
+}
+
+// parseSSHURL parses ssh://[user@]host[:port]/path URLs, and git's scp-like [user@]host:path shorthand,
+// as in git@github.com:owner/repo.git. The boolean is false for anything else.
+func parseSSHURL(remote string) (sshEndpoint, bool) {
+	for _, scheme := range []string{"ssh://", "git+ssh://", "ssh+git://"} {
+		rest, ok := strings.CutPrefix(remote, scheme)
+		if !ok {
+			continue
+		}
+		u, err := url.Parse("ssh://" + rest)
+		if err != nil || u.Hostname() == "" || u.Path == "" {
+			return sshEndpoint{}, false
+		}
+		e := sshEndpoint{host: u.Hostname(), port: u.Port(), path: u.Path}
+		if u.User != nil {
+			e.user = u.User.Username()
+		}
+		// ssh://host/~user/repo is in user's home directory, as the shell would have it.
+		if strings.HasPrefix(e.path, "/~") {
+			e.path = e.path[1:]
+		}
+		return e, true
+	}
+
+	// Like git, take a colon before any slash to mean the scp-like form, and anything else to be a path.
+	// An IPv6 address is bracketed, as in [::1]:repo.git.
+	login, path, ok := strings.Cut(remote, ":")
+	if open := strings.IndexByte(remote, '['); open >= 0 && open < len(login) {
+		if end := strings.Index(remote, "]:"); end > open {
+			login, path, ok = remote[:end+1], remote[end+2:], true
+		}
+	}
+	if !ok || login == "" || path == "" || strings.Contains(login, "/") || strings.HasPrefix(path, "//") {
+		return sshEndpoint{}, false
+	}
+	var e sshEndpoint
+	if at := strings.LastIndexByte(login, '@'); at >= 0 {
+		e.user, login = login[:at], login[at+1:]
+	}
+	e.host = strings.TrimSuffix(strings.TrimPrefix(login, "["), "]")
+	e.path = path
+	return e, e.host != ""
+}
+
+// sshTransport runs a git service, like git-upload-pack, on an SSH remote, carrying requests on its
+// standard input and responses on its standard output.
+//
+// An sshTransport is created by calling [dialSSH].
+type sshTransport struct {
+	client  *ssh.Client
+	session *ssh.Session
+	stdin   io.WriteCloser
+	stdout  io.Reader
+}
+
+// dialSSH logs in to the remote at e and starts service, e.g. git-upload-pack or git-receive-pack, on the
+// repository, asking for protocol, e.g. version=2, through GIT_PROTOCOL unless it is "". It returns the
+// transport and the service's output, which starts with its advertisement.
+//
+// The host's HostName, User, Port, and IdentityFile settings in ~/.ssh/config apply, with the user and
+// port in the URL taking precedence. The remote's host key must be in ~/.ssh/known_hosts or
+// /etc/ssh/ssh_known_hosts, or the connection fails with [ErrUnknownHost]; a key that doesn't match the
+// one recorded fails with [ErrHostKeyMismatch]. The login is with the keys of [sshSigners].
+func dialSSH(e sshEndpoint, service, protocol string) (*sshTransport, io.ReadCloser, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, nil, err
+	}
+	hostConfig, err := readSSHConfig(home, e.host)
+	if err != nil {
+		return nil, nil, fmt.Errorf("git: failed to read ssh config: %w", err)
+	}
+	addr := net.JoinHostPort(cmp.Or(hostConfig.hostName, e.host), cmp.Or(e.port, hostConfig.port, "22"))
+	hostKeys, algorithms, err := knownHosts(home, addr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The agent is only needed to sign the login, so it is let go once connected. One that can't be
+	// reached, like one whose socket outlived it, is no worse than having no agent.
+	var keys agent.Agent
+	conn, err := dialSSHAgent()
+	if err != nil {
+		logAt(slog.LevelDebug, "ssh agent unreachable", "err", err)
+	} else if conn != nil {
+		defer conn.Close()
+		keys = agent.NewClient(conn)
+	}
+	config := &ssh.ClientConfig{
+		User:              cmp.Or(e.user, hostConfig.user, loginName()),
+		Auth:              []ssh.AuthMethod{ssh.PublicKeysCallback(func() ([]ssh.Signer, error) { return sshSigners(home, hostConfig, keys), nil })},
+		HostKeyCallback:   hostKeys,
+		HostKeyAlgorithms: algorithms,
+		Timeout:           sshDialTimeout,
+	}
+	client, err := ssh.Dial("tcp", addr, config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("git: failed to connect to %s: %w", e.host, err)
+	}
+
+	t := &sshTransport{client: client}
+	if t.session, err = client.NewSession(); err != nil {
+		client.Close()
+		return nil, nil, err
+	}
+	if protocol != "" {
+		// A server that refuses the variable speaks protocol v0, which the advertisement reveals.
+		t.session.Setenv("GIT_PROTOCOL", protocol)
+	}
+	var stderr strings.Builder
+	t.session.Stderr = &stderr
+	if t.stdin, err = t.session.StdinPipe(); err == nil {
+		var stdout io.Reader
+		stdout, err = t.session.StdoutPipe()
+		t.stdout = &sshOutput{r: stdout, session: t.session, stderr: &stderr, host: e.host}
+	}
+	if err == nil {
+		err = t.session.Start(service + " " + shellQuote(e.path))
+	}
+	if err != nil {
+		t.Close()
+		return nil, nil, err
+	}
+	return t, io.NopCloser(t.stdout), nil
+}
+
+func (t *sshTransport) roundTrip(request io.Reader) (io.ReadCloser, error) {
+	if _, err := io.Copy(t.stdin, request); err != nil {
+		return nil, err
+	}
+	return io.NopCloser(t.stdout), nil
+}
+
+// Close tells the service the conversation is over, and logs out.
+func (t *sshTransport) Close() error {
+	if t.stdin != nil {
+		t.stdin.Write([]byte("0000"))
+		t.stdin.Close()
+	}
+	if t.session != nil {
+		t.session.Close()
+	}
+	return t.client.Close()
+}
+
+// sshOutput is a service's standard output, which fails with what the service said on its standard error
+// when it ends early, since that is where servers explain refusing access.
+type sshOutput struct {
+	r       io.Reader
+	session *ssh.Session
+	stderr  *strings.Builder
+	host    string
+	ended   bool
+}
+
+func (o *sshOutput) Read(p []byte) (int, error) {
+	n, err := o.r.Read(p)
+	if errors.Is(err, io.EOF) {
+		if !o.ended {
+			// Once the service has exited, everything it wrote to standard error has arrived.
+			o.ended = true
+			o.session.Wait()
+		}
+		if o.stderr.Len() > 0 {
+			err = &remoteMessage{host: o.host, text: strings.TrimSpace(o.stderr.String())}
+		}
+	}
+	return n, err
+}
+
+// remoteMessage is what a remote said when it hung up, which explains why better than anything else.
+type remoteMessage struct {
+	host, text string
+}
+
+func (m *remoteMessage) Error() string {
+	return "git: " + m.host + ": " + m.text
+}
+
+// knownHosts returns the callback that checks addr's host key against the known_hosts files, and the
+// kinds of key they hold for it, so the server is asked for a key that can be checked.
+func knownHosts(home, addr string) (ssh.HostKeyCallback, []string, error) {
+	var files []string
+	for _, name := range []string{filepath.Join(home, ".ssh", "known_hosts"), "/etc/ssh/ssh_known_hosts"} {
+		if _, err := os.Stat(name); err == nil {
+			files = append(files, name)
+		}
+	}
+	if len(files) == 0 {
+		return nil, nil, fmt.Errorf("%w: there is no ~/.ssh/known_hosts to check %s against; connect once with ssh to trust it", ErrUnknownHost, addr)
+	}
+	check, err := knownhosts.New(files...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("git: failed to read known_hosts: %w", err)
+	}
+
+	// Checking a key known_hosts can't have lists the keys it does have.
+	probe, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	probeKey, err := ssh.NewPublicKey(probe)
+	if err != nil {
+		return nil, nil, err
+	}
+	var algorithms []string
+	var keyErr *knownhosts.KeyError
+	if errors.As(check(addr, &net.TCPAddr{IP: net.IPv4zero}, probeKey), &keyErr) {
+		for _, known := range keyErr.Want {
+			switch kind := known.Key.Type(); kind {
+			case ssh.KeyAlgoRSA:
+				algorithms = append(algorithms, ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA)
+			default:
+				algorithms = append(algorithms, kind)
+			}
+		}
+	}
+
+	callback := func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		err := check(hostname, remote, key)
+		var keyErr *knownhosts.KeyError
+		if !errors.As(err, &keyErr) {
+			return err
+		}
+		if len(keyErr.Want) == 0 {
+			return fmt.Errorf("%w: %s; connect once with ssh to check its key and trust it", ErrUnknownHost, hostname)
+		}
+		return fmt.Errorf("%w: %s offered a different %s key, so it may be impersonated", ErrHostKeyMismatch, hostname, key.Type())
+	}
+	return callback, algorithms, nil
+}
+
+// sshSigners returns the keys to log in with, in the order they are offered: those of the identity files
+// hostConfig names, then the rest the agent holds, unless IdentitiesOnly is set, and then, when no
+// identity file is named, ~/.ssh/id_ed25519, id_ecdsa, and id_rsa. An identity file protected by a
+// passphrase is only used when the agent holds its key, which is found by the public key in the file or
+// next to it in a .pub file, since there is no one to ask for the passphrase. keys may be nil.
+func sshSigners(home string, hostConfig sshHostConfig, keys agent.Agent) []ssh.Signer {
+	held := map[string]ssh.Signer{}
+	var agentSigners []ssh.Signer
+	if keys != nil {
+		// An agent that fails to list its keys is no worse than having no agent.
+		agentSigners, _ = keys.Signers()
+		for _, signer := range agentSigners {
+			held[string(signer.PublicKey().Marshal())] = signer
+		}
+	}
+
+	var signers []ssh.Signer
+	offered := map[string]bool{}
+	offer := func(signer ssh.Signer) {
+		if key := string(signer.PublicKey().Marshal()); !offered[key] {
+			offered[key] = true
+			signers = append(signers, signer)
+		}
+	}
+	load := func(name string) {
+		data, err := os.ReadFile(name)
+		if err == nil {
+			signer, err := ssh.ParsePrivateKey(data)
+			var locked *ssh.PassphraseMissingError
+			switch {
+			case err == nil:
+				offer(signer)
+				return
+			case errors.As(err, &locked) && locked.PublicKey != nil:
+				if signer, ok := held[string(locked.PublicKey.Marshal())]; ok {
+					offer(signer)
+					return
+				}
+			}
+		}
+		if data, err := os.ReadFile(name + ".pub"); err == nil {
+			if public, _, _, _, err := ssh.ParseAuthorizedKey(data); err == nil {
+				if signer, ok := held[string(public.Marshal())]; ok {
+					offer(signer)
+				}
+			}
+		}
+	}
+
+	for _, name := range hostConfig.identityFiles {
+		load(name)
+	}
+	if !hostConfig.identitiesOnly {
+		for _, signer := range agentSigners {
+			offer(signer)
+		}
+	}
+	if len(hostConfig.identityFiles) == 0 {
+		for _, name := range []string{"id_ed25519", "id_ecdsa", "id_rsa"} {
+			load(filepath.Join(home, ".ssh", name))
+		}
+	}
+	return signers
+}
+
+// loginName is the local user's name, which SSH logs in as when a URL doesn't name a user.
+func loginName() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// shellQuote quotes s for the remote's shell the way git does, in single quotes.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(strings.ReplaceAll(s, "'", `'\''`), "!", `'\!'`) + "'"
+}
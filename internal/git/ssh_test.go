@@ -0,0 +1,283 @@
+package git
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/pem"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func TestParseSSHURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want sshEndpoint
+		ok   bool
+	}{
+		{"git@github.com:owner/repo.git", sshEndpoint{user: "git", host: "github.com", path: "owner/repo.git"}, true},
+		{"host:/srv/repo.git", sshEndpoint{host: "host", path: "/srv/repo.git"}, true},
+		{"[::1]:repo.git", sshEndpoint{host: "::1", path: "repo.git"}, true},
+		{"ssh://git@example.com:2222/owner/repo.git", sshEndpoint{user: "git", host: "example.com", port: "2222", path: "/owner/repo.git"}, true},
+		{"git+ssh://example.com/~alex/repo.git", sshEndpoint{host: "example.com", path: "~alex/repo.git"}, true},
+		{"https://github.com/owner/repo.git", sshEndpoint{}, false},
+		{"./dir:with/colon", sshEndpoint{}, false},
+		{"/srv/repo.git", sshEndpoint{}, false},
+		{"origin", sshEndpoint{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseSSHURL(tt.url)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseSSHURL(%q) = %+v, %v, want %+v, %v", tt.url, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+// serveSSHUploadPack serves the refs of server over protocol v2 from git-upload-pack '/repo.git' on an SSH
+// server that lets git in with clientKey, returning its address and host key.
+func serveSSHUploadPack(t *testing.T, server *Repository, refs map[string]string, clientKey ssh.PublicKey) (string, ssh.PublicKey) {
+	t.Helper()
+	_, hostKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostSigner, err := ssh.NewSignerFromKey(hostKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if conn.User() != "git" || !bytes.Equal(key.Marshal(), clientKey.Marshal()) {
+				return nil, errors.New("denied")
+			}
+			return nil, nil
+		},
+	}
+	config.AddHostKey(hostSigner)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	run := func(ch ssh.Channel, command, protocol string) {
+		defer ch.Close()
+		status := uint32(0)
+		defer func() { ch.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{status})) }()
+		if command != "git-upload-pack '/repo.git'" || protocol != "version=2" {
+			ch.Stderr().Write([]byte("fatal: '" + command + "' does not appear to be a git repository\n"))
+			status = 128
+			return
+		}
+		ch.Write(testAdvertisement())
+		p := newPktReader(ch)
+		for {
+			lines, err := readTestRequest(p)
+			if err != nil || len(lines) == 0 {
+				return
+			}
+			ch.Write(answerTestRequest(t, server, refs, lines))
+		}
+	}
+	go func() {
+		for {
+			nc, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, chans, reqs, err := ssh.NewServerConn(nc, config)
+				if err != nil {
+					return
+				}
+				go ssh.DiscardRequests(reqs)
+				for newChannel := range chans {
+					ch, requests, err := newChannel.Accept()
+					if err != nil {
+						return
+					}
+					go func() {
+						var protocol string
+						for req := range requests {
+							switch req.Type {
+							case "env":
+								var env struct{ Name, Value string }
+								ssh.Unmarshal(req.Payload, &env)
+								if env.Name == "GIT_PROTOCOL" {
+									protocol = env.Value
+								}
+								req.Reply(true, nil)
+							case "exec":
+								var exec struct{ Command string }
+								ssh.Unmarshal(req.Payload, &exec)
+								req.Reply(true, nil)
+								go run(ch, exec.Command, protocol)
+							default:
+								req.Reply(false, nil)
+							}
+						}
+					}()
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String(), hostSigner.PublicKey()
+}
+
+func TestFetchOverSSH(t *testing.T) {
+	server, refs := writeTestServer(t)
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	// An agent that went away leaves its socket behind, and the key files to log in with.
+	t.Setenv("SSH_AUTH_SOCK", filepath.Join(home, "agent.sock"))
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(private, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientKey, err := ssh.NewPublicKey(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFiles(t, map[string]string{filepath.Join(home, ".ssh", "id_ed25519"): string(pem.EncodeToMemory(block))})
+	addr, hostKey := serveSSHUploadPack(t, server, refs, clientKey)
+	trust := func(key ssh.PublicKey) {
+		t.Helper()
+		line := knownhosts.Line([]string{knownhosts.Normalize(addr)}, key)
+		writeTestFiles(t, map[string]string{filepath.Join(home, ".ssh", "known_hosts"): line + "\n"})
+	}
+
+	newTestRepo(t)
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fetch := func(path string) error {
+		_, err := repo.Fetch(nil, "ssh://git@"+addr+path, []string{"+refs/heads/*:refs/remotes/origin/*"}, maintenanceSignature)
+		return err
+	}
+
+	if err := fetch("/repo.git"); !errors.Is(err, ErrUnknownHost) {
+		t.Errorf("Fetch() without known_hosts = %v, want %v", err, ErrUnknownHost)
+	}
+	_, other, _ := ed25519.GenerateKey(nil)
+	otherKey, _ := ssh.NewSignerFromKey(other)
+	trust(otherKey.PublicKey())
+	if err := fetch("/repo.git"); !errors.Is(err, ErrHostKeyMismatch) {
+		t.Errorf("Fetch() from a host with another key = %v, want %v", err, ErrHostKeyMismatch)
+	}
+
+	trust(hostKey)
+	if err := fetch("/repo.git"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := repo.ResolveRevision("refs/remotes/origin/main"); err != nil || got != refs["refs/heads/main"] {
+		t.Errorf("origin/main = %s, %v, want %s", got, err, refs["refs/heads/main"])
+	}
+	if commit, err := repo.ReadCommit(refs["refs/heads/main"]); err != nil || commit.Message != "main" {
+		t.Errorf("ReadCommit() of a commit fetched over ssh = %+v, %v", commit, err)
+	}
+
+	if err := fetch("/missing.git"); err == nil || !strings.Contains(err.Error(), "does not appear to be a git repository") {
+		t.Errorf("Fetch() of a missing repository = %v, want the server's complaint", err)
+	}
+	os.Remove(filepath.Join(home, ".ssh", "id_ed25519"))
+	if err := fetch("/repo.git"); err == nil {
+		t.Error("Fetch() without a key succeeded")
+	}
+}
+
+// serveTestAgent serves keyring as the ssh agent for the rest of the test, over a Unix socket in
+// SSH_AUTH_SOCK.
+func serveTestAgent(t *testing.T, keyring agent.Agent) {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("no Unix sockets for the agent: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				agent.ServeAgent(keyring, conn)
+				conn.Close()
+			}()
+		}
+	}()
+	t.Setenv("SSH_AUTH_SOCK", socket)
+}
+
+func TestFetchOverSSHWithAgent(t *testing.T) {
+	server, refs := writeTestServer(t)
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientKey, err := ssh.NewPublicKey(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr, hostKey := serveSSHUploadPack(t, server, refs, clientKey)
+	host, port, _ := net.SplitHostPort(addr)
+
+	// The key on disk needs a passphrase, so only the agent can sign with it.
+	block, err := ssh.MarshalPrivateKeyWithPassphrase(private, "", []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFiles(t, map[string]string{
+		filepath.Join(home, ".ssh", "work_ed25519"): string(pem.EncodeToMemory(block)),
+		filepath.Join(home, ".ssh", "known_hosts"):  knownhosts.Line([]string{knownhosts.Normalize(addr)}, hostKey) + "\n",
+		filepath.Join(home, ".ssh", "config"):       "Host work\n\tHostName " + host + "\n\tPort " + port + "\n\tUser git\n\tIdentityFile ~/.ssh/work_ed25519\n\tIdentitiesOnly yes\n",
+	})
+
+	keyring := agent.NewKeyring()
+	_, decoy, _ := ed25519.GenerateKey(nil)
+	for _, key := range []ed25519.PrivateKey{decoy, private} {
+		if err := keyring.Add(agent.AddedKey{PrivateKey: key}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	serveTestAgent(t, keyring)
+
+	newTestRepo(t)
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Fetch(nil, "work:/repo.git", []string{"+refs/heads/*:refs/remotes/origin/*"}, maintenanceSignature); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := repo.ResolveRevision("refs/remotes/origin/main"); err != nil || got != refs["refs/heads/main"] {
+		t.Errorf("origin/main = %s, %v, want %s", got, err, refs["refs/heads/main"])
+	}
+
+	// Only the agent's key for the identity file is offered.
+	signers := sshSigners(home, sshHostConfig{identityFiles: []string{filepath.Join(home, ".ssh", "work_ed25519")}, identitiesOnly: true}, keyring)
+	if len(signers) != 1 || !bytes.Equal(signers[0].PublicKey().Marshal(), clientKey.Marshal()) {
+		t.Errorf("sshSigners() with IdentitiesOnly = %d keys, want only the identity file's", len(signers))
+	}
+	if signers := sshSigners(home, sshHostConfig{}, keyring); len(signers) != 2 {
+		t.Errorf("sshSigners() = %d keys, want both of the agent's", len(signers))
+	}
+}
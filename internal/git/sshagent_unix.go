@@ -0,0 +1,18 @@
+//go:build !windows
+
+package git
+
+import (
+	"io"
+	"net"
+	"os"
+)
+
+// dialSSHAgent connects to the SSH agent listening on $SSH_AUTH_SOCK. Without one it returns nil.
+func dialSSHAgent() (io.ReadWriteCloser, error) {
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	if socket == "" {
+		return nil, nil
+	}
+	return net.DialTimeout("unix", socket, sshDialTimeout)
+}
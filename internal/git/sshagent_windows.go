@@ -0,0 +1,129 @@
+package git
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"runtime"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// openSSHAgentPipe is where the Windows port of OpenSSH's agent listens.
+const openSSHAgentPipe = `\\.\pipe\openssh-ssh-agent`
+
+// dialSSHAgent connects to the SSH agent $SSH_AUTH_SOCK names, which is a named pipe or a Unix socket,
+// or else to OpenSSH's agent, or else to Pageant. Without any it returns nil.
+func dialSSHAgent() (io.ReadWriteCloser, error) {
+	if socket := os.Getenv("SSH_AUTH_SOCK"); socket != "" {
+		if strings.HasPrefix(socket, `\\.\pipe\`) {
+			return os.OpenFile(socket, os.O_RDWR, 0)
+		}
+		return net.DialTimeout("unix", socket, sshDialTimeout)
+	}
+	if pipe, err := os.OpenFile(openSSHAgentPipe, os.O_RDWR, 0); err == nil {
+		return pipe, nil
+	}
+	if window, _, _ := findWindow.Call(uintptr(unsafe.Pointer(pageantClass)), uintptr(unsafe.Pointer(pageantClass))); window != 0 {
+		return &pageantConn{window: window}, nil
+	}
+	return nil, nil
+}
+
+const (
+	pageantCopyDataID = 0x804e50ba // Marks a WM_COPYDATA message as an agent request
+	pageantMaxMessage = 8192       // The most a request or response may take up, length included
+	wmCopyData        = 0x004a
+)
+
+var (
+	user32       = windows.NewLazySystemDLL("user32.dll")
+	kernel32     = windows.NewLazySystemDLL("kernel32.dll")
+	findWindow   = user32.NewProc("FindWindowW")
+	sendMessage  = user32.NewProc("SendMessageW")
+	moveMemory   = kernel32.NewProc("RtlMoveMemory")
+	pageantClass = windows.StringToUTF16Ptr("Pageant")
+)
+
+// pageantConn speaks the agent protocol to Pageant, which takes each request in shared memory named by a
+// WM_COPYDATA message to its window and answers in the same memory. Requests are gathered from writes
+// until one is complete, and its response is then there to read.
+type pageantConn struct {
+	window            uintptr
+	request, response bytes.Buffer
+}
+
+func (c *pageantConn) Write(p []byte) (int, error) {
+	c.request.Write(p)
+	b := c.request.Bytes()
+	if len(b) < 4 || len(b) < 4+int(binary.BigEndian.Uint32(b)) {
+		return len(p), nil
+	}
+	response, err := c.query(b)
+	c.request.Reset()
+	if err != nil {
+		return 0, err
+	}
+	c.response.Write(response)
+	return len(p), nil
+}
+
+func (c *pageantConn) Read(p []byte) (int, error) {
+	if c.response.Len() == 0 {
+		return 0, io.EOF
+	}
+	return c.response.Read(p)
+}
+
+func (c *pageantConn) Close() error {
+	return nil
+}
+
+// copyData is the COPYDATASTRUCT a WM_COPYDATA message points to.
+type copyData struct {
+	data uintptr
+	size uint32
+	ptr  uintptr
+}
+
+// query hands request to Pageant and returns its response.
+func (c *pageantConn) query(request []byte) ([]byte, error) {
+	if len(request) > pageantMaxMessage {
+		return nil, fmt.Errorf("git: a %d byte request is too large for Pageant", len(request))
+	}
+	name := fmt.Sprintf("PageantRequest%08x", windows.GetCurrentThreadId())
+	mapping, err := windows.CreateFileMapping(windows.InvalidHandle, nil, windows.PAGE_READWRITE, 0, pageantMaxMessage, windows.StringToUTF16Ptr(name))
+	if err != nil {
+		return nil, fmt.Errorf("git: failed to share memory with Pageant: %w", err)
+	}
+	defer windows.CloseHandle(mapping)
+	view, err := windows.MapViewOfFile(mapping, windows.FILE_MAP_WRITE, 0, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("git: failed to share memory with Pageant: %w", err)
+	}
+	defer windows.UnmapViewOfFile(view)
+
+	moveMemory.Call(view, uintptr(unsafe.Pointer(&request[0])), uintptr(len(request)))
+	// Pageant takes the name of the memory in the ANSI code page, which covers the ASCII it is made of.
+	ansi := append([]byte(name), 0)
+	message := copyData{data: pageantCopyDataID, size: uint32(len(ansi)), ptr: uintptr(unsafe.Pointer(&ansi[0]))}
+	ok, _, _ := sendMessage.Call(c.window, wmCopyData, 0, uintptr(unsafe.Pointer(&message)))
+	runtime.KeepAlive(ansi)
+	if ok == 0 {
+		return nil, errors.New("git: Pageant refused the request")
+	}
+
+	response := make([]byte, pageantMaxMessage)
+	moveMemory.Call(uintptr(unsafe.Pointer(&response[0])), view, pageantMaxMessage)
+	size := binary.BigEndian.Uint32(response)
+	if size > pageantMaxMessage-4 {
+		return nil, fmt.Errorf("git: Pageant answered with a %d byte response", size)
+	}
+	return response[:4+size], nil
+}
@@ -0,0 +1,189 @@
+package git
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// maxSSHConfigDepth bounds how deeply ssh config files may Include each other.
+const maxSSHConfigDepth = 16
+
+// sshHostConfig is what ~/.ssh/config says about connecting to a host, as far as plain follows it.
+type sshHostConfig struct {
+	hostName       string   // The host to connect to, if the one in the URL is an alias
+	user           string   // Who to log in as, if the URL doesn't say
+	port           string   // The port, if the URL doesn't say
+	identityFiles  []string // Private keys to offer, which replace ~/.ssh/id_* when given
+	identitiesOnly bool     // Offer only identityFiles, not everything the agent holds
+}
+
+// readSSHConfig reads the settings for host from ~/.ssh/config and then /etc/ssh/ssh_config, the first
+// value found for each winning as with ssh, except that every IdentityFile is kept. Host sections, with
+// their * and ? patterns and ! negations, and Include are understood; Match sections are skipped, and
+// anything else is ignored. Missing files are no error.
+func readSSHConfig(home, host string) (sshHostConfig, error) {
+	r := sshConfigReader{home: home, host: host}
+	for _, name := range []string{filepath.Join(home, ".ssh", "config"), "/etc/ssh/ssh_config"} {
+		if err := r.read(name, 0); err != nil {
+			return sshHostConfig{}, err
+		}
+	}
+	return r.config, nil
+}
+
+type sshConfigReader struct {
+	home, host string
+	config     sshHostConfig
+	set        map[string]bool // The keywords a value has been found for
+}
+
+// read reads one config file, starting outside of any section, so that everything applies until the
+// first Host.
+func (r *sshConfigReader) read(name string, depth int) error {
+	f, err := os.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	applies := true
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		keyword, args := splitSSHConfigLine(scanner.Text())
+		switch keyword {
+		case "":
+			continue
+		case "host":
+			applies = matchSSHHost(r.host, args)
+			continue
+		case "match":
+			applies = false
+			continue
+		}
+		if !applies || len(args) == 0 {
+			continue
+		}
+		if keyword == "include" {
+			if depth == maxSSHConfigDepth {
+				return errors.New("git: ssh config includes nest too deeply in " + name)
+			}
+			for _, pattern := range args {
+				pattern = r.expand(pattern)
+				if !filepath.IsAbs(pattern) {
+					// Relative includes are in ~/.ssh for the user's config, and /etc/ssh for the system's.
+					pattern = filepath.Join(filepath.Dir(name), pattern)
+				}
+				matches, _ := filepath.Glob(pattern)
+				for _, match := range matches {
+					if err := r.read(match, depth+1); err != nil {
+						return err
+					}
+				}
+			}
+			continue
+		}
+		r.apply(keyword, args[0])
+	}
+	return scanner.Err()
+}
+
+func (r *sshConfigReader) apply(keyword, value string) {
+	if keyword == "identityfile" {
+		if value != "none" {
+			r.config.identityFiles = append(r.config.identityFiles, r.expand(value))
+		}
+		return
+	}
+	if r.set[keyword] {
+		return
+	}
+	if r.set == nil {
+		r.set = map[string]bool{}
+	}
+	r.set[keyword] = true
+	switch keyword {
+	case "hostname":
+		r.config.hostName = value
+	case "user":
+		r.config.user = value
+	case "port":
+		r.config.port = value
+	case "identitiesonly":
+		r.config.identitiesOnly = strings.EqualFold(value, "yes")
+	}
+}
+
+// expand expands a leading ~ and the %d (home), %h (host), %u (local user), and %% tokens of a path.
+func (r *sshConfigReader) expand(s string) string {
+	if s == "~" || strings.HasPrefix(s, "~/") {
+		s = r.home + s[1:]
+	}
+	if !strings.Contains(s, "%") {
+		return s
+	}
+	host := r.host
+	if r.config.hostName != "" {
+		host = r.config.hostName
+	}
+	return strings.NewReplacer("%%", "%", "%d", r.home, "%h", host, "%u", loginName()).Replace(s)
+}
+
+// splitSSHConfigLine splits a config line into its keyword, lower cased, and arguments, which may be
+// separated from it by an equals sign and may be double quoted. Comments and blank lines have no keyword.
+func splitSSHConfigLine(line string) (string, []string) {
+	line = strings.TrimSpace(line)
+	if line == "" || line[0] == '#' {
+		return "", nil
+	}
+	end := strings.IndexAny(line, " \t=")
+	if end < 0 {
+		return strings.ToLower(line), nil
+	}
+	keyword, rest := strings.ToLower(line[:end]), strings.TrimLeft(line[end:], " \t")
+	rest = strings.TrimLeft(strings.TrimPrefix(rest, "="), " \t")
+
+	var args []string
+	for rest != "" {
+		var arg string
+		if rest[0] == '"' {
+			closing := strings.IndexByte(rest[1:], '"')
+			if closing < 0 {
+				arg, rest = rest[1:], ""
+			} else {
+				arg, rest = rest[1:closing+1], rest[closing+2:]
+			}
+		} else {
+			end := strings.IndexAny(rest, " \t")
+			if end < 0 {
+				end = len(rest)
+			}
+			arg, rest = rest[:end], rest[end:]
+		}
+		args = append(args, arg)
+		rest = strings.TrimLeft(rest, " \t")
+	}
+	return keyword, args
+}
+
+// matchSSHHost reports whether host matches the patterns of a Host line: at least one of them, and none
+// of those negated with !.
+func matchSSHHost(host string, patterns []string) bool {
+	matched := false
+	for _, pattern := range patterns {
+		negated := strings.HasPrefix(pattern, "!")
+		ok, _ := path.Match(strings.ToLower(strings.TrimPrefix(pattern, "!")), strings.ToLower(host))
+		if ok && negated {
+			return false
+		}
+		matched = matched || ok
+	}
+	return matched
+}
@@ -0,0 +1,56 @@
+package git
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestReadSSHConfig(t *testing.T) {
+	home := t.TempDir()
+	writeTestFiles(t, map[string]string{
+		filepath.Join(home, ".ssh", "config"): `# Work
+Host work work-*
+	HostName git.example.com
+	User = git
+	IdentityFile ~/.ssh/work_ed25519
+	IdentitiesOnly yes
+
+Host *.example.com !internal.example.com
+	Port 2222
+
+Match host *
+	User wrong
+
+Host *
+	IdentityFile "%d/.ssh/id_%h"
+	User fallback
+	Include conf.d/*
+`,
+		filepath.Join(home, ".ssh", "conf.d", "extra"): "Host work\n\tHostName ignored.example.com\n\tIdentityFile ~/.ssh/extra\n",
+	})
+
+	got, err := readSSHConfig(home, "work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := sshHostConfig{
+		hostName:       "git.example.com",
+		user:           "git",
+		identityFiles:  []string{filepath.Join(home, ".ssh", "work_ed25519"), home + "/.ssh/id_git.example.com", filepath.Join(home, ".ssh", "extra")},
+		identitiesOnly: true,
+	}
+	if got.hostName != want.hostName || got.user != want.user || got.port != "" || !got.identitiesOnly || !slices.Equal(got.identityFiles, want.identityFiles) {
+		t.Errorf("readSSHConfig(work) = %+v, want %+v", got, want)
+	}
+
+	if got, _ := readSSHConfig(home, "ci.example.com"); got.port != "2222" || got.user != "fallback" || got.hostName != "" {
+		t.Errorf("readSSHConfig(ci.example.com) = %+v, want port 2222 and the fallback user", got)
+	}
+	if got, _ := readSSHConfig(home, "internal.example.com"); got.port != "" {
+		t.Errorf("readSSHConfig(internal.example.com) = %+v, want the negated pattern to leave the port unset", got)
+	}
+	if got, err := readSSHConfig(t.TempDir(), "work"); err != nil || got.user != "" {
+		t.Errorf("readSSHConfig() without a config = %+v, %v, want nothing set", got, err)
+	}
+}
@@ -0,0 +1,213 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+var ErrNothingToStash = errors.New("no local changes to stash")
+
+const stashRef = "refs/stash"
+
+// StashEntry is one stash, as git stash list shows them.
+type StashEntry struct {
+	Index     int       // n in stash@{n}, counting from 0 for the newest
+	Hash      string    // The stash commit
+	Message   string    // e.g. "WIP on main: 1a2b3c4 Fix typo" or "On main: before rebasing"
+	Branch    string    // The branch the changes were stashed on, or "" if HEAD was detached
+	Base      string    // The commit HEAD was at
+	Staged    string    // The tree of the index as it was stashed
+	Tree      string    // The tree of the tracked files in the work tree as they were stashed
+	Untracked string    // The tree of the stashed untracked files, or "" if they weren't stashed
+	Time      time.Time // When the changes were stashed
+}
+
+// StashOptions controls what [Repository.Stash] stashes.
+type StashOptions struct {
+	Message string // Describes the stash; the default names HEAD's commit, like git stash
+
+	// IncludeUntracked stashes and removes untracked files too, except those Ignore matches.
+	IncludeUntracked bool
+	Ignore           *Ignore
+}
+
+// Stashes returns the stashes in refs/stash and its reflog, newest first, like git stash list.
+func (repo *Repository) Stashes() ([]StashEntry, error) {
+	hash, err := repo.resolveRef(stashRef)
+	if errors.Is(err, ErrRefNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	log, err := repo.Reflog(stashRef)
+	if err != nil {
+		return nil, err
+	}
+	if len(log) == 0 {
+		// Without a reflog only the latest stash is known.
+		log = []ReflogEntry{{New: hash}}
+	}
+
+	stashes := make([]StashEntry, 0, len(log))
+	for i, entry := range slices.Backward(log) {
+		stash, err := repo.readStash(entry.New)
+		if err != nil {
+			return nil, fmt.Errorf("git: failed to read stash@{%d}: %w", len(log)-1-i, err)
+		}
+		stash.Index = len(log) - 1 - i
+		if entry.Message != "" {
+			stash.Message = entry.Message
+		}
+		stashes = append(stashes, stash)
+	}
+	return stashes, nil
+}
+
+// readStash reads the stash commit hash: its parents are HEAD, the index commit, and optionally the
+// untracked files commit.
+func (repo *Repository) readStash(hash string) (StashEntry, error) {
+	commit, err := repo.ReadCommit(hash)
+	if err != nil {
+		return StashEntry{}, err
+	}
+	if len(commit.Parents) < 2 {
+		return StashEntry{}, fmt.Errorf("%s is not a stash commit", hash)
+	}
+
+	stash := StashEntry{Hash: hash, Message: commit.Message, Base: commit.Parents[0], Tree: commit.Tree, Time: commit.Committer.Time}
+	for i, field := range []*string{&stash.Staged, &stash.Untracked} {
+		if i+1 >= len(commit.Parents) {
+			break
+		}
+		parent, err := repo.ReadCommit(commit.Parents[i+1])
+		if err != nil {
+			return StashEntry{}, err
+		}
+		*field = parent.Tree
+	}
+
+	// "WIP on <branch>: ..." or "On <branch>: ...", with "(no branch)" for a detached HEAD.
+	rest, ok := strings.CutPrefix(commit.Message, "WIP on ")
+	if !ok {
+		rest, ok = strings.CutPrefix(commit.Message, "On ")
+	}
+	if branch, _, found := strings.Cut(rest, ": "); ok && found && branch != "(no branch)" {
+		stash.Branch = branch
+	}
+	return stash, nil
+}
+
+// Stash parks the changes in the index and work tree as a stash commit in refs/stash, in the layout git
+// stash uses so git stash pop can restore it, and then puts the index and work tree back the way HEAD
+// has them. If there are no changes, it fails with [ErrNothingToStash].
+func (repo *Repository) Stash(who Signature, opts StashOptions) (StashEntry, error) {
+	head, err := repo.ResolveRevision("HEAD")
+	if err != nil {
+		return StashEntry{}, err
+	}
+	headCommit, err := repo.ReadCommit(head)
+	if err != nil {
+		return StashEntry{}, err
+	}
+	branch, err := repo.CurrentBranch()
+	if err != nil {
+		return StashEntry{}, err
+	}
+	label := branch
+	if label == "" {
+		label = "(no branch)"
+	}
+	subject, _, _ := strings.Cut(headCommit.Message, "\n")
+	on := label + ": " + head[:7] + " " + subject
+
+	idx, err := repo.Index()
+	if err != nil {
+		return StashEntry{}, err
+	}
+	if len(idx.Conflicts()) > 0 {
+		return StashEntry{}, errors.New("git: cannot stash while there are unresolved conflicts")
+	}
+	staged, err := idx.WriteTree(repo.Encoder())
+	if err != nil {
+		return StashEntry{}, err
+	}
+
+	changes, err := repo.Status(opts.Ignore)
+	if err != nil {
+		return StashEntry{}, err
+	}
+	// The work tree's tree is the index with every unstaged change staged, in a copy of the index.
+	worktreeIdx, err := repo.Index()
+	if err != nil {
+		return StashEntry{}, err
+	}
+	untrackedIdx := &Index{}
+	for _, change := range changes {
+		switch {
+		case change.Unstaged == StatusUntracked:
+			// Nested repositories are listed with a trailing slash, and aren't stashed.
+			if opts.IncludeUntracked && !strings.HasSuffix(change.Path, "/") {
+				err = repo.StagePath(untrackedIdx, change.Path)
+			}
+		case change.Unstaged != 0:
+			err = repo.StagePath(worktreeIdx, change.Path)
+		}
+		if err != nil {
+			return StashEntry{}, err
+		}
+	}
+	tree, err := worktreeIdx.WriteTree(repo.Encoder())
+	if err != nil {
+		return StashEntry{}, err
+	}
+	if staged == headCommit.Tree && tree == staged && len(untrackedIdx.Entries) == 0 {
+		return StashEntry{}, ErrNothingToStash
+	}
+
+	indexCommit, err := repo.createCommit(staged, []string{head}, who, who, "index on "+on, nil)
+	if err != nil {
+		return StashEntry{}, err
+	}
+	parents := []string{head, indexCommit}
+	if len(untrackedIdx.Entries) > 0 {
+		untracked, err := untrackedIdx.WriteTree(repo.Encoder())
+		if err != nil {
+			return StashEntry{}, err
+		}
+		untrackedCommit, err := repo.createCommit(untracked, nil, who, who, "untracked files on "+on, nil)
+		if err != nil {
+			return StashEntry{}, err
+		}
+		parents = append(parents, untrackedCommit)
+	}
+
+	message := "WIP on " + on
+	if opts.Message != "" {
+		message = "On " + label + ": " + opts.Message
+	}
+	hash, err := repo.createCommit(tree, parents, who, who, message, nil)
+	if err != nil {
+		return StashEntry{}, err
+	}
+	if err := repo.UpdateRef(stashRef, "", hash, who, message); err != nil {
+		return StashEntry{}, err
+	}
+
+	if err := repo.resetToHead(idx); err != nil {
+		return StashEntry{}, err
+	}
+	if err := repo.WriteIndex(idx); err != nil {
+		return StashEntry{}, err
+	}
+	for _, entry := range untrackedIdx.Entries {
+		if err := removeWorktreeFile(repo.WorkTree, entry.Path); err != nil {
+			return StashEntry{}, err
+		}
+	}
+	return repo.readStash(hash)
+}
@@ -0,0 +1,85 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestStash(t *testing.T) {
+	newTestRepo(t)
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stageTestFiles(t, repo, map[string]string{"a.txt": "a\n", "b.txt": "b\n"})
+	commitTestIndex(t, repo)
+	who := Signature{Name: "Ann", Email: "ann@example.com", Time: time.Unix(1703130000, 0)}
+
+	if _, err := repo.Stash(who, StashOptions{}); !errors.Is(err, ErrNothingToStash) {
+		t.Fatalf("stashing a clean tree: got %v", err)
+	}
+
+	// a.txt is staged, b.txt is only changed in the work tree, and new.txt is untracked.
+	stageTestFiles(t, repo, map[string]string{"a.txt": "staged\n"})
+	if err := os.WriteFile("b.txt", []byte("unstaged\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("new.txt", []byte("new\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	first, err := repo.Stash(who, StashOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Branch != "main" || first.Untracked != "" {
+		t.Errorf("stash on %q with untracked tree %q, want main and none", first.Branch, first.Untracked)
+	}
+	for path, want := range map[string]string{"a.txt": "a\n", "b.txt": "b\n", "new.txt": "new\n"} {
+		if data, _ := os.ReadFile(path); string(data) != want {
+			t.Errorf("%s = %q after stashing, want %q", path, data, want)
+		}
+	}
+	r, err := newCommitReader(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	for tree, want := range map[string]map[string]string{
+		first.Staged: {"a.txt": "staged\n", "b.txt": "b\n"},
+		first.Tree:   {"a.txt": "staged\n", "b.txt": "unstaged\n"},
+	} {
+		files := map[string]TreeEntry{}
+		if err := r.flatten(tree, "", files); err != nil {
+			t.Fatal(err)
+		}
+		for path, content := range want {
+			if data, _ := repo.ReadBlob(files[path].Hash); string(data) != content {
+				t.Errorf("tree %s has %s = %q, want %q", tree[:7], path, data, content)
+			}
+		}
+	}
+
+	second, err := repo.Stash(who, StashOptions{Message: "keep new", IncludeUntracked: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat("new.txt"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("new.txt is still there after stashing untracked files: %v", err)
+	}
+	if second.Untracked == "" || second.Message != "On main: keep new" {
+		t.Errorf("stash has untracked tree %q and message %q", second.Untracked, second.Message)
+	}
+
+	stashes, err := repo.Stashes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stashes) != 2 || stashes[0].Hash != second.Hash || stashes[1].Hash != first.Hash || stashes[1].Index != 1 {
+		t.Fatalf("Stashes() = %+v, want the second stash then the first", stashes)
+	}
+	if stashes[1].Staged != first.Staged || stashes[1].Base != first.Base {
+		t.Errorf("stash@{1} = %+v, want %+v", stashes[1], first)
+	}
+}
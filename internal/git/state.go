@@ -0,0 +1,101 @@
+package git
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Operation is a git command that stopped partway, usually on conflicts, and waits to be continued or
+// aborted.
+type Operation string
+
+const (
+	NoOperation         Operation = ""
+	OperationMerge      Operation = "merge"
+	OperationRebase     Operation = "rebase"
+	OperationCherryPick Operation = "cherry-pick"
+	OperationRevert     Operation = "revert"
+	OperationBisect     Operation = "bisect"
+	OperationApplyMbox  Operation = "am"
+)
+
+// RepoState is what [Repository.State] found in progress.
+type RepoState struct {
+	Operation Operation
+	Commit    string // The commit being merged, picked, reverted, or replayed, if known
+	Branch    string // The branch being rebased, e.g. refs/heads/feature, or bisected from
+	Onto      string // The commit a rebase is replaying onto
+}
+
+// State reports the operation in progress in this work tree from the files git keeps for it in the git
+// directory: rebase-merge or rebase-apply for a rebase, rebase-apply/applying for git am, MERGE_HEAD,
+// CHERRY_PICK_HEAD, REVERT_HEAD, and BISECT_LOG. A rebase that stopped on a conflict is reported as a
+// rebase rather than as the cherry-pick it uses underneath, as git status does. With nothing in progress
+// the operation is [NoOperation].
+func (repo *Repository) State() (RepoState, error) {
+	for _, dir := range []string{rebaseDir, "rebase-apply"} {
+		path := filepath.Join(repo.GitDir, dir)
+		if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return RepoState{}, err
+		}
+
+		state := RepoState{Operation: OperationRebase}
+		if _, err := os.Stat(filepath.Join(path, "applying")); err == nil {
+			state.Operation = OperationApplyMbox
+		}
+		for name, field := range map[string]*string{"head-name": &state.Branch, "onto": &state.Onto} {
+			if err := readStateFile(filepath.Join(path, name), field); err != nil {
+				return RepoState{}, err
+			}
+		}
+		if err := readStateFile(filepath.Join(repo.GitDir, rebaseHead), &state.Commit); err != nil {
+			return RepoState{}, err
+		}
+		return state, nil
+	}
+
+	for _, op := range []struct {
+		file string
+		kind Operation
+	}{{"MERGE_HEAD", OperationMerge}, {cherryPickHead, OperationCherryPick}, {revertHead, OperationRevert}} {
+		var commit string
+		if err := readStateFile(filepath.Join(repo.GitDir, op.file), &commit); err != nil {
+			return RepoState{}, err
+		}
+		if commit != "" {
+			// MERGE_HEAD lists every commit an octopus merge brings in, one per line.
+			commit, _, _ = strings.Cut(commit, "\n")
+			return RepoState{Operation: op.kind, Commit: commit}, nil
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(repo.GitDir, "BISECT_LOG")); err == nil {
+		state := RepoState{Operation: OperationBisect}
+		if err := readStateFile(filepath.Join(repo.GitDir, "BISECT_START"), &state.Branch); err != nil {
+			return RepoState{}, err
+		}
+		return state, nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return RepoState{}, err
+	}
+	return RepoState{}, nil
+}
+
+// readStateFile sets value to the trimmed contents of the file at path, leaving it alone if there is no
+// such file.
+func readStateFile(path string, value *string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	*value = strings.TrimSpace(string(data))
+	return nil
+}
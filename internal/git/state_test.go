@@ -0,0 +1,57 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestState(t *testing.T) {
+	gitDir := newTestRepo(t)
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(gitDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	check := func(want RepoState) {
+		t.Helper()
+		got, err := repo.State()
+		if err != nil || got != want {
+			t.Errorf("State() = %+v, %v, want %+v", got, err, want)
+		}
+	}
+	commit := writeTestCommit(t, gitDir, "theirs")
+	other := writeTestCommit(t, gitDir, "other")
+
+	check(RepoState{})
+
+	write("BISECT_LOG", "git bisect start\n")
+	write("BISECT_START", "main\n")
+	check(RepoState{Operation: OperationBisect, Branch: "main"})
+
+	write(revertHead, commit+"\n")
+	check(RepoState{Operation: OperationRevert, Commit: commit})
+	write(cherryPickHead, commit+"\n")
+	check(RepoState{Operation: OperationCherryPick, Commit: commit})
+	write("MERGE_HEAD", commit+"\n"+other+"\n")
+	check(RepoState{Operation: OperationMerge, Commit: commit})
+
+	// A rebase stopped on a conflict leaves CHERRY_PICK_HEAD behind too, but is reported as a rebase.
+	write(rebaseDir+"/head-name", "refs/heads/feature\n")
+	write(rebaseDir+"/onto", other+"\n")
+	write(rebaseHead, commit+"\n")
+	check(RepoState{Operation: OperationRebase, Commit: commit, Branch: "refs/heads/feature", Onto: other})
+	os.RemoveAll(filepath.Join(gitDir, rebaseDir))
+
+	write("rebase-apply/applying", "")
+	check(RepoState{Operation: OperationApplyMbox, Commit: commit})
+}
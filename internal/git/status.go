@@ -0,0 +1,322 @@
+package git
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// FileStatus describes how a path differs between two of HEAD, the index, and the work tree.
+type FileStatus int
+
+const (
+	StatusModified   FileStatus = iota + 1 // The content or mode changed
+	StatusAdded                            // The path is new
+	StatusDeleted                          // The path was removed
+	StatusUntracked                        // The path exists in the work tree but was never staged
+	StatusConflicted                       // The path has unresolved merge conflict stages
+	StatusRenamed                          // The path was moved from another, which git status tells from a delete and add
+)
+
+var fileStatusName = map[FileStatus]string{
+	StatusModified:   "modified",
+	StatusAdded:      "added",
+	StatusDeleted:    "deleted",
+	StatusUntracked:  "untracked",
+	StatusConflicted: "conflicted",
+	StatusRenamed:    "renamed",
+}
+
+func (s FileStatus) String() string {
+	return fileStatusName[s]
+}
+
+// Code returns the single letter git status --porcelain uses for s: M, A, D, ?, U or R, and . when unchanged.
+func (s FileStatus) Code() byte {
+	switch s {
+	case StatusModified:
+		return 'M'
+	case StatusAdded:
+		return 'A'
+	case StatusDeleted:
+		return 'D'
+	case StatusUntracked:
+		return '?'
+	case StatusConflicted:
+		return 'U'
+	case StatusRenamed:
+		return 'R'
+	default:
+		return '.'
+	}
+}
+
+// StatusEntry is a path that differs between HEAD, the index, or the work tree.
+//
+// A zero Staged or Unstaged means that side is unchanged. Conflicted paths are reported with Staged set to
+// [StatusConflicted], and untracked paths with Unstaged set to [StatusUntracked].
+type StatusEntry struct {
+	Path     string     // The slash separated path relative to the work tree root
+	Staged   FileStatus // How the index differs from HEAD
+	Unstaged FileStatus // How the work tree differs from the index
+	From     string     // The path a [StatusRenamed] path was renamed from
+}
+
+// Status compares HEAD's tree against the index, and the index against the work tree, returning every
+// path that differs sorted by path. A clean work tree has no entries.
+//
+// Like git, files whose size and modification time match what the index cached are assumed unchanged,
+// unless they were modified too close to when the index was written for the cache to be trusted.
+// Untracked files are listed individually, and nested repositories as their directory with a trailing
+// slash. Untracked paths that ignore matches are left out; pass nil to list them all. Submodules and
+// paths outside a sparse checkout are not inspected, though a sparse index's directories are expanded
+// to compare their files against HEAD.
+func (repo *Repository) Status(ignore *Ignore) ([]StatusEntry, error) {
+	defer phase("status")()
+	head, err := repo.headFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	idx, err := repo.Index()
+	if err != nil {
+		return nil, err
+	}
+	staged, err := repo.expandSparseDirs(idx.Entries)
+	if err != nil {
+		return nil, err
+	}
+
+	var indexWritten int64
+	if info, err := os.Stat(filepath.Join(repo.GitDir, "index")); err == nil {
+		indexWritten = info.ModTime().UnixNano()
+	}
+
+	changes := map[string]*StatusEntry{}
+	change := func(path string) *StatusEntry {
+		if changes[path] == nil {
+			changes[path] = &StatusEntry{Path: path}
+		}
+		return changes[path]
+	}
+
+	tracked := make(map[string]bool, len(staged))
+	for _, entry := range staged {
+		tracked[entry.Path] = true
+		if entry.Stage != 0 {
+			change(entry.Path).Staged = StatusConflicted
+		}
+	}
+
+	for _, entry := range staged {
+		if entry.Stage != 0 {
+			continue
+		}
+
+		// Intent-to-add entries are placeholders, and only show up as new in the work tree.
+		if !entry.IntentToAdd {
+			if old, ok := head[entry.Path]; !ok {
+				change(entry.Path).Staged = StatusAdded
+			} else if old.Hash != entry.Hash || old.Mode != entry.Mode {
+				change(entry.Path).Staged = StatusModified
+			}
+		}
+
+		if entry.SkipWorktree || entry.Mode == ModeSubmodule {
+			continue
+		}
+		status, err := repo.worktreeStatus(entry, indexWritten)
+		if err != nil {
+			return nil, err
+		}
+		if status != 0 {
+			change(entry.Path).Unstaged = status
+		}
+	}
+
+	for path := range head {
+		if !tracked[path] {
+			change(path).Staged = StatusDeleted
+		}
+	}
+
+	err = repo.walkUntracked("", tracked, ignore, func(path string) {
+		change(path).Unstaged = StatusUntracked
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]StatusEntry, 0, len(changes))
+	for _, entry := range changes {
+		entries = append(entries, *entry)
+	}
+	slices.SortFunc(entries, func(a, b StatusEntry) int { return strings.Compare(a.Path, b.Path) })
+	return entries, nil
+}
+
+// headFiles returns every file in HEAD's tree keyed by path, or none if HEAD has no commits yet.
+func (repo *Repository) headFiles() (map[string]TreeEntry, error) {
+	files := map[string]TreeEntry{}
+
+	hash, err := repo.ResolveRevision("HEAD")
+	if errors.Is(err, ErrRefNotFound) {
+		return files, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := newCommitReader(repo)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	commit, _, err := r.read(hash)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.flatten(commit.Tree, "", files); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// expandSparseDirs replaces each sparse directory entry with entries for the files in its tree, which
+// are outside the sparse checkout like the directory was.
+func (repo *Repository) expandSparseDirs(entries []IndexEntry) ([]IndexEntry, error) {
+	if !slices.ContainsFunc(entries, func(e IndexEntry) bool { return e.Mode == ModeTree }) {
+		return entries, nil
+	}
+
+	r, err := newCommitReader(repo)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	expanded := make([]IndexEntry, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Mode != ModeTree {
+			expanded = append(expanded, entry)
+			continue
+		}
+		files := map[string]TreeEntry{}
+		if err := r.flatten(entry.Hash, entry.Path, files); err != nil {
+			return nil, err
+		}
+		for path, file := range files {
+			expanded = append(expanded, IndexEntry{Path: path, Mode: file.Mode, Hash: file.Hash, SkipWorktree: true})
+		}
+	}
+	return expanded, nil
+}
+
+// worktreeStatus compares a stage 0 index entry against the file in the work tree.
+func (repo *Repository) worktreeStatus(entry IndexEntry, indexWritten int64) (FileStatus, error) {
+	full := filepath.Join(repo.WorkTree, filepath.FromSlash(entry.Path))
+	info, err := os.Lstat(full)
+	if errors.Is(err, fs.ErrNotExist) || err == nil && info.IsDir() {
+		return StatusDeleted, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if entry.IntentToAdd {
+		return StatusAdded, nil
+	}
+
+	mode := worktreeMode(info)
+	if mode != entry.Mode {
+		return StatusModified, nil
+	}
+
+	// The stat cache is only trusted for files last written before the index, since a write within the
+	// same timestamp tick as the index could have gone unnoticed.
+	mtime := info.ModTime()
+	hit := mtime.Equal(entry.MTime) && uint32(info.Size()) == entry.Size && mtime.UnixNano() < indexWritten
+	observe(func(o Observer) { o.CacheLookup("stat", hit) })
+	if hit {
+		return 0, nil
+	}
+
+	hash, err := hashWorktreeFile(repo.Format, full, info)
+	if err != nil {
+		return 0, err
+	}
+	if hash != entry.Hash {
+		return StatusModified, nil
+	}
+	return 0, nil
+}
+
+func worktreeMode(info fs.FileInfo) FileMode {
+	switch {
+	case info.Mode()&fs.ModeSymlink != 0:
+		return ModeSymlink
+	case info.Mode()&0o111 != 0:
+		return ModeExecutable
+	default:
+		return ModeFile
+	}
+}
+
+// hashWorktreeFile returns the blob hash in format git would store for the file at path.
+func hashWorktreeFile(format HashFormat, path string, info fs.FileInfo) (string, error) {
+	if info.Mode()&fs.ModeSymlink != 0 {
+		target, err := os.Readlink(path)
+		if err != nil {
+			return "", err
+		}
+		return format.HashObject(BlobObject, []byte(filepath.ToSlash(target))), nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return format.HashObjectStream(BlobObject, info.Size(), f)
+}
+
+// walkUntracked calls found for every file below dir (slash separated, relative to the work tree root)
+// that isn't in tracked or ignored. Ignored directories aren't descended into.
+func (repo *Repository) walkUntracked(dir string, tracked map[string]bool, ignore *Ignore, found func(path string)) error {
+	entries, err := os.ReadDir(filepath.Join(repo.WorkTree, filepath.FromSlash(dir)))
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.Name() == ".git" {
+			continue
+		}
+
+		path := dir + entry.Name()
+		if !tracked[path] && ignore != nil && ignore.matches(path, entry.IsDir()) {
+			continue
+		}
+		if !entry.IsDir() {
+			if !tracked[path] {
+				found(path)
+			}
+			continue
+		}
+
+		if _, err := os.Lstat(filepath.Join(repo.WorkTree, filepath.FromSlash(path), ".git")); err == nil {
+			if !tracked[path] {
+				found(path + "/")
+			}
+			continue
+		}
+		if err := repo.walkUntracked(path+"/", tracked, ignore, found); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func writeTestFiles(t *testing.T, files map[string]string) {
+	t.Helper()
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// commitTestIndex commits the paths staged in the index to main and points HEAD at it.
+func commitTestIndex(t *testing.T, repo *Repository) {
+	t.Helper()
+	idx, err := repo.Index()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := NewTreeBuilder(repo.Encoder())
+	for _, entry := range idx.Entries {
+		if err := b.Add(entry.Path, entry.Mode, entry.Hash); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tree, err := b.Write()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sig := Signature{Name: "Jane", Email: "jane@example.com", Time: time.Unix(1703123457, 0)}
+	hash, err := repo.CreateCommit(tree, nil, sig, sig, "initial")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestRef(t, repo.GitDir, "refs/heads/main", hash)
+	if err := os.WriteFile(filepath.Join(repo.GitDir, "HEAD"), []byte("ref: refs/heads/main\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	newTestRepo(t)
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeTestFiles(t, map[string]string{"a.txt": "a\n", "dir/b.txt": "b\n", "gone.txt": "gone\n", "same.txt": "same\n"})
+	idx, _ := repo.Index()
+	for _, path := range []string{"a.txt", "dir/b.txt", "gone.txt", "same.txt"} {
+		if err := repo.StagePath(idx, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.WriteIndex(idx); err != nil {
+		t.Fatal(err)
+	}
+	commitTestIndex(t, repo)
+
+	if entries, err := repo.Status(nil); err != nil || len(entries) != 0 {
+		t.Fatalf("expected a clean status right after committing, got %+v, %v", entries, err)
+	}
+
+	writeTestFiles(t, map[string]string{"a.txt": "changed\n", "new.txt": "new\n", "untracked/u.txt": "u\n"})
+	os.Remove("gone.txt")
+	idx, _ = repo.Index()
+	idx.Remove("dir/b.txt")
+	if err := repo.StagePath(idx, "new.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.WriteIndex(idx); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := repo.Status(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []StatusEntry{
+		{Path: "a.txt", Unstaged: StatusModified},
+		{Path: "dir/b.txt", Staged: StatusDeleted, Unstaged: StatusUntracked},
+		{Path: "gone.txt", Unstaged: StatusDeleted},
+		{Path: "new.txt", Staged: StatusAdded},
+		{Path: "untracked/u.txt", Unstaged: StatusUntracked},
+	}
+	if !slices.Equal(entries, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, entries)
+	}
+}
+
+func TestStatusConflict(t *testing.T) {
+	newTestRepo(t)
+	repo, _ := OpenRepository()
+
+	hash := HashObject(BlobObject, []byte("a\n"))
+	idx := &Index{Version: 2}
+	for stage := 1; stage <= 3; stage++ {
+		idx.Add(IndexEntry{Path: "a.txt", Mode: ModeFile, Hash: hash, Stage: stage})
+	}
+	if err := repo.WriteIndex(idx); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFiles(t, map[string]string{"a.txt": "<<<<<<< ours\n"})
+
+	entries, err := repo.Status(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Staged != StatusConflicted || entries[0].Unstaged != 0 {
+		t.Fatalf("expected a.txt to be conflicted, got %+v", entries)
+	}
+}
+
+func TestStatusSparseIndex(t *testing.T) {
+	if !GitInstalled() {
+		t.Skip("git isn't installed")
+	}
+	t.Chdir(t.TempDir())
+	writeTestFiles(t, map[string]string{"a.txt": "a\n", "dir/b.txt": "b\n", "dir/sub/c.txt": "c\n"})
+	c := NewShellClient()
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"add", "."},
+		{"-c", "user.name=Ann", "-c", "user.email=ann@example.com", "commit", "--quiet", "--message", "base"},
+		{"sparse-checkout", "init", "--cone", "--sparse-index"},
+		{"sparse-checkout", "set"},
+	} {
+		if err := c.Run(args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx, err := repo.Index()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := idx.Entry("dir/"); !ok {
+		t.Fatalf("expected dir/ to be a sparse directory, got %+v", idx.Entries)
+	}
+
+	if entries, err := repo.Status(nil); err != nil || len(entries) != 0 {
+		t.Fatalf("expected a clean sparse checkout, got %+v, %v", entries, err)
+	}
+
+	writeTestFiles(t, map[string]string{"a.txt": "changed\n"})
+	entries, err := repo.Status(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []StatusEntry{{Path: "a.txt", Unstaged: StatusModified}}; !slices.Equal(entries, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, entries)
+	}
+}
+
+func TestFileStatusCode(t *testing.T) {
+	codes := map[FileStatus]byte{0: '.', StatusModified: 'M', StatusAdded: 'A', StatusDeleted: 'D', StatusUntracked: '?', StatusConflicted: 'U', StatusRenamed: 'R'}
+	for status, want := range codes {
+		if got := status.Code(); got != want {
+			t.Errorf("%v.Code() = %c, want %c", status, got, want)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrNoTag = errors.New("no tag found")
+
+// NearestTag returns the name of the most recent tag on rev or one of its ancestors, like
+// git describe --tags --abbrev=0. Both lightweight and annotated tags are considered, limited to those
+// whose name match accepts when it isn't nil; when several tags point to the same commit the greatest
+// name wins. Fails with [ErrNoTag] if no tag is reachable.
+func (repo *Repository) NearestTag(rev string, match func(name string) bool) (string, error) {
+	hash, err := repo.ResolveRevision(rev)
+	if err != nil {
+		return "", err
+	}
+	history, err := repo.historyFrom(hash)
+	if err != nil {
+		return "", err
+	}
+
+	tagged, err := repo.taggedCommits(match)
+	if err != nil {
+		return "", err
+	}
+
+	for _, commit := range topoOrder(history.Graph) {
+		if name, ok := tagged[commit.Hash]; ok {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("%w in the history of %s", ErrNoTag, rev)
+}
+
+// taggedCommits maps each commit a tag accepted by match points to, after peeling annotated tags, to the
+// tag's short name.
+func (repo *Repository) taggedCommits(match func(name string) bool) (map[string]string, error) {
+	refs, err := repo.listRefs("refs/tags/")
+	if err != nil {
+		return nil, fmt.Errorf("git: failed to read tags: %w", err)
+	}
+
+	r, err := newCommitReader(repo)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	tagged := map[string]string{}
+	for ref, hash := range refs {
+		name := strings.TrimPrefix(ref, "refs/tags/")
+		if match != nil && !match(name) {
+			continue
+		}
+
+		commit, err := r.peel(hash)
+		if err != nil {
+			return nil, fmt.Errorf("git: failed to read %s: %w", ref, err)
+		}
+		if name > tagged[commit] {
+			tagged[commit] = name
+		}
+	}
+	return tagged, nil
+}
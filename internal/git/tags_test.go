@@ -0,0 +1,52 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestNearestTag(t *testing.T) {
+	gitDir := newTestRepo(t)
+	root := writeTestCommit(t, gitDir, "root")
+	middle := writeTestCommit(t, gitDir, "middle", root)
+	head := writeTestCommit(t, gitDir, "head", middle)
+	writeTestRef(t, gitDir, "refs/heads/main", head)
+	writeTestRef(t, gitDir, "refs/tags/v0.1.0", root)
+
+	annotated := writeTestObject(t, gitDir, TagObject, fmt.Sprintf(
+		"object %s\ntype commit\ntag v0.2.0\ntagger John Doe <john.doe@example.com> 1703123456 +0000\n\nv0.2.0\n", middle))
+	writeTestRef(t, gitDir, "refs/tags/v0.2.0", annotated)
+
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tag, err := repo.NearestTag("main", nil); err != nil || tag != "v0.2.0" {
+		t.Fatalf("expected v0.2.0 through the annotated tag, got %q, %v", tag, err)
+	}
+	if tag, err := repo.NearestTag(root, nil); err != nil || tag != "v0.1.0" {
+		t.Fatalf("expected v0.1.0 on the root commit, got %q, %v", tag, err)
+	}
+
+	untagged := writeTestCommit(t, gitDir, "untagged")
+	if _, err := repo.NearestTag(untagged, nil); !errors.Is(err, ErrNoTag) {
+		t.Fatalf("expected ErrNoTag, got %v", err)
+	}
+}
+
+func TestNearestTagMatching(t *testing.T) {
+	gitDir := newTestRepo(t)
+	root := writeTestCommit(t, gitDir, "root")
+	head := writeTestCommit(t, gitDir, "head", root)
+	writeTestRef(t, gitDir, "refs/tags/api/v1.0.0", root)
+	writeTestRef(t, gitDir, "refs/tags/web/v2.0.0", head)
+
+	repo, _ := OpenRepository()
+	tag, err := repo.NearestTag(head, func(name string) bool { return strings.HasPrefix(name, "api/") })
+	if err != nil || tag != "api/v1.0.0" {
+		t.Fatalf("expected api/v1.0.0, got %q, %v", tag, err)
+	}
+}
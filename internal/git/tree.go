@@ -0,0 +1,192 @@
+package git
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var ErrTreeConflict = errors.New("path conflicts with an existing tree entry")
+
+// FileMode is the mode git records for a tree entry.
+type FileMode uint32
+
+const (
+	ModeTree       FileMode = 0o040000 // A subdirectory
+	ModeFile       FileMode = 0o100644 // A regular file
+	ModeExecutable FileMode = 0o100755 // An executable file
+	ModeSymlink    FileMode = 0o120000 // A symbolic link, whose blob holds the link target
+	ModeSubmodule  FileMode = 0o160000 // A submodule, whose hash is a commit in another repository
+)
+
+func (m FileMode) String() string {
+	return strconv.FormatUint(uint64(m), 8)
+}
+
+// TreeEntry is a single entry of a git tree object.
+type TreeEntry struct {
+	Mode FileMode // The kind of entry and its permissions
+	Name string   // The entry's name within its directory
+	Hash string   // The blob, tree, or commit the entry points to
+}
+
+// TreeBuilder assembles tree objects from file paths, creating the nested trees for each directory.
+//
+// Paths can be added in any order; entries are sorted the way git requires when the trees are written.
+// A new TreeBuilder is created by calling [NewTreeBuilder].
+type TreeBuilder struct {
+	e    *Encoder
+	root *treeNode
+}
+
+type treeNode struct {
+	entries map[string]TreeEntry // Files, symlinks, and submodules in this directory
+	dirs    map[string]*treeNode // Subdirectories, written as trees
+}
+
+// NewTreeBuilder returns an empty TreeBuilder that writes its trees with e.
+func NewTreeBuilder(e *Encoder) *TreeBuilder {
+	return &TreeBuilder{e: e, root: newTreeNode()}
+}
+
+func newTreeNode() *treeNode {
+	return &treeNode{entries: map[string]TreeEntry{}, dirs: map[string]*treeNode{}}
+}
+
+// Add places the object hash at the slash separated path, replacing any entry already there.
+//
+// Adding an entry with [ModeTree] grafts an existing tree in as a directory. Fails with [ErrTreeConflict]
+// if a parent directory of path was added as a file.
+func (b *TreeBuilder) Add(path string, mode FileMode, hash string) error {
+	if !isFullHash(hash) {
+		return fmt.Errorf("git: %q is not a full hash", hash)
+	}
+
+	dir, name, err := b.parent(path, true)
+	if err != nil {
+		return err
+	}
+
+	delete(dir.dirs, name)
+	delete(dir.entries, name)
+	dir.entries[name] = TreeEntry{Mode: mode, Name: name, Hash: hash}
+	return nil
+}
+
+// Remove deletes the entry or directory at path. Removing a path that isn't present does nothing.
+func (b *TreeBuilder) Remove(path string) {
+	dir, name, err := b.parent(path, false)
+	if err != nil || dir == nil {
+		return
+	}
+	delete(dir.dirs, name)
+	delete(dir.entries, name)
+}
+
+// Write stores every tree and returns the hash of the root tree.
+// Directories left without any entries are omitted, as git never records empty directories.
+func (b *TreeBuilder) Write() (string, error) {
+	return b.write(b.root)
+}
+
+func (b *TreeBuilder) write(node *treeNode) (string, error) {
+	entries := make([]TreeEntry, 0, len(node.entries)+len(node.dirs))
+	for _, entry := range node.entries {
+		entries = append(entries, entry)
+	}
+
+	for name, dir := range node.dirs {
+		if dir.empty() {
+			continue
+		}
+		hash, err := b.write(dir)
+		if err != nil {
+			return "", err
+		}
+		entries = append(entries, TreeEntry{Mode: ModeTree, Name: name, Hash: hash})
+	}
+
+	data, err := serializeTree(entries)
+	if err != nil {
+		return "", err
+	}
+	return b.e.Encode(TreeObject, data)
+}
+
+// parent walks to the directory holding path, creating directories along the way when create is set,
+// and returns it with the final path element.
+func (b *TreeBuilder) parent(path string, create bool) (*treeNode, string, error) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	for _, part := range parts {
+		if part == "" || part == "." || part == ".." || part == ".git" {
+			return nil, "", fmt.Errorf("git: invalid tree path %q", path)
+		}
+	}
+
+	node := b.root
+	for _, part := range parts[:len(parts)-1] {
+		// A file, or a tree grafted in by hash, can't have entries added beneath it.
+		if _, ok := node.entries[part]; ok {
+			return nil, "", fmt.Errorf("%w: %s", ErrTreeConflict, path)
+		}
+
+		next, ok := node.dirs[part]
+		if !ok {
+			if !create {
+				return nil, "", nil
+			}
+			next = newTreeNode()
+			node.dirs[part] = next
+		}
+		node = next
+	}
+	return node, parts[len(parts)-1], nil
+}
+
+func (n *treeNode) empty() bool {
+	if len(n.entries) > 0 {
+		return false
+	}
+	for _, dir := range n.dirs {
+		if !dir.empty() {
+			return false
+		}
+	}
+	return true
+}
+
+// serializeTree writes entries in git's canonical tree format, without the object header.
+func serializeTree(entries []TreeEntry) ([]byte, error) {
+	slices.SortFunc(entries, compareTreeEntries)
+
+	var b bytes.Buffer
+	for _, entry := range entries {
+		raw, err := hex.DecodeString(entry.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("git: bad hash for tree entry %s: %w", entry.Name, err)
+		}
+		b.WriteString(entry.Mode.String())
+		b.WriteByte(' ')
+		b.WriteString(entry.Name)
+		b.WriteByte(0)
+		b.Write(raw)
+	}
+	return b.Bytes(), nil
+}
+
+// compareTreeEntries orders entries the way git does: by name, with trees compared as if their name
+// ended in a slash. So "foo.c" sorts before the directory "foo", which sorts before "foo0".
+func compareTreeEntries(a, b TreeEntry) int {
+	return strings.Compare(treeSortKey(a), treeSortKey(b))
+}
+
+func treeSortKey(entry TreeEntry) string {
+	if entry.Mode == ModeTree {
+		return entry.Name + "/"
+	}
+	return entry.Name
+}
@@ -0,0 +1,56 @@
+package git
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTreeBuilder(t *testing.T) {
+	e := NewEncoder(t.TempDir(), SHA1)
+	blob, err := e.Encode(BlobObject, []byte("hello\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := NewTreeBuilder(e)
+	for _, path := range []string{"foo0", "foo/bar.txt", "foo.c", "docs/empty/gone.txt"} {
+		if err := b.Add(path, ModeFile, blob); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := b.Add("run.sh", ModeExecutable, blob); err != nil {
+		t.Fatal(err)
+	}
+	b.Remove("docs/empty/gone.txt")
+
+	root, err := b.Write()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The same layout built by git update-index + write-tree.
+	if root != "32a0fa1d6141d91831541f56e7679a1b3ac38635" {
+		t.Fatalf("unexpected root tree %s", root)
+	}
+}
+
+func TestTreeBuilderEmpty(t *testing.T) {
+	root, err := NewTreeBuilder(NewEncoder(t.TempDir(), SHA1)).Write()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root != "4b825dc642cb6eb9a060e54bf8d69288fbee4904" {
+		t.Fatalf("expected the empty tree, got %s", root)
+	}
+}
+
+func TestTreeBuilderConflict(t *testing.T) {
+	e := NewEncoder(t.TempDir(), SHA1)
+	blob, _ := e.Encode(BlobObject, []byte("x"))
+
+	b := NewTreeBuilder(e)
+	b.Add("file", ModeFile, blob)
+	if err := b.Add("file/child", ModeFile, blob); !errors.Is(err, ErrTreeConflict) {
+		t.Fatalf("expected ErrTreeConflict, got %v", err)
+	}
+}
@@ -0,0 +1,378 @@
+package git
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var ErrRemoteProtocol = errors.New("remote broke the git protocol")
+
+// transport carries requests to a git service on a remote, like git-upload-pack or git-receive-pack, and
+// their responses back, over HTTP or SSH.
+type transport interface {
+	// roundTrip sends a request, a message of pkt-lines that may be followed by a pack, and returns the
+	// response, which must be read up to the packet that ends it before the next request.
+	roundTrip(request io.Reader) (io.ReadCloser, error)
+	Close() error
+}
+
+// uploadPack is a conversation in git's protocol v2 with a remote's git-upload-pack, which lists refs
+// and sends the objects they lead to.
+//
+// An uploadPack is started by calling [dialUploadPack], which reads the remote's capabilities.
+type uploadPack struct {
+	t            transport
+	remote       string // The remote's URL, for errors
+	format       HashFormat
+	capabilities map[string]string // Capability name to its value, which is "" for capabilities without one
+}
+
+// dialUploadPack connects to the git-upload-pack of the remote at remoteURL, over HTTP through client for
+// http and https URLs, and over SSH for ssh:// and scp-like user@host:path URLs. It fails with
+// [ErrUnsupportedRemote] for any other URL, and with [ErrRemoteProtocol] if the remote only speaks an
+// older protocol.
+func dialUploadPack(client *http.Client, remoteURL string, format HashFormat) (*uploadPack, error) {
+	c := &uploadPack{remote: remoteURL, format: format}
+	var advertisement io.ReadCloser
+	var err error
+	c.t, advertisement, err = dialService(client, remoteURL, "git-upload-pack", "version=2")
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.readCapabilities(advertisement)
+	advertisement.Close()
+	if err != nil {
+		c.t.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// dialService starts service on the remote at remoteURL, asking for protocol as [dialSSH] and
+// [dialSmartHTTP] do, and returns the transport and the service's advertisement. It fails with
+// [ErrUnsupportedRemote] for anything but http, https, ssh, and scp-like URLs.
+func dialService(client *http.Client, remoteURL, service, protocol string) (transport, io.ReadCloser, error) {
+	if endpoint, ok := parseSSHURL(remoteURL); ok {
+		return dialSSH(endpoint, service, protocol)
+	}
+	if u, _ := url.Parse(remoteURL); u != nil && (u.Scheme == "https" || u.Scheme == "http") {
+		return dialSmartHTTP(client, remoteURL, service, protocol)
+	}
+	return nil, nil, fmt.Errorf("%w: only https and ssh remotes can be used natively, not %s", ErrUnsupportedRemote, remoteURL)
+}
+
+// readCapabilities reads the capability advertisement that starts every protocol v2 conversation.
+func (c *uploadPack) readCapabilities(r io.Reader) error {
+	p := newPktReader(r)
+	line, ok, err := p.line()
+	if err == nil && ok && strings.HasPrefix(line, "# service=") {
+		// Servers may start the advertisement the way protocol v0 does over HTTP, with the service it is for.
+		if _, _, err = p.line(); err == nil {
+			line, ok, err = p.line()
+		}
+	}
+	var message *remoteMessage
+	if errors.As(err, &message) {
+		return message
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s didn't advertise its capabilities: %w", ErrRemoteProtocol, c.remote, err)
+	}
+	if !ok || line != "version 2" {
+		return fmt.Errorf("%w: %s doesn't speak protocol v2", ErrRemoteProtocol, c.remote)
+	}
+	c.capabilities = map[string]string{}
+	for {
+		line, ok, err := p.line()
+		if err != nil {
+			return fmt.Errorf("%w: %s didn't advertise its capabilities: %w", ErrRemoteProtocol, c.remote, err)
+		}
+		if !ok {
+			break
+		}
+		name, value, _ := strings.Cut(line, "=")
+		c.capabilities[name] = value
+	}
+
+	for _, command := range []string{"ls-refs", "fetch"} {
+		if _, ok := c.capabilities[command]; !ok {
+			return fmt.Errorf("%w: %s doesn't support %s", ErrRemoteProtocol, c.remote, command)
+		}
+	}
+	if remote := c.capabilities["object-format"]; remote != "" && remote != c.format.String() || remote == "" && c.format != SHA1 {
+		return fmt.Errorf("git: %s names objects with %s, but the repository uses %s", c.remote, cmp.Or(remote, SHA1.String()), c.format)
+	}
+	return nil
+}
+
+// lsRefs lists the server's refs under any of prefixes, or all of them if there are none.
+func (c *uploadPack) lsRefs(prefixes []string) ([]RemoteRef, error) {
+	var w pktWriter
+	c.command(&w, "ls-refs")
+	w.line("symrefs\n")
+	w.line("peel\n")
+	for _, prefix := range prefixes {
+		w.line("ref-prefix " + prefix + "\n")
+	}
+	w.flush()
+
+	body, err := c.t.roundTrip(bytes.NewReader(w.buf))
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var refs []RemoteRef
+	p := newPktReader(body)
+	for {
+		line, ok, err := p.line()
+		if err != nil {
+			return nil, fmt.Errorf("%w: listing refs: %w", ErrRemoteProtocol, err)
+		}
+		if !ok {
+			return refs, nil
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("%w: bad ref %q", ErrRemoteProtocol, line)
+		}
+		ref := RemoteRef{Hash: fields[0], Name: fields[1]}
+		for _, attribute := range fields[2:] {
+			if target, ok := strings.CutPrefix(attribute, "symref-target:"); ok {
+				ref.Target = target
+			} else if peeled, ok := strings.CutPrefix(attribute, "peeled:"); ok {
+				ref.Peeled = peeled
+			}
+		}
+		refs = append(refs, ref)
+	}
+}
+
+// shallowUpdate is the shallow-info a server sends along with a pack to a shallow repository: the commits
+// whose parents were left out of it, and those the repository had been cut off at whose parents it now has.
+type shallowUpdate struct {
+	shallow, unshallow []string
+}
+
+// havesPerRound is how many commits the repository has that are offered to a server in each round of
+// a fetch's negotiation, so a server that finds enough in common early can stop it there.
+const havesPerRound = 32
+
+// fetch asks the server for a pack of the objects reachable from wants that aren't reachable from haves,
+// and returns it, to be read until it ends and then closed. The pack may be thin, with deltas against
+// objects in haves. Haves are offered a round at a time, the first most likely to be in common, until
+// the server is ready to send the pack or there are none left.
+//
+// Commits in shallow are where the repository's history was cut off; with deepen above 0, the pack goes
+// on to deepen more generations of history behind them, as git fetch --deepen asks. With includeTag,
+// annotated tags pointing at objects in the pack come along with it.
+func (c *uploadPack) fetch(wants, haves []string, shallow map[string]bool, deepen int, includeTag bool) (io.ReadCloser, shallowUpdate, error) {
+	var update shallowUpdate
+	if deepen > 0 && !slices.Contains(strings.Fields(c.capabilities["fetch"]), "shallow") {
+		return nil, update, fmt.Errorf("%w: %s doesn't support deepening shallow clones", ErrRemoteProtocol, c.remote)
+	}
+
+	// Protocol v2 is stateless, so each round repeats the wants and the haves found in common so far.
+	var common []string
+	for sent := 0; ; {
+		batch := haves[sent:min(sent+havesPerRound, len(haves))]
+		sent += len(batch)
+		done := sent == len(haves)
+
+		var w pktWriter
+		c.command(&w, "fetch")
+		w.line("thin-pack\n")
+		w.line("ofs-delta\n")
+		w.line("no-progress\n")
+		if includeTag {
+			w.line("include-tag\n")
+		}
+		for _, hash := range wants {
+			w.line("want " + hash + "\n")
+		}
+		for hash := range shallow {
+			w.line("shallow " + hash + "\n")
+		}
+		if deepen > 0 {
+			w.line("deepen " + strconv.Itoa(deepen) + "\n")
+			w.line("deepen-relative\n")
+		}
+		for _, hash := range slices.Concat(common, batch) {
+			w.line("have " + hash + "\n")
+		}
+		if done {
+			w.line("done\n")
+		}
+		w.flush()
+
+		body, err := c.t.roundTrip(bytes.NewReader(w.buf))
+		if err != nil {
+			return nil, update, err
+		}
+		p := newPktReader(body)
+		if !done {
+			acked, ready, err := readAcknowledgments(p)
+			if err != nil {
+				body.Close()
+				return nil, update, err
+			}
+			common = append(common, acked...)
+			if !ready {
+				body.Close()
+				continue
+			}
+		}
+		if update, err = readPackSections(p); err != nil {
+			body.Close()
+			return nil, update, err
+		}
+		return &sidebandReader{p: p, body: body}, update, nil
+	}
+}
+
+// readAcknowledgments reads the acknowledgments section a server answers a round of negotiation
+// without done with: the haves it has too, and whether it's ready to send the pack, which then follows.
+func readAcknowledgments(p *pktReader) ([]string, bool, error) {
+	section, ok, err := p.line()
+	if err != nil {
+		return nil, false, fmt.Errorf("%w: fetching: %w", ErrRemoteProtocol, err)
+	}
+	if !ok || section != "acknowledgments" {
+		return nil, false, fmt.Errorf("%w: the response to a fetch has no acknowledgments", ErrRemoteProtocol)
+	}
+	var acked []string
+	ready := false
+	for {
+		kind, data, err := p.next()
+		if err != nil {
+			return nil, false, fmt.Errorf("%w: fetching: %w", ErrRemoteProtocol, err)
+		}
+		switch kind {
+		case pktDelim:
+			// The pack follows, which the server only sends once it's ready.
+			if !ready {
+				return nil, false, fmt.Errorf("%w: the server sent a pack before it was ready", ErrRemoteProtocol)
+			}
+			return acked, true, nil
+		case pktData:
+		default:
+			return acked, false, nil
+		}
+		switch line := strings.TrimSuffix(string(data), "\n"); {
+		case line == "NAK":
+		case line == "ready":
+			ready = true
+		case strings.HasPrefix(line, "ACK "):
+			acked = append(acked, strings.TrimPrefix(line, "ACK "))
+		default:
+			return nil, false, fmt.Errorf("%w: bad acknowledgment %q", ErrRemoteProtocol, data)
+		}
+	}
+}
+
+// readPackSections reads the sections of a fetch's response up to the pack, collecting the shallow-info
+// section when there is one and skipping any others.
+func readPackSections(p *pktReader) (shallowUpdate, error) {
+	var update shallowUpdate
+	for {
+		section, ok, err := p.line()
+		if err != nil {
+			return update, fmt.Errorf("%w: fetching: %w", ErrRemoteProtocol, err)
+		}
+		if !ok {
+			return update, fmt.Errorf("%w: the response to a fetch has no pack", ErrRemoteProtocol)
+		}
+		if section == "packfile" {
+			return update, nil
+		}
+		for {
+			kind, data, err := p.next()
+			if err != nil {
+				return update, fmt.Errorf("%w: fetching: %w", ErrRemoteProtocol, err)
+			}
+			if kind == pktDelim {
+				break
+			}
+			if kind != pktData {
+				return update, fmt.Errorf("%w: the response to a fetch has no pack", ErrRemoteProtocol)
+			}
+			if section != "shallow-info" {
+				continue
+			}
+			switch verb, hash, _ := strings.Cut(strings.TrimSuffix(string(data), "\n"), " "); verb {
+			case "shallow":
+				update.shallow = append(update.shallow, hash)
+			case "unshallow":
+				update.unshallow = append(update.unshallow, hash)
+			default:
+				return update, fmt.Errorf("%w: bad shallow-info %q", ErrRemoteProtocol, data)
+			}
+		}
+	}
+}
+
+// sidebandReader reads the pack out of a fetch's packfile section, which comes multiplexed with
+// progress messages and errors, each packet starting with its band. Closing it closes the response.
+type sidebandReader struct {
+	p    *pktReader
+	body io.Closer
+	buf  []byte // What's left of the last packet of the pack
+	end  bool
+}
+
+func (s *sidebandReader) Read(b []byte) (int, error) {
+	for len(s.buf) == 0 {
+		if s.end {
+			return 0, io.EOF
+		}
+		kind, data, err := s.p.next()
+		if err != nil {
+			return 0, fmt.Errorf("%w: receiving the pack: %w", ErrRemoteProtocol, err)
+		}
+		if kind != pktData {
+			s.end = true
+			continue
+		}
+		if len(data) == 0 {
+			continue
+		}
+		switch data[0] {
+		case 1:
+			s.buf = data[1:]
+		case 2:
+		case 3:
+			return 0, fmt.Errorf("git: the remote failed: %s", strings.TrimSpace(string(data[1:])))
+		default:
+			return 0, fmt.Errorf("%w: unknown sideband %d", ErrRemoteProtocol, data[0])
+		}
+	}
+	n := copy(b, s.buf)
+	s.buf = s.buf[n:]
+	return n, nil
+}
+
+func (s *sidebandReader) Close() error {
+	return s.body.Close()
+}
+
+// command starts a request to run command, naming the hash format when it isn't the default.
+func (c *uploadPack) command(w *pktWriter, command string) {
+	w.line("command=" + command + "\n")
+	if c.format != SHA1 {
+		w.line("object-format=" + c.format.String() + "\n")
+	}
+	w.delim()
+}
+
+func (c *uploadPack) Close() error {
+	return c.t.Close()
+}
@@ -0,0 +1,143 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrNoUpstream = errors.New("no upstream configured")
+	ErrBadRefspec = errors.New("invalid refspec")
+)
+
+// Refspec maps refs on a remote to local refs, as in remote.<name>.fetch.
+type Refspec struct {
+	Force    bool   // Set for "+src:dst", which allows non-fast-forward updates
+	Negative bool   // Set for "^src", which excludes refs that other refspecs would fetch
+	Src      string // The remote refs, with at most one * wildcard
+	Dst      string // Where they are stored locally, with a * if Src has one, or "" to not store them
+}
+
+// ParseRefspec parses a fetch refspec such as +refs/heads/*:refs/remotes/origin/*.
+func ParseRefspec(spec string) (Refspec, error) {
+	var r Refspec
+	rest := spec
+	if after, ok := strings.CutPrefix(rest, "+"); ok {
+		r.Force, rest = true, after
+	} else if after, ok := strings.CutPrefix(rest, "^"); ok {
+		r.Negative, rest = true, after
+	}
+	r.Src, r.Dst, _ = strings.Cut(rest, ":")
+
+	if r.Src == "" || strings.Count(r.Src, "*") > 1 || strings.Count(r.Src, "*") != strings.Count(r.Dst, "*") && r.Dst != "" {
+		return Refspec{}, fmt.Errorf("%w: %q", ErrBadRefspec, spec)
+	}
+	if r.Negative && r.Dst != "" {
+		return Refspec{}, fmt.Errorf("%w: negative refspecs have no destination: %q", ErrBadRefspec, spec)
+	}
+	return r, nil
+}
+
+// Map returns the local ref the remote ref is stored in under this refspec, and whether the refspec
+// covers ref at all.
+func (r Refspec) Map(ref string) (string, bool) {
+	prefix, suffix, wildcard := strings.Cut(r.Src, "*")
+	if !wildcard {
+		return r.Dst, ref == r.Src
+	}
+	if len(ref) < len(prefix)+len(suffix) || !strings.HasPrefix(ref, prefix) || !strings.HasSuffix(ref, suffix) {
+		return "", false
+	}
+	match := ref[len(prefix) : len(ref)-len(suffix)]
+	return strings.Replace(r.Dst, "*", match, 1), true
+}
+
+// Upstream is the branch a local branch tracks.
+type Upstream struct {
+	Remote string // The remote, from branch.<name>.remote, or "." for a local branch
+	Merge  string // The upstream branch as the remote names it, e.g. refs/heads/main
+	Ref    string // The local ref holding its last fetched state, or "" if no fetch refspec stores it
+}
+
+// Upstream resolves the upstream of branch from branch.<name>.remote and branch.<name>.merge, and the
+// remote's fetch refspecs, like git's @{upstream}. So a branch tracking main on a remote whose fetch
+// refspec is +refs/heads/*:refs/remotes/upstream/* has the ref refs/remotes/upstream/main. A branch
+// without an upstream fails with [ErrNoUpstream].
+func (c *Config) Upstream(branch string) (Upstream, error) {
+	remote, _ := c.Get("branch." + branch + ".remote")
+	merge, _ := c.Get("branch." + branch + ".merge")
+	if remote == "" || merge == "" {
+		return Upstream{}, fmt.Errorf("%w for %s", ErrNoUpstream, branch)
+	}
+	if !strings.HasPrefix(merge, "refs/") {
+		merge = "refs/heads/" + merge
+	}
+
+	up := Upstream{Remote: remote, Merge: merge}
+	if remote == "." {
+		up.Ref = merge
+		return up, nil
+	}
+
+	var specs []Refspec
+	for _, value := range c.GetAll("remote." + remote + ".fetch") {
+		spec, err := ParseRefspec(value)
+		if err != nil {
+			return Upstream{}, err
+		}
+		specs = append(specs, spec)
+	}
+	for _, spec := range specs {
+		if _, ok := spec.Map(merge); spec.Negative && ok {
+			return up, nil
+		}
+	}
+	for _, spec := range specs {
+		if dst, ok := spec.Map(merge); !spec.Negative && ok && dst != "" {
+			up.Ref = dst
+			break
+		}
+	}
+	return up, nil
+}
+
+// Upstream resolves the upstream of branch in this repository's config, as [Config.Upstream] does.
+func (repo *Repository) Upstream(branch string) (Upstream, error) {
+	c, err := repo.Config()
+	if err != nil {
+		return Upstream{}, err
+	}
+	return c.Upstream(branch)
+}
+
+// setUpstream makes merge, a branch on remote like refs/heads/main, the upstream of branch, as
+// git push --set-upstream does, unless it already is. The settings go in a section of their own at the
+// end of the repository's config, which takes precedence over any earlier one since the last value of
+// a variable is the one that counts.
+func (repo *Repository) setUpstream(branch, remote, merge string) error {
+	if up, err := repo.Upstream(branch); err == nil && up.Remote == remote && up.Merge == merge {
+		return nil
+	} else if err != nil && !errors.Is(err, ErrNoUpstream) {
+		return err
+	}
+
+	name := filepath.Join(repo.CommonDir, "config")
+	data, err := os.ReadFile(name)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	section := fmt.Sprintf("[branch \"%s\"]\n\tremote = %s\n\tmerge = %s\n", strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(branch), remote, merge)
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		section = "\n" + section
+	}
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = f.WriteString(section)
+	return errors.Join(err, f.Close())
+}
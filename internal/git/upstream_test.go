@@ -0,0 +1,65 @@
+package git
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestUpstream(t *testing.T) {
+	entries, err := ParseConfig([]byte(`[remote "origin"]
+	url = git@example.com:team/app.git
+	fetch = +refs/heads/*:refs/remotes/origin/*
+[remote "upstream"]
+	url = git@example.com:upstream/app.git
+	fetch = +refs/heads/main:refs/remotes/upstream/trunk
+	fetch = +refs/heads/release/*:refs/remotes/upstream/releases/*
+[remote "mirror"]
+	fetch = +refs/heads/*:refs/remotes/mirror/*
+	fetch = ^refs/heads/secret
+[branch "feature"]
+	remote = origin
+	merge = refs/heads/feature/login
+[branch "main"]
+	remote = upstream
+	merge = refs/heads/main
+[branch "hotfix"]
+	remote = upstream
+	merge = release/1.2
+[branch "local"]
+	remote = .
+	merge = refs/heads/main
+[branch "hidden"]
+	remote = mirror
+	merge = refs/heads/secret
+[branch "orphan"]
+	merge = refs/heads/main
+`), "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Config{entries: entries}
+
+	cases := map[string]Upstream{
+		"feature": {Remote: "origin", Merge: "refs/heads/feature/login", Ref: "refs/remotes/origin/feature/login"},
+		"main":    {Remote: "upstream", Merge: "refs/heads/main", Ref: "refs/remotes/upstream/trunk"},
+		"hotfix":  {Remote: "upstream", Merge: "refs/heads/release/1.2", Ref: "refs/remotes/upstream/releases/1.2"},
+		"local":   {Remote: ".", Merge: "refs/heads/main", Ref: "refs/heads/main"},
+		"hidden":  {Remote: "mirror", Merge: "refs/heads/secret"},
+	}
+	for branch, want := range cases {
+		if got, err := c.Upstream(branch); err != nil || got != want {
+			t.Errorf("Upstream(%q) = %+v, %v, want %+v", branch, got, err, want)
+		}
+	}
+	for _, branch := range []string{"orphan", "missing"} {
+		if _, err := c.Upstream(branch); !errors.Is(err, ErrNoUpstream) {
+			t.Errorf("Upstream(%q) = %v, want ErrNoUpstream", branch, err)
+		}
+	}
+
+	for _, bad := range []string{"", "refs/heads/*:refs/remotes/*/*", "^refs/heads/a:refs/remotes/a"} {
+		if _, err := ParseRefspec(bad); !errors.Is(err, ErrBadRefspec) {
+			t.Errorf("ParseRefspec(%q) = %v, want ErrBadRefspec", bad, err)
+		}
+	}
+}
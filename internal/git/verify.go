@@ -0,0 +1,412 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ProblemKind says what [Repository.Verify] found wrong.
+type ProblemKind string
+
+const (
+	CorruptObject   ProblemKind = "corrupt"       // The object can't be decompressed, or its header or size is wrong
+	HashMismatch    ProblemKind = "hash-mismatch" // The object's content doesn't hash to the name it's stored under
+	MalformedObject ProblemKind = "malformed"     // A commit, tree, or tag isn't laid out the way git writes them
+	MissingObject   ProblemKind = "missing"       // An object points to one that isn't in the store
+	BrokenRef       ProblemKind = "broken-ref"    // A ref doesn't hold a hash, or points to a missing object
+)
+
+// VerifyProblem is one thing wrong with the repository.
+type VerifyProblem struct {
+	Kind   ProblemKind
+	Object string // The hash of the object at fault, or the name of the ref for a broken ref
+	Detail string
+}
+
+// VerifyReport is what [Repository.Verify] checked, and what it found wrong.
+type VerifyReport struct {
+	Objects  int // How many loose objects were read
+	Refs     int // How many refs were checked, counting HEAD
+	Packs    int // How many packs there are, whose objects aren't checked
+	Problems []VerifyProblem
+}
+
+// OK reports whether no problems were found.
+func (r VerifyReport) OK() bool {
+	return len(r.Problems) == 0
+}
+
+// objectLink is a reference from one object to another, such as a commit's tree.
+type objectLink struct {
+	from, to string
+	kind     GitObjectKind // The kind of object to must be
+	what     string        // What the reference is, e.g. "parent" or "entry src", for messages
+}
+
+// Verify checks the repository's loose objects and refs the way git fsck does. Every object is
+// decompressed and hashed to make sure it is stored under its own name and has the size its header
+// claims. Commits, trees, and tags are checked for the layout git writes, and for references to objects
+// that are missing or of the wrong kind. Every ref, and HEAD, must point to an object in the store.
+//
+// Objects in pack files are not read, only looked up in the packs' indexes when something points to them.
+// The parents of shallow commits and submodule commits are never expected to be present. Failing to read the store is returned as an error; anything wrong with
+// what it holds is in the report.
+func (repo *Repository) Verify() (VerifyReport, error) {
+	defer phase("verify")()
+	var report VerifyReport
+	problem := func(kind ProblemKind, object, format string, args ...any) {
+		report.Problems = append(report.Problems, VerifyProblem{Kind: kind, Object: object, Detail: fmt.Sprintf(format, args...)})
+	}
+
+	shallow, err := readShallow(repo.CommonDir)
+	if err != nil {
+		return VerifyReport{}, err
+	}
+
+	objectsPath := filepath.Join(repo.CommonDir, "objects")
+	dirs, err := os.ReadDir(objectsPath)
+	if err != nil {
+		return VerifyReport{}, err
+	}
+	packs, err := openPacks(objectsPath, repo.Format)
+	if err != nil {
+		return VerifyReport{}, err
+	}
+	defer packs.Close()
+	report.Packs = len(packs.packs)
+
+	kinds := map[string]GitObjectKind{}
+	unreadable := map[string]bool{}
+	var links []objectLink
+	var d *Decoder
+	defer func() {
+		if d != nil {
+			d.Close()
+		}
+	}()
+
+	for _, dir := range dirs {
+		if !dir.IsDir() || len(dir.Name()) != 2 {
+			continue
+		}
+		entries, err := os.ReadDir(filepath.Join(objectsPath, dir.Name()))
+		if err != nil {
+			return VerifyReport{}, err
+		}
+		for _, entry := range entries {
+			hash := dir.Name() + entry.Name()
+			// Anything else, like the temporary files git writes objects to, isn't an object.
+			if len(hash) != repo.Format.HexSize() || !isFullHash(hash) {
+				continue
+			}
+			report.Objects++
+
+			header, data, bad, err := repo.readVerified(&d, hash, false)
+			if err != nil {
+				return VerifyReport{}, err
+			}
+			if bad != nil {
+				report.Problems = append(report.Problems, *bad)
+				unreadable[hash] = true
+				continue
+			}
+			kinds[hash] = header.Kind
+
+			var found []objectLink
+			switch header.Kind {
+			case CommitObject:
+				found, err = checkCommit(repo.Format, data)
+			case TreeObject:
+				found, err = checkTree(repo.Format, data)
+			case TagObject:
+				found, err = checkTag(repo.Format, data)
+			}
+			if err != nil {
+				problem(MalformedObject, hash, "%s %s", header.Kind, err)
+				continue
+			}
+			for _, link := range found {
+				if header.Kind == CommitObject && link.kind == CommitObject && shallow[hash] {
+					continue
+				}
+				link.from = hash
+				links = append(links, link)
+			}
+		}
+	}
+
+	exists := func(hash string) bool {
+		_, ok := kinds[hash]
+		return ok || unreadable[hash] || packs.has(hash)
+	}
+	for _, link := range links {
+		kind, ok := kinds[link.to]
+		switch {
+		case !exists(link.to):
+			problem(MissingObject, link.from, "%s %s is missing", link.what, link.to)
+		case ok && kind != link.kind:
+			problem(MalformedObject, link.from, "%s %s is a %s, not a %s", link.what, link.to, kind, link.kind)
+		}
+	}
+
+	refs, err := repo.Refs()
+	if err != nil {
+		return VerifyReport{}, err
+	}
+	if head, err := repo.resolveRef("HEAD"); err == nil {
+		refs["HEAD"] = head
+	} else if !errors.Is(err, ErrRefNotFound) {
+		return VerifyReport{}, err
+	}
+	names := make([]string, 0, len(refs))
+	for name := range refs {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		report.Refs++
+		target := refs[name]
+		if symbolic, ok := strings.CutPrefix(target, "ref: "); ok {
+			// A HEAD on a branch without commits yet is fine, but it has to name a ref.
+			if !strings.HasPrefix(symbolic, "refs/") {
+				problem(BrokenRef, name, "points to %s, which is not a ref", symbolic)
+			}
+			continue
+		}
+		switch {
+		case !isFullHash(target):
+			problem(BrokenRef, name, "holds %q, which is not a hash", target)
+		case !exists(target):
+			problem(BrokenRef, name, "points to %s, which is missing", target)
+		}
+	}
+	return report, nil
+}
+
+// readVerified reads the loose object named hash with *d, creating the decoder on first use, and checks
+// that its content matches its header and hashes to its name. The content is returned for commits, trees,
+// and tags, and for blobs too if blobs is set; otherwise they're hashed as they're read, so large files
+// aren't held in memory. An object that fails the checks is returned as a problem rather than an error.
+func (repo *Repository) readVerified(d **Decoder, hash string, blobs bool) (ObjectHeader, []byte, *VerifyProblem, error) {
+	corrupt := func(format string, args ...any) (ObjectHeader, []byte, *VerifyProblem, error) {
+		return ObjectHeader{}, nil, &VerifyProblem{Kind: CorruptObject, Object: hash, Detail: fmt.Sprintf(format, args...)}, nil
+	}
+
+	f, err := os.Open(repo.objectPath(hash))
+	if err != nil {
+		return ObjectHeader{}, nil, nil, err
+	}
+	defer f.Close()
+	if logging(slog.LevelDebug) {
+		logAt(slog.LevelDebug, "read object", "path", f.Name())
+	}
+
+	if *d == nil {
+		*d, err = NewDecoder(f)
+		if err != nil {
+			*d = nil
+		}
+	} else {
+		err = (*d).Reset(f)
+	}
+	if err != nil {
+		return corrupt("can't be decompressed: %v", err)
+	}
+	header, err := (*d).Header()
+	if err != nil {
+		return corrupt("has a bad header: %v", err)
+	}
+
+	var data []byte
+	content := io.Reader((*d).br)
+	if header.Kind != BlobObject || blobs {
+		// Read one byte past the size the header claims, so content that's too long is noticed.
+		data, err = io.ReadAll(io.LimitReader(content, header.Size+1))
+		if err != nil {
+			return corrupt("is truncated: %v", err)
+		}
+		content = bytes.NewReader(data)
+	}
+	actual, err := repo.Format.HashObjectStream(header.Kind, header.Size, content)
+	if errors.Is(err, ErrSizeMismatch) {
+		return corrupt("has a size that doesn't match its header: %v", err)
+	}
+	if err != nil {
+		return corrupt("is truncated: %v", err)
+	}
+	observe(func(o Observer) {
+		o.ObjectRead(header.Kind, header.Size, false)
+		o.Inflated(header.Size)
+	})
+	if actual != hash {
+		return ObjectHeader{}, nil, &VerifyProblem{Kind: HashMismatch, Object: hash, Detail: "content hashes to " + actual}, nil
+	}
+	return header, data, nil, nil
+}
+
+// checkCommit checks that data is laid out as git writes commits: a tree, any parents, an author and a
+// committer in that order, then any other headers and a blank line before the message. It returns the
+// objects the commit points to.
+func checkCommit(format HashFormat, data []byte) ([]objectLink, error) {
+	headers, err := splitHeaders(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var links []objectLink
+	next := func(name string) (string, bool) {
+		if len(headers) == 0 || headers[0][0] != name {
+			return "", false
+		}
+		value := headers[0][1]
+		headers = headers[1:]
+		return value, true
+	}
+
+	tree, ok := next("tree")
+	if !ok {
+		return nil, errors.New("has no tree")
+	}
+	if !isFormatHash(format, tree) {
+		return nil, fmt.Errorf("has a bad tree hash %q", tree)
+	}
+	links = append(links, objectLink{to: tree, kind: TreeObject, what: "tree"})
+	for parent, ok := next("parent"); ok; parent, ok = next("parent") {
+		if !isFormatHash(format, parent) {
+			return nil, fmt.Errorf("has a bad parent hash %q", parent)
+		}
+		links = append(links, objectLink{to: parent, kind: CommitObject, what: "parent"})
+	}
+	for _, name := range []string{"author", "committer"} {
+		value, ok := next(name)
+		if !ok {
+			return nil, fmt.Errorf("has no %s", name)
+		}
+		if _, err := parseSignature([]byte(value)); err != nil {
+			return nil, fmt.Errorf("has a bad %s %q", name, value)
+		}
+	}
+	return links, nil
+}
+
+// checkTag checks that data is laid out as git writes annotated tags: the tagged object, its type, and
+// the tag's name, then optionally who tagged it. It returns the tagged object.
+func checkTag(format HashFormat, data []byte) ([]objectLink, error) {
+	headers, err := splitHeaders(data)
+	if err != nil {
+		return nil, err
+	}
+	for i, name := range []string{"object", "type", "tag"} {
+		if len(headers) <= i || headers[i][0] != name {
+			return nil, fmt.Errorf("has no %s", name)
+		}
+	}
+	object, typeName, name := headers[0][1], headers[1][1], headers[2][1]
+	if !isFormatHash(format, object) {
+		return nil, fmt.Errorf("has a bad object hash %q", object)
+	}
+	kind, ok := parseObjectKind([]byte(typeName))
+	if !ok {
+		return nil, fmt.Errorf("has an unknown type %q", typeName)
+	}
+	if name == "" {
+		return nil, errors.New("has an empty name")
+	}
+	if len(headers) > 3 && headers[3][0] == "tagger" {
+		if _, err := parseSignature([]byte(headers[3][1])); err != nil {
+			return nil, fmt.Errorf("has a bad tagger %q", headers[3][1])
+		}
+	}
+	return []objectLink{{to: object, kind: kind, what: "tagged " + typeName}}, nil
+}
+
+// splitHeaders returns the name and value of each header line of a commit or tag, leaving out the lines
+// continuing a multi-line value. The headers must end with a blank line, and can't contain NUL bytes.
+func splitHeaders(data []byte) ([][2]string, error) {
+	end := bytes.Index(data, []byte("\n\n"))
+	if end == -1 {
+		return nil, errors.New("has no blank line after its headers")
+	}
+	if bytes.IndexByte(data[:end], 0) != -1 {
+		return nil, errors.New("has a NUL byte in its headers")
+	}
+
+	var headers [][2]string
+	for _, line := range strings.Split(string(data[:end]), "\n") {
+		if strings.HasPrefix(line, " ") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, " ")
+		if !ok {
+			return nil, fmt.Errorf("has a malformed header line %q", line)
+		}
+		headers = append(headers, [2]string{name, value})
+	}
+	return headers, nil
+}
+
+// checkTree checks that data is a tree git would write: entries with known modes and names that are
+// valid path components, sorted in git's order without duplicates. It returns the objects the entries
+// point to, except for submodules, whose commits live in another repository.
+func checkTree(format HashFormat, data []byte) ([]objectLink, error) {
+	var links []objectLink
+	var previous TreeEntry
+	for len(data) > 0 {
+		modeBytes, rest, ok := bytes.Cut(data, []byte{' '})
+		if !ok {
+			return nil, errors.New("has a truncated entry")
+		}
+		mode, err := strconv.ParseUint(string(modeBytes), 8, 32)
+		if err != nil || modeBytes[0] == '0' {
+			return nil, fmt.Errorf("has a bad mode %q", modeBytes)
+		}
+		name, rest, ok := bytes.Cut(rest, []byte{0})
+		if !ok || len(rest) < format.HexSize()/2 {
+			return nil, errors.New("has a truncated entry")
+		}
+		entry := TreeEntry{Mode: FileMode(mode), Name: string(name), Hash: fmt.Sprintf("%x", rest[:format.HexSize()/2])}
+		data = rest[format.HexSize()/2:]
+
+		switch entry.Mode {
+		case ModeTree, ModeFile, ModeExecutable, ModeSymlink, ModeSubmodule:
+		default:
+			return nil, fmt.Errorf("has an unknown mode %s for %q", entry.Mode, entry.Name)
+		}
+		switch {
+		case entry.Name == "", strings.Contains(entry.Name, "/"):
+			return nil, fmt.Errorf("has a bad entry name %q", entry.Name)
+		case entry.Name == ".", entry.Name == "..", strings.EqualFold(entry.Name, ".git"):
+			return nil, fmt.Errorf("has an entry named %q", entry.Name)
+		}
+		if previous.Name != "" {
+			if previous.Name == entry.Name {
+				return nil, fmt.Errorf("has a duplicate entry %q", entry.Name)
+			}
+			if compareTreeEntries(previous, entry) > 0 {
+				return nil, fmt.Errorf("is not sorted: %q comes before %q", previous.Name, entry.Name)
+			}
+		}
+		previous = entry
+
+		switch entry.Mode {
+		case ModeSubmodule:
+		case ModeTree:
+			links = append(links, objectLink{to: entry.Hash, kind: TreeObject, what: "entry " + entry.Name})
+		default:
+			links = append(links, objectLink{to: entry.Hash, kind: BlobObject, what: "entry " + entry.Name})
+		}
+	}
+	return links, nil
+}
+
+func isFormatHash(format HashFormat, s string) bool {
+	return len(s) == format.HexSize() && isFullHash(s)
+}
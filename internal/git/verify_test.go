@@ -0,0 +1,166 @@
+package git
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeRawObject stores raw, header included, as the loose object named hash, whether or not that's
+// its real name.
+func writeRawObject(t *testing.T, gitDir, hash string, raw []byte) {
+	t.Helper()
+	var b bytes.Buffer
+	zw := zlib.NewWriter(&b)
+	zw.Write(raw)
+	zw.Close()
+	path := filepath.Join(gitDir, "objects", hash[:2], hash[2:])
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, b.Bytes(), 0o444); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func treeEntry(mode, name, hash string) string {
+	raw, _ := hex.DecodeString(hash)
+	return mode + " " + name + "\x00" + string(raw)
+}
+
+func TestVerify(t *testing.T) {
+	gitDir := newTestRepo(t)
+	blob := writeTestObject(t, gitDir, BlobObject, "hello\n")
+	writeTestObject(t, gitDir, TreeObject, "")
+	tree := writeTestObject(t, gitDir, TreeObject, treeEntry("100644", "a.txt", blob)+treeEntry("160000", "lib", blob[1:]+"0")+treeEntry("100755", "run", blob))
+	first := writeTestCommit(t, gitDir, "first")
+	second := writeTestObject(t, gitDir, CommitObject, "tree "+tree+"\nparent "+first+"\nauthor A <a@example.com> 1703123456 +0000\ncommitter A <a@example.com> 1703123456 +0000\n\nsecond\n")
+	tag := writeTestObject(t, gitDir, TagObject, "object "+second+"\ntype commit\ntag v1\ntagger A <a@example.com> 1703123456 +0000\n\nv1\n")
+	writeTestRef(t, gitDir, "refs/heads/main", second)
+	writeTestRef(t, gitDir, "refs/tags/v1", tag)
+	writeTestRef(t, gitDir, "HEAD", "ref: refs/heads/main")
+
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := repo.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.OK() || report.Objects != 6 || report.Refs != 3 {
+		t.Errorf("Verify() = %+v, want 6 objects and 3 refs without problems", report)
+	}
+}
+
+func TestVerifyProblems(t *testing.T) {
+	const missing = "0123456789abcdef0123456789abcdef01234567"
+	commit := func(headers string) string {
+		return headers + "author A <a@example.com> 1703123456 +0000\ncommitter A <a@example.com> 1703123456 +0000\n\nmessage\n"
+	}
+
+	tests := []struct {
+		name  string
+		setup func(t *testing.T, gitDir string) string // Returns the object or ref at fault
+		kind  ProblemKind
+	}{
+		{"not zlib", func(t *testing.T, gitDir string) string {
+			path := filepath.Join(gitDir, "objects", missing[:2], missing[2:])
+			os.MkdirAll(filepath.Dir(path), 0o755)
+			os.WriteFile(path, []byte("garbage"), 0o444)
+			return missing
+		}, CorruptObject},
+		{"bad header", func(t *testing.T, gitDir string) string {
+			writeRawObject(t, gitDir, missing, []byte("blobby 3\x00abc"))
+			return missing
+		}, CorruptObject},
+		{"wrong size", func(t *testing.T, gitDir string) string {
+			writeRawObject(t, gitDir, missing, []byte("blob 5\x00abc"))
+			return missing
+		}, CorruptObject},
+		{"hash mismatch", func(t *testing.T, gitDir string) string {
+			writeRawObject(t, gitDir, missing, []byte("blob 3\x00abc"))
+			return missing
+		}, HashMismatch},
+		{"commit without tree", func(t *testing.T, gitDir string) string {
+			return writeTestObject(t, gitDir, CommitObject, commit(""))
+		}, MalformedObject},
+		{"bad signature", func(t *testing.T, gitDir string) string {
+			return writeTestObject(t, gitDir, CommitObject, "tree 4b825dc642cb6eb9a060e54bf8d69288fbee4904\nauthor A <a@example.com>\ncommitter A\n\n")
+		}, MalformedObject},
+		{"unsorted tree", func(t *testing.T, gitDir string) string {
+			blob := writeTestObject(t, gitDir, BlobObject, "x")
+			return writeTestObject(t, gitDir, TreeObject, treeEntry("100644", "b", blob)+treeEntry("100644", "a", blob))
+		}, MalformedObject},
+		{"dot git in tree", func(t *testing.T, gitDir string) string {
+			blob := writeTestObject(t, gitDir, BlobObject, "x")
+			return writeTestObject(t, gitDir, TreeObject, treeEntry("100644", ".GIT", blob))
+		}, MalformedObject},
+		{"bad mode", func(t *testing.T, gitDir string) string {
+			blob := writeTestObject(t, gitDir, BlobObject, "x")
+			return writeTestObject(t, gitDir, TreeObject, treeEntry("100600", "a", blob))
+		}, MalformedObject},
+		{"tree entry of the wrong kind", func(t *testing.T, gitDir string) string {
+			blob := writeTestObject(t, gitDir, BlobObject, "x")
+			return writeTestObject(t, gitDir, TreeObject, treeEntry("40000", "a", blob))
+		}, MalformedObject},
+		{"missing parent", func(t *testing.T, gitDir string) string {
+			writeTestObject(t, gitDir, TreeObject, "")
+			return writeTestCommit(t, gitDir, "orphaned", missing)
+		}, MissingObject},
+		{"ref to missing object", func(t *testing.T, gitDir string) string {
+			writeTestRef(t, gitDir, "refs/heads/gone", missing)
+			return "refs/heads/gone"
+		}, BrokenRef},
+		{"ref without a hash", func(t *testing.T, gitDir string) string {
+			writeTestRef(t, gitDir, "refs/heads/junk", "not a hash")
+			return "refs/heads/junk"
+		}, BrokenRef},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gitDir := newTestRepo(t)
+			object := test.setup(t, gitDir)
+			repo, err := OpenRepository()
+			if err != nil {
+				t.Fatal(err)
+			}
+			report, err := repo.Verify()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(report.Problems) != 1 || report.Problems[0].Kind != test.kind || report.Problems[0].Object != object {
+				t.Errorf("Verify() problems = %+v, want one %s problem with %s", report.Problems, test.kind, object)
+			}
+		})
+	}
+}
+
+func TestVerifySkipsShallowParentsAndPackedObjects(t *testing.T) {
+	gitDir := newTestRepo(t)
+	writeTestObject(t, gitDir, TreeObject, "")
+	base := writeTestCommit(t, gitDir, "base")
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Repack(); err != nil {
+		t.Fatal(err)
+	}
+	writeTestRef(t, gitDir, "refs/heads/main", writeTestCommit(t, gitDir, "tip", base))
+	if report, err := repo.Verify(); err != nil || !report.OK() || report.Objects != 1 || report.Packs != 1 {
+		t.Errorf("Verify() with packed objects = %+v, %v, want one loose object and one pack without problems", report, err)
+	}
+
+	const missing = "0123456789abcdef0123456789abcdef01234567"
+	shallow := writeTestCommit(t, gitDir, "grafted", missing)
+	if err := os.WriteFile(filepath.Join(gitDir, "shallow"), []byte(shallow+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if report, err := repo.Verify(); err != nil || !report.OK() {
+		t.Errorf("Verify() of a shallow clone = %+v, %v, want no problems", report, err)
+	}
+}
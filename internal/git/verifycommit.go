@@ -0,0 +1,251 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+var ErrNoAllowedSigners = errors.New("git: gpg.ssh.allowedSignersFile must be set to verify ssh signatures")
+
+// SignatureStatus is what checking a commit's signature found, with the letters git log's %G? uses.
+type SignatureStatus byte
+
+const (
+	Unsigned           SignatureStatus = 'N'
+	GoodSignature      SignatureStatus = 'G' // Good, by a key trusted at least as gpg.minTrustLevel asks
+	UntrustedSignature SignatureStatus = 'U' // Good, by a key trusted less, or not an allowed ssh signer
+	BadSignature       SignatureStatus = 'B'
+	ExpiredSignature   SignatureStatus = 'X'
+	ExpiredKey         SignatureStatus = 'Y'
+	RevokedKey         SignatureStatus = 'R'
+	UncheckedSignature SignatureStatus = 'E' // Couldn't be checked, usually for want of the key
+)
+
+func (s SignatureStatus) String() string {
+	switch s {
+	case Unsigned:
+		return "unsigned"
+	case GoodSignature:
+		return "good"
+	case UntrustedSignature:
+		return "good, from an untrusted key"
+	case BadSignature:
+		return "bad"
+	case ExpiredSignature:
+		return "good, but expired"
+	case ExpiredKey:
+		return "good, from an expired key"
+	case RevokedKey:
+		return "good, from a revoked key"
+	case UncheckedSignature:
+		return "can't be checked"
+	}
+	return fmt.Sprintf("SignatureStatus(%q)", byte(s))
+}
+
+// trustLevels are the levels of trust gpg reports in its TRUST_ status lines, in the order git ranks them.
+var trustLevels = []string{"undefined", "never", "marginal", "fully", "ultimate"}
+
+// Verification is the result of checking a commit's signature.
+type Verification struct {
+	Status SignatureStatus
+	Format string // How the commit was signed: openpgp, x509, or ssh
+	Signer string // The key's user ID, or the principal the allowed signers file names for an ssh key
+	Key    string // The fingerprint of the key that signed, or its ID when gpg doesn't have it
+	Trust  string // One of trustLevels; ssh keys in the allowed signers file are trusted fully
+}
+
+// Verified reports whether the commit carries a good signature by a trusted key.
+func (v Verification) Verified() bool {
+	return v.Status == GoodSignature
+}
+
+// VerifyCommit checks the signature of the commit hash names, as git verify-commit does: gpg and gpgsm
+// signatures against the user's keyring, run with the program gpg.program or its gpg.format variants
+// set, and ssh signatures against gpg.ssh.allowedSignersFile and gpg.ssh.revocationFile with ssh-keygen.
+// A signature that doesn't check out is no error, but described by the Verification's Status.
+func (repo *Repository) VerifyCommit(hash string) (Verification, error) {
+	c, err := repo.ReadCommit(hash)
+	if err != nil {
+		return Verification{}, err
+	}
+	name := repo.signatureHeader()
+	i := slices.IndexFunc(c.Headers, func(h CommitHeader) bool { return h.Name == name })
+	if i < 0 {
+		return Verification{Status: Unsigned}, nil
+	}
+	signature := c.Headers[i].Value + "\n"
+	c.Headers = slices.Delete(slices.Clone(c.Headers), i, i+1)
+	payload, err := EncodeCommit(c)
+	if err != nil {
+		return Verification{}, err
+	}
+	config, err := repo.Config()
+	if err != nil {
+		return Verification{}, err
+	}
+
+	dir, err := os.MkdirTemp("", "plain-verify-")
+	if err != nil {
+		return Verification{}, err
+	}
+	defer os.RemoveAll(dir)
+	sigFile := filepath.Join(dir, "signature")
+	if err := os.WriteFile(sigFile, []byte(signature), 0o600); err != nil {
+		return Verification{}, err
+	}
+
+	var v Verification
+	switch {
+	case strings.HasPrefix(signature, "-----BEGIN PGP SIGNATURE-----"), strings.HasPrefix(signature, "-----BEGIN PGP MESSAGE-----"):
+		v, err = verifyGPG(signingProgram(config, "openpgp"), []string{"--keyid-format=long"}, sigFile, payload)
+		v.Format = "openpgp"
+	case strings.HasPrefix(signature, "-----BEGIN SIGNED MESSAGE-----"):
+		v, err = verifyGPG(signingProgram(config, "x509"), nil, sigFile, payload)
+		v.Format = "x509"
+	case strings.HasPrefix(signature, "-----BEGIN SSH SIGNATURE-----"):
+		v, err = verifySSH(config, sigFile, payload, c.Committer)
+		v.Format = "ssh"
+	default:
+		return Verification{Status: UncheckedSignature}, nil
+	}
+	if err != nil {
+		return Verification{}, err
+	}
+
+	if v.Status == GoodSignature {
+		min, _ := config.Get("gpg.minTrustLevel")
+		if slices.Index(trustLevels, v.Trust) < slices.Index(trustLevels, strings.ToLower(min)) {
+			v.Status = UntrustedSignature
+		}
+	}
+	return v, nil
+}
+
+// verifyGPG checks a detached signature with gpg or gpgsm, reading the result from its status lines
+// rather than its exit status, which is also nonzero for a signature that checked out as bad.
+func verifyGPG(program string, args []string, sigFile string, payload []byte) (Verification, error) {
+	cmd := exec.Command(program, append(args, "--status-fd=1", "--verify", sigFile, "-")...)
+	cmd.Stdin = bytes.NewReader(payload)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return Verification{}, fmt.Errorf("git: failed to run %s: %w", program, err)
+	}
+	v := parseGPGStatus(stdout.String())
+	if v.Status == 0 {
+		return Verification{}, fmt.Errorf("git: %s reported nothing about the signature%s", program, signerOutput(stderr.String()))
+	}
+	return v, nil
+}
+
+// parseGPGStatus reads what gpg's status lines say about a signature.
+func parseGPGStatus(status string) Verification {
+	results := map[string]SignatureStatus{
+		"GOODSIG":   GoodSignature,
+		"BADSIG":    BadSignature,
+		"EXPSIG":    ExpiredSignature,
+		"EXPKEYSIG": ExpiredKey,
+		"REVKEYSIG": RevokedKey,
+		"ERRSIG":    UncheckedSignature,
+	}
+	v := Verification{Trust: trustLevels[0]}
+	for _, line := range strings.Split(status, "\n") {
+		rest, ok := strings.CutPrefix(line, "[GNUPG:] ")
+		if !ok {
+			continue
+		}
+		keyword, args, _ := strings.Cut(rest, " ")
+		if result, ok := results[keyword]; ok {
+			v.Status = result
+			keyID, uid, _ := strings.Cut(args, " ")
+			if v.Key == "" {
+				v.Key = keyID
+			}
+			if result != UncheckedSignature {
+				v.Signer = uid
+			}
+			continue
+		}
+		if level, ok := strings.CutPrefix(keyword, "TRUST_"); ok {
+			v.Trust = strings.ToLower(level)
+			continue
+		}
+		if keyword == "VALIDSIG" {
+			v.Key, _, _ = strings.Cut(args, " ")
+		}
+	}
+	return v
+}
+
+// verifySSH checks an ssh signature as git does: it looks up who the allowed signers file says the key
+// belongs to, and checks the signature for each of them as of when it was committed. A good signature by
+// a key that isn't in the file is untrusted.
+func verifySSH(config *Config, sigFile string, payload []byte, committer Signature) (Verification, error) {
+	allowed, err := config.Path("gpg.ssh.allowedSignersFile")
+	if err != nil {
+		return Verification{}, err
+	}
+	if allowed == "" {
+		return Verification{}, ErrNoAllowedSigners
+	}
+	program := signingProgram(config, "ssh")
+	verifyTime := "-Overify-time=" + committer.Time.Local().Format("20060102150405")
+
+	out, _ := exec.Command(program, "-Y", "find-principals", "-f", allowed, "-s", sigFile, verifyTime).Output()
+	principals := strings.Fields(string(out))
+	if len(principals) == 0 {
+		cmd := exec.Command(program, "-Y", "check-novalidate", "-n", "git", "-s", sigFile, verifyTime)
+		cmd.Stdin = bytes.NewReader(payload)
+		out, err := cmd.Output()
+		if err != nil {
+			return sshRunResult(program, err)
+		}
+		return Verification{Status: UntrustedSignature, Key: sshKeyFingerprint(string(out)), Trust: trustLevels[0]}, nil
+	}
+
+	revoked, err := config.Path("gpg.ssh.revocationFile")
+	if err != nil {
+		return Verification{}, err
+	}
+	var lastErr error
+	for _, principal := range principals {
+		args := []string{"-Y", "verify", "-n", "git", "-f", allowed, "-I", principal, "-s", sigFile, verifyTime}
+		if revoked != "" {
+			args = append(args, "-r", revoked)
+		}
+		cmd := exec.Command(program, args...)
+		cmd.Stdin = bytes.NewReader(payload)
+		out, err := cmd.Output()
+		if err == nil {
+			return Verification{Status: GoodSignature, Signer: principal, Key: sshKeyFingerprint(string(out)), Trust: "fully"}, nil
+		}
+		lastErr = err
+	}
+	return sshRunResult(program, lastErr)
+}
+
+// sshRunResult turns ssh-keygen failing to check a signature into a bad signature, unless it couldn't
+// be run at all.
+func sshRunResult(program string, err error) (Verification, error) {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return Verification{}, fmt.Errorf("git: failed to run %s: %w", program, err)
+	}
+	return Verification{Status: BadSignature, Trust: trustLevels[0]}, nil
+}
+
+// sshKeyFingerprint returns the fingerprint at the end of ssh-keygen's report of a good signature, as in
+// Good "git" signature for ann with ED25519 key SHA256:....
+func sshKeyFingerprint(report string) string {
+	_, fingerprint, _ := strings.Cut(strings.TrimSpace(report), " key ")
+	return fingerprint
+}
@@ -0,0 +1,166 @@
+package git
+
+import (
+	"crypto/ed25519"
+	"encoding/pem"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// fixedSigner signs anything with the same signature, to make commits whose signature doesn't match.
+type fixedSigner string
+
+func (s fixedSigner) Sign([]byte) (string, error) { return string(s), nil }
+
+func TestParseGPGStatus(t *testing.T) {
+	tests := []struct {
+		status string
+		want   Verification
+	}{
+		{
+			"[GNUPG:] NEWSIG\n[GNUPG:] KEY_CONSIDERED 0123456789ABCDEF0123456789ABCDEF01234567 0\n" +
+				"[GNUPG:] GOODSIG 89ABCDEF01234567 Ann Lee <ann@example.com>\n" +
+				"[GNUPG:] VALIDSIG 0123456789ABCDEF0123456789ABCDEF01234567 2025-01-01 1735689600 0 4 0 22 10 00 0123456789ABCDEF0123456789ABCDEF01234567\n" +
+				"[GNUPG:] TRUST_ULTIMATE 0 pgp\n",
+			Verification{Status: GoodSignature, Signer: "Ann Lee <ann@example.com>", Key: "0123456789ABCDEF0123456789ABCDEF01234567", Trust: "ultimate"},
+		},
+		{
+			"[GNUPG:] ERRSIG 89ABCDEF01234567 22 10 00 1735689600 9 -\n[GNUPG:] NO_PUBKEY 89ABCDEF01234567\n",
+			Verification{Status: UncheckedSignature, Key: "89ABCDEF01234567", Trust: "undefined"},
+		},
+		{
+			"[GNUPG:] BADSIG 89ABCDEF01234567 Ann Lee <ann@example.com>\n",
+			Verification{Status: BadSignature, Signer: "Ann Lee <ann@example.com>", Key: "89ABCDEF01234567", Trust: "undefined"},
+		},
+		{
+			"[GNUPG:] EXPKEYSIG 89ABCDEF01234567 Ann Lee <ann@example.com>\n[GNUPG:] TRUST_NEVER 0 pgp\n",
+			Verification{Status: ExpiredKey, Signer: "Ann Lee <ann@example.com>", Key: "89ABCDEF01234567", Trust: "never"},
+		},
+	}
+	for _, tt := range tests {
+		if got := parseGPGStatus(tt.status); got != tt.want {
+			t.Errorf("parseGPGStatus(%q) = %+v, want %+v", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestVerifyGPGSignedCommit(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	gitDir := newTestRepo(t)
+	dir := t.TempDir()
+	signer := writeTestSigner(t, dir, "[GNUPG:] SIG_CREATED D 22 8 00 1700000000 ABCDEF")
+	verifier := filepath.Join(dir, "fake-gpg-verify")
+	script := "#!/bin/sh\ncat > /dev/null\necho '[GNUPG:] GOODSIG 89ABCDEF01234567 Ann Lee <ann@example.com>'\necho '[GNUPG:] TRUST_MARGINAL 0 pgp'\nexit 0\n"
+	if err := os.WriteFile(verifier, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFiles(t, map[string]string{".git/config": "[commit]\n\tgpgsign = true\n[gpg]\n\tprogram = " + signer + "\n"})
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := writeTestObject(t, gitDir, TreeObject, "")
+	hash, err := repo.CreateCommit(tree, nil, maintenanceSignature, maintenanceSignature, "signed\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeTestFiles(t, map[string]string{".git/config": "[gpg]\n\tprogram = " + verifier + "\n"})
+	want := Verification{Status: GoodSignature, Format: "openpgp", Signer: "Ann Lee <ann@example.com>", Key: "89ABCDEF01234567", Trust: "marginal"}
+	if got, err := repo.VerifyCommit(hash); err != nil || got != want {
+		t.Errorf("VerifyCommit() = %+v, %v, want %+v", got, err, want)
+	}
+	writeTestFiles(t, map[string]string{".git/config": "[gpg]\n\tprogram = " + verifier + "\n\tminTrustLevel = fully\n"})
+	if got, err := repo.VerifyCommit(hash); err != nil || got.Status != UntrustedSignature || got.Verified() {
+		t.Errorf("VerifyCommit() below gpg.minTrustLevel = %+v, %v, want it untrusted", got, err)
+	}
+}
+
+func TestVerifySSHSignedCommit(t *testing.T) {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen isn't installed")
+	}
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	gitDir := newTestRepo(t)
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := writeTestObject(t, gitDir, TreeObject, "")
+
+	unsigned, err := repo.CreateCommit(tree, nil, maintenanceSignature, maintenanceSignature, "unsigned\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := repo.VerifyCommit(unsigned); err != nil || got.Status != Unsigned {
+		t.Errorf("VerifyCommit() of an unsigned commit = %+v, %v, want %c", got, err, Unsigned)
+	}
+
+	dir := t.TempDir()
+	writeKey := func(name string) ssh.PublicKey {
+		t.Helper()
+		public, private, err := ed25519.GenerateKey(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block, err := ssh.MarshalPrivateKey(private, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), pem.EncodeToMemory(block), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		key, err := ssh.NewPublicKey(public)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return key
+	}
+	key, other := writeKey("id_ed25519"), writeKey("other")
+	writeTestFiles(t, map[string]string{
+		filepath.Join(dir, "allowed"):       "ann@example.com " + string(ssh.MarshalAuthorizedKey(key)),
+		filepath.Join(dir, "allowed_other"): "bob@example.com " + string(ssh.MarshalAuthorizedKey(other)),
+	})
+
+	sshConfig := "[gpg]\n\tformat = ssh\n[user]\n\tsigningkey = " + filepath.ToSlash(filepath.Join(dir, "id_ed25519")) + "\n"
+	writeTestFiles(t, map[string]string{".git/config": "[commit]\n\tgpgsign = true\n" + sshConfig})
+	hash, err := repo.CreateCommit(tree, nil, maintenanceSignature, maintenanceSignature, "signed\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.VerifyCommit(hash); !errors.Is(err, ErrNoAllowedSigners) {
+		t.Errorf("VerifyCommit() without gpg.ssh.allowedSignersFile = %v, want %v", err, ErrNoAllowedSigners)
+	}
+
+	writeTestFiles(t, map[string]string{".git/config": sshConfig + "[gpg \"ssh\"]\n\tallowedSignersFile = " + filepath.ToSlash(filepath.Join(dir, "allowed")) + "\n"})
+	got, err := repo.VerifyCommit(hash)
+	want := Verification{Status: GoodSignature, Format: "ssh", Signer: "ann@example.com", Key: ssh.FingerprintSHA256(key), Trust: "fully"}
+	if err != nil || got != want {
+		t.Errorf("VerifyCommit() = %+v, %v, want %+v", got, err, want)
+	}
+
+	// The signature of one commit doesn't hold for another.
+	signed, err := repo.ReadCommit(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged, err := repo.createCommit(tree, nil, maintenanceSignature, maintenanceSignature, "forged\n", fixedSigner(signed.Headers[0].Value))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := repo.VerifyCommit(forged); err != nil || got.Status != BadSignature {
+		t.Errorf("VerifyCommit() of a forged commit = %+v, %v, want %c", got, err, BadSignature)
+	}
+
+	writeTestFiles(t, map[string]string{".git/config": sshConfig + "[gpg \"ssh\"]\n\tallowedSignersFile = " + filepath.ToSlash(filepath.Join(dir, "allowed_other")) + "\n"})
+	if got, err := repo.VerifyCommit(hash); err != nil || got.Status != UntrustedSignature || got.Key != ssh.FingerprintSHA256(key) {
+		t.Errorf("VerifyCommit() by a key that isn't an allowed signer = %+v, %v, want it untrusted", got, err)
+	}
+}
@@ -0,0 +1,191 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+)
+
+// ErrStopWalk can be returned by an [ObjectVisitor] callback to end [Repository.WalkObjects] early
+// without failing it.
+var ErrStopWalk = errors.New("stop walk")
+
+// ObjectVisitor receives the objects [Repository.WalkObjects] reaches. Any callback may be nil; trees
+// are only read when OnTree is set. An error returned from a callback stops the walk and is returned
+// from it, except for [ErrStopWalk].
+type ObjectVisitor struct {
+	OnCommit func(commit Commit) error
+	OnTree   func(hash string, entries []TreeEntry) error
+	OnTag    func(tag Tag) error
+}
+
+// WalkObjects streams every commit, tree, and annotated tag reachable from tips to v as each is decoded,
+// visiting each object once. Empty tips means HEAD and every ref. Nothing decoded is kept after its
+// callback returns, only the hashes of the objects already visited, so the walk runs in memory
+// proportional to the number of objects rather than their size.
+//
+// Commits are visited newest first along each line of history, each followed by its tree and the
+// trees beneath it. Replacements and grafts are honoured, and the walk stops at shallow commits. Refs to
+// objects that don't exist are skipped.
+func (repo *Repository) WalkObjects(tips []string, v ObjectVisitor) error {
+	r, err := newCommitReader(repo)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	shallow, err := readShallow(repo.CommonDir)
+	if err != nil {
+		return err
+	}
+
+	var hashes []string
+	for _, tip := range tips {
+		hash, err := repo.ResolveRevision(tip)
+		if err != nil {
+			return err
+		}
+		hashes = append(hashes, hash)
+	}
+	if len(tips) == 0 {
+		refs, err := repo.listRefs("refs/")
+		if err != nil {
+			return err
+		}
+		if head, err := repo.resolveSymbolic("HEAD"); err == nil {
+			hashes = append(hashes, head)
+		}
+		for _, hash := range refs {
+			if isFullHash(hash) {
+				hashes = append(hashes, hash)
+			}
+		}
+	}
+
+	w := objectWalker{r: r, v: v, seen: map[string]bool{}}
+	var stack []string
+	for _, hash := range hashes {
+		commit, err := w.tags(hash)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return w.result(err)
+		}
+		if commit != "" {
+			stack = append(stack, commit)
+		}
+	}
+
+	for len(stack) > 0 {
+		hash := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if w.seen[hash] {
+			continue
+		}
+		w.seen[hash] = true
+
+		commit, ok, err := r.read(hash)
+		if err != nil {
+			return fmt.Errorf("git: failed to read commit %s: %w", hash, err)
+		}
+		if !ok {
+			continue
+		}
+		if v.OnCommit != nil {
+			if err := v.OnCommit(commit); err != nil {
+				return w.result(err)
+			}
+		}
+		if err := w.tree(commit.Tree); err != nil {
+			return w.result(err)
+		}
+		if shallow[hash] {
+			continue
+		}
+		// Pushed in reverse so the first parent is visited next.
+		for i := len(commit.Parents) - 1; i >= 0; i-- {
+			if !w.seen[commit.Parents[i]] {
+				stack = append(stack, commit.Parents[i])
+			}
+		}
+	}
+	return nil
+}
+
+// objectWalker holds what [Repository.WalkObjects] shares between commits.
+type objectWalker struct {
+	r    *commitReader
+	v    ObjectVisitor
+	seen map[string]bool
+}
+
+// tags visits the chain of annotated tags starting at hash and returns the commit it ends at, or "" if
+// it ends at another kind of object. Trees are visited, but blobs aren't.
+func (w *objectWalker) tags(hash string) (string, error) {
+	for range maxSymbolicDepth {
+		header, closer, err := w.r.open(hash)
+		if err != nil {
+			return "", err
+		}
+		switch header.Kind {
+		case CommitObject:
+			closer.Close()
+			return hash, nil
+		case TreeObject:
+			closer.Close()
+			return "", w.tree(hash)
+		case TagObject:
+		default:
+			closer.Close()
+			return "", nil
+		}
+
+		tag, err := w.r.d.DecodeTag(hash)
+		closer.Close()
+		if err != nil {
+			return "", err
+		}
+		if !w.seen[hash] {
+			w.seen[hash] = true
+			if w.v.OnTag != nil {
+				if err := w.v.OnTag(tag); err != nil {
+					return "", err
+				}
+			}
+		}
+		hash = tag.Object
+	}
+	return "", fmt.Errorf("git: tag nesting too deep at %s", hash)
+}
+
+// tree visits the tree hash and every tree beneath it not seen before, if there's an OnTree callback.
+func (w *objectWalker) tree(hash string) error {
+	if w.v.OnTree == nil || w.seen[hash] {
+		return nil
+	}
+	w.seen[hash] = true
+
+	entries, err := w.r.tree(hash)
+	if err != nil {
+		return fmt.Errorf("git: failed to read tree %s: %w", hash, err)
+	}
+	if err := w.v.OnTree(hash, entries); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.Mode == ModeTree {
+			if err := w.tree(entry.Hash); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// result turns [ErrStopWalk] into a successful walk.
+func (w *objectWalker) result(err error) error {
+	if errors.Is(err, ErrStopWalk) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,72 @@
+package git
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestWalkObjects(t *testing.T) {
+	gitDir := newTestRepo(t)
+	repo, err := OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	who := Signature{Name: "Ann", Email: "ann@example.com", Time: time.Unix(1703130000, 0)}
+
+	blob, _ := repo.Encoder().Encode(BlobObject, []byte("package main\n"))
+	b := NewTreeBuilder(repo.Encoder())
+	if err := b.Add("src/main.go", ModeFile, blob); err != nil {
+		t.Fatal(err)
+	}
+	tree, _ := b.Write()
+	root, err := repo.CreateCommit(tree, nil, who, who, "root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Both commits keep src as it was in root.
+	head := writeTestTreeCommit(t, repo, root, who, "head", map[string]string{"README": "hello\n", "src/main.go": "package main\n"})
+	side := writeTestTreeCommit(t, repo, root, who, "side", map[string]string{"src/main.go": "package main\n", "docs/a.md": "a\n"})
+	writeTestRef(t, gitDir, "refs/heads/main", head)
+	writeTestRef(t, gitDir, "refs/heads/side", side)
+	writeTestRef(t, gitDir, "HEAD", "ref: refs/heads/main")
+	tag := writeTestObject(t, gitDir, TagObject, fmt.Sprintf(
+		"object %s\ntype commit\ntag v1\ntagger John Doe <john.doe@example.com> 1703123456 +0000\n\nv1\n", root))
+	writeTestRef(t, gitDir, "refs/tags/v1", tag)
+
+	var commits, tags []string
+	trees := map[string]int{}
+	err = repo.WalkObjects(nil, ObjectVisitor{
+		OnCommit: func(c Commit) error { commits = append(commits, c.Message); return nil },
+		OnTree:   func(hash string, entries []TreeEntry) error { trees[hash]++; return nil },
+		OnTag:    func(tag Tag) error { tags = append(tags, tag.Name); return nil },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(commits)
+	if !slices.Equal(commits, []string{"head", "root", "side"}) || !slices.Equal(tags, []string{"v1"}) {
+		t.Errorf("visited commits %q and tags %q", commits, tags)
+	}
+	// Three root trees, one src tree shared by all of them, and docs.
+	if len(trees) != 5 {
+		t.Errorf("visited %d trees, want 5", len(trees))
+	}
+	for hash, n := range trees {
+		if n != 1 {
+			t.Errorf("tree %s visited %d times", hash, n)
+		}
+	}
+
+	var visited []string
+	err = repo.WalkObjects([]string{"main"}, ObjectVisitor{
+		OnCommit: func(c Commit) error {
+			visited = append(visited, c.Message)
+			return ErrStopWalk
+		},
+	})
+	if err != nil || !slices.Equal(visited, []string{"head"}) {
+		t.Errorf("stopping after the first commit visited %q, %v", visited, err)
+	}
+}
@@ -0,0 +1,336 @@
+package httpclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	DefaultRetries    = 3
+	DefaultMinBackoff = 500 * time.Millisecond
+	DefaultMaxBackoff = 10 * time.Second
+	DefaultTimeout    = 30 * time.Second
+
+	// DefaultMaxWait is the longest a request waits for a rate limit to reset before giving up with a
+	// [*RateLimitError] instead.
+	DefaultMaxWait = time.Minute
+)
+
+// RateLimitError reports a request the server turned away because a rate limit was used up, and it
+// would reset too late to wait for.
+type RateLimitError struct {
+	Host  string
+	Reset time.Time // When the limit resets, or zero if the server didn't say
+}
+
+func (e *RateLimitError) Error() string {
+	if e.Reset.IsZero() {
+		return fmt.Sprintf("rate limit exceeded on %s, try again later", e.Host)
+	}
+	return fmt.Sprintf("rate limit exceeded on %s, it resets at %s", e.Host, e.Reset.Local().Format("15:04"))
+}
+
+// Transport is an [http.RoundTripper] that makes requests resilient to flaky networks and busy servers.
+//
+// Requests that fail to connect, time out, or get a 502, 503, or 504 are retried with exponential backoff
+// and jitter, but only for idempotent methods, since a POST may have been acted on before the failure.
+// Responses saying a rate limit was hit (a 429, or a 403 with an exhausted X-RateLimit-Remaining or
+// RateLimit-Remaining header) are retried for any method, after waiting as long as Retry-After or the
+// reset header asks, unless that's longer than MaxWait. Each attempt is canceled once it makes no
+// progress for the timeout for its host. A new Transport is created by calling [NewTransport].
+type Transport struct {
+	Base       http.RoundTripper
+	Retries    int // How many times a failed request is retried
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	MaxWait    time.Duration
+
+	// Timeouts limits how long requests to a host may go without progress, whether connecting, waiting
+	// for the response, or sending or reading a body, keyed by host name. A key also covers its
+	// subdomains, so github.com applies to api.github.com too. Hosts without one use Timeout.
+	Timeouts map[string]time.Duration
+	Timeout  time.Duration
+
+	sleep func(ctx context.Context, d time.Duration) error
+	now   func() time.Time
+}
+
+// NewTransport returns a Transport with the default retry policy over [http.DefaultTransport].
+func NewTransport() *Transport {
+	return &Transport{
+		Base:       http.DefaultTransport,
+		Retries:    DefaultRetries,
+		MinBackoff: DefaultMinBackoff,
+		MaxBackoff: DefaultMaxBackoff,
+		MaxWait:    DefaultMaxWait,
+		Timeouts:   map[string]time.Duration{},
+		Timeout:    DefaultTimeout,
+		sleep:      sleepContext,
+		now:        time.Now,
+	}
+}
+
+// Client returns an HTTP client using t.
+func (t *Transport) Client() *http.Client {
+	return &http.Client{Transport: t}
+}
+
+// New returns an HTTP client with a [NewTransport] transport.
+func New() *http.Client {
+	return NewTransport().Client()
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.Body != nil && req.Body != http.NoBody {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+
+		resp, err := t.attempt(req)
+		wait, retry := t.retryAfter(req, resp, err, attempt)
+		if !retry {
+			return resp, err
+		}
+		if resp != nil {
+			// Drain a little of the body so the connection can be reused.
+			io.CopyN(io.Discard, resp.Body, 4096)
+			resp.Body.Close()
+		}
+		if err := t.pause(req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// attempt sends req once, giving up when it makes no progress for the timeout for its host.
+func (t *Transport) attempt(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	timeout := t.timeout(req.URL.Hostname())
+	if timeout <= 0 {
+		return base.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithCancel(req.Context())
+	w := &watchdog{timeout: timeout, cancel: cancel}
+	w.timer = time.AfterFunc(timeout, w.expire)
+	req = req.WithContext(ctx)
+	if req.Body != nil && req.Body != http.NoBody {
+		req.Body = &watchedBody{ReadCloser: req.Body, w: w}
+	}
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		w.stop()
+		return nil, w.err(err)
+	}
+	resp.Body = &watchedBody{ReadCloser: resp.Body, w: w, release: true}
+	return resp, nil
+}
+
+func (t *Transport) timeout(host string) time.Duration {
+	for {
+		if timeout, ok := t.Timeouts[host]; ok {
+			return timeout
+		}
+		_, parent, ok := strings.Cut(host, ".")
+		if !ok || !strings.Contains(parent, ".") {
+			return t.Timeout
+		}
+		host = parent
+	}
+}
+
+// retryAfter decides whether the outcome of an attempt is worth retrying and how long to wait first.
+func (t *Transport) retryAfter(req *http.Request, resp *http.Response, err error, attempt int) (time.Duration, bool) {
+	if attempt >= t.Retries || req.Context().Err() != nil {
+		return 0, false
+	}
+	rewindable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	if !rewindable {
+		return 0, false
+	}
+
+	if err != nil {
+		return t.backoff(attempt), idempotent(req.Method)
+	}
+
+	if limited, reset := rateLimited(resp, t.clock()); limited {
+		wait := reset.Sub(t.clock())
+		if reset.IsZero() {
+			wait = t.backoff(attempt)
+		}
+		// Too long to wait: hand the response back for the caller to report.
+		return max(wait, 0), wait <= t.MaxWait
+	}
+
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After"), t.clock()); ok {
+			return retryAfter, retryAfter <= t.MaxWait
+		}
+		return t.backoff(attempt), idempotent(req.Method)
+	}
+	return 0, false
+}
+
+func (t *Transport) clock() time.Time {
+	if t.now == nil {
+		return time.Now()
+	}
+	return t.now()
+}
+
+func (t *Transport) pause(ctx context.Context, d time.Duration) error {
+	if t.sleep == nil {
+		return sleepContext(ctx, d)
+	}
+	return t.sleep(ctx, d)
+}
+
+func (t *Transport) backoff(attempt int) time.Duration {
+	wait := t.MinBackoff << attempt
+	if wait > t.MaxBackoff || wait <= 0 {
+		wait = t.MaxBackoff
+	}
+	// Full jitter between half and all of the backoff keeps clients that failed together from retrying together.
+	return wait/2 + rand.N(wait/2+1)
+}
+
+// CheckRateLimit turns a response rejected by a rate limit into a [*RateLimitError], and returns nil for
+// any other response. Clients call it on error responses so users learn when to try again.
+func CheckRateLimit(resp *http.Response) error {
+	limited, reset := rateLimited(resp, time.Now())
+	if !limited {
+		return nil
+	}
+	return &RateLimitError{Host: resp.Request.URL.Hostname(), Reset: reset}
+}
+
+// rateLimited reports whether resp was turned away by a rate limit, and when the limit resets if the
+// server said.
+func rateLimited(resp *http.Response, now time.Time) (bool, time.Time) {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusForbidden {
+		return false, time.Time{}
+	}
+
+	exhausted := resp.StatusCode == http.StatusTooManyRequests
+	var reset time.Time
+	for _, prefix := range []string{"X-RateLimit-", "RateLimit-"} {
+		if resp.Header.Get(prefix+"Remaining") == "0" {
+			exhausted = true
+			if seconds, err := strconv.ParseInt(resp.Header.Get(prefix+"Reset"), 10, 64); err == nil {
+				reset = time.Unix(seconds, 0)
+			}
+		}
+	}
+	if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After"), now); ok {
+		// GitHub's secondary rate limits are 403s that only carry Retry-After.
+		exhausted, reset = true, now.Add(retryAfter)
+	}
+	return exhausted, reset
+}
+
+// parseRetryAfter parses a Retry-After header, which holds either seconds or an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if when, err := http.ParseTime(value); err == nil {
+		return max(when.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// watchdog cancels an attempt that makes no progress for its timeout. Connecting, the TLS handshake,
+// and waiting for the response's headers each have the whole timeout, but sending the request body and
+// reading the response body restart it with every read, so a large upload or download that keeps
+// moving isn't cut off.
+type watchdog struct {
+	timeout time.Duration
+	timer   *time.Timer
+	cancel  context.CancelFunc
+	expired atomic.Bool
+}
+
+func (w *watchdog) expire() {
+	w.expired.Store(true)
+	w.cancel()
+}
+
+func (w *watchdog) progress() {
+	w.timer.Reset(w.timeout)
+}
+
+func (w *watchdog) stop() {
+	w.timer.Stop()
+	w.cancel()
+}
+
+// err reports an attempt the watchdog canceled as having timed out.
+func (w *watchdog) err(err error) error {
+	if err == nil || err == io.EOF || !w.expired.Load() {
+		return err
+	}
+	return fmt.Errorf("no progress for %s: %w", w.timeout, context.DeadlineExceeded)
+}
+
+// watchedBody tells its watchdog about every read of a request or response body, and for a response,
+// releases it once the body is closed.
+type watchedBody struct {
+	io.ReadCloser
+	w       *watchdog
+	release bool
+}
+
+func (b *watchedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.w.progress()
+	}
+	return n, b.w.err(err)
+}
+
+func (b *watchedBody) Close() error {
+	err := b.ReadCloser.Close()
+	if b.release {
+		b.w.stop()
+	}
+	return err
+}
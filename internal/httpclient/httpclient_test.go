@@ -0,0 +1,178 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testTransport returns a Transport that records how long it was asked to sleep instead of sleeping.
+func testTransport(slept *[]time.Duration) *Transport {
+	t := NewTransport()
+	t.sleep = func(ctx context.Context, d time.Duration) error {
+		*slept = append(*slept, d)
+		return nil
+	}
+	return t
+}
+
+func TestRetriesServerErrors(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		if calls < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	var slept []time.Duration
+	client := testTransport(&slept).Client()
+
+	req, _ := http.NewRequest(http.MethodPut, server.URL, strings.NewReader("payload"))
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "payload" || calls != 3 {
+		t.Fatalf("got %s %q after %d calls", resp.Status, body, calls)
+	}
+	if len(slept) != 2 || slept[1] < slept[0]/2 {
+		t.Errorf("expected two growing backoffs, got %v", slept)
+	}
+
+	// A POST may have been acted on, so a gateway error isn't retried.
+	calls = 0
+	resp, err = client.Post(server.URL, "text/plain", strings.NewReader("x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadGateway || calls != 1 {
+		t.Errorf("POST got %s after %d calls, want one 502", resp.Status, calls)
+	}
+}
+
+func TestRateLimits(t *testing.T) {
+	reset := time.Now().Add(20 * time.Second).Unix()
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path == "/long" || calls == 1 {
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset, 10))
+			if r.URL.Path == "/long" {
+				w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset+3600, 10))
+			}
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	var slept []time.Duration
+	client := testTransport(&slept).Client()
+
+	// Rate limited requests weren't acted on, so even a POST is retried once the limit resets.
+	resp, err := client.Post(server.URL, "text/plain", strings.NewReader("x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated || len(slept) != 1 || slept[0] < 15*time.Second {
+		t.Fatalf("got %s after sleeping %v", resp.Status, slept)
+	}
+
+	resp, err = client.Get(server.URL + "/long")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	var limitErr *RateLimitError
+	if err := CheckRateLimit(resp); !errors.As(err, &limitErr) || limitErr.Reset.Unix() != reset+3600 {
+		t.Errorf("a limit resetting in an hour: got %v", err)
+	}
+	if CheckRateLimit(&http.Response{StatusCode: http.StatusForbidden, Header: http.Header{}}) != nil {
+		t.Error("a plain 403 was taken for a rate limit")
+	}
+}
+
+func TestHostTimeouts(t *testing.T) {
+	tr := NewTransport()
+	tr.Timeouts["github.com"] = time.Second
+	for host, want := range map[string]time.Duration{
+		"github.com":     time.Second,
+		"api.github.com": time.Second,
+		"gitlab.com":     DefaultTimeout,
+		"notgithub.com":  DefaultTimeout,
+	} {
+		if got := tr.timeout(host); got != want {
+			t.Errorf("timeout(%s) = %v, want %v", host, got, want)
+		}
+	}
+
+	block := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-block:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(block)
+
+	var slept []time.Duration
+	tr = testTransport(&slept)
+	tr.Timeout, tr.Retries = 50*time.Millisecond, 1
+	start := time.Now()
+	if _, err := tr.Client().Get(server.URL); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected a timeout, got %v", err)
+	}
+	if len(slept) != 1 || time.Since(start) > 5*time.Second {
+		t.Errorf("expected one retry after the timeout, slept %v", slept)
+	}
+}
+
+// trickle is a request body that takes a while to send, but keeps sending.
+type trickle struct{ chunks int }
+
+func (r *trickle) Read(p []byte) (int, error) {
+	if r.chunks == 0 {
+		return 0, io.EOF
+	}
+	r.chunks--
+	time.Sleep(20 * time.Millisecond)
+	return copy(p, "chunk"), nil
+}
+
+func TestTimeoutAllowsSlowUploads(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	var slept []time.Duration
+	tr := testTransport(&slept)
+	tr.Timeout = 50 * time.Millisecond
+	resp, err := tr.Client().Post(server.URL, "application/octet-stream", &trickle{chunks: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if body, _ := io.ReadAll(resp.Body); len(body) != 10*len("chunk") {
+		t.Errorf("expected the whole upload echoed back, got %d bytes", len(body))
+	}
+}
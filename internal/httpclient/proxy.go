@@ -0,0 +1,145 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Network holds the proxy and certificate settings requests are sent with, mirroring git's http.proxy
+// and http.sslCAInfo so plain reaches the same servers git does from behind a corporate proxy.
+type Network struct {
+	// Proxy is the proxy every request goes through, overriding HTTPS_PROXY and HTTP_PROXY. A value
+	// without a scheme, like proxy.example.com:3128, is taken as an http:// proxy. When empty, the
+	// proxy comes from the environment.
+	Proxy string
+
+	// NoProxy lists the hosts that are reached directly, in the format of NO_PROXY: a comma-separated
+	// list of host names, which also cover their subdomains, IP addresses, CIDR ranges, and * for every
+	// host. When empty, NO_PROXY from the environment is used.
+	NoProxy string
+
+	// CAInfo is a file of PEM certificates trusted besides the system's roots, for servers whose
+	// certificates are signed by a private authority.
+	CAInfo string
+}
+
+// Base returns an [http.Transport] with [http.DefaultTransport]'s settings that uses n's proxy and
+// certificates, ready to be used as a [Transport]'s Base.
+func (n Network) Base() (*http.Transport, error) {
+	base := http.DefaultTransport.(*http.Transport).Clone()
+
+	if n.Proxy != "" {
+		proxy, err := parseProxy(n.Proxy)
+		if err != nil {
+			return nil, err
+		}
+		noProxy := n.NoProxy
+		if noProxy == "" {
+			noProxy = getenvAny("NO_PROXY", "no_proxy")
+		}
+		base.Proxy = func(req *http.Request) (*url.URL, error) {
+			if bypassProxy(req.URL, noProxy) {
+				return nil, nil
+			}
+			return proxy, nil
+		}
+	} else if n.NoProxy != "" {
+		base.Proxy = func(req *http.Request) (*url.URL, error) {
+			if bypassProxy(req.URL, n.NoProxy) {
+				return nil, nil
+			}
+			return http.ProxyFromEnvironment(req)
+		}
+	}
+
+	if n.CAInfo != "" {
+		pem, err := os.ReadFile(n.CAInfo)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read CA bundle: %w", err)
+		}
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificates found in %s", n.CAInfo)
+		}
+		base.TLSClientConfig = &tls.Config{RootCAs: roots}
+	}
+	return base, nil
+}
+
+// NewTransportFor returns a [NewTransport] Transport whose requests go through n's proxy and trust
+// its certificates.
+func NewTransportFor(n Network) (*Transport, error) {
+	base, err := n.Base()
+	if err != nil {
+		return nil, err
+	}
+	t := NewTransport()
+	t.Base = base
+	return t, nil
+}
+
+func parseProxy(proxy string) (*url.URL, error) {
+	if !strings.Contains(proxy, "://") {
+		proxy = "http://" + proxy
+	}
+	u, err := url.Parse(proxy)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy %q", proxy)
+	}
+	return u, nil
+}
+
+// bypassProxy reports whether noProxy says the host of u should be reached directly.
+func bypassProxy(u *url.URL, noProxy string) bool {
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if port == "" {
+		port = map[string]string{"http": "80", "https": "443"}[u.Scheme]
+	}
+	ip := net.ParseIP(host)
+	for _, entry := range strings.Split(noProxy, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		switch {
+		case entry == "":
+			continue
+		case entry == "*":
+			return true
+		}
+
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && network.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if h, p, err := net.SplitHostPort(entry); err == nil {
+			if p != port {
+				continue
+			}
+			entry = h
+		}
+		entry = strings.TrimPrefix(strings.TrimPrefix(entry, "*"), ".")
+		if host == entry || strings.HasSuffix(host, "."+entry) {
+			return true
+		}
+	}
+	return false
+}
+
+func getenvAny(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
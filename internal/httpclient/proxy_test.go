@@ -0,0 +1,92 @@
+package httpclient
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBypassProxy(t *testing.T) {
+	tests := []struct {
+		url     string
+		noProxy string
+		want    bool
+	}{
+		{"https://github.com/x", "", false},
+		{"https://github.com/x", "github.com", true},
+		{"https://api.github.com/x", "github.com", true},
+		{"https://api.github.com/x", ".github.com", true},
+		{"https://notgithub.com/x", "github.com", false},
+		{"https://git.corp.example/x", "localhost, *.corp.example", true},
+		{"https://git.corp.example/x", "git.corp.example:8443", false},
+		{"https://git.corp.example:8443/x", "git.corp.example:8443", true},
+		{"https://git.corp.example/x", "git.corp.example:443", true},
+		{"http://10.1.2.3/x", "10.0.0.0/8", true},
+		{"http://192.168.1.1/x", "10.0.0.0/8", false},
+		{"https://anything/x", "*", true},
+	}
+	for _, test := range tests {
+		u, _ := url.Parse(test.url)
+		if got := bypassProxy(u, test.noProxy); got != test.want {
+			t.Errorf("bypassProxy(%s, %q) = %v, want %v", test.url, test.noProxy, got, test.want)
+		}
+	}
+}
+
+func TestNetworkProxy(t *testing.T) {
+	base, err := Network{Proxy: "proxy.corp.example:3128", NoProxy: "internal.example"}.Base()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest("GET", "https://api.github.com/user", nil)
+	proxy, err := base.Proxy(req)
+	if err != nil || proxy == nil || proxy.String() != "http://proxy.corp.example:3128" {
+		t.Errorf("proxy for api.github.com = %v, %v", proxy, err)
+	}
+	req, _ = http.NewRequest("GET", "https://git.internal.example/api/v4", nil)
+	if proxy, err := base.Proxy(req); err != nil || proxy != nil {
+		t.Errorf("expected git.internal.example to bypass the proxy, got %v, %v", proxy, err)
+	}
+
+	if _, err := (Network{Proxy: "http://"}).Base(); err == nil {
+		t.Error("expected an error for a proxy without a host")
+	}
+}
+
+func TestNetworkCAInfo(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	if _, err := http.Get(server.URL); err == nil {
+		t.Fatal("expected the test server's certificate to be untrusted by default")
+	}
+
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(bundle, cert, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	transport, err := NewTransportFor(Network{CAInfo: bundle})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := transport.Client().Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if err := os.WriteFile(bundle, []byte("not a certificate"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (Network{CAInfo: bundle}).Base(); err == nil {
+		t.Error("expected an error for a bundle without certificates")
+	}
+}
@@ -0,0 +1,115 @@
+// Package logging is where plain's logs go: to standard error with --verbose or --debug, and to a log
+// file in plain's state directory when git config plain.logFile is set, for diagnosing problems after
+// they happened. The log file is rotated as it grows, so it never takes more than a few megabytes.
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+const (
+	fileName = "plain.log"
+
+	// MaxSize is how large a log file grows before it is rotated.
+	MaxSize = 1 << 20
+
+	// Keep is how many rotated log files are kept, as plain.log.1, the newest, up to plain.log.<Keep>.
+	Keep = 3
+)
+
+// File is a log file that rotates itself. Every write opens the file, appends to it, and closes it again,
+// so a File needs no closing, and several plain commands logging at once never overwrite each other. A
+// new File is created by calling [NewFile].
+type File struct {
+	Path string // Where the current log is written
+}
+
+// NewFile returns the log File kept in the plain directory of the user's cache directory, beside the
+// record plain bugreport reads.
+func NewFile() (*File, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return &File{Path: filepath.Join(cacheDir, "plain", fileName)}, nil
+}
+
+// Write appends p to the log, first rotating it if p would take it past [MaxSize].
+func (f *File) Write(p []byte) (int, error) {
+	if info, err := os.Stat(f.Path); err == nil && info.Size()+int64(len(p)) > MaxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0o755); err != nil {
+		return 0, err
+	}
+	file, err := os.OpenFile(f.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	n, err := file.Write(p)
+	return n, errors.Join(err, file.Close())
+}
+
+// rotate moves the log to plain.log.1, and each older one a number up, dropping the one past [Keep].
+func (f *File) rotate() error {
+	for i := Keep; i > 0; i-- {
+		from := f.Path
+		if i > 1 {
+			from = fmt.Sprintf("%s.%d", f.Path, i-1)
+		}
+		if err := os.Rename(from, fmt.Sprintf("%s.%d", f.Path, i)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Tee returns a handler that passes every record to each of handlers that is enabled for its level.
+func Tee(handlers ...slog.Handler) slog.Handler {
+	return tee(handlers)
+}
+
+type tee []slog.Handler
+
+func (t tee) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range t {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t tee) Handle(ctx context.Context, record slog.Record) error {
+	var errs []error
+	for _, h := range t {
+		if h.Enabled(ctx, record.Level) {
+			errs = append(errs, h.Handle(ctx, record.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (t tee) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(tee, len(t))
+	for i, h := range t {
+		handlers[i] = h.WithAttrs(attrs)
+	}
+	return handlers
+}
+
+func (t tee) WithGroup(name string) slog.Handler {
+	handlers := make(tee, len(t))
+	for i, h := range t {
+		handlers[i] = h.WithGroup(name)
+	}
+	return handlers
+}
@@ -0,0 +1,56 @@
+package logging
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileRotates(t *testing.T) {
+	f := &File{Path: filepath.Join(t.TempDir(), "plain", fileName)}
+	line := []byte(strings.Repeat("x", MaxSize/2-1) + "\n")
+	for i := range Keep + 3 {
+		if _, err := f.Write(line); err != nil {
+			t.Fatalf("write %d: %v", i, err)
+		}
+	}
+
+	// Two lines fit in a file, so six lines fill three: the current one and two rotated ones.
+	for _, name := range []string{fileName, fileName + ".1", fileName + ".2"} {
+		info, err := os.Stat(filepath.Join(filepath.Dir(f.Path), name))
+		if err != nil || info.Size() != int64(2*len(line)) {
+			t.Errorf("%s: %v, %v, want %d bytes", name, info, err, 2*len(line))
+		}
+	}
+	if _, err := os.Stat(fmt.Sprintf("%s.%d", f.Path, Keep)); err == nil {
+		t.Errorf("%s.%d exists, but only two files were rotated", fileName, Keep)
+	}
+
+	for range 2 * Keep {
+		f.Write(line)
+	}
+	if _, err := os.Stat(fmt.Sprintf("%s.%d", f.Path, Keep+1)); err == nil {
+		t.Errorf("more than %d rotated files are kept", Keep)
+	}
+}
+
+func TestTee(t *testing.T) {
+	var info, debug bytes.Buffer
+	log := slog.New(Tee(
+		slog.NewTextHandler(&info, &slog.HandlerOptions{Level: slog.LevelInfo}),
+		slog.NewTextHandler(&debug, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	)).With("command", "plain done")
+	log.Info("ran git")
+	log.Debug("read object")
+
+	if got := strings.Count(info.String(), "\n"); got != 1 || !strings.Contains(info.String(), "command=\"plain done\"") {
+		t.Errorf("the info handler got %q, want the info record with its attributes", info.String())
+	}
+	if got := strings.Count(debug.String(), "\n"); got != 2 {
+		t.Errorf("the debug handler got %q, want both records", debug.String())
+	}
+}
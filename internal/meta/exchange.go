@@ -0,0 +1,105 @@
+package meta
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// FormatVersion is the version of the JSON document [Export] writes. [Import] rejects newer ones.
+const FormatVersion = 1
+
+var ErrUnsupportedVersion = errors.New("unsupported metadata format version")
+
+// document is the JSON layout of an export.
+type document struct {
+	Version  int       `json:"version"`
+	Features []Feature `json:"features"`
+}
+
+// Export writes features to w as an indented JSON document that [Import] reads back.
+func Export(w io.Writer, features []Feature) error {
+	if features == nil {
+		features = []Feature{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(document{Version: FormatVersion, Features: features})
+}
+
+// Import reads a JSON document written by [Export].
+func Import(r io.Reader) ([]Feature, error) {
+	var doc document
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("malformed metadata: %w", err)
+	}
+	if doc.Version < 1 || doc.Version > FormatVersion {
+		return nil, fmt.Errorf("%w %d", ErrUnsupportedVersion, doc.Version)
+	}
+	for _, feature := range doc.Features {
+		if err := validName(feature.Name); err != nil {
+			return nil, err
+		}
+	}
+	return doc.Features, nil
+}
+
+// Merge records incoming features in the store and returns how many changed.
+//
+// A feature whose recorded metadata is at least as recent as the incoming copy is kept as it is, so
+// importing an old backup or a teammate's stale copy never loses newer edits. With replace, features the
+// store has that aren't in incoming are deleted, making the store a copy of incoming.
+func (s *Store) Merge(incoming []Feature, replace bool) (int, error) {
+	changed := 0
+	names := map[string]bool{}
+	for _, feature := range incoming {
+		names[feature.Name] = true
+		current, ok, err := s.Load(feature.Name)
+		if err != nil {
+			return changed, err
+		}
+		if ok && !feature.Updated.After(current.Updated) {
+			continue
+		}
+		if err := s.Save(feature); err != nil {
+			return changed, err
+		}
+		changed++
+	}
+
+	if !replace {
+		return changed, nil
+	}
+	features, err := s.All()
+	if err != nil {
+		return changed, err
+	}
+	for _, feature := range features {
+		if names[feature.Name] {
+			continue
+		}
+		if err := s.Delete(feature.Name); err != nil {
+			return changed, err
+		}
+		changed++
+	}
+	return changed, nil
+}
+
+// Combine returns the union of two sets of features, keeping the most recently updated copy of any feature
+// in both, sorted by name.
+func Combine(a, b []Feature) []Feature {
+	newest := map[string]Feature{}
+	for _, feature := range slices.Concat(a, b) {
+		if current, ok := newest[feature.Name]; !ok || feature.Updated.After(current.Updated) {
+			newest[feature.Name] = feature
+		}
+	}
+	combined := slices.Collect(maps.Values(newest))
+	slices.SortFunc(combined, func(a, b Feature) int { return strings.Compare(a.Name, b.Name) })
+	return combined
+}
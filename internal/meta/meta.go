@@ -0,0 +1,160 @@
+package meta
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const featureExt = ".json"
+
+var ErrInvalidName = errors.New("invalid feature name")
+
+// Feature is what plain knows about a feature beyond its branch.
+type Feature struct {
+	Name        string    `json:"name"` // The feature's branch, e.g. login-form
+	Base        string    `json:"base,omitempty"`
+	BaseCommit  string    `json:"base_commit,omitempty"`
+	Issue       string    `json:"issue,omitempty"` // The issue the feature is linked to, e.g. PROJ-42
+	Description string    `json:"description,omitempty"`
+	Owner       string    `json:"owner,omitempty"`
+	Status      string    `json:"status,omitempty"`
+	Created     time.Time `json:"created"`
+	Updated     time.Time `json:"updated"` // When the metadata last changed, which decides merges
+}
+
+// Store keeps the metadata of every feature as one JSON file per feature. It lives in the repository's
+// common git directory, so every worktree shares it. A new Store is created by calling [NewStore].
+type Store struct {
+	Dir string // Where the feature files are kept
+}
+
+// NewStore returns a Store keeping its files in commonDir/plain/features.
+func NewStore(commonDir string) *Store {
+	return &Store{Dir: filepath.Join(commonDir, "plain", "features")}
+}
+
+// Load returns the metadata of the named feature. The boolean is false if none was recorded.
+func (s *Store) Load(name string) (Feature, bool, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return Feature{}, false, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Feature{}, false, nil
+	}
+	if err != nil {
+		return Feature{}, false, err
+	}
+
+	var feature Feature
+	if err := json.Unmarshal(data, &feature); err != nil {
+		return Feature{}, false, fmt.Errorf("%s: %w", path, err)
+	}
+	feature.Name = name
+	return feature, true, nil
+}
+
+// All returns the metadata of every feature, sorted by name.
+func (s *Store) All() ([]Feature, error) {
+	var features []Feature
+	err := filepath.WalkDir(s.Dir, func(path string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && path == s.Dir {
+			return filepath.SkipDir
+		}
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, featureExt) {
+			return err
+		}
+
+		rel, err := filepath.Rel(s.Dir, path)
+		if err != nil {
+			return err
+		}
+		feature, _, err := s.Load(strings.TrimSuffix(filepath.ToSlash(rel), featureExt))
+		if err != nil {
+			return err
+		}
+		features = append(features, feature)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(features, func(a, b Feature) int { return strings.Compare(a.Name, b.Name) })
+	return features, nil
+}
+
+// Save records the feature's metadata, replacing what was recorded for it before. The file is written
+// under a temporary name and renamed into place, so a concurrent reader never sees half of it.
+func (s *Store) Save(feature Feature) error {
+	path, err := s.path(feature.Name)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(feature, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
+
+// Delete forgets the named feature's metadata. Deleting a feature without any is not an error.
+func (s *Store) Delete(name string) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// path returns the file holding the named feature. Branch names may contain slashes, which become
+// directories, but nothing that would escape the store.
+func (s *Store) path(name string) (string, error) {
+	if err := validName(name); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.Dir, filepath.FromSlash(name)+featureExt), nil
+}
+
+func validName(name string) error {
+	if name == "" || strings.ContainsAny(name, "\\\x00") {
+		return fmt.Errorf("%w %q", ErrInvalidName, name)
+	}
+	for _, part := range strings.Split(name, "/") {
+		if part == "" || part == "." || part == ".." {
+			return fmt.Errorf("%w %q", ErrInvalidName, name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package meta
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sim-deos/plain/internal/git"
+)
+
+func testFeature(name string, updated time.Time) Feature {
+	return Feature{Name: name, Base: "main", Issue: "PROJ-42", Created: updated.Add(-time.Hour), Updated: updated}
+}
+
+func TestStore(t *testing.T) {
+	s := NewStore(t.TempDir())
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	if _, ok, err := s.Load("login"); ok || err != nil {
+		t.Fatalf("Load on an empty store = %v, %v", ok, err)
+	}
+	for _, name := range []string{"login", "team/api"} {
+		if err := s.Save(testFeature(name, now)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, ok, err := s.Load("team/api")
+	if err != nil || !ok || got.Issue != "PROJ-42" || !got.Updated.Equal(now) {
+		t.Fatalf("Load(team/api) = %+v, %v, %v", got, ok, err)
+	}
+	all, err := s.All()
+	if err != nil || len(all) != 2 || all[0].Name != "login" || all[1].Name != "team/api" {
+		t.Fatalf("All() = %+v, %v", all, err)
+	}
+
+	if err := s.Save(Feature{Name: "../escape"}); !errors.Is(err, ErrInvalidName) {
+		t.Errorf("saving a name outside the store: got %v", err)
+	}
+	if err := s.Delete("login"); err != nil {
+		t.Fatal(err)
+	}
+	if all, _ := s.All(); len(all) != 1 {
+		t.Errorf("expected one feature after deleting, got %+v", all)
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	features := []Feature{testFeature("login", time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))}
+	var b bytes.Buffer
+	if err := Export(&b, features); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Import(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Name != "login" || got[0].Base != "main" || !got[0].Updated.Equal(features[0].Updated) {
+		t.Fatalf("round trip gave %+v", got)
+	}
+
+	if _, err := Import(strings.NewReader(`{"version": 2, "features": []}`)); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("importing a newer format: got %v", err)
+	}
+	if _, err := Import(strings.NewReader(`{"version": 1, "features": [{"name": "a/../../b"}]}`)); !errors.Is(err, ErrInvalidName) {
+		t.Errorf("importing an escaping name: got %v", err)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	s := NewStore(t.TempDir())
+	old := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	recent := old.Add(24 * time.Hour)
+
+	local := testFeature("login", recent)
+	local.Description = "local edit"
+	for _, feature := range []Feature{local, testFeature("stale", old), testFeature("local-only", old)} {
+		if err := s.Save(feature); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	incoming := []Feature{testFeature("login", old), testFeature("stale", recent), testFeature("new", old)}
+	changed, err := s.Merge(incoming, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed != 2 {
+		t.Errorf("expected the stale and new features to change, got %d changes", changed)
+	}
+	if got, _, _ := s.Load("login"); got.Description != "local edit" {
+		t.Error("an older incoming copy overwrote a newer local one")
+	}
+
+	if changed, err := s.Merge(incoming, true); err != nil || changed != 1 {
+		t.Errorf("Merge with replace = %d, %v, want only local-only deleted", changed, err)
+	}
+	if _, ok, _ := s.Load("local-only"); ok {
+		t.Error("replace kept a feature that wasn't imported")
+	}
+}
+
+func TestRef(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"objects", "refs/heads"} {
+		if err := os.MkdirAll(filepath.Join(root, ".git", dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Chdir(root)
+	repo, err := git.OpenRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if features, err := ReadRef(repo); err != nil || len(features) != 0 {
+		t.Fatalf("ReadRef before writing = %v, %v", features, err)
+	}
+
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	who := git.Signature{Name: "Ann", Email: "ann@example.com", Time: now}
+	features := []Feature{testFeature("login", now), testFeature("team/api", now)}
+	if changed, err := WriteRef(repo, features, who, ""); err != nil || !changed {
+		t.Fatalf("WriteRef = %v, %v", changed, err)
+	}
+	if changed, err := WriteRef(repo, features, who, ""); err != nil || changed {
+		t.Errorf("writing the same features again = %v, %v, want no change", changed, err)
+	}
+
+	got, err := ReadRef(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[1].Name != "team/api" || got[1].Issue != "PROJ-42" {
+		t.Fatalf("ReadRef = %+v", got)
+	}
+}
+
+func TestCombine(t *testing.T) {
+	old := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	recent := old.Add(time.Hour)
+	mine, theirs := testFeature("login", recent), testFeature("login", old)
+	mine.Owner, theirs.Owner = "Ann", "Bob"
+
+	got := Combine([]Feature{testFeature("zeta", old), mine}, []Feature{theirs, testFeature("api", old)})
+	if len(got) != 3 || got[0].Name != "api" || got[1].Owner != "Ann" || got[2].Name != "zeta" {
+		t.Errorf("Combine = %+v", got)
+	}
+}
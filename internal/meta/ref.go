@@ -0,0 +1,139 @@
+package meta
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/sim-deos/plain/internal/git"
+)
+
+// Ref is the ref feature metadata is shared through. Each commit on it holds one <name>.json file per
+// feature, so it can be pushed and fetched like any other ref and its history shows who changed what.
+const Ref = "refs/plain/meta"
+
+// WriteRef commits features to [Ref], on top of what it held before, with who as the author. The boolean
+// is false, and nothing is written, when the ref already holds exactly these features.
+//
+// If merged is set, it is a remote's copy of the ref whose features were merged into features, and it
+// becomes a parent of the new commit unless the ref already contains it, so pushing the result fast-forwards
+// the remote.
+func WriteRef(repo *git.Repository, features []Feature, who git.Signature, merged string) (bool, error) {
+	e := repo.Encoder()
+	b := git.NewTreeBuilder(e)
+	for _, feature := range features {
+		if err := validName(feature.Name); err != nil {
+			return false, err
+		}
+		data, err := json.MarshalIndent(feature, "", "  ")
+		if err != nil {
+			return false, err
+		}
+		hash, err := e.Encode(git.BlobObject, append(data, '\n'))
+		if err != nil {
+			return false, err
+		}
+		if err := b.Add(feature.Name+featureExt, git.ModeFile, hash); err != nil {
+			return false, err
+		}
+	}
+	tree, err := b.Write()
+	if err != nil {
+		return false, err
+	}
+
+	old := git.ZeroHash
+	var parents []string
+	current, err := repo.ResolveRevision(Ref)
+	if err != nil && !errors.Is(err, git.ErrUnknownRevision) {
+		return false, err
+	}
+	if err == nil {
+		old, parents = current, []string{current}
+	}
+	if merged != "" && merged != current {
+		ahead, behind := 1, 1
+		if current != "" {
+			var err error
+			if ahead, behind, err = repo.AheadBehind(current, merged); err != nil {
+				return false, err
+			}
+		}
+		switch {
+		case behind == 0:
+			// The ref already contains the remote's copy.
+		case ahead == 0 || current == "":
+			parents = []string{merged}
+		default:
+			parents = append(parents, merged)
+		}
+	}
+
+	if len(parents) == 1 {
+		commit, err := repo.ReadCommit(parents[0])
+		if err != nil {
+			return false, err
+		}
+		if commit.Tree == tree && parents[0] == current {
+			return false, nil
+		}
+		if commit.Tree == tree {
+			// The remote's copy holds exactly these features already, so the ref can simply follow it.
+			return true, repo.UpdateRef(Ref, old, parents[0], who, "plain meta: fast-forward")
+		}
+	}
+
+	message := fmt.Sprintf("plain meta: %d feature(s)", len(features))
+	hash, err := repo.CreateCommit(tree, parents, who, who, message)
+	if err != nil {
+		return false, err
+	}
+	return true, repo.UpdateRef(Ref, old, hash, who, message)
+}
+
+// ReadRef returns the features [Ref] holds, sorted by name, or none if the ref doesn't exist.
+func ReadRef(repo *git.Repository) ([]Feature, error) {
+	return ReadCommit(repo, Ref)
+}
+
+// ReadCommit returns the features held by rev, a commit written by [WriteRef], sorted by name. A rev
+// that doesn't exist holds none.
+func ReadCommit(repo *git.Repository, rev string) ([]Feature, error) {
+	hash, err := repo.ResolveRevision(rev)
+	if errors.Is(err, git.ErrUnknownRevision) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	commit, err := repo.ReadCommit(hash)
+	if err != nil {
+		return nil, err
+	}
+	files, err := repo.ReadTreeFiles(commit.Tree)
+	if err != nil {
+		return nil, err
+	}
+
+	var features []Feature
+	for path, entry := range files {
+		name, ok := strings.CutSuffix(path, featureExt)
+		if !ok {
+			continue
+		}
+		data, err := repo.ReadBlob(entry.Hash)
+		if err != nil {
+			return nil, err
+		}
+		var feature Feature
+		if err := json.Unmarshal(data, &feature); err != nil {
+			return nil, fmt.Errorf("%s:%s: %w", rev, path, err)
+		}
+		feature.Name = name
+		features = append(features, feature)
+	}
+	slices.SortFunc(features, func(a, b Feature) int { return strings.Compare(a.Name, b.Name) })
+	return features, nil
+}
@@ -0,0 +1,169 @@
+// Package oplock keeps two plain commands from changing a repository at once. A command that switches
+// branches, resets the work tree, or moves refs holds a lock file in the repository's git directory
+// while it runs, and another that finds the lock held fails rather than interleave its changes with the
+// first's. git itself doesn't know the lock, so an editor's git integration isn't held off by it, but git
+// takes its own index and ref locks, which plain honours too.
+package oplock
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// File is where the lock is kept, relative to the repository's common git directory.
+const File = "plain/lock"
+
+// StaleAfter is how old a lock must be to be taken as left behind when whether the command holding it is
+// still running can't be told, as when it ran on another machine sharing the repository.
+const StaleAfter = time.Hour
+
+var ErrLocked = errors.New("another plain command is changing the repository")
+
+// Holder is what a lock records about the command holding it.
+type Holder struct {
+	Command string    `json:"command"` // e.g. plain done
+	PID     int       `json:"pid"`
+	Host    string    `json:"host"`
+	Started time.Time `json:"started"`
+}
+
+func (h Holder) String() string {
+	if h.Command == "" {
+		return fmt.Sprintf("a command that started %s ago", time.Since(h.Started).Round(time.Second))
+	}
+	return fmt.Sprintf("%s (process %d on %s, started %s ago)", h.Command, h.PID, h.Host, time.Since(h.Started).Round(time.Second))
+}
+
+// stale reports whether the command holding the lock has exited without releasing it, as one that
+// crashed or was killed would. A running process with the holder's ID is taken to be the holder.
+func (h Holder) stale(host string) bool {
+	if h.PID != 0 && h.Host == host {
+		return !running(h.PID)
+	}
+	return time.Since(h.Started) > StaleAfter
+}
+
+// Lock is a held lock on a repository. A Lock is acquired by calling [Acquire], and must be released
+// with [Lock.Release].
+type Lock struct {
+	path string
+	data []byte // What the lock file holds while it's ours
+}
+
+// Acquire locks the repository whose common git directory is commonDir for command. If another command
+// holds the lock, an error wrapping [ErrLocked] says which. A lock left behind by a command that is no
+// longer running is taken over.
+func Acquire(commonDir, command string) (*Lock, error) {
+	host, _ := os.Hostname()
+	data, err := json.Marshal(Holder{Command: command, PID: os.Getpid(), Host: host, Started: time.Now()})
+	if err != nil {
+		return nil, err
+	}
+	l := &Lock{path: filepath.Join(commonDir, File), data: data}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o755); err != nil {
+		return nil, err
+	}
+
+	for tookOver := false; ; tookOver = true {
+		err := l.create()
+		if !errors.Is(err, fs.ErrExist) {
+			if err != nil {
+				return nil, err
+			}
+			return l, nil
+		}
+		holder, held, err := readHolder(l.path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if tookOver || !holder.stale(host) {
+			return nil, fmt.Errorf("%w: %s", ErrLocked, holder)
+		}
+		// Another command may have taken over the stale lock since it was read, so it's only removed if
+		// it hasn't changed.
+		if err := removeIfHolds(l.path, held); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// create creates the lock file, failing with [fs.ErrExist] if it's already there.
+func (l *Lock) create() error {
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(l.data); err != nil {
+		f.Close()
+		os.Remove(l.path)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(l.path)
+		return err
+	}
+	return nil
+}
+
+// Release releases the lock. A lock another command has since taken over is left alone.
+func (l *Lock) Release() error {
+	return removeIfHolds(l.path, l.data)
+}
+
+// readHolder reads who holds the lock at path, along with the file's content. A lock file that can't be
+// decoded, as one whose holder is still writing it or crashed while doing so, is held by an unknown
+// command since the file was last changed.
+func readHolder(path string) (Holder, []byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Holder{}, nil, err
+	}
+	var holder Holder
+	if json.Unmarshal(data, &holder) != nil || holder.Started.IsZero() {
+		info, err := os.Stat(path)
+		if err != nil {
+			return Holder{}, nil, err
+		}
+		holder = Holder{Started: info.ModTime()}
+	}
+	return holder, data, nil
+}
+
+// removeIfHolds removes the lock file at path if it still holds data. The file is first renamed aside,
+// so that it's the file that was compared that is removed: another command taking over the lock between
+// the comparison and the removal would otherwise lose its new lock. A file found to hold something else
+// is put back, unless a new lock has been created in the meantime.
+func removeIfHolds(path string, data []byte) error {
+	aside := fmt.Sprintf("%s.%d.%d", path, os.Getpid(), time.Now().UnixNano())
+	if err := os.Rename(path, aside); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	current, err := os.ReadFile(aside)
+	if err == nil && bytes.Equal(current, data) {
+		return os.Remove(aside)
+	}
+	// Linking, unlike renaming, doesn't replace a lock created since; where links aren't supported the
+	// file is renamed back only if there is none.
+	if linkErr := os.Link(aside, path); linkErr == nil || errors.Is(linkErr, fs.ErrExist) {
+		if rmErr := os.Remove(aside); err == nil {
+			err = rmErr
+		}
+	} else if _, statErr := os.Lstat(path); errors.Is(statErr, fs.ErrNotExist) {
+		if renameErr := os.Rename(aside, path); err == nil {
+			err = renameErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,99 @@
+package oplock
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAcquire(t *testing.T) {
+	dir := t.TempDir()
+	lock, err := Acquire(dir, "plain done")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Acquire(dir, "plain start"); !errors.Is(err, ErrLocked) {
+		t.Fatalf("Acquire() while locked = %v, want %v", err, ErrLocked)
+	}
+	if err := lock.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, File)); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("the lock file is left after Release(): %v", err)
+	}
+
+	lock, err = Acquire(dir, "plain start")
+	if err != nil {
+		t.Fatalf("Acquire() after Release() = %v", err)
+	}
+	if err := lock.Release(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAcquireStale(t *testing.T) {
+	host, _ := os.Hostname()
+	exited := exec.Command(os.Args[0], "-test.run=^$")
+	if err := exited.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name   string
+		holder Holder
+		stale  bool
+	}{
+		{"exited process", Holder{Command: "plain done", PID: exited.Process.Pid, Host: host, Started: time.Now()}, true},
+		{"running process", Holder{Command: "plain done", PID: os.Getpid(), Host: host, Started: time.Now().Add(-2 * StaleAfter)}, false},
+		{"another host, recently", Holder{Command: "plain done", PID: 1, Host: host + "-other", Started: time.Now()}, false},
+		{"another host, long ago", Holder{Command: "plain done", PID: 1, Host: host + "-other", Started: time.Now().Add(-2 * StaleAfter)}, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			data, _ := json.Marshal(tt.holder)
+			os.MkdirAll(filepath.Join(dir, "plain"), 0o755)
+			if err := os.WriteFile(filepath.Join(dir, File), data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			lock, err := Acquire(dir, "plain start")
+			if tt.stale && err != nil {
+				t.Fatalf("Acquire() over a stale lock = %v", err)
+			}
+			if !tt.stale && !errors.Is(err, ErrLocked) {
+				t.Fatalf("Acquire() over a held lock = %v, want %v", err, ErrLocked)
+			}
+			if lock != nil {
+				lock.Release()
+			}
+		})
+	}
+}
+
+func TestReleaseTakenOver(t *testing.T) {
+	dir := t.TempDir()
+	lock, err := Acquire(dir, "plain done")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Another command found the lock stale and took it over.
+	path := filepath.Join(dir, File)
+	if err := os.WriteFile(path, []byte(`{"command":"plain start"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := lock.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("Release() removed a lock it no longer held: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != `{"command":"plain start"}` {
+		t.Errorf("lock holds %s after Release(), want the new holder's", data)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("Release() left %d files next to the lock, want none", len(entries)-1)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package oplock
+
+import (
+	"errors"
+	"syscall"
+)
+
+// running reports whether a process with the ID pid exists. Signal 0 checks without sending anything.
+func running(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
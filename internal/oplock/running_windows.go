@@ -0,0 +1,29 @@
+package oplock
+
+import (
+	"errors"
+	"syscall"
+)
+
+const (
+	// processQueryLimitedInformation is PROCESS_QUERY_LIMITED_INFORMATION, which the syscall package
+	// doesn't define.
+	processQueryLimitedInformation = 0x1000
+
+	// stillActive is the exit code Windows reports for a process that hasn't exited.
+	stillActive = 259
+)
+
+// running reports whether a process with the ID pid exists and hasn't exited.
+func running(pid int) bool {
+	h, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		return errors.Is(err, syscall.ERROR_ACCESS_DENIED)
+	}
+	defer syscall.CloseHandle(h)
+	var code uint32
+	if err := syscall.GetExitCodeProcess(h, &code); err != nil {
+		return true
+	}
+	return code == stillActive
+}
@@ -0,0 +1,119 @@
+// Package pair keeps track of who the user is pairing with, so checkpoints can credit them.
+package pair
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const stateFile = "pair.json"
+
+var ErrBadPartner = errors.New("not a name and email address")
+
+// Partner is someone the user is pairing with.
+type Partner struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// ParsePartner parses a partner written as "Name <email>", as git writes authors.
+func ParsePartner(s string) (Partner, error) {
+	address, err := mail.ParseAddress(strings.TrimSpace(s))
+	if err != nil || address.Name == "" {
+		return Partner{}, fmt.Errorf("%w: %q, write it like \"Ann Lee <ann@example.com>\"", ErrBadPartner, s)
+	}
+	return Partner{Name: address.Name, Email: address.Address}, nil
+}
+
+func (p Partner) String() string {
+	return p.Name + " <" + p.Email + ">"
+}
+
+// Trailer returns the Co-authored-by trailer crediting p on a commit, which GitHub and GitLab understand.
+func (p Partner) Trailer() string {
+	return "Co-authored-by: " + p.String()
+}
+
+// Trailers returns the trailers crediting partners, in order.
+func Trailers(partners []Partner) []string {
+	var trailers []string
+	for _, p := range partners {
+		trailers = append(trailers, p.Trailer())
+	}
+	return trailers
+}
+
+// Session is the pairing going on in a worktree. Its partners are kept in a directory inside the
+// worktree's git directory until it is stopped. A new Session is created by calling [NewSession].
+type Session struct {
+	Dir string // Where the partners are kept
+}
+
+// NewSession returns a Session keeping its state in gitDir/plain.
+func NewSession(gitDir string) *Session {
+	return &Session{Dir: filepath.Join(gitDir, "plain")}
+}
+
+// Partners returns who the user is pairing with, in the order they were added, or none when not pairing.
+func (s *Session) Partners() ([]Partner, error) {
+	data, err := os.ReadFile(filepath.Join(s.Dir, stateFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var partners []Partner
+	if err := json.Unmarshal(data, &partners); err != nil {
+		return nil, fmt.Errorf("pair: failed to read partners: %w", err)
+	}
+	return partners, nil
+}
+
+// Add adds partners to the session, leaving out anyone already in it by email, and returns everyone
+// now in it.
+func (s *Session) Add(partners ...Partner) ([]Partner, error) {
+	current, err := s.Partners()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range partners {
+		known := false
+		for _, q := range current {
+			known = known || strings.EqualFold(p.Email, q.Email)
+		}
+		if !known {
+			current = append(current, p)
+		}
+	}
+
+	data, err := json.Marshal(current)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(s.Dir, stateFile), data, 0o644); err != nil {
+		return nil, err
+	}
+	return current, nil
+}
+
+// Stop ends the session, returning who was in it.
+func (s *Session) Stop() ([]Partner, error) {
+	partners, err := s.Partners()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Remove(filepath.Join(s.Dir, stateFile)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return partners, nil
+}
@@ -0,0 +1,52 @@
+package pair
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestParsePartner(t *testing.T) {
+	p, err := ParsePartner("Ann Lee <ann@example.com>")
+	if err != nil || p != (Partner{Name: "Ann Lee", Email: "ann@example.com"}) {
+		t.Fatalf("ParsePartner() = %+v, %v", p, err)
+	}
+	if p.Trailer() != "Co-authored-by: Ann Lee <ann@example.com>" {
+		t.Errorf("Trailer() = %q", p.Trailer())
+	}
+	for _, bad := range []string{"ann", "ann@example.com", "Ann Lee"} {
+		if _, err := ParsePartner(bad); !errors.Is(err, ErrBadPartner) {
+			t.Errorf("ParsePartner(%q) = %v, want %v", bad, err, ErrBadPartner)
+		}
+	}
+}
+
+func TestSession(t *testing.T) {
+	s := &Session{Dir: t.TempDir()}
+	if partners, err := s.Partners(); err != nil || partners != nil {
+		t.Fatalf("Partners() before pairing = %v, %v, want none", partners, err)
+	}
+
+	ann := Partner{Name: "Ann", Email: "ann@example.com"}
+	bob := Partner{Name: "Bob", Email: "bob@example.com"}
+	if _, err := s.Add(ann); err != nil {
+		t.Fatal(err)
+	}
+	partners, err := s.Add(bob, Partner{Name: "Ann Lee", Email: "ANN@example.com"})
+	if err != nil || !slices.Equal(partners, []Partner{ann, bob}) {
+		t.Fatalf("Add() = %v, %v, want Ann once and Bob", partners, err)
+	}
+	if got := Trailers(partners); !slices.Equal(got, []string{"Co-authored-by: Ann <ann@example.com>", "Co-authored-by: Bob <bob@example.com>"}) {
+		t.Errorf("Trailers() = %q", got)
+	}
+
+	if stopped, err := s.Stop(); err != nil || len(stopped) != 2 {
+		t.Fatalf("Stop() = %v, %v, want both partners", stopped, err)
+	}
+	if partners, err := s.Partners(); err != nil || partners != nil {
+		t.Errorf("Partners() after stopping = %v, %v, want none", partners, err)
+	}
+	if _, err := s.Stop(); err != nil {
+		t.Errorf("Stop() when not pairing = %v", err)
+	}
+}
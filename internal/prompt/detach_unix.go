@@ -0,0 +1,13 @@
+//go:build !windows
+
+package prompt
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// detach starts the process in a session of its own, so closing the terminal doesn't hang it up.
+func detach(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
@@ -0,0 +1,15 @@
+package prompt
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// detachedProcess is DETACHED_PROCESS, which the syscall package doesn't define.
+const detachedProcess = 0x00000008
+
+// detach starts the process without a console, in a process group of its own so Ctrl+C in the terminal
+// doesn't reach it.
+func detach(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: detachedProcess | syscall.CREATE_NEW_PROCESS_GROUP}
+}
@@ -0,0 +1,12 @@
+package prompt
+
+import (
+	"os/exec"
+	"strconv"
+)
+
+// notifyCommand shows a notification in the macOS Notification Center through AppleScript.
+func notifyCommand(title, message string) *exec.Cmd {
+	script := "display notification " + strconv.Quote(message) + " with title " + strconv.Quote(title)
+	return exec.Command("osascript", "-e", script)
+}
@@ -0,0 +1,10 @@
+//go:build !windows && !darwin
+
+package prompt
+
+import "os/exec"
+
+// notifyCommand shows a desktop notification through notify-send, which most Linux and BSD desktops have.
+func notifyCommand(title, message string) *exec.Cmd {
+	return exec.Command("notify-send", "--app-name=plain", title, message)
+}
@@ -0,0 +1,18 @@
+package prompt
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// notifyCommand shows a balloon notification from the tray through PowerShell, which every supported
+// Windows has, and waits long enough for it to be seen before removing the icon again.
+func notifyCommand(title, message string) *exec.Cmd {
+	quote := func(s string) string { return "'" + strings.ReplaceAll(s, "'", "''") + "'" }
+	script := `Add-Type -AssemblyName System.Windows.Forms; ` +
+		`$n = New-Object System.Windows.Forms.NotifyIcon; ` +
+		`$n.Icon = [System.Drawing.SystemIcons]::Information; $n.Visible = $true; ` +
+		`$n.ShowBalloonTip(10000, ` + quote(title) + `, ` + quote(message) + `, 'Info'); ` +
+		`Start-Sleep -Seconds 10; $n.Dispose()`
+	return exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script)
+}
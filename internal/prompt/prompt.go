@@ -0,0 +1,149 @@
+package prompt
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sim-deos/plain/internal/display"
+)
+
+const (
+	cacheFile = "prompt.json"
+
+	// DefaultTTL is how long a cached state is shown without being recomputed, since edits to the work
+	// tree don't change anything cheap enough to check on every prompt.
+	DefaultTTL = 5 * time.Second
+
+	// DefaultBudget is how long the prompt may spend computing a fresh state before it falls back to
+	// the cache.
+	DefaultBudget = 100 * time.Millisecond
+)
+
+// State is what the prompt shows for a repository.
+type State struct {
+	Key      string    `json:"key"`    // The fingerprint of the repository the state was computed from
+	Branch   string    `json:"branch"` // The current branch, empty when HEAD is detached
+	Upstream string    `json:"upstream,omitempty"`
+	Ahead    int       `json:"ahead"`  // Commits on the branch that aren't on its upstream
+	Behind   int       `json:"behind"` // Commits on the upstream that aren't on the branch
+	Dirty    bool      `json:"dirty"`  // The index or work tree differs from HEAD
+	Computed time.Time `json:"computed"`
+
+	// DirtySince is when the changes were first seen, or the last checkpoint was made if that is later,
+	// and zero when there are none.
+	DirtySince time.Time `json:"dirtySince,omitzero"`
+
+	// Overdue is set when the changes have gone without a checkpoint for longer than the reminder
+	// allows, which Format marks with a "!". It is worked out when the prompt is shown, not cached.
+	Overdue bool `json:"-"`
+}
+
+// TrackDirty sets DirtySince from the state computed before, previous, and when HEAD was last committed
+// to: changes seen before keep the time they were first seen, unless there has been a checkpoint since,
+// and new changes start at Computed.
+func (s *State) TrackDirty(previous State, lastCheckpoint time.Time) {
+	switch {
+	case !s.Dirty:
+		s.DirtySince = time.Time{}
+	case previous.Dirty && !previous.DirtySince.IsZero() && previous.Branch == s.Branch:
+		s.DirtySince = previous.DirtySince
+		if lastCheckpoint.After(s.DirtySince) {
+			s.DirtySince = lastCheckpoint
+		}
+	default:
+		s.DirtySince = s.Computed
+	}
+}
+
+// CheckpointOverdue reports whether at now the changes have gone without a checkpoint for at least
+// after. An after of 0 or less never reminds.
+func (s State) CheckpointOverdue(after time.Duration, now time.Time) bool {
+	return after > 0 && s.Dirty && !s.DirtySince.IsZero() && now.Sub(s.DirtySince) >= after
+}
+
+// Fresh reports whether the state was computed for key no longer than ttl before now.
+func (s State) Fresh(key string, ttl time.Duration, now time.Time) bool {
+	return s.Key == key && now.Sub(s.Computed) <= ttl
+}
+
+// Format renders the state as a single compact line such as "login ↑2 ↓1 *": the branch (or "detached"),
+// how far it is ahead of and behind its upstream when it differs, and a star when there are changes,
+// followed by a "!" when they are overdue for a checkpoint.
+func (s State) Format(charset display.Charset) string {
+	parts := []string{s.Branch}
+	if s.Branch == "" {
+		parts[0] = "detached"
+	}
+	if s.Ahead > 0 {
+		parts = append(parts, fmt.Sprintf("%s%d", charset.Ahead, s.Ahead))
+	}
+	if s.Behind > 0 {
+		parts = append(parts, fmt.Sprintf("%s%d", charset.Behind, s.Behind))
+	}
+	if s.Dirty && s.Overdue {
+		parts = append(parts, "*!")
+	} else if s.Dirty {
+		parts = append(parts, "*")
+	}
+	return strings.Join(parts, " ")
+}
+
+// Cache keeps the last computed [State] of a worktree, in a directory inside its git directory.
+// A new Cache is created by calling [NewCache].
+type Cache struct {
+	Dir string // Where the cached state is kept
+}
+
+// NewCache returns a Cache keeping its state in gitDir/plain.
+func NewCache(gitDir string) *Cache {
+	return &Cache{Dir: filepath.Join(gitDir, "plain")}
+}
+
+// Load returns the cached state. The boolean is false if nothing was cached yet or the cache is unreadable,
+// which a prompt treats the same way.
+func (c *Cache) Load() (State, bool) {
+	data, err := os.ReadFile(filepath.Join(c.Dir, cacheFile))
+	if err != nil {
+		return State{}, false
+	}
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return State{}, false
+	}
+	return state, true
+}
+
+// Save replaces the cached state. The file is written under a temporary name and renamed into place, so
+// a prompt running at the same time never reads half of it.
+func (c *Cache) Save(state State) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.Dir, 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(c.Dir, cacheFile+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), filepath.Join(c.Dir, cacheFile)); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
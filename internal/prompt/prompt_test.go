@@ -0,0 +1,120 @@
+package prompt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sim-deos/plain/internal/display"
+)
+
+func TestFormat(t *testing.T) {
+	cases := []struct {
+		state State
+		want  string
+	}{
+		{State{Branch: "main"}, "main"},
+		{State{Branch: "login", Ahead: 2, Behind: 1, Dirty: true}, "login ↑2 ↓1 *"},
+		{State{Behind: 3}, "detached ↓3"},
+		{State{Branch: "login", Dirty: true, Overdue: true}, "login *!"},
+	}
+	for _, c := range cases {
+		if got := c.state.Format(display.Unicode); got != c.want {
+			t.Errorf("Format(%+v) = %q, want %q", c.state, got, c.want)
+		}
+	}
+	if got := (State{Branch: "login", Ahead: 2}).Format(display.ASCII); got != "login +2" {
+		t.Errorf("expected an ASCII prompt, got %q", got)
+	}
+}
+
+func TestCache(t *testing.T) {
+	cache := NewCache(t.TempDir())
+	if _, ok := cache.Load(); ok {
+		t.Fatal("expected an empty cache")
+	}
+
+	now := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	key := "abc-1-def"
+	if err := cache.Save(State{Key: key, Branch: "main", Ahead: 1, Computed: now}); err != nil {
+		t.Fatal(err)
+	}
+
+	state, ok := cache.Load()
+	if !ok || state.Branch != "main" || state.Ahead != 1 {
+		t.Fatalf("unexpected cached state %+v", state)
+	}
+	if !state.Fresh(key, DefaultTTL, now.Add(time.Second)) {
+		t.Error("expected the state to be fresh within its TTL")
+	}
+	if state.Fresh(key, DefaultTTL, now.Add(time.Minute)) {
+		t.Error("expected the state to expire after its TTL")
+	}
+	if state.Fresh("abc-1-fed", DefaultTTL, now) {
+		t.Error("expected a different key to miss the cache")
+	}
+}
+
+func TestTrackDirty(t *testing.T) {
+	start := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	first := State{Branch: "main", Dirty: true, Computed: start}
+	first.TrackDirty(State{Branch: "main", Computed: start.Add(-time.Minute)}, time.Time{})
+	if !first.DirtySince.Equal(start) {
+		t.Fatalf("DirtySince of new changes = %v, want %v", first.DirtySince, start)
+	}
+
+	later := State{Branch: "main", Dirty: true, Computed: start.Add(time.Hour)}
+	later.TrackDirty(first, start.Add(-time.Hour))
+	if !later.DirtySince.Equal(start) {
+		t.Errorf("DirtySince of the same changes = %v, want when they were first seen", later.DirtySince)
+	}
+	if later.CheckpointOverdue(2*time.Hour, later.Computed) || !later.CheckpointOverdue(time.Hour, later.Computed) {
+		t.Error("expected the changes to be overdue after an hour, not before two")
+	}
+	if later.CheckpointOverdue(0, later.Computed.Add(24*time.Hour)) {
+		t.Error("expected a reminder of 0 to be off")
+	}
+
+	checkpoint := start.Add(30 * time.Minute)
+	later.TrackDirty(first, checkpoint)
+	if !later.DirtySince.Equal(checkpoint) {
+		t.Errorf("DirtySince after a checkpoint = %v, want %v", later.DirtySince, checkpoint)
+	}
+
+	switched := State{Branch: "login", Dirty: true, Computed: start.Add(time.Hour)}
+	switched.TrackDirty(first, time.Time{})
+	if !switched.DirtySince.Equal(switched.Computed) {
+		t.Errorf("DirtySince on another branch = %v, want it to start over", switched.DirtySince)
+	}
+
+	clean := State{Branch: "main", Computed: start.Add(time.Hour)}
+	clean.TrackDirty(first, time.Time{})
+	if !clean.DirtySince.IsZero() || clean.CheckpointOverdue(time.Minute, clean.Computed) {
+		t.Errorf("DirtySince of a clean tree = %v, want none", clean.DirtySince)
+	}
+}
+
+func TestClaimRefresh(t *testing.T) {
+	cache := NewCache(t.TempDir())
+	now := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	if !cache.ClaimRefresh(now) {
+		t.Fatal("expected the first refresh to start")
+	}
+	if cache.ClaimRefresh(now.Add(time.Second)) {
+		t.Fatal("expected a second refresh right away to be held back")
+	}
+	if !cache.ClaimRefresh(now.Add(RefreshSpacing)) {
+		t.Fatal("expected a refresh to start again after the spacing")
+	}
+
+	if !cache.ClaimFetch(time.Hour, now) || cache.ClaimFetch(time.Hour, now.Add(time.Minute)) {
+		t.Fatal("expected one fetch per interval")
+	}
+	if cache.ClaimFetch(0, now.Add(2*time.Hour)) {
+		t.Fatal("expected an interval of 0 to disable fetching")
+	}
+
+	if !cache.ClaimReminder(time.Hour, now) || cache.ClaimReminder(time.Hour, now.Add(time.Minute)) {
+		t.Fatal("expected one reminder per period")
+	}
+}
@@ -0,0 +1,71 @@
+package prompt
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+const (
+	refreshStamp = "prompt-refresh"
+	fetchStamp   = "prompt-fetch"
+	remindStamp  = "prompt-remind"
+
+	// RefreshSpacing is the least time between two background refreshes of the same worktree, so that a
+	// burst of prompts starts one refresh rather than one each.
+	RefreshSpacing = DefaultTTL
+
+	// DefaultFetchInterval is how often a background refresh fetches the upstream remote.
+	DefaultFetchInterval = 5 * time.Minute
+)
+
+// ClaimRefresh reports whether a background refresh should start at now, and if so records that one did.
+func (c *Cache) ClaimRefresh(now time.Time) bool {
+	return c.claim(refreshStamp, RefreshSpacing, now)
+}
+
+// ClaimFetch reports whether the refresh running at now should fetch, which it should when no refresh
+// fetched within interval, and if so records that it does. An interval of 0 or less never fetches.
+func (c *Cache) ClaimFetch(interval time.Duration, now time.Time) bool {
+	return interval > 0 && c.claim(fetchStamp, interval, now)
+}
+
+// ClaimReminder reports whether the refresh running at now should send a desktop notification that a
+// checkpoint is overdue, which it should when none was sent within after, and if so records that it does.
+func (c *Cache) ClaimReminder(after time.Duration, now time.Time) bool {
+	return after > 0 && c.claim(remindStamp, after, now)
+}
+
+// claim checks and moves the modification time of the stamp file name. Two prompts racing for the same
+// stamp may both win, which only costs a redundant refresh.
+func (c *Cache) claim(name string, interval time.Duration, now time.Time) bool {
+	stamp := filepath.Join(c.Dir, name)
+	if info, err := os.Stat(stamp); err == nil && now.Sub(info.ModTime()) < interval {
+		return false
+	}
+	if err := os.MkdirAll(c.Dir, 0o755); err != nil {
+		return false
+	}
+	if err := os.WriteFile(stamp, nil, 0o644); err != nil {
+		return false
+	}
+	return os.Chtimes(stamp, now, now) == nil
+}
+
+// StartDetached starts cmd in the background, detached from the terminal and the calling process so that
+// it outlives the prompt that started it and never writes to it.
+func StartDetached(cmd *exec.Cmd) error {
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = nil, nil, nil
+	detach(cmd)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	return cmd.Process.Release()
+}
+
+// Notify shows a desktop notification with title and message, using whatever the platform offers:
+// notify-send, AppleScript on macOS, or PowerShell on Windows. It doesn't wait for it to be dismissed.
+func Notify(title, message string) error {
+	return StartDetached(notifyCommand(title, message))
+}
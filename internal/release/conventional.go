@@ -0,0 +1,40 @@
+package release
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/sim-deos/plain/internal/git"
+)
+
+var (
+	conventionalSubject = regexp.MustCompile(`^(\w+)(?:\(([^)]*)\))?(!)?:\s+(.+)$`)
+	breakingFooter      = regexp.MustCompile(`(?m)^BREAKING[ -]CHANGE:\s`)
+)
+
+// Change is a commit read as a conventional commit, e.g. "feat(auth)!: drop password logins".
+type Change struct {
+	Commit      git.Commit
+	Type        string // The lowercased type, e.g. feat or fix, or "" if the subject isn't conventional
+	Scope       string // The optional scope in parentheses
+	Breaking    bool   // Marked with ! or a BREAKING CHANGE footer
+	Description string // The subject after the type, or the whole subject for other commits
+}
+
+// ParseChange reads a commit's message as a conventional commit.
+func ParseChange(commit git.Commit) Change {
+	subject, _, _ := strings.Cut(commit.Message, "\n")
+	subject = strings.TrimSpace(subject)
+
+	change := Change{Commit: commit, Description: subject, Breaking: breakingFooter.MatchString(commit.Message)}
+	match := conventionalSubject.FindStringSubmatch(subject)
+	if match == nil {
+		return change
+	}
+
+	change.Type = strings.ToLower(match[1])
+	change.Scope = match[2]
+	change.Breaking = change.Breaking || match[3] == "!"
+	change.Description = match[4]
+	return change
+}
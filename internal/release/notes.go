@@ -0,0 +1,64 @@
+package release
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sim-deos/plain/internal/git"
+)
+
+// Notes renders release notes in markdown for commits, given newest first.
+//
+// Commits are grouped into breaking changes, features, fixes, and everything else, keeping their order
+// within each group. Merge commits are left out, since the commits they bring in are listed themselves.
+func Notes(commits []git.Commit) string {
+	sections := []struct {
+		title   string
+		changes []Change
+	}{
+		{title: "Breaking changes"},
+		{title: "Features"},
+		{title: "Fixes"},
+		{title: "Other changes"},
+	}
+
+	for _, commit := range commits {
+		if len(commit.Parents) > 1 {
+			continue
+		}
+		change := ParseChange(commit)
+		switch {
+		case change.Breaking:
+			sections[0].changes = append(sections[0].changes, change)
+		case change.Type == "feat":
+			sections[1].changes = append(sections[1].changes, change)
+		case change.Type == "fix":
+			sections[2].changes = append(sections[2].changes, change)
+		default:
+			sections[3].changes = append(sections[3].changes, change)
+		}
+	}
+
+	var b strings.Builder
+	for _, section := range sections {
+		if len(section.changes) == 0 {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "## %s\n\n", section.title)
+		for _, change := range section.changes {
+			b.WriteString("- ")
+			if change.Scope != "" {
+				b.WriteString("**" + change.Scope + ":** ")
+			}
+			fmt.Fprintf(&b, "%s (%s)\n", change.Description, change.Commit.DisName())
+		}
+	}
+
+	if b.Len() == 0 {
+		return "No changes.\n"
+	}
+	return b.String()
+}
@@ -0,0 +1,57 @@
+package release
+
+import (
+	"testing"
+
+	"github.com/sim-deos/plain/internal/git"
+)
+
+func TestParseChange(t *testing.T) {
+	tests := []struct {
+		message  string
+		expected Change
+	}{
+		{"feat(auth): add device login", Change{Type: "feat", Scope: "auth", Description: "add device login"}},
+		{"Fix!: drop old flag", Change{Type: "fix", Breaking: true, Description: "drop old flag"}},
+		{"refactor: split parser\n\nBREAKING CHANGE: Parse is gone", Change{Type: "refactor", Breaking: true, Description: "split parser"}},
+		{"Update README", Change{Description: "Update README"}},
+	}
+
+	for _, test := range tests {
+		change := ParseChange(git.Commit{Message: test.message})
+		if change.Type != test.expected.Type || change.Scope != test.expected.Scope ||
+			change.Breaking != test.expected.Breaking || change.Description != test.expected.Description {
+			t.Errorf("%q: expected %+v, got %+v", test.message, test.expected, change)
+		}
+	}
+}
+
+func TestNotes(t *testing.T) {
+	commits := []git.Commit{
+		{Hash: "1111111aaaa", Message: "fix: handle empty input", Parents: []string{"p"}},
+		{Hash: "2222222bbbb", Message: "Merge branch 'feature'", Parents: []string{"p", "q"}},
+		{Hash: "3333333cccc", Message: "feat(cli)!: rename --out to --output", Parents: []string{"p"}},
+		{Hash: "4444444dddd", Message: "feat: add --json", Parents: []string{"p"}},
+		{Hash: "5555555eeee", Message: "Bump dependencies", Parents: []string{"p"}},
+	}
+
+	expected := `## Breaking changes
+
+- **cli:** rename --out to --output (3333333)
+
+## Features
+
+- add --json (4444444)
+
+## Fixes
+
+- handle empty input (1111111)
+
+## Other changes
+
+- Bump dependencies (5555555)
+`
+	if notes := Notes(commits); notes != expected {
+		t.Fatalf("unexpected notes:\n%s", notes)
+	}
+}
@@ -0,0 +1,122 @@
+package release
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/sim-deos/plain/internal/git"
+)
+
+var ErrBadVersion = errors.New("not a semantic version")
+
+var semverPattern = regexp.MustCompile(`^(v?)(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)(?:-([0-9A-Za-z.-]+))?(?:\+([0-9A-Za-z.-]+))?$`)
+
+// Version is a semantic version, as found in tags like v1.4.2 or 2.0.0-rc.1.
+type Version struct {
+	Prefix     string // "v" if the version was written with one, kept when the next version is formatted
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease string // The part after "-", e.g. rc.1
+	Build      string // The part after "+", which doesn't affect precedence
+}
+
+// ParseVersion parses a semantic version, with or without a leading v.
+func ParseVersion(s string) (Version, error) {
+	match := semverPattern.FindStringSubmatch(s)
+	if match == nil {
+		return Version{}, fmt.Errorf("%w: %q", ErrBadVersion, s)
+	}
+
+	v := Version{Prefix: match[1], Prerelease: match[5], Build: match[6]}
+	v.Major, _ = strconv.Atoi(match[2])
+	v.Minor, _ = strconv.Atoi(match[3])
+	v.Patch, _ = strconv.Atoi(match[4])
+	return v, nil
+}
+
+func (v Version) String() string {
+	s := fmt.Sprintf("%s%d.%d.%d", v.Prefix, v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+	return s
+}
+
+// Bump is which part of a version a set of changes calls for incrementing.
+type Bump int
+
+const (
+	NoBump Bump = iota // Nothing releasable changed
+	PatchBump
+	MinorBump
+	MajorBump
+)
+
+var bumpName = map[Bump]string{
+	NoBump:    "none",
+	PatchBump: "patch",
+	MinorBump: "minor",
+	MajorBump: "major",
+}
+
+func (b Bump) String() string {
+	return bumpName[b]
+}
+
+// BumpFor returns the bump conventional commits call for: major for breaking changes, minor for
+// features, and patch for fixes and performance improvements. Other commits don't need a release.
+func BumpFor(commits []git.Commit) Bump {
+	bump := NoBump
+	for _, commit := range commits {
+		change := ParseChange(commit)
+		switch {
+		case change.Breaking:
+			bump = max(bump, MajorBump)
+		case change.Type == "feat":
+			bump = max(bump, MinorBump)
+		case change.Type == "fix" || change.Type == "perf":
+			bump = max(bump, PatchBump)
+		}
+	}
+	return bump
+}
+
+// Next returns the version after v for bump.
+//
+// While the major version is 0 the API isn't considered stable, so breaking changes only bump the minor
+// version. A pre-release is promoted to its final version when
+// that already covers the bump, so 1.1.0-rc.1 with new features becomes 1.1.0 rather than 1.2.0.
+func (v Version) Next(bump Bump) Version {
+	if bump == NoBump {
+		return v
+	}
+	if v.Major == 0 && bump == MajorBump {
+		bump = MinorBump
+	}
+
+	next := Version{Prefix: v.Prefix, Major: v.Major, Minor: v.Minor, Patch: v.Patch}
+	if v.Prerelease != "" {
+		covered := bump == PatchBump ||
+			bump == MinorBump && v.Patch == 0 ||
+			bump == MajorBump && v.Minor == 0 && v.Patch == 0
+		if covered {
+			return next
+		}
+	}
+
+	switch bump {
+	case MajorBump:
+		next.Major, next.Minor, next.Patch = v.Major+1, 0, 0
+	case MinorBump:
+		next.Minor, next.Patch = v.Minor+1, 0
+	case PatchBump:
+		next.Patch = v.Patch + 1
+	}
+	return next
+}
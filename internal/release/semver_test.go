@@ -0,0 +1,117 @@
+package release
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sim-deos/plain/internal/git"
+)
+
+func TestParseVersion(t *testing.T) {
+	v, err := ParseVersion("v1.12.3-rc.1+build.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Major != 1 || v.Minor != 12 || v.Patch != 3 || v.Prerelease != "rc.1" || v.Build != "build.5" || v.String() != "v1.12.3-rc.1+build.5" {
+		t.Fatalf("unexpected version %+v", v)
+	}
+
+	for _, bad := range []string{"1.2", "v01.2.3", "release-1"} {
+		if _, err := ParseVersion(bad); !errors.Is(err, ErrBadVersion) {
+			t.Errorf("%q: expected ErrBadVersion, got %v", bad, err)
+		}
+	}
+}
+
+func TestBumpFor(t *testing.T) {
+	commits := func(messages ...string) []git.Commit {
+		var c []git.Commit
+		for _, m := range messages {
+			c = append(c, git.Commit{Message: m})
+		}
+		return c
+	}
+
+	cases := []struct {
+		commits  []git.Commit
+		expected Bump
+	}{
+		{commits("docs: typo", "chore: deps"), NoBump},
+		{commits("fix: crash", "docs: typo"), PatchBump},
+		{commits("fix: crash", "feat: flag"), MinorBump},
+		{commits("feat: flag", "refactor!: drop v1 API"), MajorBump},
+	}
+	for _, c := range cases {
+		if bump := BumpFor(c.commits); bump != c.expected {
+			t.Errorf("expected %s, got %s", c.expected, bump)
+		}
+	}
+}
+
+func TestVersionNext(t *testing.T) {
+	cases := []struct {
+		version  string
+		bump     Bump
+		expected string
+	}{
+		{"v1.2.3", PatchBump, "v1.2.4"},
+		{"v1.2.3", MinorBump, "v1.3.0"},
+		{"1.2.3", MajorBump, "2.0.0"},
+		{"v1.2.3", NoBump, "v1.2.3"},
+		{"v0.4.1", MajorBump, "v0.5.0"},
+		{"v0.4.1", MinorBump, "v0.5.0"},
+		{"v1.1.0-rc.1", MinorBump, "v1.1.0"},
+		{"v1.1.0-rc.1", MajorBump, "v2.0.0"},
+	}
+	for _, c := range cases {
+		v, _ := ParseVersion(c.version)
+		if next := v.Next(c.bump).String(); next != c.expected {
+			t.Errorf("%s with a %s bump: expected %s, got %s", c.version, c.bump, c.expected, next)
+		}
+	}
+}
+
+func TestUpdateVersionFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "package.json")
+	os.WriteFile(path, []byte(`{"name": "app", "version": "1.2.3"}`), 0o644)
+
+	from, _ := ParseVersion("v1.2.3")
+	if err := UpdateVersionFile(path, from, from.Next(MinorBump)); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != `{"name": "app", "version": "1.3.0"}` {
+		t.Fatalf("unexpected contents %s", data)
+	}
+
+	if err := UpdateVersionFile(path, from, from.Next(PatchBump)); err == nil {
+		t.Fatal("expected an error for a file without the current version")
+	}
+}
+
+func TestUpdateVersionFileWholeVersions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "version.go")
+	os.WriteFile(path, []byte("1.2.3 11.2.3 1.2.30 v1.2.3, 1.2.3"), 0o644)
+
+	from, _ := ParseVersion("1.2.3")
+	if err := UpdateVersionFile(path, from, from.Next(MajorBump)); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "2.0.0 11.2.3 1.2.30 v2.0.0, 2.0.0" {
+		t.Fatalf("unexpected contents %s", data)
+	}
+}
+
+func TestUpdateVersionFileFirstRelease(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "package.json")
+	os.WriteFile(path, []byte(`{"version": "1.0.0", "engines": {"node": "20.1.0"}}`), 0o644)
+
+	first := Version{Prefix: "v"}
+	if err := UpdateVersionFile(path, first, first.Next(MinorBump)); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != `{"version": "0.1.0", "engines": {"node": "20.1.0"}}` {
+		t.Fatalf("unexpected contents %s", data)
+	}
+}
@@ -0,0 +1,70 @@
+package release
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// versionInText finds the first version-like x.y.z in a file, along with the characters around it.
+var versionInText = regexp.MustCompile(`(?:^|[^0-9.])(\d+\.\d+\.\d+)(?:[^0-9.]|$)`)
+
+// UpdateVersionFile rewrites every occurrence of the version from in the file at path with to, such as
+// the version in a package.json or a version.go constant. Versions are written without their v prefix,
+// as version files conventionally hold them, and only whole versions are replaced, so 1.2.3 leaves
+// 11.2.3 and 1.2.30 alone. Fails if the file doesn't mention from, since the file is then likely out of
+// sync with the tags, except on the first release, where from is 0.0.0 and the file's first version,
+// whatever it is, is replaced.
+func UpdateVersionFile(path string, from, to Version) error {
+	from.Prefix, to.Prefix = "", ""
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	updated, ok := replaceVersion(data, []byte(from.String()), []byte(to.String()))
+	if !ok && from == (Version{}) {
+		if match := versionInText.FindSubmatchIndex(data); match != nil {
+			updated = append(append(data[:match[2]:match[2]], to.String()...), data[match[3]:]...)
+			ok = true
+		}
+	}
+	if !ok {
+		return fmt.Errorf("%s does not contain version %s", path, from)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, updated, info.Mode().Perm())
+}
+
+// replaceVersion replaces every whole occurrence of from in data with to, one not preceded or followed
+// by a digit or a dot, and reports whether there was any.
+func replaceVersion(data, from, to []byte) ([]byte, bool) {
+	var out []byte
+	found := false
+	rest := data
+	for {
+		i := bytes.Index(rest, from)
+		if i < 0 {
+			break
+		}
+		end := i + len(from)
+		at := len(data) - len(rest) + i
+		if (at == 0 || !versionChar(data[at-1])) && (end == len(rest) || !versionChar(rest[end])) {
+			out = append(append(out, rest[:i]...), to...)
+			found = true
+		} else {
+			out = append(out, rest[:end]...)
+		}
+		rest = rest[end:]
+	}
+	return append(out, rest...), found
+}
+
+func versionChar(c byte) bool {
+	return c == '.' || '0' <= c && c <= '9'
+}
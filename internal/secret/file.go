@@ -0,0 +1,156 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const (
+	secretsFile = "secrets.enc"
+	keyFile     = "secrets.key"
+)
+
+// FileStore keeps secrets in an AES-GCM encrypted file, for machines without a usable keychain.
+//
+// The key lives in a separate file readable only by the user. This keeps secrets out of plaintext
+// config, backups of the secrets file, and accidental pastes, but does not protect against someone
+// who can already read the user's files. A new FileStore is created by calling [NewFileStore].
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore returns a store that keeps its files in dir.
+// An empty dir uses the plain directory under the user's config directory.
+func NewFileStore(dir string) (*FileStore, error) {
+	if dir == "" {
+		configDir, err := os.UserConfigDir()
+		if err != nil {
+			return nil, err
+		}
+		dir = filepath.Join(configDir, "plain")
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (s *FileStore) Name() string {
+	return "encrypted file (" + filepath.Join(s.dir, secretsFile) + ")"
+}
+
+func (s *FileStore) Get(service, account string) (string, error) {
+	secrets, err := s.load()
+	if err != nil {
+		return "", err
+	}
+
+	value, ok := secrets[service+"\x00"+account]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+func (s *FileStore) Set(service, account, secret string) error {
+	secrets, err := s.load()
+	if err != nil {
+		return err
+	}
+	secrets[service+"\x00"+account] = secret
+	return s.save(secrets)
+}
+
+func (s *FileStore) Delete(service, account string) error {
+	secrets, err := s.load()
+	if err != nil {
+		return err
+	}
+	delete(secrets, service+"\x00"+account)
+	return s.save(secrets)
+}
+
+func (s *FileStore) load() (map[string]string, error) {
+	secrets := map[string]string{}
+
+	sealed, err := os.ReadFile(filepath.Join(s.dir, secretsFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return secrets, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := s.cipher()
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("secret: secrets file is corrupt")
+	}
+
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("secret: failed to decrypt secrets file: %w", err)
+	}
+
+	if err := json.Unmarshal(plaintext, &secrets); err != nil {
+		return nil, fmt.Errorf("secret: secrets file is corrupt: %w", err)
+	}
+	return secrets, nil
+}
+
+func (s *FileStore) save(secrets map[string]string) error {
+	plaintext, err := json.Marshal(secrets)
+	if err != nil {
+		return err
+	}
+
+	gcm, err := s.cipher()
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	tmp := filepath.Join(s.dir, secretsFile+".tmp")
+	if err := os.WriteFile(tmp, gcm.Seal(nonce, nonce, plaintext, nil), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(s.dir, secretsFile))
+}
+
+// cipher loads the store's key, generating one the first time the store is used.
+func (s *FileStore) cipher() (cipher.AEAD, error) {
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	keyPath := filepath.Join(s.dir, keyFile)
+	key, err := os.ReadFile(keyPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(keyPath, key, 0o600); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("secret: invalid key in %s: %w", keyPath, err)
+	}
+	return cipher.NewGCM(block)
+}
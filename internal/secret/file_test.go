@@ -0,0 +1,50 @@
+package secret
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.Get("plain:github.com", "token"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound from an empty store, got %v", err)
+	}
+
+	if err := store.Set("plain:github.com", "token", "ghp_secretvalue"); err != nil {
+		t.Fatal(err)
+	}
+
+	sealed, err := os.ReadFile(filepath.Join(dir, secretsFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(sealed, []byte("ghp_secretvalue")) {
+		t.Fatal("secret was written to disk in plaintext")
+	}
+
+	// A second store over the same directory must be able to read what the first wrote.
+	reopened, _ := NewFileStore(dir)
+	value, err := reopened.Get("plain:github.com", "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != "ghp_secretvalue" {
+		t.Fatalf("expected stored secret, got %q", value)
+	}
+
+	if err := reopened.Delete("plain:github.com", "token"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reopened.Get("plain:github.com", "token"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after delete, got %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package secret
+
+import (
+	"bytes"
+	"errors"
+	"os/exec"
+	"strings"
+)
+
+// errItemNotFound is the exit status security uses when no matching keychain item exists.
+const errItemNotFound = 44
+
+// macKeychain keeps secrets in the user's login keychain through the security command.
+type macKeychain struct{}
+
+func keychain() (Store, bool) {
+	if _, err := exec.LookPath("security"); err != nil {
+		return nil, false
+	}
+	return macKeychain{}, true
+}
+
+func (macKeychain) Name() string {
+	return "macOS Keychain"
+}
+
+func (macKeychain) Get(service, account string) (string, error) {
+	output, err := exec.Command("security", "find-generic-password", "-s", service, "-a", account, "-w").Output()
+	if isExitCode(err, errItemNotFound) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(bytes.TrimSuffix(output, []byte("\n"))), nil
+}
+
+func (macKeychain) Set(service, account, secret string) error {
+	// -U updates the item in place if it already exists. Given last without a value, -w has security
+	// read the secret, and then the same again to confirm it, from its input, so that it never appears
+	// in the command line other users can see with ps.
+	cmd := exec.Command("security", "add-generic-password", "-U", "-s", service, "-a", account, "-w")
+	cmd.Stdin = strings.NewReader(secret + "\n" + secret + "\n")
+	return cmd.Run()
+}
+
+func (macKeychain) Delete(service, account string) error {
+	err := exec.Command("security", "delete-generic-password", "-s", service, "-a", account).Run()
+	if isExitCode(err, errItemNotFound) {
+		return nil
+	}
+	return err
+}
+
+func isExitCode(err error, code int) bool {
+	var exitErr *exec.ExitError
+	return errors.As(err, &exitErr) && exitErr.ExitCode() == code
+}
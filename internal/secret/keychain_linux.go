@@ -0,0 +1,50 @@
+package secret
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// libsecret keeps secrets in the desktop keyring (GNOME Keyring, KWallet) through the secret-tool command.
+type libsecret struct{}
+
+func keychain() (Store, bool) {
+	// Without a session bus, as on most servers and containers, secret-tool cannot reach a keyring.
+	if os.Getenv("DBUS_SESSION_BUS_ADDRESS") == "" {
+		return nil, false
+	}
+	if _, err := exec.LookPath("secret-tool"); err != nil {
+		return nil, false
+	}
+	return libsecret{}, true
+}
+
+func (libsecret) Name() string {
+	return "libsecret keyring"
+}
+
+func (libsecret) Get(service, account string) (string, error) {
+	output, err := exec.Command("secret-tool", "lookup", "service", service, "account", account).Output()
+
+	// secret-tool exits with status 1 and no output when nothing matches
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(output), nil
+}
+
+func (libsecret) Set(service, account, secret string) error {
+	cmd := exec.Command("secret-tool", "store", "--label", service+" "+account, "service", service, "account", account)
+	cmd.Stdin = strings.NewReader(secret)
+	return cmd.Run()
+}
+
+func (libsecret) Delete(service, account string) error {
+	return exec.Command("secret-tool", "clear", "service", service, "account", account).Run()
+}
@@ -0,0 +1,7 @@
+//go:build !darwin && !linux && !windows
+
+package secret
+
+func keychain() (Store, bool) {
+	return nil, false
+}
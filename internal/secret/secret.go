@@ -0,0 +1,82 @@
+package secret
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrNotFound       = errors.New("secret not found")
+	ErrNoKeychain     = errors.New("no OS keychain available")
+	ErrUnknownBackend = errors.New("unknown secret store backend")
+)
+
+// refPrefix marks a config value as a reference into the secret store rather than the value itself.
+const refPrefix = "secret:"
+
+// Store saves secrets such as forge tokens outside of plain's plaintext config.
+//
+// Secrets are addressed by a service (e.g. "plain:github.com") and an account within that service.
+// Backends exist for the macOS Keychain, the Windows Credential Manager, and libsecret, with an
+// encrypted file for systems that have none of them.
+type Store interface {
+	// Get returns the secret saved for service and account, or [ErrNotFound].
+	Get(service, account string) (string, error)
+
+	// Set saves secret for service and account, replacing any existing value.
+	Set(service, account, secret string) error
+
+	// Delete removes the secret for service and account. Deleting a missing secret is not an error.
+	Delete(service, account string) error
+
+	// Name describes where secrets are kept, for showing to the user.
+	Name() string
+}
+
+// Default returns the OS keychain when one is available, falling back to an encrypted file
+// in plain's config directory otherwise.
+func Default() (Store, error) {
+	return Open("auto")
+}
+
+// Open returns the store for the named backend: "keychain" for the OS keychain, "file" for the
+// encrypted file, or "auto" (or empty) to pick the keychain when available and the file otherwise.
+func Open(backend string) (Store, error) {
+	switch backend {
+	case "", "auto":
+		if store, ok := keychain(); ok {
+			return store, nil
+		}
+		return NewFileStore("")
+	case "keychain":
+		if store, ok := keychain(); ok {
+			return store, nil
+		}
+		return nil, ErrNoKeychain
+	case "file":
+		return NewFileStore("")
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownBackend, backend)
+	}
+}
+
+// Ref returns a config value that refers to the secret for service and account, in the form
+// secret:<service>/<account>. Config files hold the reference and [Resolve] fetches the secret.
+func Ref(service, account string) string {
+	return refPrefix + service + "/" + account
+}
+
+// Resolve returns the secret a config value refers to, or the value itself if it is not a reference.
+func Resolve(store Store, value string) (string, error) {
+	ref, ok := strings.CutPrefix(value, refPrefix)
+	if !ok {
+		return value, nil
+	}
+
+	i := strings.LastIndex(ref, "/")
+	if i <= 0 || i == len(ref)-1 {
+		return "", fmt.Errorf("secret: malformed reference %q, expected secret:<service>/<account>", value)
+	}
+	return store.Get(ref[:i], ref[i+1:])
+}
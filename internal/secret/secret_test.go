@@ -0,0 +1,33 @@
+package secret
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set("plain:gitlab.example.com", "token", "glpat-123")
+
+	value, err := Resolve(store, Ref("plain:gitlab.example.com", "token"))
+	if err != nil || value != "glpat-123" {
+		t.Fatalf("expected referenced secret, got %q (%v)", value, err)
+	}
+
+	if value, _ := Resolve(store, "plain-value"); value != "plain-value" {
+		t.Fatalf("expected non reference values to pass through, got %q", value)
+	}
+
+	if _, err := Resolve(store, "secret:missing"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected a malformed reference error, got %v", err)
+	}
+}
+
+func TestOpenUnknownBackend(t *testing.T) {
+	if _, err := Open("vault"); !errors.Is(err, ErrUnknownBackend) {
+		t.Fatalf("expected ErrUnknownBackend, got %v", err)
+	}
+}
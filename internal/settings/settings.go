@@ -0,0 +1,267 @@
+// Package settings reads the defaults plain's commands fall back on when they aren't given a flag: the
+// user's own in plain/config.toml under their config directory, a repository's in .plain.toml at the root
+// of its work tree, and PLAIN_ environment variables, each taking precedence over the ones before, so CI
+// and scripts can control plain without touching either file. Unlike .plain/team.toml, these are
+// preferences rather than rules, so flags override them, and so does git config over the files.
+package settings
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/sim-deos/plain/internal/display"
+	"github.com/sim-deos/plain/internal/forge"
+	"github.com/sim-deos/plain/internal/team"
+	"github.com/sim-deos/plain/internal/toml"
+)
+
+// RepoFile is where a repository keeps its settings, relative to the root of the work tree.
+const RepoFile = ".plain.toml"
+
+var ErrBadSettings = errors.New("bad settings")
+
+// Settings are plain's defaults. The zero Settings leaves everything to plain's built-in ones.
+type Settings struct {
+	Base           string            // The branch features start from and are finished into, instead of main
+	BranchTemplate string            // Shapes feature branch names when the team has no template of its own
+	MergeMethod    forge.MergeMethod // How plain done --auto-merge merges, instead of with a merge commit
+	Charset        string            // The glyphs to draw with, as git config plain.charset takes them
+	DateFormat     string            // How times are shown, as git config plain.dateFormat takes them
+	StaleAfter     string            // When plain list marks features stale, as git config plain.staleAfter takes it
+	TokenEnv       map[string]string // By forge host, the environment variable holding a token for it
+	Output         string            // text, json, or porcelain: how commands with --json and --porcelain print
+}
+
+// Parse decodes a settings file, naming file in errors. Unknown settings are errors, so that a
+// misspelling isn't quietly ignored.
+func Parse(data []byte, file string) (Settings, error) {
+	t, err := toml.Parse(data, file)
+	if err != nil {
+		return Settings{}, err
+	}
+	var s Settings
+	for name, value := range t {
+		section, ok := value.(toml.Table)
+		if !ok {
+			return Settings{}, fmt.Errorf("%w: %s isn't a section of %s", ErrBadSettings, name, file)
+		}
+		if name == "forge" {
+			if s.TokenEnv, err = parseForges(section, file); err != nil {
+				return Settings{}, err
+			}
+			continue
+		}
+		for key, value := range section {
+			setting := name + "." + key
+			str, ok := value.(string)
+			if !ok {
+				return Settings{}, fmt.Errorf("%w: %s in %s must be a string", ErrBadSettings, setting, file)
+			}
+			known, err := s.set(setting, str)
+			if !known {
+				return Settings{}, fmt.Errorf("%w: %s in %s isn't a setting plain knows", ErrBadSettings, setting, file)
+			}
+			if err != nil {
+				return Settings{}, fmt.Errorf("%w: %s in %s: %w", ErrBadSettings, setting, file, err)
+			}
+		}
+	}
+	return s, nil
+}
+
+// set sets the setting, named by its section and key as in branches.base, to value, reporting whether
+// it is one plain knows and whether value is wrong for it.
+func (s *Settings) set(setting, value string) (known bool, err error) {
+	switch setting {
+	case "branches.base":
+		s.Base = value
+	case "branches.template":
+		err = team.CheckTemplate(value)
+		s.BranchTemplate = value
+	case "done.merge-method":
+		s.MergeMethod, err = forge.ParseMergeMethod(value)
+	case "display.charset":
+		_, err = display.ParseCharset(value)
+		s.Charset = value
+	case "display.date-format":
+		_, err = display.ParseDateFormat(value)
+		s.DateFormat = value
+	case "list.stale-after":
+		s.StaleAfter = value
+	case "output.format":
+		if !slices.Contains([]string{"text", "json", "porcelain"}, value) {
+			err = fmt.Errorf("unknown output format %q, expected text, json, or porcelain", value)
+		}
+		s.Output = value
+	default:
+		return false, nil
+	}
+	return true, err
+}
+
+// envSettings are the environment variables that override settings, with the setting each overrides.
+var envSettings = []struct{ name, setting string }{
+	{"PLAIN_BASE_BRANCH", "branches.base"},
+	{"PLAIN_BRANCH_TEMPLATE", "branches.template"},
+	{"PLAIN_MERGE_METHOD", "done.merge-method"},
+	{"PLAIN_CHARSET", "display.charset"},
+	{"PLAIN_DATE_FORMAT", "display.date-format"},
+	{"PLAIN_STALE_AFTER", "list.stale-after"},
+	{"PLAIN_OUTPUT", "output.format"},
+}
+
+// Env returns the settings environment variables set, as getenv reads them: PLAIN_BASE_BRANCH for
+// branches.base, PLAIN_MERGE_METHOD for done.merge-method, and so on, with PLAIN_JSON=1 standing for
+// PLAIN_OUTPUT=json. Where forge tokens are found can't be set this way.
+func Env(getenv func(string) string) (Settings, error) {
+	var s Settings
+	if value := getenv("PLAIN_JSON"); value != "" {
+		on, err := strconv.ParseBool(value)
+		if err != nil {
+			return Settings{}, fmt.Errorf("%w: PLAIN_JSON %q isn't true or false", ErrBadSettings, value)
+		}
+		s.Output = map[bool]string{true: "json", false: "text"}[on]
+	}
+	for _, env := range envSettings {
+		if value := getenv(env.name); value != "" {
+			if _, err := s.set(env.setting, value); err != nil {
+				return Settings{}, fmt.Errorf("%w: %s: %w", ErrBadSettings, env.name, err)
+			}
+		}
+	}
+	return s, nil
+}
+
+// EnvName returns the environment variable that overrides git config key plain.<name>: PLAIN_ followed
+// by name in upper snake case, as PLAIN_GIT_TIMEOUT overrides plain.gitTimeout. Keys outside plain's
+// own section, or in subsections of it like plain.<host>.timeout, have none, and "" is returned.
+func EnvName(key string) string {
+	name, ok := strings.CutPrefix(key, "plain.")
+	if !ok || name == "" || strings.Contains(name, ".") {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("PLAIN_")
+	for i, r := range name {
+		if unicode.IsUpper(r) && i > 0 {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+// EnvOverride returns the value of the environment variable [EnvName] names for git config key, and
+// whether it is set, so it can be given to [git.SetConfigOverride].
+func EnvOverride(key string) (string, bool) {
+	name := EnvName(key)
+	if name == "" {
+		return "", false
+	}
+	value := os.Getenv(name)
+	return value, value != ""
+}
+
+// parseForges decodes the [forge."<host>"] sections, which say where to find each host's token.
+func parseForges(section toml.Table, file string) (map[string]string, error) {
+	env := map[string]string{}
+	for host, value := range section {
+		hostSection, ok := value.(toml.Table)
+		if !ok {
+			return nil, fmt.Errorf("%w: forge.%s in %s must be a section", ErrBadSettings, host, file)
+		}
+		for key, value := range hostSection {
+			name, ok := value.(string)
+			if key != "token-env" || !ok || name == "" {
+				return nil, fmt.Errorf("%w: forge.%s.%s in %s isn't a setting plain knows", ErrBadSettings, host, key, file)
+			}
+			env[host] = name
+		}
+	}
+	return env, nil
+}
+
+// Override returns s with every setting other sets replacing its own.
+func (s Settings) Override(other Settings) Settings {
+	for _, pair := range []struct{ dst, src *string }{
+		{&s.Base, &other.Base},
+		{&s.BranchTemplate, &other.BranchTemplate},
+		{&s.Charset, &other.Charset},
+		{&s.DateFormat, &other.DateFormat},
+		{&s.StaleAfter, &other.StaleAfter},
+		{&s.Output, &other.Output},
+	} {
+		if *pair.src != "" {
+			*pair.dst = *pair.src
+		}
+	}
+	if other.MergeMethod != "" {
+		s.MergeMethod = other.MergeMethod
+	}
+	if len(other.TokenEnv) > 0 {
+		env := maps.Clone(s.TokenEnv)
+		if env == nil {
+			env = map[string]string{}
+		}
+		maps.Copy(env, other.TokenEnv)
+		s.TokenEnv = env
+	}
+	return s
+}
+
+// UserFile returns where the user's settings are kept: plain/config.toml under their config directory,
+// which is $XDG_CONFIG_HOME, or ~/.config, on Linux.
+func UserFile() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "plain", "config.toml"), nil
+}
+
+// Load reads the user's settings, overrides them with those of the work tree at root, if it isn't "", and
+// those with the environment's, as [Env] reads them. Missing files are no settings. Where forge tokens
+// are found is only taken from the user's own settings, since anyone who can commit to a repository
+// could otherwise have a token sent elsewhere.
+func Load(root string) (Settings, error) {
+	var files []string
+	user, err := UserFile()
+	if err == nil {
+		files = append(files, user)
+	}
+	if root != "" {
+		files = append(files, filepath.Join(root, RepoFile))
+	}
+
+	var s Settings
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return Settings{}, err
+		}
+		found, err := Parse(data, file)
+		if err != nil {
+			return Settings{}, err
+		}
+		if file != user && found.TokenEnv != nil {
+			return Settings{}, fmt.Errorf("%w: [forge] sections belong in %s, not %s", ErrBadSettings, user, file)
+		}
+		s = s.Override(found)
+	}
+	env, err := Env(os.Getenv)
+	if err != nil {
+		return Settings{}, err
+	}
+	return s.Override(env), nil
+}
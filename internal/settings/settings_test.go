@@ -0,0 +1,177 @@
+package settings
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sim-deos/plain/internal/forge"
+	"github.com/sim-deos/plain/internal/toml"
+)
+
+const testSettings = `[branches]
+base = "develop"
+template = "{user}/{name}"
+
+[done]
+merge-method = "squash"
+
+[display]
+charset = "ascii"
+date-format = "relative"
+
+[list]
+stale-after = "2w"
+
+[forge."github.example.com"]
+token-env = "WORK_TOKEN"
+`
+
+func TestParse(t *testing.T) {
+	s, err := Parse([]byte(testSettings), "config.toml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Settings{
+		Base:           "develop",
+		BranchTemplate: "{user}/{name}",
+		MergeMethod:    forge.Squash,
+		Charset:        "ascii",
+		DateFormat:     "relative",
+		StaleAfter:     "2w",
+		TokenEnv:       map[string]string{"github.example.com": "WORK_TOKEN"},
+	}
+	if !reflect.DeepEqual(s, want) {
+		t.Errorf("Parse() = %+v, want %+v", s, want)
+	}
+
+	for _, data := range []string{
+		"base = \"develop\"",
+		"[branches]\nbse = \"develop\"",
+		"[branches]\nbase = 1",
+		"[branches]\ntemplate = \"feature/*\"",
+		"[done]\nmerge-method = \"octopus\"",
+		"[display]\ncharset = \"ebcdic\"",
+		"[forge]\ntoken-env = \"TOKEN\"",
+		"[forge.\"github.com\"]\ntoken = \"ghp_secret\"",
+	} {
+		if _, err := Parse([]byte(data), "config.toml"); !errors.Is(err, ErrBadSettings) {
+			t.Errorf("Parse(%q) = %v, want %v", data, err, ErrBadSettings)
+		}
+	}
+	if _, err := Parse([]byte("[branches"), "config.toml"); !errors.Is(err, toml.ErrSyntax) {
+		t.Errorf("Parse() of bad TOML = %v, want %v", err, toml.ErrSyntax)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	config := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", config)
+	t.Setenv("HOME", config)
+	root := t.TempDir()
+
+	if s, err := Load(root); err != nil || !reflect.DeepEqual(s, Settings{}) {
+		t.Errorf("Load() without settings = %+v, %v, want none", s, err)
+	}
+
+	user, err := UserFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(user), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(user, []byte(testSettings), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	repo := "[branches]\nbase = \"trunk\"\n[display]\ndate-format = \"iso\"\n"
+	if err := os.WriteFile(filepath.Join(root, RepoFile), []byte(repo), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Settings{
+		Base:           "trunk",
+		BranchTemplate: "{user}/{name}",
+		MergeMethod:    forge.Squash,
+		Charset:        "ascii",
+		DateFormat:     "iso",
+		StaleAfter:     "2w",
+		TokenEnv:       map[string]string{"github.example.com": "WORK_TOKEN"},
+	}
+	if !reflect.DeepEqual(s, want) {
+		t.Errorf("Load() = %+v, want the repository's settings over the user's %+v", s, want)
+	}
+	if s, err := Load(""); err != nil || s.Base != "develop" {
+		t.Errorf("Load() outside a repository = %+v, %v, want the user's settings", s, err)
+	}
+
+	// A repository can't choose where tokens come from.
+	repo += "[forge.\"github.com\"]\ntoken-env = \"AWS_SECRET_ACCESS_KEY\"\n"
+	if err := os.WriteFile(filepath.Join(root, RepoFile), []byte(repo), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(root); !errors.Is(err, ErrBadSettings) {
+		t.Errorf("Load() of a repository naming a token variable = %v, want %v", err, ErrBadSettings)
+	}
+}
+
+func TestEnv(t *testing.T) {
+	env := map[string]string{
+		"PLAIN_BASE_BRANCH":  "trunk",
+		"PLAIN_MERGE_METHOD": "rebase",
+		"PLAIN_CHARSET":      "unicode",
+		"PLAIN_STALE_AFTER":  "10d",
+		"PLAIN_JSON":         "1",
+	}
+	s, err := Env(func(name string) string { return env[name] })
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Settings{Base: "trunk", MergeMethod: forge.Rebase, Charset: "unicode", StaleAfter: "10d", Output: "json"}
+	if !reflect.DeepEqual(s, want) {
+		t.Errorf("Env() = %+v, want %+v", s, want)
+	}
+
+	// PLAIN_OUTPUT is the more specific of the two.
+	env = map[string]string{"PLAIN_JSON": "true", "PLAIN_OUTPUT": "porcelain"}
+	if s, err := Env(func(name string) string { return env[name] }); err != nil || s.Output != "porcelain" {
+		t.Errorf("Env() with PLAIN_JSON and PLAIN_OUTPUT = %+v, %v, want porcelain output", s, err)
+	}
+
+	for name, value := range map[string]string{"PLAIN_JSON": "maybe", "PLAIN_OUTPUT": "xml", "PLAIN_BRANCH_TEMPLATE": "{team}/{name}"} {
+		if _, err := Env(func(n string) string { return map[string]string{name: value}[n] }); !errors.Is(err, ErrBadSettings) {
+			t.Errorf("Env() with %s=%s = %v, want %v", name, value, err, ErrBadSettings)
+		}
+	}
+
+	// The environment comes before either file.
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, RepoFile), []byte("[branches]\nbase = \"develop\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PLAIN_BASE_BRANCH", "trunk")
+	if s, err := Load(root); err != nil || s.Base != "trunk" {
+		t.Errorf("Load() with PLAIN_BASE_BRANCH set = %+v, %v, want its base", s, err)
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	for key, want := range map[string]string{
+		"plain.gitTimeout":         "PLAIN_GIT_TIMEOUT",
+		"plain.charset":            "PLAIN_CHARSET",
+		"plain.dateFormat":         "PLAIN_DATE_FORMAT",
+		"plain.github.com.timeout": "",
+		"core.editor":              "",
+		"plain.":                   "",
+	} {
+		if got := EnvName(key); got != want {
+			t.Errorf("EnvName(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+// Package team reads the policy a team commits to its repository in .plain/team.toml, so that everyone
+// who clones it gets the same merge strategy, branch names, protected branches, and required checks.
+// The user's own git config can add to the policy but never relax it.
+package team
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/sim-deos/plain/internal/forge"
+	"github.com/sim-deos/plain/internal/git"
+	"github.com/sim-deos/plain/internal/toml"
+)
+
+// File is where the policy is kept, relative to the root of the work tree.
+const File = ".plain/team.toml"
+
+// The git config keys with which a user tightens the policy for themselves. The last two may be given
+// more than once.
+const (
+	StrategyKey  = "plain.mergeStrategy"
+	TemplateKey  = "plain.branchTemplate"
+	ProtectedKey = "plain.protectedBranch"
+	CheckKey     = "plain.requiredCheck"
+)
+
+var (
+	ErrBadPolicy = errors.New("bad team policy")
+	ErrNoEmail   = errors.New("the branch template uses {user}, but user.email isn't set")
+)
+
+// Policy is how a team works with its repository. The zero Policy leaves everything to plain's defaults.
+type Policy struct {
+	MergeStrategy  forge.MergeMethod // How features are merged, or empty to leave it to the user
+	BranchTemplate string            // Shapes feature branch names, with {name} and {user} placeholders
+	Protected      []string          // Branches, or path.Match patterns, that only take pull requests
+	RequiredChecks []string          // Checks that must pass before a feature is merged, by name
+}
+
+// Parse decodes a policy file. Unknown sections and settings are errors, so that a misspelling doesn't
+// quietly leave a rule out.
+func Parse(data []byte) (Policy, error) {
+	t, err := toml.Parse(data, File)
+	if err != nil {
+		return Policy{}, err
+	}
+	var p Policy
+	for name, value := range t {
+		section, ok := value.(toml.Table)
+		if !ok {
+			return Policy{}, fmt.Errorf("%w: %s isn't a section of %s", ErrBadPolicy, name, File)
+		}
+		for key, value := range section {
+			setting := name + "." + key
+			switch setting {
+			case "merge.strategy":
+				s, err := stringValue(setting, value)
+				if err != nil {
+					return Policy{}, err
+				}
+				if p.MergeStrategy, err = forge.ParseMergeMethod(s); err != nil {
+					return Policy{}, fmt.Errorf("%w: %s: %w", ErrBadPolicy, setting, err)
+				}
+			case "branches.template":
+				if p.BranchTemplate, err = stringValue(setting, value); err != nil {
+					return Policy{}, err
+				}
+				if err := CheckTemplate(p.BranchTemplate); err != nil {
+					return Policy{}, fmt.Errorf("%w: %s: %w", ErrBadPolicy, setting, err)
+				}
+			case "branches.protected":
+				if p.Protected, err = stringsValue(setting, value); err != nil {
+					return Policy{}, err
+				}
+			case "checks.required":
+				if p.RequiredChecks, err = stringsValue(setting, value); err != nil {
+					return Policy{}, err
+				}
+			default:
+				return Policy{}, fmt.Errorf("%w: %s in %s isn't a setting plain knows", ErrBadPolicy, setting, File)
+			}
+		}
+	}
+	return p, nil
+}
+
+func stringValue(setting string, value any) (string, error) {
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("%w: %s must be a string", ErrBadPolicy, setting)
+	}
+	return s, nil
+}
+
+func stringsValue(setting string, value any) ([]string, error) {
+	values, ok := value.([]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s must be an array of strings", ErrBadPolicy, setting)
+	}
+	strs := make([]string, len(values))
+	for i, v := range values {
+		if strs[i], ok = v.(string); !ok {
+			return nil, fmt.Errorf("%w: %s must be an array of strings", ErrBadPolicy, setting)
+		}
+	}
+	return strs, nil
+}
+
+// CheckTemplate checks that a branch template names the feature once and uses no unknown placeholders.
+func CheckTemplate(template string) error {
+	if strings.Count(template, "{name}") != 1 {
+		return errors.New("the template must contain {name} once")
+	}
+	rest := strings.NewReplacer("{name}", "", "{user}", "").Replace(template)
+	if strings.ContainsAny(rest, "{}") {
+		return fmt.Errorf("%q has a placeholder other than {name} and {user}", template)
+	}
+	return nil
+}
+
+// Read reads the policy committed to the work tree at root. A work tree without one has the zero Policy.
+func Read(root string) (Policy, error) {
+	if root == "" {
+		return Policy{}, nil
+	}
+	data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(File)))
+	if errors.Is(err, fs.ErrNotExist) {
+		return Policy{}, nil
+	}
+	if err != nil {
+		return Policy{}, err
+	}
+	return Parse(data)
+}
+
+// Load reads the repository's policy and tightens it with the user's git config. It also returns a
+// warning for each user setting ignored because it would relax the team's policy.
+func Load(repo *git.Repository) (Policy, []string, error) {
+	p, err := Read(repo.WorkTree)
+	if err != nil {
+		return Policy{}, nil, err
+	}
+	config, err := repo.Config()
+	if err != nil {
+		return Policy{}, nil, err
+	}
+
+	user := Policy{
+		Protected:      config.GetAll(ProtectedKey),
+		RequiredChecks: config.GetAll(CheckKey),
+	}
+	if s, ok := config.Get(StrategyKey); ok && s != "" {
+		if user.MergeStrategy, err = forge.ParseMergeMethod(s); err != nil {
+			return Policy{}, nil, fmt.Errorf("bad %s: %w", StrategyKey, err)
+		}
+	}
+	if user.BranchTemplate, _ = config.Get(TemplateKey); user.BranchTemplate != "" {
+		if err := CheckTemplate(user.BranchTemplate); err != nil {
+			return Policy{}, nil, fmt.Errorf("bad %s: %w", TemplateKey, err)
+		}
+	}
+	p, warnings := p.Tighten(user)
+	return p, warnings, nil
+}
+
+// Tighten returns p with the rules of user added: more protected branches and required checks, and a
+// stricter merge strategy. user's branch template applies only when p has none. Settings of user that
+// would relax p are left out and described in the warnings returned.
+func (p Policy) Tighten(user Policy) (Policy, []string) {
+	var warnings []string
+	switch {
+	case user.MergeStrategy == "":
+	case strictness(user.MergeStrategy) >= strictness(p.MergeStrategy):
+		p.MergeStrategy = user.MergeStrategy
+	default:
+		warnings = append(warnings, fmt.Sprintf("ignoring %s %s, since %s merges with %s", StrategyKey, user.MergeStrategy, File, p.MergeStrategy))
+	}
+	switch {
+	case user.BranchTemplate == "" || user.BranchTemplate == p.BranchTemplate:
+	case p.BranchTemplate == "":
+		p.BranchTemplate = user.BranchTemplate
+	default:
+		warnings = append(warnings, fmt.Sprintf("ignoring %s %s, since %s names branches %s", TemplateKey, user.BranchTemplate, File, p.BranchTemplate))
+	}
+	p.Protected = union(p.Protected, user.Protected)
+	p.RequiredChecks = union(p.RequiredChecks, user.RequiredChecks)
+	return p, warnings
+}
+
+// strictness ranks merge strategies by how much they constrain history: merge commits allow anything,
+// rebasing keeps history linear, and squashing also leaves a single commit per feature.
+func strictness(method forge.MergeMethod) int {
+	return slices.Index([]forge.MergeMethod{"", forge.MergeCommit, forge.Rebase, forge.Squash}, method)
+}
+
+func union(a, b []string) []string {
+	all := slices.Clone(a)
+	for _, s := range b {
+		if !slices.Contains(all, s) {
+			all = append(all, s)
+		}
+	}
+	return all
+}
+
+// Allows reports whether features may be merged with method, which must be at least as strict as the
+// policy's merge strategy.
+func (p Policy) Allows(method forge.MergeMethod) bool {
+	return strictness(method) >= strictness(p.MergeStrategy)
+}
+
+// IsProtected reports whether branch matches one of the protected branches.
+func (p Policy) IsProtected(branch string) bool {
+	for _, pattern := range p.Protected {
+		if ok, _ := path.Match(pattern, branch); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Rules returns the forge's protection rules for branch with the policy's added: a protected branch
+// only takes pull requests, the required checks are required, and a strategy other than merge commits
+// keeps history linear.
+func (p Policy) Rules(branch string, rules forge.BranchProtection) forge.BranchProtection {
+	if p.IsProtected(branch) {
+		rules.Protected = true
+		rules.PullRequestsOnly = true
+	}
+	rules.RequiredChecks = union(rules.RequiredChecks, p.RequiredChecks)
+	if strictness(p.MergeStrategy) > strictness(forge.MergeCommit) {
+		rules.LinearHistory = true
+	}
+	return rules
+}
+
+// FeatureName returns the branch for the feature called name, following the branch template with {user}
+// standing for the part of email before the @. A name that already fits the template is kept as is.
+func (p Policy) FeatureName(name, email string) (string, error) {
+	if p.BranchTemplate == "" {
+		return name, nil
+	}
+	template := p.BranchTemplate
+	if strings.Contains(template, "{user}") {
+		user, _, _ := strings.Cut(strings.ToLower(email), "@")
+		if user == "" {
+			return "", ErrNoEmail
+		}
+		template = strings.ReplaceAll(template, "{user}", user)
+	}
+	before, after, _ := strings.Cut(template, "{name}")
+	if len(name) > len(before)+len(after) && strings.HasPrefix(name, before) && strings.HasSuffix(name, after) {
+		return name, nil
+	}
+	return before + name + after, nil
+}
@@ -0,0 +1,127 @@
+package team
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/sim-deos/plain/internal/forge"
+	"github.com/sim-deos/plain/internal/toml"
+)
+
+const testPolicy = `[merge]
+strategy = "rebase"
+
+[branches]
+template = "{user}/{name}"
+protected = ["main", "release/*"]
+
+[checks]
+required = ["build"]
+`
+
+func TestParse(t *testing.T) {
+	p, err := Parse([]byte(testPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Policy{
+		MergeStrategy:  forge.Rebase,
+		BranchTemplate: "{user}/{name}",
+		Protected:      []string{"main", "release/*"},
+		RequiredChecks: []string{"build"},
+	}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("Parse() = %+v, want %+v", p, want)
+	}
+
+	for _, data := range []string{
+		"strategy = \"rebase\"",
+		"[merge]\nstrategy = \"octopus\"",
+		"[merge]\nmethod = \"rebase\"",
+		"[branches]\ntemplate = \"feature/*\"",
+		"[branches]\ntemplate = \"{team}/{name}\"",
+		"[branches]\nprotected = \"main\"",
+		"[checks]\nrequired = [1]",
+	} {
+		if _, err := Parse([]byte(data)); !errors.Is(err, ErrBadPolicy) {
+			t.Errorf("Parse(%q) = %v, want %v", data, err, ErrBadPolicy)
+		}
+	}
+	if _, err := Parse([]byte("[merge")); !errors.Is(err, toml.ErrSyntax) {
+		t.Errorf("Parse() of bad TOML = %v, want %v", err, toml.ErrSyntax)
+	}
+}
+
+func TestRead(t *testing.T) {
+	root := t.TempDir()
+	if p, err := Read(root); err != nil || !reflect.DeepEqual(p, Policy{}) {
+		t.Errorf("Read() without a policy = %+v, %v, want the zero Policy", p, err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, ".plain"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, ".plain", "team.toml"), []byte(testPolicy), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if p, err := Read(root); err != nil || p.MergeStrategy != forge.Rebase {
+		t.Errorf("Read() = %+v, %v, want the committed policy", p, err)
+	}
+}
+
+func TestTighten(t *testing.T) {
+	team := Policy{MergeStrategy: forge.Rebase, BranchTemplate: "{user}/{name}", Protected: []string{"main"}, RequiredChecks: []string{"build"}}
+
+	got, warnings := team.Tighten(Policy{MergeStrategy: forge.Squash, Protected: []string{"main", "develop"}, RequiredChecks: []string{"lint"}})
+	want := Policy{MergeStrategy: forge.Squash, BranchTemplate: "{user}/{name}", Protected: []string{"main", "develop"}, RequiredChecks: []string{"build", "lint"}}
+	if !reflect.DeepEqual(got, want) || len(warnings) != 0 {
+		t.Errorf("Tighten() = %+v, %q, want %+v", got, warnings, want)
+	}
+
+	got, warnings = team.Tighten(Policy{MergeStrategy: forge.MergeCommit, BranchTemplate: "{name}"})
+	if got.MergeStrategy != forge.Rebase || got.BranchTemplate != "{user}/{name}" || len(warnings) != 2 {
+		t.Errorf("Tighten() relaxing the policy = %+v, %q, want the team's settings kept with two warnings", got, warnings)
+	}
+
+	if got, _ := (Policy{}).Tighten(Policy{MergeStrategy: forge.MergeCommit, BranchTemplate: "me/{name}"}); got.MergeStrategy != forge.MergeCommit || got.BranchTemplate != "me/{name}" {
+		t.Errorf("Tighten() of no policy = %+v, want the user's settings", got)
+	}
+}
+
+func TestRules(t *testing.T) {
+	p := Policy{MergeStrategy: forge.Squash, Protected: []string{"main", "release/*"}, RequiredChecks: []string{"build"}}
+	for _, branch := range []string{"main", "release/1.0"} {
+		if rules := p.Rules(branch, forge.BranchProtection{RequiredChecks: []string{"lint"}}); !rules.PullRequestsOnly || !rules.LinearHistory || !slices.Equal(rules.RequiredChecks, []string{"lint", "build"}) {
+			t.Errorf("Rules(%q) = %+v, want pull requests only, linear history, and both checks", branch, rules)
+		}
+	}
+	if rules := p.Rules("develop", forge.BranchProtection{}); rules.PullRequestsOnly || p.IsProtected("release/1.0/rc") {
+		t.Errorf("Rules(develop) = %+v, want it unprotected", rules)
+	}
+	if !p.Allows(forge.Squash) || p.Allows(forge.Rebase) || !(Policy{}).Allows(forge.MergeCommit) {
+		t.Error("Allows() = a strategy looser than the policy's allowed, or the policy's own refused")
+	}
+}
+
+func TestFeatureName(t *testing.T) {
+	p := Policy{BranchTemplate: "{user}/{name}"}
+	cases := []struct{ name, want string }{
+		{"login", "ann/login"},
+		{"ann/login", "ann/login"},
+		{"bob/login", "ann/bob/login"},
+	}
+	for _, c := range cases {
+		if got, err := p.FeatureName(c.name, "Ann@example.com"); err != nil || got != c.want {
+			t.Errorf("FeatureName(%q) = %q, %v, want %q", c.name, got, err, c.want)
+		}
+	}
+	if _, err := p.FeatureName("login", ""); !errors.Is(err, ErrNoEmail) {
+		t.Errorf("FeatureName() without an email = %v, want %v", err, ErrNoEmail)
+	}
+	if got, _ := (Policy{}).FeatureName("login", ""); got != "login" {
+		t.Errorf("FeatureName() without a template = %q, want the name as is", got)
+	}
+}
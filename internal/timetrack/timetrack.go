@@ -0,0 +1,153 @@
+package timetrack
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	sessionFile = "session.json"
+	eventsFile  = "events.jsonl"
+
+	// DefaultIdle is how long a session survives without a checkpoint before the next one starts a new session.
+	DefaultIdle = 30 * time.Minute
+)
+
+var ErrUnknownMode = errors.New("unknown time tracking mode")
+
+// Mode is a set of flags for where checkpoint tracking data is recorded. The zero Mode records nothing.
+type Mode int
+
+const (
+	RecordTrailers Mode = 1 << iota // Trailers are added to checkpoint commit messages
+	RecordEvents                    // Events are appended to the event log
+	RecordNotes                     // Trailers are kept in git notes on refs/notes/plain instead of messages
+
+	Off  Mode = 0
+	Both      = RecordTrailers | RecordEvents
+)
+
+// ParseMode parses a plain.timeTracking value: off, trailers, events, notes, or both. Git's boolean spellings
+// are accepted too, with true meaning both.
+func ParseMode(value string) (Mode, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "off", "false", "no", "0":
+		return Off, nil
+	case "trailers":
+		return RecordTrailers, nil
+	case "events":
+		return RecordEvents, nil
+	case "notes":
+		return RecordNotes, nil
+	case "both", "on", "true", "yes", "1":
+		return Both, nil
+	default:
+		return Off, fmt.Errorf("%w %q, expected off, trailers, events, notes, or both", ErrUnknownMode, value)
+	}
+}
+
+// Session is a stretch of work made up of checkpoints no more than the idle timeout apart.
+type Session struct {
+	ID           string    `json:"id"`
+	Started      time.Time `json:"started"`
+	LastActivity time.Time `json:"last_activity"`
+}
+
+// Event is one checkpoint as recorded in the event log.
+type Event struct {
+	Time           time.Time `json:"time"`
+	Kind           string    `json:"event"` // Always "checkpoint" for now
+	Session        string    `json:"session"`
+	SessionStarted time.Time `json:"session_started"`
+	Issue          string    `json:"issue,omitempty"` // The issue the branch is linked to, e.g. PROJ-42
+	Branch         string    `json:"branch"`
+	Commit         string    `json:"commit"`
+}
+
+// Tracker links checkpoints to work sessions so time tracking tools can attribute time to issues.
+//
+// Checkpoints are recorded as JSON lines in an event log, for scripts that feed Toggl, Tempo, and similar
+// tools. State is kept per worktree, in a directory inside its git directory. A new Tracker is created
+// by calling [NewTracker].
+type Tracker struct {
+	Dir  string        // Where the session and event log are kept
+	Idle time.Duration // How long a session can go without a checkpoint
+}
+
+// NewTracker returns a Tracker keeping its state in gitDir/plain.
+func NewTracker(gitDir string) *Tracker {
+	return &Tracker{Dir: filepath.Join(gitDir, "plain"), Idle: DefaultIdle}
+}
+
+// Session returns the session a checkpoint made at now belongs to, continuing the current session if it
+// was active within the idle timeout and starting a new one otherwise. The session's last activity is
+// moved to now.
+func (t *Tracker) Session(now time.Time) (Session, error) {
+	var session Session
+	data, err := os.ReadFile(filepath.Join(t.Dir, sessionFile))
+	if err == nil {
+		err = json.Unmarshal(data, &session)
+	}
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return Session{}, fmt.Errorf("timetrack: failed to read session: %w", err)
+	}
+
+	if session.ID == "" || now.Sub(session.LastActivity) > t.Idle {
+		id := make([]byte, 8)
+		if _, err := rand.Read(id); err != nil {
+			return Session{}, err
+		}
+		session = Session{ID: hex.EncodeToString(id), Started: now}
+	}
+	session.LastActivity = now
+
+	data, err = json.Marshal(session)
+	if err != nil {
+		return Session{}, err
+	}
+	if err := os.MkdirAll(t.Dir, 0o755); err != nil {
+		return Session{}, err
+	}
+	if err := os.WriteFile(filepath.Join(t.Dir, sessionFile), data, 0o644); err != nil {
+		return Session{}, err
+	}
+	return session, nil
+}
+
+// Record appends event to the event log as a single JSON line.
+func (t *Tracker) Record(event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(t.Dir, 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(filepath.Join(t.Dir, eventsFile), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Trailers returns the commit trailers linking a checkpoint to session and issue. Issue may be empty.
+func Trailers(session Session, issue string) []string {
+	trailers := []string{"Plain-Session: " + session.ID}
+	if issue != "" {
+		trailers = append(trailers, "Plain-Issue: "+issue)
+	}
+	return trailers
+}
@@ -0,0 +1,76 @@
+package timetrack
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseMode(t *testing.T) {
+	cases := map[string]Mode{"": Off, "false": Off, "trailers": RecordTrailers, "Events": RecordEvents, "notes": RecordNotes, "true": Both}
+	for value, expected := range cases {
+		if mode, err := ParseMode(value); err != nil || mode != expected {
+			t.Errorf("ParseMode(%q) = %d, %v, expected %d", value, mode, err, expected)
+		}
+	}
+	if _, err := ParseMode("sometimes"); !errors.Is(err, ErrUnknownMode) {
+		t.Fatalf("expected ErrUnknownMode, got %v", err)
+	}
+}
+
+func TestSessionIdle(t *testing.T) {
+	tracker := NewTracker(t.TempDir())
+	start := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	first, err := tracker.Session(start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := tracker.Session(start.Add(20 * time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.ID != first.ID || !second.Started.Equal(start) {
+		t.Fatalf("expected the session to continue, got %+v after %+v", second, first)
+	}
+
+	third, err := tracker.Session(start.Add(20*time.Minute + DefaultIdle + time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third.ID == first.ID {
+		t.Fatal("expected a new session after the idle timeout")
+	}
+}
+
+func TestRecord(t *testing.T) {
+	tracker := NewTracker(t.TempDir())
+	for _, commit := range []string{"a1", "b2"} {
+		if err := tracker.Record(Event{Kind: "checkpoint", Session: "s", Commit: commit}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := os.Open(filepath.Join(tracker.Dir, eventsFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var commits []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatal(err)
+		}
+		commits = append(commits, event.Commit)
+	}
+	if len(commits) != 2 || commits[0] != "a1" || commits[1] != "b2" {
+		t.Fatalf("expected one line per event in order, got %v", commits)
+	}
+}
@@ -0,0 +1,325 @@
+// Package toml decodes the part of TOML that plain's settings files use: tables, dotted and quoted keys,
+// strings, integers, floats, booleans, and arrays of them. Multi-line strings, inline tables, arrays of
+// tables, and dates are rejected rather than misread.
+package toml
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var ErrSyntax = errors.New("toml: syntax error")
+
+// Table is a decoded TOML table. Values are strings, int64s, float64s, bools, []any arrays of them, and
+// nested Tables.
+type Table map[string]any
+
+// Parse decodes data, naming file in errors.
+func Parse(data []byte, file string) (Table, error) {
+	p := &parser{s: string(data), file: file, line: 1}
+	root := Table{}
+	current := root
+	defined := map[string]bool{} // Tables given a [header] of their own, which may only appear once
+	for {
+		p.skipBlank()
+		if p.done() {
+			return root, nil
+		}
+		if p.peek() == '[' {
+			p.pos++
+			if p.peek() == '[' {
+				return nil, p.errorf("arrays of tables aren't supported")
+			}
+			p.skipSpace()
+			keys, err := p.key()
+			if err != nil {
+				return nil, err
+			}
+			p.skipSpace()
+			if !p.consume(']') {
+				return nil, p.errorf("expected ] after the table name")
+			}
+			name := strings.Join(keys, ".")
+			if defined[name] {
+				return nil, p.errorf("table %s is defined twice", name)
+			}
+			defined[name] = true
+			if current, err = p.table(root, keys); err != nil {
+				return nil, err
+			}
+		} else {
+			keys, err := p.key()
+			if err != nil {
+				return nil, err
+			}
+			p.skipSpace()
+			if !p.consume('=') {
+				return nil, p.errorf("expected = after %s", strings.Join(keys, "."))
+			}
+			p.skipSpace()
+			value, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			parent, err := p.table(current, keys[:len(keys)-1])
+			if err != nil {
+				return nil, err
+			}
+			last := keys[len(keys)-1]
+			if _, ok := parent[last]; ok {
+				return nil, p.errorf("%s is set twice", strings.Join(keys, "."))
+			}
+			parent[last] = value
+		}
+		if err := p.endLine(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+type parser struct {
+	s    string
+	pos  int
+	file string
+	line int
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return fmt.Errorf("%w in %s line %d: %s", ErrSyntax, p.file, p.line, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) done() bool { return p.pos >= len(p.s) }
+
+func (p *parser) peek() byte {
+	if p.done() {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+func (p *parser) consume(c byte) bool {
+	if p.peek() == c && !p.done() {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) skipSpace() {
+	for p.peek() == ' ' || p.peek() == '\t' {
+		p.pos++
+	}
+}
+
+// skipBlank skips whitespace, newlines, and comments.
+func (p *parser) skipBlank() {
+	for !p.done() {
+		switch p.peek() {
+		case ' ', '\t', '\r':
+			p.pos++
+		case '\n':
+			p.pos++
+			p.line++
+		case '#':
+			for !p.done() && p.peek() != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// endLine expects nothing but a comment before the end of the line.
+func (p *parser) endLine() error {
+	p.skipSpace()
+	if p.peek() == '#' {
+		for !p.done() && p.peek() != '\n' {
+			p.pos++
+		}
+	}
+	p.consume('\r')
+	if !p.done() && !p.consume('\n') {
+		return p.errorf("unexpected %q", p.peek())
+	}
+	p.line++
+	return nil
+}
+
+// table returns the table keys name below t, creating it as needed.
+func (p *parser) table(t Table, keys []string) (Table, error) {
+	for i, key := range keys {
+		switch next := t[key].(type) {
+		case nil:
+			created := Table{}
+			t[key] = created
+			t = created
+		case Table:
+			t = next
+		default:
+			return nil, p.errorf("%s is a value, not a table", strings.Join(keys[:i+1], "."))
+		}
+	}
+	return t, nil
+}
+
+// key reads a key, which may be dotted, with each part bare or quoted.
+func (p *parser) key() ([]string, error) {
+	var keys []string
+	for {
+		p.skipSpace()
+		var key string
+		switch c := p.peek(); {
+		case c == '"' || c == '\'':
+			s, err := p.str()
+			if err != nil {
+				return nil, err
+			}
+			key = s
+		default:
+			start := p.pos
+			for isBareKey(p.peek()) && !p.done() {
+				p.pos++
+			}
+			if start == p.pos {
+				return nil, p.errorf("expected a key")
+			}
+			key = p.s[start:p.pos]
+		}
+		keys = append(keys, key)
+		p.skipSpace()
+		if !p.consume('.') {
+			return keys, nil
+		}
+	}
+}
+
+func isBareKey(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+func (p *parser) value() (any, error) {
+	switch c := p.peek(); {
+	case c == '"' || c == '\'':
+		return p.str()
+	case c == '[':
+		return p.array()
+	case c == '{':
+		return nil, p.errorf("inline tables aren't supported")
+	}
+
+	start := p.pos
+	for !p.done() && !strings.ContainsRune(" \t\r\n#,]", rune(p.peek())) {
+		p.pos++
+	}
+	word := p.s[start:p.pos]
+	switch word {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "":
+		return nil, p.errorf("expected a value")
+	}
+	digits := strings.ReplaceAll(word, "_", "")
+	if n, err := strconv.ParseInt(digits, 0, 64); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(digits, 64); err == nil && !strings.ContainsAny(digits, "xXpP") {
+		return f, nil
+	}
+	return nil, p.errorf("%q isn't a value plain understands", word)
+}
+
+// array reads an array, which may span lines and end with a comma.
+func (p *parser) array() ([]any, error) {
+	p.pos++
+	values := []any{}
+	for {
+		p.skipBlank()
+		if p.consume(']') {
+			return values, nil
+		}
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+		p.skipBlank()
+		if p.consume(']') {
+			return values, nil
+		}
+		if !p.consume(',') {
+			return nil, p.errorf("expected , or ] in an array")
+		}
+	}
+}
+
+// str reads a basic string in double quotes, with escapes, or a literal string in single quotes.
+func (p *parser) str() (string, error) {
+	quote := p.s[p.pos]
+	if strings.HasPrefix(p.s[p.pos:], strings.Repeat(string(quote), 3)) {
+		return "", p.errorf("multi-line strings aren't supported")
+	}
+	p.pos++
+	var b strings.Builder
+	for {
+		if p.done() || p.peek() == '\n' {
+			return "", p.errorf("unterminated string")
+		}
+		c := p.s[p.pos]
+		p.pos++
+		switch {
+		case c == quote:
+			return b.String(), nil
+		case c == '\\' && quote == '"':
+			r, err := p.escape()
+			if err != nil {
+				return "", err
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteByte(c)
+		}
+	}
+}
+
+func (p *parser) escape() (rune, error) {
+	if p.done() {
+		return 0, p.errorf("unterminated string")
+	}
+	c := p.s[p.pos]
+	p.pos++
+	switch c {
+	case 'b':
+		return '\b', nil
+	case 't':
+		return '\t', nil
+	case 'n':
+		return '\n', nil
+	case 'f':
+		return '\f', nil
+	case 'r':
+		return '\r', nil
+	case '"', '\\':
+		return rune(c), nil
+	case 'u', 'U':
+		size := 4
+		if c == 'U' {
+			size = 8
+		}
+		if p.pos+size > len(p.s) {
+			return 0, p.errorf("short \\%c escape", c)
+		}
+		n, err := strconv.ParseUint(p.s[p.pos:p.pos+size], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(n)) {
+			return 0, p.errorf("bad \\%c escape", c)
+		}
+		p.pos += size
+		return rune(n), nil
+	}
+	return 0, p.errorf("unknown escape \\%c", c)
+}
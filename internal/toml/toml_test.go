@@ -0,0 +1,67 @@
+package toml
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	data := `# A comment
+title = "plain"   # after a value
+"quoted key" = 'C:\literal'
+
+[merge]
+strategy = "squash"
+retries = 1_000
+ratio = 0.5
+enabled = true
+
+[branches.release]
+patterns = [
+	"release/*", # one per line
+	"hotfix/\u00e9",
+]
+nested.key = -3
+empty = []
+`
+	got, err := Parse([]byte(data), "test.toml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Table{
+		"title":      "plain",
+		"quoted key": `C:\literal`,
+		"merge":      Table{"strategy": "squash", "retries": int64(1000), "ratio": 0.5, "enabled": true},
+		"branches": Table{"release": Table{
+			"patterns": []any{"release/*", "hotfix/é"},
+			"nested":   Table{"key": int64(-3)},
+			"empty":    []any{},
+		}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse() = %#v, want %#v", got, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, data := range []string{
+		"key",
+		"key = ",
+		"key = \"unterminated\n",
+		"key = 1\nkey = 2",
+		"[a]\n[a]",
+		"a = 1\n[a]",
+		"[[tables]]",
+		"key = { inline = true }",
+		"key = \"\"\"multi\"\"\"",
+		"key = 1979-05-27",
+		"key = [1 2]",
+		"key = \"bad \\q escape\"",
+		"key = 1 trailing",
+	} {
+		if got, err := Parse([]byte(data), "test.toml"); !errors.Is(err, ErrSyntax) {
+			t.Errorf("Parse(%q) = %v, %v, want %v", data, got, err, ErrSyntax)
+		}
+	}
+}
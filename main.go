@@ -1,21 +1,50 @@
-/*
-Copyright © 2025 NAME HERE <EMAIL ADDRESS>
-*/
 package main
 
 import (
 	"os"
+	"runtime/debug"
 
 	"github.com/sim-deos/plain/cmd"
 	"github.com/sim-deos/plain/internal/app"
+	"github.com/sim-deos/plain/internal/bugreport"
 	"github.com/sim-deos/plain/internal/git"
+
+	"github.com/spf13/cobra"
 )
 
 func main() {
 	app := &app.App{Git: git.NewShellClient()}
+	if !git.GitInstalled() {
+		app.Git, app.GitMissing = git.NewNativeClient(), true
+	}
 	root := cmd.NewRootCmd(app)
-	err := root.Execute()
+	defer func() {
+		if v := recover(); v != nil {
+			recordFailure(bugreport.NewPanicRecord(commandPath(root), v, debug.Stack()))
+			panic(v)
+		}
+	}()
+	c, err := cmd.Execute(root)
 	if err != nil {
+		app.Log.Error("command failed", "command", c.CommandPath(), "err", err)
+		recordFailure(bugreport.NewErrorRecord(c.CommandPath(), err))
 		os.Exit(1)
 	}
 }
+
+// commandPath is the command os.Args runs, e.g. plain done, without the arguments, which can name things.
+func commandPath(root *cobra.Command) string {
+	c, _, err := root.Find(os.Args[1:])
+	if err != nil {
+		return root.CommandPath()
+	}
+	return c.CommandPath()
+}
+
+// recordFailure keeps record for plain bugreport. Failing to keep it mustn't hide the failure itself, so
+// errors are ignored.
+func recordFailure(record bugreport.ErrorRecord) {
+	if log, err := bugreport.NewErrorLog(); err == nil {
+		log.Record(record)
+	}
+}